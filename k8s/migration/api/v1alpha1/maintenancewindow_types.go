@@ -0,0 +1,114 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// RecurringWindow is a window that opens on a cron schedule and stays open for a duration
+type RecurringWindow struct {
+	// Schedule is a cron expression with minute, hour, day of month, month and day of week fields
+	// for the times the window opens, e.g. "0 22 * * fri" for 22:00 every Friday
+	// +kubebuilder:validation:MinLength=1
+	Schedule string `json:"schedule"`
+
+	// Duration is how long the window stays open, e.g. "4h"
+	Duration metav1.Duration `json:"duration"`
+
+	// TimeZone is the IANA time zone of the schedule, e.g. "Europe/Berlin". UTC when it is not set
+	// +optional
+	TimeZone string `json:"timeZone,omitempty"`
+}
+
+// BlackoutPeriod is a period during which a maintenance window stays closed, such as a change freeze
+type BlackoutPeriod struct {
+	// Name describes the blackout
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// Start is when the blackout begins
+	// +kubebuilder:validation:Type=string
+	// +kubebuilder:validation:Format:=date-time
+	Start metav1.Time `json:"start"`
+
+	// End is when the blackout ends
+	// +kubebuilder:validation:Type=string
+	// +kubebuilder:validation:Format:=date-time
+	End metav1.Time `json:"end"`
+}
+
+// MaintenanceWindowSpec defines the desired state of MaintenanceWindow
+type MaintenanceWindowSpec struct {
+	// Windows are the recurring windows. The maintenance window is open while any of them is open
+	// and no blackout applies
+	// +kubebuilder:validation:MinItems=1
+	Windows []RecurringWindow `json:"windows"`
+
+	// Blackouts are periods during which the maintenance window stays closed even if a window is open
+	// +optional
+	Blackouts []BlackoutPeriod `json:"blackouts,omitempty"`
+}
+
+// MaintenanceWindowStatus defines the observed state of MaintenanceWindow
+type MaintenanceWindowStatus struct {
+	// Open indicates the maintenance window is open
+	Open bool `json:"open,omitempty"`
+	// ClosesAt is when the open maintenance window closes
+	// +optional
+	ClosesAt *metav1.Time `json:"closesAt,omitempty"`
+	// NextOpen is when the closed maintenance window opens next
+	// +optional
+	NextOpen *metav1.Time `json:"nextOpen,omitempty"`
+	// ValidationStatus is Succeeded when the windows and blackouts are valid and Failed otherwise
+	// +optional
+	ValidationStatus string `json:"validationStatus,omitempty"`
+	// Message explains the state of the maintenance window
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// MaintenanceWindow is the Schema for the maintenancewindows API. It defines recurring windows and
+// blackout dates that MigrationPlans reference to confine the data copy and the cutover of their
+// migrations. A migration waits for the window to open and, if it closes before the source VM is
+// shut down, for the next one.
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Open",type="boolean",JSONPath=".status.open"
+// +kubebuilder:printcolumn:name="Closes At",type="string",JSONPath=".status.closesAt"
+// +kubebuilder:printcolumn:name="Next Open",type="string",JSONPath=".status.nextOpen"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+type MaintenanceWindow struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   MaintenanceWindowSpec   `json:"spec,omitempty"`
+	Status MaintenanceWindowStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// MaintenanceWindowList contains a list of MaintenanceWindow
+type MaintenanceWindowList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []MaintenanceWindow `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&MaintenanceWindow{}, &MaintenanceWindowList{})
+}
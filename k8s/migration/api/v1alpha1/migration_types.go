@@ -25,7 +25,7 @@ import (
 // tracking the detailed progression through various stages including validation, data copying,
 // disk conversion, and cutover. Each phase provides visibility into the migration's progress,
 // enabling precise monitoring and troubleshooting of the migration workflow.
-// +kubebuilder:validation:Enum=Pending;Validating;ValidationFailed;AwaitingDataCopyStart;CopyingBlocks;CopyingChangedBlocks;ConvertingDisk;AwaitingCutOverStartTime;AwaitingAdminCutOver;Succeeded;Failed;Unknown;ConnectingToESXi;CreatingInitiatorGroup;CreatingVolume;ImportingToCinder;MappingVolume;RescanningStorage;XCOPYInProgress
+// +kubebuilder:validation:Enum=Pending;Validating;ValidationFailed;AwaitingDataCopyStart;CopyingBlocks;CopyingChangedBlocks;VerifyingData;ConvertingDisk;AwaitingCutOverStartTime;AwaitingAdminCutOver;Succeeded;Failed;RolledBack;Unknown;ConnectingToESXi;CreatingInitiatorGroup;CreatingVolume;ImportingToCinder;MappingVolume;RescanningStorage;XCOPYInProgress
 type VMMigrationPhase string
 
 // MigrationConditionType represents the type of condition for a migration, used to track
@@ -46,6 +46,8 @@ const (
 	VMMigrationPhaseCopying VMMigrationPhase = "CopyingBlocks"
 	// VMMigrationPhaseCopyingChangedBlocks indicates copying of changed blocks is in progress
 	VMMigrationPhaseCopyingChangedBlocks VMMigrationPhase = "CopyingChangedBlocks"
+	// VMMigrationPhaseVerifyingData indicates the copied data is being verified against the source snapshot
+	VMMigrationPhaseVerifyingData VMMigrationPhase = "VerifyingData"
 	// VMMigrationPhaseConvertingDisk indicates disk format conversion is in progress
 	VMMigrationPhaseConvertingDisk VMMigrationPhase = "ConvertingDisk"
 	// VMMigrationPhaseAwaitingCutOverStartTime indicates waiting for scheduled cutover time
@@ -56,6 +58,8 @@ const (
 	VMMigrationPhaseSucceeded VMMigrationPhase = "Succeeded"
 	// VMMigrationPhaseFailed indicates the migration has failed
 	VMMigrationPhaseFailed VMMigrationPhase = "Failed"
+	// VMMigrationPhaseRolledBack indicates the cutover failed and the source VM was restored
+	VMMigrationPhaseRolledBack VMMigrationPhase = "RolledBack"
 	// VMMigrationPhaseUnknown indicates the migration state is unknown
 	VMMigrationPhaseUnknown VMMigrationPhase = "Unknown"
 
@@ -75,6 +79,18 @@ const (
 	VMMigrationPhaseStorageAcceleratedCopyInProgress VMMigrationPhase = "StorageAcceleratedCopyInProgress"
 )
 
+const (
+	// HealthCheckStatusPassed indicates a health check passed
+	HealthCheckStatusPassed = "Passed"
+	// HealthCheckStatusFailed indicates a health check did not pass within its retry budget
+	HealthCheckStatusFailed = "Failed"
+
+	// HookStatusSucceeded indicates a hook Job completed
+	HookStatusSucceeded = "Succeeded"
+	// HookStatusFailed indicates a hook Job failed or timed out
+	HookStatusFailed = "Failed"
+)
+
 // MigrationSpec defines the desired state of Migration
 type MigrationSpec struct {
 	// MigrationPlan is the name of the migration plan
@@ -102,6 +118,29 @@ type MigrationSpec struct {
 	// +optional
 	// +kubebuilder:validation:Enum=hot;cold
 	MigrationType string `json:"migrationType,omitempty"`
+
+	// Rollback requests a rollback to the source VM after a failed cutover. The source VM is powered
+	// back on and the target VM and ports are deleted. It is honoured while the migration waits for it
+	// under the Manual rollback policy of the migration plan
+	// +optional
+	Rollback bool `json:"rollback,omitempty"`
+
+	// TestBoot requests a test boot of the target from the data replicated so far. The target volumes
+	// are cloned in Cinder, converted and booted on the given network while replication continues and
+	// the source VM is left untouched. Removing it deletes the test server and its volumes. It is
+	// honoured while the migration waits for the admin cutover, the result is reported in the
+	// TestBoot condition
+	// +optional
+	TestBoot *TestBootSpec `json:"testBoot,omitempty"`
+}
+
+// TestBootSpec defines a test boot of the target VM
+type TestBootSpec struct {
+	// Network is the name of the OpenStack network the test server is attached to. It should be
+	// isolated from the networks of the source VM, the test server keeps its MAC addresses and gets
+	// its IP addresses from this network
+	// +kubebuilder:validation:MinLength=1
+	Network string `json:"network"`
 }
 
 // MigrationStatus defines the observed state of Migration
@@ -115,7 +154,8 @@ type MigrationStatus struct {
 	// AgentName is the name of the agent where migration is running
 	AgentName string `json:"agentName,omitempty"`
 
-	// CurrentDisk tracks which disk is currently being copied (e.g., "0", "1")
+	// CurrentDisk tracks which disks are currently being copied (e.g., "0", "1").
+	// Disks copied in parallel are comma-separated (e.g., "0,2")
 	// Extracted from migration pod events
 	// +optional
 	CurrentDisk string `json:"currentDisk,omitempty"`
@@ -123,17 +163,108 @@ type MigrationStatus struct {
 	// TotalDisks is the total number of disks to be migrated
 	// +optional
 	TotalDisks int `json:"totalDisks,omitempty"`
+	// BytesRemaining is the number of bytes of the disks of the VM left to copy, estimated
+	// from the copy progress reported by the migration pod
+	// +optional
+	BytesRemaining int64 `json:"bytesRemaining,omitempty"`
+
+	// ThroughputBytesPerSecond is the copy rate of the migration, measured between progress reports
+	// +optional
+	ThroughputBytesPerSecond int64 `json:"throughputBytesPerSecond,omitempty"`
+
+	// ProgressTime is when BytesRemaining last changed
+	// +optional
+	ProgressTime *metav1.Time `json:"progressTime,omitempty"`
+
+	// Source is the vCenter, ESXi host and datastores the migration copies from. It is recorded when
+	// the migration is queued or scheduled, and counts against the migration limits of each of them
+	// once the migration is placed on an agent
+	// +optional
+	Source *MigrationSource `json:"source,omitempty"`
+
+	// QueuePosition is the position of the migration in the queue it waits in, 1 is admitted next.
+	// Migrations waiting for an agent queue across all plans, migrations waiting for a slot of their
+	// source queue behind the older migrations that copy from the same limited source. It is 0 when
+	// the migration does not wait in a queue
+	// +optional
+	QueuePosition int `json:"queuePosition,omitempty"`
+
 	// Retryable indicates whether this migration can be retried when it fails.
 	// Set to false for VMs with RDM (Raw Device Mapping) disks that share storage,
 	// as RDM disk migration state prevents automatic retry.
 	// +optional
 	Retryable *bool `json:"retryable,omitempty"`
+
+	// HealthChecks are the results of the health checks of the migration plan
+	// +optional
+	HealthChecks []HealthCheckResult `json:"healthChecks,omitempty"`
+
+	// BlockedBy are the VMs of the plan the cutover of this migration waits for
+	// +optional
+	BlockedBy []string `json:"blockedBy,omitempty"`
+
+	// SnapshotType is the kind of snapshot the final sync copied from, Quiesced or CrashConsistent.
+	// It is only recorded when the plan configures snapshotConsistency
+	// +optional
+	SnapshotType string `json:"snapshotType,omitempty"`
+
+	// Hooks are the results of the hooks run for the migration
+	// +optional
+	Hooks []HookResult `json:"hooks,omitempty"`
+}
+
+// MigrationSource is the source of the data a migration copies
+type MigrationSource struct {
+	// VMwareCreds is the name of the VMwareCreds of the vCenter
+	// +optional
+	VMwareCreds string `json:"vmwareCreds,omitempty"`
+	// ESXiHost is the name of the ESXi host the VM runs on
+	// +optional
+	ESXiHost string `json:"esxiHost,omitempty"`
+	// Datastores are the datastores holding the disks of the VM
+	// +optional
+	Datastores []string `json:"datastores,omitempty"`
+}
+
+// HookResult is the outcome of a hook Job
+type HookResult struct {
+	// Name is the name of the hook in the migration plan
+	Name string `json:"name"`
+	// Stage is the stage the hook ran at
+	Stage string `json:"stage"`
+	// Status is Succeeded or Failed
+	// +kubebuilder:validation:Enum=Succeeded;Failed
+	Status string `json:"status"`
+	// Message describes the outcome of the hook Job
+	// +optional
+	Message string `json:"message,omitempty"`
+	// LastTransitionTime is when the result was reported
+	// +optional
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+}
+
+// HealthCheckResult is the outcome of a health check run against the target VM
+type HealthCheckResult struct {
+	// Name is the name of the health check in the migration plan
+	Name string `json:"name"`
+	// Status is Passed or Failed
+	// +kubebuilder:validation:Enum=Passed;Failed
+	Status string `json:"status"`
+	// Message describes the outcome of the last attempt
+	// +optional
+	Message string `json:"message,omitempty"`
+	// LastTransitionTime is when the result was reported
+	// +optional
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
 // +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=".status.phase"
 // +kubebuilder:printcolumn:name="Agent Name",type="string",JSONPath=".status.agentName"
+// +kubebuilder:printcolumn:name="Queue Position",type="integer",JSONPath=".status.queuePosition",priority=1
+// +kubebuilder:printcolumn:name="Blocked By",type="string",JSONPath=".status.blockedBy",priority=1
+// +kubebuilder:printcolumn:name="Snapshot",type="string",JSONPath=".status.snapshotType",priority=1
 // +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 
 // Migration is the Schema for the migrations API that represents a single virtual machine
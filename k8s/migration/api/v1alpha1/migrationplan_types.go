@@ -35,6 +35,15 @@ type MigrationPlanStrategy struct {
 	// +kubebuilder:validation:Type=string
 	// +kubebuilder:validation:Format:=date-time
 	VMCutoverEnd metav1.Time `json:"vmCutoverEnd,omitempty"`
+	// DataCopyWindow is the name of the MaintenanceWindow the data copy of the migrations starts in.
+	// Migrations wait for it to open after DataCopyStart
+	// +optional
+	DataCopyWindow string `json:"dataCopyWindow,omitempty"`
+	// CutoverWindow is the name of the MaintenanceWindow the cutover of the migrations runs in.
+	// Migrations wait for it to open after VMCutoverStart, and for the next one when it closes
+	// before the source VM is shut down
+	// +optional
+	CutoverWindow string `json:"cutoverWindow,omitempty"`
 	// +kubebuilder:default:=false
 	AdminInitiatedCutOver bool `json:"adminInitiatedCutOver,omitempty"`
 	// +kubebuilder:default:=false
@@ -45,6 +54,139 @@ type MigrationPlanStrategy struct {
 	DisconnectSourceNetwork bool `json:"disconnectSourceNetwork,omitempty"`
 	// +kubebuilder:default:=false
 	ArrayOffload bool `json:"arrayOffload,omitempty"`
+	// BandwidthLimitMbps caps the read bandwidth of each migration of the plan in megabits per second.
+	// It can be changed while migrations are running. 0 means unlimited
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	BandwidthLimitMbps int `json:"bandwidthLimitMbps,omitempty"`
+	// RollbackPolicy decides what happens when the cutover fails after the source VM was shut down.
+	// Automatic powers the source VM back on and removes the target VM right away, Manual waits for
+	// spec.rollback to be set on the Migration. When unset, the source VM is left as it is
+	// +optional
+	// +kubebuilder:validation:Enum=Automatic;Manual
+	RollbackPolicy string `json:"rollbackPolicy,omitempty"`
+	// HealthChecks are run against the target VM after the cutover. When set, they replace the ping
+	// and HTTP checks of PerformHealthChecks and HealthCheckPort
+	// +optional
+	HealthChecks []HealthCheck `json:"healthChecks,omitempty"`
+	// HealthCheckFailurePolicy decides what happens when a health check does not pass. Warn only
+	// reports it, Fail marks the migration failed and keeps the target VM for inspection, Rollback
+	// rolls back to the source VM following RollbackPolicy, right away when it is unset. Defaults to
+	// Rollback when RollbackPolicy is set and Warn otherwise
+	// +optional
+	// +kubebuilder:validation:Enum=Warn;Fail;Rollback
+	HealthCheckFailurePolicy string `json:"healthCheckFailurePolicy,omitempty"`
+	// SnapshotConsistency takes the snapshot the final sync copies from through VMware Tools
+	// quiescing, so that the applications of the guest flush their data first. When unset, the
+	// final snapshot is crash consistent
+	// +optional
+	SnapshotConsistency *SnapshotConsistency `json:"snapshotConsistency,omitempty"`
+}
+
+// SnapshotConsistency configures the quiesced snapshot of the final sync. The snapshot is taken
+// while the source VM still runs and the VM is powered off right after it, before its applications
+// are thawed. The final sync copies the disks as they were in the snapshot.
+type SnapshotConsistency struct {
+	// VMs limits the quiesced snapshot to these VMs of the plan. All VMs of the plan are
+	// quiesced when it is empty
+	// +optional
+	VMs []string `json:"vms,omitempty"`
+	// IncludeMemory also saves the memory of the VM in the snapshot
+	// +optional
+	IncludeMemory bool `json:"includeMemory,omitempty"`
+	// PreFreezeScript is run in the guest through VMware Tools before the snapshot
+	// +optional
+	PreFreezeScript *GuestScript `json:"preFreezeScript,omitempty"`
+	// PostThawScript is run in the guest through VMware Tools when the VM keeps running after the
+	// pre-freeze script: the snapshot failed or the VM could not be powered off
+	// +optional
+	PostThawScript *GuestScript `json:"postThawScript,omitempty"`
+	// GuestCredentialsSecretRef is the name of a secret in the migration-system namespace with the
+	// username and password keys of the guest account the scripts run as
+	// +optional
+	GuestCredentialsSecretRef string `json:"guestCredentialsSecretRef,omitempty"`
+	// FailurePolicy decides what happens when the guest cannot be quiesced, e.g. VMware Tools are
+	// not running or VSS fails. CrashConsistent takes a crash consistent snapshot instead, Fail
+	// fails the migration before the source VM is shut down
+	// +optional
+	// +kubebuilder:validation:Enum=CrashConsistent;Fail
+	// +kubebuilder:default:=CrashConsistent
+	FailurePolicy string `json:"failurePolicy,omitempty"`
+}
+
+const (
+	// SnapshotFailurePolicyCrashConsistent takes a crash consistent snapshot when the guest cannot be quiesced
+	SnapshotFailurePolicyCrashConsistent = "CrashConsistent"
+	// SnapshotFailurePolicyFail fails the migration when the guest cannot be quiesced
+	SnapshotFailurePolicyFail = "Fail"
+
+	// SnapshotTypeQuiesced is a snapshot taken after VMware Tools quiesced the guest
+	SnapshotTypeQuiesced = "Quiesced"
+	// SnapshotTypeCrashConsistent is a snapshot taken without quiescing the guest
+	SnapshotTypeCrashConsistent = "CrashConsistent"
+)
+
+// GuestScript is a program run in the guest of the source VM through VMware Tools guest operations
+type GuestScript struct {
+	// Path is the absolute path of the program in the guest
+	// +kubebuilder:validation:MinLength=1
+	Path string `json:"path"`
+	// Arguments are passed to the program on its command line
+	// +optional
+	Arguments string `json:"arguments,omitempty"`
+	// TimeoutSeconds is how long the program may run before it is terminated and considered failed
+	// +optional
+	// +kubebuilder:default:=300
+	// +kubebuilder:validation:Minimum=1
+	TimeoutSeconds int `json:"timeoutSeconds,omitempty"`
+}
+
+// HealthCheck defines a check run against the target VM after the cutover. A check is retried
+// until it passes or its retry budget is used up.
+type HealthCheck struct {
+	// Name identifies the check in the migration status
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	Name string `json:"name"`
+	// Type is the kind of check. TCP connects to Port, HTTP and HTTPS get Path from Port and
+	// ConsoleLog watches the Nova console log of the target VM for Pattern
+	// +kubebuilder:validation:Enum=TCP;HTTP;HTTPS;ConsoleLog
+	Type string `json:"type"`
+	// Port is the port checked by TCP, HTTP and HTTPS checks on every IP address of the target VM
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	Port int `json:"port,omitempty"`
+	// Path is the path requested by HTTP and HTTPS checks, defaults to /
+	// +optional
+	Path string `json:"path,omitempty"`
+	// ExpectedStatus is the response status HTTP and HTTPS checks expect, defaults to 200
+	// +optional
+	// +kubebuilder:validation:Minimum=100
+	// +kubebuilder:validation:Maximum=599
+	ExpectedStatus int `json:"expectedStatus,omitempty"`
+	// BodyRegex is a regular expression the response body of HTTP and HTTPS checks has to match
+	// +optional
+	BodyRegex string `json:"bodyRegex,omitempty"`
+	// Pattern is a regular expression that fails a ConsoleLog check as soon as the console log
+	// matches it, e.g. "Kernel panic|STOP code". The check passes if it never matches during its
+	// retry budget
+	// +optional
+	Pattern string `json:"pattern,omitempty"`
+	// TimeoutSeconds is the timeout of a single attempt
+	// +optional
+	// +kubebuilder:default:=10
+	// +kubebuilder:validation:Minimum=1
+	TimeoutSeconds int `json:"timeoutSeconds,omitempty"`
+	// Retries is the number of attempts made after the first one
+	// +optional
+	// +kubebuilder:default:=10
+	// +kubebuilder:validation:Minimum=0
+	Retries int `json:"retries,omitempty"`
+	// RetryIntervalSeconds is the wait between two attempts
+	// +optional
+	// +kubebuilder:default:=30
+	// +kubebuilder:validation:Minimum=1
+	RetryIntervalSeconds int `json:"retryIntervalSeconds,omitempty"`
 }
 
 // AdvancedOptions defines advanced configuration options for the migration process
@@ -62,6 +204,28 @@ type AdvancedOptions struct {
 	PeriodicSyncEnabled bool `json:"periodicSyncEnabled,omitempty"`
 	// NetworkPersistence instructs the migration helper to persist the source networking configuration
 	NetworkPersistence bool `json:"networkPersistence,omitempty"`
+	// DiskCopyParallelism is the number of disks of a VM copied in parallel.
+	// When unset, the DISK_COPY_PARALLELISM value from vjailbreak-settings is used
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	DiskCopyParallelism int `json:"diskCopyParallelism,omitempty"`
+	// IntegrityVerification enables hashing the copied data against the source snapshot before cutover
+	// +optional
+	IntegrityVerification *IntegrityVerification `json:"integrityVerification,omitempty"`
+}
+
+// IntegrityVerification configures the post-copy comparison of the target volumes with the
+// final source snapshot. Mismatched extents are re-copied and the migration fails if they
+// still differ afterwards.
+type IntegrityVerification struct {
+	// Mode selects whether every extent of the disks or a random sample of extents is hashed
+	// +kubebuilder:validation:Enum=Full;Sample
+	Mode string `json:"mode"`
+	// SamplePercent is the percentage of extents hashed in Sample mode, defaults to 10
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	SamplePercent int `json:"samplePercent,omitempty"`
 }
 
 // PostMigrationAction defines the post migration action for the virtual machine
@@ -78,6 +242,7 @@ type MigrationPlanSpec struct {
 	// MigrationPlanSpecPerVM is the migration plan specification per virtual machine
 	MigrationPlanSpecPerVM `json:",inline"`
 	// VirtualMachines is a list of virtual machines to be migrated
+	// +optional
 	VirtualMachines [][]string `json:"virtualMachines"`
 	SecurityGroups  []string   `json:"securityGroups,omitempty"`
 	ServerGroup     string     `json:"serverGroup,omitempty"`
@@ -85,6 +250,76 @@ type MigrationPlanSpec struct {
 	// AssignedIPsPerVM is a map of VM names to comma-separated assigned IPs for cold migration
 	// Format: {"vm-name": "IP1,IP2,IP3"} where each IP corresponds to a network interface by index
 	AssignedIPsPerVM map[string]string `json:"assignedIPsPerVM,omitempty"`
+	// Dependencies order the cutovers of the VMs of the plan. A VM is cut over only after the VMs it
+	// depends on were migrated and passed their health checks
+	// +optional
+	Dependencies []VMDependency `json:"dependencies,omitempty"`
+	// VMSelector selects more VMs from the VMwareMachine inventory of the credentials of the
+	// migration template. The VMs it resolves to are frozen into the status when the plan is first
+	// reconciled, and are migrated in parallel after the groups of VirtualMachines. A selection that
+	// matches no VMs is only frozen once the inventory of the credentials was discovered
+	// +optional
+	VMSelector *VMSelector `json:"vmSelector,omitempty"`
+}
+
+// VMSelector selects VMs by their vSphere placement, tags and names. A VM is selected when it
+// matches every criterion that is set and matches no criterion of Exclude.
+type VMSelector struct {
+	// Tags are vSphere tags as category:tag, a VM matches when it has any of them.
+	// category:* matches any tag of the category
+	// +optional
+	Tags []string `json:"tags,omitempty"`
+	// Folders are inventory folder paths, e.g. /DC1/vm/Prod, a VM matches when it is in any of
+	// them or in their subfolders
+	// +optional
+	Folders []string `json:"folders,omitempty"`
+	// ResourcePools are resource pool names, a VM matches when it is in any of them
+	// +optional
+	ResourcePools []string `json:"resourcePools,omitempty"`
+	// Clusters are cluster names, a VM matches when it runs in any of them
+	// +optional
+	Clusters []string `json:"clusters,omitempty"`
+	// NamePattern is a regular expression the VM name matches
+	// +optional
+	NamePattern string `json:"namePattern,omitempty"`
+	// Exclude removes the VMs that match any of its criteria from the selection
+	// +optional
+	Exclude *VMSelectorExclusions `json:"exclude,omitempty"`
+}
+
+// VMSelectorExclusions are the criteria of the VMs a VMSelector leaves out
+type VMSelectorExclusions struct {
+	// Tags are vSphere tags as category:tag, category:* matches any tag of the category
+	// +optional
+	Tags []string `json:"tags,omitempty"`
+	// Folders are inventory folder paths, their subfolders are excluded too
+	// +optional
+	Folders []string `json:"folders,omitempty"`
+	// ResourcePools are resource pool names
+	// +optional
+	ResourcePools []string `json:"resourcePools,omitempty"`
+	// Clusters are cluster names
+	// +optional
+	Clusters []string `json:"clusters,omitempty"`
+	// Names are VM names
+	// +optional
+	Names []string `json:"names,omitempty"`
+	// NamePattern is a regular expression of VM names
+	// +optional
+	NamePattern string `json:"namePattern,omitempty"`
+}
+
+// VMDependency makes the cutover of a VM wait for other VMs of the plan
+type VMDependency struct {
+	// VM is the name of the VM whose cutover waits
+	// +kubebuilder:validation:MinLength=1
+	VM string `json:"vm"`
+	// After are the VMs that must reach Succeeded and pass their health checks before VM is cut over,
+	// health checks under the Warn policy only report. They must be in the same or an earlier group
+	// of virtualMachines and must not be skipped by the plan. The migration of VM fails when one of
+	// them does not succeed
+	// +kubebuilder:validation:MinItems=1
+	After []string `json:"after"`
 }
 
 // MigrationPlanSpecPerVM defines the configuration that applies to each VM in the migration plan
@@ -98,6 +333,118 @@ type MigrationPlanSpecPerVM struct {
 	// +kubebuilder:default:="echo \"Add your startup script here!\""
 	FirstBootScript     string               `json:"firstBootScript,omitempty"`
 	PostMigrationAction *PostMigrationAction `json:"postMigrationAction,omitempty"`
+	// Hooks are user containers run as Kubernetes Jobs at fixed stages of the migration of each VM
+	// +optional
+	Hooks []MigrationHook `json:"hooks,omitempty"`
+	// StrictCapacity blocks the start of the plan while its capacity forecast shows that the target
+	// project lacks the quota for it
+	// +optional
+	StrictCapacity bool `json:"strictCapacity,omitempty"`
+}
+
+// CapacityForecast compares the OpenStack resources a plan needs with the quota left in the target
+// project
+type CapacityForecast struct {
+	// Resources are the resources the plan needs, named after their OpenStack quota
+	Resources []ResourceForecast `json:"resources,omitempty"`
+	// Sufficient is false when the plan needs more of a resource than the project has left
+	Sufficient bool `json:"sufficient"`
+	// Message lists the shortfalls of the project
+	Message string `json:"message,omitempty"`
+	// LastUpdated is when the forecast was computed
+	LastUpdated metav1.Time `json:"lastUpdated,omitempty"`
+}
+
+// ResourceForecast is the demand of a plan for an OpenStack resource and the quota of the target
+// project for it
+type ResourceForecast struct {
+	// Name is the quota name of the resource: cores, ram in MB, instances, ports, volumes,
+	// gigabytes, or gigabytes_<volume type>
+	Name string `json:"name"`
+	// Required is the amount the plan needs
+	Required int64 `json:"required"`
+	// Limit is the quota of the project, -1 when it is unlimited
+	Limit int64 `json:"limit"`
+	// InUse is the amount the project uses or has reserved
+	InUse int64 `json:"inUse"`
+	// Shortfall is how much the plan needs beyond what the project has left, 0 when it fits
+	// +optional
+	Shortfall int64 `json:"shortfall,omitempty"`
+}
+
+const (
+	// HookStagePreCopy runs before the disks of the VM are copied
+	HookStagePreCopy = "PreCopy"
+	// HookStagePreCutover runs before the source VM is powered off for the final sync
+	HookStagePreCutover = "PreCutover"
+	// HookStagePostCutover runs after the target VM was created and passed its health checks
+	HookStagePostCutover = "PostCutover"
+	// HookStageOnFailure runs when the migration of the VM failed
+	HookStageOnFailure = "OnFailure"
+
+	// HealthCheckFailurePolicyWarn reports health checks that did not pass without failing the migration
+	HealthCheckFailurePolicyWarn = "Warn"
+	// HealthCheckFailurePolicyFail fails the migration and keeps the target VM for inspection
+	HealthCheckFailurePolicyFail = "Fail"
+	// HealthCheckFailurePolicyRollback rolls back to the source VM following the rollback policy
+	HealthCheckFailurePolicyRollback = "Rollback"
+
+	// HookFailurePolicyBlock fails the migration when the hook does not succeed
+	HookFailurePolicyBlock = "Block"
+	// HookFailurePolicyWarn reports a hook that did not succeed and continues the migration
+	HookFailurePolicyWarn = "Warn"
+
+	// HookStageAnnotation is set on a Migration by the v2v-helper to request the hooks of a stage
+	HookStageAnnotation = "vjailbreak.k8s.pf9.io/hook-stage"
+	// HookTargetServerIDAnnotation carries the ID of the target server to the hooks
+	HookTargetServerIDAnnotation = "vjailbreak.k8s.pf9.io/hook-target-server-id"
+	// HookTargetIPsAnnotation carries the comma-separated IPs of the target server to the hooks
+	HookTargetIPsAnnotation = "vjailbreak.k8s.pf9.io/hook-target-ips"
+	// HookVMLabel is the label with the name of the VMwareMachine of a hook Job
+	HookVMLabel = "vjailbreak.k8s.pf9.io/hook-vm"
+	// HookStageLabel is the label with the stage of a hook Job
+	HookStageLabel = "vjailbreak.k8s.pf9.io/hook-stage"
+	// HookNameLabel is the label with the name of the hook of a hook Job
+	HookNameLabel = "vjailbreak.k8s.pf9.io/hook"
+)
+
+// MigrationHook is a user container run as a Kubernetes Job at a stage of the migration of a VM.
+// The Job gets the details of the VM in its environment: MIGRATION_NAME, VM_NAME, HOOK_NAME,
+// HOOK_STAGE, VMWARE_MACHINE (the VMwareMachine spec as JSON), TARGET_SERVER_ID and TARGET_IPS.
+type MigrationHook struct {
+	// Name identifies the hook in events and in the Migration status
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	// +kubebuilder:validation:MaxLength=40
+	Name string `json:"name"`
+	// Stage is the point of the migration at which the hook runs
+	// +kubebuilder:validation:Enum=PreCopy;PreCutover;PostCutover;OnFailure
+	Stage string `json:"stage"`
+	// Image is the container image of the hook
+	// +kubebuilder:validation:MinLength=1
+	Image string `json:"image"`
+	// Command overrides the entrypoint of the image
+	// +optional
+	Command []string `json:"command,omitempty"`
+	// Args are the arguments of the command
+	// +optional
+	Args []string `json:"args,omitempty"`
+	// Env are extra environment variables of the hook container
+	// +optional
+	Env []corev1.EnvVar `json:"env,omitempty"`
+	// SecretRefs are names of secrets whose keys are exposed as environment variables
+	// +optional
+	SecretRefs []string `json:"secretRefs,omitempty"`
+	// FailurePolicy is Block to fail the migration when the hook does not succeed, or Warn to
+	// only report it. Hooks of the OnFailure stage never block.
+	// +kubebuilder:validation:Enum=Block;Warn
+	// +kubebuilder:default=Block
+	// +optional
+	FailurePolicy string `json:"failurePolicy,omitempty"`
+	// TimeoutSeconds is how long the hook may run before it is stopped and counted as failed
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default=600
+	// +optional
+	TimeoutSeconds int64 `json:"timeoutSeconds,omitempty"`
 }
 
 // MigrationPlanStatus defines the observed state of MigrationPlan including
@@ -108,6 +455,21 @@ type MigrationPlanStatus struct {
 	MigrationStatus corev1.PodPhase `json:"migrationStatus"`
 	// MigrationMessage is the message associated with the migration
 	MigrationMessage string `json:"migrationMessage"`
+	// CapacityForecast is the projected OpenStack footprint of the plan compared with the quota
+	// of the target project, it is computed until the plan starts
+	// +optional
+	CapacityForecast *CapacityForecast `json:"capacityForecast,omitempty"`
+	// SelectedVMs are the VMs the vmSelector resolved to when the plan was first reconciled, VMs
+	// that match the selector afterwards do not join the plan
+	// +optional
+	SelectedVMs []string `json:"selectedVMs,omitempty"`
+	// SelectionFrozenTime is when SelectedVMs were frozen
+	// +optional
+	SelectionFrozenTime *metav1.Time `json:"selectionFrozenTime,omitempty"`
+	// ValidationWarnings are the problems found when the plan was validated that do not stop it,
+	// e.g. a flavor that cannot boot a VM with Secure Boot or a vTPM
+	// +optional
+	ValidationWarnings []string `json:"validationWarnings,omitempty"`
 }
 
 // +kubebuilder:object:root=true
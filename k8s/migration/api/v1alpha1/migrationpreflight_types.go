@@ -0,0 +1,130 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// MigrationPreflightPhase represents the current phase of the preflight
+// +kubebuilder:validation:Enum=Running;Completed;Failed
+type MigrationPreflightPhase string
+
+const (
+	// MigrationPreflightPhaseRunning indicates the checks are running
+	MigrationPreflightPhaseRunning MigrationPreflightPhase = "Running"
+	// MigrationPreflightPhaseCompleted indicates all checks ran and their results are reported
+	MigrationPreflightPhaseCompleted MigrationPreflightPhase = "Completed"
+	// MigrationPreflightPhaseFailed indicates the checks could not run, for example because the
+	// migration plan does not exist
+	MigrationPreflightPhaseFailed MigrationPreflightPhase = "Failed"
+)
+
+// PreflightResult is the outcome of a preflight check
+// +kubebuilder:validation:Enum=Pass;Warn;Fail
+type PreflightResult string
+
+const (
+	// PreflightResultPass indicates the check found nothing in the way of the migration
+	PreflightResultPass PreflightResult = "Pass"
+	// PreflightResultWarn indicates the migration can run but may be slow or need attention
+	PreflightResultWarn PreflightResult = "Warn"
+	// PreflightResultFail indicates the migration will fail
+	PreflightResultFail PreflightResult = "Fail"
+)
+
+// MigrationPreflightSpec defines the desired state of MigrationPreflight
+type MigrationPreflightSpec struct {
+	// MigrationPlan is the name of the migration plan whose VMs are checked
+	// +optional
+	MigrationPlan string `json:"migrationPlan,omitempty"`
+	// MigrationTemplate is the name of the migration template the VMs are checked against,
+	// it is only used when MigrationPlan is not set
+	// +optional
+	MigrationTemplate string `json:"migrationTemplate,omitempty"`
+	// VirtualMachines are the names of the VMs to check, they are only used when MigrationPlan
+	// is not set
+	// +optional
+	VirtualMachines []string `json:"virtualMachines,omitempty"`
+}
+
+// PreflightCheck is the result of a single preflight check
+type PreflightCheck struct {
+	// Name is the name of the check
+	Name string `json:"name"`
+	// Result is the outcome of the check
+	Result PreflightResult `json:"result"`
+	// Message explains the outcome of the check
+	Message string `json:"message,omitempty"`
+}
+
+// VMPreflightResult holds the results of the preflight checks of a VM
+type VMPreflightResult struct {
+	// Name is the name of the VM
+	Name string `json:"name"`
+	// Result is the worst result of the checks of the VM
+	Result PreflightResult `json:"result"`
+	// Checks are the results of the checks of the VM
+	Checks []PreflightCheck `json:"checks,omitempty"`
+}
+
+// MigrationPreflightStatus defines the observed state of MigrationPreflight
+type MigrationPreflightStatus struct {
+	// Phase is the current phase of the preflight
+	Phase MigrationPreflightPhase `json:"phase,omitempty"`
+	// Result is the worst result of all checks, the migration is a go unless it is Fail
+	Result PreflightResult `json:"result,omitempty"`
+	// Message is the message associated with the current state of the preflight
+	Message string `json:"message,omitempty"`
+	// Checks are the results of the checks that apply to all VMs, such as the VDDK presence
+	Checks []PreflightCheck `json:"checks,omitempty"`
+	// VMs are the results of the checks of each VM
+	VMs []VMPreflightResult `json:"vms,omitempty"`
+	// ObservedGeneration is the generation of the spec the checks ran for
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// CompletionTime is when the checks finished
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+}
+
+// MigrationPreflight is the Schema for the migrationpreflights API. It runs the checks a migration
+// would run for the VMs of a migration plan, or for a set of VMs, without creating any resources,
+// and reports a pass, warn or fail result per VM and check.
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=".status.phase"
+// +kubebuilder:printcolumn:name="Result",type="string",JSONPath=".status.result"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+type MigrationPreflight struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   MigrationPreflightSpec   `json:"spec,omitempty"`
+	Status MigrationPreflightStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// MigrationPreflightList contains a list of MigrationPreflight
+type MigrationPreflightList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []MigrationPreflight `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&MigrationPreflight{}, &MigrationPreflightList{})
+}
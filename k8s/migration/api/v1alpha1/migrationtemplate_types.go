@@ -20,16 +20,68 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+// Formats of the files imported by a file source
+const (
+	FileSourceFormatOVA  = "ova"
+	FileSourceFormatOVF  = "ovf"
+	FileSourceFormatVMDK = "vmdk"
+	FileSourceFormatVHDX = "vhdx"
+)
+
 // MigrationTemplateSource defines the source environment details for the migration template
 type MigrationTemplateSource struct {
 	// VMwareRef is the reference to the VMware credentials to be used as the source environment
-	VMwareRef string `json:"vmwareRef"`
+	// +optional
+	VMwareRef string `json:"vmwareRef,omitempty"`
+	// File imports the VMs from exported OVA/OVF appliances or loose VMDK and VHDX disks instead of
+	// a VMware environment. The virtual machines of a migration plan are the names of the files.
+	// +optional
+	File *MigrationTemplateFileSource `json:"file,omitempty"`
+}
+
+// MigrationTemplateFileSource defines where the files imported by a migration template are found
+type MigrationTemplateFileSource struct {
+	// PVCName is the PersistentVolumeClaim holding the files, it is mounted read-only in the migration pods
+	// +optional
+	PVCName string `json:"pvcName,omitempty"`
+	// Path is the directory of the files in the PersistentVolumeClaim
+	// +optional
+	Path string `json:"path,omitempty"`
+	// URL is the HTTP or HTTPS location of the directory of the files, used when PVCName is not set
+	// +optional
+	URL string `json:"url,omitempty"`
+	// Format is the format of the files, it is detected from the file extension when not set
+	// +kubebuilder:validation:Enum=ova;ovf;vmdk;vhdx
+	// +optional
+	Format string `json:"format,omitempty"`
+	// CPU is the number of vCPUs of VMs imported from loose disks, which carry no VM description
+	// +optional
+	CPU int32 `json:"cpu,omitempty"`
+	// MemoryMB is the memory of VMs imported from loose disks, which carry no VM description
+	// +optional
+	MemoryMB int32 `json:"memoryMB,omitempty"`
 }
 
 // MigrationTemplateDestination defines the destination environment details for the migration template
 type MigrationTemplateDestination struct {
 	// OpenstackRef is the reference to the OpenStack credentials to be used as the destination environment
 	OpenstackRef string `json:"openstackRef"`
+	// File writes the converted disks and a libvirt domain XML of each VM to a PersistentVolumeClaim
+	// instead of creating the VM in OpenStack. The mappings name libvirt networks then and are not
+	// checked against OpenStack.
+	// +optional
+	File *MigrationTemplateFileDestination `json:"file,omitempty"`
+}
+
+// MigrationTemplateFileDestination defines the PersistentVolumeClaim the images of migrated VMs are written to
+type MigrationTemplateFileDestination struct {
+	// PVCName is the PersistentVolumeClaim the images are written to, each VM gets a directory named after it
+	PVCName string `json:"pvcName"`
+	// Format is the format of the images
+	// +kubebuilder:validation:Enum=qcow2;raw
+	// +kubebuilder:default:=qcow2
+	// +optional
+	Format string `json:"format,omitempty"`
 }
 
 // MigrationTemplateSpec defines the desired state of MigrationTemplate including source/destination environments and mappings
@@ -0,0 +1,172 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// MigrationWavePhase is the phase of a migration wave
+type MigrationWavePhase string
+
+const (
+	// MigrationWavePhaseRunning indicates a plan of the wave is running
+	MigrationWavePhaseRunning MigrationWavePhase = "Running"
+	// MigrationWavePhaseWaitingForApproval indicates the next plan of the wave waits for an approval
+	MigrationWavePhaseWaitingForApproval MigrationWavePhase = "WaitingForApproval"
+	// MigrationWavePhaseWaitingForPlan indicates the next plan of the wave does not exist yet
+	MigrationWavePhaseWaitingForPlan MigrationWavePhase = "WaitingForPlan"
+	// MigrationWavePhasePaused indicates the wave and its started plans are paused
+	MigrationWavePhasePaused MigrationWavePhase = "Paused"
+	// MigrationWavePhaseSucceeded indicates all plans of the wave succeeded
+	MigrationWavePhaseSucceeded MigrationWavePhase = "Succeeded"
+	// MigrationWavePhaseFailed indicates the last plan of the wave failed
+	MigrationWavePhaseFailed MigrationWavePhase = "Failed"
+)
+
+const (
+	// WavePlanKindMigrationPlan is the kind of a MigrationPlan of a wave
+	WavePlanKindMigrationPlan = "MigrationPlan"
+	// WavePlanKindRollingMigrationPlan is the kind of a RollingMigrationPlan of a wave
+	WavePlanKindRollingMigrationPlan = "RollingMigrationPlan"
+
+	// WavePlanPhasePending indicates the wave has not started the plan yet
+	WavePlanPhasePending = "Pending"
+	// WavePlanPhaseNotFound indicates the plan does not exist
+	WavePlanPhaseNotFound = "NotFound"
+	// WavePlanPhaseRunning indicates the plan is running
+	WavePlanPhaseRunning = "Running"
+	// WavePlanPhasePaused indicates the plan is paused
+	WavePlanPhasePaused = "Paused"
+	// WavePlanPhaseSucceeded indicates the plan succeeded
+	WavePlanPhaseSucceeded = "Succeeded"
+	// WavePlanPhaseFailed indicates the plan failed
+	WavePlanPhaseFailed = "Failed"
+)
+
+// WavePlan is a plan of a migration wave
+type WavePlan struct {
+	// Kind is MigrationPlan or RollingMigrationPlan
+	// +kubebuilder:validation:Enum=MigrationPlan;RollingMigrationPlan
+	// +kubebuilder:default:=MigrationPlan
+	// +optional
+	Kind string `json:"kind,omitempty"`
+
+	// Name is the name of the plan in the namespace of the wave
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// RequireApproval makes the plan wait for an approval even when the previous plan succeeded
+	// +optional
+	RequireApproval bool `json:"requireApproval,omitempty"`
+}
+
+// MigrationWaveSpec defines the desired state of MigrationWave
+type MigrationWaveSpec struct {
+	// Plans are started one after the other. A plan starts when the previous one succeeded, or when
+	// it is approved. Plans the wave has not started yet are held with the pause label, create them
+	// with the label so that they do not start before the wave holds them.
+	// +kubebuilder:validation:MinItems=1
+	Plans []WavePlan `json:"plans"`
+
+	// Approvals are the names of the plans approved to start. An approval starts a plan that
+	// requires one once the previous plan succeeded, and starts a plan after a failed one.
+	// +optional
+	Approvals []string `json:"approvals,omitempty"`
+}
+
+// WavePlanStatus is the state of a plan of a migration wave
+type WavePlanStatus struct {
+	// Kind is MigrationPlan or RollingMigrationPlan
+	Kind string `json:"kind"`
+	// Name is the name of the plan
+	Name string `json:"name"`
+	// Phase is Pending, NotFound, Running, Paused, Succeeded or Failed
+	Phase string `json:"phase"`
+	// Started indicates the wave started the plan
+	// +optional
+	Started bool `json:"started,omitempty"`
+	// StartTime is when the wave started the plan
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	// Succeeded is the number of VMs of the plan that were migrated
+	// +optional
+	Succeeded int `json:"succeeded,omitempty"`
+	// Failed is the number of VMs of the plan that failed to migrate
+	// +optional
+	Failed int `json:"failed,omitempty"`
+	// InProgress is the number of VMs of the plan that are being migrated
+	// +optional
+	InProgress int `json:"inProgress,omitempty"`
+}
+
+// MigrationWaveStatus defines the observed state of MigrationWave
+type MigrationWaveStatus struct {
+	// Phase is the phase of the wave
+	// +optional
+	Phase MigrationWavePhase `json:"phase,omitempty"`
+	// CurrentPlan is the name of the last plan the wave started
+	// +optional
+	CurrentPlan string `json:"currentPlan,omitempty"`
+	// Message explains the phase of the wave
+	// +optional
+	Message string `json:"message,omitempty"`
+	// Plans are the states of the plans of the wave
+	// +optional
+	Plans []WavePlanStatus `json:"plans,omitempty"`
+	// Succeeded is the number of VMs of all plans that were migrated
+	// +optional
+	Succeeded int `json:"succeeded,omitempty"`
+	// Failed is the number of VMs of all plans that failed to migrate
+	// +optional
+	Failed int `json:"failed,omitempty"`
+	// InProgress is the number of VMs of all plans that are being migrated
+	// +optional
+	InProgress int `json:"inProgress,omitempty"`
+}
+
+// MigrationWave is the Schema for the migrationwaves API. It runs an ordered list of MigrationPlans
+// and RollingMigrationPlans one after the other, with manual approvals between them, and adds up the
+// VMs they migrated. Pausing the wave with the pause label pauses the plans it started.
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=".status.phase"
+// +kubebuilder:printcolumn:name="Current Plan",type="string",JSONPath=".status.currentPlan"
+// +kubebuilder:printcolumn:name="Succeeded",type="integer",JSONPath=".status.succeeded"
+// +kubebuilder:printcolumn:name="Failed",type="integer",JSONPath=".status.failed"
+// +kubebuilder:printcolumn:name="In Progress",type="integer",JSONPath=".status.inProgress"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+type MigrationWave struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   MigrationWaveSpec   `json:"spec,omitempty"`
+	Status MigrationWaveStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// MigrationWaveList contains a list of MigrationWave
+type MigrationWaveList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []MigrationWave `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&MigrationWave{}, &MigrationWaveList{})
+}
@@ -129,6 +129,10 @@ type RollingMigrationPlanStatus struct {
 	MigratedClusters []string `json:"migratedClusters,omitempty"`
 	// FailedClusters is the list of vCenter clusters that have failed to migrate
 	FailedClusters []string `json:"failedClusters,omitempty"`
+	// CapacityForecast is the projected OpenStack footprint of all VMs of the plan compared with
+	// the quota of the target project, it is computed until the plan starts running
+	// +optional
+	CapacityForecast *CapacityForecast `json:"capacityForecast,omitempty"`
 }
 
 // +kubebuilder:object:root=true
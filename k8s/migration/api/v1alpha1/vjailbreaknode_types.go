@@ -50,6 +50,12 @@ type VjailbreakNodeSpec struct {
 	// If empty, the security groups will be determined from the master node
 	// +optional
 	OpenstackSecurityGroups []string `json:"openstackSecurityGroups,omitempty"`
+
+	// MaxConcurrentMigrations is the number of migrations the node runs at the same time,
+	// the MAX_MIGRATIONS_PER_AGENT setting applies when it is not set
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	MaxConcurrentMigrations int `json:"maxConcurrentMigrations,omitempty"`
 }
 
 // VjailbreakNodeStatus defines the observed state of VjailbreakNode including
@@ -68,6 +74,17 @@ type VjailbreakNodeStatus struct {
 	// ActiveMigrations is the list of active migrations currently being processed on this node,
 	// containing references to MigrationPlan resources
 	ActiveMigrations []string `json:"activeMigrations,omitempty"`
+
+	// BytesRemaining is the number of bytes the active migrations of this node have left to copy
+	BytesRemaining int64 `json:"bytesRemaining,omitempty"`
+
+	// ThroughputBytesPerSecond is the combined copy rate of the active migrations of this node,
+	// measured from their progress, an idle node keeps the last measured rate
+	ThroughputBytesPerSecond int64 `json:"throughputBytesPerSecond,omitempty"`
+
+	// IdleSince is when the node last ran out of migrations, it is only tracked for nodes
+	// created by the autoscaler
+	IdleSince *metav1.Time `json:"idleSince,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -0,0 +1,130 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// NodeScaleEventType is the kind of change the autoscaler made to the agent nodes
+// +kubebuilder:validation:Enum=ScaleUp;Drain;ScaleDown
+type NodeScaleEventType string
+
+const (
+	// NodeScaleEventScaleUp indicates an agent node was created for queued migrations
+	NodeScaleEventScaleUp NodeScaleEventType = "ScaleUp"
+	// NodeScaleEventDrain indicates an idle agent node stopped taking new migrations
+	NodeScaleEventDrain NodeScaleEventType = "Drain"
+	// NodeScaleEventScaleDown indicates a drained agent node was deleted
+	NodeScaleEventScaleDown NodeScaleEventType = "ScaleDown"
+)
+
+// VjailbreakNodeAutoscalerSpec defines the desired state of VjailbreakNodeAutoscaler
+type VjailbreakNodeAutoscalerSpec struct {
+	// MaxNodes is the maximum number of agent nodes the autoscaler runs at the same time
+	// +kubebuilder:validation:Minimum=0
+	MaxNodes int `json:"maxNodes"`
+
+	// OpenstackFlavorID is the flavor of the agent nodes
+	OpenstackFlavorID string `json:"openstackFlavorID"`
+
+	// OpenstackImageID is the image of the agent nodes, the image of the master node is used when
+	// it is not set
+	// +optional
+	OpenstackImageID string `json:"openstackImageID,omitempty"`
+
+	// OpenstackVolumeType is the volume type for the root disk of the agent nodes, the volume type
+	// of the master node is used when it is not set
+	// +optional
+	OpenstackVolumeType string `json:"openstackVolumeType,omitempty"`
+
+	// OpenstackSecurityGroups are the security groups of the agent nodes, the security groups of
+	// the master node are used when it is not set
+	// +optional
+	OpenstackSecurityGroups []string `json:"openstackSecurityGroups,omitempty"`
+
+	// MaxConcurrentMigrations is the number of migrations an agent node runs at the same time,
+	// the MAX_MIGRATIONS_PER_AGENT setting applies when it is not set
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	MaxConcurrentMigrations int `json:"maxConcurrentMigrations,omitempty"`
+
+	// ScaleDownIdleMinutes is how long an agent node has no migrations before it is drained and deleted
+	// +optional
+	// +kubebuilder:default=30
+	// +kubebuilder:validation:Minimum=1
+	ScaleDownIdleMinutes int `json:"scaleDownIdleMinutes,omitempty"`
+}
+
+// NodeScaleEvent records a change the autoscaler made to the agent nodes
+type NodeScaleEvent struct {
+	// Time is when the change was made
+	Time metav1.Time `json:"time"`
+	// Type is the kind of change
+	Type NodeScaleEventType `json:"type"`
+	// Node is the name of the agent node that changed
+	Node string `json:"node"`
+	// Message explains the change
+	Message string `json:"message,omitempty"`
+}
+
+// VjailbreakNodeAutoscalerStatus defines the observed state of VjailbreakNodeAutoscaler
+type VjailbreakNodeAutoscalerStatus struct {
+	// Nodes are the names of the agent nodes created by the autoscaler
+	Nodes []string `json:"nodes,omitempty"`
+	// ReadyNodes is the number of agent nodes created by the autoscaler that take migrations
+	ReadyNodes int `json:"readyNodes,omitempty"`
+	// QueuedMigrations is the number of migrations waiting for a free agent
+	QueuedMigrations int `json:"queuedMigrations,omitempty"`
+	// Message is the message associated with the last decision of the autoscaler
+	Message string `json:"message,omitempty"`
+	// LastScaleTime is when the autoscaler last changed the agent nodes
+	LastScaleTime *metav1.Time `json:"lastScaleTime,omitempty"`
+	// Events are the most recent changes the autoscaler made, newest last
+	Events []NodeScaleEvent `json:"events,omitempty"`
+}
+
+// VjailbreakNodeAutoscaler is the Schema for the vjailbreaknodeautoscalers API. The VjailbreakNode
+// controller creates agent nodes with its profile while migrations are queued for a free agent,
+// and drains and deletes them after they were idle for a while. Only one autoscaler is used, the
+// first by name in the migration-system namespace.
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Max Nodes",type="integer",JSONPath=".spec.maxNodes"
+// +kubebuilder:printcolumn:name="Ready Nodes",type="integer",JSONPath=".status.readyNodes"
+// +kubebuilder:printcolumn:name="Queued",type="integer",JSONPath=".status.queuedMigrations"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+type VjailbreakNodeAutoscaler struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   VjailbreakNodeAutoscalerSpec   `json:"spec,omitempty"`
+	Status VjailbreakNodeAutoscalerStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// VjailbreakNodeAutoscalerList contains a list of VjailbreakNodeAutoscaler
+type VjailbreakNodeAutoscalerList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []VjailbreakNodeAutoscaler `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&VjailbreakNodeAutoscaler{}, &VjailbreakNodeAutoscalerList{})
+}
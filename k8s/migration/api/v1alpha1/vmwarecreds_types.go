@@ -21,6 +21,13 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+const (
+	// VMwareHostTypeVCenter is a vCenter server managing one or more ESXi hosts
+	VMwareHostTypeVCenter = "vCenter"
+	// VMwareHostTypeESXi is a standalone ESXi host that is not managed by a vCenter server
+	VMwareHostTypeESXi = "ESXi"
+)
+
 // VMwareCredsInfo holds the actual VMware credentials after decoding from secret
 type VMwareCredsInfo struct {
 	// Host is the vCenter host
@@ -43,6 +50,12 @@ type VMwareCredsSpec struct {
 	SecretRef corev1.ObjectReference `json:"secretRef,omitempty"`
 	// VcenterHost is the vCenter host
 	VcenterHost string `json:"vcenterHost,omitempty"`
+	// HostType is the kind of endpoint the credentials connect to, a vCenter server or a
+	// standalone ESXi host. VMs of an ESXi host are discovered and copied through the host API.
+	// +kubebuilder:validation:Enum=vCenter;ESXi
+	// +kubebuilder:default:=vCenter
+	// +optional
+	HostType string `json:"hostType,omitempty"`
 }
 
 // VMwareCredsStatus defines the observed state of VMwareCreds
@@ -51,11 +64,16 @@ type VMwareCredsStatus struct {
 	VMwareValidationStatus string `json:"vmwareValidationStatus,omitempty"`
 	// VMwareValidationMessage is the message associated with the VMware validation
 	VMwareValidationMessage string `json:"vmwareValidationMessage,omitempty"`
+	// InventorySyncedTime is the time the inventory of the VMware endpoint was last discovered in
+	// full. The VMwareMachines of the credentials are incomplete until it is set.
+	// +optional
+	InventorySyncedTime *metav1.Time `json:"inventorySyncedTime,omitempty"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
 // +kubebuilder:printcolumn:JSONPath=`.status.vmwareValidationStatus`,name=Status,type=string
+// +kubebuilder:printcolumn:JSONPath=`.spec.hostType`,name=HostType,type=string
 
 // VMwareCreds is the Schema for the vmwarecreds API that defines authentication
 // and connection details for VMware vSphere environments. It provides a secure way to
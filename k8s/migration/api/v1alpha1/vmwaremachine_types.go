@@ -33,6 +33,18 @@ func (g GPUInfo) HasGPU() bool {
 	return g.PassthroughCount > 0 || g.VGPUCount > 0
 }
 
+// FirmwareInfo contains the firmware and security device settings of a VM
+type FirmwareInfo struct {
+	// UEFI is true when the VM boots with EFI firmware instead of BIOS
+	UEFI bool `json:"uefi,omitempty"`
+	// SecureBoot is true when UEFI Secure Boot is enabled for the VM
+	SecureBoot bool `json:"secureBoot,omitempty"`
+	// VTPM is true when a virtual TPM device is attached to the VM
+	VTPM bool `json:"vtpm,omitempty"`
+	// HardwareVersion is the virtual hardware version of the VM, e.g. vmx-19
+	HardwareVersion string `json:"hardwareVersion,omitempty"`
+}
+
 // TotalCount returns the total number of GPU devices
 func (g GPUInfo) TotalCount() int {
 	return g.PassthroughCount + g.VGPUCount
@@ -64,6 +76,12 @@ type VMInfo struct {
 	ESXiName string `json:"esxiName,omitempty"`
 	// ClusterName is the name of the cluster
 	ClusterName string `json:"clusterName,omitempty"`
+	// Folder is the inventory path of the folder of the VM, e.g. /DC1/vm/Prod
+	Folder string `json:"folder,omitempty"`
+	// ResourcePool is the name of the resource pool of the VM
+	ResourcePool string `json:"resourcePool,omitempty"`
+	// Tags are the vSphere tags attached to the VM as category:tag
+	Tags []string `json:"tags,omitempty"`
 	// AssignedIp is the IP address assigned to the VM
 	AssignedIP string `json:"assignedIp,omitempty"`
 	// RDMDisks is the list of RDM disks for the virtual machine
@@ -74,6 +92,8 @@ type VMInfo struct {
 	GuestNetworks []GuestNetwork `json:"guestNetworks,omitempty"`
 	// GPU contains information about GPU devices attached to the VM
 	GPU GPUInfo `json:"gpu,omitempty"`
+	// Firmware contains the firmware, Secure Boot and vTPM settings of the VM
+	Firmware FirmwareInfo `json:"firmware,omitempty"`
 }
 
 // Disk represents a virtual disk attached to a virtual machine
@@ -44,6 +44,11 @@ func (in *AdvancedOptions) DeepCopyInto(out *AdvancedOptions) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.IntegrityVerification != nil {
+		in, out := &in.IntegrityVerification, &out.IntegrityVerification
+		*out = new(IntegrityVerification)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AdvancedOptions.
@@ -352,6 +357,23 @@ func (in *BMConfigStatus) DeepCopy() *BMConfigStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BlackoutPeriod) DeepCopyInto(out *BlackoutPeriod) {
+	*out = *in
+	in.Start.DeepCopyInto(&out.Start)
+	in.End.DeepCopyInto(&out.End)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BlackoutPeriod.
+func (in *BlackoutPeriod) DeepCopy() *BlackoutPeriod {
+	if in == nil {
+		return nil
+	}
+	out := new(BlackoutPeriod)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *BootSource) DeepCopyInto(out *BootSource) {
 	*out = *in
@@ -367,6 +389,27 @@ func (in *BootSource) DeepCopy() *BootSource {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CapacityForecast) DeepCopyInto(out *CapacityForecast) {
+	*out = *in
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = make([]ResourceForecast, len(*in))
+		copy(*out, *in)
+	}
+	in.LastUpdated.DeepCopyInto(&out.LastUpdated)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CapacityForecast.
+func (in *CapacityForecast) DeepCopy() *CapacityForecast {
+	if in == nil {
+		return nil
+	}
+	out := new(CapacityForecast)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ClusterMapping) DeepCopyInto(out *ClusterMapping) {
 	*out = *in
@@ -641,6 +684,21 @@ func (in *ESXIMigrationStatus) DeepCopy() *ESXIMigrationStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FirmwareInfo) DeepCopyInto(out *FirmwareInfo) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FirmwareInfo.
+func (in *FirmwareInfo) DeepCopy() *FirmwareInfo {
+	if in == nil {
+		return nil
+	}
+	out := new(FirmwareInfo)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GPUInfo) DeepCopyInto(out *GPUInfo) {
 	*out = *in
@@ -676,6 +734,68 @@ func (in *GuestNetwork) DeepCopy() *GuestNetwork {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GuestScript) DeepCopyInto(out *GuestScript) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GuestScript.
+func (in *GuestScript) DeepCopy() *GuestScript {
+	if in == nil {
+		return nil
+	}
+	out := new(GuestScript)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HealthCheck) DeepCopyInto(out *HealthCheck) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HealthCheck.
+func (in *HealthCheck) DeepCopy() *HealthCheck {
+	if in == nil {
+		return nil
+	}
+	out := new(HealthCheck)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HealthCheckResult) DeepCopyInto(out *HealthCheckResult) {
+	*out = *in
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HealthCheckResult.
+func (in *HealthCheckResult) DeepCopy() *HealthCheckResult {
+	if in == nil {
+		return nil
+	}
+	out := new(HealthCheckResult)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HookResult) DeepCopyInto(out *HookResult) {
+	*out = *in
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HookResult.
+func (in *HookResult) DeepCopy() *HookResult {
+	if in == nil {
+		return nil
+	}
+	out := new(HookResult)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *HostConfig) DeepCopyInto(out *HostConfig) {
 	*out = *in
@@ -698,12 +818,136 @@ func (in *HostConfig) DeepCopy() *HostConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IntegrityVerification) DeepCopyInto(out *IntegrityVerification) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IntegrityVerification.
+func (in *IntegrityVerification) DeepCopy() *IntegrityVerification {
+	if in == nil {
+		return nil
+	}
+	out := new(IntegrityVerification)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MaintenanceWindow) DeepCopyInto(out *MaintenanceWindow) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MaintenanceWindow.
+func (in *MaintenanceWindow) DeepCopy() *MaintenanceWindow {
+	if in == nil {
+		return nil
+	}
+	out := new(MaintenanceWindow)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *MaintenanceWindow) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MaintenanceWindowList) DeepCopyInto(out *MaintenanceWindowList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]MaintenanceWindow, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MaintenanceWindowList.
+func (in *MaintenanceWindowList) DeepCopy() *MaintenanceWindowList {
+	if in == nil {
+		return nil
+	}
+	out := new(MaintenanceWindowList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *MaintenanceWindowList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MaintenanceWindowSpec) DeepCopyInto(out *MaintenanceWindowSpec) {
+	*out = *in
+	if in.Windows != nil {
+		in, out := &in.Windows, &out.Windows
+		*out = make([]RecurringWindow, len(*in))
+		copy(*out, *in)
+	}
+	if in.Blackouts != nil {
+		in, out := &in.Blackouts, &out.Blackouts
+		*out = make([]BlackoutPeriod, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MaintenanceWindowSpec.
+func (in *MaintenanceWindowSpec) DeepCopy() *MaintenanceWindowSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(MaintenanceWindowSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MaintenanceWindowStatus) DeepCopyInto(out *MaintenanceWindowStatus) {
+	*out = *in
+	if in.ClosesAt != nil {
+		in, out := &in.ClosesAt, &out.ClosesAt
+		*out = (*in).DeepCopy()
+	}
+	if in.NextOpen != nil {
+		in, out := &in.NextOpen, &out.NextOpen
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MaintenanceWindowStatus.
+func (in *MaintenanceWindowStatus) DeepCopy() *MaintenanceWindowStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(MaintenanceWindowStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Migration) DeepCopyInto(out *Migration) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
+	in.Spec.DeepCopyInto(&out.Spec)
 	in.Status.DeepCopyInto(&out.Status)
 }
 
@@ -725,6 +969,43 @@ func (in *Migration) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MigrationHook) DeepCopyInto(out *MigrationHook) {
+	*out = *in
+	if in.Command != nil {
+		in, out := &in.Command, &out.Command
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Args != nil {
+		in, out := &in.Args, &out.Args
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Env != nil {
+		in, out := &in.Env, &out.Env
+		*out = make([]v1.EnvVar, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.SecretRefs != nil {
+		in, out := &in.SecretRefs, &out.SecretRefs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MigrationHook.
+func (in *MigrationHook) DeepCopy() *MigrationHook {
+	if in == nil {
+		return nil
+	}
+	out := new(MigrationHook)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MigrationList) DeepCopyInto(out *MigrationList) {
 	*out = *in
@@ -763,7 +1044,7 @@ func (in *MigrationPlan) DeepCopyInto(out *MigrationPlan) {
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MigrationPlan.
@@ -843,6 +1124,18 @@ func (in *MigrationPlanSpec) DeepCopyInto(out *MigrationPlanSpec) {
 			(*out)[key] = val
 		}
 	}
+	if in.Dependencies != nil {
+		in, out := &in.Dependencies, &out.Dependencies
+		*out = make([]VMDependency, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.VMSelector != nil {
+		in, out := &in.VMSelector, &out.VMSelector
+		*out = new(VMSelector)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MigrationPlanSpec.
@@ -865,6 +1158,13 @@ func (in *MigrationPlanSpecPerVM) DeepCopyInto(out *MigrationPlanSpecPerVM) {
 		*out = new(PostMigrationAction)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Hooks != nil {
+		in, out := &in.Hooks, &out.Hooks
+		*out = make([]MigrationHook, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MigrationPlanSpecPerVM.
@@ -880,6 +1180,25 @@ func (in *MigrationPlanSpecPerVM) DeepCopy() *MigrationPlanSpecPerVM {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MigrationPlanStatus) DeepCopyInto(out *MigrationPlanStatus) {
 	*out = *in
+	if in.CapacityForecast != nil {
+		in, out := &in.CapacityForecast, &out.CapacityForecast
+		*out = new(CapacityForecast)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SelectedVMs != nil {
+		in, out := &in.SelectedVMs, &out.SelectedVMs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.SelectionFrozenTime != nil {
+		in, out := &in.SelectionFrozenTime, &out.SelectionFrozenTime
+		*out = (*in).DeepCopy()
+	}
+	if in.ValidationWarnings != nil {
+		in, out := &in.ValidationWarnings, &out.ValidationWarnings
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MigrationPlanStatus.
@@ -898,6 +1217,16 @@ func (in *MigrationPlanStrategy) DeepCopyInto(out *MigrationPlanStrategy) {
 	in.DataCopyStart.DeepCopyInto(&out.DataCopyStart)
 	in.VMCutoverStart.DeepCopyInto(&out.VMCutoverStart)
 	in.VMCutoverEnd.DeepCopyInto(&out.VMCutoverEnd)
+	if in.HealthChecks != nil {
+		in, out := &in.HealthChecks, &out.HealthChecks
+		*out = make([]HealthCheck, len(*in))
+		copy(*out, *in)
+	}
+	if in.SnapshotConsistency != nil {
+		in, out := &in.SnapshotConsistency, &out.SnapshotConsistency
+		*out = new(SnapshotConsistency)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MigrationPlanStrategy.
@@ -910,9 +1239,144 @@ func (in *MigrationPlanStrategy) DeepCopy() *MigrationPlanStrategy {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MigrationPreflight) DeepCopyInto(out *MigrationPreflight) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MigrationPreflight.
+func (in *MigrationPreflight) DeepCopy() *MigrationPreflight {
+	if in == nil {
+		return nil
+	}
+	out := new(MigrationPreflight)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *MigrationPreflight) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MigrationPreflightList) DeepCopyInto(out *MigrationPreflightList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]MigrationPreflight, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MigrationPreflightList.
+func (in *MigrationPreflightList) DeepCopy() *MigrationPreflightList {
+	if in == nil {
+		return nil
+	}
+	out := new(MigrationPreflightList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *MigrationPreflightList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MigrationPreflightSpec) DeepCopyInto(out *MigrationPreflightSpec) {
+	*out = *in
+	if in.VirtualMachines != nil {
+		in, out := &in.VirtualMachines, &out.VirtualMachines
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MigrationPreflightSpec.
+func (in *MigrationPreflightSpec) DeepCopy() *MigrationPreflightSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(MigrationPreflightSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MigrationPreflightStatus) DeepCopyInto(out *MigrationPreflightStatus) {
+	*out = *in
+	if in.Checks != nil {
+		in, out := &in.Checks, &out.Checks
+		*out = make([]PreflightCheck, len(*in))
+		copy(*out, *in)
+	}
+	if in.VMs != nil {
+		in, out := &in.VMs, &out.VMs
+		*out = make([]VMPreflightResult, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MigrationPreflightStatus.
+func (in *MigrationPreflightStatus) DeepCopy() *MigrationPreflightStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(MigrationPreflightStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MigrationSource) DeepCopyInto(out *MigrationSource) {
+	*out = *in
+	if in.Datastores != nil {
+		in, out := &in.Datastores, &out.Datastores
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MigrationSource.
+func (in *MigrationSource) DeepCopy() *MigrationSource {
+	if in == nil {
+		return nil
+	}
+	out := new(MigrationSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MigrationSpec) DeepCopyInto(out *MigrationSpec) {
 	*out = *in
+	if in.TestBoot != nil {
+		in, out := &in.TestBoot, &out.TestBoot
+		*out = new(TestBootSpec)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MigrationSpec.
@@ -935,11 +1399,39 @@ func (in *MigrationStatus) DeepCopyInto(out *MigrationStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.ProgressTime != nil {
+		in, out := &in.ProgressTime, &out.ProgressTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Source != nil {
+		in, out := &in.Source, &out.Source
+		*out = new(MigrationSource)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Retryable != nil {
 		in, out := &in.Retryable, &out.Retryable
 		*out = new(bool)
 		**out = **in
 	}
+	if in.HealthChecks != nil {
+		in, out := &in.HealthChecks, &out.HealthChecks
+		*out = make([]HealthCheckResult, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.BlockedBy != nil {
+		in, out := &in.BlockedBy, &out.BlockedBy
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Hooks != nil {
+		in, out := &in.Hooks, &out.Hooks
+		*out = make([]HookResult, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MigrationStatus.
@@ -957,7 +1449,7 @@ func (in *MigrationTemplate) DeepCopyInto(out *MigrationTemplate) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
+	in.Spec.DeepCopyInto(&out.Spec)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MigrationTemplate.
@@ -981,6 +1473,11 @@ func (in *MigrationTemplate) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MigrationTemplateDestination) DeepCopyInto(out *MigrationTemplateDestination) {
 	*out = *in
+	if in.File != nil {
+		in, out := &in.File, &out.File
+		*out = new(MigrationTemplateFileDestination)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MigrationTemplateDestination.
@@ -993,6 +1490,36 @@ func (in *MigrationTemplateDestination) DeepCopy() *MigrationTemplateDestination
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MigrationTemplateFileDestination) DeepCopyInto(out *MigrationTemplateFileDestination) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MigrationTemplateFileDestination.
+func (in *MigrationTemplateFileDestination) DeepCopy() *MigrationTemplateFileDestination {
+	if in == nil {
+		return nil
+	}
+	out := new(MigrationTemplateFileDestination)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MigrationTemplateFileSource) DeepCopyInto(out *MigrationTemplateFileSource) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MigrationTemplateFileSource.
+func (in *MigrationTemplateFileSource) DeepCopy() *MigrationTemplateFileSource {
+	if in == nil {
+		return nil
+	}
+	out := new(MigrationTemplateFileSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MigrationTemplateList) DeepCopyInto(out *MigrationTemplateList) {
 	*out = *in
@@ -1028,6 +1555,11 @@ func (in *MigrationTemplateList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MigrationTemplateSource) DeepCopyInto(out *MigrationTemplateSource) {
 	*out = *in
+	if in.File != nil {
+		in, out := &in.File, &out.File
+		*out = new(MigrationTemplateFileSource)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MigrationTemplateSource.
@@ -1043,8 +1575,8 @@ func (in *MigrationTemplateSource) DeepCopy() *MigrationTemplateSource {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MigrationTemplateSpec) DeepCopyInto(out *MigrationTemplateSpec) {
 	*out = *in
-	out.Source = in.Source
-	out.Destination = in.Destination
+	in.Source.DeepCopyInto(&out.Source)
+	in.Destination.DeepCopyInto(&out.Destination)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MigrationTemplateSpec.
@@ -1057,6 +1589,112 @@ func (in *MigrationTemplateSpec) DeepCopy() *MigrationTemplateSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MigrationWave) DeepCopyInto(out *MigrationWave) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MigrationWave.
+func (in *MigrationWave) DeepCopy() *MigrationWave {
+	if in == nil {
+		return nil
+	}
+	out := new(MigrationWave)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *MigrationWave) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MigrationWaveList) DeepCopyInto(out *MigrationWaveList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]MigrationWave, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MigrationWaveList.
+func (in *MigrationWaveList) DeepCopy() *MigrationWaveList {
+	if in == nil {
+		return nil
+	}
+	out := new(MigrationWaveList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *MigrationWaveList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MigrationWaveSpec) DeepCopyInto(out *MigrationWaveSpec) {
+	*out = *in
+	if in.Plans != nil {
+		in, out := &in.Plans, &out.Plans
+		*out = make([]WavePlan, len(*in))
+		copy(*out, *in)
+	}
+	if in.Approvals != nil {
+		in, out := &in.Approvals, &out.Approvals
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MigrationWaveSpec.
+func (in *MigrationWaveSpec) DeepCopy() *MigrationWaveSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(MigrationWaveSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MigrationWaveStatus) DeepCopyInto(out *MigrationWaveStatus) {
+	*out = *in
+	if in.Plans != nil {
+		in, out := &in.Plans, &out.Plans
+		*out = make([]WavePlanStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MigrationWaveStatus.
+func (in *MigrationWaveStatus) DeepCopy() *MigrationWaveStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(MigrationWaveStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *NIC) DeepCopyInto(out *NIC) {
 	*out = *in
@@ -1181,6 +1819,22 @@ func (in *NetworkMappingStatus) DeepCopy() *NetworkMappingStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeScaleEvent) DeepCopyInto(out *NodeScaleEvent) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeScaleEvent.
+func (in *NodeScaleEvent) DeepCopy() *NodeScaleEvent {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeScaleEvent)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *OpenStackCredsInfo) DeepCopyInto(out *OpenStackCredsInfo) {
 	*out = *in
@@ -1610,12 +2264,27 @@ func (in *PostMigrationAction) DeepCopyInto(out *PostMigrationAction) {
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostMigrationAction.
-func (in *PostMigrationAction) DeepCopy() *PostMigrationAction {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostMigrationAction.
+func (in *PostMigrationAction) DeepCopy() *PostMigrationAction {
+	if in == nil {
+		return nil
+	}
+	out := new(PostMigrationAction)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PreflightCheck) DeepCopyInto(out *PreflightCheck) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PreflightCheck.
+func (in *PreflightCheck) DeepCopy() *PreflightCheck {
 	if in == nil {
 		return nil
 	}
-	out := new(PostMigrationAction)
+	out := new(PreflightCheck)
 	in.DeepCopyInto(out)
 	return out
 }
@@ -1722,6 +2391,37 @@ func (in *RDMDiskStatus) DeepCopy() *RDMDiskStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RecurringWindow) DeepCopyInto(out *RecurringWindow) {
+	*out = *in
+	out.Duration = in.Duration
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RecurringWindow.
+func (in *RecurringWindow) DeepCopy() *RecurringWindow {
+	if in == nil {
+		return nil
+	}
+	out := new(RecurringWindow)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResourceForecast) DeepCopyInto(out *ResourceForecast) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceForecast.
+func (in *ResourceForecast) DeepCopy() *ResourceForecast {
+	if in == nil {
+		return nil
+	}
+	out := new(ResourceForecast)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RollingMigrationPlan) DeepCopyInto(out *RollingMigrationPlan) {
 	*out = *in
@@ -1853,6 +2553,11 @@ func (in *RollingMigrationPlanStatus) DeepCopyInto(out *RollingMigrationPlanStat
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.CapacityForecast != nil {
+		in, out := &in.CapacityForecast, &out.CapacityForecast
+		*out = new(CapacityForecast)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RollingMigrationPlanStatus.
@@ -1895,6 +2600,36 @@ func (in *ServerGroupInfo) DeepCopy() *ServerGroupInfo {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SnapshotConsistency) DeepCopyInto(out *SnapshotConsistency) {
+	*out = *in
+	if in.VMs != nil {
+		in, out := &in.VMs, &out.VMs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.PreFreezeScript != nil {
+		in, out := &in.PreFreezeScript, &out.PreFreezeScript
+		*out = new(GuestScript)
+		**out = **in
+	}
+	if in.PostThawScript != nil {
+		in, out := &in.PostThawScript, &out.PostThawScript
+		*out = new(GuestScript)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SnapshotConsistency.
+func (in *SnapshotConsistency) DeepCopy() *SnapshotConsistency {
+	if in == nil {
+		return nil
+	}
+	out := new(SnapshotConsistency)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Storage) DeepCopyInto(out *Storage) {
 	*out = *in
@@ -2004,6 +2739,41 @@ func (in *StorageMappingStatus) DeepCopy() *StorageMappingStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TestBootSpec) DeepCopyInto(out *TestBootSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TestBootSpec.
+func (in *TestBootSpec) DeepCopy() *TestBootSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(TestBootSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VMDependency) DeepCopyInto(out *VMDependency) {
+	*out = *in
+	if in.After != nil {
+		in, out := &in.After, &out.After
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VMDependency.
+func (in *VMDependency) DeepCopy() *VMDependency {
+	if in == nil {
+		return nil
+	}
+	out := new(VMDependency)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VMInfo) DeepCopyInto(out *VMInfo) {
 	*out = *in
@@ -2022,6 +2792,11 @@ func (in *VMInfo) DeepCopyInto(out *VMInfo) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.Tags != nil {
+		in, out := &in.Tags, &out.Tags
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	if in.RDMDisks != nil {
 		in, out := &in.RDMDisks, &out.RDMDisks
 		*out = make([]string, len(*in))
@@ -2040,6 +2815,7 @@ func (in *VMInfo) DeepCopyInto(out *VMInfo) {
 		}
 	}
 	out.GPU = in.GPU
+	out.Firmware = in.Firmware
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VMInfo.
@@ -2052,6 +2828,106 @@ func (in *VMInfo) DeepCopy() *VMInfo {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VMPreflightResult) DeepCopyInto(out *VMPreflightResult) {
+	*out = *in
+	if in.Checks != nil {
+		in, out := &in.Checks, &out.Checks
+		*out = make([]PreflightCheck, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VMPreflightResult.
+func (in *VMPreflightResult) DeepCopy() *VMPreflightResult {
+	if in == nil {
+		return nil
+	}
+	out := new(VMPreflightResult)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VMSelector) DeepCopyInto(out *VMSelector) {
+	*out = *in
+	if in.Tags != nil {
+		in, out := &in.Tags, &out.Tags
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Folders != nil {
+		in, out := &in.Folders, &out.Folders
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ResourcePools != nil {
+		in, out := &in.ResourcePools, &out.ResourcePools
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Clusters != nil {
+		in, out := &in.Clusters, &out.Clusters
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Exclude != nil {
+		in, out := &in.Exclude, &out.Exclude
+		*out = new(VMSelectorExclusions)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VMSelector.
+func (in *VMSelector) DeepCopy() *VMSelector {
+	if in == nil {
+		return nil
+	}
+	out := new(VMSelector)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VMSelectorExclusions) DeepCopyInto(out *VMSelectorExclusions) {
+	*out = *in
+	if in.Tags != nil {
+		in, out := &in.Tags, &out.Tags
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Folders != nil {
+		in, out := &in.Folders, &out.Folders
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ResourcePools != nil {
+		in, out := &in.ResourcePools, &out.ResourcePools
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Clusters != nil {
+		in, out := &in.Clusters, &out.Clusters
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Names != nil {
+		in, out := &in.Names, &out.Names
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VMSelectorExclusions.
+func (in *VMSelectorExclusions) DeepCopy() *VMSelectorExclusions {
+	if in == nil {
+		return nil
+	}
+	out := new(VMSelectorExclusions)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VMSequenceInfo) DeepCopyInto(out *VMSequenceInfo) {
 	*out = *in
@@ -2167,7 +3043,7 @@ func (in *VMwareCreds) DeepCopyInto(out *VMwareCreds) {
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	out.Spec = in.Spec
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VMwareCreds.
@@ -2254,6 +3130,10 @@ func (in *VMwareCredsSpec) DeepCopy() *VMwareCredsSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VMwareCredsStatus) DeepCopyInto(out *VMwareCredsStatus) {
 	*out = *in
+	if in.InventorySyncedTime != nil {
+		in, out := &in.InventorySyncedTime, &out.InventorySyncedTime
+		*out = (*in).DeepCopy()
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VMwareCredsStatus.
@@ -2472,6 +3352,116 @@ func (in *VjailbreakNode) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VjailbreakNodeAutoscaler) DeepCopyInto(out *VjailbreakNodeAutoscaler) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VjailbreakNodeAutoscaler.
+func (in *VjailbreakNodeAutoscaler) DeepCopy() *VjailbreakNodeAutoscaler {
+	if in == nil {
+		return nil
+	}
+	out := new(VjailbreakNodeAutoscaler)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *VjailbreakNodeAutoscaler) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VjailbreakNodeAutoscalerList) DeepCopyInto(out *VjailbreakNodeAutoscalerList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]VjailbreakNodeAutoscaler, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VjailbreakNodeAutoscalerList.
+func (in *VjailbreakNodeAutoscalerList) DeepCopy() *VjailbreakNodeAutoscalerList {
+	if in == nil {
+		return nil
+	}
+	out := new(VjailbreakNodeAutoscalerList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *VjailbreakNodeAutoscalerList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VjailbreakNodeAutoscalerSpec) DeepCopyInto(out *VjailbreakNodeAutoscalerSpec) {
+	*out = *in
+	if in.OpenstackSecurityGroups != nil {
+		in, out := &in.OpenstackSecurityGroups, &out.OpenstackSecurityGroups
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VjailbreakNodeAutoscalerSpec.
+func (in *VjailbreakNodeAutoscalerSpec) DeepCopy() *VjailbreakNodeAutoscalerSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(VjailbreakNodeAutoscalerSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VjailbreakNodeAutoscalerStatus) DeepCopyInto(out *VjailbreakNodeAutoscalerStatus) {
+	*out = *in
+	if in.Nodes != nil {
+		in, out := &in.Nodes, &out.Nodes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.LastScaleTime != nil {
+		in, out := &in.LastScaleTime, &out.LastScaleTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Events != nil {
+		in, out := &in.Events, &out.Events
+		*out = make([]NodeScaleEvent, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VjailbreakNodeAutoscalerStatus.
+func (in *VjailbreakNodeAutoscalerStatus) DeepCopy() *VjailbreakNodeAutoscalerStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(VjailbreakNodeAutoscalerStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VjailbreakNodeList) DeepCopyInto(out *VjailbreakNodeList) {
 	*out = *in
@@ -2533,6 +3523,10 @@ func (in *VjailbreakNodeStatus) DeepCopyInto(out *VjailbreakNodeStatus) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.IdleSince != nil {
+		in, out := &in.IdleSince, &out.IdleSince
+		*out = (*in).DeepCopy()
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VjailbreakNodeStatus.
@@ -2544,3 +3538,37 @@ func (in *VjailbreakNodeStatus) DeepCopy() *VjailbreakNodeStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WavePlan) DeepCopyInto(out *WavePlan) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WavePlan.
+func (in *WavePlan) DeepCopy() *WavePlan {
+	if in == nil {
+		return nil
+	}
+	out := new(WavePlan)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WavePlanStatus) DeepCopyInto(out *WavePlanStatus) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WavePlanStatus.
+func (in *WavePlanStatus) DeepCopy() *WavePlanStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(WavePlanStatus)
+	in.DeepCopyInto(out)
+	return out
+}
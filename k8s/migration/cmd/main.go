@@ -158,6 +158,27 @@ func main() {
 		setupLog.Error(err, "unable to create controller", "controller", "RDMDisk")
 		os.Exit(1)
 	}
+	if err = (&controller.MigrationPreflightReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "MigrationPreflight")
+		os.Exit(1)
+	}
+	if err = (&controller.MaintenanceWindowReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "MaintenanceWindow")
+		os.Exit(1)
+	}
+	if err = (&controller.MigrationWaveReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "MigrationWave")
+		os.Exit(1)
+	}
 	// +kubebuilder:scaffold:builder
 
 	if err = mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
@@ -225,6 +246,8 @@ func GetManager(metricsAddr string,
 
 // SetupControllers initializes and sets up all controllers with the manager
 func SetupControllers(mgr ctrl.Manager, local bool, maxConcurrentReconciles int) error {
+	// The migration plans place migrations on agents, the autoscaler sizes the agents to their queue
+	scheduler := utils.NewMigrationScheduler()
 	if err := (&controller.MigrationReconciler{
 		Client:                  mgr.GetClient(),
 		Scheme:                  mgr.GetScheme(),
@@ -279,14 +302,16 @@ func SetupControllers(mgr ctrl.Manager, local bool, maxConcurrentReconciles int)
 		Client:                  mgr.GetClient(),
 		Scheme:                  mgr.GetScheme(),
 		MaxConcurrentReconciles: maxConcurrentReconciles,
+		Scheduler:               scheduler,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "MigrationPlan")
 		return err
 	}
 	if err := (&controller.VjailbreakNodeReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
-		Local:  local,
+		Client:    mgr.GetClient(),
+		Scheme:    mgr.GetScheme(),
+		Local:     local,
+		Scheduler: scheduler,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "VjailbreakNode")
 		return err
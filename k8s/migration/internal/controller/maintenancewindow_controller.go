@@ -0,0 +1,103 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+	"github.com/platform9/vjailbreak/k8s/migration/pkg/constants"
+	"github.com/platform9/vjailbreak/v2v-helper/pkg/k8sutils"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// MaintenanceWindowReconciler reconciles a MaintenanceWindow object
+type MaintenanceWindowReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+// +kubebuilder:rbac:groups=vjailbreak.k8s.pf9.io,resources=maintenancewindows,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=vjailbreak.k8s.pf9.io,resources=maintenancewindows/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=vjailbreak.k8s.pf9.io,resources=maintenancewindows/finalizers,verbs=update
+
+// Reconcile validates the windows and blackouts of a MaintenanceWindow and reports whether it is
+// open. It requeues when the window opens or closes next. Migrations read the window themselves,
+// the status is informational.
+func (r *MaintenanceWindowReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	ctxlog := log.FromContext(ctx).WithName(constants.MaintenanceWindowControllerName)
+
+	window := &vjailbreakv1alpha1.MaintenanceWindow{}
+	if err := r.Get(ctx, req.NamespacedName, window); err != nil {
+		if apierrors.IsNotFound(err) {
+			ctxlog.Info("Received ignorable event for a recently deleted MaintenanceWindow.")
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, errors.Wrapf(err, "failed to read MaintenanceWindow '%s'", req.Name)
+	}
+
+	status := vjailbreakv1alpha1.MaintenanceWindowStatus{}
+	var requeueAfter time.Duration
+	calendar, err := k8sutils.NewMaintenanceCalendar(window.Spec)
+	if err != nil {
+		status.ValidationStatus = constants.ValidationStatusFailed
+		status.Message = err.Error()
+	} else {
+		status.ValidationStatus = constants.ValidationStatusSucceeded
+		now := time.Now()
+		if open, closes := calendar.OpenAt(now); open {
+			status.Open = true
+			status.ClosesAt = &metav1.Time{Time: closes}
+			status.Message = fmt.Sprintf("Open until %s", closes.Format(time.RFC3339))
+			requeueAfter = time.Until(closes)
+		} else if next, ok := calendar.NextOpen(now); ok {
+			status.NextOpen = &metav1.Time{Time: next}
+			status.Message = fmt.Sprintf("Closed until %s", next.Format(time.RFC3339))
+			requeueAfter = time.Until(next)
+		} else {
+			status.Message = "Closed, the window does not open again"
+		}
+	}
+
+	if !equality.Semantic.DeepEqual(window.Status, status) {
+		window.Status = status
+		if err := r.Status().Update(ctx, window); err != nil {
+			return ctrl.Result{}, errors.Wrap(err, "failed to update MaintenanceWindow status")
+		}
+	}
+	if requeueAfter <= 0 {
+		return ctrl.Result{}, nil
+	}
+	// Requeue a little after the transition so that the window has changed state
+	return ctrl.Result{RequeueAfter: requeueAfter + time.Second}, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *MaintenanceWindowReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&vjailbreakv1alpha1.MaintenanceWindow{}).
+		Complete(r)
+}
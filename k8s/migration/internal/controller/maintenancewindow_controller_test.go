@@ -0,0 +1,98 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2" //nolint:revive // dot imports are common in Ginkgo tests
+	. "github.com/onsi/gomega"    //nolint:revive // dot imports are common in Gomega assertions
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+	"github.com/platform9/vjailbreak/k8s/migration/pkg/constants"
+)
+
+var _ = Describe("MaintenanceWindow Controller", func() {
+	Context("When reconciling a resource", func() {
+		const resourceName = "test-resource"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+		maintenancewindow := &vjailbreakv1alpha1.MaintenanceWindow{}
+
+		BeforeEach(func() {
+			By("creating the custom resource for the Kind MaintenanceWindow")
+			err := k8sClient.Get(ctx, typeNamespacedName, maintenancewindow)
+			if err != nil && errors.IsNotFound(err) {
+				resource := &vjailbreakv1alpha1.MaintenanceWindow{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      resourceName,
+						Namespace: "default",
+					},
+					Spec: vjailbreakv1alpha1.MaintenanceWindowSpec{
+						Windows: []vjailbreakv1alpha1.RecurringWindow{{
+							Schedule: "0 22 * * *",
+							Duration: metav1.Duration{Duration: 4 * time.Hour},
+						}},
+					},
+				}
+				Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+			}
+		})
+
+		AfterEach(func() {
+			resource := &vjailbreakv1alpha1.MaintenanceWindow{}
+			err := k8sClient.Get(ctx, typeNamespacedName, resource)
+			Expect(err).NotTo(HaveOccurred())
+
+			By("Cleanup the specific resource instance MaintenanceWindow")
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+		})
+		It("should report when the window opens or closes", func() {
+			By("Reconciling the created resource")
+			controllerReconciler := &MaintenanceWindowReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+
+			result, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(BeNumerically(">", 0))
+
+			resource := &vjailbreakv1alpha1.MaintenanceWindow{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(resource.Status.ValidationStatus).To(Equal(constants.ValidationStatusSucceeded))
+			if resource.Status.Open {
+				Expect(resource.Status.ClosesAt).NotTo(BeNil())
+			} else {
+				Expect(resource.Status.NextOpen).NotTo(BeNil())
+			}
+		})
+	})
+})
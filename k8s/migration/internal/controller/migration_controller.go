@@ -32,6 +32,7 @@ import (
 
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -113,8 +114,9 @@ func (r *MigrationReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		return ctrl.Result{}, nil
 	}
 
-	// If migration is already marked as Failed and no pod was created, don't keep requeuing
-	if migration.Status.Phase == vjailbreakv1alpha1.VMMigrationPhaseFailed {
+	// If migration is already marked as Failed and no pod was created, don't keep requeuing.
+	// A failed migration with a rollback request is still followed until it is rolled back.
+	if migration.Status.Phase == vjailbreakv1alpha1.VMMigrationPhaseFailed && !migration.Spec.Rollback {
 		ctxlog.Info(
 			"Migration is Failed; skipping reconciliation and requeue",
 			"migration", migration.Name,
@@ -122,6 +124,14 @@ func (r *MigrationReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		return ctrl.Result{}, nil
 	}
 
+	if migration.Status.Phase == vjailbreakv1alpha1.VMMigrationPhaseRolledBack {
+		ctxlog.Info(
+			"Migration is RolledBack; skipping reconciliation and requeue",
+			"migration", migration.Name,
+		)
+		return ctrl.Result{}, nil
+	}
+
 	oldStatus := migration.Status.DeepCopy()
 
 	migrationScope, err := scope.NewMigrationScope(scope.MigrationScopeParams{
@@ -168,12 +178,27 @@ func (r *MigrationReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		}
 	}
 
-	pod.Labels["startCutover"] = utils.SetCutoverLabel(migration.Spec.InitiateCutover, pod.Labels["startCutover"])
+	if len(migration.Status.BlockedBy) > 0 {
+		// The cutover waits for the VMs it depends on, a cutover started by an admin is taken back
+		pod.Labels["startCutover"] = constants.StartCutOverNo
+	} else {
+		pod.Labels["startCutover"] = utils.SetCutoverLabel(migration.Spec.InitiateCutover, pod.Labels["startCutover"])
+	}
 	if err = r.Update(ctx, pod); err != nil {
 		ctxlog.Error(err, fmt.Sprintf("Failed to update Pod '%s'", pod.Name))
 		return ctrl.Result{}, err
 	}
 
+	// The pod is pinned to its agent, once the agent is lost the migration gives the slot back and its
+	// plan places it on another agent
+	released, err := r.releaseLostAgent(ctx, migration, pod)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if released {
+		return ctrl.Result{RequeueAfter: 30 * time.Second}, nil
+	}
+
 	if constants.VMMigrationStatesEnum[migration.Status.Phase] <= constants.VMMigrationStatesEnum[vjailbreakv1alpha1.VMMigrationPhaseValidating] {
 		migration.Status.Phase = vjailbreakv1alpha1.VMMigrationPhaseValidating
 	}
@@ -191,11 +216,20 @@ func (r *MigrationReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 	migration.Status.Conditions = utils.CreateValidatedCondition(migration, filteredEvents)
 	migration.Status.Conditions = utils.CreateStorageAcceleratedCopyCondition(migration, filteredEvents)
 	migration.Status.Conditions = utils.CreateDataCopyCondition(migration, filteredEvents)
+	migration.Status.Conditions = utils.CreateDataVerifiedCondition(migration, filteredEvents)
+	migration.Status.Conditions = utils.CreateTestBootCondition(migration, filteredEvents)
+	migration.Status.HealthChecks = utils.CreateHealthCheckResults(migration, filteredEvents)
+	migration.Status.Hooks = utils.CreateHookResults(migration, filteredEvents)
+	migration.Status.SnapshotType = utils.GetSnapshotType(migration, filteredEvents)
 	migration.Status.Conditions = utils.CreateMigratingCondition(migration, filteredEvents)
 	migration.Status.Conditions = utils.CreateFailedCondition(migration, filteredEvents)
 	migration.Status.Conditions = utils.CreateSucceededCondition(migration, filteredEvents)
 
-	migration.Status.AgentName = pod.Spec.NodeName
+	// The migration holds the slot of the agent it was placed on until its pod is scheduled. The pod
+	// of a job deleted after its agent was lost keeps the lost node until it is gone.
+	if pod.Spec.NodeName != "" && pod.DeletionTimestamp == nil {
+		migration.Status.AgentName = pod.Spec.NodeName
+	}
 
 	// Extract current disk being copied from events
 	r.ExtractCurrentDisk(migration, filteredEvents)
@@ -217,6 +251,10 @@ func (r *MigrationReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		return ctrl.Result{}, errors.Wrap(err, "error setting migration phase")
 	}
 
+	if err := r.ExtractCopyProgress(ctx, migration, filteredEvents); err != nil {
+		ctxlog.Error(err, "Failed to estimate copy progress of migration")
+	}
+
 	// Record migration start if this is a new migration (hasn't been started in metrics yet)
 	// Check if migration was just created (within last minute) and oldStatus phase is empty or Pending
 	isNewMigration := time.Since(migration.CreationTimestamp.Time) < time.Minute &&
@@ -240,6 +278,7 @@ func (r *MigrationReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 	// Update duration for active migrations
 	if migration.Status.Phase != vjailbreakv1alpha1.VMMigrationPhaseSucceeded &&
 		migration.Status.Phase != vjailbreakv1alpha1.VMMigrationPhaseFailed &&
+		migration.Status.Phase != vjailbreakv1alpha1.VMMigrationPhaseRolledBack &&
 		migration.Status.Phase != vjailbreakv1alpha1.VMMigrationPhaseValidationFailed &&
 		migration.Status.Phase != "" {
 		metrics.RecordMigrationProgress(migration.Name, migration.Spec.VMName, migration.Namespace, migration.CreationTimestamp.Time)
@@ -247,9 +286,11 @@ func (r *MigrationReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 
 	// Record completion when transitioning to a terminal state from a non-terminal state
 	isNowTerminal := migration.Status.Phase == vjailbreakv1alpha1.VMMigrationPhaseSucceeded ||
-		migration.Status.Phase == vjailbreakv1alpha1.VMMigrationPhaseFailed
+		migration.Status.Phase == vjailbreakv1alpha1.VMMigrationPhaseFailed ||
+		migration.Status.Phase == vjailbreakv1alpha1.VMMigrationPhaseRolledBack
 	wasTerminal := oldStatus.Phase == vjailbreakv1alpha1.VMMigrationPhaseSucceeded ||
-		oldStatus.Phase == vjailbreakv1alpha1.VMMigrationPhaseFailed
+		oldStatus.Phase == vjailbreakv1alpha1.VMMigrationPhaseFailed ||
+		oldStatus.Phase == vjailbreakv1alpha1.VMMigrationPhaseRolledBack
 
 	if isNowTerminal && !wasTerminal {
 		metrics.RecordMigrationCompleted(migration.Name, migration.Spec.VMName, migration.Namespace, migration.Status.AgentName, migration.Status.Phase)
@@ -264,7 +305,12 @@ func (r *MigrationReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 
 	if string(migration.Status.Phase) != string(vjailbreakv1alpha1.VMMigrationPhaseFailed) &&
 		string(migration.Status.Phase) != string(vjailbreakv1alpha1.VMMigrationPhaseValidationFailed) &&
-		string(migration.Status.Phase) != string(vjailbreakv1alpha1.VMMigrationPhaseSucceeded) {
+		string(migration.Status.Phase) != string(vjailbreakv1alpha1.VMMigrationPhaseSucceeded) &&
+		string(migration.Status.Phase) != string(vjailbreakv1alpha1.VMMigrationPhaseRolledBack) {
+		return ctrl.Result{RequeueAfter: 30 * time.Second}, nil
+	}
+	// Follow a requested rollback until the migration pod reports it
+	if migration.Status.Phase == vjailbreakv1alpha1.VMMigrationPhaseFailed && migration.Spec.Rollback {
 		return ctrl.Result{RequeueAfter: 30 * time.Second}, nil
 	}
 
@@ -339,7 +385,9 @@ func (r *MigrationReconciler) SetupWithManager(mgr ctrl.Manager) error {
 							return true
 						}
 					}
-					return oldpod.Status.Phase != newpod.Status.Phase
+					// A cutover label set by an admin is checked against the dependencies of the migration
+					return oldpod.Status.Phase != newpod.Status.Phase ||
+						oldpod.Labels["startCutover"] != newpod.Labels["startCutover"]
 				},
 			},
 		)).
@@ -371,6 +419,23 @@ loop:
 	for i := range events.Items {
 		switch {
 		// In reverse order, because the events are sorted by timestamp latest to oldest
+		case strings.HasPrefix(events.Items[i].Message, openstackconst.EventMessageTestBoot),
+			strings.HasPrefix(events.Items[i].Message, openstackconst.EventMessageHealthCheck),
+			strings.HasPrefix(events.Items[i].Message, openstackconst.EventMessageHook),
+			strings.HasPrefix(events.Items[i].Message, openstackconst.EventMessageFinalSnapshot):
+			// Test boots, health check and hook results and the final snapshot do not change the
+			// phase, the outcome of the migration is reported separately
+			continue
+		case strings.Contains(events.Items[i].Message, openstackconst.EventMessageRolledBack):
+			// A rollback follows a failed cutover, it is newer than the failure or the rollback request
+			// and the v2v-helper reports no success after it
+			if err := r.markMigrationRolledBack(ctx, scope); err != nil {
+				return err
+			}
+			break loop
+		case strings.Contains(events.Items[i].Message, openstackconst.EventMessageWaitingForRollback):
+			scope.Migration.Status.Phase = vjailbreakv1alpha1.VMMigrationPhaseFailed
+			break loop
 		case strings.Contains(events.Items[i].Message, openstackconst.EventMessageMigrationSucessful) &&
 			constants.VMMigrationStatesEnum[scope.Migration.Status.Phase] <= constants.VMMigrationStatesEnum[vjailbreakv1alpha1.VMMigrationPhaseSucceeded]:
 			scope.Migration.Status.Phase = vjailbreakv1alpha1.VMMigrationPhaseSucceeded
@@ -395,6 +460,10 @@ loop:
 			constants.VMMigrationStatesEnum[scope.Migration.Status.Phase] <= constants.VMMigrationStatesEnum[vjailbreakv1alpha1.VMMigrationPhaseAwaitingCutOverStartTime]:
 			scope.Migration.Status.Phase = vjailbreakv1alpha1.VMMigrationPhaseAwaitingCutOverStartTime
 			break loop
+		case strings.Contains(events.Items[i].Message, openstackconst.EventMessageVerifyingData) &&
+			constants.VMMigrationStatesEnum[scope.Migration.Status.Phase] <= constants.VMMigrationStatesEnum[vjailbreakv1alpha1.VMMigrationPhaseVerifyingData]:
+			scope.Migration.Status.Phase = vjailbreakv1alpha1.VMMigrationPhaseVerifyingData
+			break loop
 		case strings.Contains(events.Items[i].Message, openstackconst.EventMessageConvertingDisk) &&
 			constants.VMMigrationStatesEnum[scope.Migration.Status.Phase] <= constants.VMMigrationStatesEnum[vjailbreakv1alpha1.VMMigrationPhaseConvertingDisk]:
 			scope.Migration.Status.Phase = vjailbreakv1alpha1.VMMigrationPhaseConvertingDisk
@@ -451,11 +520,25 @@ loop:
 // Extracted function to handle successful migration updates
 func (r *MigrationReconciler) markMigrationSuccessful(ctx context.Context, scope *scope.MigrationScope) error {
 	scope.Migration.Status.Phase = vjailbreakv1alpha1.VMMigrationPhaseSucceeded
-	vmwareCredsName, err := utils.GetVMwareCredsNameFromMigration(ctx, r.Client, scope.Migration)
+	return r.setVMwareMachineMigrated(ctx, scope, true)
+}
+
+// markMigrationRolledBack records that the source VM was restored after a failed cutover
+func (r *MigrationReconciler) markMigrationRolledBack(ctx context.Context, scope *scope.MigrationScope) error {
+	scope.Migration.Status.Phase = vjailbreakv1alpha1.VMMigrationPhaseRolledBack
+	return r.setVMwareMachineMigrated(ctx, scope, false)
+}
+
+func (r *MigrationReconciler) setVMwareMachineMigrated(ctx context.Context, scope *scope.MigrationScope, migrated bool) error {
+	migrationTemplate, err := utils.GetMigrationTemplateFromMigration(ctx, r.Client, scope.Migration)
 	if err != nil {
-		return errors.Wrap(err, "failed to get vmware credentials name")
+		return errors.Wrap(err, "failed to get migration template")
 	}
-	name, err := utils.GetK8sCompatibleVMWareObjectName(scope.Migration.Spec.VMName, vmwareCredsName)
+	// VMs imported from files have no VMwareMachine
+	if migrationTemplate.Spec.Source.File != nil {
+		return nil
+	}
+	name, err := utils.GetK8sCompatibleVMWareObjectName(scope.Migration.Spec.VMName, utils.GetSourceName(migrationTemplate))
 	if err != nil {
 		return errors.Wrap(err, "failed to get vmware machine name")
 	}
@@ -465,7 +548,10 @@ func (r *MigrationReconciler) markMigrationSuccessful(ctx context.Context, scope
 		return errors.Wrap(err, "failed to get vmware machine")
 	}
 
-	vmwvm.Status.Migrated = true
+	if vmwvm.Status.Migrated == migrated {
+		return nil
+	}
+	vmwvm.Status.Migrated = migrated
 	return r.Status().Update(ctx, vmwvm)
 }
 
@@ -519,42 +605,171 @@ func (r *MigrationReconciler) GetPod(ctx context.Context, scope *scope.Migration
 	if len(podList.Items) == 0 {
 		return nil, apierrors.NewNotFound(corev1.Resource("pods"), fmt.Sprintf("migration pod not found for vm %s", migration.Spec.VMName))
 	}
-	return &podList.Items[0], nil
+	// A job can replace a disrupted pod, the newest pod is the one carrying on the migration
+	latest := &podList.Items[0]
+	for i := range podList.Items {
+		if podList.Items[i].CreationTimestamp.After(latest.CreationTimestamp.Time) {
+			latest = &podList.Items[i]
+		}
+	}
+	return latest, nil
 }
 
-// ExtractCurrentDisk extracts which disk is currently being copied from pod events
-func (r *MigrationReconciler) ExtractCurrentDisk(migration *vjailbreakv1alpha1.Migration, events *corev1.EventList) {
-	// Events are sorted by timestamp (newest first)
-	parseCurrentDisk := func(msg string) (string, bool) {
-		if !strings.Contains(msg, "Copying disk") {
-			return "", false
+// releaseLostAgent clears the agent of a migration whose unfinished pod is on, or pinned to, an agent
+// node that is lost. It returns true while the pod is on a lost agent.
+func (r *MigrationReconciler) releaseLostAgent(ctx context.Context, migration *vjailbreakv1alpha1.Migration, pod *corev1.Pod) (bool, error) {
+	if pod.DeletionTimestamp != nil || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+		return false, nil
+	}
+	agent := pod.Spec.NodeName
+	if agent == "" {
+		agent = migration.Status.AgentName
+	}
+	if agent == "" {
+		return false, nil
+	}
+	lost, err := utils.IsAgentLost(ctx, r.Client, agent, time.Now())
+	if err != nil || !lost {
+		return false, err
+	}
+	if migration.Status.AgentName != "" {
+		log.FromContext(ctx).Info("Agent of migration is lost, releasing its slot", "migration", migration.Name, "agent", agent)
+		migration.Status.AgentName = ""
+		if err := r.Status().Update(ctx, migration); err != nil {
+			return false, errors.Wrap(err, "failed to release lost agent of migration")
+		}
+	}
+	return true, nil
+}
+
+// parseDiskProgress parses a "Copying disk <n>, Completed: <p>%" message of the migration pod into
+// the disk number and its progress in percent
+func parseDiskProgress(msg string) (string, int, bool) {
+	if !strings.Contains(msg, "Copying disk") {
+		return "", 0, false
+	}
+	parts := strings.Split(msg, "Copying disk")
+	if len(parts) <= 1 {
+		return "", 0, false
+	}
+	diskPart := strings.TrimSpace(parts[1])
+	if len(diskPart) == 0 {
+		return "", 0, false
+	}
+	diskNum := strings.Split(diskPart, ",")[0]
+	diskNum = strings.Split(diskNum, " ")[0]
+	diskNum = strings.TrimSpace(diskNum)
+	if diskNum == "" {
+		return "", 0, false
+	}
+	progress := 0
+	if _, after, found := strings.Cut(diskPart, "Completed:"); found {
+		progress, _ = strconv.Atoi(strings.TrimSuffix(strings.TrimSpace(after), "%"))
+	}
+	return diskNum, progress, true
+}
+
+// ExtractCopyProgress estimates the bytes of the disks of the VM left to copy from the latest
+// progress reported for each disk, and measures the copy rate of the migration from the change of
+// that estimate. The scheduler places new migrations on agents by these numbers. Migrations importing
+// files have no VMwareMachine with the sizes of their disks, their progress is not estimated.
+func (r *MigrationReconciler) ExtractCopyProgress(ctx context.Context, migration *vjailbreakv1alpha1.Migration, events *corev1.EventList) error {
+	migrationTemplate, err := utils.GetMigrationTemplateFromMigration(ctx, r.Client, migration)
+	if err != nil {
+		return errors.Wrap(err, "failed to get migration template")
+	}
+	if migrationTemplate.Spec.Source.File != nil {
+		return nil
+	}
+	var remaining int64
+	phaseState := constants.VMMigrationStatesEnum[migration.Status.Phase]
+	copying := phaseState < constants.VMMigrationStatesEnum[vjailbreakv1alpha1.VMMigrationPhaseCopyingChangedBlocks] &&
+		migration.Status.Phase != vjailbreakv1alpha1.VMMigrationPhaseFailed &&
+		migration.Status.Phase != vjailbreakv1alpha1.VMMigrationPhaseValidationFailed
+	if copying {
+		vmwMachineName, err := utils.GetK8sCompatibleVMWareObjectName(migration.Spec.VMName, utils.GetSourceName(migrationTemplate))
+		if err != nil {
+			return errors.Wrap(err, "failed to get VMwareMachine name")
+		}
+		vmwMachine := &vjailbreakv1alpha1.VMwareMachine{}
+		if err := r.Get(ctx, types.NamespacedName{Name: vmwMachineName, Namespace: migration.Namespace}, vmwMachine); err != nil {
+			return errors.Wrap(err, "failed to get VMwareMachine")
 		}
-		parts := strings.Split(msg, "Copying disk")
-		if len(parts) <= 1 {
-			return "", false
+
+		// Events are sorted by timestamp (newest first)
+		latestProgress := map[string]int{}
+		for i := range events.Items {
+			diskNum, progress, ok := parseDiskProgress(events.Items[i].Message)
+			if _, seen := latestProgress[diskNum]; ok && !seen {
+				latestProgress[diskNum] = progress
+			}
 		}
-		diskPart := strings.TrimSpace(parts[1])
-		if len(diskPart) == 0 {
-			return "", false
+		for i, disk := range vmwMachine.Spec.VMInfo.Disks {
+			diskBytes := int64(disk.CapacityGB) * 1024 * 1024 * 1024
+			remaining += diskBytes * int64(100-latestProgress[strconv.Itoa(i)]) / 100
 		}
-		diskNum := strings.Split(diskPart, ",")[0]
-		diskNum = strings.Split(diskNum, " ")[0]
-		diskNum = strings.TrimSpace(diskNum)
-		if diskNum == "" {
-			return "", false
+	}
+
+	now := metav1.Now()
+	if migration.Status.Phase == vjailbreakv1alpha1.VMMigrationPhaseCopying && migration.Status.ProgressTime != nil &&
+		remaining < migration.Status.BytesRemaining {
+		if elapsed := now.Sub(migration.Status.ProgressTime.Time).Seconds(); elapsed > 0 {
+			migration.Status.ThroughputBytesPerSecond = int64(float64(migration.Status.BytesRemaining-remaining) / elapsed)
 		}
-		return diskNum, true
 	}
+	if migration.Status.ProgressTime == nil || remaining != migration.Status.BytesRemaining {
+		migration.Status.BytesRemaining = remaining
+		migration.Status.ProgressTime = &now
+	}
+	return nil
+}
 
+// ExtractCurrentDisk extracts which disks are currently being copied from pod events.
+// Disks may be copied in parallel, so every disk whose latest reported progress is
+// between 0% and 100% is listed (e.g. "0,2"). When no disk is mid-copy, the disk of
+// the newest progress event is reported.
+func (r *MigrationReconciler) ExtractCurrentDisk(migration *vjailbreakv1alpha1.Migration, events *corev1.EventList) {
+	// Events are sorted by timestamp (newest first)
+	newestDisk := ""
+	latestProgress := map[string]int{}
 	for i := range events.Items {
-		if diskNum, ok := parseCurrentDisk(events.Items[i].Message); ok {
-			migration.Status.CurrentDisk = diskNum
-			return
+		diskNum, progress, ok := parseDiskProgress(events.Items[i].Message)
+		if !ok {
+			continue
+		}
+		if newestDisk == "" {
+			newestDisk = diskNum
+		}
+		if _, seen := latestProgress[diskNum]; !seen {
+			latestProgress[diskNum] = progress
+		}
+	}
+
+	inFlight := []string{}
+	for diskNum, progress := range latestProgress {
+		if progress > 0 && progress < 100 {
+			inFlight = append(inFlight, diskNum)
 		}
 	}
+	if len(inFlight) > 0 {
+		sort.Slice(inFlight, func(i, j int) bool {
+			a, errA := strconv.Atoi(inFlight[i])
+			b, errB := strconv.Atoi(inFlight[j])
+			if errA != nil || errB != nil {
+				return inFlight[i] < inFlight[j]
+			}
+			return a < b
+		})
+		migration.Status.CurrentDisk = strings.Join(inFlight, ",")
+		return
+	}
+	if newestDisk != "" {
+		migration.Status.CurrentDisk = newestDisk
+		return
+	}
 
 	for _, condition := range migration.Status.Conditions {
-		if diskNum, ok := parseCurrentDisk(condition.Message); ok {
+		if diskNum, _, ok := parseDiskProgress(condition.Message); ok {
 			migration.Status.CurrentDisk = diskNum
 			return
 		}
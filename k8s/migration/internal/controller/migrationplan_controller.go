@@ -18,10 +18,12 @@ package controller
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"os/user"
 	"reflect"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -72,6 +74,8 @@ type MigrationPlanReconciler struct {
 	Scheme                  *runtime.Scheme
 	ctxlog                  logr.Logger
 	MaxConcurrentReconciles int
+	// Scheduler places the migrations on agents, it is shared with the autoscaler
+	Scheduler *utils.MigrationScheduler
 }
 
 var migrationPlanFinalizer = "migrationplan.vjailbreak.pf9.io/finalizer"
@@ -158,6 +162,21 @@ func (r *MigrationPlanReconciler) reconcileNormal(ctx context.Context, scope *sc
 
 	controllerutil.AddFinalizer(migrationplan, migrationPlanFinalizer)
 
+	if pending, err := r.reconcileVMSelector(ctx, migrationplan); err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "failed to reconcile VM selector")
+	} else if pending {
+		return ctrl.Result{RequeueAfter: 30 * time.Second}, nil
+	}
+
+	// Hooks also run for failed plans, OnFailure hooks are requested once a migration failed
+	if err := r.reconcileHooks(ctx, migrationplan); err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "failed to reconcile hooks")
+	}
+
+	if err := r.reconcileDependencies(ctx, migrationplan); err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "failed to reconcile dependencies")
+	}
+
 	res, err := r.ReconcileMigrationPlanJob(ctx, migrationplan, scope)
 	if err != nil {
 		return res, errors.Wrap(err, "failed to reconcile migration plan job")
@@ -165,6 +184,195 @@ func (r *MigrationPlanReconciler) reconcileNormal(ctx context.Context, scope *sc
 	return res, nil
 }
 
+// reconcileVMSelector resolves the VM selector of the plan against the VMwareMachine inventory of
+// its VMware credentials, and freezes the selected VMs into the status. The selection is frozen on
+// the first reconcile, so that VMs that match the selector later do not join the plan. It returns
+// true while the selection waits for the discovery of the inventory of the credentials.
+func (r *MigrationPlanReconciler) reconcileVMSelector(ctx context.Context, migrationplan *vjailbreakv1alpha1.MigrationPlan) (bool, error) {
+	if migrationplan.Spec.VMSelector == nil || migrationplan.Status.SelectionFrozenTime != nil {
+		return false, nil
+	}
+	if err := utils.ValidateVMSelector(migrationplan); err != nil {
+		// The validation of the plan fails it
+		return false, nil
+	}
+	migrationtemplate, err := utils.GetMigrationTemplateFromMigrationPlan(ctx, r.Client, migrationplan)
+	if err != nil {
+		return false, errors.Wrap(err, "failed to get migration template")
+	}
+	if migrationtemplate.Spec.Source.File != nil {
+		return false, nil
+	}
+	vmwcreds := &vjailbreakv1alpha1.VMwareCreds{}
+	if err := r.Get(ctx, types.NamespacedName{Name: migrationtemplate.Spec.Source.VMwareRef, Namespace: migrationtemplate.Namespace}, vmwcreds); err != nil {
+		return false, errors.Wrapf(err, "failed to get VMwareCreds '%s'", migrationtemplate.Spec.Source.VMwareRef)
+	}
+	vmList, err := utils.FilterVMwareMachinesForCreds(ctx, r.Client, vmwcreds)
+	if err != nil {
+		return false, errors.Wrap(err, "failed to list VMwareMachines")
+	}
+	selected, err := utils.ResolveVMSelector(migrationplan, vmList.Items)
+	if err != nil {
+		return false, errors.Wrap(err, "failed to resolve VM selector")
+	}
+	if utils.VMSelectionPending(selected, vmwcreds) {
+		message := fmt.Sprintf("Waiting for the discovery of the VMs of VMwareCreds '%s' to resolve the VM selector", vmwcreds.Name)
+		r.ctxlog.Info(message, "migrationplan", migrationplan.Name)
+		if err := r.UpdateMigrationPlanStatus(ctx, migrationplan, corev1.PodPending, message); err != nil {
+			return false, errors.Wrap(err, "failed to update migration plan status")
+		}
+		return true, nil
+	}
+	r.ctxlog.Info(fmt.Sprintf("VM selector of MigrationPlan '%s' selected %d VMs", migrationplan.Name, len(selected)), "vms", selected)
+
+	frozen := metav1.Now()
+	err = retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		latest := &vjailbreakv1alpha1.MigrationPlan{}
+		if err := r.Get(ctx, types.NamespacedName{Name: migrationplan.Name, Namespace: migrationplan.Namespace}, latest); err != nil {
+			return err
+		}
+		latest.Status.SelectedVMs = selected
+		latest.Status.SelectionFrozenTime = &frozen
+		return r.Status().Update(ctx, latest)
+	})
+	if err != nil {
+		return false, errors.Wrap(err, "failed to freeze selected VMs of migration plan")
+	}
+	migrationplan.Status.SelectedVMs = selected
+	migrationplan.Status.SelectionFrozenTime = &frozen
+	return false, nil
+}
+
+// reconcileDependencies reports the VMs each Migration of the plan waits for and releases the
+// cutover of a Migration once its dependencies are met. A held cutover works like an admin
+// initiated one, unless the plan asks for admin initiated cutovers the release starts it.
+func (r *MigrationPlanReconciler) reconcileDependencies(ctx context.Context, migrationplan *vjailbreakv1alpha1.MigrationPlan) error {
+	if len(migrationplan.Spec.Dependencies) == 0 {
+		return nil
+	}
+	migrationList := &vjailbreakv1alpha1.MigrationList{}
+	if err := r.List(ctx, migrationList, client.InNamespace(migrationplan.Namespace), client.MatchingLabels{"migrationplan": migrationplan.Name}); err != nil {
+		return errors.Wrap(err, "failed to list migrations")
+	}
+	for i := range migrationList.Items {
+		migration := &migrationList.Items[i]
+		if len(utils.GetVMDependencies(migrationplan, migration.Spec.VMName)) == 0 {
+			continue
+		}
+		blockers, failed, err := utils.GetBlockingDependencies(ctx, r.Client, migrationplan, migration.Spec.VMName)
+		if err != nil {
+			return errors.Wrapf(err, "failed to get dependencies of VM %s", migration.Spec.VMName)
+		}
+		if len(failed) > 0 {
+			if !utils.IsMigrationFinished(migration) {
+				if err := r.failMigrationOfFailedDependency(ctx, migrationplan, migration, failed); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if !slices.Equal(migration.Status.BlockedBy, blockers) {
+			if len(blockers) > 0 {
+				r.ctxlog.Info(fmt.Sprintf("Cutover of VM '%s' waits for %s", migration.Spec.VMName, strings.Join(blockers, ", ")))
+			}
+			migration.Status.BlockedBy = blockers
+			if err := r.Status().Update(ctx, migration); err != nil {
+				return errors.Wrapf(err, "failed to update blocked VMs of migration %s", migration.Name)
+			}
+		}
+		if len(blockers) == 0 && migration.Spec.InitiateCutover && !migrationplan.Spec.MigrationStrategy.AdminInitiatedCutOver {
+			r.ctxlog.Info(fmt.Sprintf("Dependencies of VM '%s' are met, releasing its cutover", migration.Spec.VMName))
+			migration.Spec.InitiateCutover = false
+			if err := r.Update(ctx, migration); err != nil {
+				return errors.Wrapf(err, "failed to release cutover of migration %s", migration.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// failMigrationOfFailedDependency fails the migration of a VM that depends on VMs whose migrations
+// ended without succeeding, its cutover would wait for them forever. The source VM was not shut
+// down yet, the job of the migration is deleted with it.
+func (r *MigrationPlanReconciler) failMigrationOfFailedDependency(ctx context.Context, migrationplan *vjailbreakv1alpha1.MigrationPlan,
+	migration *vjailbreakv1alpha1.Migration, failed []string,
+) error {
+	message := fmt.Sprintf("VM %s depends on %s, whose migration did not succeed", migration.Spec.VMName, strings.Join(failed, ", "))
+	r.ctxlog.Info(message)
+	vmwarecreds, err := utils.GetVMwareCredsNameFromMigrationPlan(ctx, r.Client, migrationplan)
+	if err != nil {
+		return errors.Wrap(err, "failed to get vmware credentials")
+	}
+	jobName, err := utils.GetJobNameForVMName(migration.Spec.VMName, vmwarecreds)
+	if err != nil {
+		return errors.Wrap(err, "failed to get job name")
+	}
+	job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: jobName, Namespace: migration.Namespace}}
+	if err := r.Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil && !apierrors.IsNotFound(err) {
+		return errors.Wrapf(err, "failed to delete job '%s'", jobName)
+	}
+	if err := r.markMigrationFailed(ctx, migration, message); err != nil {
+		return errors.Wrapf(err, "failed to fail migration %s", migration.Name)
+	}
+	return nil
+}
+
+// reconcileHooks starts the hook Jobs of the stage each Migration of the plan requests. The
+// v2v-helper requests a stage with an annotation on its Migration and waits for the Jobs.
+func (r *MigrationPlanReconciler) reconcileHooks(ctx context.Context, migrationplan *vjailbreakv1alpha1.MigrationPlan) error {
+	if len(migrationplan.Spec.Hooks) == 0 {
+		return nil
+	}
+	migrationList := &vjailbreakv1alpha1.MigrationList{}
+	if err := r.List(ctx, migrationList, client.InNamespace(migrationplan.Namespace), client.MatchingLabels{"migrationplan": migrationplan.Name}); err != nil {
+		return errors.Wrap(err, "failed to list migrations")
+	}
+	for i := range migrationList.Items {
+		migration := &migrationList.Items[i]
+		stage := migration.Annotations[vjailbreakv1alpha1.HookStageAnnotation]
+		hooks := utils.HooksForStage(migrationplan.Spec.Hooks, stage)
+		if len(hooks) == 0 {
+			continue
+		}
+		migrationtemplate, err := utils.GetMigrationTemplateFromMigrationPlan(ctx, r.Client, migrationplan)
+		if err != nil {
+			return errors.Wrap(err, "failed to get migration template")
+		}
+		vmMachine := &vjailbreakv1alpha1.VMwareMachine{}
+		if migrationtemplate.Spec.Source.File != nil {
+			// VMs imported from files have no VMwareMachine, the hook only gets their name
+			vmMachine.Spec.VMInfo.Name = migration.Spec.VMName
+		} else {
+			vmk8sname, err := utils.GetK8sCompatibleVMWareObjectName(migration.Spec.VMName, utils.GetSourceName(migrationtemplate))
+			if err != nil {
+				return errors.Wrap(err, "failed to get vm name")
+			}
+			if err := r.Get(ctx, types.NamespacedName{Name: vmk8sname, Namespace: migrationplan.Namespace}, vmMachine); err != nil {
+				return errors.Wrapf(err, "failed to get VMwareMachine for VM %s", migration.Spec.VMName)
+			}
+		}
+		for _, hook := range hooks {
+			job := &batchv1.Job{}
+			err := r.Get(ctx, types.NamespacedName{Name: utils.GetHookJobName(migration.Name, stage, hook.Name), Namespace: migration.Namespace}, job)
+			if err == nil {
+				continue
+			}
+			if !apierrors.IsNotFound(err) {
+				return errors.Wrapf(err, "failed to get job of hook %s", hook.Name)
+			}
+			job, err = utils.NewHookJob(migration, vmMachine, hook)
+			if err != nil {
+				return errors.Wrapf(err, "failed to build job of hook %s", hook.Name)
+			}
+			r.ctxlog.Info(fmt.Sprintf("Creating Job '%s' for %s hook '%s' of VM '%s'", job.Name, stage, hook.Name, migration.Spec.VMName))
+			if err := r.createResource(ctx, migration, job); err != nil && !apierrors.IsAlreadyExists(errors.Cause(err)) {
+				return errors.Wrapf(err, "failed to create job of hook %s", hook.Name)
+			}
+		}
+	}
+	return nil
+}
+
 //nolint:unparam //future use
 func (r *MigrationPlanReconciler) reconcileDelete(
 	ctx context.Context,
@@ -232,6 +440,16 @@ func (r *MigrationPlanReconciler) reconcilePostMigration(ctx context.Context, sc
 		return nil
 	}
 
+	// The post-migration actions act on the source VM in vCenter, VMs imported from files have none
+	migrationtemplate, err := utils.GetMigrationTemplateFromMigrationPlan(ctx, r.Client, migrationplan)
+	if err != nil {
+		return errors.Wrap(err, "failed to get migration template")
+	}
+	if migrationtemplate.Spec.Source.File != nil {
+		ctxlog.Info("Skipping post-migration actions for VM imported from a file", "vm", vm)
+		return nil
+	}
+
 	// Get required resources
 	_, vmwcreds, secret, err := r.getMigrationTemplateAndCreds(ctx, migrationplan)
 	if err != nil {
@@ -421,12 +639,13 @@ func GetVMwareMachineForVM(ctx context.Context, r *MigrationPlanReconciler, vm s
 func (r *MigrationPlanReconciler) ReconcileMigrationPlanJob(ctx context.Context,
 	migrationplan *vjailbreakv1alpha1.MigrationPlan,
 	scope *scope.MigrationPlanScope) (ctrl.Result, error) {
+	vmGroups := utils.GetMigrationPlanVMGroups(migrationplan)
 	totalVMs := 0
-	for _, group := range migrationplan.Spec.VirtualMachines {
+	for _, group := range vmGroups {
 		totalVMs += len(group)
 	}
 	allVMNames := make([]string, 0, totalVMs)
-	for _, group := range migrationplan.Spec.VirtualMachines {
+	for _, group := range vmGroups {
 		allVMNames = append(allVMNames, group...)
 	}
 
@@ -452,7 +671,8 @@ func (r *MigrationPlanReconciler) ReconcileMigrationPlanJob(ctx context.Context,
 		hasExistingFailures := false
 		for _, m := range migrationList.Items {
 			existingMigrationMap[m.Spec.VMName] = true
-			if m.Status.Phase == vjailbreakv1alpha1.VMMigrationPhaseFailed || m.Status.Phase == vjailbreakv1alpha1.VMMigrationPhaseValidationFailed {
+			if m.Status.Phase == vjailbreakv1alpha1.VMMigrationPhaseFailed || m.Status.Phase == vjailbreakv1alpha1.VMMigrationPhaseValidationFailed ||
+				m.Status.Phase == vjailbreakv1alpha1.VMMigrationPhaseRolledBack {
 				hasExistingFailures = true
 			}
 		}
@@ -497,6 +717,28 @@ func (r *MigrationPlanReconciler) ReconcileMigrationPlanJob(ctx context.Context,
 		return ctrl.Result{}, nil
 	}
 
+	err := utils.ValidateDependencies(migrationplan)
+	if err == nil {
+		err = utils.ValidateMaintenanceWindows(ctx, r.Client, migrationplan)
+	}
+	if err == nil {
+		err = utils.ValidateSnapshotConsistency(migrationplan)
+	}
+	if err == nil {
+		err = utils.ValidateVMSelector(migrationplan)
+	}
+	if err != nil {
+		if updateErr := r.UpdateMigrationPlanStatus(ctx, migrationplan, corev1.PodFailed,
+			fmt.Sprintf("%s: %v", constants.MigrationPlanValidationFailedPrefix, err)); updateErr != nil {
+			r.ctxlog.Error(updateErr, "Failed to update migration plan status after validation failure")
+		}
+		return ctrl.Result{}, err
+	}
+
+	if migrationtemplate, err := utils.GetMigrationTemplateFromMigrationPlan(ctx, r.Client, migrationplan); err == nil && migrationtemplate.Spec.Source.File != nil {
+		return r.ReconcileFileSourcePlanJob(ctx, migrationplan, migrationtemplate, scope)
+	}
+
 	migrationtemplate, vmwcreds, _, err := r.getMigrationTemplateAndCreds(ctx, migrationplan)
 	if err != nil {
 		r.ctxlog.Error(err, "Failed to get migration template and credentials")
@@ -595,8 +837,18 @@ func (r *MigrationPlanReconciler) ReconcileMigrationPlanJob(ctx context.Context,
 		arraycreds = nil
 	}
 
+	waitingForCapacity := strings.HasPrefix(migrationplan.Status.MigrationMessage, constants.MigrationPlanWaitingForCapacityPrefix)
+	if (migrationplan.Status.MigrationStatus == "" || waitingForCapacity) && migrationtemplate.Spec.Destination.File == nil {
+		if blocked, err := r.reconcileCapacityForecast(ctx, migrationplan, migrationtemplate, validVMs); blocked {
+			return ctrl.Result{RequeueAfter: time.Minute}, err
+		}
+		if err := r.reconcileValidationWarnings(ctx, migrationplan, migrationtemplate, validVMs); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
 	// Starting the Migrations
-	if migrationplan.Status.MigrationStatus == "" {
+	if migrationplan.Status.MigrationStatus == "" || waitingForCapacity {
 		err := r.UpdateMigrationPlanStatus(ctx, migrationplan, corev1.PodRunning, "Migration(s) in progress")
 		if err != nil {
 			return ctrl.Result{}, errors.Wrap(err, "failed to update migration plan status")
@@ -620,7 +872,7 @@ func (r *MigrationPlanReconciler) ReconcileMigrationPlanJob(ctx context.Context,
 		return ctrl.Result{}, err
 	}
 
-	for _, parallelvms := range migrationplan.Spec.VirtualMachines {
+	for _, parallelvms := range vmGroups {
 		migrationobjs := &vjailbreakv1alpha1.MigrationList{}
 		err := r.TriggerMigration(ctx, migrationplan, migrationobjs, openstackcreds, vmwcreds, arraycreds, migrationtemplate, vmMachinesArr)
 		if err != nil {
@@ -686,6 +938,12 @@ func (r *MigrationPlanReconciler) processMigrationPhases(
 				fmt.Sprintf("Migration for VM '%s' failed: %s", migration.Spec.VMName, migration.Status.Conditions[0].Message))
 			return false, err
 
+		case vjailbreakv1alpha1.VMMigrationPhaseRolledBack:
+			r.ctxlog.Info("Migration rolled back for VM", "vm", migration.Spec.VMName)
+			err := r.UpdateMigrationPlanStatus(ctx, migrationplan, corev1.PodFailed,
+				fmt.Sprintf("Migration for VM '%s' was rolled back to the source VM", migration.Spec.VMName))
+			return false, err
+
 		case vjailbreakv1alpha1.VMMigrationPhaseSucceeded:
 			err := r.reconcilePostMigration(ctx, scope, migration.Spec.VMName)
 			if err != nil {
@@ -705,6 +963,94 @@ func (r *MigrationPlanReconciler) processMigrationPhases(
 	return allFinished, nil
 }
 
+// reconcileCapacityForecast records the capacity forecast of the VMs of the plan in its status. It
+// reports the plan as blocked when the plan has strict capacity and the target project lacks the
+// quota for it, or the forecast could not be computed.
+func (r *MigrationPlanReconciler) reconcileCapacityForecast(ctx context.Context, migrationplan *vjailbreakv1alpha1.MigrationPlan,
+	migrationtemplate *vjailbreakv1alpha1.MigrationTemplate, validVMs []*vjailbreakv1alpha1.VMwareMachine,
+) (bool, error) {
+	// Every status update requeues the plan, reuse a recent forecast rather than querying the quotas again
+	if utils.IsCapacityForecastFresh(migrationplan.Status.CapacityForecast) {
+		forecast := migrationplan.Status.CapacityForecast
+		return !forecast.Sufficient && migrationplan.Spec.StrictCapacity, nil
+	}
+	vmNames := make([]string, 0, len(validVMs))
+	for _, vmMachine := range validVMs {
+		vmNames = append(vmNames, vmMachine.Spec.VMInfo.Name)
+	}
+	forecast, err := utils.ForecastMigrationCapacity(ctx, r.Client, migrationtemplate, vmNames)
+	if err != nil {
+		r.ctxlog.Error(err, "Failed to forecast capacity of migration plan", "migrationplan", migrationplan.Name)
+		if !migrationplan.Spec.StrictCapacity {
+			return false, nil
+		}
+		msg := fmt.Sprintf("%s: failed to forecast capacity: %v", constants.MigrationPlanWaitingForCapacityPrefix, err)
+		if updateErr := r.UpdateMigrationPlanStatus(ctx, migrationplan, corev1.PodPending, msg); updateErr != nil {
+			return true, errors.Wrap(updateErr, "failed to update migration plan status")
+		}
+		return true, nil
+	}
+
+	err = retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		latest := &vjailbreakv1alpha1.MigrationPlan{}
+		if err := r.Get(ctx, types.NamespacedName{Name: migrationplan.Name, Namespace: migrationplan.Namespace}, latest); err != nil {
+			return err
+		}
+		latest.Status.CapacityForecast = forecast
+		return r.Status().Update(ctx, latest)
+	})
+	if err != nil {
+		return false, errors.Wrap(err, "failed to update capacity forecast of migration plan")
+	}
+	migrationplan.Status.CapacityForecast = forecast
+
+	if forecast.Sufficient || !migrationplan.Spec.StrictCapacity {
+		return false, nil
+	}
+	r.ctxlog.Info("Target project lacks quota for strict capacity plan, requeuing", "migrationplan", migrationplan.Name, "forecast", forecast.Message)
+	msg := fmt.Sprintf("%s: %s", constants.MigrationPlanWaitingForCapacityPrefix, forecast.Message)
+	if err := r.UpdateMigrationPlanStatus(ctx, migrationplan, corev1.PodPending, msg); err != nil {
+		return true, errors.Wrap(err, "failed to update migration plan status")
+	}
+	return true, nil
+}
+
+// reconcileValidationWarnings records the problems of the plan that do not stop it in its status,
+// the VMs with Secure Boot or a vTPM that the target cloud cannot boot with them and the IPv6
+// addresses that no subnet of their target network contains
+func (r *MigrationPlanReconciler) reconcileValidationWarnings(ctx context.Context, migrationplan *vjailbreakv1alpha1.MigrationPlan,
+	migrationtemplate *vjailbreakv1alpha1.MigrationTemplate, validVMs []*vjailbreakv1alpha1.VMwareMachine,
+) error {
+	warnings, err := utils.CheckSecurityFeatureSupport(ctx, r.Client, migrationtemplate, validVMs)
+	if err != nil {
+		r.ctxlog.Error(err, "Failed to check Secure Boot and vTPM support of migration plan", "migrationplan", migrationplan.Name)
+	}
+	ipv6Warnings, err := utils.CheckIPv6SubnetSupport(ctx, r.Client, migrationtemplate, validVMs)
+	if err != nil {
+		r.ctxlog.Error(err, "Failed to check IPv6 subnets of migration plan", "migrationplan", migrationplan.Name)
+	}
+	warnings = append(warnings, ipv6Warnings...)
+	for _, warning := range warnings {
+		r.ctxlog.Info("Migration plan validation warning", "migrationplan", migrationplan.Name, "warning", warning)
+	}
+	if slices.Equal(warnings, migrationplan.Status.ValidationWarnings) {
+		return nil
+	}
+	err = retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		latest := &vjailbreakv1alpha1.MigrationPlan{}
+		if err := r.Get(ctx, types.NamespacedName{Name: migrationplan.Name, Namespace: migrationplan.Namespace}, latest); err != nil {
+			return err
+		}
+		latest.Status.ValidationWarnings = warnings
+		return r.Status().Update(ctx, latest)
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to update validation warnings of migration plan")
+	}
+	migrationplan.Status.ValidationWarnings = warnings
+	return nil
+}
+
 // handleRDMDiskMigrationError handles errors that occur during RDM disk migration
 func (r *MigrationPlanReconciler) handleRDMDiskMigrationError(ctx context.Context, migrationplan *vjailbreakv1alpha1.MigrationPlan, err error) (ctrl.Result, error) {
 	if err == verrors.ErrRDMDiskNotMigrated {
@@ -741,6 +1087,7 @@ func (r *MigrationPlanReconciler) handleRDMDiskMigrationError(ctx context.Contex
 			m := migrationList.Items[i]
 			if m.Status.Phase == vjailbreakv1alpha1.VMMigrationPhaseSucceeded ||
 				m.Status.Phase == vjailbreakv1alpha1.VMMigrationPhaseFailed ||
+				m.Status.Phase == vjailbreakv1alpha1.VMMigrationPhaseRolledBack ||
 				m.Status.Phase == vjailbreakv1alpha1.VMMigrationPhaseValidationFailed {
 				continue
 			}
@@ -810,7 +1157,12 @@ func (r *MigrationPlanReconciler) CreateMigration(ctx context.Context,
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to get vm name")
 	}
-	vminfo := &vmMachine.Spec.VMInfo
+	// VMs imported from files have no VMwareMachine, their disks are only known to the v2v-helper
+	totalDisks, hasRDMDisks := 0, false
+	if vmMachine != nil {
+		totalDisks = len(vmMachine.Spec.VMInfo.Disks)
+		hasRDMDisks = len(vmMachine.Spec.VMInfo.RDMDisks) > 0
+	}
 
 	migrationobj := &vjailbreakv1alpha1.Migration{}
 	err = r.Get(ctx, types.NamespacedName{Name: utils.MigrationNameFromVMName(vmk8sname), Namespace: migrationplan.Namespace}, migrationobj)
@@ -829,21 +1181,22 @@ func (r *MigrationPlanReconciler) CreateMigration(ctx context.Context,
 				Namespace: migrationplan.Namespace,
 				Labels: map[string]string{
 					"migrationplan":              migrationplan.Name,
-					constants.NumberOfDisksLabel: strconv.Itoa(len(vminfo.Disks)),
+					constants.NumberOfDisksLabel: strconv.Itoa(totalDisks),
 				},
 			},
 			Spec: vjailbreakv1alpha1.MigrationSpec{
 				MigrationPlan: migrationplan.Name,
 				VMName:        vm,
 				// PodRef will be set in the migration controller
-				InitiateCutover:         migrationplan.Spec.MigrationStrategy.AdminInitiatedCutOver,
+				// The cutover of a VM with dependencies is held until they are met
+				InitiateCutover:         migrationplan.Spec.MigrationStrategy.AdminInitiatedCutOver || len(utils.GetVMDependencies(migrationplan, vm)) > 0,
 				DisconnectSourceNetwork: migrationplan.Spec.MigrationStrategy.DisconnectSourceNetwork,
 				AssignedIP:              assignedIP,
 				MigrationType:           migrationplan.Spec.MigrationStrategy.Type,
 			},
 			Status: vjailbreakv1alpha1.MigrationStatus{
 				Phase:      vjailbreakv1alpha1.VMMigrationPhasePending,
-				TotalDisks: len(vminfo.Disks),
+				TotalDisks: totalDisks,
 			},
 		}
 		migrationobj.Labels = MergeLabels(migrationobj.Labels, migrationplan.Labels)
@@ -855,7 +1208,6 @@ func (r *MigrationPlanReconciler) CreateMigration(ctx context.Context,
 		// Set retryable status based on whether VM has RDM disks
 		// VMs with RDM disks cannot be retried through UI because shared RDM disk state
 		// prevents automatic retry (RDMDisk CR may be in Error or Managed state)
-		hasRDMDisks := len(vmMachine.Spec.VMInfo.RDMDisks) > 0
 		retryable := !hasRDMDisks
 
 		migrationobj.Status.Retryable = &retryable
@@ -895,7 +1247,7 @@ func (r *MigrationPlanReconciler) CreateJob(ctx context.Context,
 	}
 	pointtrue := true
 	cutoverlabel := "yes"
-	if migrationplan.Spec.MigrationStrategy.AdminInitiatedCutOver {
+	if migrationobj.Spec.InitiateCutover {
 		cutoverlabel = "no"
 	}
 	envVars := []corev1.EnvVar{
@@ -917,7 +1269,7 @@ func (r *MigrationPlanReconciler) CreateJob(ctx context.Context,
 		},
 	}
 
-	if migrationtemplate.Spec.UseFlavorless {
+	if migrationtemplate.Spec.UseFlavorless && vmMachine != nil {
 		envVars = append(envVars, corev1.EnvVar{
 			Name:  "FLAVORLESS_FLAVOR_ID",
 			Value: vmMachine.Spec.TargetFlavorID,
@@ -932,25 +1284,84 @@ func (r *MigrationPlanReconciler) CreateJob(ctx context.Context,
 
 	job := &batchv1.Job{}
 	err = r.Get(ctx, types.NamespacedName{Name: jobName, Namespace: migrationplan.Namespace}, job)
-	if err != nil && apierrors.IsNotFound(err) {
-		r.ctxlog.Info(fmt.Sprintf("Creating new Job '%s' for VM '%s'", jobName, vm))
+	if err == nil {
+		// A job pinned to a lost agent is replaced by one placed on another agent
+		replace, deleteErr := r.deleteJobOfLostAgent(ctx, migrationobj, job)
+		if deleteErr != nil || !replace {
+			return deleteErr
+		}
+	}
+	if err == nil || apierrors.IsNotFound(err) {
+		// Placements are serialized, so that every migration sees the slots taken by the ones placed before it
+		r.Scheduler.Lock()
+		defer r.Scheduler.Unlock()
+		migrations, err := r.Scheduler.ListMigrations(ctx, r.Client)
+		if err != nil {
+			return errors.Wrap(err, "failed to list migrations for placement")
+		}
+		pausedPlans := utils.GetPausedMigrationPlans(ctx, r.Client, migrations)
+		source := utils.GetMigrationSource(vmwarecreds, vmMachine)
+		reason := constants.MigrationDependencyReason
+		agent := ""
+		message := ""
+		// A cold migration shuts the source VM down first, its whole migration waits for the dependencies
+		if migrationplan.Spec.MigrationStrategy.Type == "cold" {
+			blockers, _, err := utils.GetBlockingDependencies(ctx, r.Client, migrationplan, vm)
+			if err != nil {
+				return errors.Wrap(err, "failed to get dependencies of migration")
+			}
+			if len(blockers) > 0 {
+				message = fmt.Sprintf("Waiting for dependencies %s", strings.Join(blockers, ", "))
+			}
+		}
+		if message == "" {
+			reason = constants.MigrationMaintenanceWindowReason
+			message, err = utils.CheckMaintenanceWindows(ctx, r.Client, migrationplan)
+			if err != nil {
+				return errors.Wrap(err, "failed to check maintenance windows")
+			}
+		}
+		if message == "" {
+			// The limits of the source apply across all plans, a migration holds its slot from the time
+			// it is placed on an agent
+			reason = constants.MigrationSourceLimitReason
+			message = utils.CheckSourceMigrationLimits(migrations, migrationobj, source, vjailbreakSettings, pausedPlans)
+		}
+		if message == "" {
+			// Place the migration on an agent rather than leaving it to the Kubernetes scheduler, which
+			// only sees the static resource requests of the pod
+			reason = constants.MigrationQueuedReason
+			ahead := utils.GetMigrationQueuePosition(migrations, migrationobj, reason, source, vjailbreakSettings, pausedPlans) - 1
+			agent, message, err = utils.SelectAgentForMigration(ctx, r.Client, migrations, migrationobj, vjailbreakSettings.MaxMigrationsPerAgent, ahead)
+			if err != nil {
+				return errors.Wrap(err, "failed to select agent for migration")
+			}
+		}
+		queuePosition := 0
+		if agent == "" {
+			queuePosition = utils.GetMigrationQueuePosition(migrations, migrationobj, reason, source, vjailbreakSettings, pausedPlans)
+		} else {
+			reason = constants.MigrationScheduledReason
+		}
+		if err := r.markMigrationScheduled(ctx, migrationobj, agent, reason, message, source, queuePosition); err != nil {
+			return errors.Wrap(err, "failed to update scheduled condition of migration")
+		}
+		if agent != "" {
+			r.Scheduler.Placed(migrationobj, agent, source)
+		}
+		if agent == "" {
+			r.ctxlog.Info(fmt.Sprintf("Migration of VM '%s' is queued", vm), "reason", message, "position", queuePosition)
+			return nil
+		}
+
+		r.ctxlog.Info(fmt.Sprintf("Creating new Job '%s' for VM '%s' on agent '%s'", jobName, vm, agent))
 		job = &batchv1.Job{
 			ObjectMeta: metav1.ObjectMeta{
 				Name:      jobName,
 				Namespace: migrationplan.Namespace,
 			},
 			Spec: batchv1.JobSpec{
-				PodFailurePolicy: &batchv1.PodFailurePolicy{
-					Rules: []batchv1.PodFailurePolicyRule{
-						{
-							Action: batchv1.PodFailurePolicyActionFailJob,
-							OnExitCodes: &batchv1.PodFailurePolicyOnExitCodesRequirement{
-								Values:   []int32{0},
-								Operator: batchv1.PodFailurePolicyOnExitCodesOpNotIn,
-							},
-						},
-					},
-				},
+				PodFailurePolicy:        migrationPodFailurePolicy(),
 				TTLSecondsAfterFinished: nil,
 				Template: corev1.PodTemplateSpec{
 					ObjectMeta: metav1.ObjectMeta{
@@ -965,6 +1376,25 @@ func (r *MigrationPlanReconciler) CreateJob(ctx context.Context,
 						TerminationGracePeriodSeconds: ptr.To(constants.TerminationPeriod),
 						HostNetwork:                   true,
 						DNSPolicy:                     corev1.DNSClusterFirstWithHostNet,
+						// The pod is pinned to its agent, a migration whose agent is lost is placed again
+						// by deleteJobOfLostAgent
+						Affinity: &corev1.Affinity{
+							NodeAffinity: &corev1.NodeAffinity{
+								RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+									NodeSelectorTerms: []corev1.NodeSelectorTerm{
+										{
+											MatchFields: []corev1.NodeSelectorRequirement{
+												{
+													Key:      "metadata.name",
+													Operator: corev1.NodeSelectorOpIn,
+													Values:   []string{agent},
+												},
+											},
+										},
+									},
+								},
+							},
+						},
 						Containers: []corev1.Container{
 							{
 								Name:            "fedora",
@@ -976,22 +1406,23 @@ func (r *MigrationPlanReconciler) CreateJob(ctx context.Context,
 								},
 								Env: envVars,
 								EnvFrom: func() []corev1.EnvFromSource {
-									envFrom := []corev1.EnvFromSource{
-										{
+									envFrom := []corev1.EnvFromSource{}
+									if vmwareSecretRef != "" {
+										envFrom = append(envFrom, corev1.EnvFromSource{
 											SecretRef: &corev1.SecretEnvSource{
 												LocalObjectReference: corev1.LocalObjectReference{
 													Name: vmwareSecretRef,
 												},
 											},
-										},
-										{
-											SecretRef: &corev1.SecretEnvSource{
-												LocalObjectReference: corev1.LocalObjectReference{
-													Name: openstackSecretRef,
-												},
+										})
+									}
+									envFrom = append(envFrom, corev1.EnvFromSource{
+										SecretRef: &corev1.SecretEnvSource{
+											LocalObjectReference: corev1.LocalObjectReference{
+												Name: openstackSecretRef,
 											},
 										},
-									}
+									})
 									if arrayCredsSecretRef != "" {
 										envFrom = append(envFrom, corev1.EnvFromSource{
 											SecretRef: &corev1.SecretEnvSource{
@@ -1098,6 +1529,38 @@ func (r *MigrationPlanReconciler) CreateJob(ctx context.Context,
 				},
 			},
 		}
+		if source := migrationtemplate.Spec.Source.File; source != nil && source.PVCName != "" {
+			podSpec := &job.Spec.Template.Spec
+			podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+				Name: "source",
+				VolumeSource: corev1.VolumeSource{
+					PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+						ClaimName: source.PVCName,
+						ReadOnly:  true,
+					},
+				},
+			})
+			podSpec.Containers[0].VolumeMounts = append(podSpec.Containers[0].VolumeMounts, corev1.VolumeMount{
+				Name:      "source",
+				MountPath: constants.FileSourceMountPath,
+				ReadOnly:  true,
+			})
+		}
+		if destination := migrationtemplate.Spec.Destination.File; destination != nil {
+			podSpec := &job.Spec.Template.Spec
+			podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+				Name: "destination",
+				VolumeSource: corev1.VolumeSource{
+					PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+						ClaimName: destination.PVCName,
+					},
+				},
+			})
+			podSpec.Containers[0].VolumeMounts = append(podSpec.Containers[0].VolumeMounts, corev1.VolumeMount{
+				Name:      "destination",
+				MountPath: constants.FileDestinationMountPath,
+			})
+		}
 		if err := r.createResource(ctx, migrationobj, job); err != nil {
 			r.ctxlog.Error(err, fmt.Sprintf("Failed to create Job '%s'", jobName))
 			return errors.Wrap(err, fmt.Sprintf("failed to create job '%s'", jobName))
@@ -1141,6 +1604,39 @@ func (r *MigrationPlanReconciler) CreateFirstbootConfigMap(ctx context.Context,
 	return configMap, nil
 }
 
+// CreateMigrationCheckpointConfigMap creates the config map in which v2v-helper records disk
+// replication checkpoints. It is owned by the MigrationPlan rather than the Migration so that
+// a retried migration, which recreates the Migration object, can resume the copy.
+func (r *MigrationPlanReconciler) CreateMigrationCheckpointConfigMap(ctx context.Context,
+	migrationplan *vjailbreakv1alpha1.MigrationPlan,
+	vmwcreds *vjailbreakv1alpha1.VMwareCreds, vm string,
+) error {
+	vmname, err := utils.GetK8sCompatibleVMWareObjectName(vm, vmwcreds.Name)
+	if err != nil {
+		return errors.Wrap(err, "failed to get vm name")
+	}
+	configMapName := utils.GetMigrationCheckpointConfigMapName(vmname)
+	configMap := &corev1.ConfigMap{}
+	err = r.Get(ctx, types.NamespacedName{Name: configMapName, Namespace: migrationplan.Namespace}, configMap)
+	if err == nil {
+		return nil
+	}
+	if !apierrors.IsNotFound(err) {
+		return errors.Wrapf(err, "failed to get config map '%s'", configMapName)
+	}
+	r.ctxlog.Info(fmt.Sprintf("Creating new checkpoint ConfigMap '%s' for VM '%s'", configMapName, vmname))
+	configMap = &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      configMapName,
+			Namespace: migrationplan.Namespace,
+		},
+	}
+	if err := r.createResource(ctx, migrationplan, configMap); err != nil && !apierrors.IsAlreadyExists(errors.Cause(err)) {
+		return errors.Wrapf(err, "failed to create config map '%s'", configMapName)
+	}
+	return nil
+}
+
 // CreateMigrationConfigMap creates a config map for migration
 func (r *MigrationPlanReconciler) CreateMigrationConfigMap(ctx context.Context,
 	migrationplan *vjailbreakv1alpha1.MigrationPlan,
@@ -1205,6 +1701,8 @@ func (r *MigrationPlanReconciler) CreateMigrationConfigMap(ctx context.Context,
 				"DATACOPYSTART":              migrationplan.Spec.MigrationStrategy.DataCopyStart.Format(time.RFC3339),
 				"CUTOVERSTART":               migrationplan.Spec.MigrationStrategy.VMCutoverStart.Format(time.RFC3339),
 				"CUTOVEREND":                 migrationplan.Spec.MigrationStrategy.VMCutoverEnd.Format(time.RFC3339),
+				"DATA_COPY_WINDOW":           migrationplan.Spec.MigrationStrategy.DataCopyWindow,
+				"CUTOVER_WINDOW":             migrationplan.Spec.MigrationStrategy.CutoverWindow,
 				"NEUTRON_NETWORK_NAMES":      strings.Join(openstacknws, ","),
 				"NEUTRON_PORT_IDS":           strings.Join(openstackports, ","),
 				"CINDER_VOLUME_TYPES":        strings.Join(openstackvolumetypes, ","),
@@ -1219,11 +1717,39 @@ func (r *MigrationPlanReconciler) CreateMigrationConfigMap(ctx context.Context,
 				"PERIODIC_SYNC_INTERVAL":     migrationplan.Spec.AdvancedOptions.PeriodicSyncInterval,
 				"PERIODIC_SYNC_ENABLED":      strconv.FormatBool(migrationplan.Spec.AdvancedOptions.PeriodicSyncEnabled),
 				"NETWORK_PERSISTENCE":        strconv.FormatBool(migrationplan.Spec.AdvancedOptions.NetworkPersistence),
+				"DISK_COPY_PARALLELISM":      strconv.Itoa(migrationplan.Spec.AdvancedOptions.DiskCopyParallelism),
+				"ROLLBACK_POLICY":            migrationplan.Spec.MigrationStrategy.RollbackPolicy,
 			},
 		}
 		if utils.IsOpenstackPCD(*openstackcreds) {
 			configMap.Data["TARGET_AVAILABILITY_ZONE"] = migrationtemplate.Spec.TargetPCDClusterName
 		}
+		if verification := migrationplan.Spec.AdvancedOptions.IntegrityVerification; verification != nil {
+			configMap.Data["INTEGRITY_VERIFICATION_MODE"] = verification.Mode
+			configMap.Data["INTEGRITY_VERIFICATION_SAMPLE_PERCENT"] = strconv.Itoa(verification.SamplePercent)
+		}
+		if healthChecks := migrationplan.Spec.MigrationStrategy.HealthChecks; len(healthChecks) > 0 {
+			healthChecksJSON, err := json.Marshal(healthChecks)
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to marshal health checks")
+			}
+			configMap.Data["HEALTH_CHECKS"] = string(healthChecksJSON)
+		}
+		configMap.Data["HEALTH_CHECK_FAILURE_POLICY"] = migrationplan.Spec.MigrationStrategy.HealthCheckFailurePolicy
+		if len(migrationplan.Spec.Hooks) > 0 {
+			hooksJSON, err := json.Marshal(migrationplan.Spec.Hooks)
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to marshal hooks")
+			}
+			configMap.Data["HOOKS"] = string(hooksJSON)
+		}
+		if consistency := utils.GetSnapshotConsistency(migrationplan, vm); consistency != nil {
+			consistencyJSON, err := json.Marshal(consistency)
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to marshal snapshot consistency")
+			}
+			configMap.Data["SNAPSHOT_CONSISTENCY"] = string(consistencyJSON)
+		}
 
 		// Check if assigned IP is set from Migration spec
 		if migrationobj.Spec.AssignedIP != "" {
@@ -1232,10 +1758,10 @@ func (r *MigrationPlanReconciler) CreateMigrationConfigMap(ctx context.Context,
 			configMap.Data["ASSIGNED_IP"] = ""
 		}
 
-		// Check if target flavor is set
+		// Check if target flavor is set, file destinations need no flavor
 		if vmMachine.Spec.TargetFlavorID != "" {
 			configMap.Data["TARGET_FLAVOR_ID"] = vmMachine.Spec.TargetFlavorID
-		} else {
+		} else if migrationtemplate.Spec.Destination.File == nil {
 			// If target flavor is not set, use the closest matching flavor
 			allFlavors, err := utils.ListAllFlavors(ctx, r.Client, openstackcreds)
 			if err != nil {
@@ -1285,6 +1811,7 @@ func (r *MigrationPlanReconciler) CreateMigrationConfigMap(ctx context.Context,
 			configMap.Data["VENDOR_TYPE"] = arraycreds.Spec.VendorType
 			configMap.Data["ARRAY_CREDS_MAPPING"] = migrationtemplate.Spec.ArrayCredsMapping
 		}
+		setFileDestinationConfig(configMap, migrationtemplate)
 
 		err = r.createResource(ctx, migrationobj, configMap)
 		if err != nil {
@@ -1295,6 +1822,16 @@ func (r *MigrationPlanReconciler) CreateMigrationConfigMap(ctx context.Context,
 	return configMap, nil
 }
 
+// setFileDestinationConfig selects the file destination of the migration template in the migration configmap
+func setFileDestinationConfig(configMap *corev1.ConfigMap, migrationtemplate *vjailbreakv1alpha1.MigrationTemplate) {
+	destination := migrationtemplate.Spec.Destination.File
+	if destination == nil {
+		return
+	}
+	configMap.Data["DESTINATION_TYPE"] = constants.FileDestinationType
+	configMap.Data["DESTINATION_FORMAT"] = destination.Format
+}
+
 func (r *MigrationPlanReconciler) createResource(ctx context.Context, owner metav1.Object, controlled client.Object) error {
 	err := ctrl.SetControllerReference(owner, controlled, r.Scheme)
 	if err != nil {
@@ -1360,8 +1897,9 @@ func (r *MigrationPlanReconciler) reconcileMapping(ctx context.Context,
 	ctxlog.Info("Reconciled network", "vm", vm, "openstacknws", openstacknws)
 	ctxlog.Info("storage method", "vm", vm, "storage method", migrationtemplate.Spec.StorageCopyMethod)
 	// Skip storage mapping reconciliation for StorageCopyMethod storage copy method
-	// as it uses ArrayCredsMapping instead of StorageMapping
-	if migrationtemplate.Spec.StorageCopyMethod != StorageCopyMethod {
+	// as it uses ArrayCredsMapping instead of StorageMapping, and for file destinations
+	// which create no volumes
+	if migrationtemplate.Spec.StorageCopyMethod != StorageCopyMethod && migrationtemplate.Spec.Destination.File == nil {
 		openstackvolumetypes, err = r.reconcileStorage(ctx, migrationtemplate, vmwcreds, openstackcreds, vm, datacenter)
 		if err != nil {
 			return nil, nil, errors.Wrap(err, "failed to reconcile storage")
@@ -1418,7 +1956,8 @@ func (r *MigrationPlanReconciler) reconcileNetwork(ctx context.Context,
 		uniqueTargetList = append(uniqueTargetList, target)
 	}
 
-	if networkmap.Status.NetworkmappingValidationStatus != string(corev1.PodSucceeded) {
+	// The targets of a file destination are libvirt networks unknown to OpenStack
+	if networkmap.Status.NetworkmappingValidationStatus != string(corev1.PodSucceeded) && migrationtemplate.Spec.Destination.File == nil {
 		err = utils.VerifyNetworks(ctx, r.Client, openstackcreds, uniqueTargetList)
 		if err != nil {
 			return nil, errors.Wrap(err, "failed to verify networks")
@@ -1564,6 +2103,9 @@ func (r *MigrationPlanReconciler) TriggerMigration(ctx context.Context,
 		if err != nil {
 			return errors.Wrapf(err, "failed to create Firstboot ConfigMap for VM %s", vm)
 		}
+		if err = r.CreateMigrationCheckpointConfigMap(ctx, migrationplan, vmwcreds, vm); err != nil {
+			return errors.Wrapf(err, "failed to create checkpoint ConfigMap for VM %s", vm)
+		}
 		//nolint:gocritic // err is already declared above
 		if err = r.validateVDDKPresence(ctx, migrationobj, ctxlog); err != nil {
 			return err
@@ -1604,6 +2146,9 @@ func (r *MigrationPlanReconciler) TriggerMigration(ctx context.Context,
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *MigrationPlanReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.Scheduler == nil {
+		r.Scheduler = utils.NewMigrationScheduler()
+	}
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&vjailbreakv1alpha1.MigrationPlan{}).
 		Owns(&vjailbreakv1alpha1.Migration{}).
@@ -1911,11 +2456,12 @@ func (r *MigrationPlanReconciler) validateMigrationPlanVMs(
 ) ([]*vjailbreakv1alpha1.VMwareMachine, []*vjailbreakv1alpha1.VMwareMachine, error) {
 	var validVMs, skippedVMs []*vjailbreakv1alpha1.VMwareMachine
 
-	if len(migrationplan.Spec.VirtualMachines) == 0 {
+	vmGroups := utils.GetMigrationPlanVMGroups(migrationplan)
+	if len(vmGroups) == 0 {
 		return nil, nil, fmt.Errorf("no VMs to migrate in migration plan")
 	}
 
-	for _, vmGroup := range migrationplan.Spec.VirtualMachines {
+	for _, vmGroup := range vmGroups {
 		for _, vm := range vmGroup {
 			vmMachine, err := GetVMwareMachineForVM(ctx, r, vm, migrationtemplate, vmwcreds)
 			if err != nil {
@@ -1935,12 +2481,16 @@ func (r *MigrationPlanReconciler) validateMigrationPlanVMs(
 		}
 	}
 
+	skippedVMNames := make([]string, len(skippedVMs))
+	for i, vm := range skippedVMs {
+		skippedVMNames[i] = vm.Spec.VMInfo.Name
+	}
+	if err := utils.ValidateSkippedDependencies(migrationplan, skippedVMNames); err != nil {
+		return nil, skippedVMs, err
+	}
+
 	if len(validVMs) == 0 {
 		if len(skippedVMs) > 0 {
-			skippedVMNames := make([]string, len(skippedVMs))
-			for i, vm := range skippedVMs {
-				skippedVMNames[i] = vm.Spec.VMInfo.Name
-			}
 			msg := fmt.Sprintf("Skipped VMs due to unsupported or unknown OS: %v", skippedVMNames)
 			r.ctxlog.Info(msg)
 		}
@@ -1948,10 +2498,6 @@ func (r *MigrationPlanReconciler) validateMigrationPlanVMs(
 	}
 
 	if len(skippedVMs) > 0 {
-		skippedVMNames := make([]string, len(skippedVMs))
-		for i, vm := range skippedVMs {
-			skippedVMNames[i] = vm.Spec.VMInfo.Name
-		}
 		msg := fmt.Sprintf("Skipped VMs due to unsupported or unknown OS: %v", skippedVMNames)
 		r.ctxlog.Info(msg)
 		if updateErr := r.UpdateMigrationPlanStatus(ctx, migrationplan, corev1.PodPending, msg); updateErr != nil {
@@ -2046,6 +2592,87 @@ func (r *MigrationPlanReconciler) markMigrationValidationFailed(ctx context.Cont
 	}
 }
 
+// markMigrationScheduled records the placement of a migration in its Scheduled condition, a
+// migration without an agent is queued at queuePosition until its source and an agent have a free
+// slot
+func (r *MigrationPlanReconciler) markMigrationScheduled(ctx context.Context, migrationObj *vjailbreakv1alpha1.Migration,
+	agent, reason, message string, source *vjailbreakv1alpha1.MigrationSource, queuePosition int,
+) error {
+	condition := corev1.PodCondition{
+		Type:               constants.MigrationConditionTypeScheduled,
+		Status:             corev1.ConditionTrue,
+		Reason:             reason,
+		Message:            message,
+		LastTransitionTime: metav1.Now(),
+	}
+	if agent == "" {
+		condition.Status = corev1.ConditionFalse
+	}
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		latest := &vjailbreakv1alpha1.Migration{}
+		if err := r.Get(ctx, types.NamespacedName{Name: migrationObj.Name, Namespace: migrationObj.Namespace}, latest); err != nil {
+			return err
+		}
+		idx := utils.GetConditonIndex(latest.Status.Conditions, constants.MigrationConditionTypeScheduled,
+			constants.MigrationScheduledReason, constants.MigrationQueuedReason, constants.MigrationSourceLimitReason,
+			constants.MigrationMaintenanceWindowReason, constants.MigrationDependencyReason)
+		if idx == -1 {
+			latest.Status.Conditions = append(latest.Status.Conditions, condition)
+		} else {
+			if latest.Status.Conditions[idx].Reason == condition.Reason && latest.Status.Conditions[idx].Message == condition.Message {
+				// Keep the time the migration was first queued
+				condition.LastTransitionTime = latest.Status.Conditions[idx].LastTransitionTime
+			}
+			latest.Status.Conditions[idx] = condition
+		}
+		latest.Status.QueuePosition = queuePosition
+		// Migrations waiting for a slot of their source queue behind the older ones of the same source
+		latest.Status.Source = source
+		if agent != "" {
+			// The agent and the source hold the slot from now on, before the pod of the migration is scheduled
+			latest.Status.AgentName = agent
+		}
+		return r.Status().Update(ctx, latest)
+	})
+}
+
+// deleteJobOfLostAgent deletes the unfinished job of a migration whose agent node is lost. The
+// migration controller gives the slot of the lost agent back, the pod of the job is pinned to the
+// agent and would never run again. The migration is then placed on another agent and its new pod
+// resumes from the checkpoint.
+func (r *MigrationPlanReconciler) deleteJobOfLostAgent(ctx context.Context, migrationObj *vjailbreakv1alpha1.Migration, job *batchv1.Job) (bool, error) {
+	agent := jobAgent(job)
+	if agent == "" || migrationObj.Status.AgentName != "" || utils.IsMigrationFinished(migrationObj) ||
+		job.Status.Succeeded > 0 || job.Status.CompletionTime != nil {
+		return false, nil
+	}
+	lost, err := utils.IsAgentLost(ctx, r.Client, agent, time.Now())
+	if err != nil || !lost {
+		return false, err
+	}
+	r.ctxlog.Info(fmt.Sprintf("Agent '%s' of Job '%s' is lost, placing migration '%s' again", agent, job.Name, migrationObj.Name))
+	if err := r.Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil && !apierrors.IsNotFound(err) {
+		return false, errors.Wrapf(err, "failed to delete job '%s'", job.Name)
+	}
+	return true, nil
+}
+
+// jobAgent returns the agent node the pods of a migration job are pinned to
+func jobAgent(job *batchv1.Job) string {
+	affinity := job.Spec.Template.Spec.Affinity
+	if affinity == nil || affinity.NodeAffinity == nil || affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		return ""
+	}
+	for _, term := range affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms {
+		for _, field := range term.MatchFields {
+			if field.Key == "metadata.name" && len(field.Values) > 0 {
+				return field.Values[0]
+			}
+		}
+	}
+	return ""
+}
+
 // markMigrationFailed updates a Migration status to Failed
 func (r *MigrationPlanReconciler) markMigrationFailed(ctx context.Context, migrationObj *vjailbreakv1alpha1.Migration, message string) error {
 	condition := corev1.PodCondition{
@@ -2057,3 +2684,38 @@ func (r *MigrationPlanReconciler) markMigrationFailed(ctx context.Context, migra
 	}
 	return r.updateMigrationPhaseWithRetry(ctx, migrationObj, vjailbreakv1alpha1.VMMigrationPhaseFailed, condition, migrationObj.Name)
 }
+
+// migrationPodFailurePolicy returns the pod failure policy of migration jobs. Pods lost to evictions
+// or node shutdowns are replaced, and so are pods killed by the OOM killer until the backoff limit
+// of the job is reached. The new pod resumes disk replication from the checkpoint. Any other
+// failure of the v2v-helper fails the job.
+func migrationPodFailurePolicy() *batchv1.PodFailurePolicy {
+	return &batchv1.PodFailurePolicy{
+		Rules: []batchv1.PodFailurePolicyRule{
+			{
+				Action: batchv1.PodFailurePolicyActionIgnore,
+				OnPodConditions: []batchv1.PodFailurePolicyOnPodConditionsPattern{
+					{
+						Type:   corev1.DisruptionTarget,
+						Status: corev1.ConditionTrue,
+					},
+				},
+			},
+			{
+				// The rules are matched in order, an OOM kill must not reach the FailJob rule
+				Action: batchv1.PodFailurePolicyActionCount,
+				OnExitCodes: &batchv1.PodFailurePolicyOnExitCodesRequirement{
+					Values:   []int32{constants.OOMKilledExitCode},
+					Operator: batchv1.PodFailurePolicyOnExitCodesOpIn,
+				},
+			},
+			{
+				Action: batchv1.PodFailurePolicyActionFailJob,
+				OnExitCodes: &batchv1.PodFailurePolicyOnExitCodesRequirement{
+					Values:   []int32{0},
+					Operator: batchv1.PodFailurePolicyOnExitCodesOpNotIn,
+				},
+			},
+		},
+	}
+}
@@ -21,6 +21,8 @@ import (
 
 	"github.com/onsi/ginkgo/v2"
 	"github.com/onsi/gomega"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
@@ -80,3 +82,38 @@ var _ = ginkgo.Describe("MigrationPlan Controller", func() {
 		})
 	})
 })
+
+var _ = ginkgo.Describe("Migration job pod failure policy", func() {
+	// The rules are matched in order, the first matching rule decides
+	rules := migrationPodFailurePolicy().Rules
+
+	ginkgo.It("has a rule for disruptions, OOM kills and v2v-helper failures", func() {
+		gomega.Expect(rules).To(gomega.HaveLen(3))
+	})
+	ginkgo.It("replaces pods lost to disruptions", func() {
+		gomega.Expect(rules[0]).To(gomega.Equal(batchv1.PodFailurePolicyRule{
+			Action: batchv1.PodFailurePolicyActionIgnore,
+			OnPodConditions: []batchv1.PodFailurePolicyOnPodConditionsPattern{
+				{Type: corev1.DisruptionTarget, Status: corev1.ConditionTrue},
+			},
+		}))
+	})
+	ginkgo.It("retries pods killed by the OOM killer before failing the job", func() {
+		gomega.Expect(rules[1]).To(gomega.Equal(batchv1.PodFailurePolicyRule{
+			Action: batchv1.PodFailurePolicyActionCount,
+			OnExitCodes: &batchv1.PodFailurePolicyOnExitCodesRequirement{
+				Operator: batchv1.PodFailurePolicyOnExitCodesOpIn,
+				Values:   []int32{137},
+			},
+		}))
+	})
+	ginkgo.It("fails the job when the v2v-helper exits with any other error", func() {
+		gomega.Expect(rules[2]).To(gomega.Equal(batchv1.PodFailurePolicyRule{
+			Action: batchv1.PodFailurePolicyActionFailJob,
+			OnExitCodes: &batchv1.PodFailurePolicyOnExitCodesRequirement{
+				Operator: batchv1.PodFailurePolicyOnExitCodesOpNotIn,
+				Values:   []int32{0},
+			},
+		}))
+	})
+})
@@ -0,0 +1,315 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+	"github.com/platform9/vjailbreak/k8s/migration/pkg/constants"
+	"github.com/platform9/vjailbreak/k8s/migration/pkg/scope"
+	utils "github.com/platform9/vjailbreak/k8s/migration/pkg/utils"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// ReconcileFileSourcePlanJob runs the migrations of a plan whose template imports files. The virtual
+// machines of the plan are the names of the files, relative to the path or URL of the file source.
+// There is no VMwareMachine for them, the v2v-helper reads the VM description from the files.
+func (r *MigrationPlanReconciler) ReconcileFileSourcePlanJob(ctx context.Context,
+	migrationplan *vjailbreakv1alpha1.MigrationPlan,
+	migrationtemplate *vjailbreakv1alpha1.MigrationTemplate,
+	scope *scope.MigrationPlanScope) (ctrl.Result, error) {
+	if migrationtemplate.Spec.Source.VMwareRef != "" {
+		return ctrl.Result{}, errors.Errorf("MigrationTemplate '%s' sets both vmwareRef and file as source", migrationtemplate.Name)
+	}
+	err := utils.ValidateFileSource(migrationtemplate.Spec.Source.File)
+	if err == nil && migrationplan.Spec.VMSelector != nil {
+		err = errors.New("vmSelector selects VMs of a VMware source, it cannot be used with a file source")
+	}
+	if err != nil {
+		if updateErr := r.UpdateMigrationPlanStatus(ctx, migrationplan, corev1.PodFailed,
+			fmt.Sprintf("%s: %v", constants.MigrationPlanValidationFailedPrefix, err)); updateErr != nil {
+			r.ctxlog.Error(updateErr, "Failed to update migration plan status after validation failure")
+		}
+		return ctrl.Result{}, err
+	}
+
+	openstackcreds := &vjailbreakv1alpha1.OpenstackCreds{}
+	if ok, err := r.checkStatusSuccess(ctx, migrationtemplate.Namespace, migrationtemplate.Spec.Destination.OpenstackRef,
+		false, openstackcreds); !ok {
+		return ctrl.Result{}, errors.Wrapf(err, "failed to check openstackcreds status '%s'", migrationtemplate.Spec.Destination.OpenstackRef)
+	}
+
+	if migrationplan.Status.MigrationStatus == "" {
+		if err := r.UpdateMigrationPlanStatus(ctx, migrationplan, corev1.PodRunning, "Migration(s) in progress"); err != nil {
+			return ctrl.Result{}, errors.Wrap(err, "failed to update migration plan status")
+		}
+	}
+	if paused, err := r.checkAndHandlePausedPlan(ctx, migrationplan); paused {
+		return ctrl.Result{}, err
+	}
+
+	for _, parallelvms := range migrationplan.Spec.VirtualMachines {
+		migrationobjs := &vjailbreakv1alpha1.MigrationList{}
+		for _, file := range parallelvms {
+			migrationobj, err := r.TriggerFileMigration(ctx, migrationplan, migrationtemplate, openstackcreds, file)
+			if err != nil {
+				return ctrl.Result{}, errors.Wrapf(err, "failed to trigger migration of file %s", file)
+			}
+			migrationobjs.Items = append(migrationobjs.Items, *migrationobj)
+		}
+
+		allFinished, err := r.processMigrationPhases(ctx, scope, migrationplan, migrationobjs, parallelvms)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if !allFinished {
+			r.ctxlog.Info("Migration(s) still in progress, requeuing plan", "migrationplan", migrationplan.Name)
+			return ctrl.Result{RequeueAfter: 15 * time.Second}, nil
+		}
+	}
+
+	r.ctxlog.Info(fmt.Sprintf("All VMs in MigrationPlan '%s' have been successfully migrated", migrationplan.Name))
+	migrationplan.Status.MigrationStatus = corev1.PodSucceeded
+	migrationplan.Status.MigrationMessage = "All migrations completed successfully"
+	if err := r.Status().Update(ctx, migrationplan); err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "failed to update migration plan status")
+	}
+	return ctrl.Result{}, nil
+}
+
+// TriggerFileMigration creates the Migration, its config maps and the v2v-helper Job importing a file
+func (r *MigrationPlanReconciler) TriggerFileMigration(ctx context.Context,
+	migrationplan *vjailbreakv1alpha1.MigrationPlan,
+	migrationtemplate *vjailbreakv1alpha1.MigrationTemplate,
+	openstackcreds *vjailbreakv1alpha1.OpenstackCreds,
+	file string,
+) (*vjailbreakv1alpha1.Migration, error) {
+	migrationobj, err := r.CreateMigration(ctx, migrationplan, file, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create Migration for file %s", file)
+	}
+	if migrationobj.Status.Phase == vjailbreakv1alpha1.VMMigrationPhaseSucceeded {
+		return migrationobj, nil
+	}
+	if _, err := r.CreateFileMigrationConfigMap(ctx, migrationplan, migrationtemplate, migrationobj, openstackcreds, file); err != nil {
+		return nil, errors.Wrapf(err, "failed to create ConfigMap for file %s", file)
+	}
+	fbcm, err := r.CreateFirstbootConfigMap(ctx, migrationplan, migrationobj, file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create Firstboot ConfigMap for file %s", file)
+	}
+	if err := r.CreateJob(ctx, migrationplan, migrationtemplate, migrationobj, file, fbcm.Name, "",
+		openstackcreds.Spec.SecretRef.Name, nil, ""); err != nil {
+		return nil, errors.Wrapf(err, "failed to create Job for file %s", file)
+	}
+	return migrationobj, nil
+}
+
+// CreateFileMigrationConfigMap creates the config map of a migration importing a file. The networks
+// of the VM are only known once the v2v-helper read the file, so the whole NetworkMapping is passed
+// on. All disks get the volume type of the first entry of the StorageMapping, files have no datastores.
+func (r *MigrationPlanReconciler) CreateFileMigrationConfigMap(ctx context.Context,
+	migrationplan *vjailbreakv1alpha1.MigrationPlan,
+	migrationtemplate *vjailbreakv1alpha1.MigrationTemplate,
+	migrationobj *vjailbreakv1alpha1.Migration,
+	openstackcreds *vjailbreakv1alpha1.OpenstackCreds,
+	file string,
+) (*corev1.ConfigMap, error) {
+	vmname, err := utils.GetK8sCompatibleVMWareObjectName(file, utils.GetSourceName(migrationtemplate))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get vm name")
+	}
+	configMapName := utils.GetMigrationConfigMapName(vmname)
+	configMap := &corev1.ConfigMap{}
+	err = r.Get(ctx, types.NamespacedName{Name: configMapName, Namespace: migrationplan.Namespace}, configMap)
+	if err == nil {
+		return configMap, nil
+	}
+	if !apierrors.IsNotFound(err) {
+		return nil, errors.Wrapf(err, "failed to get config map '%s'", configMapName)
+	}
+
+	networkMapping, err := r.fileSourceNetworkMapping(ctx, migrationtemplate, openstackcreds)
+	if err != nil {
+		return nil, err
+	}
+	networkMappingJSON, err := json.Marshal(networkMapping)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal network mapping")
+	}
+	volumeType, err := r.fileSourceVolumeType(ctx, migrationtemplate, openstackcreds)
+	if err != nil {
+		return nil, err
+	}
+	openstacknws, openstackvolumetypes, openstackports := []string{}, []string{volumeType}, []string{}
+	advancedOptions := migrationplan.Spec.AdvancedOptions
+	if len(advancedOptions.GranularNetworks) > 0 {
+		if err := utils.VerifyNetworks(ctx, r.Client, openstackcreds, advancedOptions.GranularNetworks); err != nil {
+			return nil, errors.Wrap(err, "failed to verify networks in advanced mapping")
+		}
+		openstacknws = advancedOptions.GranularNetworks
+	}
+	if len(advancedOptions.GranularVolumeTypes) > 0 {
+		if err := utils.VerifyStorage(ctx, r.Client, openstackcreds, advancedOptions.GranularVolumeTypes); err != nil {
+			return nil, errors.Wrap(err, "failed to verify volume types in advanced mapping")
+		}
+		openstackvolumetypes = advancedOptions.GranularVolumeTypes
+	}
+	if len(advancedOptions.GranularPorts) > 0 {
+		if err := utils.VerifyPorts(ctx, r.Client, openstackcreds, advancedOptions.GranularPorts); err != nil {
+			return nil, errors.Wrap(err, "failed to verify ports in advanced mapping")
+		}
+		openstackports = advancedOptions.GranularPorts
+	}
+
+	virtiodrivers := migrationtemplate.Spec.VirtioWinDriver
+	if virtiodrivers == "" {
+		virtiodrivers = "https://fedorapeople.org/groups/virt/virtio-win/direct-downloads/stable-virtio/virtio-win.iso"
+	}
+	source := migrationtemplate.Spec.Source.File
+	sourcePath, sourceURL, err := fileSourceLocation(source, file)
+	if err != nil {
+		return nil, err
+	}
+
+	r.ctxlog.Info(fmt.Sprintf("Creating new ConfigMap '%s' for file '%s'", configMapName, file))
+	configMap = &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      configMapName,
+			Namespace: migrationplan.Namespace,
+		},
+		Data: map[string]string{
+			"SOURCE_VM_NAME":              file,
+			"SOURCE_FILE_PATH":            sourcePath,
+			"SOURCE_FILE_URL":             sourceURL,
+			"SOURCE_FILE_FORMAT":          source.Format,
+			"SOURCE_FILE_CPU":             strconv.Itoa(int(source.CPU)),
+			"SOURCE_FILE_MEMORY":          strconv.Itoa(int(source.MemoryMB)),
+			"NETWORK_MAPPING":             string(networkMappingJSON),
+			"CONVERT":                     "true",
+			"TYPE":                        migrationplan.Spec.MigrationStrategy.Type,
+			"DATACOPYSTART":               migrationplan.Spec.MigrationStrategy.DataCopyStart.Format(time.RFC3339),
+			"CUTOVERSTART":                migrationplan.Spec.MigrationStrategy.VMCutoverStart.Format(time.RFC3339),
+			"CUTOVEREND":                  migrationplan.Spec.MigrationStrategy.VMCutoverEnd.Format(time.RFC3339),
+			"DATA_COPY_WINDOW":            migrationplan.Spec.MigrationStrategy.DataCopyWindow,
+			"CUTOVER_WINDOW":              migrationplan.Spec.MigrationStrategy.CutoverWindow,
+			"NEUTRON_NETWORK_NAMES":       strings.Join(openstacknws, ","),
+			"NEUTRON_PORT_IDS":            strings.Join(openstackports, ","),
+			"CINDER_VOLUME_TYPES":         strings.Join(openstackvolumetypes, ","),
+			"VIRTIO_WIN_DRIVER":           virtiodrivers,
+			"OS_FAMILY":                   migrationtemplate.Spec.OSFamily,
+			"PERFORM_HEALTH_CHECKS":       strconv.FormatBool(migrationplan.Spec.MigrationStrategy.PerformHealthChecks),
+			"HEALTH_CHECK_PORT":           migrationplan.Spec.MigrationStrategy.HealthCheckPort,
+			"HEALTH_CHECK_FAILURE_POLICY": migrationplan.Spec.MigrationStrategy.HealthCheckFailurePolicy,
+			"VMWARE_MACHINE_OBJECT_NAME":  vmname,
+			"SECURITY_GROUPS":             strings.Join(migrationplan.Spec.SecurityGroups, ","),
+			"SERVER_GROUP":                migrationplan.Spec.ServerGroup,
+			"FALLBACK_TO_DHCP":            strconv.FormatBool(migrationplan.Spec.FallbackToDHCP),
+			"ASSIGNED_IP":                 migrationobj.Spec.AssignedIP,
+		},
+	}
+	if utils.IsOpenstackPCD(*openstackcreds) {
+		configMap.Data["TARGET_AVAILABILITY_ZONE"] = migrationtemplate.Spec.TargetPCDClusterName
+	}
+	if healthChecks := migrationplan.Spec.MigrationStrategy.HealthChecks; len(healthChecks) > 0 {
+		healthChecksJSON, err := json.Marshal(healthChecks)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to marshal health checks")
+		}
+		configMap.Data["HEALTH_CHECKS"] = string(healthChecksJSON)
+	}
+	if len(migrationplan.Spec.Hooks) > 0 {
+		hooksJSON, err := json.Marshal(migrationplan.Spec.Hooks)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to marshal hooks")
+		}
+		configMap.Data["HOOKS"] = string(hooksJSON)
+	}
+	setFileDestinationConfig(configMap, migrationtemplate)
+	if err := r.createResource(ctx, migrationobj, configMap); err != nil {
+		r.ctxlog.Error(err, fmt.Sprintf("Failed to create ConfigMap '%s'", configMapName))
+		return nil, errors.Wrapf(err, "failed to create config map '%s'", configMapName)
+	}
+	return configMap, nil
+}
+
+// fileSourceNetworkMapping returns the source to target networks of the NetworkMapping of the template
+func (r *MigrationPlanReconciler) fileSourceNetworkMapping(ctx context.Context,
+	migrationtemplate *vjailbreakv1alpha1.MigrationTemplate,
+	openstackcreds *vjailbreakv1alpha1.OpenstackCreds,
+) (map[string]string, error) {
+	networkmap := &vjailbreakv1alpha1.NetworkMapping{}
+	if err := r.Get(ctx, types.NamespacedName{Name: migrationtemplate.Spec.NetworkMapping, Namespace: migrationtemplate.Namespace}, networkmap); err != nil {
+		return nil, errors.Wrap(err, "failed to retrieve NetworkMapping CR")
+	}
+	if len(networkmap.Spec.Networks) == 0 {
+		return nil, errors.Errorf("NetworkMapping '%s' has no networks", networkmap.Name)
+	}
+	mapping := make(map[string]string, len(networkmap.Spec.Networks))
+	targets := []string{}
+	for _, network := range networkmap.Spec.Networks {
+		if _, ok := mapping[network.Source]; !ok {
+			mapping[network.Source] = network.Target
+			targets = append(targets, network.Target)
+		}
+	}
+	// The targets of a file destination are libvirt networks unknown to OpenStack
+	if networkmap.Status.NetworkmappingValidationStatus != string(corev1.PodSucceeded) && migrationtemplate.Spec.Destination.File == nil {
+		if err := utils.VerifyNetworks(ctx, r.Client, openstackcreds, targets); err != nil {
+			return nil, errors.Wrap(err, "failed to verify networks")
+		}
+	}
+	return mapping, nil
+}
+
+// fileSourceVolumeType returns the volume type of the first entry of the StorageMapping of the template,
+// empty for file destinations which create no volumes
+func (r *MigrationPlanReconciler) fileSourceVolumeType(ctx context.Context,
+	migrationtemplate *vjailbreakv1alpha1.MigrationTemplate,
+	openstackcreds *vjailbreakv1alpha1.OpenstackCreds,
+) (string, error) {
+	if migrationtemplate.Spec.Destination.File != nil {
+		return "", nil
+	}
+	storagemap := &vjailbreakv1alpha1.StorageMapping{}
+	if err := r.Get(ctx, types.NamespacedName{Name: migrationtemplate.Spec.StorageMapping, Namespace: migrationtemplate.Namespace}, storagemap); err != nil {
+		return "", errors.Wrap(err, "failed to retrieve StorageMapping CR")
+	}
+	if len(storagemap.Spec.Storages) == 0 {
+		return "", errors.Errorf("StorageMapping '%s' has no storages", storagemap.Name)
+	}
+	volumeType := storagemap.Spec.Storages[0].Target
+	if storagemap.Status.StoragemappingValidationStatus != string(corev1.PodSucceeded) {
+		if err := utils.VerifyStorage(ctx, r.Client, openstackcreds, []string{volumeType}); err != nil {
+			return "", errors.Wrap(err, "failed to verify volume type")
+		}
+	}
+	return volumeType, nil
+}
+
+// fileSourceLocation returns the path of a file in the migration pod or its URL
+func fileSourceLocation(source *vjailbreakv1alpha1.MigrationTemplateFileSource, file string) (sourcePath, sourceURL string, err error) {
+	if source.PVCName != "" {
+		sourcePath = path.Join(constants.FileSourceMountPath, source.Path, file)
+		if !strings.HasPrefix(sourcePath, constants.FileSourceMountPath+"/") {
+			return "", "", errors.Errorf("file %s is outside of the file source", file)
+		}
+		return sourcePath, "", nil
+	}
+	sourceURL, err = url.JoinPath(source.URL, file)
+	if err != nil {
+		return "", "", errors.Wrapf(err, "failed to build url of file %s", file)
+	}
+	return "", sourceURL, nil
+}
@@ -0,0 +1,600 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"github.com/gophercloud/gophercloud/v2/openstack/compute/v2/flavors"
+	"github.com/pkg/errors"
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+	"github.com/platform9/vjailbreak/k8s/migration/pkg/constants"
+	utils "github.com/platform9/vjailbreak/k8s/migration/pkg/utils"
+	openstackpkg "github.com/platform9/vjailbreak/pkg/common/openstack"
+	"github.com/platform9/vjailbreak/v2v-helper/pkg/k8sutils"
+	govmomitypes "github.com/vmware/govmomi/vim25/types"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Names of the preflight checks
+const (
+	PreflightCheckVDDK            = "VDDK"
+	PreflightCheckOpenStack       = "OpenStack"
+	PreflightCheckVMwareMachine   = "VMwareMachine"
+	PreflightCheckOS              = "OS"
+	PreflightCheckNetworkMapping  = "NetworkMapping"
+	PreflightCheckStorageMapping  = "StorageMapping"
+	PreflightCheckFlavor          = "Flavor"
+	PreflightCheckIPAddress       = "IPAddress"
+	PreflightCheckDatastoreSpace  = "DatastoreSpace"
+	PreflightCheckSnapshots       = "Snapshots"
+	PreflightCheckRDMDisks        = "RDMDisks"
+	preflightSnapshotSpaceRatio   = 0.1
+	preflightBytesPerGB           = 1024 * 1024 * 1024
+	preflightOpenStackUnavailable = "OpenStack is not reachable, see the OpenStack check"
+)
+
+// MigrationPreflightReconciler reconciles a MigrationPreflight object
+type MigrationPreflightReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+// +kubebuilder:rbac:groups=vjailbreak.k8s.pf9.io,resources=migrationpreflights,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=vjailbreak.k8s.pf9.io,resources=migrationpreflights/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=vjailbreak.k8s.pf9.io,resources=migrationpreflights/finalizers,verbs=update
+
+// Reconcile runs the checks of a MigrationPreflight once per generation of its spec and reports
+// their results in its status. The checks read the migration plan, template, mappings, vCenter
+// and OpenStack but create or change nothing.
+func (r *MigrationPreflightReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	ctxlog := log.FromContext(ctx).WithName(constants.MigrationPreflightControllerName)
+	ctxlog.Info(fmt.Sprintf("Reconciling MigrationPreflight '%s'", req.Name))
+
+	preflight := &vjailbreakv1alpha1.MigrationPreflight{}
+	if err := r.Get(ctx, req.NamespacedName, preflight); err != nil {
+		if apierrors.IsNotFound(err) {
+			ctxlog.Info("Received ignorable event for a recently deleted MigrationPreflight.")
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, errors.Wrapf(err, "failed to read MigrationPreflight '%s'", req.Name)
+	}
+
+	if preflight.Status.ObservedGeneration == preflight.Generation &&
+		(preflight.Status.Phase == vjailbreakv1alpha1.MigrationPreflightPhaseCompleted ||
+			preflight.Status.Phase == vjailbreakv1alpha1.MigrationPreflightPhaseFailed) {
+		return ctrl.Result{}, nil
+	}
+
+	preflight.Status = vjailbreakv1alpha1.MigrationPreflightStatus{
+		Phase:              vjailbreakv1alpha1.MigrationPreflightPhaseRunning,
+		Message:            "Running preflight checks",
+		ObservedGeneration: preflight.Generation,
+	}
+	if err := r.Status().Update(ctx, preflight); err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "failed to update MigrationPreflight status")
+	}
+
+	checker, err := r.newPreflightChecker(ctx, ctxlog, preflight)
+	if err != nil {
+		ctxlog.Error(err, "Failed to prepare preflight checks")
+		preflight.Status.Phase = vjailbreakv1alpha1.MigrationPreflightPhaseFailed
+		preflight.Status.Result = vjailbreakv1alpha1.PreflightResultFail
+		preflight.Status.Message = err.Error()
+	} else {
+		preflight.Status.Checks = checker.checkPlan(ctx)
+		preflight.Status.Result = worstPreflightResult(preflight.Status.Checks)
+		goVMs := 0
+		for _, vm := range checker.vms {
+			vmResult := checker.checkVM(ctx, vm)
+			preflight.Status.VMs = append(preflight.Status.VMs, vmResult)
+			preflight.Status.Result = worsePreflightResult(preflight.Status.Result, vmResult.Result)
+			if vmResult.Result != vjailbreakv1alpha1.PreflightResultFail {
+				goVMs++
+			}
+		}
+		preflight.Status.Phase = vjailbreakv1alpha1.MigrationPreflightPhaseCompleted
+		preflight.Status.Message = fmt.Sprintf("%d of %d VMs passed the preflight checks", goVMs, len(checker.vms))
+	}
+	now := metav1.Now()
+	preflight.Status.CompletionTime = &now
+	if err := r.Status().Update(ctx, preflight); err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "failed to update MigrationPreflight status")
+	}
+	return ctrl.Result{}, nil
+}
+
+// preflightChecker runs the checks of a preflight with the template and credentials of the
+// migration plan it stands in for
+type preflightChecker struct {
+	client.Client
+	// planr reuses the lookups of the migration plan controller
+	planr          *MigrationPlanReconciler
+	namespace      string
+	vms            []string
+	template       *vjailbreakv1alpha1.MigrationTemplate
+	vmwcreds       *vjailbreakv1alpha1.VMwareCreds
+	openstackcreds *vjailbreakv1alpha1.OpenstackCreds
+
+	networkmap       *vjailbreakv1alpha1.NetworkMapping
+	storagemap       *vjailbreakv1alpha1.StorageMapping
+	openstackClients *utils.OpenStackClients
+	flavors          []flavors.Flavor
+	baseFlavor       *flavors.Flavor
+	// ipOwners maps the IPs checked so far to their VM, an IP claimed twice fails the later VM
+	ipOwners map[string]string
+	// datastoreFreeSpace and snapshotInfo read the datastores and the VMs of the VMware credentials
+	datastoreFreeSpace func(ctx context.Context, datacenter string, datastores []string) (map[string]int64, error)
+	snapshotInfo       func(ctx context.Context, datacenter, vm string) (int, []string, error)
+}
+
+// newPreflightChecker resolves the VMs and template of a preflight, from its migration plan when set
+func (r *MigrationPreflightReconciler) newPreflightChecker(ctx context.Context, ctxlog logr.Logger,
+	preflight *vjailbreakv1alpha1.MigrationPreflight,
+) (*preflightChecker, error) {
+	checker := &preflightChecker{
+		Client:    r.Client,
+		planr:     &MigrationPlanReconciler{Client: r.Client, Scheme: r.Scheme, ctxlog: ctxlog},
+		namespace: preflight.Namespace,
+		ipOwners:  map[string]string{},
+	}
+
+	templateName := preflight.Spec.MigrationTemplate
+	checker.vms = preflight.Spec.VirtualMachines
+	if preflight.Spec.MigrationPlan != "" {
+		migrationplan := &vjailbreakv1alpha1.MigrationPlan{}
+		if err := r.Get(ctx, types.NamespacedName{Name: preflight.Spec.MigrationPlan, Namespace: preflight.Namespace}, migrationplan); err != nil {
+			return nil, errors.Wrapf(err, "failed to get MigrationPlan '%s'", preflight.Spec.MigrationPlan)
+		}
+		templateName = migrationplan.Spec.MigrationTemplate
+		checker.vms = nil
+		for _, vmGroup := range utils.GetMigrationPlanVMGroups(migrationplan) {
+			checker.vms = append(checker.vms, vmGroup...)
+		}
+	}
+	if templateName == "" || len(checker.vms) == 0 {
+		return nil, errors.New("either migrationPlan, or migrationTemplate and virtualMachines must be set")
+	}
+
+	checker.template = &vjailbreakv1alpha1.MigrationTemplate{}
+	if err := r.Get(ctx, types.NamespacedName{Name: templateName, Namespace: preflight.Namespace}, checker.template); err != nil {
+		return nil, errors.Wrapf(err, "failed to get MigrationTemplate '%s'", templateName)
+	}
+	if checker.template.Spec.Source.File != nil {
+		return nil, errors.New("preflight checks are only supported for VMs migrated from VMware")
+	}
+
+	checker.vmwcreds = &vjailbreakv1alpha1.VMwareCreds{}
+	if ok, err := checker.planr.checkStatusSuccess(ctx, preflight.Namespace, checker.template.Spec.Source.VMwareRef, true, checker.vmwcreds); !ok {
+		return nil, errors.Wrap(err, "VMwareCreds not validated")
+	}
+	checker.datastoreFreeSpace = func(ctx context.Context, datacenter string, datastores []string) (map[string]int64, error) {
+		return utils.GetVMwDatastoreFreeSpace(ctx, r.Client, checker.vmwcreds, datacenter, datastores)
+	}
+	checker.snapshotInfo = func(ctx context.Context, datacenter, vm string) (int, []string, error) {
+		return utils.GetVMwSnapshotInfo(ctx, r.Client, checker.vmwcreds, datacenter, vm)
+	}
+	checker.openstackcreds = &vjailbreakv1alpha1.OpenstackCreds{}
+	if ok, err := checker.planr.checkStatusSuccess(ctx, preflight.Namespace, checker.template.Spec.Destination.OpenstackRef, false, checker.openstackcreds); !ok {
+		return nil, errors.Wrap(err, "OpenstackCreds not validated")
+	}
+	return checker, nil
+}
+
+// checkPlan runs the checks that apply to all VMs and loads what the checks of the VMs share
+func (c *preflightChecker) checkPlan(ctx context.Context) []vjailbreakv1alpha1.PreflightCheck {
+	checks := []vjailbreakv1alpha1.PreflightCheck{c.checkVDDK()}
+	fileDestination := c.template.Spec.Destination.File != nil
+
+	if !fileDestination {
+		clients, err := utils.GetOpenStackClients(ctx, c.Client, c.openstackcreds)
+		if err != nil {
+			checks = append(checks, preflightFail(PreflightCheckOpenStack, err.Error()))
+		} else {
+			c.openstackClients = clients
+			checks = append(checks, preflightPass(PreflightCheckOpenStack, "OpenStack is reachable"))
+		}
+	}
+
+	c.networkmap = &vjailbreakv1alpha1.NetworkMapping{}
+	if err := c.Get(ctx, types.NamespacedName{Name: c.template.Spec.NetworkMapping, Namespace: c.namespace}, c.networkmap); err != nil {
+		c.networkmap = nil
+		checks = append(checks, preflightFail(PreflightCheckNetworkMapping, fmt.Sprintf("failed to get NetworkMapping: %s", err)))
+	} else if !fileDestination {
+		// The targets of a file destination are libvirt networks unknown to OpenStack
+		targets := []string{}
+		for _, network := range c.networkmap.Spec.Networks {
+			if !slices.Contains(targets, network.Target) {
+				targets = append(targets, network.Target)
+			}
+		}
+		checks = append(checks, c.verifyTargets(ctx, PreflightCheckNetworkMapping, targets, utils.VerifyNetworks))
+	}
+
+	// Storage accelerated copy maps datastores with ArrayCredsMapping and file destinations
+	// create no volumes
+	if c.template.Spec.StorageCopyMethod != StorageCopyMethod && !fileDestination {
+		c.storagemap = &vjailbreakv1alpha1.StorageMapping{}
+		if err := c.Get(ctx, types.NamespacedName{Name: c.template.Spec.StorageMapping, Namespace: c.namespace}, c.storagemap); err != nil {
+			c.storagemap = nil
+			checks = append(checks, preflightFail(PreflightCheckStorageMapping, fmt.Sprintf("failed to get StorageMapping: %s", err)))
+		} else {
+			targets := []string{}
+			for _, storage := range c.storagemap.Spec.Storages {
+				if !slices.Contains(targets, storage.Target) {
+					targets = append(targets, storage.Target)
+				}
+			}
+			checks = append(checks, c.verifyTargets(ctx, PreflightCheckStorageMapping, targets, utils.VerifyStorage))
+		}
+	}
+
+	if c.openstackClients != nil {
+		var err error
+		if c.template.Spec.UseFlavorless {
+			c.baseFlavor, err = utils.FindHotplugBaseFlavor(c.openstackClients.ComputeClient)
+			if err != nil {
+				checks = append(checks, preflightFail(PreflightCheckFlavor, fmt.Sprintf("failed to discover base flavor for flavorless migration: %s", err)))
+			}
+		} else {
+			c.flavors, err = utils.ListAllFlavors(ctx, c.Client, c.openstackcreds)
+			if err != nil {
+				checks = append(checks, preflightFail(PreflightCheckFlavor, fmt.Sprintf("failed to list flavors: %s", err)))
+			}
+		}
+	}
+	return checks
+}
+
+// checkVDDK checks the directory the migration plan controller requires before creating a migration job
+func (c *preflightChecker) checkVDDK() vjailbreakv1alpha1.PreflightCheck {
+	files, err := os.ReadDir(VDDKDirectory)
+	if err != nil {
+		return preflightFail(PreflightCheckVDDK, "VDDK directory is missing. Please create and upload the required files.")
+	}
+	if len(files) == 0 {
+		return preflightFail(PreflightCheckVDDK, "VDDK directory is empty. Please upload the required files.")
+	}
+	return preflightPass(PreflightCheckVDDK, "VDDK is present")
+}
+
+// verifyTargets checks that the targets of a mapping exist in OpenStack
+func (c *preflightChecker) verifyTargets(ctx context.Context, name string, targets []string,
+	verify func(context.Context, client.Client, *vjailbreakv1alpha1.OpenstackCreds, []string) error,
+) vjailbreakv1alpha1.PreflightCheck {
+	if c.openstackClients == nil {
+		return preflightWarn(name, preflightOpenStackUnavailable)
+	}
+	if err := verify(ctx, c.Client, c.openstackcreds, targets); err != nil {
+		return preflightFail(name, err.Error())
+	}
+	return preflightPass(name, fmt.Sprintf("targets %v exist in OpenStack", targets))
+}
+
+// checkVM runs the checks of a VM
+func (c *preflightChecker) checkVM(ctx context.Context, vm string) vjailbreakv1alpha1.VMPreflightResult {
+	result := vjailbreakv1alpha1.VMPreflightResult{Name: vm}
+	defer func() {
+		result.Result = worstPreflightResult(result.Checks)
+	}()
+
+	vmMachine, err := GetVMwareMachineForVM(ctx, c.planr, vm, c.template, c.vmwcreds)
+	if err != nil {
+		result.Checks = append(result.Checks, preflightFail(PreflightCheckVMwareMachine, err.Error()))
+		return result
+	}
+	result.Checks = append(result.Checks, c.checkOS(vmMachine))
+
+	datacenter, err := c.planr.getDatacenterForVM(ctx, vm, c.vmwcreds, c.template)
+	if err != nil {
+		result.Checks = append(result.Checks, preflightFail(PreflightCheckVMwareMachine, err.Error()))
+		return result
+	}
+	result.Checks = append(result.Checks, c.checkNetworkMapping(ctx, vm, datacenter))
+	if c.storagemap != nil {
+		result.Checks = append(result.Checks, c.checkStorageMapping(ctx, vm, datacenter))
+	}
+	if c.template.Spec.Destination.File == nil {
+		result.Checks = append(result.Checks, c.checkFlavor(vmMachine), c.checkIPAddresses(ctx, vmMachine))
+	}
+	result.Checks = append(result.Checks, c.checkDatastoreSpace(ctx, vmMachine, datacenter), c.checkSnapshots(ctx, vm, datacenter))
+	if len(vmMachine.Spec.VMInfo.RDMDisks) > 0 {
+		result.Checks = append(result.Checks, c.checkRDMDisks(ctx, vmMachine))
+	}
+	return result
+}
+
+func (c *preflightChecker) checkOS(vmMachine *vjailbreakv1alpha1.VMwareMachine) vjailbreakv1alpha1.PreflightCheck {
+	_, skipped, err := c.planr.validateVMOS(vmMachine)
+	if err != nil {
+		return preflightFail(PreflightCheckOS, err.Error())
+	}
+	if skipped {
+		return preflightWarn(PreflightCheckOS, "OS of the VM is unknown, the migration plan skips the VM")
+	}
+	return preflightPass(PreflightCheckOS, fmt.Sprintf("OS family %s is supported", vmMachine.Spec.VMInfo.OSFamily))
+}
+
+func (c *preflightChecker) checkNetworkMapping(ctx context.Context, vm, datacenter string) vjailbreakv1alpha1.PreflightCheck {
+	if c.networkmap == nil {
+		return preflightFail(PreflightCheckNetworkMapping, "NetworkMapping is not available")
+	}
+	vmnws, err := utils.GetVMwNetworks(ctx, c.Client, c.vmwcreds, datacenter, vm)
+	if err != nil {
+		return preflightFail(PreflightCheckNetworkMapping, fmt.Sprintf("failed to get networks of the VM: %s", err))
+	}
+	missing := []string{}
+	for _, vmnw := range vmnws {
+		found := false
+		for _, nwm := range c.networkmap.Spec.Networks {
+			if vmnw == nwm.Source {
+				found = true
+				break
+			}
+		}
+		if !found && !slices.Contains(missing, vmnw) {
+			missing = append(missing, vmnw)
+		}
+	}
+	if len(missing) > 0 {
+		return preflightFail(PreflightCheckNetworkMapping, fmt.Sprintf("VMware networks %v not found in NetworkMapping", missing))
+	}
+	return preflightPass(PreflightCheckNetworkMapping, fmt.Sprintf("all %d networks are mapped", len(vmnws)))
+}
+
+func (c *preflightChecker) checkStorageMapping(ctx context.Context, vm, datacenter string) vjailbreakv1alpha1.PreflightCheck {
+	vmds, err := utils.GetVMwDatastore(ctx, c.Client, c.vmwcreds, datacenter, vm)
+	if err != nil {
+		return preflightFail(PreflightCheckStorageMapping, fmt.Sprintf("failed to get datastores of the VM: %s", err))
+	}
+	missing := []string{}
+	for _, vmdatastore := range vmds {
+		found := false
+		for _, storagemaptype := range c.storagemap.Spec.Storages {
+			if vmdatastore == storagemaptype.Source {
+				found = true
+				break
+			}
+		}
+		if !found && !slices.Contains(missing, vmdatastore) {
+			missing = append(missing, vmdatastore)
+		}
+	}
+	if len(missing) > 0 {
+		return preflightFail(PreflightCheckStorageMapping, fmt.Sprintf("VMware datastores %v not found in StorageMapping", missing))
+	}
+	return preflightPass(PreflightCheckStorageMapping, fmt.Sprintf("all %d disks are on mapped datastores", len(vmds)))
+}
+
+// checkFlavor resolves the flavor the migration would create the VM with
+func (c *preflightChecker) checkFlavor(vmMachine *vjailbreakv1alpha1.VMwareMachine) vjailbreakv1alpha1.PreflightCheck {
+	vminfo := vmMachine.Spec.VMInfo
+	switch {
+	case c.openstackClients == nil:
+		return preflightWarn(PreflightCheckFlavor, preflightOpenStackUnavailable)
+	case c.template.Spec.UseFlavorless:
+		if c.baseFlavor == nil {
+			return preflightFail(PreflightCheckFlavor, "no base flavor found for flavorless migration")
+		}
+		return preflightPass(PreflightCheckFlavor, fmt.Sprintf("flavorless migration with base flavor %s", c.baseFlavor.Name))
+	case vmMachine.Spec.TargetFlavorID != "":
+		return preflightPass(PreflightCheckFlavor, fmt.Sprintf("flavor %s is set on the VM", vmMachine.Spec.TargetFlavorID))
+	case c.flavors == nil:
+		return preflightWarn(PreflightCheckFlavor, "flavors of OpenStack are not available")
+	}
+
+	// UseGPUFlavor is only applicable for PCD credentials
+	useGPUFlavor := c.template.Spec.UseGPUFlavor && utils.IsOpenstackPCD(*c.openstackcreds)
+	flavor, err := openstackpkg.GetClosestFlavour(vminfo.CPU, vminfo.Memory, vminfo.GPU.PassthroughCount, vminfo.GPU.VGPUCount, c.flavors, useGPUFlavor)
+	if err != nil {
+		return preflightFail(PreflightCheckFlavor, fmt.Sprintf("failed to get closest flavor: %s", err))
+	}
+	if flavor == nil {
+		return preflightFail(PreflightCheckFlavor, fmt.Sprintf("no suitable flavor found for %d vCPUs, %d MB RAM, %d passthrough GPU(s) and %d vGPU(s)",
+			vminfo.CPU, vminfo.Memory, vminfo.GPU.PassthroughCount, vminfo.GPU.VGPUCount))
+	}
+	return preflightPass(PreflightCheckFlavor, fmt.Sprintf("closest flavor is %s", flavor.Name))
+}
+
+// checkIPAddresses checks that the IPs the VM keeps are free in OpenStack and claimed by no
+// other VM of the preflight, and that the network of each IPv6 address has a subnet for it
+func (c *preflightChecker) checkIPAddresses(ctx context.Context, vmMachine *vjailbreakv1alpha1.VMwareMachine) vjailbreakv1alpha1.PreflightCheck {
+	if c.openstackClients == nil {
+		return preflightWarn(PreflightCheckIPAddress, preflightOpenStackUnavailable)
+	}
+	vm := vmMachine.Spec.VMInfo.Name
+	ips := []string{}
+	for _, nic := range vmMachine.Spec.VMInfo.NetworkInterfaces {
+		for _, ip := range strings.Split(nic.IPAddress, ",") {
+			if ip = strings.TrimSpace(ip); ip != "" {
+				ips = append(ips, ip)
+			}
+		}
+	}
+	if len(ips) == 0 {
+		return preflightPass(PreflightCheckIPAddress, "VM has no IP addresses to keep")
+	}
+
+	problems := []string{}
+	if c.networkmap != nil {
+		ipv6Problems, err := utils.CheckIPv6Subnets(ctx, c.openstackClients.NetworkingClient, vmMachine, c.networkmap)
+		if err != nil {
+			return preflightWarn(PreflightCheckIPAddress, err.Error())
+		}
+		problems = append(problems, ipv6Problems...)
+	}
+	for _, ip := range ips {
+		if owner, ok := c.ipOwners[ip]; ok && owner != vm {
+			problems = append(problems, fmt.Sprintf("IP %s is also used by VM %s", ip, owner))
+			continue
+		}
+		c.ipOwners[ip] = vm
+		inUse, reason, err := utils.IsIPInUse(ctx, c.openstackClients.NetworkingClient, ip)
+		if err != nil {
+			return preflightWarn(PreflightCheckIPAddress, err.Error())
+		}
+		if inUse {
+			problems = append(problems, reason)
+		}
+	}
+	if len(problems) > 0 {
+		return preflightFail(PreflightCheckIPAddress, strings.Join(problems, "; "))
+	}
+	return preflightPass(PreflightCheckIPAddress, fmt.Sprintf("IPs %v are available", ips))
+}
+
+// checkDatastoreSpace checks that the datastores of the VM have room for the delta disks of the
+// migration snapshot, which grow with the writes to the VM while its disks are copied
+func (c *preflightChecker) checkDatastoreSpace(ctx context.Context, vmMachine *vjailbreakv1alpha1.VMwareMachine, datacenter string) vjailbreakv1alpha1.PreflightCheck {
+	capacityGB := map[string]int{}
+	for _, disk := range vmMachine.Spec.VMInfo.Disks {
+		if disk.Datastore != "" {
+			capacityGB[disk.Datastore] += disk.CapacityGB
+		}
+	}
+	datastores := make([]string, 0, len(capacityGB))
+	for datastore := range capacityGB {
+		datastores = append(datastores, datastore)
+	}
+	sort.Strings(datastores)
+	if len(datastores) == 0 {
+		return preflightPass(PreflightCheckDatastoreSpace, "VM has no disks on datastores")
+	}
+
+	freeSpace, err := c.datastoreFreeSpace(ctx, datacenter, datastores)
+	if err != nil {
+		return preflightWarn(PreflightCheckDatastoreSpace, fmt.Sprintf("failed to get datastore free space: %s", err))
+	}
+	low := []string{}
+	for _, datastore := range datastores {
+		needed := int64(float64(capacityGB[datastore]) * preflightSnapshotSpaceRatio * preflightBytesPerGB)
+		if freeSpace[datastore] < needed {
+			low = append(low, fmt.Sprintf("%s has %d GB free, %d GB recommended",
+				datastore, freeSpace[datastore]/preflightBytesPerGB, needed/preflightBytesPerGB))
+		}
+	}
+	if len(low) > 0 {
+		return preflightWarn(PreflightCheckDatastoreSpace, strings.Join(low, "; "))
+	}
+	return preflightPass(PreflightCheckDatastoreSpace, "datastores have room for the migration snapshot")
+}
+
+// checkSnapshots checks for snapshots of the VM, which slow down the copy, and for independent
+// disks, which snapshots and changed block tracking leave out
+func (c *preflightChecker) checkSnapshots(ctx context.Context, vm, datacenter string) vjailbreakv1alpha1.PreflightCheck {
+	snapshots, independentDisks, err := c.snapshotInfo(ctx, datacenter, vm)
+	if err != nil {
+		return preflightWarn(PreflightCheckSnapshots, fmt.Sprintf("failed to get snapshots of the VM: %s", err))
+	}
+	if len(independentDisks) > 0 {
+		return preflightFail(PreflightCheckSnapshots, fmt.Sprintf("independent disks %v cannot be snapshotted for the copy", independentDisks))
+	}
+	if snapshots > 0 {
+		return preflightWarn(PreflightCheckSnapshots, fmt.Sprintf("VM has %d existing snapshot(s), consolidating them speeds up the copy", snapshots))
+	}
+	return preflightPass(PreflightCheckSnapshots, "VM has no snapshots")
+}
+
+// checkRDMDisks runs the checks the migration plan controller runs before importing the RDM
+// disks of the VM to Cinder
+func (c *preflightChecker) checkRDMDisks(ctx context.Context, vmMachine *vjailbreakv1alpha1.VMwareMachine) vjailbreakv1alpha1.PreflightCheck {
+	if vmMachine.Status.PowerState != string(govmomitypes.VirtualMachineGuestStateNotRunning) {
+		return preflightFail(PreflightCheckRDMDisks, "VM is not powered off, RDM disks are only migrated from powered off VMs")
+	}
+	validateOwners := constants.ValidateRDMOwnerVMs
+	if vjailbreakSettings, err := k8sutils.GetVjailbreakSettings(ctx, c.Client); err == nil {
+		validateOwners = vjailbreakSettings.ValidateRDMOwnerVMs
+	}
+
+	warnings := []string{}
+	for _, rdmDisk := range vmMachine.Spec.VMInfo.RDMDisks {
+		rdmDiskCR := &vjailbreakv1alpha1.RDMDisk{}
+		if err := c.Get(ctx, types.NamespacedName{Name: strings.TrimSpace(rdmDisk), Namespace: c.namespace}, rdmDiskCR); err != nil {
+			return preflightFail(PreflightCheckRDMDisks, fmt.Sprintf("failed to get RDMDisk %s: %s", rdmDisk, err))
+		}
+		for _, ownerVM := range rdmDiskCR.Spec.OwnerVMs {
+			if slices.Contains(c.vms, ownerVM) {
+				continue
+			}
+			msg := fmt.Sprintf("ownerVM %q in RDM disk %s not found in migration plan", ownerVM, rdmDisk)
+			if validateOwners {
+				return preflightFail(PreflightCheckRDMDisks, msg)
+			}
+			warnings = append(warnings, msg)
+		}
+		if err := ValidateRDMDiskFields(rdmDiskCR); err != nil {
+			return preflightFail(PreflightCheckRDMDisks, fmt.Sprintf("RDMDisk %s is not ready for import: %s", rdmDisk, err))
+		}
+	}
+	if len(warnings) > 0 {
+		return preflightWarn(PreflightCheckRDMDisks, strings.Join(warnings, "; "))
+	}
+	return preflightPass(PreflightCheckRDMDisks, fmt.Sprintf("%d RDM disk(s) are ready for import", len(vmMachine.Spec.VMInfo.RDMDisks)))
+}
+
+func preflightPass(name, message string) vjailbreakv1alpha1.PreflightCheck {
+	return vjailbreakv1alpha1.PreflightCheck{Name: name, Result: vjailbreakv1alpha1.PreflightResultPass, Message: message}
+}
+
+func preflightWarn(name, message string) vjailbreakv1alpha1.PreflightCheck {
+	return vjailbreakv1alpha1.PreflightCheck{Name: name, Result: vjailbreakv1alpha1.PreflightResultWarn, Message: message}
+}
+
+func preflightFail(name, message string) vjailbreakv1alpha1.PreflightCheck {
+	return vjailbreakv1alpha1.PreflightCheck{Name: name, Result: vjailbreakv1alpha1.PreflightResultFail, Message: message}
+}
+
+// worsePreflightResult returns the worse of two results, Fail over Warn over Pass
+func worsePreflightResult(a, b vjailbreakv1alpha1.PreflightResult) vjailbreakv1alpha1.PreflightResult {
+	rank := map[vjailbreakv1alpha1.PreflightResult]int{
+		vjailbreakv1alpha1.PreflightResultPass: 0,
+		vjailbreakv1alpha1.PreflightResultWarn: 1,
+		vjailbreakv1alpha1.PreflightResultFail: 2,
+	}
+	if rank[b] > rank[a] {
+		return b
+	}
+	return a
+}
+
+// worstPreflightResult returns the worst result of checks, Pass when there are none
+func worstPreflightResult(checks []vjailbreakv1alpha1.PreflightCheck) vjailbreakv1alpha1.PreflightResult {
+	result := vjailbreakv1alpha1.PreflightResultPass
+	for _, check := range checks {
+		result = worsePreflightResult(result, check.Result)
+	}
+	return result
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *MigrationPreflightReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&vjailbreakv1alpha1.MigrationPreflight{}).
+		Complete(r)
+}
@@ -0,0 +1,407 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/gophercloud/gophercloud/v2"
+	"github.com/gophercloud/gophercloud/v2/openstack/compute/v2/flavors"
+	. "github.com/onsi/ginkgo/v2" //nolint:revive // dot imports are common in Ginkgo tests
+	. "github.com/onsi/gomega"    //nolint:revive // dot imports are common in Gomega assertions
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+	"github.com/platform9/vjailbreak/k8s/migration/pkg/constants"
+	"github.com/platform9/vjailbreak/k8s/migration/pkg/utils"
+)
+
+var _ = Describe("MigrationPreflight Controller", func() {
+	Context("When reconciling a resource", func() {
+		const resourceName = "test-resource"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+		migrationpreflight := &vjailbreakv1alpha1.MigrationPreflight{}
+
+		BeforeEach(func() {
+			By("creating the custom resource for the Kind MigrationPreflight")
+			err := k8sClient.Get(ctx, typeNamespacedName, migrationpreflight)
+			if err != nil && errors.IsNotFound(err) {
+				resource := &vjailbreakv1alpha1.MigrationPreflight{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      resourceName,
+						Namespace: "default",
+					},
+					Spec: vjailbreakv1alpha1.MigrationPreflightSpec{
+						MigrationPlan: "missing-migrationplan",
+					},
+				}
+				Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+			}
+		})
+
+		AfterEach(func() {
+			resource := &vjailbreakv1alpha1.MigrationPreflight{}
+			err := k8sClient.Get(ctx, typeNamespacedName, resource)
+			Expect(err).NotTo(HaveOccurred())
+
+			By("Cleanup the specific resource instance MigrationPreflight")
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+		})
+		It("should report a failed preflight for a missing migration plan", func() {
+			By("Reconciling the created resource")
+			controllerReconciler := &MigrationPreflightReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			resource := &vjailbreakv1alpha1.MigrationPreflight{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(resource.Status.Phase).To(Equal(vjailbreakv1alpha1.MigrationPreflightPhaseFailed))
+			Expect(resource.Status.Result).To(Equal(vjailbreakv1alpha1.PreflightResultFail))
+			Expect(resource.Status.Message).To(ContainSubstring("missing-migrationplan"))
+		})
+	})
+})
+
+// newPreflightTestVM returns the VMwareMachine of a powered off VM with the given disks
+func newPreflightTestVM(name string, disks ...vjailbreakv1alpha1.Disk) *vjailbreakv1alpha1.VMwareMachine {
+	vmMachine := &vjailbreakv1alpha1.VMwareMachine{}
+	vmMachine.Spec.VMInfo.Name = name
+	vmMachine.Spec.VMInfo.CPU = 2
+	vmMachine.Spec.VMInfo.Memory = 4096
+	vmMachine.Spec.VMInfo.Disks = disks
+	vmMachine.Status.PowerState = "notRunning"
+	return vmMachine
+}
+
+// newPreflightTestNeutron serves the ports, networks and subnets the IP address check reads.
+// usedIPs maps IPs to the port holding them, subnets maps networks to their IPv6 subnets.
+func newPreflightTestNeutron(usedIPs map[string]string, subnets map[string][]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		var body any
+		switch r.URL.Path {
+		case "/v2.0/ports":
+			ports := []map[string]string{}
+			for _, fixedIP := range query["fixed_ips"] {
+				if port, ok := usedIPs[strings.TrimPrefix(fixedIP, "ip_address=")]; ok {
+					ports = append(ports, map[string]string{"id": port})
+				}
+			}
+			body = map[string]any{"ports": ports}
+		case "/v2.0/networks":
+			name := query.Get("name")
+			body = map[string]any{"networks": []map[string]string{{"id": "id-" + name, "name": name}}}
+		case "/v2.0/subnets":
+			networkSubnets := []map[string]string{}
+			for _, cidr := range subnets[strings.TrimPrefix(query.Get("network_id"), "id-")] {
+				networkSubnets = append(networkSubnets, map[string]string{"id": cidr, "cidr": cidr})
+			}
+			body = map[string]any{"subnets": networkSubnets}
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(body)
+	}))
+}
+
+var _ = Describe("MigrationPreflight checks", func() {
+	ctx := context.Background()
+	var checker *preflightChecker
+
+	BeforeEach(func() {
+		checker = &preflightChecker{
+			namespace:      "default",
+			vms:            []string{"vm-1"},
+			template:       &vjailbreakv1alpha1.MigrationTemplate{},
+			openstackcreds: &vjailbreakv1alpha1.OpenstackCreds{},
+			ipOwners:       map[string]string{},
+		}
+	})
+
+	Describe("Flavor", func() {
+		allFlavors := []flavors.Flavor{
+			{ID: "1", Name: "m1.small", VCPUs: 2, RAM: 2048},
+			{ID: "2", Name: "m1.medium", VCPUs: 4, RAM: 8192},
+		}
+		DescribeTable("resolves the flavor of the VM",
+			func(setup func(*preflightChecker, *vjailbreakv1alpha1.VMwareMachine), result vjailbreakv1alpha1.PreflightResult, message string) {
+				checker.openstackClients = &utils.OpenStackClients{}
+				checker.flavors = allFlavors
+				vmMachine := newPreflightTestVM("vm-1")
+				setup(checker, vmMachine)
+				check := checker.checkFlavor(vmMachine)
+				Expect(check.Name).To(Equal(PreflightCheckFlavor))
+				Expect(check.Result).To(Equal(result))
+				Expect(check.Message).To(ContainSubstring(message))
+			},
+			Entry("closest flavor", func(*preflightChecker, *vjailbreakv1alpha1.VMwareMachine) {},
+				vjailbreakv1alpha1.PreflightResultPass, "closest flavor is m1.medium"),
+			Entry("flavor set on the VM", func(_ *preflightChecker, vmMachine *vjailbreakv1alpha1.VMwareMachine) {
+				vmMachine.Spec.TargetFlavorID = "2"
+			}, vjailbreakv1alpha1.PreflightResultPass, "flavor 2 is set on the VM"),
+			Entry("flavorless migration", func(c *preflightChecker, _ *vjailbreakv1alpha1.VMwareMachine) {
+				c.template.Spec.UseFlavorless = true
+				c.baseFlavor = &allFlavors[0]
+			}, vjailbreakv1alpha1.PreflightResultPass, "base flavor m1.small"),
+			Entry("OpenStack not reachable", func(c *preflightChecker, _ *vjailbreakv1alpha1.VMwareMachine) {
+				c.openstackClients = nil
+			}, vjailbreakv1alpha1.PreflightResultWarn, preflightOpenStackUnavailable),
+			Entry("flavors not listed", func(c *preflightChecker, _ *vjailbreakv1alpha1.VMwareMachine) {
+				c.flavors = nil
+			}, vjailbreakv1alpha1.PreflightResultWarn, "flavors of OpenStack are not available"),
+			Entry("no base flavor for a flavorless migration", func(c *preflightChecker, _ *vjailbreakv1alpha1.VMwareMachine) {
+				c.template.Spec.UseFlavorless = true
+			}, vjailbreakv1alpha1.PreflightResultFail, "no base flavor found"),
+			Entry("no flavor fits", func(_ *preflightChecker, vmMachine *vjailbreakv1alpha1.VMwareMachine) {
+				vmMachine.Spec.VMInfo.CPU = 16
+			}, vjailbreakv1alpha1.PreflightResultFail, "no suitable flavor found for 16 vCPU(s), 4096 MB RAM"),
+		)
+	})
+
+	Describe("IPAddress", func() {
+		var neutron *httptest.Server
+
+		BeforeEach(func() {
+			neutron = newPreflightTestNeutron(
+				map[string]string{"10.0.0.9": "port-9"},
+				map[string][]string{"provider-v6": {"2001:db8:1::/64"}},
+			)
+			checker.openstackClients = &utils.OpenStackClients{NetworkingClient: &gophercloud.ServiceClient{
+				ProviderClient: &gophercloud.ProviderClient{},
+				Endpoint:       neutron.URL + "/",
+				ResourceBase:   neutron.URL + "/v2.0/",
+			}}
+			checker.networkmap = &vjailbreakv1alpha1.NetworkMapping{}
+			checker.networkmap.Spec.Networks = []vjailbreakv1alpha1.Network{
+				{Source: "VM Network", Target: "provider-v6"},
+				{Source: "Backup", Target: "backup-v4"},
+			}
+		})
+
+		AfterEach(func() {
+			neutron.Close()
+		})
+
+		DescribeTable("checks the IPs the VM keeps",
+			func(ipOwners map[string]string, nics []vjailbreakv1alpha1.NIC, guestNetworks []vjailbreakv1alpha1.GuestNetwork,
+				result vjailbreakv1alpha1.PreflightResult, message string,
+			) {
+				for ip, vm := range ipOwners {
+					checker.ipOwners[ip] = vm
+				}
+				vmMachine := newPreflightTestVM("vm-1")
+				vmMachine.Spec.VMInfo.NetworkInterfaces = nics
+				vmMachine.Spec.VMInfo.GuestNetworks = guestNetworks
+				check := checker.checkIPAddresses(ctx, vmMachine)
+				Expect(check.Name).To(Equal(PreflightCheckIPAddress))
+				Expect(check.Result).To(Equal(result))
+				Expect(check.Message).To(ContainSubstring(message))
+			},
+			Entry("no IPs", nil, []vjailbreakv1alpha1.NIC{{Network: "VM Network"}}, nil,
+				vjailbreakv1alpha1.PreflightResultPass, "VM has no IP addresses to keep"),
+			Entry("free IPs", nil, []vjailbreakv1alpha1.NIC{{Network: "VM Network", IPAddress: "10.0.0.5, 10.0.0.6"}}, nil,
+				vjailbreakv1alpha1.PreflightResultPass, "IPs [10.0.0.5 10.0.0.6] are available"),
+			Entry("IP held by a port", nil, []vjailbreakv1alpha1.NIC{{Network: "VM Network", IPAddress: "10.0.0.9"}}, nil,
+				vjailbreakv1alpha1.PreflightResultFail, "IP 10.0.0.9 is already in use by port port-9"),
+			Entry("IP claimed by another VM of the preflight", map[string]string{"10.0.0.5": "vm-0"},
+				[]vjailbreakv1alpha1.NIC{{Network: "VM Network", IPAddress: "10.0.0.5"}}, nil,
+				vjailbreakv1alpha1.PreflightResultFail, "IP 10.0.0.5 is also used by VM vm-0"),
+			Entry("IP checked for the same VM before", map[string]string{"10.0.0.5": "vm-1"},
+				[]vjailbreakv1alpha1.NIC{{Network: "VM Network", IPAddress: "10.0.0.5"}}, nil,
+				vjailbreakv1alpha1.PreflightResultPass, "are available"),
+			Entry("IPv6 address in a subnet of the target network", nil,
+				[]vjailbreakv1alpha1.NIC{{Network: "VM Network", MAC: "00:50:56:aa:00:01", IPAddress: "2001:db8:1::5"}},
+				[]vjailbreakv1alpha1.GuestNetwork{{MAC: "00:50:56:aa:00:01", IP: "2001:db8:1::5"}},
+				vjailbreakv1alpha1.PreflightResultPass, "are available"),
+			Entry("IPv6 address outside the subnets of the target network", nil,
+				[]vjailbreakv1alpha1.NIC{{Network: "VM Network", MAC: "00:50:56:aa:00:01", IPAddress: "2001:db8:9::5"}},
+				[]vjailbreakv1alpha1.GuestNetwork{{MAC: "00:50:56:aa:00:01", IP: "2001:db8:9::5"}},
+				vjailbreakv1alpha1.PreflightResultFail, "network provider-v6 has no IPv6 subnet containing 2001:db8:9::5"),
+			Entry("IPv6 address on a target network without IPv6 subnet", nil,
+				[]vjailbreakv1alpha1.NIC{{Network: "Backup", MAC: "00:50:56:aa:00:02", IPAddress: "2001:db8:2::5"}},
+				[]vjailbreakv1alpha1.GuestNetwork{{MAC: "00:50:56:aa:00:02", IP: "2001:db8:2::5"}},
+				vjailbreakv1alpha1.PreflightResultFail, "network backup-v4 has no IPv6 subnet containing 2001:db8:2::5"),
+		)
+
+		It("claims the IPs of a VM for the later VMs", func() {
+			vmMachine := newPreflightTestVM("vm-1")
+			vmMachine.Spec.VMInfo.NetworkInterfaces = []vjailbreakv1alpha1.NIC{{Network: "VM Network", IPAddress: "10.0.0.5"}}
+			Expect(checker.checkIPAddresses(ctx, vmMachine).Result).To(Equal(vjailbreakv1alpha1.PreflightResultPass))
+
+			vmMachine = newPreflightTestVM("vm-2")
+			vmMachine.Spec.VMInfo.NetworkInterfaces = []vjailbreakv1alpha1.NIC{{Network: "VM Network", IPAddress: "10.0.0.5"}}
+			check := checker.checkIPAddresses(ctx, vmMachine)
+			Expect(check.Result).To(Equal(vjailbreakv1alpha1.PreflightResultFail))
+			Expect(check.Message).To(Equal("IP 10.0.0.5 is also used by VM vm-1"))
+		})
+
+		It("warns when Neutron cannot be queried", func() {
+			neutron.Close()
+			vmMachine := newPreflightTestVM("vm-1")
+			vmMachine.Spec.VMInfo.NetworkInterfaces = []vjailbreakv1alpha1.NIC{{Network: "VM Network", IPAddress: "10.0.0.5"}}
+			Expect(checker.checkIPAddresses(ctx, vmMachine).Result).To(Equal(vjailbreakv1alpha1.PreflightResultWarn))
+		})
+
+		It("warns when OpenStack is not reachable", func() {
+			checker.openstackClients = nil
+			check := checker.checkIPAddresses(ctx, newPreflightTestVM("vm-1"))
+			Expect(check.Result).To(Equal(vjailbreakv1alpha1.PreflightResultWarn))
+			Expect(check.Message).To(Equal(preflightOpenStackUnavailable))
+		})
+	})
+
+	Describe("DatastoreSpace", func() {
+		const gb = int64(preflightBytesPerGB)
+
+		DescribeTable("requires free space for 10% of the disks of the VM",
+			func(disks []vjailbreakv1alpha1.Disk, freeSpace map[string]int64, freeSpaceErr error,
+				result vjailbreakv1alpha1.PreflightResult, message string,
+			) {
+				checker.datastoreFreeSpace = func(_ context.Context, datacenter string, datastores []string) (map[string]int64, error) {
+					Expect(datacenter).To(Equal("dc1"))
+					return freeSpace, freeSpaceErr
+				}
+				check := checker.checkDatastoreSpace(ctx, newPreflightTestVM("vm-1", disks...), "dc1")
+				Expect(check.Name).To(Equal(PreflightCheckDatastoreSpace))
+				Expect(check.Result).To(Equal(result))
+				Expect(check.Message).To(Equal(message))
+			},
+			Entry("no disks on datastores", []vjailbreakv1alpha1.Disk{{Name: "disk-1", CapacityGB: 100}}, nil, nil,
+				vjailbreakv1alpha1.PreflightResultPass, "VM has no disks on datastores"),
+			Entry("room for the snapshot", []vjailbreakv1alpha1.Disk{{Datastore: "ds1", CapacityGB: 100}},
+				map[string]int64{"ds1": 50 * gb}, nil,
+				vjailbreakv1alpha1.PreflightResultPass, "datastores have room for the migration snapshot"),
+			Entry("exactly 10% free", []vjailbreakv1alpha1.Disk{{Datastore: "ds1", CapacityGB: 100}},
+				map[string]int64{"ds1": 10 * gb}, nil,
+				vjailbreakv1alpha1.PreflightResultPass, "datastores have room for the migration snapshot"),
+			Entry("less than 10% free", []vjailbreakv1alpha1.Disk{{Datastore: "ds1", CapacityGB: 100}},
+				map[string]int64{"ds1": 9 * gb}, nil,
+				vjailbreakv1alpha1.PreflightResultWarn, "ds1 has 9 GB free, 10 GB recommended"),
+			Entry("disks on the same datastore add up", []vjailbreakv1alpha1.Disk{
+				{Datastore: "ds1", CapacityGB: 100}, {Datastore: "ds1", CapacityGB: 100}, {Datastore: "ds2", CapacityGB: 50},
+			}, map[string]int64{"ds1": 15 * gb, "ds2": 5 * gb}, nil,
+				vjailbreakv1alpha1.PreflightResultWarn, "ds1 has 15 GB free, 20 GB recommended"),
+			Entry("free space not available", []vjailbreakv1alpha1.Disk{{Datastore: "ds1", CapacityGB: 100}}, nil,
+				fmt.Errorf("vCenter unreachable"),
+				vjailbreakv1alpha1.PreflightResultWarn, "failed to get datastore free space: vCenter unreachable"),
+		)
+	})
+
+	Describe("Snapshots", func() {
+		DescribeTable("checks the snapshots and independent disks of the VM",
+			func(snapshots int, independentDisks []string, snapshotErr error, result vjailbreakv1alpha1.PreflightResult, message string) {
+				checker.snapshotInfo = func(_ context.Context, datacenter, vm string) (int, []string, error) {
+					Expect(datacenter).To(Equal("dc1"))
+					Expect(vm).To(Equal("vm-1"))
+					return snapshots, independentDisks, snapshotErr
+				}
+				check := checker.checkSnapshots(ctx, "vm-1", "dc1")
+				Expect(check.Name).To(Equal(PreflightCheckSnapshots))
+				Expect(check.Result).To(Equal(result))
+				Expect(check.Message).To(Equal(message))
+			},
+			Entry("no snapshots", 0, nil, nil,
+				vjailbreakv1alpha1.PreflightResultPass, "VM has no snapshots"),
+			Entry("existing snapshots", 2, nil, nil,
+				vjailbreakv1alpha1.PreflightResultWarn, "VM has 2 existing snapshot(s), consolidating them speeds up the copy"),
+			Entry("independent disks", 2, []string{"Hard disk 2"}, nil,
+				vjailbreakv1alpha1.PreflightResultFail, "independent disks [Hard disk 2] cannot be snapshotted for the copy"),
+			Entry("VM not readable", 0, nil, fmt.Errorf("vm not found"),
+				vjailbreakv1alpha1.PreflightResultWarn, "failed to get snapshots of the VM: vm not found"),
+		)
+	})
+
+	Describe("RDMDisks", func() {
+		newRDMDisk := func(name string, ownerVMs ...string) *vjailbreakv1alpha1.RDMDisk {
+			rdmDisk := &vjailbreakv1alpha1.RDMDisk{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"}}
+			rdmDisk.Spec.DiskName = name
+			rdmDisk.Spec.OwnerVMs = ownerVMs
+			rdmDisk.Spec.OpenstackVolumeRef = vjailbreakv1alpha1.OpenstackVolumeRef{
+				VolumeRef:         map[string]string{"source-name": name},
+				CinderBackendPool: "pool",
+				VolumeType:        "rdm",
+			}
+			return rdmDisk
+		}
+		settings := func(validateOwners string) *corev1.ConfigMap {
+			return &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: constants.VjailbreakSettingsConfigMapName, Namespace: constants.NamespaceMigrationSystem},
+				Data:       map[string]string{"VALIDATE_RDM_OWNER_VMS": validateOwners},
+			}
+		}
+
+		DescribeTable("checks the RDM disks of the VM are ready for import",
+			func(powerState string, objects []client.Object, result vjailbreakv1alpha1.PreflightResult, message string) {
+				scheme := runtime.NewScheme()
+				Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+				Expect(vjailbreakv1alpha1.AddToScheme(scheme)).To(Succeed())
+				checker.Client = fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()
+				vmMachine := newPreflightTestVM("vm-1")
+				vmMachine.Spec.VMInfo.RDMDisks = []string{"rdm-1"}
+				vmMachine.Status.PowerState = powerState
+				check := checker.checkRDMDisks(ctx, vmMachine)
+				Expect(check.Name).To(Equal(PreflightCheckRDMDisks))
+				Expect(check.Result).To(Equal(result))
+				Expect(check.Message).To(ContainSubstring(message))
+			},
+			Entry("RDM disk ready", "notRunning", []client.Object{newRDMDisk("rdm-1", "vm-1")},
+				vjailbreakv1alpha1.PreflightResultPass, "1 RDM disk(s) are ready for import"),
+			Entry("VM powered on", "running", []client.Object{newRDMDisk("rdm-1", "vm-1")},
+				vjailbreakv1alpha1.PreflightResultFail, "VM is not powered off"),
+			Entry("RDMDisk missing", "notRunning", nil,
+				vjailbreakv1alpha1.PreflightResultFail, "failed to get RDMDisk rdm-1"),
+			Entry("owner VM outside the preflight", "notRunning", []client.Object{newRDMDisk("rdm-1", "vm-1", "vm-9")},
+				vjailbreakv1alpha1.PreflightResultFail, `ownerVM "vm-9" in RDM disk rdm-1 not found in migration plan`),
+			Entry("owner VM outside the preflight without owner validation", "notRunning",
+				[]client.Object{newRDMDisk("rdm-1", "vm-1", "vm-9"), settings("false")},
+				vjailbreakv1alpha1.PreflightResultWarn, `ownerVM "vm-9" in RDM disk rdm-1 not found in migration plan`),
+			Entry("RDM disk not ready for import", "notRunning", []client.Object{func() client.Object {
+				rdmDisk := newRDMDisk("rdm-1", "vm-1")
+				rdmDisk.Spec.OpenstackVolumeRef.VolumeType = ""
+				return rdmDisk
+			}()}, vjailbreakv1alpha1.PreflightResultFail, "RDMDisk rdm-1 is not ready for import: OpenstackVolumeRef.volumeType is required"),
+		)
+	})
+})
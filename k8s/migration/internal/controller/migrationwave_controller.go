@@ -0,0 +1,350 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/pkg/errors"
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+	"github.com/platform9/vjailbreak/k8s/migration/pkg/constants"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// MigrationWaveReconciler reconciles a MigrationWave object
+type MigrationWaveReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+// +kubebuilder:rbac:groups=vjailbreak.k8s.pf9.io,resources=migrationwaves,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=vjailbreak.k8s.pf9.io,resources=migrationwaves/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=vjailbreak.k8s.pf9.io,resources=migrationwaves/finalizers,verbs=update
+// +kubebuilder:rbac:groups=vjailbreak.k8s.pf9.io,resources=migrationplans,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=vjailbreak.k8s.pf9.io,resources=rollingmigrationplans,verbs=get;list;watch;update;patch
+
+// Reconcile starts the plans of a MigrationWave one after the other and adds up their VMs. Plans
+// are held and released with the pause label of the paused-plan mechanism, so pausing the wave
+// pauses the plans it started and resuming it resumes them. A plan that started before the wave
+// could hold it is only adopted once approved.
+func (r *MigrationWaveReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	ctxlog := log.FromContext(ctx).WithName(constants.MigrationWaveControllerName)
+
+	wave := &vjailbreakv1alpha1.MigrationWave{}
+	if err := r.Get(ctx, req.NamespacedName, wave); err != nil {
+		if apierrors.IsNotFound(err) {
+			ctxlog.Info("Received ignorable event for a recently deleted MigrationWave.")
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, errors.Wrapf(err, "failed to read MigrationWave '%s'", req.Name)
+	}
+
+	if len(wave.Spec.Plans) == 0 {
+		return ctrl.Result{}, nil
+	}
+	paused := wave.Labels[constants.PauseMigrationLabel] == constants.PauseMigrationValue
+	// The wave only changes the pause label of started plans when it is paused or resumed, so that a
+	// plan paused on its own stays paused
+	pauseChanged := paused != (wave.Status.Phase == vjailbreakv1alpha1.MigrationWavePhasePaused)
+
+	status := vjailbreakv1alpha1.MigrationWaveStatus{}
+	held := false
+	for i, plan := range wave.Spec.Plans {
+		obj, planStatus, err := r.getWavePlan(ctx, wave.Namespace, plan)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if i < len(wave.Status.Plans) && wave.Status.Plans[i].Name == planStatus.Name && wave.Status.Plans[i].Kind == planStatus.Kind {
+			planStatus.Started = wave.Status.Plans[i].Started
+			planStatus.StartTime = wave.Status.Plans[i].StartTime
+		}
+
+		switch {
+		case obj == nil:
+			if !held {
+				status.Phase = vjailbreakv1alpha1.MigrationWavePhaseWaitingForPlan
+				status.Message = fmt.Sprintf("%s %s does not exist", planStatus.Kind, planStatus.Name)
+				held = true
+			}
+		case planStatus.Started:
+			if pauseChanged {
+				if err := r.setPauseLabel(ctx, obj, paused); err != nil {
+					return ctrl.Result{}, err
+				}
+			}
+		case planStartedOutsideWave(obj, planStatus) && !slices.Contains(wave.Spec.Approvals, planStatus.Name):
+			// The plan ran before the wave could hold it, pausing it now would stop migrations the
+			// wave did not start. It is adopted once approved.
+			planStatus = vjailbreakv1alpha1.WavePlanStatus{Kind: planStatus.Kind, Name: planStatus.Name, Phase: planStatus.Phase}
+			if !held && !paused {
+				status.Phase = vjailbreakv1alpha1.MigrationWavePhaseWaitingForApproval
+				status.Message = fmt.Sprintf("%s %s started before the wave held it, approve %s to adopt it",
+					planStatus.Kind, planStatus.Name, planStatus.Name)
+			}
+			held = true
+		default:
+			start, phase, message := waveGate(wave, i, status.Plans)
+			if start && !held && !paused {
+				ctxlog.Info(fmt.Sprintf("Starting %s '%s' of MigrationWave '%s'", planStatus.Kind, planStatus.Name, wave.Name))
+				if err := r.setPauseLabel(ctx, obj, false); err != nil {
+					return ctrl.Result{}, err
+				}
+				now := metav1.Now()
+				planStatus.Started = true
+				planStatus.StartTime = &now
+				planStatus.Phase = vjailbreakv1alpha1.WavePlanPhaseRunning
+				break
+			}
+			if err := r.setPauseLabel(ctx, obj, true); err != nil {
+				return ctrl.Result{}, err
+			}
+			planStatus = vjailbreakv1alpha1.WavePlanStatus{Kind: planStatus.Kind, Name: planStatus.Name, Phase: vjailbreakv1alpha1.WavePlanPhasePending}
+			if !held && !paused {
+				status.Phase, status.Message = phase, message
+			}
+			held = true
+		}
+
+		if planStatus.Started {
+			status.CurrentPlan = planStatus.Name
+		}
+		status.Succeeded += planStatus.Succeeded
+		status.Failed += planStatus.Failed
+		status.InProgress += planStatus.InProgress
+		status.Plans = append(status.Plans, planStatus)
+	}
+
+	last := status.Plans[len(status.Plans)-1]
+	switch {
+	case paused:
+		status.Phase = vjailbreakv1alpha1.MigrationWavePhasePaused
+		status.Message = "Migration wave is paused"
+	case status.Phase != "":
+	case !slices.ContainsFunc(status.Plans, func(p vjailbreakv1alpha1.WavePlanStatus) bool {
+		return p.Phase != vjailbreakv1alpha1.WavePlanPhaseSucceeded
+	}):
+		status.Phase = vjailbreakv1alpha1.MigrationWavePhaseSucceeded
+		status.Message = "All plans of the wave succeeded"
+	case last.Phase == vjailbreakv1alpha1.WavePlanPhaseFailed:
+		status.Phase = vjailbreakv1alpha1.MigrationWavePhaseFailed
+		status.Message = fmt.Sprintf("%s %s failed", last.Kind, last.Name)
+	default:
+		status.Phase = vjailbreakv1alpha1.MigrationWavePhaseRunning
+		status.Message = fmt.Sprintf("Running %s", status.CurrentPlan)
+	}
+
+	if !equality.Semantic.DeepEqual(wave.Status, status) {
+		wave.Status = status
+		if err := r.Status().Update(ctx, wave); err != nil {
+			return ctrl.Result{}, errors.Wrap(err, "failed to update MigrationWave status")
+		}
+	}
+	if status.Phase == vjailbreakv1alpha1.MigrationWavePhaseSucceeded || status.Phase == vjailbreakv1alpha1.MigrationWavePhaseFailed {
+		return ctrl.Result{}, nil
+	}
+	return ctrl.Result{RequeueAfter: constants.MigrationWaveRequeueAfter}, nil
+}
+
+// waveGate decides if the plan at index i of the wave may start. A plan starts when it is approved,
+// or when the previous plan succeeded and it does not require an approval. Otherwise it returns
+// the phase and message of the wave while the plan waits.
+func waveGate(wave *vjailbreakv1alpha1.MigrationWave, i int, previous []vjailbreakv1alpha1.WavePlanStatus) (bool, vjailbreakv1alpha1.MigrationWavePhase, string) {
+	plan := wave.Spec.Plans[i]
+	if slices.Contains(wave.Spec.Approvals, plan.Name) {
+		return true, "", ""
+	}
+	waitingForApproval := fmt.Sprintf("%s waits for approval", plan.Name)
+	if i == 0 {
+		if plan.RequireApproval {
+			return false, vjailbreakv1alpha1.MigrationWavePhaseWaitingForApproval, waitingForApproval
+		}
+		return true, "", ""
+	}
+	switch prev := previous[i-1]; prev.Phase {
+	case vjailbreakv1alpha1.WavePlanPhaseSucceeded:
+		if plan.RequireApproval {
+			return false, vjailbreakv1alpha1.MigrationWavePhaseWaitingForApproval, waitingForApproval
+		}
+		return true, "", ""
+	case vjailbreakv1alpha1.WavePlanPhaseFailed:
+		return false, vjailbreakv1alpha1.MigrationWavePhaseWaitingForApproval,
+			fmt.Sprintf("%s failed, approve %s to continue", prev.Name, plan.Name)
+	default:
+		return false, vjailbreakv1alpha1.MigrationWavePhaseRunning, fmt.Sprintf("Running %s", prev.Name)
+	}
+}
+
+// planStartedOutsideWave returns true if a plan the wave has not started already migrates VMs,
+// because it was created without the pause label and started before the wave held it
+func planStartedOutsideWave(obj client.Object, status vjailbreakv1alpha1.WavePlanStatus) bool {
+	if status.Succeeded+status.Failed+status.InProgress > 0 {
+		return true
+	}
+	rollingMigrationPlan, ok := obj.(*vjailbreakv1alpha1.RollingMigrationPlan)
+	if !ok {
+		return false
+	}
+	switch rollingMigrationPlan.Status.Phase {
+	case vjailbreakv1alpha1.RollingMigrationPlanPhaseRunning, vjailbreakv1alpha1.RollingMigrationPlanPhaseMigratingVMs:
+		return true
+	}
+	return false
+}
+
+// getWavePlan returns a plan of a wave and its state. The plan is nil when it does not exist.
+func (r *MigrationWaveReconciler) getWavePlan(ctx context.Context, namespace string, plan vjailbreakv1alpha1.WavePlan) (client.Object, vjailbreakv1alpha1.WavePlanStatus, error) {
+	status := vjailbreakv1alpha1.WavePlanStatus{Kind: plan.Kind, Name: plan.Name}
+	if status.Kind == "" {
+		status.Kind = vjailbreakv1alpha1.WavePlanKindMigrationPlan
+	}
+	key := types.NamespacedName{Name: plan.Name, Namespace: namespace}
+
+	if status.Kind == vjailbreakv1alpha1.WavePlanKindRollingMigrationPlan {
+		rollingMigrationPlan := &vjailbreakv1alpha1.RollingMigrationPlan{}
+		if err := r.Get(ctx, key, rollingMigrationPlan); err != nil {
+			if apierrors.IsNotFound(err) {
+				status.Phase = vjailbreakv1alpha1.WavePlanPhaseNotFound
+				return nil, status, nil
+			}
+			return nil, status, errors.Wrapf(err, "failed to get RollingMigrationPlan '%s'", plan.Name)
+		}
+		switch rollingMigrationPlan.Status.Phase {
+		case vjailbreakv1alpha1.RollingMigrationPlanPhaseSucceeded:
+			status.Phase = vjailbreakv1alpha1.WavePlanPhaseSucceeded
+		case vjailbreakv1alpha1.RollingMigrationPlanPhaseFailed, vjailbreakv1alpha1.RollingMigrationPlanPhaseValidationFailed:
+			status.Phase = vjailbreakv1alpha1.WavePlanPhaseFailed
+		default:
+			status.Phase = wavePlanRunningPhase(rollingMigrationPlan)
+		}
+		status.Succeeded = len(rollingMigrationPlan.Status.MigratedVMs)
+		status.Failed = len(rollingMigrationPlan.Status.FailedVMs)
+		if status.Phase == vjailbreakv1alpha1.WavePlanPhaseRunning {
+			total := 0
+			for _, cluster := range rollingMigrationPlan.Spec.ClusterSequence {
+				total += len(cluster.VMSequence)
+			}
+			status.InProgress = max(total-status.Succeeded-status.Failed, 0)
+		}
+		return rollingMigrationPlan, status, nil
+	}
+
+	migrationPlan := &vjailbreakv1alpha1.MigrationPlan{}
+	if err := r.Get(ctx, key, migrationPlan); err != nil {
+		if apierrors.IsNotFound(err) {
+			status.Phase = vjailbreakv1alpha1.WavePlanPhaseNotFound
+			return nil, status, nil
+		}
+		return nil, status, errors.Wrapf(err, "failed to get MigrationPlan '%s'", plan.Name)
+	}
+	switch migrationPlan.Status.MigrationStatus {
+	case corev1.PodSucceeded:
+		status.Phase = vjailbreakv1alpha1.WavePlanPhaseSucceeded
+	case corev1.PodFailed:
+		status.Phase = vjailbreakv1alpha1.WavePlanPhaseFailed
+	default:
+		status.Phase = wavePlanRunningPhase(migrationPlan)
+	}
+	migrationList := &vjailbreakv1alpha1.MigrationList{}
+	if err := r.List(ctx, migrationList, client.InNamespace(namespace), client.MatchingLabels{"migrationplan": migrationPlan.Name}); err != nil {
+		return nil, status, errors.Wrapf(err, "failed to list migrations of MigrationPlan '%s'", plan.Name)
+	}
+	for _, migration := range migrationList.Items {
+		switch migration.Status.Phase {
+		case vjailbreakv1alpha1.VMMigrationPhaseSucceeded:
+			status.Succeeded++
+		case vjailbreakv1alpha1.VMMigrationPhaseFailed, vjailbreakv1alpha1.VMMigrationPhaseValidationFailed,
+			vjailbreakv1alpha1.VMMigrationPhaseRolledBack:
+			status.Failed++
+		default:
+			status.InProgress++
+		}
+	}
+	return migrationPlan, status, nil
+}
+
+// wavePlanRunningPhase returns the phase of an unfinished plan of a wave
+func wavePlanRunningPhase(obj client.Object) string {
+	if obj.GetLabels()[constants.PauseMigrationLabel] == constants.PauseMigrationValue {
+		return vjailbreakv1alpha1.WavePlanPhasePaused
+	}
+	return vjailbreakv1alpha1.WavePlanPhaseRunning
+}
+
+// setPauseLabel pauses or resumes a plan of a wave with the pause label
+func (r *MigrationWaveReconciler) setPauseLabel(ctx context.Context, obj client.Object, paused bool) error {
+	labels := obj.GetLabels()
+	if (labels[constants.PauseMigrationLabel] == constants.PauseMigrationValue) == paused {
+		return nil
+	}
+	if paused {
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		labels[constants.PauseMigrationLabel] = constants.PauseMigrationValue
+	} else {
+		delete(labels, constants.PauseMigrationLabel)
+	}
+	obj.SetLabels(labels)
+	if err := r.Update(ctx, obj); err != nil {
+		return errors.Wrapf(err, "failed to update pause label of plan '%s'", obj.GetName())
+	}
+	return nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *MigrationWaveReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&vjailbreakv1alpha1.MigrationWave{}).
+		// A plan created for a wave is held as soon as it exists, before its own controller starts it
+		Watches(&vjailbreakv1alpha1.MigrationPlan{}, handler.EnqueueRequestsFromMapFunc(
+			r.wavesForPlan(vjailbreakv1alpha1.WavePlanKindMigrationPlan))).
+		Watches(&vjailbreakv1alpha1.RollingMigrationPlan{}, handler.EnqueueRequestsFromMapFunc(
+			r.wavesForPlan(vjailbreakv1alpha1.WavePlanKindRollingMigrationPlan))).
+		Complete(r)
+}
+
+// wavesForPlan returns a function that maps a plan of kind to the waves it is a plan of
+func (r *MigrationWaveReconciler) wavesForPlan(kind string) handler.MapFunc {
+	return func(ctx context.Context, obj client.Object) []reconcile.Request {
+		waveList := &vjailbreakv1alpha1.MigrationWaveList{}
+		if err := r.List(ctx, waveList, client.InNamespace(obj.GetNamespace())); err != nil {
+			log.FromContext(ctx).Error(err, "Failed to list MigrationWaves", "plan", obj.GetName())
+			return nil
+		}
+		requests := []reconcile.Request{}
+		for _, wave := range waveList.Items {
+			if slices.ContainsFunc(wave.Spec.Plans, func(plan vjailbreakv1alpha1.WavePlan) bool {
+				return plan.Name == obj.GetName() && (plan.Kind == kind || (plan.Kind == "" && kind == vjailbreakv1alpha1.WavePlanKindMigrationPlan))
+			}) {
+				requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: wave.Name, Namespace: wave.Namespace}})
+			}
+		}
+		return requests
+	}
+}
@@ -0,0 +1,150 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2" //nolint:revive // dot imports are common in Ginkgo tests
+	. "github.com/onsi/gomega"    //nolint:revive // dot imports are common in Gomega assertions
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+)
+
+var _ = Describe("MigrationWave Controller", func() {
+	Context("When reconciling a resource", func() {
+		const resourceName = "test-resource"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+		migrationwave := &vjailbreakv1alpha1.MigrationWave{}
+
+		BeforeEach(func() {
+			By("creating the custom resource for the Kind MigrationWave")
+			err := k8sClient.Get(ctx, typeNamespacedName, migrationwave)
+			if err != nil && errors.IsNotFound(err) {
+				resource := &vjailbreakv1alpha1.MigrationWave{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      resourceName,
+						Namespace: "default",
+					},
+					Spec: vjailbreakv1alpha1.MigrationWaveSpec{
+						Plans: []vjailbreakv1alpha1.WavePlan{{Name: "missing-migrationplan"}},
+					},
+				}
+				Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+			}
+		})
+
+		AfterEach(func() {
+			resource := &vjailbreakv1alpha1.MigrationWave{}
+			err := k8sClient.Get(ctx, typeNamespacedName, resource)
+			Expect(err).NotTo(HaveOccurred())
+
+			By("Cleanup the specific resource instance MigrationWave")
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+		})
+		It("should wait for a missing plan", func() {
+			By("Reconciling the created resource")
+			controllerReconciler := &MigrationWaveReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			resource := &vjailbreakv1alpha1.MigrationWave{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(resource.Status.Phase).To(Equal(vjailbreakv1alpha1.MigrationWavePhaseWaitingForPlan))
+			Expect(resource.Status.Message).To(ContainSubstring("missing-migrationplan"))
+		})
+	})
+})
+
+var _ = Describe("MigrationWave gate", func() {
+	newWave := func(approvals ...string) *vjailbreakv1alpha1.MigrationWave {
+		return &vjailbreakv1alpha1.MigrationWave{Spec: vjailbreakv1alpha1.MigrationWaveSpec{
+			Plans: []vjailbreakv1alpha1.WavePlan{
+				{Name: "plan-a"},
+				{Name: "plan-b"},
+				{Name: "plan-c", RequireApproval: true},
+			},
+			Approvals: approvals,
+		}}
+	}
+	previous := func(phases ...string) []vjailbreakv1alpha1.WavePlanStatus {
+		plans := []vjailbreakv1alpha1.WavePlanStatus{}
+		for i, phase := range phases {
+			plans = append(plans, vjailbreakv1alpha1.WavePlanStatus{Name: []string{"plan-a", "plan-b"}[i], Phase: phase})
+		}
+		return plans
+	}
+
+	DescribeTable("decides if a plan of the wave starts",
+		func(wave *vjailbreakv1alpha1.MigrationWave, i int, prev []vjailbreakv1alpha1.WavePlanStatus,
+			wantStart bool, wantPhase vjailbreakv1alpha1.MigrationWavePhase, wantMessage string) {
+			start, phase, message := waveGate(wave, i, prev)
+			Expect(start).To(Equal(wantStart))
+			Expect(phase).To(Equal(wantPhase))
+			Expect(message).To(Equal(wantMessage))
+		},
+		Entry("first plan", newWave(), 0, previous(), true, vjailbreakv1alpha1.MigrationWavePhase(""), ""),
+		Entry("first plan requiring an approval", &vjailbreakv1alpha1.MigrationWave{Spec: vjailbreakv1alpha1.MigrationWaveSpec{
+			Plans: []vjailbreakv1alpha1.WavePlan{{Name: "plan-a", RequireApproval: true}},
+		}}, 0, previous(), false, vjailbreakv1alpha1.MigrationWavePhaseWaitingForApproval, "plan-a waits for approval"),
+		Entry("previous plan running", newWave(), 1, previous(vjailbreakv1alpha1.WavePlanPhaseRunning),
+			false, vjailbreakv1alpha1.MigrationWavePhaseRunning, "Running plan-a"),
+		Entry("previous plan paused", newWave(), 1, previous(vjailbreakv1alpha1.WavePlanPhasePaused),
+			false, vjailbreakv1alpha1.MigrationWavePhaseRunning, "Running plan-a"),
+		Entry("previous plan succeeded", newWave(), 1, previous(vjailbreakv1alpha1.WavePlanPhaseSucceeded),
+			true, vjailbreakv1alpha1.MigrationWavePhase(""), ""),
+		Entry("previous plan failed", newWave(), 1, previous(vjailbreakv1alpha1.WavePlanPhaseFailed),
+			false, vjailbreakv1alpha1.MigrationWavePhaseWaitingForApproval, "plan-a failed, approve plan-b to continue"),
+		Entry("approved after a failure", newWave("plan-b"), 1, previous(vjailbreakv1alpha1.WavePlanPhaseFailed),
+			true, vjailbreakv1alpha1.MigrationWavePhase(""), ""),
+		Entry("plan requiring an approval", newWave(), 2,
+			previous(vjailbreakv1alpha1.WavePlanPhaseSucceeded, vjailbreakv1alpha1.WavePlanPhaseSucceeded),
+			false, vjailbreakv1alpha1.MigrationWavePhaseWaitingForApproval, "plan-c waits for approval"),
+		Entry("approved plan before the previous plan finished", newWave("plan-c"), 2,
+			previous(vjailbreakv1alpha1.WavePlanPhaseSucceeded, vjailbreakv1alpha1.WavePlanPhaseRunning),
+			true, vjailbreakv1alpha1.MigrationWavePhase(""), ""),
+	)
+
+	It("detects plans that started before the wave held them", func() {
+		migrationPlan := &vjailbreakv1alpha1.MigrationPlan{}
+		Expect(planStartedOutsideWave(migrationPlan, vjailbreakv1alpha1.WavePlanStatus{Phase: vjailbreakv1alpha1.WavePlanPhaseRunning})).To(BeFalse())
+		Expect(planStartedOutsideWave(migrationPlan, vjailbreakv1alpha1.WavePlanStatus{InProgress: 1})).To(BeTrue())
+
+		rollingMigrationPlan := &vjailbreakv1alpha1.RollingMigrationPlan{}
+		rollingMigrationPlan.Status.Phase = vjailbreakv1alpha1.RollingMigrationPlanPhaseWaiting
+		Expect(planStartedOutsideWave(rollingMigrationPlan, vjailbreakv1alpha1.WavePlanStatus{})).To(BeFalse())
+		rollingMigrationPlan.Status.Phase = vjailbreakv1alpha1.RollingMigrationPlanPhaseMigratingVMs
+		Expect(planStartedOutsideWave(rollingMigrationPlan, vjailbreakv1alpha1.WavePlanStatus{})).To(BeTrue())
+	})
+})
@@ -156,6 +156,17 @@ func (r *RollingMigrationPlanReconciler) reconcileNormal(ctx context.Context, sc
 			}
 			return ctrl.Result{RequeueAfter: 1 * time.Minute}, nil
 		}
+
+		if sufficient, err := r.reconcileCapacityForecast(ctx, scope); err != nil {
+			return ctrl.Result{}, err
+		} else if !sufficient {
+			log.Info(fmt.Sprintf("RollingMigrationPlan %s lacks capacity: %s, requeueing after 1 minute", migrationPlan.Name, migrationPlan.Status.Message))
+			migrationPlan.Status.Phase = vjailbreakv1alpha1.RollingMigrationPlanPhaseValidationFailed
+			if err := r.Status().Update(ctx, migrationPlan); err != nil {
+				return ctrl.Result{}, errors.Wrap(err, "failed to update rolling migration plan")
+			}
+			return ctrl.Result{RequeueAfter: 1 * time.Minute}, nil
+		}
 	}
 
 	if err := utils.ResumeRollingMigrationPlan(ctx, scope); err != nil {
@@ -454,7 +465,7 @@ func (r *RollingMigrationPlanReconciler) UpdateRollingMigrationPlanStatus(ctx co
 					return errors.Wrap(err, "failed to get VMMigration")
 				}
 				switch migration.Status.Phase {
-				case vjailbreakv1alpha1.VMMigrationPhaseFailed:
+				case vjailbreakv1alpha1.VMMigrationPhaseFailed, vjailbreakv1alpha1.VMMigrationPhaseRolledBack:
 					scope.RollingMigrationPlan.Status.FailedVMs = append(scope.RollingMigrationPlan.Status.FailedVMs, vm)
 				case vjailbreakv1alpha1.VMMigrationPhaseSucceeded:
 					scope.RollingMigrationPlan.Status.MigratedVMs = append(scope.RollingMigrationPlan.Status.MigratedVMs, vm)
@@ -509,3 +520,51 @@ func (r *RollingMigrationPlanReconciler) ExecuteRollingMigrationPlan(ctx context
 	}
 	return false, nil
 }
+
+// reconcileCapacityForecast sets the capacity forecast of all VMs of the rolling migration plan in
+// its status. It returns false with the reason in the status message when the plan has strict
+// capacity and the target project lacks the quota for it, or the forecast could not be computed.
+func (r *RollingMigrationPlanReconciler) reconcileCapacityForecast(ctx context.Context, scope *scope.RollingMigrationPlanScope) (bool, error) {
+	log := scope.Logger
+	migrationPlan := scope.RollingMigrationPlan
+
+	// Every status update requeues the plan, reuse a recent forecast rather than querying the quotas again
+	forecast := migrationPlan.Status.CapacityForecast
+	if !utils.IsCapacityForecastFresh(forecast) {
+		var err error
+		forecast, err = r.forecastCapacity(ctx, migrationPlan)
+		if err != nil {
+			log.Error(err, "Failed to forecast capacity of rolling migration plan")
+			if !migrationPlan.Spec.StrictCapacity {
+				return true, nil
+			}
+			migrationPlan.Status.Message = fmt.Sprintf("%s: failed to forecast capacity: %v", constants.MigrationPlanWaitingForCapacityPrefix, err)
+			return false, nil
+		}
+		migrationPlan.Status.CapacityForecast = forecast
+		if err := r.Status().Update(ctx, migrationPlan); err != nil {
+			return false, errors.Wrap(err, "failed to update capacity forecast of rolling migration plan")
+		}
+	}
+
+	if !forecast.Sufficient && migrationPlan.Spec.StrictCapacity {
+		migrationPlan.Status.Message = fmt.Sprintf("%s: %s", constants.MigrationPlanWaitingForCapacityPrefix, forecast.Message)
+		return false, nil
+	}
+	return true, nil
+}
+
+// forecastCapacity computes the capacity forecast of all VMs of the rolling migration plan
+func (r *RollingMigrationPlanReconciler) forecastCapacity(ctx context.Context, migrationPlan *vjailbreakv1alpha1.RollingMigrationPlan) (*vjailbreakv1alpha1.CapacityForecast, error) {
+	migrationTemplate, err := utils.GetMigrationTemplateFromRollingMigrationPlan(ctx, r.Client, migrationPlan)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get migration template")
+	}
+	vmNames := []string{}
+	for _, cluster := range migrationPlan.Spec.ClusterSequence {
+		for _, vm := range cluster.VMSequence {
+			vmNames = append(vmNames, vm.VMName)
+		}
+	}
+	return utils.ForecastMigrationCapacity(ctx, r.Client, migrationTemplate, vmNames)
+}
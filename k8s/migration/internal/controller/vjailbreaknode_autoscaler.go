@@ -0,0 +1,218 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+	"github.com/platform9/vjailbreak/k8s/migration/pkg/constants"
+	"github.com/platform9/vjailbreak/k8s/migration/pkg/scope"
+	"github.com/platform9/vjailbreak/k8s/migration/pkg/utils"
+	"github.com/platform9/vjailbreak/v2v-helper/pkg/k8sutils"
+)
+
+// reconcileAutoscaler sizes the agent nodes of the autoscaler to the migration queue. It runs with
+// the reconcile of the master node and carries out the scaling decided by utils.PlanAgentNodeScaling.
+func (r *VjailbreakNodeReconciler) reconcileAutoscaler(ctx context.Context, scope *scope.VjailbreakNodeScope) (ctrl.Result, error) {
+	log := scope.Logger
+	masterNode := scope.VjailbreakNode
+
+	autoscalerList := &vjailbreakv1alpha1.VjailbreakNodeAutoscalerList{}
+	if err := r.List(ctx, autoscalerList, client.InNamespace(constants.NamespaceMigrationSystem)); err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "failed to list vjailbreak node autoscalers")
+	}
+	if len(autoscalerList.Items) == 0 {
+		return ctrl.Result{}, nil
+	}
+	sort.Slice(autoscalerList.Items, func(i, j int) bool {
+		return autoscalerList.Items[i].Name < autoscalerList.Items[j].Name
+	})
+	autoscaler := &autoscalerList.Items[0]
+
+	nodeList := &vjailbreakv1alpha1.VjailbreakNodeList{}
+	if err := r.List(ctx, nodeList, client.InNamespace(constants.NamespaceMigrationSystem),
+		client.MatchingLabels{constants.VjailbreakNodeAutoscalerLabel: autoscaler.Name}); err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "failed to list autoscaled vjailbreak nodes")
+	}
+	migrations, err := r.Scheduler.ListMigrations(ctx, r.Client)
+	if err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "failed to get migration queue")
+	}
+	assigned, queued := utils.GetMigrationQueue(migrations)
+	vjailbreakSettings, err := k8sutils.GetVjailbreakSettings(ctx, r.Client)
+	if err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "failed to get vjailbreak settings")
+	}
+	migrationsPerNode := autoscaler.Spec.MaxConcurrentMigrations
+	if migrationsPerNode <= 0 {
+		migrationsPerNode = max(vjailbreakSettings.MaxMigrationsPerAgent, 1)
+	}
+	idlePeriod := time.Duration(autoscaler.Spec.ScaleDownIdleMinutes) * time.Minute
+	if idlePeriod <= 0 {
+		idlePeriod = 30 * time.Minute
+	}
+
+	now := metav1.Now()
+	oldStatus := autoscaler.Status.DeepCopy()
+	events := []vjailbreakv1alpha1.NodeScaleEvent{}
+	recordEvent := func(eventType vjailbreakv1alpha1.NodeScaleEventType, node, message string) {
+		log.Info(message, "autoscaler", autoscaler.Name, "node", node, "event", eventType)
+		events = append(events, vjailbreakv1alpha1.NodeScaleEvent{Time: now, Type: eventType, Node: node, Message: message})
+	}
+
+	scaling := utils.PlanAgentNodeScaling(nodeList.Items, assigned, queued, migrationsPerNode, autoscaler.Spec.MaxNodes, idlePeriod, now.Time)
+	nodesByName := map[string]*vjailbreakv1alpha1.VjailbreakNode{}
+	nodes := []string{}
+	for i := range nodeList.Items {
+		nodesByName[nodeList.Items[i].Name] = &nodeList.Items[i]
+		nodes = append(nodes, nodeList.Items[i].Name)
+	}
+	for _, name := range scaling.Busy {
+		if err := r.setNodeIdleSince(ctx, nodesByName[name], nil); err != nil {
+			return ctrl.Result{}, errors.Wrapf(err, "failed to scale agent node %s", name)
+		}
+	}
+	for _, name := range scaling.Idle {
+		if err := r.setNodeIdleSince(ctx, nodesByName[name], &now); err != nil {
+			return ctrl.Result{}, errors.Wrapf(err, "failed to scale agent node %s", name)
+		}
+	}
+	for _, name := range scaling.Drain {
+		node := nodesByName[name]
+		idleSince := node.Status.IdleSince.Format(time.RFC3339)
+		if err := r.drainNode(ctx, node); err != nil {
+			return ctrl.Result{}, errors.Wrapf(err, "failed to scale agent node %s", name)
+		}
+		recordEvent(vjailbreakv1alpha1.NodeScaleEventDrain, name, fmt.Sprintf("Draining agent node %s, idle since %s", name, idleSince))
+	}
+	for _, name := range scaling.Delete {
+		if err := r.Delete(ctx, nodesByName[name]); err != nil && !apierrors.IsNotFound(err) {
+			return ctrl.Result{}, errors.Wrapf(err, "failed to scale agent node %s", name)
+		}
+		recordEvent(vjailbreakv1alpha1.NodeScaleEventScaleDown, name, fmt.Sprintf("Deleted drained agent node %s", name))
+	}
+	for _, name := range scaling.DeleteFailed {
+		if err := r.Delete(ctx, nodesByName[name]); err != nil && !apierrors.IsNotFound(err) {
+			return ctrl.Result{}, errors.Wrapf(err, "failed to scale agent node %s", name)
+		}
+		recordEvent(vjailbreakv1alpha1.NodeScaleEventScaleDown, name, fmt.Sprintf("Deleted agent node %s that failed to provision", name))
+	}
+	for range scaling.Create {
+		node, err := r.createAutoscaledNode(ctx, autoscaler, masterNode, migrationsPerNode)
+		if err != nil {
+			return ctrl.Result{}, errors.Wrap(err, "failed to create agent node")
+		}
+		nodes = append(nodes, node.Name)
+		recordEvent(vjailbreakv1alpha1.NodeScaleEventScaleUp, node.Name,
+			fmt.Sprintf("Created agent node %s for %d queued migrations", node.Name, queued))
+	}
+
+	autoscaler.Status.Nodes = nodes
+	autoscaler.Status.ReadyNodes = scaling.ReadyNodes
+	autoscaler.Status.QueuedMigrations = queued
+	autoscaler.Status.Message = scaling.Message
+	if len(events) > 0 {
+		autoscaler.Status.LastScaleTime = &now
+		autoscaler.Status.Events = append(autoscaler.Status.Events, events...)
+		if extra := len(autoscaler.Status.Events) - constants.VjailbreakNodeAutoscalerMaxEvents; extra > 0 {
+			autoscaler.Status.Events = autoscaler.Status.Events[extra:]
+		}
+	}
+	if !reflect.DeepEqual(&autoscaler.Status, oldStatus) {
+		if err := r.Status().Update(ctx, autoscaler); err != nil {
+			return ctrl.Result{}, errors.Wrap(err, "failed to update vjailbreak node autoscaler status")
+		}
+	}
+	return ctrl.Result{RequeueAfter: constants.VjailbreakNodeAutoscalerInterval}, nil
+}
+
+// createAutoscaledNode creates a worker VjailbreakNode with the profile of the autoscaler, the
+// VjailbreakNode controller then creates its OpenStack VM
+func (r *VjailbreakNodeReconciler) createAutoscaledNode(ctx context.Context, autoscaler *vjailbreakv1alpha1.VjailbreakNodeAutoscaler,
+	masterNode *vjailbreakv1alpha1.VjailbreakNode, migrationsPerNode int,
+) (*vjailbreakv1alpha1.VjailbreakNode, error) {
+	imageID := autoscaler.Spec.OpenstackImageID
+	if imageID == "" {
+		imageID = masterNode.Spec.OpenstackImageID
+	}
+	node := &vjailbreakv1alpha1.VjailbreakNode{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      constants.VjailbreakAgentNamePrefix + utilrand.String(6),
+			Namespace: constants.NamespaceMigrationSystem,
+			Labels: map[string]string{
+				constants.VjailbreakNodeAutoscalerLabel: autoscaler.Name,
+			},
+		},
+		Spec: vjailbreakv1alpha1.VjailbreakNodeSpec{
+			NodeRole:                "worker",
+			OpenstackCreds:          masterNode.Spec.OpenstackCreds,
+			OpenstackFlavorID:       autoscaler.Spec.OpenstackFlavorID,
+			OpenstackImageID:        imageID,
+			OpenstackVolumeType:     autoscaler.Spec.OpenstackVolumeType,
+			OpenstackSecurityGroups: autoscaler.Spec.OpenstackSecurityGroups,
+			MaxConcurrentMigrations: migrationsPerNode,
+		},
+	}
+	if err := r.Create(ctx, node); err != nil {
+		return nil, errors.Wrapf(err, "failed to create vjailbreak node %s", node.Name)
+	}
+	return node, nil
+}
+
+// setNodeIdleSince records since when an autoscaled node has no migrations, nil marks it busy
+func (r *VjailbreakNodeReconciler) setNodeIdleSince(ctx context.Context, node *vjailbreakv1alpha1.VjailbreakNode, idleSince *metav1.Time) error {
+	if (node.Status.IdleSince == nil) == (idleSince == nil) {
+		return nil
+	}
+	patch := client.MergeFrom(node.DeepCopy())
+	node.Status.IdleSince = idleSince
+	return r.Status().Patch(ctx, node, patch)
+}
+
+// drainNode stops new migrations from being placed on a node. The scheduler only places migrations
+// on ready nodes, the Kubernetes node is cordoned as well.
+func (r *VjailbreakNodeReconciler) drainNode(ctx context.Context, node *vjailbreakv1alpha1.VjailbreakNode) error {
+	patch := client.MergeFrom(node.DeepCopy())
+	node.Status.Phase = constants.VjailbreakNodePhaseDraining
+	if err := r.Status().Patch(ctx, node, patch); err != nil {
+		return errors.Wrap(err, "failed to set vjailbreak node phase to draining")
+	}
+
+	k8sNode := &corev1.Node{}
+	if err := r.Get(ctx, client.ObjectKey{Name: node.Name}, k8sNode); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return errors.Wrap(err, "failed to get node")
+	}
+	nodePatch := client.MergeFrom(k8sNode.DeepCopy())
+	k8sNode.Spec.Unschedulable = true
+	return errors.Wrap(r.Patch(ctx, k8sNode, nodePatch), "failed to cordon node")
+}
@@ -22,10 +22,13 @@ import (
 
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	k8stypes "k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	"github.com/pkg/errors"
 	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
@@ -39,12 +42,17 @@ type VjailbreakNodeReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
 	Local  bool
+	// Scheduler places the migrations on agents, the autoscaler sizes the agents to its queue
+	Scheduler *utils.MigrationScheduler
 }
 
 // +kubebuilder:rbac:groups=vjailbreak.k8s.pf9.io,resources=vjailbreaknodes,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=vjailbreak.k8s.pf9.io,resources=vjailbreaknodes/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=vjailbreak.k8s.pf9.io,resources=vjailbreaknodes/finalizers,verbs=update
-// +kubebuilder:rbac:groups=core,resources=nodes,verbs=get;list;watch;delete
+// +kubebuilder:rbac:groups=vjailbreak.k8s.pf9.io,resources=vjailbreaknodeautoscalers,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=vjailbreak.k8s.pf9.io,resources=vjailbreaknodeautoscalers/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=vjailbreak.k8s.pf9.io,resources=vjailbreaknodeautoscalers/finalizers,verbs=update
+// +kubebuilder:rbac:groups=core,resources=nodes,verbs=get;list;watch;update;patch;delete
 
 // Reconcile handles the reconciliation of VjailbreakNode resources
 func (r *VjailbreakNodeReconciler) Reconcile(ctx context.Context, req ctrl.Request) (_ ctrl.Result, reterr error) {
@@ -81,9 +89,15 @@ func (r *VjailbreakNodeReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 		return r.reconcileDelete(ctx, vjailbreakNodeScope)
 	}
 
-	// Quick path for just updating ActiveMigrations if node is ready
-	if vjailbreakNode.Status.Phase == constants.VjailbreakNodePhaseNodeReady {
-		return r.updateActiveMigrations(ctx, vjailbreakNodeScope)
+	// Quick path for just updating ActiveMigrations if node is ready or draining
+	if vjailbreakNode.Status.Phase == constants.VjailbreakNodePhaseNodeReady ||
+		vjailbreakNode.Status.Phase == constants.VjailbreakNodePhaseDraining {
+		result, err := r.updateActiveMigrations(ctx, vjailbreakNodeScope)
+		if err != nil || vjailbreakNode.Spec.NodeRole != constants.NodeRoleMaster {
+			return result, err
+		}
+		// The master node runs the autoscaler of the agent nodes
+		return r.reconcileAutoscaler(ctx, vjailbreakNodeScope)
 	}
 
 	// Handle regular VjailbreakNode reconcile
@@ -107,6 +121,9 @@ func (r *VjailbreakNodeReconciler) reconcileNormal(ctx context.Context,
 			return ctrl.Result{RequeueAfter: 30 * time.Second}, errors.Wrap(err, "failed to update master node image id")
 		}
 		log.Info("Skipping master node, updating flavor", "name", vjNode.Name)
+		if _, err := r.reconcileAutoscaler(ctx, scope); err != nil {
+			return ctrl.Result{RequeueAfter: 30 * time.Second}, errors.Wrap(err, "failed to reconcile autoscaler")
+		}
 		return ctrl.Result{RequeueAfter: 1 * time.Minute}, nil
 	}
 
@@ -249,24 +266,50 @@ func (r *VjailbreakNodeReconciler) reconcileDelete(ctx context.Context,
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *VjailbreakNodeReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.Scheduler == nil {
+		r.Scheduler = utils.NewMigrationScheduler()
+	}
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&vjailbreakv1alpha1.VjailbreakNode{}).
+		// Changes to the autoscaler are handled by the reconcile of the master node
+		Watches(&vjailbreakv1alpha1.VjailbreakNodeAutoscaler{}, handler.EnqueueRequestsFromMapFunc(
+			func(_ context.Context, _ client.Object) []reconcile.Request {
+				return []reconcile.Request{{NamespacedName: k8stypes.NamespacedName{
+					Name:      constants.VjailbreakMasterNodeName,
+					Namespace: constants.NamespaceMigrationSystem,
+				}}}
+			})).
 		Complete(r)
 }
 
-// updateActiveMigrations efficiently updates just the ActiveMigrations field
+// updateActiveMigrations efficiently updates just the ActiveMigrations and migration load fields
 func (r *VjailbreakNodeReconciler) updateActiveMigrations(ctx context.Context,
 	scope *scope.VjailbreakNodeScope) (ctrl.Result, error) {
 	vjNode := scope.VjailbreakNode
 
+	// Migrations record the Kubernetes node they run on, which differs from the name of the master entry
+	nodeName, err := utils.GetK8sNodeNameForVjailbreakNode(ctx, r.Client, vjNode)
+	if err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "failed to get kubernetes node name")
+	}
+
 	// Get active migrations happening on the node
-	activeMigrations, err := utils.GetActiveMigrations(ctx, vjNode.Name, r.Client)
+	activeMigrations, err := utils.GetActiveMigrations(ctx, nodeName, r.Client)
 	if err != nil {
 		return ctrl.Result{}, errors.Wrap(err, "failed to get active migrations")
 	}
-	// Create a patch to update only the ActiveMigrations field
+	bytesRemaining, throughput, err := utils.GetMigrationLoad(ctx, nodeName, r.Client)
+	if err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "failed to get migration load")
+	}
+	// Create a patch to update only the migration fields
 	patch := client.MergeFrom(vjNode.DeepCopy())
 	vjNode.Status.ActiveMigrations = activeMigrations
+	vjNode.Status.BytesRemaining = bytesRemaining
+	// An idle node keeps the throughput it was last measured at
+	if throughput > 0 {
+		vjNode.Status.ThroughputBytesPerSecond = throughput
+	}
 
 	err = r.Client.Status().Patch(ctx, vjNode, patch)
 	if err != nil {
@@ -23,10 +23,13 @@ import (
 
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/log"
@@ -44,6 +47,9 @@ import (
 type VMwareCredsReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+	// Inventory keeps the VMware objects in sync with vCenter between reconciles. When it is nil,
+	// every reconcile scans the inventory in full
+	Inventory *utils.VMwareInventoryWatchers
 }
 
 // +kubebuilder:rbac:groups=vjailbreak.k8s.pf9.io,resources=vmwarecreds,verbs=get;list;watch;create;update;patch;delete
@@ -130,32 +136,52 @@ func (r *VMwareCredsReconciler) reconcileNormal(ctx context.Context, scope *scop
 	}
 	ctxlog.Info("Successfully validated VMwareCreds, adding finalizer", "name", scope.Name(), "finalizers", scope.VMwareCreds.Finalizers)
 	controllerutil.AddFinalizer(scope.VMwareCreds, constants.VMwareCredsFinalizer)
-	err = utils.CreateVMwareClustersAndHosts(ctx, scope)
+	// The inventory session applies the changes made in vCenter as they happen, the inventory is
+	// only scanned in full when the session starts
+	if r.Inventory == nil || !r.Inventory.Watch(scope.VMwareCreds) {
+		if err := r.syncInventory(ctx, scope); err != nil {
+			return ctrl.Result{}, err
+		}
+		if r.Inventory != nil {
+			r.Inventory.MarkSynced(scope.VMwareCreds)
+		}
+		synced := metav1.Now()
+		scope.VMwareCreds.Status.InventorySyncedTime = &synced
+		if err := r.Status().Update(ctx, scope.VMwareCreds); err != nil {
+			return ctrl.Result{}, errors.Wrap(err, fmt.Sprintf("Error updating inventory sync time of VMwareCreds '%s'", scope.Name()))
+		}
+	}
+	// Get vjailbreak settings to get requeue after time
+	vjailbreakSettings, err := k8sutils.GetVjailbreakSettings(ctx, r.Client)
 	if err != nil {
-		return ctrl.Result{}, errors.Wrap(err, fmt.Sprintf("Error creating VMs for VMwareCreds '%s'", scope.Name()))
+		return ctrl.Result{}, errors.Wrap(err, "failed to get vjailbreak settings")
+	}
+	return ctrl.Result{RequeueAfter: time.Duration(vjailbreakSettings.VMwareCredsRequeueAfterMinutes) * time.Minute}, nil
+}
+
+// syncInventory scans the vCenter inventory in full and updates the VMware objects
+func (r *VMwareCredsReconciler) syncInventory(ctx context.Context, scope *scope.VMwareCredsScope) error {
+	err := utils.CreateVMwareClustersAndHosts(ctx, scope)
+	if err != nil {
+		return errors.Wrap(err, fmt.Sprintf("Error creating VMs for VMwareCreds '%s'", scope.Name()))
 	}
 	vminfo, rdmDiskMap, err := utils.GetAndCreateAllVMs(ctx, scope, scope.VMwareCreds.Spec.DataCenter)
 	if err != nil {
-		return ctrl.Result{}, errors.Wrap(err, fmt.Sprintf("Error getting info of all VMs for VMwareCreds '%s'", scope.Name()))
+		return errors.Wrap(err, fmt.Sprintf("Error getting info of all VMs for VMwareCreds '%s'", scope.Name()))
 	}
 	err = utils.CreateOrUpdateRDMDisks(ctx, r.Client, scope.VMwareCreds, rdmDiskMap)
 	if err != nil {
-		return ctrl.Result{}, errors.Wrap(err, fmt.Sprintf("Error creating RDM disk CR for VMwareCreds '%s'", scope.Name()))
+		return errors.Wrap(err, fmt.Sprintf("Error creating RDM disk CR for VMwareCreds '%s'", scope.Name()))
 	}
 	err = utils.DeleteStaleVMwareMachines(ctx, r.Client, scope.VMwareCreds, vminfo)
 	if err != nil {
-		return ctrl.Result{}, errors.Wrap(err, fmt.Sprintf("Error finding deleted VMs for VMwareCreds '%s'", scope.Name()))
+		return errors.Wrap(err, fmt.Sprintf("Error finding deleted VMs for VMwareCreds '%s'", scope.Name()))
 	}
 	err = utils.DeleteStaleVMwareClustersAndHosts(ctx, scope)
 	if err != nil {
-		return ctrl.Result{}, errors.Wrap(err, fmt.Sprintf("Error finding deleted clusters and hosts for VMwareCreds '%s'", scope.Name()))
-	}
-	// Get vjailbreak settings to get requeue after time
-	vjailbreakSettings, err := k8sutils.GetVjailbreakSettings(ctx, r.Client)
-	if err != nil {
-		return ctrl.Result{}, errors.Wrap(err, "failed to get vjailbreak settings")
+		return errors.Wrap(err, fmt.Sprintf("Error finding deleted clusters and hosts for VMwareCreds '%s'", scope.Name()))
 	}
-	return ctrl.Result{RequeueAfter: time.Duration(vjailbreakSettings.VMwareCredsRequeueAfterMinutes) * time.Minute}, nil
+	return nil
 }
 
 // nolint:unparam
@@ -164,6 +190,9 @@ func (r *VMwareCredsReconciler) reconcileDelete(ctx context.Context, scope *scop
 
 	// Cleanup cached VMware client
 	utils.CleanupCachedVMwareClient(ctx, scope.VMwareCreds)
+	if r.Inventory != nil {
+		r.Inventory.Stop(scope.VMwareCreds)
+	}
 
 	err := utils.DeleteDependantObjectsForVMwareCreds(ctx, scope)
 	if err != nil {
@@ -181,8 +210,16 @@ func (r *VMwareCredsReconciler) reconcileDelete(ctx context.Context, scope *scop
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *VMwareCredsReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.Inventory == nil {
+		r.Inventory = utils.NewVMwareInventoryWatchers(r.Client)
+	}
+	if err := mgr.Add(r.Inventory); err != nil {
+		return errors.Wrap(err, "failed to add VMware inventory watchers")
+	}
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&vjailbreakv1alpha1.VMwareCreds{}).
 		WithEventFilter(predicate.GenerationChangedPredicate{}).
+		// VMwareCreds whose inventory session was lost are resynced
+		WatchesRawSource(source.Channel(r.Inventory.Events(), &handler.EnqueueRequestForObject{})).
 		Complete(r)
 }
@@ -24,6 +24,19 @@ const (
 	// MaxJobNameLength defines the maximum length of a job name
 	MaxJobNameLength = 46 // 63 - 11 (prefix v2v-helper-) - 1 (hyphen) - 5 (hash)
 
+	// DefaultHookTimeoutSeconds is how long a hook Job may run when its hook sets no timeout
+	DefaultHookTimeoutSeconds = int64(600)
+
+	// OOMKilledExitCode is the exit code of a container killed by the OOM killer
+	OOMKilledExitCode = int32(137)
+
+	// FileSourceMountPath is where the PersistentVolumeClaim of a file source is mounted in the migration pods
+	FileSourceMountPath = "/home/fedora/source"
+	// FileDestinationMountPath is where the PersistentVolumeClaim of a file destination is mounted in the migration pods
+	FileDestinationMountPath = "/home/fedora/destination"
+	// FileDestinationType selects the file destination in the migration configmap
+	FileDestinationType = "file"
+
 	// VjailbreakNodeControllerName is the name of the vjailbreak node controller
 	VjailbreakNodeControllerName = "vjailbreaknode-controller"
 
@@ -153,6 +166,21 @@ const (
 	// VjailbreakNodePhaseError is the phase for node in error state
 	VjailbreakNodePhaseError = vjailbreakv1alpha1.VjailbreakNodePhase("Error")
 
+	// VjailbreakNodePhaseDraining is the phase for a node that takes no new migrations before it is deleted
+	VjailbreakNodePhaseDraining = vjailbreakv1alpha1.VjailbreakNodePhase("Draining")
+
+	// VjailbreakNodeAutoscalerLabel is the label with the name of the autoscaler that created a node
+	VjailbreakNodeAutoscalerLabel = "vjailbreak.k8s.pf9.io/autoscaler"
+
+	// VjailbreakNodeAutoscalerInterval is how often the autoscaler checks the migration queue
+	VjailbreakNodeAutoscalerInterval = time.Minute
+
+	// VjailbreakNodeAutoscalerMaxEvents is the number of scale events kept in the autoscaler status
+	VjailbreakNodeAutoscalerMaxEvents = 20
+
+	// VjailbreakAgentNamePrefix is the name prefix of agent nodes
+	VjailbreakAgentNamePrefix = "vjailbreak-agent-"
+
 	// NamespaceMigrationSystem is the namespace for migration system
 	NamespaceMigrationSystem = "migration-system"
 
@@ -168,6 +196,24 @@ const (
 	// CredsRequeueAfter is the time to requeue after
 	CredsRequeueAfter = 1 * time.Minute
 
+	// MigrationWaveRequeueAfter is how often an unfinished migration wave checks its plans
+	MigrationWaveRequeueAfter = 30 * time.Second
+
+	// VMwareInventoryFlushInterval is how often the vCenter inventory changes seen by a VMwareCreds
+	// are applied, so that a burst of changes to a VM is applied once
+	VMwareInventoryFlushInterval = 10 * time.Second
+
+	// VMwareInventoryRetryAfter is the wait before the inventory of a VMwareCreds is resynced after
+	// its vCenter session was lost
+	VMwareInventoryRetryAfter = 1 * time.Minute
+
+	// VMTagsBatchSize is the number of VMs whose tags are fetched from vCenter in one request
+	VMTagsBatchSize = 500
+
+	// VMwareTagsRefreshInterval is how often the tags of the VMs of a VMwareCreds are polled, attaching
+	// or detaching a tag does not show in the inventory session
+	VMwareTagsRefreshInterval = 5 * time.Minute
+
 	// OpenstackCredsRequeueAfter is the time to requeue after.
 	OpenstackCredsRequeueAfterMinutes = 60
 
@@ -183,6 +229,28 @@ const (
 	// MigrationReason is the reason for migration
 	MigrationReason = "Migration"
 
+	// MigrationQueuedReason is the reason of the Scheduled condition of a migration waiting for a free agent
+	MigrationQueuedReason = "Queued"
+
+	// MigrationScheduledReason is the reason of the Scheduled condition of a migration placed on an agent
+	MigrationScheduledReason = "Scheduled"
+
+	// AgentLostTimeout is how long the node of an agent may be gone or NotReady before its unfinished
+	// migrations are placed on another agent
+	AgentLostTimeout = 5 * time.Minute
+
+	// MigrationSourceLimitReason is the reason of the Scheduled condition of a migration waiting for a
+	// free slot of its vCenter, ESXi host or datastore
+	MigrationSourceLimitReason = "SourceLimitReached"
+
+	// MigrationMaintenanceWindowReason is the reason of the Scheduled condition of a migration waiting
+	// for the maintenance window of its plan to open
+	MigrationMaintenanceWindowReason = "MaintenanceWindowClosed"
+
+	// MigrationDependencyReason is the reason of the Scheduled condition of a cold migration waiting
+	// for the VMs it depends on
+	MigrationDependencyReason = "WaitingForDependencies"
+
 	// StartCutOverYes is the value for start cut over yes
 	StartCutOverYes = "yes"
 
@@ -203,6 +271,15 @@ const (
 
 	// RDMDiskControllerName is the name of the RDM disk controller
 	RDMDiskControllerName = "rdmdisk-controller"
+
+	// MigrationPreflightControllerName is the name of the migration preflight controller
+	MigrationPreflightControllerName = "migrationpreflight-controller"
+
+	// MaintenanceWindowControllerName is the name of the maintenance window controller
+	MaintenanceWindowControllerName = "maintenancewindow-controller"
+
+	// MigrationWaveControllerName is the name of the migration wave controller
+	MigrationWaveControllerName = "migrationwave-controller"
 	// VCenterVMScanConcurrencyLimit is the limit for concurrency while scanning vCenter VMs
 	VCenterVMScanConcurrencyLimit = 100
 
@@ -245,9 +322,14 @@ const (
 
 	// MigrationPlan status message prefix
 	MigrationPlanValidationFailedPrefix = "Migration plan validation failed"
+	// MigrationPlanWaitingForCapacityPrefix is the status message prefix of a strict capacity plan
+	// waiting for quota in the target project
+	MigrationPlanWaitingForCapacityPrefix = "Waiting for capacity"
 
 	// ValidationStatusFailed is the status value for failed validation
 	ValidationStatusFailed = "Failed"
+	// ValidationStatusSucceeded is the status value for successful validation
+	ValidationStatusSucceeded = "Succeeded"
 
 	// VjailbreakSettingsConfigMapName is the name of the vjailbreak settings configmap
 	VjailbreakSettingsConfigMapName = "vjailbreak-settings"
@@ -293,9 +375,18 @@ runcmd:
 	// MigrationConditionTypeStorageAcceleratedCopy represents the condition type for StorageAcceleratedCopy phases
 	MigrationConditionTypeStorageAcceleratedCopy corev1.PodConditionType = "StorageAcceleratedCopy"
 
+	// MigrationConditionTypeDataVerified represents the condition type for data integrity verification
+	MigrationConditionTypeDataVerified corev1.PodConditionType = "DataVerified"
+
 	// MigrationConditionTypeMigrated represents the condition type for successful completion
 	MigrationConditionTypeMigrated corev1.PodConditionType = "Migrated"
 
+	// MigrationConditionTypeScheduled represents the condition type for the placement of the migration on an agent node
+	MigrationConditionTypeScheduled corev1.PodConditionType = "Scheduled"
+
+	// MigrationConditionTypeTestBoot represents the condition type for the result of the last test boot
+	MigrationConditionTypeTestBoot corev1.PodConditionType = "TestBoot"
+
 	// VMMigrationStatesEnum is a map of migration phase to state
 	VMMigrationStatesEnum = map[vjailbreakv1alpha1.VMMigrationPhase]int{
 		vjailbreakv1alpha1.VMMigrationPhasePending:               0,
@@ -313,11 +404,13 @@ runcmd:
 		// Common phases to both the copy methods.
 		vjailbreakv1alpha1.VMMigrationPhaseCopying:                  11,
 		vjailbreakv1alpha1.VMMigrationPhaseCopyingChangedBlocks:     12,
-		vjailbreakv1alpha1.VMMigrationPhaseConvertingDisk:           13,
-		vjailbreakv1alpha1.VMMigrationPhaseAwaitingCutOverStartTime: 14,
-		vjailbreakv1alpha1.VMMigrationPhaseAwaitingAdminCutOver:     15,
-		vjailbreakv1alpha1.VMMigrationPhaseSucceeded:                16,
-		vjailbreakv1alpha1.VMMigrationPhaseUnknown:                  17,
+		vjailbreakv1alpha1.VMMigrationPhaseVerifyingData:            13,
+		vjailbreakv1alpha1.VMMigrationPhaseConvertingDisk:           14,
+		vjailbreakv1alpha1.VMMigrationPhaseAwaitingCutOverStartTime: 15,
+		vjailbreakv1alpha1.VMMigrationPhaseAwaitingAdminCutOver:     16,
+		vjailbreakv1alpha1.VMMigrationPhaseSucceeded:                17,
+		vjailbreakv1alpha1.VMMigrationPhaseRolledBack:               18,
+		vjailbreakv1alpha1.VMMigrationPhaseUnknown:                  19,
 	}
 
 	// MigrationJobTTL is the TTL for migration job
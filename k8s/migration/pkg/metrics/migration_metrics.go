@@ -112,11 +112,13 @@ func UpdateMigrationPhase(migrationName, vmName, namespace, agentName string, ph
 		vjailbreakv1alpha1.VMMigrationPhaseAwaitingDataCopyStart,
 		vjailbreakv1alpha1.VMMigrationPhaseCopying,
 		vjailbreakv1alpha1.VMMigrationPhaseCopyingChangedBlocks,
+		vjailbreakv1alpha1.VMMigrationPhaseVerifyingData,
 		vjailbreakv1alpha1.VMMigrationPhaseConvertingDisk,
 		vjailbreakv1alpha1.VMMigrationPhaseAwaitingCutOverStartTime,
 		vjailbreakv1alpha1.VMMigrationPhaseAwaitingAdminCutOver,
 		vjailbreakv1alpha1.VMMigrationPhaseSucceeded,
 		vjailbreakv1alpha1.VMMigrationPhaseFailed,
+		vjailbreakv1alpha1.VMMigrationPhaseRolledBack,
 		vjailbreakv1alpha1.VMMigrationPhaseUnknown,
 	}
 
@@ -166,11 +168,13 @@ func CleanupMigrationMetrics(migrationName, vmName, namespace, migrationPlan, ag
 		vjailbreakv1alpha1.VMMigrationPhaseAwaitingDataCopyStart,
 		vjailbreakv1alpha1.VMMigrationPhaseCopying,
 		vjailbreakv1alpha1.VMMigrationPhaseCopyingChangedBlocks,
+		vjailbreakv1alpha1.VMMigrationPhaseVerifyingData,
 		vjailbreakv1alpha1.VMMigrationPhaseConvertingDisk,
 		vjailbreakv1alpha1.VMMigrationPhaseAwaitingCutOverStartTime,
 		vjailbreakv1alpha1.VMMigrationPhaseAwaitingAdminCutOver,
 		vjailbreakv1alpha1.VMMigrationPhaseSucceeded,
 		vjailbreakv1alpha1.VMMigrationPhaseFailed,
+		vjailbreakv1alpha1.VMMigrationPhaseRolledBack,
 		vjailbreakv1alpha1.VMMigrationPhaseUnknown,
 	}
 
@@ -0,0 +1,304 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+	"github.com/platform9/vjailbreak/k8s/migration/pkg/constants"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// MigrationScheduler serializes the placement of migrations on agents and source slots. The cached
+// Migration list lags behind the placements written to the status of the migrations, so the
+// scheduler remembers the placements it made until the cache shows them. Without it a batch of
+// migrations placed in one reconcile, or in parallel reconciles, would see the same free slot.
+type MigrationScheduler struct {
+	// mu serializes placements
+	mu sync.Mutex
+	// placedMu guards placed, which is also read outside of placements
+	placedMu sync.Mutex
+	placed   map[k8stypes.NamespacedName]migrationPlacement
+}
+
+// migrationPlacement is the agent and the source a migration was placed on
+type migrationPlacement struct {
+	agent  string
+	source *vjailbreakv1alpha1.MigrationSource
+}
+
+// NewMigrationScheduler returns a MigrationScheduler without placements
+func NewMigrationScheduler() *MigrationScheduler {
+	return &MigrationScheduler{placed: map[k8stypes.NamespacedName]migrationPlacement{}}
+}
+
+// Lock serializes placements, it is held from listing the migrations until the placement is recorded
+func (s *MigrationScheduler) Lock() {
+	s.mu.Lock()
+}
+
+// Unlock ends a placement
+func (s *MigrationScheduler) Unlock() {
+	s.mu.Unlock()
+}
+
+// Placed records that migration holds a slot of agent and source
+func (s *MigrationScheduler) Placed(migration *vjailbreakv1alpha1.Migration, agent string, source *vjailbreakv1alpha1.MigrationSource) {
+	s.placedMu.Lock()
+	defer s.placedMu.Unlock()
+	s.placed[k8stypes.NamespacedName{Namespace: migration.Namespace, Name: migration.Name}] = migrationPlacement{agent: agent, source: source}
+}
+
+// ListMigrations returns the migrations of all plans, with the placements the cache does not show yet
+func (s *MigrationScheduler) ListMigrations(ctx context.Context, k3sclient client.Client) ([]vjailbreakv1alpha1.Migration, error) {
+	migrationList := &vjailbreakv1alpha1.MigrationList{}
+	if err := k3sclient.List(ctx, migrationList); err != nil {
+		return nil, errors.Wrap(err, "failed to list migrations")
+	}
+	return s.applyPlacements(migrationList.Items), nil
+}
+
+// applyPlacements sets the recorded placements on the migrations that do not show them yet, and
+// forgets the placements the migrations show or whose migration is gone
+func (s *MigrationScheduler) applyPlacements(migrations []vjailbreakv1alpha1.Migration) []vjailbreakv1alpha1.Migration {
+	s.placedMu.Lock()
+	defer s.placedMu.Unlock()
+	seen := map[k8stypes.NamespacedName]bool{}
+	for i := range migrations {
+		migration := &migrations[i]
+		key := k8stypes.NamespacedName{Namespace: migration.Namespace, Name: migration.Name}
+		placement, ok := s.placed[key]
+		if !ok {
+			continue
+		}
+		if migration.Status.AgentName != "" {
+			delete(s.placed, key)
+			continue
+		}
+		seen[key] = true
+		migration.Status.AgentName = placement.agent
+		migration.Status.Source = placement.source
+	}
+	for key := range s.placed {
+		if !seen[key] {
+			delete(s.placed, key)
+		}
+	}
+	return migrations
+}
+
+// agentLoad is the load of an agent node as seen by the migration scheduler
+type agentLoad struct {
+	name           string
+	active         int
+	limit          int
+	bytesRemaining int64
+	throughput     int64
+}
+
+// migrationFinishedPhases are the phases of migrations that no longer hold a slot on an agent
+var migrationFinishedPhases = []vjailbreakv1alpha1.VMMigrationPhase{
+	vjailbreakv1alpha1.VMMigrationPhaseSucceeded,
+	vjailbreakv1alpha1.VMMigrationPhaseFailed,
+	vjailbreakv1alpha1.VMMigrationPhaseRolledBack,
+	vjailbreakv1alpha1.VMMigrationPhaseValidationFailed,
+}
+
+// GetK8sNodeNameForVjailbreakNode returns the name of the Kubernetes node of a VjailbreakNode. Agents
+// are named after their node, the master node entry has a fixed name.
+func GetK8sNodeNameForVjailbreakNode(ctx context.Context, k3sclient client.Client, vjNode *vjailbreakv1alpha1.VjailbreakNode) (string, error) {
+	if vjNode.Spec.NodeRole != constants.NodeRoleMaster {
+		return vjNode.Name, nil
+	}
+	masterNode, err := GetMasterK8sNode(ctx, k3sclient)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get master node")
+	}
+	return masterNode.Name, nil
+}
+
+// SelectAgentForMigration picks the agent node the migration runs on. Only ready nodes with fewer
+// active migrations than their limit are considered, the limit of a node is its
+// MaxConcurrentMigrations or defaultLimit. Of those the node with the fewest active migrations wins,
+// ties go to the node that finishes its remaining copies first at its measured throughput. The
+// free slots go to the ahead migrations that waited longer first. When no slot is left for the
+// migration the returned node name is empty and the message tells why the migration is queued.
+func SelectAgentForMigration(ctx context.Context, k3sclient client.Client, migrations []vjailbreakv1alpha1.Migration,
+	migration *vjailbreakv1alpha1.Migration, defaultLimit, ahead int,
+) (string, string, error) {
+	nodeList, err := GetAllk8sNodes(ctx, k3sclient)
+	if err != nil {
+		return "", "", errors.Wrap(err, "failed to list nodes")
+	}
+	loads := map[string]*agentLoad{}
+	for i := range nodeList.Items {
+		node := &nodeList.Items[i]
+		if node.Spec.Unschedulable || !IsNodeReady(node) {
+			continue
+		}
+		load := &agentLoad{name: node.Name, limit: defaultLimit}
+		vjNodeName := node.Name
+		if IsMasterNode(node) {
+			vjNodeName = constants.VjailbreakMasterNodeName
+		}
+		vjNode := &vjailbreakv1alpha1.VjailbreakNode{}
+		err := k3sclient.Get(ctx, k8stypes.NamespacedName{Name: vjNodeName, Namespace: constants.NamespaceMigrationSystem}, vjNode)
+		switch {
+		case err == nil:
+			if vjNode.Status.Phase != constants.VjailbreakNodePhaseNodeReady {
+				continue
+			}
+			if vjNode.Spec.MaxConcurrentMigrations > 0 {
+				load.limit = vjNode.Spec.MaxConcurrentMigrations
+			}
+			load.throughput = vjNode.Status.ThroughputBytesPerSecond
+		case !apierrors.IsNotFound(err):
+			return "", "", errors.Wrapf(err, "failed to get vjailbreak node %s", vjNodeName)
+		}
+		loads[node.Name] = load
+	}
+	if len(loads) == 0 {
+		return "", "No agent node is ready", nil
+	}
+
+	countAgentSlots(loads, migrations, migration)
+	free := freeAgentSlots(loads)
+	if free == 0 {
+		return "", fmt.Sprintf("Queued, all %d agent nodes are running their maximum number of migrations", len(loads)), nil
+	}
+	if free <= ahead {
+		return "", fmt.Sprintf("Queued behind %d migrations waiting for an agent node", ahead), nil
+	}
+	agent := pickLeastLoadedAgent(loads)
+	return agent, fmt.Sprintf("Scheduled on agent node %s", agent), nil
+}
+
+// IsAgentLost tells if the agent node a migration was placed on is gone, or has not been ready for
+// longer than constants.AgentLostTimeout
+func IsAgentLost(ctx context.Context, k3sclient client.Client, agent string, now time.Time) (bool, error) {
+	node := &corev1.Node{}
+	if err := k3sclient.Get(ctx, k8stypes.NamespacedName{Name: agent}, node); err != nil {
+		if apierrors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, errors.Wrapf(err, "failed to get node %s", agent)
+	}
+	return isNodeLost(node, now), nil
+}
+
+// isNodeLost tells if node has not been ready for longer than constants.AgentLostTimeout. A node
+// that reports no Ready condition yet is not lost.
+func isNodeLost(node *corev1.Node, now time.Time) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == corev1.NodeReady {
+			return condition.Status != corev1.ConditionTrue && now.Sub(condition.LastTransitionTime.Time) > constants.AgentLostTimeout
+		}
+	}
+	return false
+}
+
+// IsMigrationFinished tells if the migration no longer holds a slot on an agent
+func IsMigrationFinished(migration *vjailbreakv1alpha1.Migration) bool {
+	return slices.Contains(migrationFinishedPhases, migration.Status.Phase)
+}
+
+// countAgentSlots adds the unfinished migrations placed on the agents to their load, leaving out
+// the migration being placed
+func countAgentSlots(loads map[string]*agentLoad, migrations []vjailbreakv1alpha1.Migration, migration *vjailbreakv1alpha1.Migration) {
+	for i := range migrations {
+		other := &migrations[i]
+		if other.Namespace == migration.Namespace && other.Name == migration.Name {
+			continue
+		}
+		if other.Status.AgentName == "" || slices.Contains(migrationFinishedPhases, other.Status.Phase) {
+			continue
+		}
+		if load, ok := loads[other.Status.AgentName]; ok {
+			load.active++
+			load.bytesRemaining += other.Status.BytesRemaining
+		}
+	}
+}
+
+// freeAgentSlots returns the number of migrations the agents can take on
+func freeAgentSlots(loads map[string]*agentLoad) int {
+	free := 0
+	for _, load := range loads {
+		free += max(load.limit-load.active, 0)
+	}
+	return free
+}
+
+// pickLeastLoadedAgent returns the name of the agent with a free slot and the fewest active
+// migrations, then the shortest time to copy its remaining bytes. Agents without a measured
+// throughput are assumed to copy at the average rate of the others.
+func pickLeastLoadedAgent(loads map[string]*agentLoad) string {
+	var measured, total int64
+	for _, load := range loads {
+		if load.throughput > 0 {
+			measured++
+			total += load.throughput
+		}
+	}
+	averageThroughput := int64(1)
+	if measured > 0 {
+		averageThroughput = total / measured
+	}
+	drainSeconds := func(load *agentLoad) int64 {
+		if load.throughput > 0 {
+			return load.bytesRemaining / load.throughput
+		}
+		return load.bytesRemaining / averageThroughput
+	}
+
+	candidates := []*agentLoad{}
+	for _, load := range loads {
+		if load.active < load.limit {
+			candidates = append(candidates, load)
+		}
+	}
+	if len(candidates) == 0 {
+		return ""
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.active != b.active {
+			return a.active < b.active
+		}
+		if da, db := drainSeconds(a), drainSeconds(b); da != db {
+			return da < db
+		}
+		return a.name < b.name
+	})
+	return candidates[0].name
+}
+
+// GetMigrationQueue returns the number of unfinished migrations placed on each node and the number
+// of migrations queued for a free agent. Migrations waiting for a slot of their source are not
+// counted as queued, more agents would not run them sooner.
+func GetMigrationQueue(migrations []vjailbreakv1alpha1.Migration) (map[string]int, int) {
+	assigned := map[string]int{}
+	queued := 0
+	for i := range migrations {
+		migration := &migrations[i]
+		if slices.Contains(migrationFinishedPhases, migration.Status.Phase) {
+			continue
+		}
+		if migration.Status.AgentName != "" {
+			assigned[migration.Status.AgentName]++
+			continue
+		}
+		if isMigrationWaiting(migration, constants.MigrationQueuedReason) {
+			queued++
+		}
+	}
+	return assigned, queued
+}
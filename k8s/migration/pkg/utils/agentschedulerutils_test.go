@@ -0,0 +1,195 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+	"github.com/platform9/vjailbreak/k8s/migration/pkg/constants"
+	"github.com/platform9/vjailbreak/k8s/migration/pkg/sdk/testutils"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newTestMigration(name, agent string, phase vjailbreakv1alpha1.VMMigrationPhase) vjailbreakv1alpha1.Migration {
+	return vjailbreakv1alpha1.Migration{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "migration-system"},
+		Status:     vjailbreakv1alpha1.MigrationStatus{AgentName: agent, Phase: phase},
+	}
+}
+
+func TestPickLeastLoadedAgent(t *testing.T) {
+	tests := []struct {
+		name  string
+		loads []agentLoad
+		want  string
+	}{
+		{
+			name:  "no agents",
+			loads: nil,
+			want:  "",
+		},
+		{
+			name: "all agents full",
+			loads: []agentLoad{
+				{name: "a", active: 2, limit: 2},
+				{name: "b", active: 3, limit: 2},
+			},
+			want: "",
+		},
+		{
+			name: "fewest active migrations",
+			loads: []agentLoad{
+				{name: "a", active: 2, limit: 4},
+				{name: "b", active: 1, limit: 4},
+			},
+			want: "b",
+		},
+		{
+			name: "full agent is skipped",
+			loads: []agentLoad{
+				{name: "a", active: 1, limit: 1},
+				{name: "b", active: 3, limit: 4},
+			},
+			want: "b",
+		},
+		{
+			name: "tie goes to the agent that drains first",
+			loads: []agentLoad{
+				{name: "a", active: 1, limit: 4, bytesRemaining: 1000, throughput: 10},
+				{name: "b", active: 1, limit: 4, bytesRemaining: 1000, throughput: 100},
+			},
+			want: "b",
+		},
+		{
+			name: "unmeasured agent copies at the average rate",
+			loads: []agentLoad{
+				{name: "a", active: 1, limit: 4, bytesRemaining: 1000, throughput: 10},
+				{name: "b", active: 1, limit: 4, bytesRemaining: 500},
+			},
+			want: "b",
+		},
+		{
+			name: "tie on load and drain time goes to the first name",
+			loads: []agentLoad{
+				{name: "b", active: 1, limit: 4},
+				{name: "a", active: 1, limit: 4},
+			},
+			want: "a",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loads := map[string]*agentLoad{}
+			for i := range tt.loads {
+				loads[tt.loads[i].name] = &tt.loads[i]
+			}
+			testutils.Equals(t, tt.want, pickLeastLoadedAgent(loads))
+		})
+	}
+}
+
+func TestCountAgentSlots(t *testing.T) {
+	migration := newTestMigration("vm-self", "a", vjailbreakv1alpha1.VMMigrationPhaseCopying)
+	migrations := []vjailbreakv1alpha1.Migration{
+		migration,
+		newTestMigration("vm-1", "a", vjailbreakv1alpha1.VMMigrationPhaseCopying),
+		newTestMigration("vm-2", "a", vjailbreakv1alpha1.VMMigrationPhaseSucceeded),
+		newTestMigration("vm-3", "b", vjailbreakv1alpha1.VMMigrationPhaseFailed),
+		newTestMigration("vm-4", "b", vjailbreakv1alpha1.VMMigrationPhasePending),
+		newTestMigration("vm-5", "", vjailbreakv1alpha1.VMMigrationPhasePending),
+		newTestMigration("vm-6", "gone", vjailbreakv1alpha1.VMMigrationPhaseCopying),
+	}
+	migrations[1].Status.BytesRemaining = 100
+	loads := map[string]*agentLoad{
+		"a": {name: "a", limit: 2},
+		"b": {name: "b", limit: 2},
+	}
+	countAgentSlots(loads, migrations, &migration)
+	testutils.Equals(t, 1, loads["a"].active)
+	testutils.Equals(t, int64(100), loads["a"].bytesRemaining)
+	testutils.Equals(t, 1, loads["b"].active)
+}
+
+// TestMigrationSchedulerBatch places a batch of migrations against a cache that does not show the
+// placements yet, every agent slot is handed out once
+func TestMigrationSchedulerBatch(t *testing.T) {
+	scheduler := NewMigrationScheduler()
+	cached := []vjailbreakv1alpha1.Migration{
+		newTestMigration("vm-1", "", vjailbreakv1alpha1.VMMigrationPhasePending),
+		newTestMigration("vm-2", "", vjailbreakv1alpha1.VMMigrationPhasePending),
+		newTestMigration("vm-3", "", vjailbreakv1alpha1.VMMigrationPhasePending),
+	}
+	placed := []string{}
+	for i := range cached {
+		migrations := scheduler.applyPlacements(cloneMigrations(cached))
+		loads := map[string]*agentLoad{
+			"a": {name: "a", limit: 1},
+			"b": {name: "b", limit: 1},
+		}
+		countAgentSlots(loads, migrations, &cached[i])
+		agent := pickLeastLoadedAgent(loads)
+		if agent != "" {
+			scheduler.Placed(&cached[i], agent, &vjailbreakv1alpha1.MigrationSource{VMwareCreds: "vcenter"})
+		}
+		placed = append(placed, agent)
+	}
+	testutils.Equals(t, []string{"a", "b", ""}, placed)
+
+	migrations := scheduler.applyPlacements(cloneMigrations(cached))
+	testutils.Equals(t, "a", migrations[0].Status.AgentName)
+	testutils.Equals(t, "vcenter", migrations[0].Status.Source.VMwareCreds)
+	testutils.Equals(t, "", migrations[2].Status.AgentName)
+}
+
+func TestMigrationSchedulerForgetsPlacements(t *testing.T) {
+	scheduler := NewMigrationScheduler()
+	first := newTestMigration("vm-1", "", vjailbreakv1alpha1.VMMigrationPhasePending)
+	second := newTestMigration("vm-2", "", vjailbreakv1alpha1.VMMigrationPhasePending)
+	scheduler.Placed(&first, "a", nil)
+	scheduler.Placed(&second, "b", nil)
+
+	// The cache shows the placement of the first migration, the second migration was deleted
+	first.Status.AgentName = "c"
+	migrations := scheduler.applyPlacements([]vjailbreakv1alpha1.Migration{first})
+	testutils.Equals(t, "c", migrations[0].Status.AgentName)
+	testutils.Equals(t, 0, len(scheduler.placed))
+
+	// A migration whose placement is cleared later is not placed again by a stale record
+	first.Status.AgentName = ""
+	migrations = scheduler.applyPlacements([]vjailbreakv1alpha1.Migration{first})
+	testutils.Equals(t, "", migrations[0].Status.AgentName)
+}
+
+func cloneMigrations(migrations []vjailbreakv1alpha1.Migration) []vjailbreakv1alpha1.Migration {
+	clones := make([]vjailbreakv1alpha1.Migration, len(migrations))
+	for i := range migrations {
+		migrations[i].DeepCopyInto(&clones[i])
+	}
+	return clones
+}
+
+func TestIsNodeLost(t *testing.T) {
+	now := time.Now()
+	newNode := func(status corev1.ConditionStatus, since time.Duration) *corev1.Node {
+		return &corev1.Node{Status: corev1.NodeStatus{Conditions: []corev1.NodeCondition{
+			{Type: corev1.NodeReady, Status: status, LastTransitionTime: metav1.NewTime(now.Add(-since))},
+		}}}
+	}
+	tests := []struct {
+		name string
+		node *corev1.Node
+		want bool
+	}{
+		{name: "ready", node: newNode(corev1.ConditionTrue, time.Hour), want: false},
+		{name: "not ready for a short time", node: newNode(corev1.ConditionFalse, time.Minute), want: false},
+		{name: "not ready past the timeout", node: newNode(corev1.ConditionFalse, constants.AgentLostTimeout+time.Minute), want: true},
+		{name: "unreachable past the timeout", node: newNode(corev1.ConditionUnknown, constants.AgentLostTimeout+time.Minute), want: true},
+		{name: "no ready condition", node: &corev1.Node{}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testutils.Equals(t, tt.want, isNodeLost(tt.node, now))
+		})
+	}
+}
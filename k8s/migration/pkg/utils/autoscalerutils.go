@@ -0,0 +1,85 @@
+package utils
+
+import (
+	"fmt"
+	"time"
+
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+	"github.com/platform9/vjailbreak/k8s/migration/pkg/constants"
+)
+
+// AgentNodeScaling is what the autoscaler does with its agent nodes in one reconcile
+type AgentNodeScaling struct {
+	// Busy are the idle nodes that have migrations again
+	Busy []string
+	// Idle are the nodes that ran out of migrations
+	Idle []string
+	// Drain are the nodes idle for longer than the idle period while no migrations are queued
+	Drain []string
+	// Delete are the drained nodes whose migrations finished
+	Delete []string
+	// DeleteFailed are the nodes that failed to provision
+	DeleteFailed []string
+	// Create is the number of nodes to create for the queued migrations
+	Create int
+	// ReadyNodes and ProvisioningNodes count the nodes by phase
+	ReadyNodes        int
+	ProvisioningNodes int
+	// Message describes the queue and the nodes
+	Message string
+}
+
+// PlanAgentNodeScaling decides how the autoscaler sizes its agent nodes to the migration queue.
+// assigned is the number of migrations placed on each node and queued the number of migrations
+// waiting for an agent. Nodes are created while more migrations are queued than the nodes being
+// provisioned take, up to maxNodes. Nodes without migrations for idlePeriod are drained first, and
+// only deleted once no migration is placed on them. A node that reports active migrations is never
+// deleted, not even when it failed to provision.
+func PlanAgentNodeScaling(nodes []vjailbreakv1alpha1.VjailbreakNode, assigned map[string]int, queued, migrationsPerNode, maxNodes int,
+	idlePeriod time.Duration, now time.Time,
+) AgentNodeScaling {
+	scaling := AgentNodeScaling{}
+	for i := range nodes {
+		node := &nodes[i]
+		busy := assigned[node.Name] > 0 || len(node.Status.ActiveMigrations) > 0
+		switch node.Status.Phase {
+		case constants.VjailbreakNodePhaseNodeReady:
+			scaling.ReadyNodes++
+			switch {
+			case busy:
+				if node.Status.IdleSince != nil {
+					scaling.Busy = append(scaling.Busy, node.Name)
+				}
+			case node.Status.IdleSince == nil:
+				scaling.Idle = append(scaling.Idle, node.Name)
+			case queued == 0 && now.Sub(node.Status.IdleSince.Time) >= idlePeriod:
+				scaling.Drain = append(scaling.Drain, node.Name)
+			}
+		case constants.VjailbreakNodePhaseDraining:
+			// Migrations placed on the node before it was drained finish first
+			if !busy {
+				scaling.Delete = append(scaling.Delete, node.Name)
+			}
+		case constants.VjailbreakNodePhaseError:
+			// A node that failed to provision holds quota without ever taking migrations
+			if !busy {
+				scaling.DeleteFailed = append(scaling.DeleteFailed, node.Name)
+			}
+		case constants.VjailbreakNodePhaseDeleting:
+		default:
+			scaling.ProvisioningNodes++
+		}
+	}
+
+	scaling.Message = fmt.Sprintf("%d migrations queued, %d agent nodes ready and %d provisioning",
+		queued, scaling.ReadyNodes, scaling.ProvisioningNodes)
+	if uncovered := queued - scaling.ProvisioningNodes*migrationsPerNode; uncovered > 0 {
+		wanted := (uncovered + migrationsPerNode - 1) / migrationsPerNode
+		room := max(maxNodes-len(nodes), 0)
+		if wanted > room {
+			scaling.Message = fmt.Sprintf("%s, reached the maximum of %d agent nodes", scaling.Message, maxNodes)
+		}
+		scaling.Create = min(wanted, room)
+	}
+	return scaling
+}
@@ -0,0 +1,181 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+	"github.com/platform9/vjailbreak/k8s/migration/pkg/constants"
+	"github.com/platform9/vjailbreak/k8s/migration/pkg/sdk/testutils"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// newTestAgentNode returns an autoscaled node in phase, idle for idle when idle is not negative,
+// that reports active migrations
+func newTestAgentNode(name string, phase vjailbreakv1alpha1.VjailbreakNodePhase, idle time.Duration, now time.Time, activeMigrations ...string) vjailbreakv1alpha1.VjailbreakNode {
+	node := vjailbreakv1alpha1.VjailbreakNode{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	node.Status.Phase = phase
+	node.Status.ActiveMigrations = activeMigrations
+	if idle >= 0 {
+		idleSince := metav1.NewTime(now.Add(-idle))
+		node.Status.IdleSince = &idleSince
+	}
+	return node
+}
+
+func TestPlanAgentNodeScaling(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	const (
+		ready        = constants.VjailbreakNodePhaseNodeReady
+		draining     = constants.VjailbreakNodePhaseDraining
+		failed       = constants.VjailbreakNodePhaseError
+		provisioning = constants.VjailbreakNodePhaseVMCreating
+		notIdle      = time.Duration(-1)
+		idlePeriod   = 30 * time.Minute
+	)
+	tests := []struct {
+		name     string
+		nodes    []vjailbreakv1alpha1.VjailbreakNode
+		assigned map[string]int
+		queued   int
+		maxNodes int
+		want     AgentNodeScaling
+	}{
+		{
+			name:     "nothing queued",
+			maxNodes: 3,
+			want:     AgentNodeScaling{Message: "0 migrations queued, 0 agent nodes ready and 0 provisioning"},
+		},
+		{
+			name:     "scale up for the queue",
+			queued:   5,
+			maxNodes: 4,
+			want:     AgentNodeScaling{Create: 3, Message: "5 migrations queued, 0 agent nodes ready and 0 provisioning"},
+		},
+		{
+			name:     "provisioning nodes cover part of the queue",
+			nodes:    []vjailbreakv1alpha1.VjailbreakNode{newTestAgentNode("agent-1", provisioning, notIdle, now)},
+			queued:   3,
+			maxNodes: 4,
+			want:     AgentNodeScaling{Create: 1, ProvisioningNodes: 1, Message: "3 migrations queued, 0 agent nodes ready and 1 provisioning"},
+		},
+		{
+			name:     "provisioning nodes cover the queue",
+			nodes:    []vjailbreakv1alpha1.VjailbreakNode{newTestAgentNode("agent-1", provisioning, notIdle, now)},
+			queued:   2,
+			maxNodes: 4,
+			want:     AgentNodeScaling{ProvisioningNodes: 1, Message: "2 migrations queued, 0 agent nodes ready and 1 provisioning"},
+		},
+		{
+			name:     "maximum number of nodes",
+			nodes:    []vjailbreakv1alpha1.VjailbreakNode{newTestAgentNode("agent-1", ready, notIdle, now, "vm-1")},
+			queued:   6,
+			maxNodes: 2,
+			want: AgentNodeScaling{
+				Create:     1,
+				ReadyNodes: 1,
+				Message:    "6 migrations queued, 1 agent nodes ready and 0 provisioning, reached the maximum of 2 agent nodes",
+			},
+		},
+		{
+			name: "more nodes than the maximum",
+			nodes: []vjailbreakv1alpha1.VjailbreakNode{
+				newTestAgentNode("agent-1", ready, notIdle, now, "vm-1"),
+				newTestAgentNode("agent-2", ready, notIdle, now, "vm-2"),
+			},
+			queued:   1,
+			maxNodes: 1,
+			want: AgentNodeScaling{
+				ReadyNodes: 2,
+				Message:    "1 migrations queued, 2 agent nodes ready and 0 provisioning, reached the maximum of 1 agent nodes",
+			},
+		},
+		{
+			name: "idle nodes",
+			nodes: []vjailbreakv1alpha1.VjailbreakNode{
+				newTestAgentNode("agent-1", ready, notIdle, now),
+				newTestAgentNode("agent-2", ready, 10*time.Minute, now),
+				newTestAgentNode("agent-3", ready, time.Hour, now),
+			},
+			maxNodes: 3,
+			want: AgentNodeScaling{
+				Idle:       []string{"agent-1"},
+				Drain:      []string{"agent-3"},
+				ReadyNodes: 3,
+				Message:    "0 migrations queued, 3 agent nodes ready and 0 provisioning",
+			},
+		},
+		{
+			name:     "idle node is kept while migrations are queued",
+			nodes:    []vjailbreakv1alpha1.VjailbreakNode{newTestAgentNode("agent-1", ready, time.Hour, now)},
+			queued:   1,
+			maxNodes: 1,
+			want: AgentNodeScaling{
+				ReadyNodes: 1,
+				Message:    "1 migrations queued, 1 agent nodes ready and 0 provisioning, reached the maximum of 1 agent nodes",
+			},
+		},
+		{
+			name: "idle nodes with migrations again",
+			nodes: []vjailbreakv1alpha1.VjailbreakNode{
+				newTestAgentNode("agent-1", ready, time.Hour, now),
+				newTestAgentNode("agent-2", ready, time.Hour, now, "vm-2"),
+				newTestAgentNode("agent-3", ready, notIdle, now, "vm-3"),
+			},
+			assigned: map[string]int{"agent-1": 1},
+			maxNodes: 3,
+			want: AgentNodeScaling{
+				Busy:       []string{"agent-1", "agent-2"},
+				ReadyNodes: 3,
+				Message:    "0 migrations queued, 3 agent nodes ready and 0 provisioning",
+			},
+		},
+		{
+			name: "drained nodes are deleted once their migrations finish",
+			nodes: []vjailbreakv1alpha1.VjailbreakNode{
+				newTestAgentNode("agent-1", draining, time.Hour, now),
+				newTestAgentNode("agent-2", draining, time.Hour, now),
+				newTestAgentNode("agent-3", draining, time.Hour, now, "vm-3"),
+			},
+			assigned: map[string]int{"agent-2": 1},
+			maxNodes: 3,
+			want: AgentNodeScaling{
+				Delete:  []string{"agent-1"},
+				Message: "0 migrations queued, 0 agent nodes ready and 0 provisioning",
+			},
+		},
+		{
+			name: "nodes that failed to provision",
+			nodes: []vjailbreakv1alpha1.VjailbreakNode{
+				newTestAgentNode("agent-1", failed, notIdle, now),
+				newTestAgentNode("agent-2", failed, notIdle, now, "vm-2"),
+				newTestAgentNode("agent-3", constants.VjailbreakNodePhaseDeleting, notIdle, now),
+			},
+			maxNodes: 3,
+			want: AgentNodeScaling{
+				DeleteFailed: []string{"agent-1"},
+				Message:      "0 migrations queued, 0 agent nodes ready and 0 provisioning",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testutils.Equals(t, tt.want, PlanAgentNodeScaling(tt.nodes, tt.assigned, tt.queued, 2, tt.maxNodes, idlePeriod, now))
+		})
+	}
+}
+
+// TestPlanAgentNodeScalingNeverDeletesActiveNodes checks that no node reporting active migrations
+// is drained or deleted, whatever its phase and idle time
+func TestPlanAgentNodeScalingNeverDeletesActiveNodes(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	nodes := []vjailbreakv1alpha1.VjailbreakNode{}
+	for _, phase := range []vjailbreakv1alpha1.VjailbreakNodePhase{
+		constants.VjailbreakNodePhaseNodeReady, constants.VjailbreakNodePhaseDraining,
+		constants.VjailbreakNodePhaseError, constants.VjailbreakNodePhaseDeleting,
+	} {
+		nodes = append(nodes, newTestAgentNode("agent-"+string(phase), phase, 24*time.Hour, now, "vm"))
+	}
+	scaling := PlanAgentNodeScaling(nodes, nil, 0, 2, 10, time.Minute, now)
+	testutils.Equals(t, 0, len(scaling.Drain)+len(scaling.Delete)+len(scaling.DeleteFailed))
+}
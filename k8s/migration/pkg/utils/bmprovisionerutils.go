@@ -692,13 +692,23 @@ func GetMigrationTemplateFromMigration(ctx context.Context, k3sclient client.Cli
 	return GetMigrationTemplateFromMigrationPlan(ctx, k3sclient, migrationPlan)
 }
 
-// GetVMwareCredsNameFromMigration retrieves the source VMware credentials name from a Migration
+// GetSourceName returns the name the object names of the VMs of a migration template are derived
+// from. It is the VMware credentials, or the template itself when the template imports files.
+func GetSourceName(migrationTemplate *vjailbreakv1alpha1.MigrationTemplate) string {
+	if migrationTemplate.Spec.Source.File != nil {
+		return migrationTemplate.Name
+	}
+	return migrationTemplate.Spec.Source.VMwareRef
+}
+
+// GetVMwareCredsNameFromMigration retrieves the source VMware credentials name from a Migration,
+// see GetSourceName for templates importing files
 func GetVMwareCredsNameFromMigration(ctx context.Context, k3sclient client.Client, migration *vjailbreakv1alpha1.Migration) (string, error) {
 	migrationTemplate, err := GetMigrationTemplateFromMigration(ctx, k3sclient, migration)
 	if err != nil {
 		return "", errors.Wrap(err, "failed to get migration template")
 	}
-	return migrationTemplate.Spec.Source.VMwareRef, nil
+	return GetSourceName(migrationTemplate), nil
 }
 
 // GetOpenstackCredsNameFromMigration retrieves the destination OpenStack credentials name from a Migration
@@ -710,13 +720,14 @@ func GetOpenstackCredsNameFromMigration(ctx context.Context, k3sclient client.Cl
 	return migrationTemplate.Spec.Destination.OpenstackRef, nil
 }
 
-// GetVMwareCredsNameFromMigrationPlan retrieves the source VMware credentials name from a MigrationPlan
+// GetVMwareCredsNameFromMigrationPlan retrieves the source VMware credentials name from a MigrationPlan,
+// see GetSourceName for templates importing files
 func GetVMwareCredsNameFromMigrationPlan(ctx context.Context, k3sclient client.Client, migrationPlan *vjailbreakv1alpha1.MigrationPlan) (string, error) {
 	migrationTemplate, err := GetMigrationTemplateFromMigrationPlan(ctx, k3sclient, migrationPlan)
 	if err != nil {
 		return "", errors.Wrap(err, "failed to get migration template")
 	}
-	return migrationTemplate.Spec.Source.VMwareRef, nil
+	return GetSourceName(migrationTemplate), nil
 }
 
 // GetOpenstackCredsNameFromMigrationPlan retrieves the destination OpenStack credentials name from a MigrationPlan
@@ -0,0 +1,237 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gophercloud/gophercloud/v2/openstack/blockstorage/v3/quotasets"
+	"github.com/gophercloud/gophercloud/v2/openstack/compute/v2/flavors"
+	computequotasets "github.com/gophercloud/gophercloud/v2/openstack/compute/v2/quotasets"
+	"github.com/gophercloud/gophercloud/v2/openstack/networking/v2/extensions/quotas"
+	"github.com/pkg/errors"
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+	openstackpkg "github.com/platform9/vjailbreak/pkg/common/openstack"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Names of the OpenStack quotas a migration consumes
+const (
+	QuotaCores     = "cores"
+	QuotaRAM       = "ram"
+	QuotaInstances = "instances"
+	QuotaPorts     = "ports"
+	QuotaVolumes   = "volumes"
+	QuotaGigabytes = "gigabytes"
+)
+
+// CapacityForecastInterval is how long a capacity forecast is used before it is computed again
+const CapacityForecastInterval = time.Minute
+
+// IsCapacityForecastFresh checks if the capacity forecast was computed less than
+// CapacityForecastInterval ago
+func IsCapacityForecastFresh(forecast *vjailbreakv1alpha1.CapacityForecast) bool {
+	return forecast != nil && time.Since(forecast.LastUpdated.Time) < CapacityForecastInterval
+}
+
+// ForecastMigrationCapacity projects the OpenStack resources the migration of vmNames with
+// migrationtemplate needs and compares them with the quota left in the target project. The flavor
+// of a VM is resolved the way the migration resolves it and its volumes are counted against the
+// volume types of the storage mapping of the template.
+func ForecastMigrationCapacity(ctx context.Context, k3sclient client.Client, migrationtemplate *vjailbreakv1alpha1.MigrationTemplate, vmNames []string) (*vjailbreakv1alpha1.CapacityForecast, error) {
+	openstackcreds := &vjailbreakv1alpha1.OpenstackCreds{}
+	if err := k3sclient.Get(ctx, k8stypes.NamespacedName{Name: migrationtemplate.Spec.Destination.OpenstackRef, Namespace: migrationtemplate.Namespace}, openstackcreds); err != nil {
+		return nil, errors.Wrap(err, "failed to get openstack credentials")
+	}
+
+	vmMachines := make([]*vjailbreakv1alpha1.VMwareMachine, 0, len(vmNames))
+	for _, vm := range vmNames {
+		vmk8sname, err := GetK8sCompatibleVMWareObjectName(vm, GetSourceName(migrationtemplate))
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get k8s compatible name for VM %s", vm)
+		}
+		vmMachine := &vjailbreakv1alpha1.VMwareMachine{}
+		if err := k3sclient.Get(ctx, k8stypes.NamespacedName{Name: vmk8sname, Namespace: migrationtemplate.Namespace}, vmMachine); err != nil {
+			return nil, errors.Wrapf(err, "failed to get VMwareMachine for VM %s", vm)
+		}
+		vmMachines = append(vmMachines, vmMachine)
+	}
+
+	volumeTypes := map[string]string{}
+	if migrationtemplate.Spec.StorageMapping != "" {
+		storagemap := &vjailbreakv1alpha1.StorageMapping{}
+		if err := k3sclient.Get(ctx, k8stypes.NamespacedName{Name: migrationtemplate.Spec.StorageMapping, Namespace: migrationtemplate.Namespace}, storagemap); err != nil {
+			return nil, errors.Wrap(err, "failed to get storage mapping")
+		}
+		for _, storage := range storagemap.Spec.Storages {
+			volumeTypes[storage.Source] = storage.Target
+		}
+	}
+
+	openstackClients, err := GetOpenStackClients(ctx, k3sclient, openstackcreds)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get openstack clients")
+	}
+	allFlavors, err := ListAllFlavors(ctx, k3sclient, openstackcreds)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list all flavors")
+	}
+	// UseGPUFlavor is only applicable for PCD credentials
+	useGPUFlavor := migrationtemplate.Spec.UseGPUFlavor && IsOpenstackPCD(*openstackcreds)
+	required, err := migrationFootprint(vmMachines, allFlavors, migrationtemplate.Spec.UseFlavorless, useGPUFlavor, volumeTypes)
+	if err != nil {
+		return nil, err
+	}
+
+	limits, inUse, err := getProjectQuota(ctx, k3sclient, openstackcreds, openstackClients)
+	if err != nil {
+		return nil, err
+	}
+	return buildCapacityForecast(required, limits, inUse), nil
+}
+
+// migrationFootprint adds up the resources the target VMs of vmMachines need, keyed by quota name.
+// Flavorless migrations size the VM after its source, as do VMs without a suitable flavor.
+func migrationFootprint(vmMachines []*vjailbreakv1alpha1.VMwareMachine, allFlavors []flavors.Flavor,
+	useFlavorless, useGPUFlavor bool, volumeTypes map[string]string,
+) (map[string]int64, error) {
+	required := map[string]int64{}
+	for _, vmMachine := range vmMachines {
+		vminfo := vmMachine.Spec.VMInfo
+		cpu, ram := int64(vminfo.CPU), int64(vminfo.Memory)
+		if !useFlavorless {
+			flavor, err := getTargetFlavor(vmMachine, allFlavors, useGPUFlavor)
+			if err != nil {
+				return nil, err
+			}
+			if flavor != nil {
+				cpu, ram = int64(flavor.VCPUs), int64(flavor.RAM)
+			}
+		}
+		required[QuotaCores] += cpu
+		required[QuotaRAM] += ram
+		required[QuotaInstances]++
+
+		nics := len(vminfo.NetworkInterfaces)
+		if nics == 0 {
+			nics = len(vminfo.Networks)
+		}
+		required[QuotaPorts] += int64(nics)
+
+		for _, disk := range vminfo.Disks {
+			required[QuotaVolumes]++
+			required[QuotaGigabytes] += int64(disk.CapacityGB)
+			if volumeType := volumeTypes[disk.Datastore]; volumeType != "" {
+				required[QuotaGigabytes+"_"+volumeType] += int64(disk.CapacityGB)
+			}
+		}
+	}
+	return required, nil
+}
+
+// getTargetFlavor returns the flavor the migration of vmMachine boots its target VM with, the flavor
+// set on the VMwareMachine or the closest one. It is nil when the flavor set is not found.
+func getTargetFlavor(vmMachine *vjailbreakv1alpha1.VMwareMachine, allFlavors []flavors.Flavor, useGPUFlavor bool) (*flavors.Flavor, error) {
+	vminfo := vmMachine.Spec.VMInfo
+	if vmMachine.Spec.TargetFlavorID == "" {
+		flavor, err := openstackpkg.GetClosestFlavour(vminfo.CPU, vminfo.Memory, vminfo.GPU.PassthroughCount, vminfo.GPU.VGPUCount, allFlavors, useGPUFlavor)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get closest flavor for VM %s", vminfo.Name)
+		}
+		return flavor, nil
+	}
+	for i := range allFlavors {
+		if allFlavors[i].ID == vmMachine.Spec.TargetFlavorID {
+			return &allFlavors[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// getProjectQuota returns the Nova, Cinder and Neutron quota limits of the project of the OpenStack
+// credentials and what the project uses or has reserved of them, keyed by quota name
+func getProjectQuota(ctx context.Context, k3sclient client.Client, openstackcreds *vjailbreakv1alpha1.OpenstackCreds, openstackClients *OpenStackClients) (map[string]int64, map[string]int64, error) {
+	projectID, err := getOpenstackProjectID(ctx, k3sclient, openstackcreds, openstackClients)
+	if err != nil {
+		return nil, nil, err
+	}
+	limits, inUse := map[string]int64{}, map[string]int64{}
+
+	compute, err := computequotasets.GetDetail(ctx, openstackClients.ComputeClient, projectID).Extract()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to get compute quota")
+	}
+	for name, detail := range map[string]computequotasets.QuotaDetail{
+		QuotaCores:     compute.Cores,
+		QuotaRAM:       compute.RAM,
+		QuotaInstances: compute.Instances,
+	} {
+		limits[name] = int64(detail.Limit)
+		inUse[name] = int64(detail.InUse + detail.Reserved)
+	}
+
+	network, err := quotas.GetDetail(ctx, openstackClients.NetworkingClient, projectID).Extract()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to get network quota")
+	}
+	limits[QuotaPorts] = int64(network.Port.Limit)
+	inUse[QuotaPorts] = int64(network.Port.Used + network.Port.Reserved)
+
+	// The quotas of volume types are only returned as extra gigabytes_<volume type> keys
+	var usage struct {
+		QuotaSet map[string]json.RawMessage `json:"quota_set"`
+	}
+	if err := quotasets.GetUsage(ctx, openstackClients.BlockStorageClient, projectID).ExtractInto(&usage); err != nil {
+		return nil, nil, errors.Wrap(err, "failed to get block storage quota")
+	}
+	for name, raw := range usage.QuotaSet {
+		if name != QuotaVolumes && name != QuotaGigabytes && !strings.HasPrefix(name, QuotaGigabytes+"_") {
+			continue
+		}
+		var detail quotasets.QuotaUsage
+		if err := json.Unmarshal(raw, &detail); err != nil {
+			return nil, nil, errors.Wrapf(err, "failed to parse block storage quota %s", name)
+		}
+		limits[name] = int64(detail.Limit)
+		inUse[name] = int64(detail.InUse + detail.Reserved)
+	}
+	return limits, inUse, nil
+}
+
+// buildCapacityForecast compares the required resources with the quota left. A resource without a
+// quota, such as a volume type without its own quota, or with a negative limit is unlimited.
+func buildCapacityForecast(required, limits, inUse map[string]int64) *vjailbreakv1alpha1.CapacityForecast {
+	forecast := &vjailbreakv1alpha1.CapacityForecast{Sufficient: true, LastUpdated: metav1.Now()}
+	names := make([]string, 0, len(required))
+	for name := range required {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	shortfalls := []string{}
+	for _, name := range names {
+		resource := vjailbreakv1alpha1.ResourceForecast{Name: name, Required: required[name], Limit: -1}
+		if limit, ok := limits[name]; ok && limit >= 0 {
+			resource.Limit = limit
+			resource.InUse = inUse[name]
+			if shortfall := resource.Required - (limit - resource.InUse); shortfall > 0 {
+				resource.Shortfall = shortfall
+				forecast.Sufficient = false
+				shortfalls = append(shortfalls, fmt.Sprintf("%s short by %d (needs %d, %d of %d in use)",
+					name, shortfall, resource.Required, resource.InUse, limit))
+			}
+		}
+		forecast.Resources = append(forecast.Resources, resource)
+	}
+	if len(shortfalls) > 0 {
+		forecast.Message = "Insufficient quota in the target project: " + strings.Join(shortfalls, ", ")
+	} else {
+		forecast.Message = "Target project has enough quota for the plan"
+	}
+	return forecast
+}
@@ -0,0 +1,177 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/gophercloud/gophercloud/v2/openstack/compute/v2/flavors"
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+	"github.com/platform9/vjailbreak/k8s/migration/pkg/sdk/testutils"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestMigrationFootprint(t *testing.T) {
+	allFlavors := []flavors.Flavor{
+		{ID: "1", Name: "m1.small", VCPUs: 2, RAM: 2048},
+		{ID: "2", Name: "m1.medium", VCPUs: 4, RAM: 8192},
+	}
+	newVM := func(flavorID string, cpu, memory int, nics, networks []string, disks ...vjailbreakv1alpha1.Disk) *vjailbreakv1alpha1.VMwareMachine {
+		vmMachine := &vjailbreakv1alpha1.VMwareMachine{}
+		vmMachine.Spec.TargetFlavorID = flavorID
+		vmMachine.Spec.VMInfo.Name = "vm"
+		vmMachine.Spec.VMInfo.CPU = cpu
+		vmMachine.Spec.VMInfo.Memory = memory
+		for _, network := range nics {
+			vmMachine.Spec.VMInfo.NetworkInterfaces = append(vmMachine.Spec.VMInfo.NetworkInterfaces, vjailbreakv1alpha1.NIC{Network: network})
+		}
+		vmMachine.Spec.VMInfo.Networks = networks
+		vmMachine.Spec.VMInfo.Disks = disks
+		return vmMachine
+	}
+
+	tests := []struct {
+		name          string
+		vmMachines    []*vjailbreakv1alpha1.VMwareMachine
+		useFlavorless bool
+		volumeTypes   map[string]string
+		expected      map[string]int64
+		expectErr     bool
+	}{
+		{
+			name:       "flavor set on the VM",
+			vmMachines: []*vjailbreakv1alpha1.VMwareMachine{newVM("2", 1, 1024, []string{"net-a"}, nil)},
+			expected:   map[string]int64{QuotaCores: 4, QuotaRAM: 8192, QuotaInstances: 1, QuotaPorts: 1},
+		},
+		{
+			name:       "closest flavor",
+			vmMachines: []*vjailbreakv1alpha1.VMwareMachine{newVM("", 3, 4096, nil, []string{"net-a", "net-b"})},
+			expected:   map[string]int64{QuotaCores: 4, QuotaRAM: 8192, QuotaInstances: 1, QuotaPorts: 2},
+		},
+		{
+			name:          "flavorless",
+			vmMachines:    []*vjailbreakv1alpha1.VMwareMachine{newVM("2", 3, 4096, nil, nil)},
+			useFlavorless: true,
+			expected:      map[string]int64{QuotaCores: 3, QuotaRAM: 4096, QuotaInstances: 1, QuotaPorts: 0},
+		},
+		{
+			name:       "flavor set on the VM not found",
+			vmMachines: []*vjailbreakv1alpha1.VMwareMachine{newVM("9", 3, 4096, nil, nil)},
+			expected:   map[string]int64{QuotaCores: 3, QuotaRAM: 4096, QuotaInstances: 1, QuotaPorts: 0},
+		},
+		{
+			name:       "no flavor fits",
+			vmMachines: []*vjailbreakv1alpha1.VMwareMachine{newVM("", 16, 65536, nil, nil)},
+			expectErr:  true,
+		},
+		{
+			name: "NICs take precedence over networks",
+			vmMachines: []*vjailbreakv1alpha1.VMwareMachine{
+				newVM("1", 2, 2048, []string{"net-a", "net-a", "net-b"}, []string{"net-a", "net-b"}),
+			},
+			expected: map[string]int64{QuotaCores: 2, QuotaRAM: 2048, QuotaInstances: 1, QuotaPorts: 3},
+		},
+		{
+			name: "disks of several VMs and volume types",
+			vmMachines: []*vjailbreakv1alpha1.VMwareMachine{
+				newVM("1", 2, 2048, []string{"net-a"}, nil,
+					vjailbreakv1alpha1.Disk{Datastore: "ds-ssd", CapacityGB: 20},
+					vjailbreakv1alpha1.Disk{Datastore: "ds-hdd", CapacityGB: 100}),
+				newVM("2", 4, 8192, []string{"net-a"}, nil,
+					vjailbreakv1alpha1.Disk{Datastore: "ds-ssd", CapacityGB: 30},
+					vjailbreakv1alpha1.Disk{Datastore: "ds-unmapped", CapacityGB: 10}),
+			},
+			volumeTypes: map[string]string{"ds-ssd": "ssd", "ds-hdd": "hdd"},
+			expected: map[string]int64{
+				QuotaCores: 6, QuotaRAM: 10240, QuotaInstances: 2, QuotaPorts: 2,
+				QuotaVolumes: 4, QuotaGigabytes: 160, QuotaGigabytes + "_ssd": 50, QuotaGigabytes + "_hdd": 100,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			required, err := migrationFootprint(tt.vmMachines, allFlavors, tt.useFlavorless, false, tt.volumeTypes)
+			if tt.expectErr {
+				testutils.Assert(t, err != nil, "expected an error, got none")
+				return
+			}
+			testutils.Ok(t, err)
+			testutils.Equals(t, tt.expected, required)
+		})
+	}
+}
+
+func TestBuildCapacityForecast(t *testing.T) {
+	required := map[string]int64{QuotaCores: 8, QuotaInstances: 2, QuotaRAM: 16384}
+
+	tests := []struct {
+		name     string
+		limits   map[string]int64
+		inUse    map[string]int64
+		expected *vjailbreakv1alpha1.CapacityForecast
+	}{
+		{
+			name:   "enough quota",
+			limits: map[string]int64{QuotaCores: 20, QuotaInstances: 10, QuotaRAM: 51200},
+			inUse:  map[string]int64{QuotaCores: 12, QuotaInstances: 2, QuotaRAM: 8192},
+			expected: &vjailbreakv1alpha1.CapacityForecast{
+				Sufficient: true,
+				Message:    "Target project has enough quota for the plan",
+				Resources: []vjailbreakv1alpha1.ResourceForecast{
+					{Name: QuotaCores, Required: 8, Limit: 20, InUse: 12},
+					{Name: QuotaInstances, Required: 2, Limit: 10, InUse: 2},
+					{Name: QuotaRAM, Required: 16384, Limit: 51200, InUse: 8192},
+				},
+			},
+		},
+		{
+			name:   "quota exceeded",
+			limits: map[string]int64{QuotaCores: 10, QuotaInstances: 10, QuotaRAM: 20480},
+			inUse:  map[string]int64{QuotaCores: 4, QuotaInstances: 2, QuotaRAM: 8192},
+			expected: &vjailbreakv1alpha1.CapacityForecast{
+				Sufficient: false,
+				Message: "Insufficient quota in the target project: cores short by 2 (needs 8, 4 of 10 in use), " +
+					"ram short by 4096 (needs 16384, 8192 of 20480 in use)",
+				Resources: []vjailbreakv1alpha1.ResourceForecast{
+					{Name: QuotaCores, Required: 8, Limit: 10, InUse: 4, Shortfall: 2},
+					{Name: QuotaInstances, Required: 2, Limit: 10, InUse: 2},
+					{Name: QuotaRAM, Required: 16384, Limit: 20480, InUse: 8192, Shortfall: 4096},
+				},
+			},
+		},
+		{
+			name:   "unlimited quota",
+			limits: map[string]int64{QuotaCores: -1, QuotaInstances: -1, QuotaRAM: -1},
+			inUse:  map[string]int64{QuotaCores: 400, QuotaInstances: 100, QuotaRAM: 819200},
+			expected: &vjailbreakv1alpha1.CapacityForecast{
+				Sufficient: true,
+				Message:    "Target project has enough quota for the plan",
+				Resources: []vjailbreakv1alpha1.ResourceForecast{
+					{Name: QuotaCores, Required: 8, Limit: -1},
+					{Name: QuotaInstances, Required: 2, Limit: -1},
+					{Name: QuotaRAM, Required: 16384, Limit: -1},
+				},
+			},
+		},
+		{
+			name:   "quota not reported",
+			limits: map[string]int64{QuotaCores: 8},
+			inUse:  map[string]int64{QuotaCores: 0},
+			expected: &vjailbreakv1alpha1.CapacityForecast{
+				Sufficient: true,
+				Message:    "Target project has enough quota for the plan",
+				Resources: []vjailbreakv1alpha1.ResourceForecast{
+					{Name: QuotaCores, Required: 8, Limit: 8},
+					{Name: QuotaInstances, Required: 2, Limit: -1},
+					{Name: QuotaRAM, Required: 16384, Limit: -1},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			forecast := buildCapacityForecast(required, tt.limits, tt.inUse)
+			testutils.Assert(t, !forecast.LastUpdated.IsZero(), "expected LastUpdated to be set")
+			forecast.LastUpdated = metav1.Time{}
+			testutils.Equals(t, tt.expected, forecast)
+		})
+	}
+}
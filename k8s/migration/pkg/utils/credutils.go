@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"math"
 	"net/url"
+	"path"
 	"reflect"
 	"slices"
 	"strings"
@@ -40,6 +41,8 @@ import (
 	"github.com/vmware/govmomi/property"
 	"github.com/vmware/govmomi/session"
 	"github.com/vmware/govmomi/session/cache"
+	"github.com/vmware/govmomi/vapi/rest"
+	"github.com/vmware/govmomi/vapi/tags"
 	"github.com/vmware/govmomi/vim25"
 	"github.com/vmware/govmomi/vim25/mo"
 	"github.com/vmware/govmomi/vim25/types"
@@ -355,30 +358,10 @@ func GetOpenstackInfo(ctx context.Context, k3sclient client.Client, openstackcre
 		openstacknetworks = append(openstacknetworks, allNetworks[i].Name)
 	}
 
-	credsInfo, err := GetOpenstackCredentialsFromSecret(ctx, k3sclient, openstackcreds.Spec.SecretRef.Name)
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to get openstack credentials for project lookup")
-	}
-
-	identityClient, err := openstack.NewIdentityV3(openstackClients.BlockStorageClient.ProviderClient, gophercloud.EndpointOpts{})
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to create identity client")
-	}
-
-	listOpts := projects.ListOpts{Name: credsInfo.TenantName}
-	allPages, err := projects.List(identityClient, listOpts).AllPages(ctx)
-	if err != nil {
-		return nil, errors.Wrapf(err, "failed to list projects with name %s", credsInfo.TenantName)
-	}
-
-	allProjects, err := projects.ExtractProjects(allPages)
+	projectID, err := getOpenstackProjectID(ctx, k3sclient, openstackcreds, openstackClients)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to extract projects")
-	}
-	if len(allProjects) == 0 {
-		return nil, fmt.Errorf("no project found with name %s", credsInfo.TenantName)
+		return nil, err
 	}
-	projectID := allProjects[0].ID
 
 	allSecGroupPages, err := groups.List(openstackClients.NetworkingClient, groups.ListOpts{
 		TenantID: projectID,
@@ -433,6 +416,34 @@ func GetOpenstackInfo(ctx context.Context, k3sclient client.Client, openstackcre
 	}, nil
 }
 
+// getOpenstackProjectID looks up the ID of the project of the OpenStack credentials
+func getOpenstackProjectID(ctx context.Context, k3sclient client.Client, openstackcreds *vjailbreakv1alpha1.OpenstackCreds, openstackClients *OpenStackClients) (string, error) {
+	credsInfo, err := GetOpenstackCredentialsFromSecret(ctx, k3sclient, openstackcreds.Spec.SecretRef.Name)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get openstack credentials for project lookup")
+	}
+
+	identityClient, err := openstack.NewIdentityV3(openstackClients.BlockStorageClient.ProviderClient, gophercloud.EndpointOpts{})
+	if err != nil {
+		return "", errors.Wrap(err, "failed to create identity client")
+	}
+
+	listOpts := projects.ListOpts{Name: credsInfo.TenantName}
+	allPages, err := projects.List(identityClient, listOpts).AllPages(ctx)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to list projects with name %s", credsInfo.TenantName)
+	}
+
+	allProjects, err := projects.ExtractProjects(allPages)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to extract projects")
+	}
+	if len(allProjects) == 0 {
+		return "", fmt.Errorf("no project found with name %s", credsInfo.TenantName)
+	}
+	return allProjects[0].ID, nil
+}
+
 // GetOpenStackClients is a function to create openstack clients
 func GetOpenStackClients(ctx context.Context, k3sclient client.Client, openstackcreds *vjailbreakv1alpha1.OpenstackCreds) (*OpenStackClients, error) {
 	if openstackcreds == nil {
@@ -718,6 +729,91 @@ func GetVMwDatastore(ctx context.Context, k3sclient client.Client, vmwcreds *vja
 	return datastores, nil
 }
 
+// GetVMwSnapshotInfo gets the number of snapshots of a VM and the names of its independent disks,
+// which are left out of snapshots
+func GetVMwSnapshotInfo(ctx context.Context, k3sclient client.Client, vmwcreds *vjailbreakv1alpha1.VMwareCreds, datacenter, vmname string) (int, []string, error) {
+	_, finder, err := GetFinderForVMwareCreds(ctx, k3sclient, vmwcreds, datacenter)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to get finder: %w", err)
+	}
+
+	vm, err := finder.VirtualMachine(ctx, vmname)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to find vm: %w", err)
+	}
+
+	var vmProps mo.VirtualMachine
+	err = vm.Properties(ctx, vm.Reference(), []string{"config", "snapshot"}, &vmProps)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to get VM properties: %w", err)
+	}
+
+	snapshots := 0
+	if vmProps.Snapshot != nil {
+		trees := vmProps.Snapshot.RootSnapshotList
+		for len(trees) > 0 {
+			snapshots++
+			trees = append(trees[1:], trees[0].ChildSnapshotList...)
+		}
+	}
+
+	var independentDisks []string
+	for _, device := range vmProps.Config.Hardware.Device {
+		disk, ok := device.(*types.VirtualDisk)
+		if !ok {
+			continue
+		}
+		backing, ok := disk.Backing.(*types.VirtualDiskFlatVer2BackingInfo)
+		if !ok {
+			continue
+		}
+		if strings.HasPrefix(backing.DiskMode, "independent") {
+			independentDisks = append(independentDisks, disk.DeviceInfo.GetDescription().Label)
+		}
+	}
+	return snapshots, independentDisks, nil
+}
+
+// GetVMwDatastoreFreeSpace gets the free space in bytes of the named datastores
+func GetVMwDatastoreFreeSpace(ctx context.Context, k3sclient client.Client, vmwcreds *vjailbreakv1alpha1.VMwareCreds, datacenter string, datastores []string) (map[string]int64, error) {
+	_, finder, err := GetFinderForVMwareCreds(ctx, k3sclient, vmwcreds, datacenter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get finder: %w", err)
+	}
+
+	freeSpace := make(map[string]int64, len(datastores))
+	for _, name := range datastores {
+		ds, err := finder.Datastore(ctx, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find datastore %s: %w", name, err)
+		}
+		var dsProps mo.Datastore
+		if err := ds.Properties(ctx, ds.Reference(), []string{"summary"}, &dsProps); err != nil {
+			return nil, fmt.Errorf("failed to get properties of datastore %s: %w", name, err)
+		}
+		freeSpace[name] = dsProps.Summary.FreeSpace
+	}
+	return freeSpace, nil
+}
+
+// IsIPInUse checks whether a fixed IP is held by a port in OpenStack, the reason names the port
+func IsIPInUse(ctx context.Context, networkingClient *gophercloud.ServiceClient, ip string) (bool, string, error) {
+	allPages, err := ports.List(networkingClient, ports.ListOpts{
+		FixedIPs: []ports.FixedIPOpts{{IPAddress: ip}},
+	}).AllPages(ctx)
+	if err != nil {
+		return false, "", errors.Wrapf(err, "failed to list ports for IP %s", ip)
+	}
+	allPorts, err := ports.ExtractPorts(allPages)
+	if err != nil {
+		return false, "", errors.Wrap(err, "failed to extract ports")
+	}
+	if len(allPorts) > 0 {
+		return true, fmt.Sprintf("IP %s is already in use by port %s", ip, allPorts[0].ID), nil
+	}
+	return false, "", nil
+}
+
 // GetAndCreateAllVMs gets all the VMs in a datacenter.
 func GetAndCreateAllVMs(ctx context.Context, scope *scope.VMwareCredsScope, datacenter string) ([]vjailbreakv1alpha1.VMInfo, *sync.Map, error) {
 	log := scope.Logger
@@ -788,6 +884,18 @@ func GetAndCreateAllVMs(ctx context.Context, scope *scope.VMwareCredsScope, data
 		allVMs = append(allVMs, vms...)
 	}
 
+	var vmTags map[string][]string
+	if VMTagsSupported(scope.VMwareCreds) {
+		refs := make([]types.ManagedObjectReference, 0, len(allVMs))
+		for _, vm := range allVMs {
+			refs = append(refs, vm.Reference())
+		}
+		if vmTags, err = getAllVMTags(ctx, scope.Client, scope.VMwareCreds, c, refs); err != nil {
+			// Tags only feed VM selectors, the VMs keep the tags they had
+			log.Error(err, "failed to get VM tags")
+		}
+	}
+
 	// Pre-allocate vminfo slice
 	vminfo := make([]vjailbreakv1alpha1.VMInfo, 0, len(allVMs))
 
@@ -808,7 +916,7 @@ func GetAndCreateAllVMs(ctx context.Context, scope *scope.VMwareCredsScope, data
 				}
 			}()
 			vmDatacenter := vmToDatacenter[allVMs[i].Reference().Value]
-			processSingleVM(ctx, scope, allVMs[i], &errMu, &vmErrors, &vminfoMu, &vminfo, c, rdmDiskMap, vmDatacenter)
+			processSingleVM(ctx, scope, allVMs[i], &errMu, &vmErrors, &vminfoMu, &vminfo, c, rdmDiskMap, vmDatacenter, vmTags)
 		}(i)
 	}
 	// Wait for all VMs to be processed
@@ -827,6 +935,79 @@ func GetAndCreateAllVMs(ctx context.Context, scope *scope.VMwareCredsScope, data
 	return vminfo, rdmDiskMap, nil
 }
 
+// VMTagsSupported checks if the VMware endpoint serves vSphere tags. A standalone ESXi host has no
+// vSphere Automation API.
+func VMTagsSupported(vmwcreds *vjailbreakv1alpha1.VMwareCreds) bool {
+	return vmwcreds.Spec.HostType != vjailbreakv1alpha1.VMwareHostTypeESXi
+}
+
+// LoginVSphereAutomation logs in to the vSphere Automation API of the vCenter, which serves the
+// tags. Its session is separate from the session of c.
+func LoginVSphereAutomation(ctx context.Context, k3sclient client.Client, vmwcreds *vjailbreakv1alpha1.VMwareCreds, c *vim25.Client) (*rest.Client, error) {
+	vmwareCredsinfo, err := GetVMwareCredentialsFromSecret(ctx, k3sclient, vmwcreds.Spec.SecretRef.Name)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get vCenter credentials from secret")
+	}
+	restClient := rest.NewClient(c)
+	if err := restClient.Login(ctx, url.UserPassword(vmwareCredsinfo.Username, vmwareCredsinfo.Password)); err != nil {
+		return nil, errors.Wrap(err, "failed to login to the vSphere Automation API")
+	}
+	return restClient, nil
+}
+
+// getAllVMTags returns the tags of the VMs in a session of its own
+func getAllVMTags(ctx context.Context, k3sclient client.Client, vmwcreds *vjailbreakv1alpha1.VMwareCreds, c *vim25.Client,
+	vms []types.ManagedObjectReference,
+) (map[string][]string, error) {
+	restClient, err := LoginVSphereAutomation(ctx, k3sclient, vmwcreds, c)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := restClient.Logout(ctx); err != nil {
+			log.FromContext(ctx).Error(err, "failed to logout from the vSphere Automation API")
+		}
+	}()
+	return GetVMTags(ctx, restClient, vms)
+}
+
+// GetVMTags returns the vSphere tags attached to the VMs as category:tag, by VM managed object ID
+func GetVMTags(ctx context.Context, restClient *rest.Client, vms []types.ManagedObjectReference) (map[string][]string, error) {
+	manager := tags.NewManager(restClient)
+	categories, err := manager.GetCategories(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get tag categories")
+	}
+	categoryNames := make(map[string]string, len(categories))
+	for _, category := range categories {
+		categoryNames[category.ID] = category.Name
+	}
+
+	vmTags := make(map[string][]string, len(vms))
+	for _, vm := range vms {
+		// VMs without tags are listed too, so that their removed tags are cleared
+		vmTags[vm.Value] = nil
+	}
+	for start := 0; start < len(vms); start += constants.VMTagsBatchSize {
+		refs := make([]mo.Reference, 0, constants.VMTagsBatchSize)
+		for _, vm := range vms[start:min(start+constants.VMTagsBatchSize, len(vms))] {
+			refs = append(refs, vm)
+		}
+		attached, err := manager.GetAttachedTagsOnObjects(ctx, refs)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get attached tags")
+		}
+		for _, objectTags := range attached {
+			id := objectTags.ObjectID.Reference().Value
+			for _, tag := range objectTags.Tags {
+				vmTags[id] = append(vmTags[id], categoryNames[tag.CategoryID]+":"+tag.Name)
+			}
+			slices.Sort(vmTags[id])
+		}
+	}
+	return vmTags, nil
+}
+
 // CountGPUs counts the number of GPU devices attached to a VM.
 // It separately counts PCI passthrough GPUs and vGPU devices.
 func CountGPUs(vmProps *mo.VirtualMachine) vjailbreakv1alpha1.GPUInfo {
@@ -856,6 +1037,29 @@ func CountGPUs(vmProps *mo.VirtualMachine) vjailbreakv1alpha1.GPUInfo {
 	return info
 }
 
+// DetectFirmware reads the firmware type, Secure Boot and vTPM settings of a VM from its config.
+func DetectFirmware(vmProps *mo.VirtualMachine) vjailbreakv1alpha1.FirmwareInfo {
+	info := vjailbreakv1alpha1.FirmwareInfo{}
+
+	if vmProps.Config == nil {
+		return info
+	}
+
+	info.UEFI = vmProps.Config.Firmware == string(types.GuestOsDescriptorFirmwareTypeEfi)
+	info.HardwareVersion = vmProps.Config.Version
+	if bootOptions := vmProps.Config.BootOptions; bootOptions != nil && bootOptions.EfiSecureBootEnabled != nil {
+		info.SecureBoot = info.UEFI && *bootOptions.EfiSecureBootEnabled
+	}
+	for _, device := range vmProps.Config.Hardware.Device {
+		if _, ok := device.(*types.VirtualTPM); ok {
+			info.VTPM = true
+			break
+		}
+	}
+
+	return info
+}
+
 // DetectGPUUsage checks if the VM has any GPU devices attached.
 // It detects PCI passthrough devices (including GPUs) and vGPU profiles.
 //
@@ -1221,6 +1425,19 @@ func DeleteStaleVMwareMachines(ctx context.Context, client client.Client, vmwcre
 	return nil
 }
 
+// DeleteVMwareMachine deletes the VMwareMachine object of a VM that was removed from the vCenter
+func DeleteVMwareMachine(ctx context.Context, client client.Client, vmwcreds *vjailbreakv1alpha1.VMwareCreds, vmName string) error {
+	name, err := GetK8sCompatibleVMWareObjectName(vmName, vmwcreds.Name)
+	if err != nil {
+		return errors.Wrap(err, "failed to convert vm name")
+	}
+	vm := &vjailbreakv1alpha1.VMwareMachine{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: vmwcreds.Namespace}}
+	if err := client.Delete(ctx, vm); err != nil && !apierrors.IsNotFound(err) {
+		return errors.Wrap(err, fmt.Sprintf("Error deleting VM '%s'", name))
+	}
+	return nil
+}
+
 // VMExistsInVcenter checks if a VM exists in the vCenter
 func VMExistsInVcenter(vmName string, vcenterVMs []vjailbreakv1alpha1.VMInfo) bool {
 	for _, vm := range vcenterVMs {
@@ -1658,7 +1875,7 @@ var rdmSemaphore = &sync.Mutex{}
 // due to complexity, it is marked with a gocyclo linter directive to allow higher cyclomatic complexity.
 //
 //nolint:gocyclo
-func processSingleVM(ctx context.Context, scope *scope.VMwareCredsScope, vm *object.VirtualMachine, errMu *sync.Mutex, vmErrors *[]vmError, vminfoMu *sync.Mutex, vminfo *[]vjailbreakv1alpha1.VMInfo, c *vim25.Client, rdmDiskMap *sync.Map, vmDatacenter string) {
+func processSingleVM(ctx context.Context, scope *scope.VMwareCredsScope, vm *object.VirtualMachine, errMu *sync.Mutex, vmErrors *[]vmError, vminfoMu *sync.Mutex, vminfo *[]vjailbreakv1alpha1.VMInfo, c *vim25.Client, rdmDiskMap *sync.Map, vmDatacenter string, vmTags map[string][]string) {
 	var vmProps mo.VirtualMachine
 	var datastores []string
 	networks := make([]string, 0, 4)               // Pre-allocate with estimated capacity
@@ -1671,6 +1888,7 @@ func processSingleVM(ctx context.Context, scope *scope.VMwareCredsScope, vm *obj
 		"guest",
 		"runtime",
 		"network",
+		"resourcePool",
 		"summary.config.annotation",
 	}, &vmProps)
 	if err != nil {
@@ -1780,6 +1998,18 @@ func processSingleVM(ctx context.Context, scope *scope.VMwareCredsScope, vm *obj
 	if clusterName == "" {
 		clusterName = GetClusterK8sID(clusterName, vmDatacenter)
 	}
+	var resourcePool mo.ResourcePool
+	if vmProps.ResourcePool != nil {
+		if err := pc.RetrieveOne(ctx, *vmProps.ResourcePool, []string{"name"}, &resourcePool); err != nil {
+			appendToVMErrorsThreadSafe(errMu, vmErrors, vm.Name(), fmt.Errorf("failed to get resource pool: %w", err))
+			return
+		}
+	}
+	// The inventory path of the VM is the path of its folder followed by its name
+	folder := ""
+	if strings.HasPrefix(vm.InventoryPath, "/") {
+		folder = path.Dir(vm.InventoryPath)
+	}
 	if len(rdmForVM) >= 1 && len(disks) == 0 {
 		log.Info("Skipping VM: VM has RDM disks but no regular bootable disks found, migration not supported", "VM NAME", vm.Name())
 		return
@@ -1819,6 +2049,11 @@ func processSingleVM(ctx context.Context, scope *scope.VMwareCredsScope, vm *obj
 	var guestNetworks []vjailbreakv1alpha1.GuestNetwork
 	var osFamily string
 	err = scope.Client.Get(ctx, vmwvmKey, vmwvm)
+	// VMs whose tags could not be fetched keep the tags they had
+	vmTagList, ok := vmTags[vm.Reference().Value]
+	if !ok {
+		vmTagList = vmwvm.Spec.VMInfo.Tags
+	}
 	switch {
 	case apierrors.IsNotFound(err):
 		// First time creation – use whatever vCenter gave us (could be nil)
@@ -1891,10 +2126,14 @@ func processSingleVM(ctx context.Context, scope *scope.VMwareCredsScope, vm *obj
 		Memory:            int(vmProps.Config.Hardware.MemoryMB),
 		ESXiName:          host.Name,
 		ClusterName:       clusterName,
+		Folder:            folder,
+		ResourcePool:      resourcePool.Name,
+		Tags:              vmTagList,
 		RDMDisks:          rdmForVM,
 		NetworkInterfaces: nicList,
 		GuestNetworks:     guestNetworks,
 		GPU:               gpuInfo,
+		Firmware:          DetectFirmware(&vmProps),
 	}
 	appendToVMInfoThreadSafe(vminfoMu, vminfo, currentVM)
 	err = CreateOrUpdateVMwareMachine(ctx, scope.Client, scope.VMwareCreds, &currentVM, vmDatacenter)
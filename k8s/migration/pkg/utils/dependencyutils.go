@@ -0,0 +1,184 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/pkg/errors"
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// GetVMDependencies returns the VMs of the plan the cutover of vm waits for
+func GetVMDependencies(migrationplan *vjailbreakv1alpha1.MigrationPlan, vm string) []string {
+	var after []string
+	for _, dependency := range migrationplan.Spec.Dependencies {
+		if dependency.VM != vm {
+			continue
+		}
+		for _, predecessor := range dependency.After {
+			if !slices.Contains(after, predecessor) {
+				after = append(after, predecessor)
+			}
+		}
+	}
+	return after
+}
+
+// ValidateDependencies checks that the dependencies of the plan name its VMs and have no cycles.
+// The groups of virtualMachines run one after the other, so a VM can only depend on VMs of its own
+// or an earlier group.
+func ValidateDependencies(migrationplan *vjailbreakv1alpha1.MigrationPlan) error {
+	if len(migrationplan.Spec.Dependencies) == 0 {
+		return nil
+	}
+	groups := map[string]int{}
+	for i, group := range GetMigrationPlanVMGroups(migrationplan) {
+		for _, vm := range group {
+			groups[vm] = i
+		}
+	}
+	edges := map[string][]string{}
+	for _, dependency := range migrationplan.Spec.Dependencies {
+		group, ok := groups[dependency.VM]
+		if !ok {
+			return fmt.Errorf("dependency of unknown VM %s", dependency.VM)
+		}
+		for _, predecessor := range dependency.After {
+			predecessorGroup, ok := groups[predecessor]
+			if !ok {
+				return fmt.Errorf("VM %s depends on unknown VM %s", dependency.VM, predecessor)
+			}
+			if predecessorGroup > group {
+				return fmt.Errorf("VM %s depends on VM %s of a later group", dependency.VM, predecessor)
+			}
+			edges[dependency.VM] = append(edges[dependency.VM], predecessor)
+		}
+	}
+
+	// Depth first search, a VM reached again while it is on the path closes a cycle
+	const (
+		unvisited = iota
+		onPath
+		done
+	)
+	state := map[string]int{}
+	var path []string
+	var visit func(vm string) error
+	visit = func(vm string) error {
+		switch state[vm] {
+		case onPath:
+			cycle := append(slices.Clone(path[slices.Index(path, vm):]), vm)
+			return fmt.Errorf("dependencies form a cycle: %s", strings.Join(cycle, " -> "))
+		case done:
+			return nil
+		}
+		state[vm] = onPath
+		path = append(path, vm)
+		for _, predecessor := range edges[vm] {
+			if err := visit(predecessor); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[vm] = done
+		return nil
+	}
+	for _, dependency := range migrationplan.Spec.Dependencies {
+		if err := visit(dependency.VM); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateSkippedDependencies checks that no VM of the plan depends on a VM the plan skips. The
+// skipped VM gets no migration and the cutover of the VM depending on it would wait forever.
+func ValidateSkippedDependencies(migrationplan *vjailbreakv1alpha1.MigrationPlan, skipped []string) error {
+	for _, dependency := range migrationplan.Spec.Dependencies {
+		for _, predecessor := range dependency.After {
+			if slices.Contains(skipped, predecessor) {
+				return fmt.Errorf("VM %s depends on VM %s, which is skipped for its unsupported or unknown OS", dependency.VM, predecessor)
+			}
+		}
+	}
+	return nil
+}
+
+// GetHealthCheckFailurePolicy returns the policy the health checks of the plan follow when they do
+// not pass. When the plan sets none it is Rollback for plans that roll back failed cutovers and
+// Warn otherwise.
+func GetHealthCheckFailurePolicy(migrationplan *vjailbreakv1alpha1.MigrationPlan) string {
+	strategy := migrationplan.Spec.MigrationStrategy
+	if strategy.HealthCheckFailurePolicy != "" {
+		return strategy.HealthCheckFailurePolicy
+	}
+	if strategy.RollbackPolicy != "" {
+		return vjailbreakv1alpha1.HealthCheckFailurePolicyRollback
+	}
+	return vjailbreakv1alpha1.HealthCheckFailurePolicyWarn
+}
+
+// IsDependencyMet checks if a migration another VM depends on succeeded and passed its health
+// checks. Health checks that did not pass under the Warn policy only report, they do not hold the
+// VMs depending on the migration.
+func IsDependencyMet(migration *vjailbreakv1alpha1.Migration, healthCheckPolicy string) bool {
+	if migration.Status.Phase != vjailbreakv1alpha1.VMMigrationPhaseSucceeded {
+		return false
+	}
+	if healthCheckPolicy == vjailbreakv1alpha1.HealthCheckFailurePolicyWarn {
+		return true
+	}
+	for _, result := range migration.Status.HealthChecks {
+		if result.Status != vjailbreakv1alpha1.HealthCheckStatusPassed {
+			return false
+		}
+	}
+	return true
+}
+
+// IsDependencyFailed checks if a migration another VM depends on ended without succeeding, the VMs
+// depending on it would wait for it forever
+func IsDependencyFailed(migration *vjailbreakv1alpha1.Migration) bool {
+	return IsMigrationFinished(migration) && migration.Status.Phase != vjailbreakv1alpha1.VMMigrationPhaseSucceeded
+}
+
+// GetBlockingDependencies returns the VMs of the plan whose migrations the cutover of vm still waits
+// for, and those of them whose migration ended without succeeding
+func GetBlockingDependencies(ctx context.Context, k3sclient client.Client, migrationplan *vjailbreakv1alpha1.MigrationPlan, vm string) ([]string, []string, error) {
+	after := GetVMDependencies(migrationplan, vm)
+	if len(after) == 0 {
+		return nil, nil, nil
+	}
+	migrationList := &vjailbreakv1alpha1.MigrationList{}
+	if err := k3sclient.List(ctx, migrationList, client.InNamespace(migrationplan.Namespace),
+		client.MatchingLabels{"migrationplan": migrationplan.Name}); err != nil {
+		return nil, nil, errors.Wrap(err, "failed to list migrations")
+	}
+	blockers, failed := getBlockingDependencies(after, migrationList.Items, GetHealthCheckFailurePolicy(migrationplan))
+	return blockers, failed, nil
+}
+
+// getBlockingDependencies returns the VMs of after whose migrations are not met, and those of them
+// whose migration ended without succeeding
+func getBlockingDependencies(after []string, migrations []vjailbreakv1alpha1.Migration, healthCheckPolicy string) ([]string, []string) {
+	var blockers, failed []string
+	for _, predecessor := range after {
+		met := false
+		for i := range migrations {
+			if migrations[i].Spec.VMName == predecessor {
+				met = IsDependencyMet(&migrations[i], healthCheckPolicy)
+				if IsDependencyFailed(&migrations[i]) {
+					failed = append(failed, predecessor)
+				}
+				break
+			}
+		}
+		if !met {
+			blockers = append(blockers, predecessor)
+		}
+	}
+	return blockers, failed
+}
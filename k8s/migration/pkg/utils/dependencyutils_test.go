@@ -0,0 +1,228 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+	"github.com/platform9/vjailbreak/k8s/migration/pkg/sdk/testutils"
+)
+
+func TestValidateDependencies(t *testing.T) {
+	tests := []struct {
+		name         string
+		selectedVMs  []string
+		dependencies []vjailbreakv1alpha1.VMDependency
+		wantErr      string
+	}{
+		{
+			name: "no dependencies",
+		},
+		{
+			name: "same and earlier group",
+			dependencies: []vjailbreakv1alpha1.VMDependency{
+				{VM: "app", After: []string{"db"}},
+				{VM: "web", After: []string{"app", "db"}},
+			},
+		},
+		{
+			name:        "selected VMs follow the groups",
+			selectedVMs: []string{"cache"},
+			dependencies: []vjailbreakv1alpha1.VMDependency{
+				{VM: "cache", After: []string{"web"}},
+			},
+		},
+		{
+			name: "unknown VM",
+			dependencies: []vjailbreakv1alpha1.VMDependency{
+				{VM: "mail", After: []string{"db"}},
+			},
+			wantErr: "dependency of unknown VM mail",
+		},
+		{
+			name: "unknown predecessor",
+			dependencies: []vjailbreakv1alpha1.VMDependency{
+				{VM: "web", After: []string{"mail"}},
+			},
+			wantErr: "VM web depends on unknown VM mail",
+		},
+		{
+			name: "later group",
+			dependencies: []vjailbreakv1alpha1.VMDependency{
+				{VM: "db", After: []string{"web"}},
+			},
+			wantErr: "VM db depends on VM web of a later group",
+		},
+		{
+			name: "VM depends on itself",
+			dependencies: []vjailbreakv1alpha1.VMDependency{
+				{VM: "db", After: []string{"db"}},
+			},
+			wantErr: "dependencies form a cycle: db -> db",
+		},
+		{
+			name: "cycle",
+			dependencies: []vjailbreakv1alpha1.VMDependency{
+				{VM: "db", After: []string{"app"}},
+				{VM: "app", After: []string{"db"}},
+			},
+			wantErr: "dependencies form a cycle: db -> app -> db",
+		},
+		{
+			name: "cycle reached from another VM",
+			dependencies: []vjailbreakv1alpha1.VMDependency{
+				{VM: "web", After: []string{"app"}},
+				{VM: "app", After: []string{"db"}},
+				{VM: "db", After: []string{"app"}},
+			},
+			wantErr: "dependencies form a cycle: app -> db -> app",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			migrationplan := &vjailbreakv1alpha1.MigrationPlan{
+				Spec: vjailbreakv1alpha1.MigrationPlanSpec{
+					VirtualMachines: [][]string{{"db", "app"}, {"web"}},
+					Dependencies:    tt.dependencies,
+				},
+				Status: vjailbreakv1alpha1.MigrationPlanStatus{SelectedVMs: tt.selectedVMs},
+			}
+			err := ValidateDependencies(migrationplan)
+			if tt.wantErr == "" {
+				testutils.Ok(t, err)
+				return
+			}
+			testutils.Assert(t, err != nil && strings.Contains(err.Error(), tt.wantErr), "expected error %q, got %v", tt.wantErr, err)
+		})
+	}
+}
+
+func TestGetVMDependencies(t *testing.T) {
+	migrationplan := &vjailbreakv1alpha1.MigrationPlan{
+		Spec: vjailbreakv1alpha1.MigrationPlanSpec{
+			Dependencies: []vjailbreakv1alpha1.VMDependency{
+				{VM: "web", After: []string{"app", "db"}},
+				{VM: "app", After: []string{"db"}},
+				{VM: "web", After: []string{"db", "cache"}},
+			},
+		},
+	}
+	testutils.Equals(t, []string{"app", "db", "cache"}, GetVMDependencies(migrationplan, "web"))
+	testutils.Equals(t, []string(nil), GetVMDependencies(migrationplan, "db"))
+}
+
+func TestIsDependencyMet(t *testing.T) {
+	migration := &vjailbreakv1alpha1.Migration{}
+	migration.Status.Phase = vjailbreakv1alpha1.VMMigrationPhaseCopying
+	testutils.Equals(t, false, IsDependencyMet(migration, vjailbreakv1alpha1.HealthCheckFailurePolicyFail))
+
+	migration.Status.Phase = vjailbreakv1alpha1.VMMigrationPhaseSucceeded
+	testutils.Equals(t, true, IsDependencyMet(migration, vjailbreakv1alpha1.HealthCheckFailurePolicyFail))
+
+	migration.Status.HealthChecks = []vjailbreakv1alpha1.HealthCheckResult{
+		{Status: vjailbreakv1alpha1.HealthCheckStatusPassed},
+		{Status: vjailbreakv1alpha1.HealthCheckStatusFailed},
+	}
+	testutils.Equals(t, false, IsDependencyMet(migration, vjailbreakv1alpha1.HealthCheckFailurePolicyFail))
+	testutils.Equals(t, false, IsDependencyMet(migration, vjailbreakv1alpha1.HealthCheckFailurePolicyRollback))
+	// Health checks under the Warn policy only report
+	testutils.Equals(t, true, IsDependencyMet(migration, vjailbreakv1alpha1.HealthCheckFailurePolicyWarn))
+}
+
+func TestGetHealthCheckFailurePolicy(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy vjailbreakv1alpha1.MigrationPlanStrategy
+		want     string
+	}{
+		{name: "default", want: vjailbreakv1alpha1.HealthCheckFailurePolicyWarn},
+		{
+			name:     "default with a rollback policy",
+			strategy: vjailbreakv1alpha1.MigrationPlanStrategy{RollbackPolicy: "Manual"},
+			want:     vjailbreakv1alpha1.HealthCheckFailurePolicyRollback,
+		},
+		{
+			name: "set on the plan",
+			strategy: vjailbreakv1alpha1.MigrationPlanStrategy{
+				RollbackPolicy:           "Automatic",
+				HealthCheckFailurePolicy: vjailbreakv1alpha1.HealthCheckFailurePolicyFail,
+			},
+			want: vjailbreakv1alpha1.HealthCheckFailurePolicyFail,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			migrationplan := &vjailbreakv1alpha1.MigrationPlan{}
+			migrationplan.Spec.MigrationStrategy = tt.strategy
+			testutils.Equals(t, tt.want, GetHealthCheckFailurePolicy(migrationplan))
+		})
+	}
+}
+
+func TestGetBlockingDependencies(t *testing.T) {
+	newMigration := func(vm string, phase vjailbreakv1alpha1.VMMigrationPhase, checks ...string) vjailbreakv1alpha1.Migration {
+		migration := vjailbreakv1alpha1.Migration{}
+		migration.Spec.VMName = vm
+		migration.Status.Phase = phase
+		for _, status := range checks {
+			migration.Status.HealthChecks = append(migration.Status.HealthChecks, vjailbreakv1alpha1.HealthCheckResult{Status: status})
+		}
+		return migration
+	}
+	migrations := []vjailbreakv1alpha1.Migration{
+		newMigration("db", vjailbreakv1alpha1.VMMigrationPhaseSucceeded, vjailbreakv1alpha1.HealthCheckStatusPassed),
+		newMigration("app", vjailbreakv1alpha1.VMMigrationPhaseSucceeded, vjailbreakv1alpha1.HealthCheckStatusFailed),
+		newMigration("cache", vjailbreakv1alpha1.VMMigrationPhaseCopying),
+		newMigration("queue", vjailbreakv1alpha1.VMMigrationPhaseFailed),
+		newMigration("search", vjailbreakv1alpha1.VMMigrationPhaseRolledBack),
+	}
+	tests := []struct {
+		name        string
+		after       []string
+		policy      string
+		wantBlocked []string
+		wantFailed  []string
+	}{
+		{name: "met", after: []string{"db"}, policy: vjailbreakv1alpha1.HealthCheckFailurePolicyFail},
+		{name: "failed health check under Warn", after: []string{"db", "app"}, policy: vjailbreakv1alpha1.HealthCheckFailurePolicyWarn},
+		{
+			name:        "failed health check under Fail",
+			after:       []string{"db", "app"},
+			policy:      vjailbreakv1alpha1.HealthCheckFailurePolicyFail,
+			wantBlocked: []string{"app"},
+		},
+		{
+			name:        "migration still running or not created yet",
+			after:       []string{"cache", "web"},
+			policy:      vjailbreakv1alpha1.HealthCheckFailurePolicyWarn,
+			wantBlocked: []string{"cache", "web"},
+		},
+		{
+			name:        "migrations that did not succeed",
+			after:       []string{"db", "queue", "search"},
+			policy:      vjailbreakv1alpha1.HealthCheckFailurePolicyWarn,
+			wantBlocked: []string{"queue", "search"},
+			wantFailed:  []string{"queue", "search"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blockers, failed := getBlockingDependencies(tt.after, migrations, tt.policy)
+			testutils.Equals(t, tt.wantBlocked, blockers)
+			testutils.Equals(t, tt.wantFailed, failed)
+		})
+	}
+}
+
+func TestValidateSkippedDependencies(t *testing.T) {
+	migrationplan := &vjailbreakv1alpha1.MigrationPlan{
+		Spec: vjailbreakv1alpha1.MigrationPlanSpec{
+			Dependencies: []vjailbreakv1alpha1.VMDependency{{VM: "web", After: []string{"app", "db"}}},
+		},
+	}
+	testutils.Ok(t, ValidateSkippedDependencies(migrationplan, nil))
+	testutils.Ok(t, ValidateSkippedDependencies(migrationplan, []string{"web"}))
+	err := ValidateSkippedDependencies(migrationplan, []string{"db"})
+	testutils.Assert(t, err != nil && err.Error() == "VM web depends on VM db, which is skipped for its unsupported or unknown OS",
+		"unexpected error %v", err)
+}
@@ -0,0 +1,98 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+	"github.com/platform9/vjailbreak/k8s/migration/pkg/constants"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+)
+
+// GetHookJobName generates the name of the Job running a hook at a stage of a migration
+func GetHookJobName(migrationName, stage, hookName string) string {
+	return fmt.Sprintf("hook-%s-%s", hookName, GenerateSha256Hash(fmt.Sprintf("%s/%s/%s", migrationName, stage, hookName))[:constants.HashSuffixLength])
+}
+
+// HooksForStage returns the hooks of the migration plan that run at the given stage
+func HooksForStage(hooks []vjailbreakv1alpha1.MigrationHook, stage string) []vjailbreakv1alpha1.MigrationHook {
+	var stageHooks []vjailbreakv1alpha1.MigrationHook
+	for _, hook := range hooks {
+		if hook.Stage == stage {
+			stageHooks = append(stageHooks, hook)
+		}
+	}
+	return stageHooks
+}
+
+// NewHookJob builds the Job running a hook for a migration. The details of the VM and of the
+// target server requested by the v2v-helper are passed in the environment of the hook container.
+func NewHookJob(migration *vjailbreakv1alpha1.Migration, vmMachine *vjailbreakv1alpha1.VMwareMachine, hook vjailbreakv1alpha1.MigrationHook) (*batchv1.Job, error) {
+	vmMachineSpec, err := json.Marshal(vmMachine.Spec)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal vmware machine spec")
+	}
+	stage := migration.Annotations[vjailbreakv1alpha1.HookStageAnnotation]
+	env := []corev1.EnvVar{
+		{Name: "MIGRATION_NAME", Value: migration.Name},
+		{Name: "VM_NAME", Value: migration.Spec.VMName},
+		{Name: "HOOK_NAME", Value: hook.Name},
+		{Name: "HOOK_STAGE", Value: stage},
+		{Name: "VMWARE_MACHINE", Value: string(vmMachineSpec)},
+		{Name: "TARGET_SERVER_ID", Value: migration.Annotations[vjailbreakv1alpha1.HookTargetServerIDAnnotation]},
+		{Name: "TARGET_IPS", Value: migration.Annotations[vjailbreakv1alpha1.HookTargetIPsAnnotation]},
+	}
+	// Variables of the hook come last so that they can override the injected ones
+	env = append(env, hook.Env...)
+	envFrom := make([]corev1.EnvFromSource, 0, len(hook.SecretRefs))
+	for _, secretName := range hook.SecretRefs {
+		envFrom = append(envFrom, corev1.EnvFromSource{
+			SecretRef: &corev1.SecretEnvSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: secretName},
+			},
+		})
+	}
+	timeoutSeconds := hook.TimeoutSeconds
+	if timeoutSeconds <= 0 {
+		timeoutSeconds = constants.DefaultHookTimeoutSeconds
+	}
+	vmk8sname := strings.TrimPrefix(migration.Name, MigrationNameFromVMName(""))
+
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      GetHookJobName(migration.Name, stage, hook.Name),
+			Namespace: migration.Namespace,
+			Labels: map[string]string{
+				vjailbreakv1alpha1.HookVMLabel:    vmk8sname,
+				vjailbreakv1alpha1.HookStageLabel: stage,
+				vjailbreakv1alpha1.HookNameLabel:  hook.Name,
+			},
+		},
+		Spec: batchv1.JobSpec{
+			// A hook runs once, its outcome decides whether the migration goes on
+			BackoffLimit:          ptr.To(int32(0)),
+			ActiveDeadlineSeconds: ptr.To(timeoutSeconds),
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyNever,
+					Containers: []corev1.Container{
+						{
+							Name:            "hook",
+							Image:           hook.Image,
+							ImagePullPolicy: corev1.PullIfNotPresent,
+							Command:         hook.Command,
+							Args:            hook.Args,
+							Env:             env,
+							EnvFrom:         envFrom,
+						},
+					},
+				},
+			},
+		},
+	}, nil
+}
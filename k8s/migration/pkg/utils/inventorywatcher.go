@@ -0,0 +1,636 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+	"github.com/platform9/vjailbreak/k8s/migration/pkg/constants"
+	scope "github.com/platform9/vjailbreak/k8s/migration/pkg/scope"
+	netutils "github.com/platform9/vjailbreak/pkg/common/utils"
+	"github.com/vmware/govmomi/find"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/session"
+	"github.com/vmware/govmomi/vapi/rest"
+	"github.com/vmware/govmomi/view"
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/soap"
+	"github.com/vmware/govmomi/vim25/types"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+// inventoryVMProperties are the properties of a VM its VMwareMachine is built from, a change to
+// any of them updates the VMwareMachine
+var inventoryVMProperties = []string{
+	"name",
+	"config.hardware",
+	"config.firmware",
+	"config.bootOptions",
+	"config.version",
+	"guest.net",
+	"guest.guestState",
+	"guest.guestFamily",
+	"guest.ipAddress",
+	"parent",
+	"resourcePool",
+	"runtime.host",
+	"summary.config.annotation",
+}
+
+// VMwareInventoryWatchers keeps a PropertyCollector WaitForUpdatesEx session with the vCenter of
+// each VMwareCreds and applies the changes of its inventory to the VMwareMachines, VMwareHosts,
+// VMwareClusters and RDMDisks as they happen. The inventory is scanned in full only when a session
+// starts. When a session is lost, an event is sent for the VMwareCreds so that it is resynced.
+type VMwareInventoryWatchers struct {
+	client client.Client
+	events chan event.GenericEvent
+
+	mu       sync.Mutex
+	ctx      context.Context
+	watchers map[k8stypes.UID]*inventoryWatcher
+}
+
+// NewVMwareInventoryWatchers creates the inventory watchers. They start their sessions once the
+// manager they are added to runs.
+func NewVMwareInventoryWatchers(k3sclient client.Client) *VMwareInventoryWatchers {
+	return &VMwareInventoryWatchers{
+		client:   k3sclient,
+		events:   make(chan event.GenericEvent, 16),
+		watchers: map[k8stypes.UID]*inventoryWatcher{},
+	}
+}
+
+// Start implements manager.Runnable. It ends all sessions when the manager stops.
+func (w *VMwareInventoryWatchers) Start(ctx context.Context) error {
+	w.mu.Lock()
+	w.ctx = ctx
+	w.mu.Unlock()
+
+	<-ctx.Done()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for uid, watcher := range w.watchers {
+		watcher.cancel()
+		delete(w.watchers, uid)
+	}
+	return nil
+}
+
+// Events returns the channel the VMwareCreds whose session was lost are sent to
+func (w *VMwareInventoryWatchers) Events() <-chan event.GenericEvent {
+	return w.events
+}
+
+// Watch makes sure a session runs for the current spec of the VMwareCreds. It returns whether the
+// inventory was scanned in full since the session started, the caller scans it and calls
+// MarkSynced otherwise.
+func (w *VMwareInventoryWatchers) Watch(vmwcreds *vjailbreakv1alpha1.VMwareCreds) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.ctx == nil {
+		// The manager has not started the watchers yet
+		return false
+	}
+	if watcher, ok := w.watchers[vmwcreds.UID]; ok {
+		if watcher.generation == vmwcreds.Generation {
+			return watcher.synced
+		}
+		// The credentials or the datacenter changed, the session is started again
+		watcher.cancel()
+	}
+
+	ctx, cancel := context.WithCancel(w.ctx)
+	watcher := &inventoryWatcher{
+		cancel:     cancel,
+		generation: vmwcreds.Generation,
+		key:        k8stypes.NamespacedName{Name: vmwcreds.Name, Namespace: vmwcreds.Namespace},
+		datacenter: vmwcreds.Spec.DataCenter,
+		withTags:   VMTagsSupported(vmwcreds),
+		client:     w.client,
+		log:        ctrl.Log.WithName("vmware-inventory").WithValues("vmwarecreds", vmwcreds.Name),
+		vmNames:    map[string]string{},
+		changedVMs: map[string]bool{},
+		vmDCs:      map[string]string{},
+	}
+	w.watchers[vmwcreds.UID] = watcher
+	go w.run(ctx, watcher, vmwcreds.DeepCopy())
+	return false
+}
+
+// MarkSynced records that the inventory of the VMwareCreds was scanned in full since its session started
+func (w *VMwareInventoryWatchers) MarkSynced(vmwcreds *vjailbreakv1alpha1.VMwareCreds) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if watcher, ok := w.watchers[vmwcreds.UID]; ok && watcher.generation == vmwcreds.Generation {
+		watcher.synced = true
+	}
+}
+
+// Stop ends the session of the VMwareCreds
+func (w *VMwareInventoryWatchers) Stop(vmwcreds *vjailbreakv1alpha1.VMwareCreds) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if watcher, ok := w.watchers[vmwcreds.UID]; ok {
+		watcher.cancel()
+		delete(w.watchers, vmwcreds.UID)
+	}
+}
+
+func (w *VMwareInventoryWatchers) run(ctx context.Context, watcher *inventoryWatcher, vmwcreds *vjailbreakv1alpha1.VMwareCreds) {
+	err := watcher.run(ctx, vmwcreds)
+
+	w.mu.Lock()
+	if w.watchers[vmwcreds.UID] == watcher {
+		delete(w.watchers, vmwcreds.UID)
+	}
+	w.mu.Unlock()
+	if ctx.Err() != nil {
+		// Stopped on purpose
+		return
+	}
+	watcher.log.Error(err, "Lost vCenter inventory session, resyncing", "after", constants.VMwareInventoryRetryAfter)
+	select {
+	case <-ctx.Done():
+	case <-time.After(constants.VMwareInventoryRetryAfter):
+		// The controller stops reading the events when the manager stops
+		select {
+		case <-ctx.Done():
+		case w.events <- event.GenericEvent{Object: vmwcreds}:
+		}
+	}
+}
+
+// inventoryWatcher is the session of a VMwareCreds. Its fields past log are guarded by mu, they
+// are written by the updates of the session and read by the flushes.
+type inventoryWatcher struct {
+	cancel     context.CancelFunc
+	generation int64
+	// synced is guarded by the mutex of VMwareInventoryWatchers
+	synced     bool
+	key        k8stypes.NamespacedName
+	datacenter string
+	// withTags is set when the endpoint serves vSphere tags
+	withTags bool
+	client   client.Client
+	log      logr.Logger
+
+	// tagsClient is the vSphere Automation API session the tags are read with, it is only used by
+	// the flush goroutine
+	tagsClient *rest.Client
+
+	mu sync.Mutex
+	// initialized is set once the initial state of the inventory was received, the full scan
+	// already covers it
+	initialized bool
+	// vmNames are the names of the VMs by managed object ID
+	vmNames map[string]string
+	// changedVMs and deletedVMs are the VMs to apply at the next flush
+	changedVMs   map[string]bool
+	deletedVMs   []string
+	hostsChanged bool
+	// vmDCs are the datacenters of the VMs by managed object ID
+	vmDCs map[string]string
+}
+
+func (watcher *inventoryWatcher) run(ctx context.Context, vmwcreds *vjailbreakv1alpha1.VMwareCreds) error {
+	c, err := loginVMwareSession(ctx, watcher.client, vmwcreds)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := session.NewManager(c).Logout(context.Background()); err != nil {
+			watcher.log.Error(err, "Failed to logout of vCenter inventory session")
+		}
+	}()
+
+	root := c.ServiceContent.RootFolder
+	if watcher.datacenter != "" {
+		dc, err := find.NewFinder(c, false).Datacenter(ctx, watcher.datacenter)
+		if err != nil {
+			return errors.Wrapf(err, "failed to find datacenter %s", watcher.datacenter)
+		}
+		root = dc.Reference()
+	}
+	containerView, err := view.NewManager(c).CreateContainerView(ctx, root,
+		[]string{"VirtualMachine", "HostSystem", "ClusterComputeResource"}, true)
+	if err != nil {
+		return errors.Wrap(err, "failed to create inventory view")
+	}
+	defer func() {
+		_ = containerView.Destroy(context.Background())
+	}()
+	pc, err := property.DefaultCollector(c).Create(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to create property collector")
+	}
+	defer func() {
+		_ = pc.Destroy(context.Background())
+	}()
+
+	filter := &property.WaitFilter{}
+	filter.Spec.ObjectSet = []types.ObjectSpec{{
+		Obj:       containerView.Reference(),
+		Skip:      types.NewBool(true),
+		SelectSet: []types.BaseSelectionSpec{&types.TraversalSpec{Type: "ContainerView", Path: "view"}},
+	}}
+	filter.Spec.PropSet = []types.PropertySpec{
+		{Type: "VirtualMachine", PathSet: inventoryVMProperties},
+		{Type: "HostSystem", PathSet: []string{"name", "parent"}},
+		{Type: "ClusterComputeResource", PathSet: []string{"name", "host"}},
+	}
+
+	flushCtx, stopFlush := context.WithCancel(ctx)
+	flushDone := make(chan struct{})
+	defer func() {
+		stopFlush()
+		<-flushDone
+	}()
+	go func() {
+		defer close(flushDone)
+		defer watcher.logoutTags()
+		ticker := time.NewTicker(constants.VMwareInventoryFlushInterval)
+		defer ticker.Stop()
+		tagsTicker := time.NewTicker(constants.VMwareTagsRefreshInterval)
+		defer tagsTicker.Stop()
+		for {
+			select {
+			case <-flushCtx.Done():
+				return
+			case <-ticker.C:
+				watcher.flush(flushCtx, c)
+			case <-tagsTicker.C:
+				watcher.refreshTags(flushCtx, c)
+			}
+		}
+	}()
+
+	watcher.log.Info("Started vCenter inventory session")
+	err = property.WaitForUpdatesEx(ctx, pc, filter, func(updates []types.ObjectUpdate) bool {
+		watcher.record(updates, filter.Truncated)
+		return false
+	})
+	if err == nil && ctx.Err() == nil {
+		err = fmt.Errorf("inventory session ended")
+	}
+	return err
+}
+
+// record remembers the VMs, hosts and clusters that changed until the next flush
+func (watcher *inventoryWatcher) record(updates []types.ObjectUpdate, truncated bool) {
+	watcher.mu.Lock()
+	defer watcher.mu.Unlock()
+	for _, update := range updates {
+		if update.Obj.Type != "VirtualMachine" {
+			// Hosts and clusters are few, they are synced together when one of them changes
+			watcher.hostsChanged = watcher.hostsChanged || watcher.initialized
+			continue
+		}
+		id := update.Obj.Value
+		if update.Kind == types.ObjectUpdateKindLeave {
+			if name := watcher.vmNames[id]; name != "" {
+				watcher.deletedVMs = append(watcher.deletedVMs, name)
+			}
+			delete(watcher.vmNames, id)
+			delete(watcher.changedVMs, id)
+			delete(watcher.vmDCs, id)
+			continue
+		}
+		for _, change := range update.ChangeSet {
+			if change.Name != "name" {
+				continue
+			}
+			name, _ := change.Val.(string)
+			if previous := watcher.vmNames[id]; previous != "" && previous != name {
+				// The VMwareMachine of a renamed VM is created again under its new name
+				watcher.deletedVMs = append(watcher.deletedVMs, previous)
+			}
+			watcher.vmNames[id] = name
+		}
+		if watcher.initialized {
+			watcher.changedVMs[id] = true
+		}
+	}
+	// The initial state of a large inventory is received in several truncated parts
+	if !truncated {
+		watcher.initialized = true
+	}
+}
+
+// flush applies the changes recorded since the last flush. The changes that fail to apply are
+// recorded again and retried at the next flush, there is no full scan that would repair them.
+func (watcher *inventoryWatcher) flush(ctx context.Context, c *vim25.Client) {
+	changed, deleted, hostsChanged := watcher.take()
+	if len(changed) == 0 && len(deleted) == 0 && !hostsChanged {
+		return
+	}
+	failedVMs, failedDeletions, hostsFailed := watcher.apply(ctx, c, changed, deleted, hostsChanged)
+	watcher.requeue(failedVMs, failedDeletions, hostsFailed)
+}
+
+// take returns the changes recorded since the last flush, as the names of the changed VMs by
+// managed object ID, the names of the deleted VMs and whether hosts or clusters changed
+func (watcher *inventoryWatcher) take() (map[string]string, []string, bool) {
+	watcher.mu.Lock()
+	defer watcher.mu.Unlock()
+	changed := map[string]string{}
+	for id := range watcher.changedVMs {
+		changed[id] = watcher.vmNames[id]
+	}
+	deleted := watcher.deletedVMs
+	hostsChanged := watcher.hostsChanged
+	watcher.changedVMs = map[string]bool{}
+	watcher.deletedVMs = nil
+	watcher.hostsChanged = false
+	return changed, deleted, hostsChanged
+}
+
+// requeue records changes that failed to apply for the next flush. The failed deletion of a name
+// that a VM has again is dropped, the VMwareMachine of that name belongs to the VM now.
+func (watcher *inventoryWatcher) requeue(vmIDs, deleted []string, hostsChanged bool) {
+	watcher.mu.Lock()
+	defer watcher.mu.Unlock()
+	for _, id := range vmIDs {
+		// VMs that left the inventory since are not applied again
+		if _, ok := watcher.vmNames[id]; ok {
+			watcher.changedVMs[id] = true
+		}
+	}
+	names := map[string]bool{}
+	for _, name := range watcher.vmNames {
+		names[name] = true
+	}
+	for _, name := range deleted {
+		if !names[name] && !slices.Contains(watcher.deletedVMs, name) {
+			watcher.deletedVMs = append(watcher.deletedVMs, name)
+		}
+	}
+	watcher.hostsChanged = watcher.hostsChanged || hostsChanged
+}
+
+// apply applies changes to the VMwareMachines, VMwareHosts, VMwareClusters and RDMDisks. It returns
+// the IDs of the VMs and the names of the deleted VMs that failed to apply, and whether the hosts
+// and clusters failed to.
+func (watcher *inventoryWatcher) apply(ctx context.Context, c *vim25.Client, changed map[string]string,
+	deleted []string, hostsChanged bool,
+) ([]string, []string, bool) {
+	failedVMs := make([]string, 0, len(changed))
+	for id := range changed {
+		failedVMs = append(failedVMs, id)
+	}
+	vmwcreds := &vjailbreakv1alpha1.VMwareCreds{}
+	if err := watcher.client.Get(ctx, watcher.key, vmwcreds); err != nil {
+		watcher.log.Error(err, "Failed to get VMwareCreds to apply inventory changes")
+		return failedVMs, deleted, hostsChanged
+	}
+	credsScope, err := scope.NewVMwareCredsScope(scope.VMwareCredsScopeParams{
+		Logger:      watcher.log,
+		Client:      watcher.client,
+		VMwareCreds: vmwcreds,
+	})
+	if err != nil {
+		watcher.log.Error(err, "Failed to create VMwareCreds scope")
+		return failedVMs, deleted, hostsChanged
+	}
+	watcher.log.Info("Applying vCenter inventory changes", "changedVMs", len(changed), "deletedVMs", len(deleted), "hostsChanged", hostsChanged)
+
+	hostsFailed := false
+	if hostsChanged {
+		if err := CreateVMwareClustersAndHosts(ctx, credsScope); err != nil {
+			watcher.log.Error(err, "Failed to update clusters and hosts")
+			hostsFailed = true
+		} else if err := DeleteStaleVMwareClustersAndHosts(ctx, credsScope); err != nil {
+			watcher.log.Error(err, "Failed to delete clusters and hosts")
+			hostsFailed = true
+		}
+	}
+
+	// Deleted VMs go first, a VM created under the name of a deleted one keeps its VMwareMachine
+	failedDeletions := []string{}
+	for _, name := range deleted {
+		if err := DeleteVMwareMachine(ctx, watcher.client, vmwcreds, name); err != nil {
+			watcher.log.Error(err, "Failed to delete VMwareMachine", "vm", name)
+			failedDeletions = append(failedDeletions, name)
+		}
+	}
+
+	vmErrors := []vmError{}
+	errMu := sync.Mutex{}
+	vminfo := []vjailbreakv1alpha1.VMInfo{}
+	vminfoMu := sync.Mutex{}
+	rdmDiskMap := &sync.Map{}
+	vms := make([]*object.VirtualMachine, 0, len(changed))
+	for id, name := range changed {
+		vm := object.NewVirtualMachine(c, types.ManagedObjectReference{Type: "VirtualMachine", Value: id})
+		vm.InventoryPath = name
+		// The inventory path gives the folder of the VM
+		if inventoryPath, err := find.InventoryPath(ctx, c, vm.Reference()); err == nil {
+			vm.InventoryPath = inventoryPath
+		} else {
+			watcher.log.Error(err, "Failed to get VM inventory path", "vm", name)
+		}
+		vms = append(vms, vm)
+	}
+	refs := make([]types.ManagedObjectReference, 0, len(vms))
+	for _, vm := range vms {
+		refs = append(refs, vm.Reference())
+	}
+	vmTags := watcher.getTags(ctx, c, refs)
+	for _, vm := range vms {
+		name := changed[vm.Reference().Value]
+		dc, err := watcher.vmDatacenter(ctx, c, vm.Reference())
+		if err != nil {
+			vmErrors = append(vmErrors, vmError{vmName: name, err: err})
+			continue
+		}
+		processSingleVM(ctx, credsScope, vm, &errMu, &vmErrors, &vminfoMu, &vminfo, c, rdmDiskMap, dc, vmTags)
+	}
+	if err := CreateOrUpdateRDMDisks(ctx, watcher.client, vmwcreds, rdmDiskMap); err != nil {
+		watcher.log.Error(err, "Failed to update RDM disks")
+	}
+
+	failedNames := map[string]bool{}
+	for _, e := range vmErrors {
+		watcher.log.Error(e.err, "Failed to apply VM change", "vmName", e.vmName)
+		failedNames[e.vmName] = true
+	}
+	failedVMs = failedVMs[:0]
+	for id, name := range changed {
+		if failedNames[name] {
+			failedVMs = append(failedVMs, id)
+		}
+	}
+	return failedVMs, failedDeletions, hostsFailed
+}
+
+// getTags returns the tags of the VMs. When the tags cannot be read, the VMs keep the tags they had
+// until the next refresh of the tags.
+func (watcher *inventoryWatcher) getTags(ctx context.Context, c *vim25.Client, refs []types.ManagedObjectReference) map[string][]string {
+	if !watcher.withTags || len(refs) == 0 {
+		return nil
+	}
+	vmTags, err := watcher.readTags(ctx, c, refs)
+	if err != nil {
+		watcher.log.Error(err, "Failed to get VM tags")
+		return nil
+	}
+	return vmTags
+}
+
+// refreshTags reads the tags of all VMs and records the VMs whose tags differ from the tags of their
+// VMwareMachine, attaching or detaching a tag does not show in the inventory session
+func (watcher *inventoryWatcher) refreshTags(ctx context.Context, c *vim25.Client) {
+	if !watcher.withTags {
+		return
+	}
+	vmwcreds := &vjailbreakv1alpha1.VMwareCreds{}
+	if err := watcher.client.Get(ctx, watcher.key, vmwcreds); err != nil {
+		watcher.log.Error(err, "Failed to get VMwareCreds to refresh VM tags")
+		return
+	}
+	vmList, err := FilterVMwareMachinesForCreds(ctx, watcher.client, vmwcreds)
+	if err != nil {
+		watcher.log.Error(err, "Failed to list VMwareMachines to refresh VM tags")
+		return
+	}
+	knownTags := make(map[string][]string, len(vmList.Items))
+	for i := range vmList.Items {
+		knownTags[vmList.Items[i].Spec.VMInfo.Name] = vmList.Items[i].Spec.VMInfo.Tags
+	}
+
+	watcher.mu.Lock()
+	refs := make([]types.ManagedObjectReference, 0, len(watcher.vmNames))
+	for id, name := range watcher.vmNames {
+		// VMs without a VMwareMachine are not migrated, their tags do not matter
+		if _, ok := knownTags[name]; ok {
+			refs = append(refs, types.ManagedObjectReference{Type: "VirtualMachine", Value: id})
+		}
+	}
+	watcher.mu.Unlock()
+	if len(refs) == 0 {
+		return
+	}
+	vmTags, err := watcher.readTags(ctx, c, refs)
+	if err != nil {
+		watcher.log.Error(err, "Failed to refresh VM tags")
+		return
+	}
+
+	watcher.mu.Lock()
+	defer watcher.mu.Unlock()
+	for id, tags := range vmTags {
+		name, ok := watcher.vmNames[id]
+		if ok && !slices.Equal(knownTags[name], tags) {
+			watcher.changedVMs[id] = true
+		}
+	}
+}
+
+// readTags reads the tags of the VMs in the vSphere Automation API session of the watcher. The
+// session is logged in again after a failure.
+func (watcher *inventoryWatcher) readTags(ctx context.Context, c *vim25.Client, refs []types.ManagedObjectReference) (map[string][]string, error) {
+	if watcher.tagsClient == nil {
+		vmwcreds := &vjailbreakv1alpha1.VMwareCreds{}
+		if err := watcher.client.Get(ctx, watcher.key, vmwcreds); err != nil {
+			return nil, errors.Wrap(err, "failed to get VMwareCreds")
+		}
+		tagsClient, err := LoginVSphereAutomation(ctx, watcher.client, vmwcreds, c)
+		if err != nil {
+			return nil, err
+		}
+		watcher.tagsClient = tagsClient
+	}
+	vmTags, err := GetVMTags(ctx, watcher.tagsClient, refs)
+	if err != nil {
+		watcher.logoutTags()
+		return nil, err
+	}
+	return vmTags, nil
+}
+
+// logoutTags ends the vSphere Automation API session of the watcher
+func (watcher *inventoryWatcher) logoutTags() {
+	if watcher.tagsClient == nil {
+		return
+	}
+	if err := watcher.tagsClient.Logout(context.Background()); err != nil {
+		watcher.log.Error(err, "Failed to logout from the vSphere Automation API")
+	}
+	watcher.tagsClient = nil
+}
+
+// vmDatacenter returns the name of the datacenter of a VM
+func (watcher *inventoryWatcher) vmDatacenter(ctx context.Context, c *vim25.Client, ref types.ManagedObjectReference) (string, error) {
+	if watcher.datacenter != "" {
+		return watcher.datacenter, nil
+	}
+	watcher.mu.Lock()
+	dc, ok := watcher.vmDCs[ref.Value]
+	watcher.mu.Unlock()
+	if ok {
+		return dc, nil
+	}
+
+	pc := property.DefaultCollector(c)
+	var vmProps mo.VirtualMachine
+	if err := pc.RetrieveOne(ctx, ref, []string{"parent", "parentVApp"}, &vmProps); err != nil {
+		return "", errors.Wrap(err, "failed to get VM parent")
+	}
+	parent := vmProps.Parent
+	if parent == nil {
+		// VMs of a vApp have no folder
+		parent = vmProps.ParentVApp
+	}
+	for parent != nil && parent.Type != "Datacenter" {
+		var entity mo.ManagedEntity
+		if err := pc.RetrieveOne(ctx, *parent, []string{"parent"}, &entity); err != nil {
+			return "", errors.Wrapf(err, "failed to get parent of %s", parent.Value)
+		}
+		parent = entity.Parent
+	}
+	if parent == nil {
+		return "", fmt.Errorf("VM %s is not in a datacenter", ref.Value)
+	}
+	var datacenter mo.Datacenter
+	if err := pc.RetrieveOne(ctx, *parent, []string{"name"}, &datacenter); err != nil {
+		return "", errors.Wrap(err, "failed to get datacenter name")
+	}
+
+	watcher.mu.Lock()
+	watcher.vmDCs[ref.Value] = datacenter.Name
+	watcher.mu.Unlock()
+	return datacenter.Name, nil
+}
+
+// loginVMwareSession logs in to the vCenter of the credentials with a session of its own. The
+// client of ValidateVMwareCreds is shared and logged out by its callers, which would end the
+// inventory session.
+func loginVMwareSession(ctx context.Context, k3sclient client.Client, vmwcreds *vjailbreakv1alpha1.VMwareCreds) (*vim25.Client, error) {
+	vmwareCredsinfo, err := GetVMwareCredentialsFromSecret(ctx, k3sclient, vmwcreds.Spec.SecretRef.Name)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get vCenter credentials from secret")
+	}
+	u, err := netutils.NormalizeVCenterURL(vmwareCredsinfo.Host)
+	if err != nil {
+		return nil, err
+	}
+	c, err := vim25.NewClient(ctx, soap.NewClient(u, vmwareCredsinfo.Insecure))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create vCenter client")
+	}
+	if err := session.NewManager(c).Login(ctx, url.UserPassword(vmwareCredsinfo.Username, vmwareCredsinfo.Password)); err != nil {
+		return nil, errors.Wrap(err, "failed to login to vCenter")
+	}
+	return c, nil
+}
@@ -0,0 +1,164 @@
+package utils
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/platform9/vjailbreak/k8s/migration/pkg/sdk/testutils"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+func newTestInventoryWatcher() *inventoryWatcher {
+	return &inventoryWatcher{
+		vmNames:    map[string]string{},
+		changedVMs: map[string]bool{},
+		vmDCs:      map[string]string{},
+	}
+}
+
+func vmUpdate(kind types.ObjectUpdateKind, id, name string) types.ObjectUpdate {
+	update := types.ObjectUpdate{
+		Kind: kind,
+		Obj:  types.ManagedObjectReference{Type: "VirtualMachine", Value: id},
+	}
+	if name != "" {
+		update.ChangeSet = []types.PropertyChange{{Name: "name", Op: types.PropertyChangeOpAssign, Val: name}}
+	}
+	return update
+}
+
+func changedIDs(watcher *inventoryWatcher) []string {
+	ids := []string{}
+	for id := range watcher.changedVMs {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func TestInventoryWatcherRecord(t *testing.T) {
+	tests := []struct {
+		name             string
+		initial          []types.ObjectUpdate
+		updates          []types.ObjectUpdate
+		wantNames        map[string]string
+		wantChanged      []string
+		wantDeleted      []string
+		wantHostsChanged bool
+	}{
+		{
+			name:        "initial state is not a change",
+			updates:     nil,
+			wantNames:   map[string]string{"vm-1": "web", "vm-2": "db"},
+			wantChanged: []string{},
+		},
+		{
+			name:        "modified VM",
+			updates:     []types.ObjectUpdate{vmUpdate(types.ObjectUpdateKindModify, "vm-1", "")},
+			wantNames:   map[string]string{"vm-1": "web", "vm-2": "db"},
+			wantChanged: []string{"vm-1"},
+		},
+		{
+			name:        "renamed VM",
+			updates:     []types.ObjectUpdate{vmUpdate(types.ObjectUpdateKindModify, "vm-1", "web-new")},
+			wantNames:   map[string]string{"vm-1": "web-new", "vm-2": "db"},
+			wantChanged: []string{"vm-1"},
+			wantDeleted: []string{"web"},
+		},
+		{
+			name: "deleted VM",
+			updates: []types.ObjectUpdate{
+				vmUpdate(types.ObjectUpdateKindModify, "vm-1", ""),
+				vmUpdate(types.ObjectUpdateKindLeave, "vm-1", ""),
+			},
+			wantNames:   map[string]string{"vm-2": "db"},
+			wantChanged: []string{},
+			wantDeleted: []string{"web"},
+		},
+		{
+			name: "VM deleted and created again under its name",
+			updates: []types.ObjectUpdate{
+				vmUpdate(types.ObjectUpdateKindLeave, "vm-1", ""),
+				vmUpdate(types.ObjectUpdateKindEnter, "vm-3", "web"),
+			},
+			wantNames:   map[string]string{"vm-2": "db", "vm-3": "web"},
+			wantChanged: []string{"vm-3"},
+			wantDeleted: []string{"web"},
+		},
+		{
+			name: "host change",
+			updates: []types.ObjectUpdate{{
+				Kind: types.ObjectUpdateKindModify,
+				Obj:  types.ManagedObjectReference{Type: "HostSystem", Value: "host-1"},
+			}},
+			wantNames:        map[string]string{"vm-1": "web", "vm-2": "db"},
+			wantChanged:      []string{},
+			wantHostsChanged: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			watcher := newTestInventoryWatcher()
+			watcher.record([]types.ObjectUpdate{
+				vmUpdate(types.ObjectUpdateKindEnter, "vm-1", "web"),
+				vmUpdate(types.ObjectUpdateKindEnter, "vm-2", "db"),
+				{Kind: types.ObjectUpdateKindEnter, Obj: types.ManagedObjectReference{Type: "HostSystem", Value: "host-1"}},
+			}, false)
+			watcher.record(tt.updates, false)
+			testutils.Equals(t, tt.wantNames, watcher.vmNames)
+			testutils.Equals(t, tt.wantChanged, changedIDs(watcher))
+			testutils.Equals(t, tt.wantDeleted, watcher.deletedVMs)
+			testutils.Equals(t, tt.wantHostsChanged, watcher.hostsChanged)
+		})
+	}
+}
+
+func TestInventoryWatcherRecordTruncatedInitialState(t *testing.T) {
+	watcher := newTestInventoryWatcher()
+	watcher.record([]types.ObjectUpdate{vmUpdate(types.ObjectUpdateKindEnter, "vm-1", "web")}, true)
+	watcher.record([]types.ObjectUpdate{
+		vmUpdate(types.ObjectUpdateKindEnter, "vm-2", "db"),
+		{Kind: types.ObjectUpdateKindEnter, Obj: types.ManagedObjectReference{Type: "ClusterComputeResource", Value: "c-1"}},
+	}, true)
+	testutils.Equals(t, false, watcher.initialized)
+	watcher.record([]types.ObjectUpdate{vmUpdate(types.ObjectUpdateKindEnter, "vm-3", "app")}, false)
+
+	// The parts of the initial state are covered by the full scan
+	testutils.Equals(t, true, watcher.initialized)
+	testutils.Equals(t, map[string]string{"vm-1": "web", "vm-2": "db", "vm-3": "app"}, watcher.vmNames)
+	testutils.Equals(t, []string{}, changedIDs(watcher))
+	testutils.Equals(t, false, watcher.hostsChanged)
+
+	watcher.record([]types.ObjectUpdate{vmUpdate(types.ObjectUpdateKindModify, "vm-2", "")}, false)
+	testutils.Equals(t, []string{"vm-2"}, changedIDs(watcher))
+}
+
+func TestInventoryWatcherRequeue(t *testing.T) {
+	watcher := newTestInventoryWatcher()
+	watcher.record([]types.ObjectUpdate{
+		vmUpdate(types.ObjectUpdateKindEnter, "vm-1", "web"),
+		vmUpdate(types.ObjectUpdateKindEnter, "vm-2", "db"),
+	}, false)
+	watcher.record([]types.ObjectUpdate{
+		vmUpdate(types.ObjectUpdateKindModify, "vm-1", ""),
+		vmUpdate(types.ObjectUpdateKindLeave, "vm-2", ""),
+	}, false)
+
+	changed, deleted, hostsChanged := watcher.take()
+	testutils.Equals(t, map[string]string{"vm-1": "web"}, changed)
+	testutils.Equals(t, []string{"db"}, deleted)
+	testutils.Equals(t, false, hostsChanged)
+	testutils.Equals(t, []string{}, changedIDs(watcher))
+
+	// A VM of the deleted name appears, and a VM leaves, before the failures are recorded again
+	watcher.record([]types.ObjectUpdate{
+		vmUpdate(types.ObjectUpdateKindEnter, "vm-3", "db"),
+		vmUpdate(types.ObjectUpdateKindEnter, "vm-4", "app"),
+		vmUpdate(types.ObjectUpdateKindLeave, "vm-4", ""),
+	}, false)
+	watcher.requeue([]string{"vm-1", "vm-4"}, []string{"db", "cache"}, true)
+
+	testutils.Equals(t, []string{"vm-1", "vm-3"}, changedIDs(watcher))
+	testutils.Equals(t, []string{"app", "cache"}, watcher.deletedVMs)
+	testutils.Equals(t, true, watcher.hostsChanged)
+}
@@ -0,0 +1,143 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/gophercloud/gophercloud/v2"
+	"github.com/gophercloud/gophercloud/v2/openstack/networking/v2/networks"
+	"github.com/gophercloud/gophercloud/v2/openstack/networking/v2/subnets"
+	"github.com/pkg/errors"
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// CheckIPv6SubnetSupport returns warnings for the IPv6 addresses of vmMachines that the target
+// networks of the NetworkMapping of migrationtemplate cannot keep, the port of such a NIC cannot
+// be created
+func CheckIPv6SubnetSupport(ctx context.Context, k3sclient client.Client, migrationtemplate *vjailbreakv1alpha1.MigrationTemplate,
+	vmMachines []*vjailbreakv1alpha1.VMwareMachine,
+) ([]string, error) {
+	networkmap := &vjailbreakv1alpha1.NetworkMapping{}
+	if err := k3sclient.Get(ctx, k8stypes.NamespacedName{Name: migrationtemplate.Spec.NetworkMapping, Namespace: migrationtemplate.Namespace}, networkmap); err != nil {
+		return nil, errors.Wrap(err, "failed to retrieve NetworkMapping CR")
+	}
+	var warnings []string
+	var networkingClient *gophercloud.ServiceClient
+	for _, vmMachine := range vmMachines {
+		if len(getIPv6AddressesByTarget(vmMachine, networkmap)) == 0 {
+			continue
+		}
+		if networkingClient == nil {
+			openstackcreds := &vjailbreakv1alpha1.OpenstackCreds{}
+			if err := k3sclient.Get(ctx, k8stypes.NamespacedName{Name: migrationtemplate.Spec.Destination.OpenstackRef, Namespace: migrationtemplate.Namespace}, openstackcreds); err != nil {
+				return nil, errors.Wrap(err, "failed to get openstack credentials")
+			}
+			openstackClients, err := GetOpenStackClients(ctx, k3sclient, openstackcreds)
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to get openstack clients")
+			}
+			networkingClient = openstackClients.NetworkingClient
+		}
+		problems, err := CheckIPv6Subnets(ctx, networkingClient, vmMachine, networkmap)
+		if err != nil {
+			return nil, err
+		}
+		for _, problem := range problems {
+			warnings = append(warnings, fmt.Sprintf("VM %s: %s", vmMachine.Spec.VMInfo.Name, problem))
+		}
+	}
+	return warnings, nil
+}
+
+// CheckIPv6Subnets returns the IPv6 addresses of vmMachine that no IPv6 subnet of the network its
+// NIC is mapped to contains. The migration keeps the IPv6 addresses of a VM apart from link-local
+// ones, and creating its port fails for an address outside the subnets of the network.
+func CheckIPv6Subnets(ctx context.Context, networkingClient *gophercloud.ServiceClient, vmMachine *vjailbreakv1alpha1.VMwareMachine,
+	networkmap *vjailbreakv1alpha1.NetworkMapping,
+) ([]string, error) {
+	addresses := getIPv6AddressesByTarget(vmMachine, networkmap)
+	cidrs := map[string][]string{}
+	for target := range addresses {
+		allPages, err := networks.List(networkingClient, networks.ListOpts{Name: target}).AllPages(ctx)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to list networks named %s", target)
+		}
+		targetNetworks, err := networks.ExtractNetworks(allPages)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to extract networks")
+		}
+		for _, network := range targetNetworks {
+			allPages, err := subnets.List(networkingClient, subnets.ListOpts{NetworkID: network.ID, IPVersion: 6}).AllPages(ctx)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to list subnets of network %s", target)
+			}
+			networkSubnets, err := subnets.ExtractSubnets(allPages)
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to extract subnets")
+			}
+			for _, subnet := range networkSubnets {
+				cidrs[target] = append(cidrs[target], subnet.CIDR)
+			}
+		}
+	}
+	return uncoveredIPv6Addresses(addresses, cidrs), nil
+}
+
+// getIPv6AddressesByTarget returns the IPv6 addresses the guest reported for its NICs, keyed by the
+// OpenStack network the network of the NIC is mapped to. Link-local addresses are left out, the
+// target derives them from the MAC address again.
+func getIPv6AddressesByTarget(vmMachine *vjailbreakv1alpha1.VMwareMachine, networkmap *vjailbreakv1alpha1.NetworkMapping) map[string][]string {
+	targets := map[string]string{}
+	for _, nwm := range networkmap.Spec.Networks {
+		if _, ok := targets[nwm.Source]; !ok {
+			targets[nwm.Source] = nwm.Target
+		}
+	}
+	macToTarget := map[string]string{}
+	for _, nic := range vmMachine.Spec.VMInfo.NetworkInterfaces {
+		if target, ok := targets[nic.Network]; ok {
+			macToTarget[strings.ToLower(nic.MAC)] = target
+		}
+	}
+	addresses := map[string][]string{}
+	for _, gn := range vmMachine.Spec.VMInfo.GuestNetworks {
+		ip := net.ParseIP(gn.IP)
+		if ip == nil || ip.To4() != nil || ip.IsLinkLocalUnicast() {
+			continue
+		}
+		if target, ok := macToTarget[strings.ToLower(gn.MAC)]; ok {
+			addresses[target] = append(addresses[target], gn.IP)
+		}
+	}
+	return addresses
+}
+
+// uncoveredIPv6Addresses describes the addresses that none of the IPv6 subnet cidrs of their
+// target network contains
+func uncoveredIPv6Addresses(addresses, cidrs map[string][]string) []string {
+	var problems []string
+	for target, ips := range addresses {
+		for _, ip := range ips {
+			if !containsIP(cidrs[target], net.ParseIP(ip)) {
+				problems = append(problems, fmt.Sprintf("network %s has no IPv6 subnet containing %s", target, ip))
+			}
+		}
+	}
+	sort.Strings(problems)
+	return problems
+}
+
+// containsIP returns true if one of cidrs contains ip
+func containsIP(cidrs []string, ip net.IP) bool {
+	for _, cidr := range cidrs {
+		if _, ipnet, err := net.ParseCIDR(cidr); err == nil && ipnet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+package utils
+
+import (
+	"testing"
+
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+	"github.com/platform9/vjailbreak/k8s/migration/pkg/sdk/testutils"
+)
+
+func TestGetIPv6AddressesByTarget(t *testing.T) {
+	networkmap := &vjailbreakv1alpha1.NetworkMapping{}
+	networkmap.Spec.Networks = []vjailbreakv1alpha1.Network{
+		{Source: "VM Network", Target: "provider-v6"},
+		{Source: "Backup", Target: "backup-v4"},
+	}
+	vmMachine := &vjailbreakv1alpha1.VMwareMachine{}
+	vmMachine.Spec.VMInfo.NetworkInterfaces = []vjailbreakv1alpha1.NIC{
+		{Network: "VM Network", MAC: "00:50:56:AA:00:01"},
+		{Network: "Backup", MAC: "00:50:56:aa:00:02"},
+		{Network: "Unmapped", MAC: "00:50:56:aa:00:03"},
+	}
+	vmMachine.Spec.VMInfo.GuestNetworks = []vjailbreakv1alpha1.GuestNetwork{
+		{MAC: "00:50:56:aa:00:01", IP: "10.0.0.5"},
+		{MAC: "00:50:56:aa:00:01", IP: "2001:db8:1::5", Origin: "manual"},
+		{MAC: "00:50:56:aa:00:01", IP: "2001:db8:1::250:56ff:feaa:1", Origin: "linklayer"},
+		{MAC: "00:50:56:aa:00:01", IP: "fe80::250:56ff:feaa:1", Origin: "linklayer"},
+		{MAC: "00:50:56:aa:00:02", IP: "2001:db8:2::5", Origin: "dhcp"},
+		{MAC: "00:50:56:aa:00:03", IP: "2001:db8:3::5", Origin: "manual"},
+	}
+	testutils.Equals(t, map[string][]string{
+		"provider-v6": {"2001:db8:1::5", "2001:db8:1::250:56ff:feaa:1"},
+		"backup-v4":   {"2001:db8:2::5"},
+	}, getIPv6AddressesByTarget(vmMachine, networkmap))
+}
+
+func TestUncoveredIPv6Addresses(t *testing.T) {
+	tests := []struct {
+		name      string
+		addresses map[string][]string
+		cidrs     map[string][]string
+		want      []string
+	}{
+		{
+			name:      "no IPv6 addresses",
+			addresses: map[string][]string{},
+			want:      nil,
+		},
+		{
+			name:      "subnet contains the address",
+			addresses: map[string][]string{"provider-v6": {"2001:db8:1::5"}},
+			cidrs:     map[string][]string{"provider-v6": {"2001:db8:2::/64", "2001:db8:1::/64"}},
+			want:      nil,
+		},
+		{
+			name:      "network without IPv6 subnet",
+			addresses: map[string][]string{"backup-v4": {"2001:db8:2::5"}},
+			want:      []string{"network backup-v4 has no IPv6 subnet containing 2001:db8:2::5"},
+		},
+		{
+			name:      "address outside the IPv6 subnets",
+			addresses: map[string][]string{"provider-v6": {"2001:db8:1::5", "2001:db8:9::5"}},
+			cidrs:     map[string][]string{"provider-v6": {"2001:db8:1::/64"}},
+			want:      []string{"network provider-v6 has no IPv6 subnet containing 2001:db8:9::5"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testutils.Equals(t, tt.want, uncoveredIPv6Addresses(tt.addresses, tt.cidrs))
+		})
+	}
+}
@@ -7,11 +7,15 @@
 package utils
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
+	"net/url"
 	"regexp"
+	"slices"
 	"strings"
+	"time"
 	"unicode"
 
 	"github.com/pkg/errors"
@@ -19,7 +23,9 @@ import (
 	corev1 "k8s.io/api/core/v1"
 
 	"github.com/platform9/vjailbreak/k8s/migration/pkg/constants"
+	"github.com/platform9/vjailbreak/v2v-helper/pkg/k8sutils"
 	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 // MigrationNameFromVMName generates a migration name from a VM name
@@ -32,6 +38,11 @@ func GetMigrationConfigMapName(vmname string) string {
 	return fmt.Sprintf("migration-config-%s", vmname)
 }
 
+// GetMigrationCheckpointConfigMapName generates the name of the config map holding disk replication checkpoints
+func GetMigrationCheckpointConfigMapName(vmname string) string {
+	return fmt.Sprintf("migration-checkpoint-%s", vmname)
+}
+
 // GetFirstbootConfigMapName generates a config map name for a migration
 func GetFirstbootConfigMapName(vmname string) string {
 	return fmt.Sprintf("firstboot-config-%s", vmname)
@@ -87,14 +98,103 @@ func ValidateMigrationPlan(migrationplan *vjailbreakv1alpha1.MigrationPlan) erro
 
 	// If granular options are set, then there should only be 1 VM in the migrationplan
 	// Periodic sync options are plan-level and can apply to multiple VMs
-	if hasGranularOptions &&
-		(len(migrationplan.Spec.VirtualMachines) != 1 || len(migrationplan.Spec.VirtualMachines[0]) != 1) {
+	vmGroups := GetMigrationPlanVMGroups(migrationplan)
+	if hasGranularOptions && (len(vmGroups) != 1 || len(vmGroups[0]) != 1) {
 		return fmt.Errorf(`granular options (volumes/networks/ports) can only be set for a single VM.
 			Please remove granular options or reduce the number of VMs in the migrationplan`)
 	}
 	return nil
 }
 
+// ValidateMaintenanceWindows checks that the data copy and cutover windows the migration plan
+// references exist and are valid
+func ValidateMaintenanceWindows(ctx context.Context, k3sclient client.Client, migrationplan *vjailbreakv1alpha1.MigrationPlan) error {
+	for _, name := range []string{migrationplan.Spec.MigrationStrategy.DataCopyWindow, migrationplan.Spec.MigrationStrategy.CutoverWindow} {
+		if name == "" {
+			continue
+		}
+		if _, err := k8sutils.GetMaintenanceCalendar(ctx, k3sclient, name); err != nil {
+			return errors.Wrapf(err, "invalid maintenance window %s", name)
+		}
+	}
+	return nil
+}
+
+// CheckMaintenanceWindows checks if the migrations of the plan can start. They wait for the data
+// copy window, and cold migrations also for the cutover window since they shut the source VM down
+// first. The returned message names the closed window, it is empty when the migrations can start.
+func CheckMaintenanceWindows(ctx context.Context, k3sclient client.Client, migrationplan *vjailbreakv1alpha1.MigrationPlan) (string, error) {
+	names := []string{migrationplan.Spec.MigrationStrategy.DataCopyWindow}
+	if migrationplan.Spec.MigrationStrategy.Type == "cold" {
+		names = append(names, migrationplan.Spec.MigrationStrategy.CutoverWindow)
+	}
+	now := time.Now()
+	for _, name := range names {
+		if name == "" {
+			continue
+		}
+		calendar, err := k8sutils.GetMaintenanceCalendar(ctx, k3sclient, name)
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to get maintenance window %s", name)
+		}
+		if open, _ := calendar.OpenAt(now); open {
+			continue
+		}
+		if next, ok := calendar.NextOpen(now); ok {
+			return fmt.Sprintf("Waiting for maintenance window %s, it opens at %s", name, next.Format(time.RFC3339)), nil
+		}
+		return fmt.Sprintf("Waiting for maintenance window %s, it does not open again", name), nil
+	}
+	return "", nil
+}
+
+// ValidateSnapshotConsistency checks that the snapshot consistency options of the migration plan
+// name its VMs and that guest scripts have credentials to run with
+func ValidateSnapshotConsistency(migrationplan *vjailbreakv1alpha1.MigrationPlan) error {
+	consistency := migrationplan.Spec.MigrationStrategy.SnapshotConsistency
+	if consistency == nil {
+		return nil
+	}
+	for _, vm := range consistency.VMs {
+		if !slices.ContainsFunc(GetMigrationPlanVMGroups(migrationplan), func(group []string) bool {
+			return slices.Contains(group, vm)
+		}) {
+			return fmt.Errorf("snapshot consistency of unknown VM %s", vm)
+		}
+	}
+	if (consistency.PreFreezeScript != nil || consistency.PostThawScript != nil) && consistency.GuestCredentialsSecretRef == "" {
+		return fmt.Errorf("guestCredentialsSecretRef is required to run pre-freeze and post-thaw scripts")
+	}
+	return nil
+}
+
+// GetSnapshotConsistency returns the snapshot consistency options of the migration plan that apply
+// to vm, nil when its final snapshot is crash consistent
+func GetSnapshotConsistency(migrationplan *vjailbreakv1alpha1.MigrationPlan, vm string) *vjailbreakv1alpha1.SnapshotConsistency {
+	consistency := migrationplan.Spec.MigrationStrategy.SnapshotConsistency
+	if consistency == nil || (len(consistency.VMs) > 0 && !slices.Contains(consistency.VMs, vm)) {
+		return nil
+	}
+	return consistency
+}
+
+// ValidateFileSource validates the file source of a MigrationTemplate
+func ValidateFileSource(source *vjailbreakv1alpha1.MigrationTemplateFileSource) error {
+	if (source.PVCName == "") == (source.URL == "") {
+		return fmt.Errorf("exactly one of pvcName and url must be set for a file source")
+	}
+	if source.URL != "" {
+		sourceURL, err := url.Parse(source.URL)
+		if err != nil {
+			return errors.Wrapf(err, "invalid file source url %s", source.URL)
+		}
+		if sourceURL.Scheme != "http" && sourceURL.Scheme != "https" {
+			return fmt.Errorf("file source url %s must use http or https", source.URL)
+		}
+	}
+	return nil
+}
+
 // GetJobNameForVMName generates a unique name for a job resource
 func GetJobNameForVMName(vmname string, credName string) (string, error) {
 	vmk8sname, err := GetK8sCompatibleVMWareObjectName(vmname, credName)
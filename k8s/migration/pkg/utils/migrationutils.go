@@ -99,6 +99,195 @@ func CreateDataCopyCondition(migration *vjailbreakv1alpha1.Migration, eventList
 	return existingConditions
 }
 
+// CreateDataVerifiedCondition creates a data verified condition for a migration. The condition
+// is true once the copied data matched the source snapshot and false if a mismatch persisted.
+func CreateDataVerifiedCondition(migration *vjailbreakv1alpha1.Migration, eventList *corev1.EventList) []corev1.PodCondition {
+	existingConditions := migration.Status.Conditions
+	for i := 0; i < len(eventList.Items); i++ {
+		if eventList.Items[i].Reason != constants.MigrationReason {
+			continue
+		}
+		var status corev1.ConditionStatus
+		switch {
+		case strings.HasPrefix(eventList.Items[i].Message, "Data integrity verified"):
+			status = corev1.ConditionTrue
+		case strings.HasPrefix(eventList.Items[i].Message, "Data integrity mismatch"):
+			status = corev1.ConditionFalse
+		default:
+			continue
+		}
+
+		idx := GetConditonIndex(existingConditions, constants.MigrationConditionTypeDataVerified, constants.MigrationReason)
+		statuscondition := GeneratePodCondition(constants.MigrationConditionTypeDataVerified,
+			status,
+			constants.MigrationReason,
+			eventList.Items[i].Message,
+			eventList.Items[i].LastTimestamp)
+
+		if idx == -1 {
+			existingConditions = append(existingConditions, *statuscondition)
+		} else {
+			existingConditions[idx] = *statuscondition
+		}
+		break
+	}
+	return existingConditions
+}
+
+// CreateTestBootCondition creates a test boot condition for a migration. The condition is unknown
+// while a test boot runs, true once the test server is active and false if it did not boot.
+func CreateTestBootCondition(migration *vjailbreakv1alpha1.Migration, eventList *corev1.EventList) []corev1.PodCondition {
+	existingConditions := migration.Status.Conditions
+	for i := 0; i < len(eventList.Items); i++ {
+		if eventList.Items[i].Reason != constants.MigrationReason {
+			continue
+		}
+		var status corev1.ConditionStatus
+		switch {
+		case strings.HasPrefix(eventList.Items[i].Message, "Test boot started"):
+			status = corev1.ConditionUnknown
+		case strings.HasPrefix(eventList.Items[i].Message, "Test boot succeeded"):
+			status = corev1.ConditionTrue
+		case strings.HasPrefix(eventList.Items[i].Message, "Test boot did not succeed"):
+			status = corev1.ConditionFalse
+		default:
+			continue
+		}
+
+		idx := GetConditonIndex(existingConditions, constants.MigrationConditionTypeTestBoot, constants.MigrationReason)
+		statuscondition := GeneratePodCondition(constants.MigrationConditionTypeTestBoot,
+			status,
+			constants.MigrationReason,
+			eventList.Items[i].Message,
+			eventList.Items[i].LastTimestamp)
+
+		if idx == -1 {
+			existingConditions = append(existingConditions, *statuscondition)
+		} else {
+			existingConditions[idx] = *statuscondition
+		}
+		break
+	}
+	return existingConditions
+}
+
+// CreateHealthCheckResults returns the health check results of a migration updated with the
+// latest result reported for each check. Results are reported in events of the form
+// "Health check <name> passed: <message>" and "Health check <name> did not pass: <message>".
+func CreateHealthCheckResults(migration *vjailbreakv1alpha1.Migration, eventList *corev1.EventList) []vjailbreakv1alpha1.HealthCheckResult {
+	results := migration.Status.HealthChecks
+	seen := map[string]bool{}
+	// Events are sorted latest first, the first event of a check is its latest result
+	for i := 0; i < len(eventList.Items); i++ {
+		if eventList.Items[i].Reason != constants.MigrationReason {
+			continue
+		}
+		rest, found := strings.CutPrefix(eventList.Items[i].Message, "Health check ")
+		if !found {
+			continue
+		}
+		name, outcome, found := strings.Cut(rest, " ")
+		if !found || seen[name] {
+			continue
+		}
+		var status string
+		switch {
+		case strings.HasPrefix(outcome, "passed"):
+			status = vjailbreakv1alpha1.HealthCheckStatusPassed
+		case strings.HasPrefix(outcome, "did not pass"):
+			status = vjailbreakv1alpha1.HealthCheckStatusFailed
+		default:
+			continue
+		}
+		seen[name] = true
+		_, message, _ := strings.Cut(outcome, ": ")
+		result := vjailbreakv1alpha1.HealthCheckResult{
+			Name:               name,
+			Status:             status,
+			Message:            message,
+			LastTransitionTime: eventList.Items[i].LastTimestamp,
+		}
+
+		idx := slices.IndexFunc(results, func(r vjailbreakv1alpha1.HealthCheckResult) bool { return r.Name == name })
+		if idx == -1 {
+			results = append(results, result)
+		} else {
+			results[idx] = result
+		}
+	}
+	return results
+}
+
+// GetSnapshotType returns the kind of snapshot the final sync of a migration copied from. It is
+// reported in events of the form "Final snapshot <type>" and "Final snapshot <type>: <reason>".
+func GetSnapshotType(migration *vjailbreakv1alpha1.Migration, eventList *corev1.EventList) string {
+	// Events are sorted latest first
+	for i := 0; i < len(eventList.Items); i++ {
+		if eventList.Items[i].Reason != constants.MigrationReason {
+			continue
+		}
+		rest, found := strings.CutPrefix(eventList.Items[i].Message, "Final snapshot ")
+		if !found {
+			continue
+		}
+		snapshotType, _, _ := strings.Cut(rest, ":")
+		return snapshotType
+	}
+	return migration.Status.SnapshotType
+}
+
+// CreateHookResults returns the hook results of a migration updated with the latest result
+// reported for each hook and stage. Results are reported in events of the form
+// "Hook <name> at <stage> succeeded: <message>" and "Hook <name> at <stage> did not succeed: <message>".
+func CreateHookResults(migration *vjailbreakv1alpha1.Migration, eventList *corev1.EventList) []vjailbreakv1alpha1.HookResult {
+	results := migration.Status.Hooks
+	seen := map[string]bool{}
+	// Events are sorted latest first, the first event of a hook is its latest result
+	for i := 0; i < len(eventList.Items); i++ {
+		if eventList.Items[i].Reason != constants.MigrationReason {
+			continue
+		}
+		rest, found := strings.CutPrefix(eventList.Items[i].Message, "Hook ")
+		if !found {
+			continue
+		}
+		name, rest, found := strings.Cut(rest, " at ")
+		if !found {
+			continue
+		}
+		stage, outcome, found := strings.Cut(rest, " ")
+		if !found || seen[name+"/"+stage] {
+			continue
+		}
+		var status string
+		switch {
+		case strings.HasPrefix(outcome, "succeeded"):
+			status = vjailbreakv1alpha1.HookStatusSucceeded
+		case strings.HasPrefix(outcome, "did not succeed"):
+			status = vjailbreakv1alpha1.HookStatusFailed
+		default:
+			continue
+		}
+		seen[name+"/"+stage] = true
+		_, message, _ := strings.Cut(outcome, ": ")
+		result := vjailbreakv1alpha1.HookResult{
+			Name:               name,
+			Stage:              stage,
+			Status:             status,
+			Message:            message,
+			LastTransitionTime: eventList.Items[i].LastTimestamp,
+		}
+
+		idx := slices.IndexFunc(results, func(r vjailbreakv1alpha1.HookResult) bool { return r.Name == name && r.Stage == stage })
+		if idx == -1 {
+			results = append(results, result)
+		} else {
+			results[idx] = result
+		}
+	}
+	return results
+}
+
 // CreateMigratingCondition creates a migrating condition for a migration
 func CreateMigratingCondition(migration *vjailbreakv1alpha1.Migration, eventList *corev1.EventList) []corev1.PodCondition {
 	existingConditions := migration.Status.Conditions
@@ -131,6 +320,11 @@ func CreateFailedCondition(migration *vjailbreakv1alpha1.Migration, eventList *c
 		if eventList.Items[i].Reason != constants.MigrationReason || !strings.Contains(eventList.Items[i].Message, "failed to") {
 			continue
 		}
+		// Test boots, health checks and hooks that did not pass are reported on their own
+		if strings.HasPrefix(eventList.Items[i].Message, "Test boot") || strings.HasPrefix(eventList.Items[i].Message, "Health check ") ||
+			strings.HasPrefix(eventList.Items[i].Message, "Hook ") {
+			continue
+		}
 
 		idx := GetConditonIndex(existingConditions, constants.MigrationConditionTypeFailed, constants.MigrationReason)
 		statuscondition := GeneratePodCondition(constants.MigrationConditionTypeFailed,
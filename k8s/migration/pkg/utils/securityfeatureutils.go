@@ -0,0 +1,90 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gophercloud/gophercloud/v2"
+	"github.com/gophercloud/gophercloud/v2/openstack"
+	"github.com/gophercloud/gophercloud/v2/openstack/compute/v2/flavors"
+	"github.com/pkg/errors"
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+	openstackpkg "github.com/platform9/vjailbreak/pkg/common/openstack"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// CheckSecurityFeatureSupport returns warnings for the VMs with Secure Boot or a vTPM that the
+// target cloud cannot boot with them. The flavor of a VM is resolved the way the migration resolves
+// it, and a vTPM needs the key manager service to store its secret.
+func CheckSecurityFeatureSupport(ctx context.Context, k3sclient client.Client, migrationtemplate *vjailbreakv1alpha1.MigrationTemplate,
+	vmMachines []*vjailbreakv1alpha1.VMwareMachine,
+) ([]string, error) {
+	secured := []*vjailbreakv1alpha1.VMwareMachine{}
+	needsKeyManager := false
+	for _, vmMachine := range vmMachines {
+		firmware := vmMachine.Spec.VMInfo.Firmware
+		if firmware.SecureBoot || firmware.VTPM {
+			secured = append(secured, vmMachine)
+		}
+		needsKeyManager = needsKeyManager || firmware.VTPM
+	}
+	if len(secured) == 0 {
+		return nil, nil
+	}
+
+	openstackcreds := &vjailbreakv1alpha1.OpenstackCreds{}
+	if err := k3sclient.Get(ctx, k8stypes.NamespacedName{Name: migrationtemplate.Spec.Destination.OpenstackRef, Namespace: migrationtemplate.Namespace}, openstackcreds); err != nil {
+		return nil, errors.Wrap(err, "failed to get openstack credentials")
+	}
+
+	var warnings []string
+	if !migrationtemplate.Spec.UseFlavorless {
+		allFlavors, err := ListAllFlavors(ctx, k3sclient, openstackcreds)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to list all flavors")
+		}
+		// UseGPUFlavor is only applicable for PCD credentials
+		useGPUFlavor := migrationtemplate.Spec.UseGPUFlavor && IsOpenstackPCD(*openstackcreds)
+		warnings, err = flavorSecurityFeatureWarnings(secured, allFlavors, useGPUFlavor)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if needsKeyManager {
+		openstackCredential, err := GetOpenstackCredentialsFromSecret(ctx, k3sclient, openstackcreds.Spec.SecretRef.Name)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get openstack credentials from secret")
+		}
+		openstackClients, err := GetOpenStackClients(ctx, k3sclient, openstackcreds)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get openstack clients")
+		}
+		endpoint := gophercloud.EndpointOpts{Region: openstackCredential.RegionName}
+		if _, err := openstack.NewKeyManagerV1(openstackClients.ComputeClient.ProviderClient, endpoint); err != nil {
+			warnings = append(warnings, "the cloud has no key manager service, Nova cannot create a vTPM without it")
+		}
+	}
+	return warnings, nil
+}
+
+// flavorSecurityFeatureWarnings returns the warnings for the flavors the VMs of vmMachines boot with,
+// prefixed with the name of the VM
+func flavorSecurityFeatureWarnings(vmMachines []*vjailbreakv1alpha1.VMwareMachine, allFlavors []flavors.Flavor, useGPUFlavor bool) ([]string, error) {
+	var warnings []string
+	for _, vmMachine := range vmMachines {
+		vminfo := vmMachine.Spec.VMInfo
+		flavor, err := getTargetFlavor(vmMachine, allFlavors, useGPUFlavor)
+		if err != nil {
+			return nil, err
+		}
+		if flavor == nil {
+			continue
+		}
+		for _, warning := range openstackpkg.GetSecurityFeatureWarnings(*flavor, vminfo.Firmware.SecureBoot, vminfo.Firmware.VTPM) {
+			warnings = append(warnings, fmt.Sprintf("VM %s: %s", vminfo.Name, warning))
+		}
+	}
+	return warnings, nil
+}
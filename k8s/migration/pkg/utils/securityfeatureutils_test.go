@@ -0,0 +1,37 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/gophercloud/gophercloud/v2/openstack/compute/v2/flavors"
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+	"github.com/platform9/vjailbreak/k8s/migration/pkg/sdk/testutils"
+)
+
+func TestFlavorSecurityFeatureWarnings(t *testing.T) {
+	allFlavors := []flavors.Flavor{
+		{ID: "1", Name: "m1.small", VCPUs: 2, RAM: 2048, ExtraSpecs: map[string]string{"hw:machine_type": "pc"}},
+		{ID: "2", Name: "m1.secure", VCPUs: 2, RAM: 2048, ExtraSpecs: map[string]string{"hw:machine_type": "q35", "hw:tpm_model": "tpm-tis"}},
+	}
+	newVM := func(name, flavorID string, secureBoot, vtpm bool) *vjailbreakv1alpha1.VMwareMachine {
+		vmMachine := &vjailbreakv1alpha1.VMwareMachine{}
+		vmMachine.Spec.TargetFlavorID = flavorID
+		vmMachine.Spec.VMInfo.Name = name
+		vmMachine.Spec.VMInfo.CPU = 2
+		vmMachine.Spec.VMInfo.Memory = 2048
+		vmMachine.Spec.VMInfo.Firmware = vjailbreakv1alpha1.FirmwareInfo{UEFI: true, SecureBoot: secureBoot, VTPM: vtpm}
+		return vmMachine
+	}
+
+	warnings, err := flavorSecurityFeatureWarnings([]*vjailbreakv1alpha1.VMwareMachine{
+		newVM("vm-1", "1", true, false),
+		newVM("vm-2", "2", false, true),
+		newVM("vm-3", "2", true, false),
+		newVM("vm-4", "missing", true, true),
+	}, allFlavors, false)
+	testutils.Ok(t, err)
+	testutils.Equals(t, []string{
+		"VM vm-1: flavor m1.small sets hw:machine_type=pc, Secure Boot and vTPM need q35",
+		"VM vm-2: flavor m1.secure sets hw:tpm_model=tpm-tis, the VM has a CRB TPM",
+	}, warnings)
+}
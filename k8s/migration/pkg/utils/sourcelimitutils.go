@@ -0,0 +1,203 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+	"github.com/platform9/vjailbreak/k8s/migration/pkg/constants"
+	"github.com/platform9/vjailbreak/v2v-helper/pkg/k8sutils"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// GetMigrationSource returns the source of the migration of vmMachine from the vCenter of
+// vmwarecreds. VMs without a VMwareMachine only count against the vCenter.
+func GetMigrationSource(vmwarecreds string, vmMachine *vjailbreakv1alpha1.VMwareMachine) *vjailbreakv1alpha1.MigrationSource {
+	source := &vjailbreakv1alpha1.MigrationSource{VMwareCreds: vmwarecreds}
+	if vmMachine == nil {
+		return source
+	}
+	source.ESXiHost = vmMachine.Spec.VMInfo.ESXiName
+	source.Datastores = append(source.Datastores, vmMachine.Spec.VMInfo.Datastores...)
+	for _, disk := range vmMachine.Spec.VMInfo.Disks {
+		if disk.Datastore != "" && !slices.Contains(source.Datastores, disk.Datastore) {
+			source.Datastores = append(source.Datastores, disk.Datastore)
+		}
+	}
+	return source
+}
+
+// sourceLimits are the migration limits per vCenter, ESXi host and datastore of the vjailbreak settings
+type sourceLimits struct {
+	vcenters   map[string]int
+	hosts      map[string]int
+	datastores map[string]int
+}
+
+// parseSourceLimits parses the migration limits of the vjailbreak settings
+func parseSourceLimits(settings *k8sutils.VjailbreakSettings) sourceLimits {
+	return sourceLimits{
+		vcenters:   k8sutils.ParseMigrationLimits(settings.VCenterMigrationLimits),
+		hosts:      k8sutils.ParseMigrationLimits(settings.ESXiHostMigrationLimits),
+		datastores: k8sutils.ParseMigrationLimits(settings.DatastoreMigrationLimits),
+	}
+}
+
+// empty checks if no source has a migration limit
+func (limits sourceLimits) empty() bool {
+	return len(limits.vcenters) == 0 && len(limits.hosts) == 0 && len(limits.datastores) == 0
+}
+
+// sharedLimit returns the first source with a migration limit that both migrations copy from,
+// empty when they share none
+func (limits sourceLimits) sharedLimit(a, b *vjailbreakv1alpha1.MigrationSource) string {
+	if a.VMwareCreds == b.VMwareCreds && k8sutils.MigrationLimitFor(limits.vcenters, a.VMwareCreds) > 0 {
+		return fmt.Sprintf("vCenter %s", a.VMwareCreds)
+	}
+	if a.ESXiHost != "" && a.ESXiHost == b.ESXiHost && k8sutils.MigrationLimitFor(limits.hosts, a.ESXiHost) > 0 {
+		return fmt.Sprintf("ESXi host %s", a.ESXiHost)
+	}
+	for _, datastore := range a.Datastores {
+		if slices.Contains(b.Datastores, datastore) && k8sutils.MigrationLimitFor(limits.datastores, datastore) > 0 {
+			return fmt.Sprintf("datastore %s", datastore)
+		}
+	}
+	return ""
+}
+
+// CheckSourceMigrationLimits checks if a migration from source stays within the migration limits
+// per vCenter, ESXi host and datastore of the vjailbreak settings. Scheduled migrations of all
+// plans hold a slot of their source until they finish. Migrations waiting for a slot are admitted
+// oldest first, a migration also waits while an older migration waits for a source it copies
+// from. The returned message names the limit that is reached, it is empty when the migration can
+// be scheduled.
+func CheckSourceMigrationLimits(migrations []vjailbreakv1alpha1.Migration, migration *vjailbreakv1alpha1.Migration,
+	source *vjailbreakv1alpha1.MigrationSource, settings *k8sutils.VjailbreakSettings, pausedPlans map[string]bool,
+) string {
+	limits := parseSourceLimits(settings)
+	if limits.empty() {
+		return ""
+	}
+
+	vcenters, hosts, datastores := map[string]int{}, map[string]int{}, map[string]int{}
+	for i := range migrations {
+		other := &migrations[i]
+		if other.Namespace == migration.Namespace && other.Name == migration.Name {
+			continue
+		}
+		if other.Status.AgentName == "" || other.Status.Source == nil || slices.Contains(migrationFinishedPhases, other.Status.Phase) {
+			continue
+		}
+		vcenters[other.Status.Source.VMwareCreds]++
+		hosts[other.Status.Source.ESXiHost]++
+		for _, datastore := range other.Status.Source.Datastores {
+			datastores[datastore]++
+		}
+	}
+
+	if limit := k8sutils.MigrationLimitFor(limits.vcenters, source.VMwareCreds); limit > 0 && vcenters[source.VMwareCreds] >= limit {
+		return fmt.Sprintf("Waiting for a free slot of vCenter %s, %d of %d migrations running", source.VMwareCreds, vcenters[source.VMwareCreds], limit)
+	}
+	if source.ESXiHost != "" {
+		if limit := k8sutils.MigrationLimitFor(limits.hosts, source.ESXiHost); limit > 0 && hosts[source.ESXiHost] >= limit {
+			return fmt.Sprintf("Waiting for a free slot of ESXi host %s, %d of %d migrations running", source.ESXiHost, hosts[source.ESXiHost], limit)
+		}
+	}
+	for _, datastore := range source.Datastores {
+		if limit := k8sutils.MigrationLimitFor(limits.datastores, datastore); limit > 0 && datastores[datastore] >= limit {
+			return fmt.Sprintf("Waiting for a free slot of datastore %s, %d of %d migrations running", datastore, datastores[datastore], limit)
+		}
+	}
+	for _, other := range migrationsAhead(migrations, migration, constants.MigrationSourceLimitReason, pausedPlans) {
+		if other.Status.Source == nil {
+			continue
+		}
+		if shared := limits.sharedLimit(source, other.Status.Source); shared != "" {
+			return fmt.Sprintf("Waiting behind migration %s for a free slot of %s", other.Name, shared)
+		}
+	}
+	return ""
+}
+
+// GetMigrationQueuePosition returns the position of the migration in the queue it waits in, 0 when
+// it does not wait in a queue. Migrations waiting for an agent form one queue across all plans,
+// migrations waiting for a slot of their source queue behind the older migrations waiting for one
+// of the limited sources they copy from. Both queues are oldest first, the migration at position 1
+// is admitted next.
+func GetMigrationQueuePosition(migrations []vjailbreakv1alpha1.Migration, migration *vjailbreakv1alpha1.Migration,
+	reason string, source *vjailbreakv1alpha1.MigrationSource, settings *k8sutils.VjailbreakSettings, pausedPlans map[string]bool,
+) int {
+	switch reason {
+	case constants.MigrationQueuedReason:
+		return len(migrationsAhead(migrations, migration, reason, pausedPlans)) + 1
+	case constants.MigrationSourceLimitReason:
+		limits := parseSourceLimits(settings)
+		position := 1
+		for _, other := range migrationsAhead(migrations, migration, reason, pausedPlans) {
+			if other.Status.Source != nil && limits.sharedLimit(source, other.Status.Source) != "" {
+				position++
+			}
+		}
+		return position
+	}
+	return 0
+}
+
+// migrationsAhead returns the migrations of all plans that wait for reason and were created before
+// the migration, oldest first. Migrations of paused plans keep their place but are not waited for.
+func migrationsAhead(migrations []vjailbreakv1alpha1.Migration, migration *vjailbreakv1alpha1.Migration,
+	reason string, pausedPlans map[string]bool,
+) []*vjailbreakv1alpha1.Migration {
+	ahead := []*vjailbreakv1alpha1.Migration{}
+	for i := range migrations {
+		other := &migrations[i]
+		if other.Namespace == migration.Namespace && other.Name == migration.Name {
+			continue
+		}
+		if pausedPlans[other.Spec.MigrationPlan] || !isMigrationWaiting(other, reason) || !queuedBefore(other, migration) {
+			continue
+		}
+		ahead = append(ahead, other)
+	}
+	sort.Slice(ahead, func(i, j int) bool {
+		return queuedBefore(ahead[i], ahead[j])
+	})
+	return ahead
+}
+
+// queuedBefore checks if migration a is ahead of migration b in a queue
+func queuedBefore(a, b *vjailbreakv1alpha1.Migration) bool {
+	if !a.CreationTimestamp.Equal(&b.CreationTimestamp) {
+		return a.CreationTimestamp.Before(&b.CreationTimestamp)
+	}
+	return a.Name < b.Name
+}
+
+// GetPausedMigrationPlans returns the names of the paused plans of the migrations waiting to be scheduled
+func GetPausedMigrationPlans(ctx context.Context, k3sclient client.Client, migrations []vjailbreakv1alpha1.Migration) map[string]bool {
+	paused := map[string]bool{}
+	checked := map[string]bool{}
+	for i := range migrations {
+		migration := &migrations[i]
+		plan := migration.Spec.MigrationPlan
+		if checked[plan] || !isMigrationWaiting(migration, constants.MigrationQueuedReason, constants.MigrationSourceLimitReason) {
+			continue
+		}
+		checked[plan] = true
+		if IsMigrationPlanPaused(ctx, plan, k3sclient) {
+			paused[plan] = true
+		}
+	}
+	return paused
+}
+
+// isMigrationWaiting checks if an unfinished migration without an agent waits to be scheduled for
+// one of reasons
+func isMigrationWaiting(migration *vjailbreakv1alpha1.Migration, reasons ...string) bool {
+	if migration.Status.AgentName != "" || slices.Contains(migrationFinishedPhases, migration.Status.Phase) {
+		return false
+	}
+	return GetConditonIndex(migration.Status.Conditions, constants.MigrationConditionTypeScheduled, reasons...) != -1
+}
@@ -0,0 +1,217 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+	"github.com/platform9/vjailbreak/k8s/migration/pkg/constants"
+	"github.com/platform9/vjailbreak/k8s/migration/pkg/sdk/testutils"
+	"github.com/platform9/vjailbreak/v2v-helper/pkg/k8sutils"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var testQueueStart = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// newRunningMigration returns a migration placed on an agent that copies from source
+func newRunningMigration(name string, source *vjailbreakv1alpha1.MigrationSource) vjailbreakv1alpha1.Migration {
+	migration := newTestMigration(name, "agent", vjailbreakv1alpha1.VMMigrationPhaseCopying)
+	migration.Status.Source = source
+	return migration
+}
+
+// newWaitingMigration returns a migration of plan created minute minutes into the test queue that
+// waits to be scheduled for reason
+func newWaitingMigration(name, plan string, minute int, reason string, source *vjailbreakv1alpha1.MigrationSource) vjailbreakv1alpha1.Migration {
+	migration := newTestMigration(name, "", vjailbreakv1alpha1.VMMigrationPhasePending)
+	migration.CreationTimestamp = metav1.NewTime(testQueueStart.Add(time.Duration(minute) * time.Minute))
+	migration.Spec.MigrationPlan = plan
+	migration.Status.Source = source
+	migration.Status.Conditions = []corev1.PodCondition{{
+		Type:   constants.MigrationConditionTypeScheduled,
+		Status: corev1.ConditionFalse,
+		Reason: reason,
+	}}
+	return migration
+}
+
+func TestCheckSourceMigrationLimits(t *testing.T) {
+	settings := &k8sutils.VjailbreakSettings{
+		VCenterMigrationLimits:   "vcenter-a=2",
+		ESXiHostMigrationLimits:  "esx-01=1",
+		DatastoreMigrationLimits: "ds1=1,*=3",
+	}
+	hostSource := &vjailbreakv1alpha1.MigrationSource{VMwareCreds: "vcenter-b", ESXiHost: "esx-01"}
+	tests := []struct {
+		name       string
+		settings   *k8sutils.VjailbreakSettings
+		source     *vjailbreakv1alpha1.MigrationSource
+		migrations []vjailbreakv1alpha1.Migration
+		paused     map[string]bool
+		want       string
+	}{
+		{
+			name:     "no limits",
+			settings: &k8sutils.VjailbreakSettings{},
+			source:   hostSource,
+			migrations: []vjailbreakv1alpha1.Migration{
+				newRunningMigration("vm-1", hostSource),
+			},
+			want: "",
+		},
+		{
+			name:   "free slots",
+			source: &vjailbreakv1alpha1.MigrationSource{VMwareCreds: "vcenter-a", Datastores: []string{"ds2"}},
+			migrations: []vjailbreakv1alpha1.Migration{
+				newRunningMigration("vm-1", &vjailbreakv1alpha1.MigrationSource{VMwareCreds: "vcenter-a", Datastores: []string{"ds2"}}),
+			},
+			want: "",
+		},
+		{
+			name:   "vCenter limit",
+			source: &vjailbreakv1alpha1.MigrationSource{VMwareCreds: "vcenter-a"},
+			migrations: []vjailbreakv1alpha1.Migration{
+				newRunningMigration("vm-1", &vjailbreakv1alpha1.MigrationSource{VMwareCreds: "vcenter-a"}),
+				newRunningMigration("vm-2", &vjailbreakv1alpha1.MigrationSource{VMwareCreds: "vcenter-a"}),
+			},
+			want: "Waiting for a free slot of vCenter vcenter-a, 2 of 2 migrations running",
+		},
+		{
+			name:   "ESXi host limit",
+			source: hostSource,
+			migrations: []vjailbreakv1alpha1.Migration{
+				newRunningMigration("vm-1", hostSource),
+			},
+			want: "Waiting for a free slot of ESXi host esx-01, 1 of 1 migrations running",
+		},
+		{
+			name:   "default datastore limit",
+			source: &vjailbreakv1alpha1.MigrationSource{VMwareCreds: "vcenter-b", Datastores: []string{"ds1", "ds2"}},
+			migrations: []vjailbreakv1alpha1.Migration{
+				newRunningMigration("vm-1", &vjailbreakv1alpha1.MigrationSource{VMwareCreds: "vcenter-b", Datastores: []string{"ds2"}}),
+				newRunningMigration("vm-2", &vjailbreakv1alpha1.MigrationSource{VMwareCreds: "vcenter-b", Datastores: []string{"ds2"}}),
+				newRunningMigration("vm-3", &vjailbreakv1alpha1.MigrationSource{VMwareCreds: "vcenter-b", Datastores: []string{"ds2"}}),
+			},
+			want: "Waiting for a free slot of datastore ds2, 3 of 3 migrations running",
+		},
+		{
+			name:   "finished and unplaced migrations hold no slot",
+			source: hostSource,
+			migrations: func() []vjailbreakv1alpha1.Migration {
+				finished := newRunningMigration("vm-1", hostSource)
+				finished.Status.Phase = vjailbreakv1alpha1.VMMigrationPhaseSucceeded
+				return []vjailbreakv1alpha1.Migration{
+					finished,
+					newWaitingMigration("vm-2", "plan-a", 20, constants.MigrationMaintenanceWindowReason, hostSource),
+				}
+			}(),
+			want: "",
+		},
+		{
+			name:   "older migration waits for the same source",
+			source: hostSource,
+			migrations: []vjailbreakv1alpha1.Migration{
+				newWaitingMigration("vm-1", "plan-a", 5, constants.MigrationSourceLimitReason, hostSource),
+			},
+			want: "Waiting behind migration vm-1 for a free slot of ESXi host esx-01",
+		},
+		{
+			name:   "older migration waits for another source",
+			source: hostSource,
+			migrations: []vjailbreakv1alpha1.Migration{
+				newWaitingMigration("vm-1", "plan-a", 5, constants.MigrationSourceLimitReason,
+					&vjailbreakv1alpha1.MigrationSource{VMwareCreds: "vcenter-b", ESXiHost: "esx-02"}),
+			},
+			want: "",
+		},
+		{
+			name:   "older migration of a paused plan",
+			source: hostSource,
+			migrations: []vjailbreakv1alpha1.Migration{
+				newWaitingMigration("vm-1", "plan-a", 5, constants.MigrationSourceLimitReason, hostSource),
+			},
+			paused: map[string]bool{"plan-a": true},
+			want:   "",
+		},
+		{
+			name:   "newer migration waits for the same source",
+			source: hostSource,
+			migrations: []vjailbreakv1alpha1.Migration{
+				newWaitingMigration("vm-1", "plan-a", 15, constants.MigrationSourceLimitReason, hostSource),
+			},
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			migration := newWaitingMigration("vm-self", "plan-b", 10, constants.MigrationSourceLimitReason, tt.source)
+			migrations := append([]vjailbreakv1alpha1.Migration{migration}, tt.migrations...)
+			if tt.settings == nil {
+				tt.settings = settings
+			}
+			testutils.Equals(t, tt.want, CheckSourceMigrationLimits(migrations, &migration, tt.source, tt.settings, tt.paused))
+		})
+	}
+}
+
+func TestGetMigrationQueuePosition(t *testing.T) {
+	settings := &k8sutils.VjailbreakSettings{ESXiHostMigrationLimits: "esx-01=1"}
+	source := &vjailbreakv1alpha1.MigrationSource{VMwareCreds: "vcenter-a", ESXiHost: "esx-01"}
+	otherSource := &vjailbreakv1alpha1.MigrationSource{VMwareCreds: "vcenter-a", ESXiHost: "esx-02"}
+	migration := newWaitingMigration("vm-self", "plan-b", 10, constants.MigrationSourceLimitReason, source)
+	migrations := []vjailbreakv1alpha1.Migration{
+		migration,
+		newWaitingMigration("vm-1", "plan-a", 1, constants.MigrationSourceLimitReason, source),
+		newWaitingMigration("vm-2", "plan-a", 2, constants.MigrationSourceLimitReason, otherSource),
+		newWaitingMigration("vm-3", "plan-c", 3, constants.MigrationSourceLimitReason, source),
+		newWaitingMigration("vm-4", "plan-a", 4, constants.MigrationQueuedReason, source),
+		newWaitingMigration("vm-5", "plan-a", 5, constants.MigrationQueuedReason, otherSource),
+		newWaitingMigration("vm-6", "plan-a", 20, constants.MigrationSourceLimitReason, source),
+		newRunningMigration("vm-7", source),
+	}
+	// vm-1 and vm-3 wait for the same ESXi host
+	testutils.Equals(t, 3, GetMigrationQueuePosition(migrations, &migration, constants.MigrationSourceLimitReason, source, settings, nil))
+	testutils.Equals(t, 2, GetMigrationQueuePosition(migrations, &migration, constants.MigrationSourceLimitReason, source, settings,
+		map[string]bool{"plan-c": true}))
+	// vm-4 and vm-5 wait for an agent
+	testutils.Equals(t, 3, GetMigrationQueuePosition(migrations, &migration, constants.MigrationQueuedReason, source, settings, nil))
+	testutils.Equals(t, 0, GetMigrationQueuePosition(migrations, &migration, constants.MigrationMaintenanceWindowReason, source, settings, nil))
+}
+
+func TestMigrationsAheadOrder(t *testing.T) {
+	migration := newWaitingMigration("vm-b", "plan-a", 10, constants.MigrationQueuedReason, nil)
+	migrations := []vjailbreakv1alpha1.Migration{
+		newWaitingMigration("vm-c", "plan-a", 5, constants.MigrationQueuedReason, nil),
+		migration,
+		newWaitingMigration("vm-a", "plan-a", 10, constants.MigrationQueuedReason, nil),
+		newWaitingMigration("vm-d", "plan-a", 1, constants.MigrationQueuedReason, nil),
+		newWaitingMigration("vm-e", "plan-a", 10, constants.MigrationQueuedReason, nil),
+	}
+	names := []string{}
+	for _, other := range migrationsAhead(migrations, &migration, constants.MigrationQueuedReason, nil) {
+		names = append(names, other.Name)
+	}
+	// Migrations created at the same time queue by name
+	testutils.Equals(t, []string{"vm-d", "vm-c", "vm-a"}, names)
+}
+
+func TestFreeAgentSlots(t *testing.T) {
+	loads := map[string]*agentLoad{
+		"a": {name: "a", active: 1, limit: 3},
+		"b": {name: "b", active: 4, limit: 2},
+		"c": {name: "c", active: 0, limit: 1},
+	}
+	testutils.Equals(t, 3, freeAgentSlots(loads))
+}
+
+func TestGetMigrationSource(t *testing.T) {
+	testutils.Equals(t, &vjailbreakv1alpha1.MigrationSource{VMwareCreds: "vcenter-a"}, GetMigrationSource("vcenter-a", nil))
+
+	vmMachine := &vjailbreakv1alpha1.VMwareMachine{}
+	vmMachine.Spec.VMInfo.ESXiName = "esx-01"
+	vmMachine.Spec.VMInfo.Datastores = []string{"ds1"}
+	vmMachine.Spec.VMInfo.Disks = []vjailbreakv1alpha1.Disk{{Datastore: "ds1"}, {Datastore: "ds2"}, {}}
+	testutils.Equals(t, &vjailbreakv1alpha1.MigrationSource{VMwareCreds: "vcenter-a", ESXiHost: "esx-01", Datastores: []string{"ds1", "ds2"}},
+		GetMigrationSource("vcenter-a", vmMachine))
+}
@@ -186,9 +186,13 @@ func CreateOpenstackVMForWorkerNode(ctx context.Context, k3sclient client.Client
 	if err != nil {
 		return "", errors.Wrap(err, "failed to update vjailbreak node status")
 	}
-	imageID, err := GetImageID(ctx, k3sclient)
-	if err != nil {
-		return "", errors.Wrap(err, "failed to get image id")
+	// Use the image of the spec if provided, otherwise the image of the master node
+	imageID := vjNode.Spec.OpenstackImageID
+	if imageID == "" {
+		imageID, err = GetImageID(ctx, k3sclient)
+		if err != nil {
+			return "", errors.Wrap(err, "failed to get image id")
+		}
 	}
 
 	token, err := os.ReadFile(constants.K3sTokenFileLocation)
@@ -784,6 +788,7 @@ func GetActiveMigrations(ctx context.Context, nodeName string, k3sclient client.
 	ignorePhases := []vjailbreakv1alpha1.VMMigrationPhase{vjailbreakv1alpha1.VMMigrationPhasePending,
 		vjailbreakv1alpha1.VMMigrationPhaseFailed,
 		vjailbreakv1alpha1.VMMigrationPhaseSucceeded,
+		vjailbreakv1alpha1.VMMigrationPhaseRolledBack,
 		vjailbreakv1alpha1.VMMigrationPhaseUnknown,
 	}
 
@@ -798,6 +803,25 @@ func GetActiveMigrations(ctx context.Context, nodeName string, k3sclient client.
 	return activeMigrations, nil
 }
 
+// GetMigrationLoad returns the bytes the unfinished migrations on a node have left to copy and
+// their combined copy rate
+func GetMigrationLoad(ctx context.Context, nodeName string, k3sclient client.Client) (int64, int64, error) {
+	migrationList := &vjailbreakv1alpha1.MigrationList{}
+	if err := k3sclient.List(ctx, migrationList); err != nil {
+		return 0, 0, errors.Wrap(err, "failed to list migrations")
+	}
+
+	var bytesRemaining, throughput int64
+	for i := range migrationList.Items {
+		migration := &migrationList.Items[i]
+		if migration.Status.AgentName == nodeName && !slices.Contains(migrationFinishedPhases, migration.Status.Phase) {
+			bytesRemaining += migration.Status.BytesRemaining
+			throughput += migration.Status.ThroughputBytesPerSecond
+		}
+	}
+	return bytesRemaining, throughput, nil
+}
+
 // GetInclusterClient creates and returns a Kubernetes in-cluster client
 func GetInclusterClient() (client.Client, error) {
 	// Create a direct Kubernetes client
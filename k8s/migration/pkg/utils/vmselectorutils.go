@@ -0,0 +1,171 @@
+package utils
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/pkg/errors"
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+)
+
+// vmSelectorMatcher is a VM selector with its name patterns compiled
+type vmSelectorMatcher struct {
+	selector           *vjailbreakv1alpha1.VMSelector
+	namePattern        *regexp.Regexp
+	excludeNamePattern *regexp.Regexp
+}
+
+// newVMSelectorMatcher validates a VM selector and compiles its name patterns
+func newVMSelectorMatcher(selector *vjailbreakv1alpha1.VMSelector) (*vmSelectorMatcher, error) {
+	matcher := &vmSelectorMatcher{selector: selector}
+	tags := selector.Tags
+	if selector.NamePattern != "" {
+		pattern, err := regexp.Compile(selector.NamePattern)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid namePattern")
+		}
+		matcher.namePattern = pattern
+	}
+	if exclude := selector.Exclude; exclude != nil {
+		tags = append(slices.Clone(tags), exclude.Tags...)
+		if exclude.NamePattern != "" {
+			pattern, err := regexp.Compile(exclude.NamePattern)
+			if err != nil {
+				return nil, errors.Wrap(err, "invalid exclude namePattern")
+			}
+			matcher.excludeNamePattern = pattern
+		}
+	}
+	for _, tag := range tags {
+		if category, name, ok := strings.Cut(tag, ":"); !ok || category == "" || name == "" {
+			return nil, fmt.Errorf("tag %s is not category:tag", tag)
+		}
+	}
+	return matcher, nil
+}
+
+// matches returns true if the VM matches every criterion of the selector and no exclusion
+func (matcher *vmSelectorMatcher) matches(vminfo *vjailbreakv1alpha1.VMInfo) bool {
+	selector := matcher.selector
+	if len(selector.Tags) > 0 && !matchesAnyTag(selector.Tags, vminfo.Tags) {
+		return false
+	}
+	if len(selector.Folders) > 0 && !matchesAnyFolder(selector.Folders, vminfo.Folder) {
+		return false
+	}
+	if len(selector.ResourcePools) > 0 && !slices.Contains(selector.ResourcePools, vminfo.ResourcePool) {
+		return false
+	}
+	if len(selector.Clusters) > 0 && !slices.Contains(selector.Clusters, vminfo.ClusterName) {
+		return false
+	}
+	if matcher.namePattern != nil && !matcher.namePattern.MatchString(vminfo.Name) {
+		return false
+	}
+
+	exclude := selector.Exclude
+	if exclude == nil {
+		return true
+	}
+	return !matchesAnyTag(exclude.Tags, vminfo.Tags) &&
+		!matchesAnyFolder(exclude.Folders, vminfo.Folder) &&
+		!slices.Contains(exclude.ResourcePools, vminfo.ResourcePool) &&
+		!slices.Contains(exclude.Clusters, vminfo.ClusterName) &&
+		!slices.Contains(exclude.Names, vminfo.Name) &&
+		(matcher.excludeNamePattern == nil || !matcher.excludeNamePattern.MatchString(vminfo.Name))
+}
+
+// matchesAnyTag returns true if one of the tags of a VM is one of the selected tags. A selected
+// category:* is any tag of the category.
+func matchesAnyTag(selected, vmTags []string) bool {
+	for _, tag := range selected {
+		category, name, _ := strings.Cut(tag, ":")
+		for _, vmTag := range vmTags {
+			if vmTag == tag || (name == "*" && strings.HasPrefix(vmTag, category+":")) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchesAnyFolder returns true if the folder of a VM is one of the selected folders or one of
+// their subfolders
+func matchesAnyFolder(selected []string, folder string) bool {
+	if folder == "" {
+		return false
+	}
+	for _, selectedFolder := range selected {
+		selectedFolder = strings.TrimSuffix(selectedFolder, "/")
+		if folder == selectedFolder || strings.HasPrefix(folder, selectedFolder+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateVMSelector checks the VM selector of the plan, and that the plan has VMs once its
+// selection is frozen
+func ValidateVMSelector(migrationplan *vjailbreakv1alpha1.MigrationPlan) error {
+	if migrationplan.Spec.VMSelector == nil {
+		return nil
+	}
+	if _, err := newVMSelectorMatcher(migrationplan.Spec.VMSelector); err != nil {
+		return errors.Wrap(err, "invalid vmSelector")
+	}
+	if migrationplan.Status.SelectionFrozenTime == nil {
+		return nil
+	}
+	for _, group := range GetMigrationPlanVMGroups(migrationplan) {
+		if len(group) > 0 {
+			return nil
+		}
+	}
+	return errors.New("vmSelector matches no VMs")
+}
+
+// ResolveVMSelector returns the names of the VMs the selector of the plan selects, in name order.
+// The VMs listed in virtualMachines and the VMs that were migrated already are left out.
+func ResolveVMSelector(migrationplan *vjailbreakv1alpha1.MigrationPlan, vmMachines []vjailbreakv1alpha1.VMwareMachine) ([]string, error) {
+	matcher, err := newVMSelectorMatcher(migrationplan.Spec.VMSelector)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid vmSelector")
+	}
+	listed := map[string]bool{}
+	for _, group := range migrationplan.Spec.VirtualMachines {
+		for _, vm := range group {
+			listed[vm] = true
+		}
+	}
+	selected := []string{}
+	for i := range vmMachines {
+		vmMachine := &vmMachines[i]
+		name := vmMachine.Spec.VMInfo.Name
+		if listed[name] || vmMachine.Status.Migrated || !matcher.matches(&vmMachine.Spec.VMInfo) {
+			continue
+		}
+		listed[name] = true
+		selected = append(selected, name)
+	}
+	slices.Sort(selected)
+	return selected, nil
+}
+
+// VMSelectionPending returns true while an empty selection must not be frozen, because the
+// inventory of the VMware credentials has not been discovered in full yet and VMs that match the
+// selector may still appear
+func VMSelectionPending(selected []string, vmwcreds *vjailbreakv1alpha1.VMwareCreds) bool {
+	return len(selected) == 0 && vmwcreds.Status.InventorySyncedTime == nil
+}
+
+// GetMigrationPlanVMGroups returns the groups of VMs of the plan, the groups of virtualMachines
+// followed by the VMs its selector resolved to, which are migrated in parallel
+func GetMigrationPlanVMGroups(migrationplan *vjailbreakv1alpha1.MigrationPlan) [][]string {
+	if len(migrationplan.Status.SelectedVMs) == 0 {
+		return migrationplan.Spec.VirtualMachines
+	}
+	groups := slices.Clone(migrationplan.Spec.VirtualMachines)
+	return append(groups, migrationplan.Status.SelectedVMs)
+}
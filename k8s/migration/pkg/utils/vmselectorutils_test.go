@@ -0,0 +1,215 @@
+package utils
+
+import (
+	"testing"
+
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+	"github.com/platform9/vjailbreak/k8s/migration/pkg/sdk/testutils"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newTestVMwareMachine(vminfo vjailbreakv1alpha1.VMInfo, migrated bool) vjailbreakv1alpha1.VMwareMachine {
+	return vjailbreakv1alpha1.VMwareMachine{
+		Spec:   vjailbreakv1alpha1.VMwareMachineSpec{VMInfo: vminfo},
+		Status: vjailbreakv1alpha1.VMwareMachineStatus{Migrated: migrated},
+	}
+}
+
+func TestVMSelectorMatches(t *testing.T) {
+	vminfo := vjailbreakv1alpha1.VMInfo{
+		Name:         "web-01",
+		Tags:         []string{"env:prod", "tier:web"},
+		Folder:       "/DC1/vm/Prod/Web",
+		ResourcePool: "Gold",
+		ClusterName:  "cluster-a",
+	}
+	tests := []struct {
+		name     string
+		selector vjailbreakv1alpha1.VMSelector
+		want     bool
+	}{
+		{name: "empty selector", selector: vjailbreakv1alpha1.VMSelector{}, want: true},
+		{name: "tag", selector: vjailbreakv1alpha1.VMSelector{Tags: []string{"env:prod"}}, want: true},
+		{name: "any of the tags", selector: vjailbreakv1alpha1.VMSelector{Tags: []string{"env:dev", "tier:web"}}, want: true},
+		{name: "other tag", selector: vjailbreakv1alpha1.VMSelector{Tags: []string{"env:dev"}}, want: false},
+		{name: "tag of another category", selector: vjailbreakv1alpha1.VMSelector{Tags: []string{"owner:prod"}}, want: false},
+		{name: "any tag of the category", selector: vjailbreakv1alpha1.VMSelector{Tags: []string{"tier:*"}}, want: true},
+		{name: "any tag of another category", selector: vjailbreakv1alpha1.VMSelector{Tags: []string{"owner:*"}}, want: false},
+		{name: "folder", selector: vjailbreakv1alpha1.VMSelector{Folders: []string{"/DC1/vm/Prod/Web"}}, want: true},
+		{name: "parent folder", selector: vjailbreakv1alpha1.VMSelector{Folders: []string{"/DC1/vm/Prod/"}}, want: true},
+		{name: "folder with the same prefix", selector: vjailbreakv1alpha1.VMSelector{Folders: []string{"/DC1/vm/Pro"}}, want: false},
+		{name: "folder below the VM", selector: vjailbreakv1alpha1.VMSelector{Folders: []string{"/DC1/vm/Prod/Web/Frontend"}}, want: false},
+		{name: "resource pool", selector: vjailbreakv1alpha1.VMSelector{ResourcePools: []string{"Silver", "Gold"}}, want: true},
+		{name: "other resource pool", selector: vjailbreakv1alpha1.VMSelector{ResourcePools: []string{"Silver"}}, want: false},
+		{name: "cluster", selector: vjailbreakv1alpha1.VMSelector{Clusters: []string{"cluster-a"}}, want: true},
+		{name: "other cluster", selector: vjailbreakv1alpha1.VMSelector{Clusters: []string{"cluster-b"}}, want: false},
+		{name: "name pattern", selector: vjailbreakv1alpha1.VMSelector{NamePattern: "^web-[0-9]+$"}, want: true},
+		{name: "other name pattern", selector: vjailbreakv1alpha1.VMSelector{NamePattern: "^db-"}, want: false},
+		{
+			name:     "every criterion must match",
+			selector: vjailbreakv1alpha1.VMSelector{Tags: []string{"env:prod"}, Clusters: []string{"cluster-b"}},
+			want:     false,
+		},
+		{
+			name:     "all criteria match",
+			selector: vjailbreakv1alpha1.VMSelector{Tags: []string{"env:prod"}, Folders: []string{"/DC1/vm/Prod"}, NamePattern: "web"},
+			want:     true,
+		},
+		{
+			name: "excluded tag",
+			selector: vjailbreakv1alpha1.VMSelector{
+				Tags:    []string{"env:prod"},
+				Exclude: &vjailbreakv1alpha1.VMSelectorExclusions{Tags: []string{"tier:*"}},
+			},
+			want: false,
+		},
+		{
+			name:     "excluded folder",
+			selector: vjailbreakv1alpha1.VMSelector{Exclude: &vjailbreakv1alpha1.VMSelectorExclusions{Folders: []string{"/DC1/vm/Prod"}}},
+			want:     false,
+		},
+		{
+			name:     "excluded resource pool",
+			selector: vjailbreakv1alpha1.VMSelector{Exclude: &vjailbreakv1alpha1.VMSelectorExclusions{ResourcePools: []string{"Gold"}}},
+			want:     false,
+		},
+		{
+			name:     "excluded cluster",
+			selector: vjailbreakv1alpha1.VMSelector{Exclude: &vjailbreakv1alpha1.VMSelectorExclusions{Clusters: []string{"cluster-a"}}},
+			want:     false,
+		},
+		{
+			name:     "excluded name",
+			selector: vjailbreakv1alpha1.VMSelector{Exclude: &vjailbreakv1alpha1.VMSelectorExclusions{Names: []string{"web-01"}}},
+			want:     false,
+		},
+		{
+			name:     "excluded name pattern",
+			selector: vjailbreakv1alpha1.VMSelector{Exclude: &vjailbreakv1alpha1.VMSelectorExclusions{NamePattern: "-01$"}},
+			want:     false,
+		},
+		{
+			name: "exclusions that do not match",
+			selector: vjailbreakv1alpha1.VMSelector{Exclude: &vjailbreakv1alpha1.VMSelectorExclusions{
+				Tags: []string{"env:dev"}, Folders: []string{"/DC1/vm/Test"}, Names: []string{"web-02"}, NamePattern: "^db-",
+			}},
+			want: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matcher, err := newVMSelectorMatcher(&tt.selector)
+			testutils.Ok(t, err)
+			testutils.Equals(t, tt.want, matcher.matches(&vminfo))
+		})
+	}
+}
+
+func TestVMSelectorFolderOfVMWithoutFolder(t *testing.T) {
+	matcher, err := newVMSelectorMatcher(&vjailbreakv1alpha1.VMSelector{Folders: []string{"/"}})
+	testutils.Ok(t, err)
+	testutils.Equals(t, false, matcher.matches(&vjailbreakv1alpha1.VMInfo{Name: "vm"}))
+}
+
+func TestNewVMSelectorMatcherErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		selector vjailbreakv1alpha1.VMSelector
+	}{
+		{name: "name pattern", selector: vjailbreakv1alpha1.VMSelector{NamePattern: "web-("}},
+		{name: "exclude name pattern", selector: vjailbreakv1alpha1.VMSelector{Exclude: &vjailbreakv1alpha1.VMSelectorExclusions{NamePattern: "["}}},
+		{name: "tag without category", selector: vjailbreakv1alpha1.VMSelector{Tags: []string{"prod"}}},
+		{name: "tag without name", selector: vjailbreakv1alpha1.VMSelector{Tags: []string{"env:"}}},
+		{name: "excluded tag without category", selector: vjailbreakv1alpha1.VMSelector{Exclude: &vjailbreakv1alpha1.VMSelectorExclusions{Tags: []string{":prod"}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newVMSelectorMatcher(&tt.selector)
+			testutils.Assert(t, err != nil, "expected an error for %+v", tt.selector)
+		})
+	}
+}
+
+func TestResolveVMSelector(t *testing.T) {
+	migrationplan := &vjailbreakv1alpha1.MigrationPlan{
+		Spec: vjailbreakv1alpha1.MigrationPlanSpec{
+			VirtualMachines: [][]string{{"web-03"}},
+			VMSelector: &vjailbreakv1alpha1.VMSelector{
+				Tags:    []string{"env:prod"},
+				Exclude: &vjailbreakv1alpha1.VMSelectorExclusions{Names: []string{"web-04"}},
+			},
+		},
+	}
+	vmMachines := []vjailbreakv1alpha1.VMwareMachine{
+		newTestVMwareMachine(vjailbreakv1alpha1.VMInfo{Name: "web-02", Tags: []string{"env:prod"}}, false),
+		newTestVMwareMachine(vjailbreakv1alpha1.VMInfo{Name: "web-01", Tags: []string{"env:prod"}}, false),
+		// Listed in virtualMachines
+		newTestVMwareMachine(vjailbreakv1alpha1.VMInfo{Name: "web-03", Tags: []string{"env:prod"}}, false),
+		// Excluded
+		newTestVMwareMachine(vjailbreakv1alpha1.VMInfo{Name: "web-04", Tags: []string{"env:prod"}}, false),
+		// Migrated already
+		newTestVMwareMachine(vjailbreakv1alpha1.VMInfo{Name: "web-05", Tags: []string{"env:prod"}}, true),
+		// Not selected
+		newTestVMwareMachine(vjailbreakv1alpha1.VMInfo{Name: "web-06", Tags: []string{"env:dev"}}, false),
+		// Seen through a second VMwareMachine
+		newTestVMwareMachine(vjailbreakv1alpha1.VMInfo{Name: "web-01", Tags: []string{"env:prod"}}, false),
+	}
+	selected, err := ResolveVMSelector(migrationplan, vmMachines)
+	testutils.Ok(t, err)
+	testutils.Equals(t, []string{"web-01", "web-02"}, selected)
+
+	migrationplan.Spec.VMSelector.NamePattern = "("
+	_, err = ResolveVMSelector(migrationplan, vmMachines)
+	testutils.Assert(t, err != nil, "expected an error for an invalid name pattern")
+}
+
+func TestGetMigrationPlanVMGroups(t *testing.T) {
+	migrationplan := &vjailbreakv1alpha1.MigrationPlan{
+		Spec: vjailbreakv1alpha1.MigrationPlanSpec{VirtualMachines: [][]string{{"a", "b"}, {"c"}}},
+	}
+	testutils.Equals(t, [][]string{{"a", "b"}, {"c"}}, GetMigrationPlanVMGroups(migrationplan))
+
+	migrationplan.Status.SelectedVMs = []string{"d", "e"}
+	testutils.Equals(t, [][]string{{"a", "b"}, {"c"}, {"d", "e"}}, GetMigrationPlanVMGroups(migrationplan))
+	testutils.Equals(t, 2, len(migrationplan.Spec.VirtualMachines))
+}
+
+func TestValidateVMSelector(t *testing.T) {
+	frozen := metav1.Now()
+	migrationplan := &vjailbreakv1alpha1.MigrationPlan{
+		Spec: vjailbreakv1alpha1.MigrationPlanSpec{VMSelector: &vjailbreakv1alpha1.VMSelector{NamePattern: "^web-"}},
+	}
+	// The selection is not frozen yet
+	testutils.Ok(t, ValidateVMSelector(migrationplan))
+
+	migrationplan.Status.SelectionFrozenTime = &frozen
+	testutils.Assert(t, ValidateVMSelector(migrationplan) != nil, "expected an error for a selector that matches no VMs")
+
+	migrationplan.Status.SelectedVMs = []string{"web-01"}
+	testutils.Ok(t, ValidateVMSelector(migrationplan))
+
+	migrationplan.Spec.VMSelector.Tags = []string{"prod"}
+	testutils.Assert(t, ValidateVMSelector(migrationplan) != nil, "expected an error for a tag without category")
+}
+
+func TestVMSelectionPending(t *testing.T) {
+	synced := metav1.Now()
+	tests := []struct {
+		name     string
+		selected []string
+		synced   *metav1.Time
+		want     bool
+	}{
+		{name: "no VMs before the discovery", selected: []string{}, want: true},
+		{name: "VMs before the discovery", selected: []string{"web-01"}, want: false},
+		{name: "no VMs after the discovery", selected: []string{}, synced: &synced, want: false},
+		{name: "VMs after the discovery", selected: []string{"web-01"}, synced: &synced, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vmwcreds := &vjailbreakv1alpha1.VMwareCreds{}
+			vmwcreds.Status.InventorySyncedTime = tt.synced
+			testutils.Equals(t, tt.want, VMSelectionPending(tt.selected, vmwcreds))
+		})
+	}
+}
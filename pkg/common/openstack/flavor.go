@@ -122,3 +122,29 @@ func getVGPUCount(flavor flavors.Flavor) int {
 	}
 	return 0
 }
+
+// GetSecurityFeatureWarnings returns warnings when the extra specs of the flavor stop Nova from
+// booting a VM with Secure Boot or a vTPM. Flavor extra specs win over the image properties of the
+// boot volume. Both features need the q35 machine type, vSphere only offers TPM 2.0 with the CRB
+// interface.
+func GetSecurityFeatureWarnings(flavor flavors.Flavor, secureBoot, vtpm bool) []string {
+	var warnings []string
+	if !secureBoot && !vtpm {
+		return warnings
+	}
+	if machineType, ok := flavor.ExtraSpecs["hw:machine_type"]; ok && !strings.Contains(machineType, "q35") {
+		warnings = append(warnings, fmt.Sprintf("flavor %s sets hw:machine_type=%s, Secure Boot and vTPM need q35", flavor.Name, machineType))
+	}
+	if secureBoot && strings.EqualFold(flavor.ExtraSpecs["os:secure_boot"], "disabled") {
+		warnings = append(warnings, fmt.Sprintf("flavor %s disables Secure Boot with os:secure_boot=disabled", flavor.Name))
+	}
+	if vtpm {
+		if version, ok := flavor.ExtraSpecs["hw:tpm_version"]; ok && version != "2.0" {
+			warnings = append(warnings, fmt.Sprintf("flavor %s sets hw:tpm_version=%s, the VM has a TPM 2.0", flavor.Name, version))
+		}
+		if model, ok := flavor.ExtraSpecs["hw:tpm_model"]; ok && model != "tpm-crb" {
+			warnings = append(warnings, fmt.Sprintf("flavor %s sets hw:tpm_model=%s, the VM has a CRB TPM", flavor.Name, model))
+		}
+	}
+	return warnings
+}
@@ -257,3 +257,73 @@ func TestGetClosestFlavourWithGPU(t *testing.T) {
 		})
 	}
 }
+
+func TestGetSecurityFeatureWarnings(t *testing.T) {
+	tests := []struct {
+		name       string
+		extraSpecs map[string]string
+		secureBoot bool
+		vtpm       bool
+		expected   []string
+	}{
+		{
+			name:       "no security features",
+			extraSpecs: map[string]string{"hw:machine_type": "pc"},
+			expected:   nil,
+		},
+		{
+			name:       "flavor without extra specs",
+			secureBoot: true,
+			vtpm:       true,
+			expected:   nil,
+		},
+		{
+			name:       "matching extra specs",
+			extraSpecs: map[string]string{"hw:machine_type": "x86_64=q35", "os:secure_boot": "required", "hw:tpm_version": "2.0", "hw:tpm_model": "tpm-crb"},
+			secureBoot: true,
+			vtpm:       true,
+			expected:   nil,
+		},
+		{
+			name:       "machine type",
+			extraSpecs: map[string]string{"hw:machine_type": "pc"},
+			vtpm:       true,
+			expected:   []string{"flavor m1.large sets hw:machine_type=pc, Secure Boot and vTPM need q35"},
+		},
+		{
+			name:       "secure boot disabled",
+			extraSpecs: map[string]string{"os:secure_boot": "Disabled"},
+			secureBoot: true,
+			expected:   []string{"flavor m1.large disables Secure Boot with os:secure_boot=disabled"},
+		},
+		{
+			name:       "secure boot disabled without secure boot",
+			extraSpecs: map[string]string{"os:secure_boot": "disabled"},
+			vtpm:       true,
+			expected:   nil,
+		},
+		{
+			name:       "TPM version and model",
+			extraSpecs: map[string]string{"hw:tpm_version": "1.2", "hw:tpm_model": "tpm-tis"},
+			vtpm:       true,
+			expected: []string{
+				"flavor m1.large sets hw:tpm_version=1.2, the VM has a TPM 2.0",
+				"flavor m1.large sets hw:tpm_model=tpm-tis, the VM has a CRB TPM",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flavor := flavors.Flavor{Name: "m1.large", ExtraSpecs: tt.extraSpecs}
+			result := GetSecurityFeatureWarnings(flavor, tt.secureBoot, tt.vtpm)
+			if len(result) != len(tt.expected) {
+				t.Fatalf("expected %v, got %v", tt.expected, result)
+			}
+			for i := range result {
+				if result[i] != tt.expected[i] {
+					t.Errorf("expected %q, got %q", tt.expected[i], result[i])
+				}
+			}
+		})
+	}
+}
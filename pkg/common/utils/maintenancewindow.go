@@ -0,0 +1,251 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxCronSearchYears bounds the search for the next run of a schedule that never matches, such as
+// one for the 31st of February
+const maxCronSearchYears = 5
+
+// maxNextOpenSteps bounds the search for the next time a maintenance calendar opens
+const maxNextOpenSteps = 1000
+
+// CronSchedule is a cron expression with minute, hour, day of month, month and day of week fields
+type CronSchedule struct {
+	minutes, hours, days, months, weekdays uint64
+	// A restricted day of month or day of week matches when either of them matches
+	anyDay, anyWeekday bool
+}
+
+var cronMonthNames = map[string]int{
+	"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+	"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+}
+
+var cronWeekdayNames = map[string]int{
+	"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+}
+
+// ParseCron parses a cron expression of five fields: minute, hour, day of month, month and day of
+// week. Fields take *, numbers, ranges, lists and steps such as */15 or 1-5/2. Months and days of
+// week also take their three letter English names, Sunday is 0 or 7.
+func ParseCron(expr string) (*CronSchedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron expression %q must have 5 fields, it has %d", expr, len(fields))
+	}
+	schedule := &CronSchedule{
+		anyDay:     fields[2] == "*",
+		anyWeekday: fields[4] == "*",
+	}
+	var err error
+	if schedule.minutes, err = parseCronField(fields[0], 0, 59, nil); err != nil {
+		return nil, fmt.Errorf("invalid minute field: %w", err)
+	}
+	if schedule.hours, err = parseCronField(fields[1], 0, 23, nil); err != nil {
+		return nil, fmt.Errorf("invalid hour field: %w", err)
+	}
+	if schedule.days, err = parseCronField(fields[2], 1, 31, nil); err != nil {
+		return nil, fmt.Errorf("invalid day of month field: %w", err)
+	}
+	if schedule.months, err = parseCronField(fields[3], 1, 12, cronMonthNames); err != nil {
+		return nil, fmt.Errorf("invalid month field: %w", err)
+	}
+	if schedule.weekdays, err = parseCronField(fields[4], 0, 7, cronWeekdayNames); err != nil {
+		return nil, fmt.Errorf("invalid day of week field: %w", err)
+	}
+	// 7 is another name for Sunday
+	if schedule.weekdays&(1<<7) != 0 {
+		schedule.weekdays |= 1
+	}
+	return schedule, nil
+}
+
+// parseCronField parses a cron field into a bitset of the values it matches
+func parseCronField(field string, lowest, highest int, names map[string]int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			step, err = strconv.Atoi(stepPart)
+			if err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step %q", stepPart)
+			}
+		}
+		start, end := lowest, highest
+		if rangePart != "*" {
+			first, last, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if start, err = parseCronValue(first, names); err != nil {
+				return 0, err
+			}
+			end = start
+			if isRange {
+				if end, err = parseCronValue(last, names); err != nil {
+					return 0, err
+				}
+			} else if hasStep {
+				end = highest
+			}
+		}
+		if start < lowest || end > highest || start > end {
+			return 0, fmt.Errorf("%q is out of the range %d-%d", part, lowest, highest)
+		}
+		for value := start; value <= end; value += step {
+			bits |= 1 << value
+		}
+	}
+	return bits, nil
+}
+
+func parseCronValue(value string, names map[string]int) (int, error) {
+	if n, ok := names[strings.ToLower(value)]; ok {
+		return n, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", value)
+	}
+	return n, nil
+}
+
+// matchesDay checks if the schedule runs on the day of t
+func (s *CronSchedule) matchesDay(t time.Time) bool {
+	day := s.days&(1<<t.Day()) != 0
+	weekday := s.weekdays&(1<<int(t.Weekday())) != 0
+	if s.anyDay || s.anyWeekday {
+		return day && weekday
+	}
+	return day || weekday
+}
+
+// Next returns the first time after t the schedule runs, in the location of t. It returns the zero
+// time if the schedule does not run in the next years.
+func (s *CronSchedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(maxCronSearchYears, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case s.months&(1<<int(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		case !s.matchesDay(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		case s.hours&(1<<t.Hour()) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+		case s.minutes&(1<<t.Minute()) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// RecurringWindow is a window that opens on a schedule in a time zone and stays open for a duration
+type RecurringWindow struct {
+	Schedule *CronSchedule
+	Duration time.Duration
+	Location *time.Location
+}
+
+// NewRecurringWindow parses a recurring window. An empty time zone is UTC.
+func NewRecurringWindow(schedule string, duration time.Duration, timeZone string) (RecurringWindow, error) {
+	cron, err := ParseCron(schedule)
+	if err != nil {
+		return RecurringWindow{}, err
+	}
+	if duration <= 0 {
+		return RecurringWindow{}, fmt.Errorf("duration of window %q must be positive", schedule)
+	}
+	loc := time.UTC
+	if timeZone != "" {
+		if loc, err = time.LoadLocation(timeZone); err != nil {
+			return RecurringWindow{}, fmt.Errorf("invalid time zone %q: %w", timeZone, err)
+		}
+	}
+	return RecurringWindow{Schedule: cron, Duration: duration, Location: loc}, nil
+}
+
+// openAt returns whether the window is open at t and when it closes
+func (w RecurringWindow) openAt(t time.Time) (bool, time.Time) {
+	var closes time.Time
+	// Every start within the duration before t opened a window that may still be open
+	for start := w.Schedule.Next(t.In(w.Location).Add(-w.Duration).Add(-time.Minute)); !start.IsZero() && !start.After(t); start = w.Schedule.Next(start) {
+		if end := start.Add(w.Duration); end.After(t) && end.After(closes) {
+			closes = end
+		}
+	}
+	return !closes.IsZero(), closes
+}
+
+// Blackout is a period during which a maintenance calendar stays closed
+type Blackout struct {
+	Start time.Time
+	End   time.Time
+}
+
+// MaintenanceCalendar is a set of recurring windows with blackouts. It is open while any window is
+// open and no blackout applies.
+type MaintenanceCalendar struct {
+	Windows   []RecurringWindow
+	Blackouts []Blackout
+}
+
+// OpenAt returns whether the calendar is open at t and, if it is, when it closes
+func (c *MaintenanceCalendar) OpenAt(t time.Time) (bool, time.Time) {
+	for _, blackout := range c.Blackouts {
+		if !t.Before(blackout.Start) && t.Before(blackout.End) {
+			return false, time.Time{}
+		}
+	}
+	var closes time.Time
+	for _, window := range c.Windows {
+		if open, end := window.openAt(t); open && end.After(closes) {
+			closes = end
+		}
+	}
+	if closes.IsZero() {
+		return false, time.Time{}
+	}
+	for _, blackout := range c.Blackouts {
+		if blackout.Start.After(t) && blackout.Start.Before(closes) {
+			closes = blackout.Start
+		}
+	}
+	return true, closes
+}
+
+// NextOpen returns the first time at or after t the calendar is open. It returns false if the
+// calendar does not open again.
+func (c *MaintenanceCalendar) NextOpen(t time.Time) (time.Time, bool) {
+	for range maxNextOpenSteps {
+		if open, _ := c.OpenAt(t); open {
+			return t, true
+		}
+		// The calendar can only open when a window starts or a blackout ends
+		var next time.Time
+		consider := func(candidate time.Time) {
+			if candidate.After(t) && (next.IsZero() || candidate.Before(next)) {
+				next = candidate
+			}
+		}
+		for _, window := range c.Windows {
+			consider(window.Schedule.Next(t.In(window.Location)))
+		}
+		for _, blackout := range c.Blackouts {
+			consider(blackout.End)
+		}
+		if next.IsZero() {
+			return time.Time{}, false
+		}
+		t = next
+	}
+	return time.Time{}, false
+}
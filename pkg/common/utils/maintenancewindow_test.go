@@ -0,0 +1,139 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func mustTime(t *testing.T, value string) time.Time {
+	t.Helper()
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		t.Fatalf("failed to parse time %q: %v", value, err)
+	}
+	return parsed
+}
+
+func TestParseCron_Invalid(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"* * * foo *",
+	} {
+		if _, err := ParseCron(expr); err == nil {
+			t.Errorf("expected an error for %q", expr)
+		}
+	}
+}
+
+func TestCronSchedule_Next(t *testing.T) {
+	tests := []struct {
+		expr  string
+		after string
+		want  string
+	}{
+		{"0 22 * * 5", "2025-01-01T00:00:00Z", "2025-01-03T22:00:00Z"},
+		{"0 22 * * fri", "2025-01-03T22:00:00Z", "2025-01-10T22:00:00Z"},
+		{"*/15 * * * *", "2025-01-01T10:07:30Z", "2025-01-01T10:15:00Z"},
+		{"30 1 1 jan-mar *", "2025-03-02T00:00:00Z", "2026-01-01T01:30:00Z"},
+		{"0 0 * * 7", "2025-01-01T00:00:00Z", "2025-01-05T00:00:00Z"},
+		// A restricted day of month and day of week match on either
+		{"0 0 15 * 1", "2025-01-07T00:00:00Z", "2025-01-13T00:00:00Z"},
+		{"0 3 1-5/2 * *", "2025-01-01T04:00:00Z", "2025-01-03T03:00:00Z"},
+	}
+	for _, tt := range tests {
+		schedule, err := ParseCron(tt.expr)
+		if err != nil {
+			t.Fatalf("failed to parse %q: %v", tt.expr, err)
+		}
+		got := schedule.Next(mustTime(t, tt.after))
+		if want := mustTime(t, tt.want); !got.Equal(want) {
+			t.Errorf("%q after %s: expected %s, got %s", tt.expr, tt.after, want, got)
+		}
+	}
+}
+
+func TestCronSchedule_NextNeverMatches(t *testing.T) {
+	schedule, err := ParseCron("0 0 31 2 *")
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+	if next := schedule.Next(mustTime(t, "2025-01-01T00:00:00Z")); !next.IsZero() {
+		t.Errorf("expected zero time, got %s", next)
+	}
+}
+
+func TestRecurringWindow_TimeZone(t *testing.T) {
+	window, err := NewRecurringWindow("0 22 * * *", 2*time.Hour, "America/New_York")
+	if err != nil {
+		t.Fatalf("failed to create window: %v", err)
+	}
+	calendar := &MaintenanceCalendar{Windows: []RecurringWindow{window}}
+
+	// 22:00 in New York is 03:00 UTC in winter
+	open, closes := calendar.OpenAt(mustTime(t, "2025-01-16T03:30:00Z"))
+	if !open || !closes.Equal(mustTime(t, "2025-01-16T05:00:00Z")) {
+		t.Errorf("expected window open until 05:00 UTC, got open=%v closes=%s", open, closes)
+	}
+	if open, _ := calendar.OpenAt(mustTime(t, "2025-01-16T22:30:00Z")); open {
+		t.Errorf("expected window closed at 22:30 UTC")
+	}
+
+	if _, err := NewRecurringWindow("0 22 * * *", time.Hour, "Mars/Olympus"); err == nil {
+		t.Errorf("expected an error for an unknown time zone")
+	}
+	if _, err := NewRecurringWindow("0 22 * * *", 0, ""); err == nil {
+		t.Errorf("expected an error for a zero duration")
+	}
+}
+
+func TestMaintenanceCalendar_Blackouts(t *testing.T) {
+	window, err := NewRecurringWindow("0 20 * * *", 6*time.Hour, "")
+	if err != nil {
+		t.Fatalf("failed to create window: %v", err)
+	}
+	calendar := &MaintenanceCalendar{
+		Windows: []RecurringWindow{window},
+		Blackouts: []Blackout{
+			// A change freeze covering two nights
+			{Start: mustTime(t, "2025-12-20T00:00:00Z"), End: mustTime(t, "2025-12-22T00:00:00Z")},
+			// A blackout starting in the middle of a window
+			{Start: mustTime(t, "2025-12-23T23:00:00Z"), End: mustTime(t, "2025-12-24T12:00:00Z")},
+		},
+	}
+
+	if open, _ := calendar.OpenAt(mustTime(t, "2025-12-20T21:00:00Z")); open {
+		t.Errorf("expected calendar closed during the change freeze")
+	}
+	next, ok := calendar.NextOpen(mustTime(t, "2025-12-20T21:00:00Z"))
+	if !ok || !next.Equal(mustTime(t, "2025-12-22T00:00:00Z")) {
+		t.Errorf("expected calendar to open when the freeze ends inside a window, got %s (%v)", next, ok)
+	}
+
+	open, closes := calendar.OpenAt(mustTime(t, "2025-12-23T21:00:00Z"))
+	if !open || !closes.Equal(mustTime(t, "2025-12-23T23:00:00Z")) {
+		t.Errorf("expected calendar to close at the start of the blackout, got open=%v closes=%s", open, closes)
+	}
+	next, ok = calendar.NextOpen(mustTime(t, "2025-12-23T23:30:00Z"))
+	if !ok || !next.Equal(mustTime(t, "2025-12-24T20:00:00Z")) {
+		t.Errorf("expected calendar to open with the next window, got %s (%v)", next, ok)
+	}
+}
+
+func TestMaintenanceCalendar_NeverOpens(t *testing.T) {
+	window, err := NewRecurringWindow("0 0 31 2 *", time.Hour, "")
+	if err != nil {
+		t.Fatalf("failed to create window: %v", err)
+	}
+	calendar := &MaintenanceCalendar{Windows: []RecurringWindow{window}}
+	if _, ok := calendar.NextOpen(mustTime(t, "2025-01-01T00:00:00Z")); ok {
+		t.Errorf("expected calendar to never open")
+	}
+}
@@ -18,7 +18,9 @@ import (
 	"github.com/vmware/govmomi/find"
 	"github.com/vmware/govmomi/session"
 	"github.com/vmware/govmomi/session/cache"
+	"github.com/vmware/govmomi/view"
 	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/mo"
 	corev1 "k8s.io/api/core/v1"
 	k8stypes "k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -179,6 +181,16 @@ func Validate(ctx context.Context, k8sClient client.Client, vmwcreds *vjailbreak
 		}
 	}
 
+	// Check that the endpoint is of the kind the credentials claim to be
+	if result := validateHostType(host, vmwcreds.Spec.HostType, c.ServiceContent.About.ApiType); result != nil {
+		return *result
+	}
+	if vmwcreds.Spec.HostType == vjailbreakv1alpha1.VMwareHostTypeESXi {
+		if managedBy := getManagingVCenter(ctx, c); managedBy != "" {
+			ctxlog.Info("ESXi host is managed by a vCenter server, changes made through the host may be reverted", "host", host, "vcenter", managedBy)
+		}
+	}
+
 	// Check if the datacenter exists (only if datacenter is provided)
 	if datacenter != "" {
 		finder := find.NewFinder(c, false)
@@ -195,13 +207,54 @@ func Validate(ctx context.Context, k8sClient client.Client, vmwcreds *vjailbreak
 	// All validations passed - cache the fully validated client
 	vmwareClientMap.Store(mapKey, c)
 
+	message := "Successfully authenticated to VMware"
+	if !c.IsVC() {
+		message = "Successfully authenticated to standalone ESXi host"
+	}
 	return ValidationResult{
 		Valid:   true,
-		Message: "Successfully authenticated to VMware",
+		Message: message,
 		Error:   nil,
 	}
 }
 
+// validateHostType checks the API type reported by the endpoint against the hostType of the
+// credentials, it returns nil when they match. Credentials of the default type vCenter are also
+// accepted for a standalone ESXi host, the host is detected when migrating.
+func validateHostType(host, hostType, apiType string) *ValidationResult {
+	if hostType == vjailbreakv1alpha1.VMwareHostTypeESXi && apiType == "VirtualCenter" {
+		return &ValidationResult{
+			Valid:   false,
+			Message: fmt.Sprintf("%s is a vCenter server, set hostType to %s", host, vjailbreakv1alpha1.VMwareHostTypeVCenter),
+			Error:   fmt.Errorf("expected an ESXi host, got %s", apiType),
+		}
+	}
+	return nil
+}
+
+// getManagingVCenter returns the address of the vCenter server managing an ESXi host, or an
+// empty string when the host is standalone or the address cannot be read.
+func getManagingVCenter(ctx context.Context, c *vim25.Client) string {
+	m := view.NewManager(c)
+	v, err := m.CreateContainerView(ctx, c.ServiceContent.RootFolder, []string{"HostSystem"}, true)
+	if err != nil {
+		return ""
+	}
+	defer func() {
+		_ = v.Destroy(ctx)
+	}()
+	var hosts []mo.HostSystem
+	if err := v.Retrieve(ctx, []string{"HostSystem"}, []string{"summary.managementServerIp"}, &hosts); err != nil {
+		return ""
+	}
+	for _, host := range hosts {
+		if host.Summary.ManagementServerIp != "" {
+			return host.Summary.ManagementServerIp
+		}
+	}
+	return ""
+}
+
 // getCredentialsFromSecret retrieves VMware credentials from a Kubernetes secret
 func getCredentialsFromSecret(ctx context.Context, k8sClient client.Client, secretName string) (vjailbreakv1alpha1.VMwareCredsInfo, error) {
 	var vmwareCredsInfo vjailbreakv1alpha1.VMwareCredsInfo
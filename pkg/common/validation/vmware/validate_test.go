@@ -0,0 +1,71 @@
+// Copyright © 2024 The vjailbreak authors
+
+package vmware
+
+import (
+	"testing"
+
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+)
+
+func TestValidateHostType(t *testing.T) {
+	tests := []struct {
+		name      string
+		hostType  string
+		apiType   string
+		wantValid bool
+	}{
+		{
+			name:      "vCenter server",
+			hostType:  vjailbreakv1alpha1.VMwareHostTypeVCenter,
+			apiType:   "VirtualCenter",
+			wantValid: true,
+		},
+		{
+			name:      "standalone ESXi host",
+			hostType:  vjailbreakv1alpha1.VMwareHostTypeESXi,
+			apiType:   "HostAgent",
+			wantValid: true,
+		},
+		{
+			name:      "ESXi host type for a vCenter server",
+			hostType:  vjailbreakv1alpha1.VMwareHostTypeESXi,
+			apiType:   "VirtualCenter",
+			wantValid: false,
+		},
+		{
+			name:      "default host type for a standalone ESXi host",
+			hostType:  vjailbreakv1alpha1.VMwareHostTypeVCenter,
+			apiType:   "HostAgent",
+			wantValid: true,
+		},
+		{
+			name:      "host type not set",
+			hostType:  "",
+			apiType:   "VirtualCenter",
+			wantValid: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := validateHostType("vcenter.example.com", tt.hostType, tt.apiType)
+			if tt.wantValid {
+				if result != nil {
+					t.Errorf("validateHostType() = %q, want no error", result.Message)
+				}
+				return
+			}
+			if result == nil {
+				t.Fatalf("validateHostType() accepted host type %s for API type %s", tt.hostType, tt.apiType)
+			}
+			if result.Valid || result.Error == nil {
+				t.Errorf("validateHostType() = %+v, want an invalid result with an error", result)
+			}
+			want := "vcenter.example.com is a vCenter server, set hostType to vCenter"
+			if result.Message != want {
+				t.Errorf("validateHostType() message = %q, want %q", result.Message, want)
+			}
+		})
+	}
+}
@@ -51,6 +51,10 @@ parse_address_pair() {
     if echo "$1" | grep -qE '^([0-9A-Fa-f]{2}(:[0-9A-Fa-f]{2}){5}):ip:([0-9]{1,3}\.[0-9]{1,3}\.[0-9]{1,3}\.[0-9]{1,3}).*$'; then
         FOUND_MAC=$(echo "$1" | sed -nE 's/^([0-9A-Fa-f]{2}(:[0-9A-Fa-f]{2}){5}):ip:.*$/\1/p')
         FOUND_IP=$(echo "$1" | sed -nE 's/^.*:ip:([0-9]{1,3}\.[0-9]{1,3}\.[0-9]{1,3}\.[0-9]{1,3}).*$/\1/p')
+    elif echo "$1" | grep -qE '^([0-9A-Fa-f]{2}(:[0-9A-Fa-f]{2}){5}):ip:[0-9A-Fa-f]*:[0-9A-Fa-f:]+$'; then
+        # MAC:ip:IPv6 for interfaces without an IPv4 address
+        FOUND_MAC=$(echo "$1" | sed -nE 's/^([0-9A-Fa-f]{2}(:[0-9A-Fa-f]{2}){5}):ip:.*$/\1/p')
+        FOUND_IP=$(echo "$1" | sed -nE 's/^([0-9A-Fa-f]{2}(:[0-9A-Fa-f]{2}){5}):ip:(.*)$/\3/p')
     elif echo "$1" | grep -qE '^([0-9A-Fa-f]{2}(:[0-9A-Fa-f]{2}){5}):ip:$'; then
         # Handle MAC:ip: (empty IP) case
         FOUND_MAC=$(echo "$1" | sed -nE 's/^([0-9A-Fa-f]{2}(:[0-9A-Fa-f]{2}){5}):ip:.*$/\1/p')
@@ -111,7 +115,7 @@ process_ifcfg_infrastructure() {
         fi
 
         # Locate script matching the IP
-        local CFG_FILE=$(grep -l "IPADDR=.*$FOUND_IP" "$TARGET_DIR"/ifcfg-* 2>/dev/null)
+        local CFG_FILE=$(grep -lE "(IPADDR|IPV6ADDR|IPV6ADDR_SECONDARIES)[0-9]*=.*$FOUND_IP" "$TARGET_DIR"/ifcfg-* 2>/dev/null)
         
         if [[ -z "$CFG_FILE" ]]; then
             display_msg "Notice: No existing config for $FOUND_IP. Generating fallback."
@@ -127,6 +131,12 @@ process_ifcfg_infrastructure() {
                 echo "PEERDNS=yes"                 
                 echo "PEERROUTES=yes"              
                 echo "DHCP_HOSTNAME=myhost" 
+                if [[ "$FOUND_IP" == *:* ]]; then
+                    # IPv6 only interface, address it through router advertisements or DHCPv6
+                    echo "IPV6INIT=yes"
+                    echo "IPV6_AUTOCONF=yes"
+                    echo "DHCPV6C=yes"
+                fi
             } > "$TARGET_DIR/ifcfg-vjb$VJB_INDEX"
             VJB_INDEX=$((VJB_INDEX+1))
         fi
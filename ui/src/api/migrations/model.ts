@@ -128,11 +128,13 @@ export enum Phase {
   AwaitingDataCopyStart = 'AwaitingDataCopyStart',
   CopyingBlocks = 'CopyingBlocks',
   CopyingChangedBlocks = 'CopyingChangedBlocks',
+  VerifyingData = 'VerifyingData',
   ConvertingDisk = 'ConvertingDisk',
   AwaitingCutOverStartTime = 'AwaitingCutOverStartTime',
   AwaitingAdminCutOver = 'AwaitingAdminCutOver',
   Succeeded = 'Succeeded',
   Failed = 'Failed',
+  RolledBack = 'RolledBack',
   Unknown = 'Unknown'
 }
 
@@ -379,6 +379,7 @@ export default function Sidenav({
     Phase.AwaitingDataCopyStart,
     Phase.CopyingBlocks,
     Phase.CopyingChangedBlocks,
+    Phase.VerifyingData,
     Phase.ConvertingDisk,
     Phase.AwaitingCutOverStartTime,
     Phase.AwaitingAdminCutOver,
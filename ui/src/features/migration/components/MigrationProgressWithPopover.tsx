@@ -118,12 +118,17 @@ export default function MigrationProgressWithPopover({
         Phase.AwaitingDataCopyStart,
         Phase.CopyingBlocks,
         Phase.CopyingChangedBlocks,
+        Phase.VerifyingData,
         Phase.ConvertingDisk,
         Phase.AwaitingCutOverStartTime
       ].includes(phase as Phase)
     ) {
       return <CircularProgress size={20} style={{ marginRight: 3 }} />
-    } else if (phase === Phase.Failed || phase === Phase.ValidationFailed) {
+    } else if (
+      phase === Phase.Failed ||
+      phase === Phase.ValidationFailed ||
+      phase === Phase.RolledBack
+    ) {
       return <ErrorOutlineIcon style={{ color: 'red' }} />
     } else {
       return <HourglassBottomIcon style={{ color: 'grey' }} />
@@ -47,12 +47,14 @@ const PHASE_STEPS = {
   [Phase.AwaitingDataCopyStart]: 3,
   [Phase.CopyingBlocks]: 4,
   [Phase.CopyingChangedBlocks]: 5,
+  [Phase.VerifyingData]: 5,
   [Phase.ConvertingDisk]: 6,
   [Phase.AwaitingCutOverStartTime]: 7,
   [Phase.AwaitingAdminCutOver]: 8,
   [Phase.Succeeded]: 9,
   [Phase.Failed]: 10,
-  [Phase.ValidationFailed]: 11
+  [Phase.ValidationFailed]: 11,
+  [Phase.RolledBack]: 12
 }
 
 const IN_PROGRESS_PHASES = [
@@ -61,6 +63,7 @@ const IN_PROGRESS_PHASES = [
   Phase.AwaitingDataCopyStart,
   Phase.CopyingBlocks,
   Phase.CopyingChangedBlocks,
+  Phase.VerifyingData,
   Phase.ConvertingDisk,
   Phase.AwaitingCutOverStartTime,
   Phase.AwaitingAdminCutOver
@@ -85,15 +88,26 @@ const getProgressText = (
 
   const message = latestCondition?.message || phase
 
-  if (phase === Phase.Failed || phase === Phase.ValidationFailed || phase === Phase.Succeeded) {
+  if (
+    phase === Phase.Failed ||
+    phase === Phase.ValidationFailed ||
+    phase === Phase.Succeeded ||
+    phase === Phase.RolledBack
+  ) {
     return `${phase} - ${message}`
   }
 
   let diskInfo = ''
   if (currentDisk && totalDisks && (phase === Phase.CopyingBlocks || phase === Phase.CopyingChangedBlocks)) {
-    const parsedDisk = parseInt(currentDisk, 10)
-    const currentDiskNum = Number.isNaN(parsedDisk) ? 1 : parsedDisk + 1
-    diskInfo = ` (disk ${currentDiskNum}/${totalDisks})`
+    // Disks copied in parallel are reported as a comma-separated list, e.g. "0,2"
+    const diskNums = currentDisk.split(',').map((disk) => {
+      const parsedDisk = parseInt(disk, 10)
+      return Number.isNaN(parsedDisk) ? 1 : parsedDisk + 1
+    })
+    diskInfo =
+      diskNums.length > 1
+        ? ` (disks ${diskNums.join(',')}/${totalDisks})`
+        : ` (disk ${diskNums[0]}/${totalDisks})`
   }
 
   let progressText = `STEP ${stepNumber}/${totalSteps}: ${phase}${diskInfo} - ${message}`
@@ -284,7 +298,7 @@ export default function MigrationsTable({
           // Logic for the blinking pulse
           const activePhases = new Set([
             Phase.Pending, Phase.Validating, Phase.AwaitingDataCopyStart,
-            Phase.CopyingBlocks, Phase.CopyingChangedBlocks, Phase.ConvertingDisk,
+            Phase.CopyingBlocks, Phase.CopyingChangedBlocks, Phase.VerifyingData, Phase.ConvertingDisk,
             Phase.AwaitingCutOverStartTime, Phase.AwaitingAdminCutOver, Phase.Unknown
           ])
           const isInProgress = activePhases.has(phase)
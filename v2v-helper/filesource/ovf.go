@@ -0,0 +1,295 @@
+// Copyright © 2024 The vjailbreak authors
+
+package filesource
+
+import (
+	"encoding/xml"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// CIM resource types of the virtual hardware items of an OVF descriptor
+const (
+	resourceTypeProcessor = 3
+	resourceTypeMemory    = 4
+	resourceTypeEthernet  = 10
+	resourceTypeDisk      = 17
+)
+
+// Envelope is the part of an OVF descriptor describing the disks, networks and virtual hardware of a VM.
+// Elements and attributes are matched by their local name, exports of different tools use different
+// namespace prefixes for them.
+type Envelope struct {
+	XMLName       xml.Name      `xml:"Envelope"`
+	References    []File        `xml:"References>File"`
+	Disks         []Disk        `xml:"DiskSection>Disk"`
+	VirtualSystem VirtualSystem `xml:"VirtualSystem"`
+}
+
+// File is a file referenced by an OVF descriptor
+type File struct {
+	ID          string `xml:"id,attr"`
+	Href        string `xml:"href,attr"`
+	Compression string `xml:"compression,attr"`
+}
+
+// Disk is a virtual disk of an OVF descriptor
+type Disk struct {
+	DiskID   string `xml:"diskId,attr"`
+	FileRef  string `xml:"fileRef,attr"`
+	Capacity string `xml:"capacity,attr"`
+	Units    string `xml:"capacityAllocationUnits,attr"`
+}
+
+// VirtualSystem is the VM of an OVF descriptor
+type VirtualSystem struct {
+	ID              string          `xml:"id,attr"`
+	Name            string          `xml:"Name"`
+	OperatingSystem OperatingSystem `xml:"OperatingSystemSection"`
+	Hardware        VirtualHardware `xml:"VirtualHardwareSection"`
+}
+
+// OperatingSystem is the guest OS of an OVF descriptor
+type OperatingSystem struct {
+	OSType      string `xml:"osType,attr"`
+	Description string `xml:"Description"`
+}
+
+// VirtualHardware is the virtual hardware of an OVF descriptor. OVF 2.0 descriptors list disks and
+// NICs as StorageItem and EthernetPortItem instead of Item.
+type VirtualHardware struct {
+	Items             []Item   `xml:"Item"`
+	StorageItems      []Item   `xml:"StorageItem"`
+	EthernetPortItems []Item   `xml:"EthernetPortItem"`
+	Configs           []Config `xml:"Config"`
+}
+
+// Item is a virtual hardware item of an OVF descriptor
+type Item struct {
+	ResourceType    int      `xml:"ResourceType"`
+	ElementName     string   `xml:"ElementName"`
+	Address         string   `xml:"Address"`
+	VirtualQuantity int64    `xml:"VirtualQuantity"`
+	AllocationUnits string   `xml:"AllocationUnits"`
+	HostResource    []string `xml:"HostResource"`
+	Connection      []string `xml:"Connection"`
+}
+
+// Config is a VMware extra configuration of an OVF descriptor, like the firmware of the VM
+type Config struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:"value,attr"`
+}
+
+// DescriptorDisk is a disk of the VM of an OVF descriptor in the order of its virtual hardware
+type DescriptorDisk struct {
+	Name string
+	// Href is the file of the disk relative to the descriptor
+	Href string
+	// Capacity is the size of the disk in bytes, 0 when the descriptor does not tell it
+	Capacity int64
+}
+
+// DescriptorNIC is a network interface of the VM of an OVF descriptor
+type DescriptorNIC struct {
+	Network string
+	// MAC is empty when the descriptor does not keep the addresses of the source VM
+	MAC string
+}
+
+// ParseOVF parses an OVF descriptor
+func ParseOVF(r io.Reader) (*Envelope, error) {
+	envelope := &Envelope{}
+	if err := xml.NewDecoder(r).Decode(envelope); err != nil {
+		return nil, errors.Wrap(err, "failed to parse OVF descriptor")
+	}
+	return envelope, nil
+}
+
+// CPU returns the number of vCPUs of the VM
+func (envelope *Envelope) CPU() int32 {
+	for _, item := range envelope.VirtualSystem.Hardware.Items {
+		if item.ResourceType == resourceTypeProcessor {
+			return int32(item.VirtualQuantity)
+		}
+	}
+	return 0
+}
+
+// MemoryMB returns the memory of the VM in MB
+func (envelope *Envelope) MemoryMB() int32 {
+	for _, item := range envelope.VirtualSystem.Hardware.Items {
+		if item.ResourceType == resourceTypeMemory {
+			units := item.AllocationUnits
+			if units == "" {
+				units = "byte * 2^20"
+			}
+			return int32(item.VirtualQuantity * allocationUnitBytes(units) / (1024 * 1024))
+		}
+	}
+	return 0
+}
+
+// Firmware returns whether the VM boots with UEFI and Secure Boot
+func (envelope *Envelope) Firmware() (uefi, secureBoot bool) {
+	for _, config := range envelope.VirtualSystem.Hardware.Configs {
+		switch config.Key {
+		case "firmware":
+			uefi = config.Value == "efi"
+		case "bootOptions.efiSecureBootEnabled", "uefi.secureBoot.enabled":
+			secureBoot = strings.EqualFold(config.Value, "true")
+		}
+	}
+	return uefi, uefi && secureBoot
+}
+
+// OSFamily returns windowsGuest or linuxGuest for the guest OS of the VM, empty when the descriptor
+// does not tell it
+func (envelope *Envelope) OSFamily() string {
+	guest := envelope.VirtualSystem.OperatingSystem
+	description := strings.ToLower(guest.OSType + " " + guest.Description)
+	if strings.Contains(description, "windows") {
+		return "windowsGuest"
+	}
+	for _, linux := range linuxGuestNames {
+		if strings.Contains(description, linux) {
+			return "linuxGuest"
+		}
+	}
+	return ""
+}
+
+// DiskList returns the disks of the VM in the order of its virtual hardware
+func (envelope *Envelope) DiskList() ([]DescriptorDisk, error) {
+	hardware := envelope.VirtualSystem.Hardware
+	items := append(append([]Item{}, hardware.Items...), hardware.StorageItems...)
+	disks := []DescriptorDisk{}
+	for _, item := range items {
+		if item.ResourceType != resourceTypeDisk || len(item.HostResource) == 0 {
+			continue
+		}
+		// The host resource is ovf:/disk/<diskId> or, for disks without a DiskSection, ovf:/file/<fileId>
+		resource := item.HostResource[0]
+		fileID := ""
+		var capacity int64
+		if diskID, ok := cutResource(resource, "disk"); ok {
+			disk := envelope.disk(diskID)
+			if disk == nil {
+				return nil, errors.Errorf("disk %s of the OVF descriptor is not described", diskID)
+			}
+			fileID = disk.FileRef
+			capacity = disk.capacityBytes()
+		} else if id, ok := cutResource(resource, "file"); ok {
+			fileID = id
+		} else {
+			return nil, errors.Errorf("unsupported disk resource %s in the OVF descriptor", resource)
+		}
+		file := envelope.file(fileID)
+		if file == nil {
+			// Disks without a file are blank disks created at deployment, there is nothing to import
+			continue
+		}
+		if file.Compression != "" {
+			return nil, errors.Errorf("compressed disk file %s of the OVF descriptor is not supported", file.Href)
+		}
+		name := item.ElementName
+		if name == "" {
+			name = file.Href
+		}
+		disks = append(disks, DescriptorDisk{Name: name, Href: file.Href, Capacity: capacity})
+	}
+	if len(disks) == 0 {
+		return nil, errors.New("the OVF descriptor has no disks")
+	}
+	return disks, nil
+}
+
+// NICs returns the network interfaces of the VM
+func (envelope *Envelope) NICs() []DescriptorNIC {
+	hardware := envelope.VirtualSystem.Hardware
+	items := append(append([]Item{}, hardware.Items...), hardware.EthernetPortItems...)
+	nics := []DescriptorNIC{}
+	for _, item := range items {
+		if item.ResourceType != resourceTypeEthernet {
+			continue
+		}
+		nic := DescriptorNIC{MAC: strings.ToLower(item.Address)}
+		if len(item.Connection) > 0 {
+			nic.Network = item.Connection[0]
+		}
+		nics = append(nics, nic)
+	}
+	return nics
+}
+
+func (envelope *Envelope) disk(diskID string) *Disk {
+	for i := range envelope.Disks {
+		if envelope.Disks[i].DiskID == diskID {
+			return &envelope.Disks[i]
+		}
+	}
+	return nil
+}
+
+func (envelope *Envelope) file(fileID string) *File {
+	for i := range envelope.References {
+		if envelope.References[i].ID == fileID {
+			return &envelope.References[i]
+		}
+	}
+	return nil
+}
+
+func (disk *Disk) capacityBytes() int64 {
+	capacity, err := strconv.ParseInt(disk.Capacity, 10, 64)
+	if err != nil {
+		return 0
+	}
+	units := disk.Units
+	if units == "" {
+		units = "byte"
+	}
+	return capacity * allocationUnitBytes(units)
+}
+
+// cutResource returns the id of a host resource like ovf:/disk/vmdisk1 for the given kind
+func cutResource(resource, kind string) (string, bool) {
+	for _, prefix := range []string{"ovf:/" + kind + "/", "/" + kind + "/"} {
+		if id, ok := strings.CutPrefix(resource, prefix); ok {
+			return id, true
+		}
+	}
+	return "", false
+}
+
+// linuxGuestNames are found in the guest OS types and descriptions of Linux VMs
+var linuxGuestNames = []string{"linux", "rhel", "centos", "ubuntu", "debian", "sles", "suse", "fedora", "oracle", "rocky", "alma", "photon", "amazon"}
+
+var allocationUnitsRegexp = regexp.MustCompile(`^byte\s*\*\s*(\d+)\s*\^\s*(\d+)$`)
+
+// allocationUnitBytes returns the number of bytes of allocation units like "byte * 2^20"
+func allocationUnitBytes(units string) int64 {
+	units = strings.TrimSpace(units)
+	switch strings.ToLower(units) {
+	case "byte", "bytes":
+		return 1
+	case "kilobytes", "kb":
+		return 1 << 10
+	case "megabytes", "mb":
+		return 1 << 20
+	case "gigabytes", "gb":
+		return 1 << 30
+	}
+	match := allocationUnitsRegexp.FindStringSubmatch(units)
+	if match == nil {
+		return 1
+	}
+	base, _ := strconv.ParseFloat(match[1], 64)
+	exponent, _ := strconv.ParseFloat(match[2], 64)
+	return int64(math.Pow(base, exponent))
+}
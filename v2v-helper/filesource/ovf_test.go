@@ -0,0 +1,155 @@
+package filesource
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testOVF = `<?xml version="1.0" encoding="UTF-8"?>
+<Envelope xmlns="http://schemas.dmtf.org/ovf/envelope/1" xmlns:ovf="http://schemas.dmtf.org/ovf/envelope/1"
+  xmlns:rasd="http://schemas.dmtf.org/wbem/wscim/1/cim-schema/2/CIM_ResourceAllocationSettingData"
+  xmlns:vmw="http://www.vmware.com/schema/ovf">
+  <References>
+    <File ovf:id="file1" ovf:href="web-01-disk1.vmdk"/>
+    <File ovf:id="file2" ovf:href="web-01-disk2.vmdk"/>
+  </References>
+  <DiskSection>
+    <Disk ovf:diskId="vmdisk1" ovf:fileRef="file1" ovf:capacity="20" ovf:capacityAllocationUnits="byte * 2^30"/>
+    <Disk ovf:diskId="vmdisk2" ovf:fileRef="file2" ovf:capacity="1073741824"/>
+    <Disk ovf:diskId="vmdisk3" ovf:capacity="10" ovf:capacityAllocationUnits="byte * 2^30"/>
+  </DiskSection>
+  <VirtualSystem ovf:id="web-01">
+    <Name>web-01</Name>
+    <OperatingSystemSection ovf:id="80" vmw:osType="rhel8_64Guest">
+      <Description>Red Hat Enterprise Linux 8 (64-bit)</Description>
+    </OperatingSystemSection>
+    <VirtualHardwareSection>
+      <Item>
+        <rasd:AllocationUnits>hertz * 10^6</rasd:AllocationUnits>
+        <rasd:ElementName>4 virtual CPU(s)</rasd:ElementName>
+        <rasd:ResourceType>3</rasd:ResourceType>
+        <rasd:VirtualQuantity>4</rasd:VirtualQuantity>
+      </Item>
+      <Item>
+        <rasd:AllocationUnits>byte * 2^30</rasd:AllocationUnits>
+        <rasd:ElementName>8GB of memory</rasd:ElementName>
+        <rasd:ResourceType>4</rasd:ResourceType>
+        <rasd:VirtualQuantity>8</rasd:VirtualQuantity>
+      </Item>
+      <Item>
+        <rasd:ElementName>Hard disk 1</rasd:ElementName>
+        <rasd:HostResource>ovf:/disk/vmdisk1</rasd:HostResource>
+        <rasd:ResourceType>17</rasd:ResourceType>
+      </Item>
+      <Item>
+        <rasd:ElementName>Hard disk 2</rasd:ElementName>
+        <rasd:HostResource>ovf:/disk/vmdisk2</rasd:HostResource>
+        <rasd:ResourceType>17</rasd:ResourceType>
+      </Item>
+      <Item>
+        <rasd:ElementName>Hard disk 3</rasd:ElementName>
+        <rasd:HostResource>ovf:/disk/vmdisk3</rasd:HostResource>
+        <rasd:ResourceType>17</rasd:ResourceType>
+      </Item>
+      <Item>
+        <rasd:Address>00:50:56:AB:CD:EF</rasd:Address>
+        <rasd:Connection>VM Network</rasd:Connection>
+        <rasd:ElementName>Network adapter 1</rasd:ElementName>
+        <rasd:ResourceType>10</rasd:ResourceType>
+      </Item>
+      <Item>
+        <rasd:Connection>Backup</rasd:Connection>
+        <rasd:ElementName>Network adapter 2</rasd:ElementName>
+        <rasd:ResourceType>10</rasd:ResourceType>
+      </Item>
+      <vmw:Config ovf:required="false" vmw:key="firmware" vmw:value="efi"/>
+      <vmw:Config ovf:required="false" vmw:key="bootOptions.efiSecureBootEnabled" vmw:value="true"/>
+    </VirtualHardwareSection>
+  </VirtualSystem>
+</Envelope>`
+
+func TestParseOVF(t *testing.T) {
+	envelope, err := ParseOVF(strings.NewReader(testOVF))
+	assert.NoError(t, err)
+
+	assert.Equal(t, int32(4), envelope.CPU())
+	assert.Equal(t, int32(8192), envelope.MemoryMB())
+	assert.Equal(t, "linuxGuest", envelope.OSFamily())
+
+	uefi, secureBoot := envelope.Firmware()
+	assert.True(t, uefi)
+	assert.True(t, secureBoot)
+
+	disks, err := envelope.DiskList()
+	assert.NoError(t, err)
+	assert.Equal(t, []DescriptorDisk{
+		{Name: "Hard disk 1", Href: "web-01-disk1.vmdk", Capacity: 20 << 30},
+		{Name: "Hard disk 2", Href: "web-01-disk2.vmdk", Capacity: 1 << 30},
+	}, disks)
+
+	assert.Equal(t, []DescriptorNIC{
+		{Network: "VM Network", MAC: "00:50:56:ab:cd:ef"},
+		{Network: "Backup"},
+	}, envelope.NICs())
+}
+
+func TestDiskListErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		ovf      string
+		expected string
+	}{
+		{
+			name:     "compressed disk",
+			ovf:      strings.Replace(testOVF, `ovf:href="web-01-disk1.vmdk"`, `ovf:href="web-01-disk1.vmdk.gz" ovf:compression="gzip"`, 1),
+			expected: "compressed disk file web-01-disk1.vmdk.gz",
+		},
+		{
+			name:     "undescribed disk",
+			ovf:      strings.Replace(testOVF, `ovf:/disk/vmdisk2`, `ovf:/disk/vmdisk9`, 1),
+			expected: "disk vmdisk9 of the OVF descriptor is not described",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			envelope, err := ParseOVF(strings.NewReader(tt.ovf))
+			assert.NoError(t, err)
+			_, err = envelope.DiskList()
+			assert.ErrorContains(t, err, tt.expected)
+		})
+	}
+}
+
+func TestAllocationUnitBytes(t *testing.T) {
+	tests := map[string]int64{
+		"byte":         1,
+		"byte * 2^20":  1 << 20,
+		"byte*2^30":    1 << 30,
+		"byte * 10^3":  1000,
+		"GigaBytes":    1 << 30,
+		"unknown unit": 1,
+	}
+	for units, expected := range tests {
+		assert.Equal(t, expected, allocationUnitBytes(units), units)
+	}
+}
+
+func TestParseProgress(t *testing.T) {
+	percent, ok := parseProgress("    (42.07/100%)")
+	assert.True(t, ok)
+	assert.Equal(t, 42, percent)
+
+	_, ok = parseProgress("qemu-img: Could not open")
+	assert.False(t, ok)
+}
+
+func TestGenerateMAC(t *testing.T) {
+	mac := generateMAC("/mnt/source/web-01.ova", 0)
+	assert.Regexp(t, `^fa:16:3e(:[0-9a-f]{2}){3}$`, mac)
+	// A retried import gets the same MAC address back
+	assert.Equal(t, mac, generateMAC("/mnt/source/web-01.ova", 0))
+	assert.NotEqual(t, mac, generateMAC("/mnt/source/web-01.ova", 1))
+	assert.NotEqual(t, mac, generateMAC("/mnt/source/web-02.ova", 0))
+}
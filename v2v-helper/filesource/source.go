@@ -0,0 +1,405 @@
+// Copyright © 2024 The vjailbreak authors
+
+package filesource
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+	"github.com/platform9/vjailbreak/v2v-helper/pkg/utils"
+	"github.com/platform9/vjailbreak/v2v-helper/vm"
+)
+
+// Sizing of VMs imported from loose disks when the file source does not set it
+const (
+	DefaultCPU      = 2
+	DefaultMemoryMB = 4096
+)
+
+// uriPlaceholder stands for the NBD URI of a disk served by nbdkit in a qemu-img command line
+const uriPlaceholder = "\x00uri"
+
+// Source is a file VMs are imported from: an OVA or OVF appliance, or a loose VMDK or VHDX disk. It is
+// read from a path in the migration pod or from an HTTP URL. Disks on a path are read by qemu-img,
+// disks in an OVA or behind a URL are served to qemu-img by nbdkit.
+type Source struct {
+	Path string
+	URL  string
+	// Format is ova, ovf, vmdk or vhdx, it is detected from the file extension when empty
+	Format string
+	// CPU and MemoryMB size VMs imported from loose disks, which carry no VM description
+	CPU      int32
+	MemoryMB int32
+
+	disks []diskFile
+}
+
+// diskFile is where the data of a disk of the source is found
+type diskFile struct {
+	// location is the path or URL of the file holding the disk
+	location string
+	// tarEntry is the name of the disk in an OVA, empty when the file is the disk
+	tarEntry string
+	// format is the qemu-img format of the disk, empty lets qemu-img probe it
+	format string
+}
+
+// Location returns the path or URL of the source
+func (src *Source) Location() string {
+	if src.URL != "" {
+		return src.URL
+	}
+	return src.Path
+}
+
+// Open reads the description of the VM from the source and returns it as the VMInfo of the VM named
+// name. osType is the OS family set for the migration, the descriptor of an appliance is used when it
+// is empty. NICs without a MAC address in the descriptor get a new one.
+func (src *Source) Open(ctx context.Context, name, osType string) (vm.VMInfo, error) {
+	vminfo := vm.VMInfo{
+		Name:      name,
+		OSType:    osType,
+		IPperMac:  map[string][]vm.IpEntry{},
+		GatewayIP: map[string]string{},
+	}
+	format, err := src.format()
+	if err != nil {
+		return vminfo, err
+	}
+
+	var capacities []int64
+	var nics []DescriptorNIC
+	switch format {
+	case vjailbreakv1alpha1.FileSourceFormatOVA, vjailbreakv1alpha1.FileSourceFormatOVF:
+		var envelope *Envelope
+		if format == vjailbreakv1alpha1.FileSourceFormatOVA {
+			envelope, err = src.readOVADescriptor(ctx)
+		} else {
+			envelope, err = src.readOVFDescriptor(ctx)
+		}
+		if err != nil {
+			return vminfo, err
+		}
+		descriptorDisks, err := envelope.DiskList()
+		if err != nil {
+			return vminfo, err
+		}
+		src.disks = []diskFile{}
+		for _, disk := range descriptorDisks {
+			file := diskFile{format: diskFormat(disk.Href)}
+			if format == vjailbreakv1alpha1.FileSourceFormatOVA {
+				file.location, file.tarEntry = src.Location(), disk.Href
+			} else if file.location, err = src.relativeLocation(disk.Href); err != nil {
+				return vminfo, err
+			}
+			src.disks = append(src.disks, file)
+			capacities = append(capacities, disk.Capacity)
+			vminfo.VMDisks = append(vminfo.VMDisks, vm.VMDisk{Name: disk.Name})
+		}
+		vminfo.CPU = envelope.CPU()
+		vminfo.Memory = envelope.MemoryMB()
+		vminfo.UEFI, vminfo.SecureBoot = envelope.Firmware()
+		if vminfo.OSType == "" {
+			vminfo.OSType = envelope.OSFamily()
+		}
+		nics = envelope.NICs()
+	default:
+		// A loose disk has no VM description, it gets the sizing of the source and a single NIC
+		src.disks = []diskFile{{location: src.Location(), format: format}}
+		capacities = []int64{0}
+		vminfo.VMDisks = []vm.VMDisk{{Name: "Hard disk 1"}}
+		vminfo.CPU, vminfo.Memory = src.CPU, src.MemoryMB
+		nics = []DescriptorNIC{{}}
+	}
+	if vminfo.CPU <= 0 {
+		vminfo.CPU = DefaultCPU
+	}
+	if vminfo.Memory <= 0 {
+		vminfo.Memory = DefaultMemoryMB
+	}
+	if vminfo.OSType == "" {
+		return vminfo, errors.Errorf("the OS family of %s is not known, set osFamily in the MigrationTemplate", src.Location())
+	}
+
+	for idx := range vminfo.VMDisks {
+		size := capacities[idx]
+		if size <= 0 {
+			if size, err = src.diskSize(ctx, idx); err != nil {
+				return vminfo, err
+			}
+		}
+		vminfo.VMDisks[idx].Size = size
+	}
+	for idx, nic := range nics {
+		mac := nic.MAC
+		if mac == "" {
+			mac = generateMAC(src.Location(), idx)
+		}
+		vminfo.Mac = append(vminfo.Mac, mac)
+		vminfo.NetworkInterfaces = append(vminfo.NetworkInterfaces, vjailbreakv1alpha1.NIC{
+			Network: nic.Network,
+			MAC:     mac,
+			Index:   idx,
+		})
+	}
+	return vminfo, nil
+}
+
+// CopyDisk writes disk idx of the source as raw data to dest, the device of the volume of the disk.
+// progress is called with the percentage of the disk copied so far.
+func (src *Source) CopyDisk(ctx context.Context, idx int, dest string, progress func(percent int)) error {
+	disk := src.disks[idx]
+	cmd := disk.command(ctx, func(source string) []string {
+		args := []string{"convert", "-p", "-n"}
+		if disk.format != "" {
+			args = append(args, "-f", disk.format)
+		}
+		return append(args, "-O", "raw", source, dest)
+	})
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return errors.Wrap(err, "failed to get output of qemu-img")
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	utils.PrintLog(fmt.Sprintf("Executing %s", cmd.String()))
+	if err := cmd.Start(); err != nil {
+		return errors.Wrap(err, "failed to start qemu-img")
+	}
+	scanner := bufio.NewScanner(stdout)
+	scanner.Split(scanProgress)
+	for scanner.Scan() {
+		if percent, ok := parseProgress(scanner.Text()); ok {
+			progress(percent)
+		}
+	}
+	if err := cmd.Wait(); err != nil {
+		return errors.Wrapf(err, "failed to copy disk %d of %s: %s", idx, src.Location(), strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// diskSize returns the virtual size of disk idx of the source in bytes
+func (src *Source) diskSize(ctx context.Context, idx int) (int64, error) {
+	disk := src.disks[idx]
+	cmd := disk.command(ctx, func(source string) []string {
+		args := []string{"info", "--output=json"}
+		if disk.format != "" {
+			args = append(args, "-f", disk.format)
+		}
+		return append(args, source)
+	})
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to get size of disk %d of %s: %s", idx, src.Location(), strings.TrimSpace(stderr.String()))
+	}
+	info := struct {
+		VirtualSize int64 `json:"virtual-size"`
+	}{}
+	if err := json.Unmarshal(output, &info); err != nil {
+		return 0, errors.Wrap(err, "failed to parse qemu-img info")
+	}
+	return info.VirtualSize, nil
+}
+
+// command returns the qemu-img command built by qemuArgs for the source of the disk. Disks in an OVA
+// or behind a URL are served by a captive nbdkit which runs qemu-img on its NBD URI.
+func (disk diskFile) command(ctx context.Context, qemuArgs func(source string) []string) *exec.Cmd {
+	remote := isURL(disk.location)
+	if !remote && disk.tarEntry == "" {
+		return exec.CommandContext(ctx, "qemu-img", qemuArgs(disk.location)...)
+	}
+	run := []string{"qemu-img"}
+	for _, arg := range qemuArgs(uriPlaceholder) {
+		if arg == uriPlaceholder {
+			run = append(run, `"$uri"`)
+		} else {
+			run = append(run, shellQuote(arg))
+		}
+	}
+	args := []string{"--readonly", "--exit-with-parent", "--unix", "-", "--run", strings.Join(run, " ")}
+	if disk.tarEntry != "" {
+		args = append(args, "--filter=tar")
+	}
+	if remote {
+		args = append(args, "curl", "url="+disk.location)
+	} else {
+		args = append(args, "file", "file="+disk.location)
+	}
+	if disk.tarEntry != "" {
+		args = append(args, "tar-entry="+disk.tarEntry)
+	}
+	return exec.CommandContext(ctx, "nbdkit", args...)
+}
+
+// format returns the format of the source, detected from the file extension when not set
+func (src *Source) format() (string, error) {
+	if src.Format != "" {
+		return strings.ToLower(src.Format), nil
+	}
+	name := src.Location()
+	if sourceURL, err := url.Parse(name); err == nil && isURL(name) {
+		name = sourceURL.Path
+	}
+	switch format := strings.TrimPrefix(strings.ToLower(path.Ext(name)), "."); format {
+	case vjailbreakv1alpha1.FileSourceFormatOVA, vjailbreakv1alpha1.FileSourceFormatOVF,
+		vjailbreakv1alpha1.FileSourceFormatVMDK, vjailbreakv1alpha1.FileSourceFormatVHDX:
+		return format, nil
+	}
+	return "", errors.Errorf("cannot detect the format of %s, set format in the file source", src.Location())
+}
+
+// readOVADescriptor reads the OVF descriptor of an OVA, it is the first file of the archive
+func (src *Source) readOVADescriptor(ctx context.Context) (*Envelope, error) {
+	file, err := openLocation(ctx, src.Location())
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	archive := tar.NewReader(file)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			return nil, errors.Errorf("OVA %s has no OVF descriptor", src.Location())
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read OVA %s", src.Location())
+		}
+		if strings.EqualFold(path.Ext(header.Name), ".ovf") {
+			return ParseOVF(archive)
+		}
+	}
+}
+
+// readOVFDescriptor reads the OVF descriptor of an OVF appliance
+func (src *Source) readOVFDescriptor(ctx context.Context) (*Envelope, error) {
+	file, err := openLocation(ctx, src.Location())
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ParseOVF(file)
+}
+
+// relativeLocation returns the location of a file referenced by the OVF descriptor of the source
+func (src *Source) relativeLocation(href string) (string, error) {
+	if src.URL != "" {
+		base, err := url.Parse(src.URL)
+		if err != nil {
+			return "", errors.Wrapf(err, "invalid url %s", src.URL)
+		}
+		reference, err := url.Parse(href)
+		if err != nil {
+			return "", errors.Wrapf(err, "invalid file reference %s", href)
+		}
+		return base.ResolveReference(reference).String(), nil
+	}
+	dir := path.Dir(src.Path)
+	location := path.Join(dir, href)
+	if !strings.HasPrefix(location, dir+"/") {
+		return "", errors.Errorf("file %s referenced by %s is outside of its directory", href, src.Path)
+	}
+	return location, nil
+}
+
+// openLocation opens a path or downloads a URL
+func openLocation(ctx context.Context, location string) (io.ReadCloser, error) {
+	if !isURL(location) {
+		file, err := os.Open(location)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to open %s", location)
+		}
+		return file, nil
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, location, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to build request for %s", location)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to download %s", location)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, errors.Errorf("failed to download %s: %s", location, resp.Status)
+	}
+	return resp.Body, nil
+}
+
+// diskFormat returns the qemu-img format of a disk file of an appliance from its extension
+func diskFormat(name string) string {
+	switch ext := strings.TrimPrefix(strings.ToLower(path.Ext(name)), "."); ext {
+	case "vmdk", "vhdx", "qcow2":
+		return ext
+	case "vhd":
+		return "vpc"
+	case "img", "raw":
+		return "raw"
+	}
+	return ""
+}
+
+func isURL(location string) bool {
+	return strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://")
+}
+
+// generateMAC returns the MAC address of NIC idx of the file at location, with the prefix OpenStack
+// uses for the ports it creates. It is derived from both so that a retried import finds the port
+// it created before.
+func generateMAC(location string, idx int) string {
+	suffix := sha256.Sum256([]byte(fmt.Sprintf("%s#%d", location, idx)))
+	return fmt.Sprintf("fa:16:3e:%02x:%02x:%02x", suffix[0], suffix[1], suffix[2])
+}
+
+func shellQuote(arg string) string {
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
+
+var progressRegexp = regexp.MustCompile(`\((\d+)(?:\.\d+)?/100%\)`)
+
+// parseProgress parses a progress line of qemu-img like "    (42.07/100%)"
+func parseProgress(line string) (int, bool) {
+	match := progressRegexp.FindStringSubmatch(line)
+	if match == nil {
+		return 0, false
+	}
+	percent, err := strconv.Atoi(match[1])
+	if err != nil {
+		return 0, false
+	}
+	return percent, true
+}
+
+// scanProgress splits the output of qemu-img at carriage returns as well as newlines, progress
+// updates overwrite the same line
+func scanProgress(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+		return i + 1, data[:i], nil
+	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
@@ -4,11 +4,14 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"path"
 	"strings"
 	"time"
 
+	"github.com/platform9/vjailbreak/v2v-helper/filesource"
 	"github.com/platform9/vjailbreak/v2v-helper/migrate"
 	"github.com/platform9/vjailbreak/v2v-helper/nbd"
 	"github.com/platform9/vjailbreak/v2v-helper/openstack"
@@ -18,6 +21,7 @@ import (
 	"github.com/platform9/vjailbreak/v2v-helper/vcenter"
 	"github.com/platform9/vjailbreak/v2v-helper/vm"
 	"github.com/vmware/govmomi/vim25/types"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 func main() {
@@ -81,9 +85,20 @@ func main() {
 		openstackProjectName = strings.TrimSpace(os.Getenv("OS_TENANT_NAME"))
 	}
 
-	starttime, _ := time.Parse(time.RFC3339, migrationparams.DataCopyStart)
-	cutstart, _ := time.Parse(time.RFC3339, migrationparams.VMcutoverStart)
-	cutend, _ := time.Parse(time.RFC3339, migrationparams.VMcutoverEnd)
+	var destination migrate.Destination
+	if migrationparams.DestinationType == constants.DestinationTypeFile {
+		fileDestination, err := migrate.NewFileDestination(constants.DestinationMountPath, migrationparams.DestinationFormat)
+		if err != nil {
+			handleError(fmt.Sprintf("Failed to set up file destination: %v", err))
+			return
+		}
+		destination = fileDestination
+	}
+
+	if migrationparams.SourceFilePath != "" || migrationparams.SourceFileURL != "" {
+		migrateFromFile(ctx, client, migrationparams, destination, openstackInsecure, openstackProjectName, eventReporter, eventReporterChan, podLabelWatcherChan, handleError)
+		return
+	}
 
 	// Validate vCenter connection
 	vcclient, err := vcenter.VCenterClientBuilder(ctx, vCenterUserName, vCenterPassword, vCenterURL, vCenterInsecure)
@@ -91,16 +106,18 @@ func main() {
 		handleError(fmt.Sprintf("Failed to validate vCenter connection: %v", err))
 		return
 	}
-	utils.PrintLog(fmt.Sprintf("Connected to vCenter: %s\n", vCenterURL))
+	sourceIsESXi := !vcclient.VCClient.IsVC()
+	if sourceIsESXi {
+		utils.PrintLog(fmt.Sprintf("Connected to standalone ESXi host: %s\n", vCenterURL))
+	} else {
+		utils.PrintLog(fmt.Sprintf("Connected to vCenter: %s\n", vCenterURL))
+	}
 	defer vcclient.VCClient.CloseIdleConnections()
-	// Validate OpenStack connection
-	openstackclients, err := openstack.NewOpenStackClients(ctx, openstackInsecure)
+	openstackclients, err := connectOpenStack(ctx, client, destination, openstackInsecure)
 	if err != nil {
-		handleError(fmt.Sprintf("Failed to validate OpenStack connection: %v", err))
+		handleError(err.Error())
 		return
 	}
-	openstackclients.K8sClient = client
-	utils.PrintLog("Connected to OpenStack")
 
 	// Get thumbprint
 	thumbprint, err := vcenter.GetThumbprint(vCenterURL)
@@ -116,53 +133,29 @@ func main() {
 		handleError(fmt.Sprintf("Failed to get source VM: %v", err))
 		return
 	}
-	migrationobj := migrate.Migrate{
-		URL:                     vCenterURL,
-		UserName:                vCenterUserName,
-		Password:                vCenterPassword,
-		Insecure:                vCenterInsecure,
-		Networknames:            utils.RemoveEmptyStrings(strings.Split(migrationparams.OpenstackNetworkNames, ",")),
-		Networkports:            utils.RemoveEmptyStrings(strings.Split(migrationparams.OpenstackNetworkPorts, ",")),
-		Volumetypes:             utils.RemoveEmptyStrings(strings.Split(migrationparams.OpenstackVolumeTypes, ",")),
-		Virtiowin:               migrationparams.OpenstackVirtioWin,
-		Ostype:                  migrationparams.OpenstackOSType,
-		Thumbprint:              thumbprint,
-		Convert:                 migrationparams.OpenstackConvert,
-		DisconnectSourceNetwork: migrationparams.DisconnectSourceNetwork,
-		Openstackclients:        openstackclients,
-		Vcclient:                vcclient,
-		VMops:                   vmops,
-		Nbdops:                  []nbd.NBDOperations{},
-		EventReporter:           eventReporterChan,
-		PodLabelWatcher:         podLabelWatcherChan,
-		InPod:                   reporter.IsRunningInPod(),
-		MigrationTimes: migrate.MigrationTimes{
-			DataCopyStart:  starttime,
-			VMCutoverStart: cutstart,
-			VMCutoverEnd:   cutend,
-		},
-		MigrationType:          migrationparams.MigrationType,
-		PerformHealthChecks:    migrationparams.PerformHealthChecks,
-		HealthCheckPort:        migrationparams.HealthCheckPort,
-		K8sClient:              client,
-		TargetFlavorId:         migrationparams.TARGET_FLAVOR_ID,
-		TargetAvailabilityZone: migrationparams.TargetAvailabilityZone,
-		AssignedIP:             migrationparams.AssignedIP,
-		SecurityGroups:         utils.RemoveEmptyStrings(strings.Split(migrationparams.SecurityGroups, ",")),
-		ServerGroup:            migrationparams.ServerGroup,
-		RDMDisks:               utils.RemoveEmptyStrings(strings.Split(migrationparams.RDMDisks, ",")),
-		UseFlavorless:          os.Getenv("USE_FLAVORLESS") == "true",
-		TenantName:             openstackProjectName,
-		Reporter:               eventReporter,
-		FallbackToDHCP:         migrationparams.FallbackToDHCP,
-		StorageCopyMethod:      migrationparams.StorageCopyMethod,
-		ArrayHost:              arrayHost,
-		ArrayUser:              arrayUser,
-		ArrayPassword:          arrayPassword,
-		ArrayInsecure:          arrayInsecure,
-		VendorType:             migrationparams.VendorType,
-		ArrayCredsMapping:      migrationparams.ArrayCredsMapping,
-	}
+	migrationobj := newMigrate(client, migrationparams, destination, openstackclients, openstackProjectName, eventReporter, eventReporterChan, podLabelWatcherChan)
+	migrationobj.URL = vCenterURL
+	migrationobj.UserName = vCenterUserName
+	migrationobj.Password = vCenterPassword
+	migrationobj.Insecure = vCenterInsecure
+	migrationobj.Thumbprint = thumbprint
+	migrationobj.DisconnectSourceNetwork = migrationparams.DisconnectSourceNetwork
+	migrationobj.Vcclient = vcclient
+	migrationobj.VMops = vmops
+	migrationobj.RDMDisks = utils.RemoveEmptyStrings(strings.Split(migrationparams.RDMDisks, ","))
+	migrationobj.StorageCopyMethod = migrationparams.StorageCopyMethod
+	migrationobj.ArrayHost = arrayHost
+	migrationobj.ArrayUser = arrayUser
+	migrationobj.ArrayPassword = arrayPassword
+	migrationobj.ArrayInsecure = arrayInsecure
+	migrationobj.VendorType = migrationparams.VendorType
+	migrationobj.ArrayCredsMapping = migrationparams.ArrayCredsMapping
+	migrationobj.DiskCopyParallelism = migrationparams.DiskCopyParallelism
+	migrationobj.VerifyMode = migrationparams.VerifyMode
+	migrationobj.VerifySamplePercent = migrationparams.VerifySamplePercent
+	migrationobj.RollbackPolicy = migrationparams.RollbackPolicy
+	migrationobj.SnapshotConsistency = migrationparams.SnapshotConsistency
+	migrationobj.SourceIsESXi = sourceIsESXi
 
 	if migrationobj.ServerGroup != "" {
 		utils.PrintLog(fmt.Sprintf("Server group configured: %s", migrationobj.ServerGroup))
@@ -176,6 +169,11 @@ func main() {
 		PreMigrationPowerState = types.VirtualMachinePowerStatePoweredOn
 	}
 	if err := migrationobj.MigrateVM(ctx); err != nil {
+		if errors.Is(err, migrate.ErrRolledBack) {
+			migrationobj.RunFailureHooks(ctx)
+			utils.PrintLog(fmt.Sprintf("----- Migration rolled back at %s for VM %s: %v -----", time.Now().Format(time.RFC3339), migrationparams.SourceVMName, err))
+			return
+		}
 		msg := fmt.Sprintf("Failed to migrate VM: %v. ", err)
 
 		// Try to power on the VM if migration failed
@@ -191,9 +189,105 @@ func main() {
 		}
 
 		handleError(msg)
+		migrationobj.RunFailureHooks(ctx)
 		utils.PrintLog(fmt.Sprintf("----- Migration completed with errors at %s for VM %s -----", time.Now().Format(time.RFC3339), migrationparams.SourceVMName))
 		return
 	}
 
 	utils.PrintLog(fmt.Sprintf("----- Migration completed successfully at %s for VM %s -----", time.Now().Format(time.RFC3339), migrationparams.SourceVMName))
 }
+
+// migrateFromFile imports the VM of an OVA, OVF or disk file, there is no vCenter and no source VM
+func migrateFromFile(ctx context.Context, client ctrlclient.Client, migrationparams *utils.MigrationParams, destination migrate.Destination,
+	openstackInsecure bool, openstackProjectName string, eventReporter *reporter.Reporter, eventReporterChan, podLabelWatcherChan chan string,
+	handleError func(string)) {
+	openstackclients, err := connectOpenStack(ctx, client, destination, openstackInsecure)
+	if err != nil {
+		handleError(err.Error())
+		return
+	}
+
+	migrationobj := newMigrate(client, migrationparams, destination, openstackclients, openstackProjectName, eventReporter, eventReporterChan, podLabelWatcherChan)
+	migrationobj.FileSource = &filesource.Source{
+		Path:     migrationparams.SourceFilePath,
+		URL:      migrationparams.SourceFileURL,
+		Format:   migrationparams.SourceFileFormat,
+		CPU:      int32(migrationparams.SourceFileCPU),
+		MemoryMB: int32(migrationparams.SourceFileMemoryMB),
+	}
+	migrationobj.NetworkMapping = migrationparams.NetworkMapping
+
+	// The target VM is named after the file without its extension
+	name := path.Base(migrationparams.SourceVMName)
+	name = strings.TrimSuffix(name, path.Ext(name))
+	if err := migrationobj.MigrateFromFile(ctx, name); err != nil {
+		handleError(fmt.Sprintf("Failed to migrate VM: %v. ", err))
+		migrationobj.RunFailureHooks(ctx)
+		utils.PrintLog(fmt.Sprintf("----- Migration completed with errors at %s for VM %s -----", time.Now().Format(time.RFC3339), migrationparams.SourceVMName))
+		return
+	}
+	utils.PrintLog(fmt.Sprintf("----- Migration completed successfully at %s for VM %s -----", time.Now().Format(time.RFC3339), migrationparams.SourceVMName))
+}
+
+// newMigrate returns the migration of the VM with the settings that do not depend on its source,
+// the caller adds those of the vCenter or file the VM is read from
+func newMigrate(client ctrlclient.Client, migrationparams *utils.MigrationParams, destination migrate.Destination,
+	openstackclients openstack.OpenstackOperations, openstackProjectName string, eventReporter *reporter.Reporter,
+	eventReporterChan, podLabelWatcherChan chan string) *migrate.Migrate {
+	starttime, _ := time.Parse(time.RFC3339, migrationparams.DataCopyStart)
+	cutstart, _ := time.Parse(time.RFC3339, migrationparams.VMcutoverStart)
+	cutend, _ := time.Parse(time.RFC3339, migrationparams.VMcutoverEnd)
+	return &migrate.Migrate{
+		Networknames:     utils.RemoveEmptyStrings(strings.Split(migrationparams.OpenstackNetworkNames, ",")),
+		Networkports:     utils.RemoveEmptyStrings(strings.Split(migrationparams.OpenstackNetworkPorts, ",")),
+		Volumetypes:      utils.RemoveEmptyStrings(strings.Split(migrationparams.OpenstackVolumeTypes, ",")),
+		Virtiowin:        migrationparams.OpenstackVirtioWin,
+		Ostype:           migrationparams.OpenstackOSType,
+		Convert:          migrationparams.OpenstackConvert,
+		Openstackclients: openstackclients,
+		Nbdops:           []nbd.NBDOperations{},
+		EventReporter:    eventReporterChan,
+		PodLabelWatcher:  podLabelWatcherChan,
+		InPod:            reporter.IsRunningInPod(),
+		MigrationTimes: migrate.MigrationTimes{
+			DataCopyStart:  starttime,
+			VMCutoverStart: cutstart,
+			VMCutoverEnd:   cutend,
+			DataCopyWindow: migrationparams.DataCopyWindow,
+			CutoverWindow:  migrationparams.CutoverWindow,
+		},
+		MigrationType:          migrationparams.MigrationType,
+		PerformHealthChecks:    migrationparams.PerformHealthChecks,
+		HealthCheckPort:        migrationparams.HealthCheckPort,
+		K8sClient:              client,
+		TargetFlavorId:         migrationparams.TARGET_FLAVOR_ID,
+		TargetAvailabilityZone: migrationparams.TargetAvailabilityZone,
+		AssignedIP:             migrationparams.AssignedIP,
+		SecurityGroups:         utils.RemoveEmptyStrings(strings.Split(migrationparams.SecurityGroups, ",")),
+		ServerGroup:            migrationparams.ServerGroup,
+		UseFlavorless:          os.Getenv("USE_FLAVORLESS") == "true",
+		TenantName:             openstackProjectName,
+		Reporter:               eventReporter,
+		FallbackToDHCP:         migrationparams.FallbackToDHCP,
+		HealthChecks:           migrationparams.HealthChecks,
+		HealthCheckPolicy:      migrationparams.HealthCheckPolicy,
+		Hooks:                  migrationparams.Hooks,
+		Destination:            destination,
+	}
+}
+
+// connectOpenStack validates the OpenStack connection, there is none when the VM is written to a file
+// destination
+func connectOpenStack(ctx context.Context, client ctrlclient.Client, destination migrate.Destination, insecure bool) (openstack.OpenstackOperations, error) {
+	if destination != nil {
+		utils.PrintLog("Writing the VM to a file destination, not connecting to OpenStack")
+		return nil, nil
+	}
+	openstackclients, err := openstack.NewOpenStackClients(ctx, insecure)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to validate OpenStack connection: %v", err)
+	}
+	openstackclients.K8sClient = client
+	utils.PrintLog("Connected to OpenStack")
+	return openstackclients, nil
+}
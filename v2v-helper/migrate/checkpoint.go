@@ -0,0 +1,217 @@
+// Copyright © 2024 The vjailbreak authors
+
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/gophercloud/gophercloud/v2/openstack/blockstorage/v3/volumes"
+	"github.com/pkg/errors"
+	"github.com/platform9/vjailbreak/v2v-helper/pkg/k8sutils"
+	"github.com/platform9/vjailbreak/v2v-helper/pkg/utils"
+	"github.com/platform9/vjailbreak/v2v-helper/vm"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// LoadCheckpoint fetches the disk replication checkpoint left behind by a previous
+// run of this migration. Checkpointing stays disabled when not running in a pod or
+// when the controller did not create a checkpoint configmap for the VM, and for
+// destinations other than OpenStack.
+func (migobj *Migrate) LoadCheckpoint(ctx context.Context, vminfo vm.VMInfo) error {
+	if !migobj.InPod || migobj.K8sClient == nil || migobj.Destination != nil {
+		return nil
+	}
+	vmK8sName, err := k8sutils.GetVMwareMachineName()
+	if err != nil {
+		return errors.Wrap(err, "failed to get vmware machine name")
+	}
+	checkpoint, err := k8sutils.GetMigrationCheckpoint(ctx, migobj.K8sClient, vmK8sName)
+	if err != nil {
+		if apierrors.IsNotFound(errors.Cause(err)) {
+			utils.PrintLog("No checkpoint configmap found, disk replication will not be resumable")
+			return nil
+		}
+		return errors.Wrap(err, "failed to load migration checkpoint")
+	}
+	migobj.checkpointVMName = vmK8sName
+
+	if checkpoint != nil && checkpoint.VMUUID != vminfo.UUID {
+		migobj.logMessage(fmt.Sprintf("Ignoring checkpoint recorded for VM UUID %s, current VM UUID is %s", checkpoint.VMUUID, vminfo.UUID))
+		checkpoint = nil
+	}
+	if checkpoint == nil {
+		checkpoint = &k8sutils.MigrationCheckpoint{VMUUID: vminfo.UUID}
+	} else {
+		migobj.logMessage(fmt.Sprintf("Resuming disk replication from checkpoint (CBT iteration %d)", checkpoint.CBTIteration))
+	}
+	migobj.Checkpoint = checkpoint
+	return nil
+}
+
+// saveCheckpoint persists the in-memory checkpoint. Failures are only logged since
+// losing a checkpoint makes a restart slower but does not affect this run.
+func (migobj *Migrate) saveCheckpoint(ctx context.Context) {
+	if migobj.Checkpoint == nil {
+		return
+	}
+	if err := k8sutils.SaveMigrationCheckpoint(ctx, migobj.K8sClient, migobj.checkpointVMName, migobj.Checkpoint); err != nil {
+		utils.PrintLog(fmt.Sprintf("Failed to save migration checkpoint: %v", err))
+	}
+}
+
+// ClearCheckpoint removes the checkpoint once the volumes it refers to are gone or the migration is done
+func (migobj *Migrate) ClearCheckpoint(ctx context.Context) {
+	if migobj.Checkpoint == nil {
+		return
+	}
+	if err := k8sutils.SaveMigrationCheckpoint(ctx, migobj.K8sClient, migobj.checkpointVMName, nil); err != nil {
+		utils.PrintLog(fmt.Sprintf("Failed to clear migration checkpoint: %v", err))
+	}
+	migobj.Checkpoint = &k8sutils.MigrationCheckpoint{VMUUID: migobj.Checkpoint.VMUUID}
+}
+
+// diskCheckpoint returns the checkpoint entry of a disk, creating it if needed
+func (migobj *Migrate) diskCheckpoint(vmdisk vm.VMDisk) *k8sutils.DiskCheckpoint {
+	if disk := migobj.Checkpoint.GetDisk(vmdisk.Disk.Key); disk != nil {
+		return disk
+	}
+	migobj.Checkpoint.Disks = append(migobj.Checkpoint.Disks, k8sutils.DiskCheckpoint{
+		DiskName:  vmdisk.Name,
+		DeviceKey: vmdisk.Disk.Key,
+	})
+	return &migobj.Checkpoint.Disks[len(migobj.Checkpoint.Disks)-1]
+}
+
+// adoptVolume returns the volume a previous run created for the disk, detached from
+// any agent it was left attached to, or nil if a new volume has to be created.
+func (migobj *Migrate) adoptVolume(ctx context.Context, vmdisk vm.VMDisk) *volumes.Volume {
+	if migobj.Checkpoint == nil || vmdisk.Disk == nil {
+		return nil
+	}
+	disk := migobj.Checkpoint.GetDisk(vmdisk.Disk.Key)
+	if disk == nil || disk.VolumeID == "" {
+		return nil
+	}
+	openstackops := migobj.Openstackclients
+	volume, err := openstackops.GetVolume(ctx, disk.VolumeID)
+	if err != nil {
+		migobj.logMessage(fmt.Sprintf("Checkpointed volume %s of disk %s is not usable, creating a new one: %v", disk.VolumeID, vmdisk.Name, err))
+		*disk = k8sutils.DiskCheckpoint{DiskName: vmdisk.Name, DeviceKey: vmdisk.Disk.Key}
+		return nil
+	}
+	if int64(volume.Size) < int64(math.Ceil(float64(vmdisk.Size)/(1024*1024*1024))) {
+		migobj.logMessage(fmt.Sprintf("Checkpointed volume %s is smaller than disk %s, creating a new one", volume.ID, vmdisk.Name))
+		*disk = k8sutils.DiskCheckpoint{DiskName: vmdisk.Name, DeviceKey: vmdisk.Disk.Key}
+		return nil
+	}
+	for _, attachment := range volume.Attachments {
+		utils.PrintLog(fmt.Sprintf("Volume %s is still attached to server %s, detaching it", volume.ID, attachment.ServerID))
+		if err := openstackops.DetachVolumeFromServer(ctx, attachment.ServerID, volume.ID); err != nil {
+			migobj.logMessage(fmt.Sprintf("Failed to detach checkpointed volume %s, creating a new one: %v", volume.ID, err))
+			*disk = k8sutils.DiskCheckpoint{DiskName: vmdisk.Name, DeviceKey: vmdisk.Disk.Key}
+			return nil
+		}
+	}
+	if len(volume.Attachments) > 0 {
+		if err := openstackops.WaitForVolume(ctx, volume.ID); err != nil {
+			migobj.logMessage(fmt.Sprintf("Checkpointed volume %s did not become available, creating a new one: %v", volume.ID, err))
+			*disk = k8sutils.DiskCheckpoint{DiskName: vmdisk.Name, DeviceKey: vmdisk.Disk.Key}
+			return nil
+		}
+	}
+	migobj.logMessage(fmt.Sprintf("Adopted existing volume %s for disk %s", volume.ID, vmdisk.Name))
+	return volume
+}
+
+// checkpointVolume records the volume created for a disk
+func (migobj *Migrate) checkpointVolume(ctx context.Context, vmdisk vm.VMDisk) {
+	if migobj.Checkpoint == nil || vmdisk.Disk == nil {
+		return
+	}
+	disk := migobj.diskCheckpoint(vmdisk)
+	disk.VolumeID = vmdisk.OpenstackVol.ID
+	disk.ChangeID = ""
+	disk.FullCopyCompleted = false
+	migobj.saveCheckpoint(ctx)
+}
+
+// fullCopyCompleted reports whether a previous run already finished the full copy of a disk
+func (migobj *Migrate) fullCopyCompleted(vmdisk vm.VMDisk) bool {
+	if migobj.Checkpoint == nil || vmdisk.Disk == nil {
+		return false
+	}
+	disk := migobj.Checkpoint.GetDisk(vmdisk.Disk.Key)
+	return disk != nil && disk.FullCopyCompleted && disk.ChangeID != ""
+}
+
+// restoreChangeIDs rewinds the change IDs of disks copied by a previous run so the
+// next changed block query covers everything written since their last good copy.
+func (migobj *Migrate) restoreChangeIDs(vminfo *vm.VMInfo) {
+	for idx, vmdisk := range vminfo.VMDisks {
+		if !migobj.fullCopyCompleted(vmdisk) {
+			continue
+		}
+		disk := migobj.Checkpoint.GetDisk(vmdisk.Disk.Key)
+		vminfo.VMDisks[idx].ChangeID = disk.ChangeID
+		migobj.logMessage(fmt.Sprintf("Disk %d (%s): resuming from change ID %s", idx, vmdisk.Name, disk.ChangeID))
+	}
+}
+
+// checkpointChangeID records that the volume of a disk holds its contents up to its current change ID
+func (migobj *Migrate) checkpointChangeID(ctx context.Context, vmdisk vm.VMDisk) {
+	if migobj.Checkpoint == nil || vmdisk.Disk == nil {
+		return
+	}
+	migobj.checkpointLock.Lock()
+	defer migobj.checkpointLock.Unlock()
+	disk := migobj.diskCheckpoint(vmdisk)
+	disk.VolumeID = vmdisk.OpenstackVol.ID
+	disk.ChangeID = vmdisk.ChangeID
+	disk.FullCopyCompleted = true
+	migobj.saveCheckpoint(ctx)
+}
+
+// checkpointIteration records the number of finished CBT iterations and returns the
+// iteration count to continue with, which includes iterations of previous runs.
+func (migobj *Migrate) checkpointIteration(ctx context.Context, iteration int) int {
+	if migobj.Checkpoint == nil {
+		return iteration
+	}
+	if migobj.Checkpoint.CBTIteration > iteration {
+		iteration = migobj.Checkpoint.CBTIteration
+	}
+	migobj.Checkpoint.CBTIteration = iteration
+	migobj.saveCheckpoint(ctx)
+	return iteration
+}
+
+// resetCheckpointCopyState forgets copy progress while keeping the volumes, used when
+// CBT had to be (re-)enabled and previously recorded change IDs are no longer valid.
+func (migobj *Migrate) resetCheckpointCopyState(ctx context.Context) {
+	if migobj.Checkpoint == nil {
+		return
+	}
+	for idx := range migobj.Checkpoint.Disks {
+		migobj.Checkpoint.Disks[idx].ChangeID = ""
+		migobj.Checkpoint.Disks[idx].FullCopyCompleted = false
+	}
+	migobj.Checkpoint.CBTIteration = 0
+	migobj.saveCheckpoint(ctx)
+}
+
+// shouldPreserveCheckpoint reports whether volumes must be kept for a later restart
+// on termination: that is the case while the Migration object is still active,
+// e.g. when the pod is evicted by a node drain or an agent upgrade.
+func (migobj *Migrate) shouldPreserveCheckpoint(ctx context.Context) bool {
+	if migobj.Checkpoint == nil || len(migobj.Checkpoint.Disks) == 0 {
+		return false
+	}
+	active, err := k8sutils.IsMigrationActive(ctx, migobj.K8sClient, migobj.checkpointVMName)
+	if err != nil {
+		utils.PrintLog(fmt.Sprintf("Failed to check migration state, not preserving volumes: %v", err))
+		return false
+	}
+	return active
+}
@@ -0,0 +1,107 @@
+// Copyright © 2024 The vjailbreak authors
+
+package migrate
+
+import (
+	"context"
+
+	"github.com/gophercloud/gophercloud/v2/openstack/blockstorage/v3/volumes"
+	"github.com/pkg/errors"
+	"github.com/platform9/vjailbreak/v2v-helper/vm"
+)
+
+// Destination is where the disks of a migrated VM are written and the target VM is created.
+// Migrate uses the OpenStack destination when its Destination is nil.
+type Destination interface {
+	// CreateDisk creates the target of disk idx of the VM. The target is described as a volume
+	// even when the destination does not create Cinder volumes.
+	CreateDisk(ctx context.Context, vminfo vm.VMInfo, idx int, volumeType string) (*volumes.Volume, error)
+	// SetBootDisk marks the target of a disk as the one the VM boots from
+	SetBootDisk(ctx context.Context, disk vm.VMDisk) error
+	// AttachDisk makes the target of a disk writable by the helper and returns its path
+	AttachDisk(ctx context.Context, disk vm.VMDisk) (string, error)
+	// DetachDisk releases the target of a disk attached with AttachDisk
+	DetachDisk(ctx context.Context, disk vm.VMDisk) error
+	// DeleteDisk deletes the target of a disk
+	DeleteDisk(ctx context.Context, disk vm.VMDisk) error
+	// ReservePorts reserves the network interfaces of the target VM on networknames, the target
+	// networks of the NICs of the VM
+	ReservePorts(ctx context.Context, vminfo *vm.VMInfo, networknames []string) (networkids, portids, ipaddresses []string, err error)
+	// CreateInstance creates the target VM from the targets of its disks and returns its ID. The
+	// ID is returned as soon as the VM exists, also when it did not start.
+	CreateInstance(ctx context.Context, vminfo vm.VMInfo, networkids, portids []string) (string, error)
+}
+
+// destination returns the destination of the migration
+func (migobj *Migrate) destination() Destination {
+	if migobj.Destination != nil {
+		return migobj.Destination
+	}
+	return &openstackDestination{migobj: migobj}
+}
+
+// openstackDestination creates Cinder volumes attached to the helper VM and boots a Nova server
+// from them
+type openstackDestination struct {
+	migobj *Migrate
+}
+
+func (dest *openstackDestination) CreateDisk(ctx context.Context, vminfo vm.VMInfo, idx int, volumeType string) (*volumes.Volume, error) {
+	vmdisk := vminfo.VMDisks[idx]
+	setRDMLabel := len(vminfo.RDMDisks) > 0
+	volume, err := dest.migobj.Openstackclients.CreateVolume(ctx, vminfo.Name+"-"+vmdisk.Name, vmdisk.Size, vminfo.OSType, vminfo.UEFI, volumeType, setRDMLabel)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create volume")
+	}
+	return volume, nil
+}
+
+func (dest *openstackDestination) SetBootDisk(ctx context.Context, disk vm.VMDisk) error {
+	return dest.migobj.Openstackclients.SetVolumeBootable(ctx, disk.OpenstackVol)
+}
+
+func (dest *openstackDestination) AttachDisk(ctx context.Context, disk vm.VMDisk) (string, error) {
+	openstackops := dest.migobj.Openstackclients
+	volumeID := disk.OpenstackVol.ID
+	if err := openstackops.AttachVolumeToVM(ctx, volumeID); err != nil {
+		return "", errors.Wrap(err, "failed to attach volume to VM")
+	}
+
+	// Get the Path of the attached volume
+	devicePath, err := openstackops.FindDevice(volumeID)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to find device")
+	}
+	return devicePath, nil
+}
+
+func (dest *openstackDestination) DetachDisk(ctx context.Context, disk vm.VMDisk) error {
+	openstackops := dest.migobj.Openstackclients
+	if err := openstackops.DetachVolumeFromVM(ctx, disk.OpenstackVol.ID); err != nil {
+		return errors.Wrap(err, "failed to detach volume from VM")
+	}
+	if err := openstackops.WaitForVolume(ctx, disk.OpenstackVol.ID); err != nil {
+		return errors.Wrap(err, "failed to wait for volume to become available")
+	}
+	return nil
+}
+
+func (dest *openstackDestination) DeleteDisk(ctx context.Context, disk vm.VMDisk) error {
+	if err := dest.migobj.Openstackclients.DeleteVolume(ctx, disk.OpenstackVol.ID); err != nil {
+		return errors.Wrap(err, "failed to delete volume")
+	}
+	return nil
+}
+
+// ReservePorts creates the Neutron ports of the VM, networknames are those of the migration
+func (dest *openstackDestination) ReservePorts(ctx context.Context, vminfo *vm.VMInfo, networknames []string) ([]string, []string, []string, error) {
+	return dest.migobj.ReservePortsForVM(ctx, vminfo)
+}
+
+func (dest *openstackDestination) CreateInstance(ctx context.Context, vminfo vm.VMInfo, networkids, portids []string) (string, error) {
+	server, err := dest.migobj.bootServer(ctx, vminfo, networkids, portids)
+	if server == nil {
+		return "", err
+	}
+	return server.ID, err
+}
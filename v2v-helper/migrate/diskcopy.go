@@ -0,0 +1,66 @@
+// Copyright © 2024 The vjailbreak authors
+
+package migrate
+
+import (
+	"context"
+	"sync"
+)
+
+// diskCopyParallelism returns the number of disks to copy at once. The value from
+// the migration plan takes precedence over the vjailbreak-settings default.
+func (migobj *Migrate) diskCopyParallelism(settingsValue, diskCount int) int {
+	parallelism := migobj.DiskCopyParallelism
+	if parallelism <= 0 {
+		parallelism = settingsValue
+	}
+	if parallelism > diskCount {
+		parallelism = diskCount
+	}
+	if parallelism < 1 {
+		parallelism = 1
+	}
+	return parallelism
+}
+
+// forEachDisk runs fn for every disk index with at most parallelism calls in flight.
+// The first failure cancels the context passed to the remaining calls and no new
+// disks are started; the first error is returned once all running calls finished.
+func forEachDisk(ctx context.Context, diskCount, parallelism int, fn func(ctx context.Context, idx int) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+	sem := make(chan struct{}, parallelism)
+	for idx := 0; idx < diskCount; idx++ {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func(idx int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if err := fn(ctx, idx); err != nil {
+				errOnce.Do(func() {
+					firstErr = err
+					cancel()
+				})
+			}
+		}(idx)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	// only the parent can have cancelled the context at this point
+	return ctx.Err()
+}
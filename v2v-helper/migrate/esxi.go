@@ -0,0 +1,59 @@
+// Copyright © 2024 The vjailbreak authors
+
+package migrate
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	netutils "github.com/platform9/vjailbreak/pkg/common/utils"
+	esxissh "github.com/platform9/vjailbreak/v2v-helper/esxi-ssh"
+	"github.com/platform9/vjailbreak/v2v-helper/pkg/utils"
+)
+
+// powerOffSourceVM powers off the source VM through the VMware API. Standalone ESXi hosts with a
+// free license reject power operations through the API, there the VM is powered off over SSH with
+// the ESXi SSH key instead.
+func (migobj *Migrate) powerOffSourceVM(ctx context.Context) error {
+	err := migobj.VMops.VMPowerOff()
+	if err == nil || !migobj.SourceIsESXi {
+		return err
+	}
+	utils.PrintLog(fmt.Sprintf("Could not power off the VM through the ESXi host API, retrying over SSH: %v", err))
+
+	if len(migobj.ESXiSSHPrivateKey) == 0 {
+		if keyErr := migobj.LoadESXiSSHKey(ctx); keyErr != nil {
+			return errors.Wrapf(err, "no ESXi SSH key to power off over SSH: %v", keyErr)
+		}
+	}
+	// On a standalone host the managed object ID of a VM is its vim-cmd ID
+	vmID := migobj.VMops.GetVMObj().Reference().Value
+	powerOff := migobj.esxiSSHPowerOff
+	if powerOff == nil {
+		powerOff = migobj.powerOffOverSSH
+	}
+	if err := powerOff(ctx, vmID); err != nil {
+		return err
+	}
+	migobj.logMessage(fmt.Sprintf("Powered off VM %s over SSH", vmID))
+	return nil
+}
+
+// powerOffOverSSH powers off the VM with the given vim-cmd ID over SSH to the ESXi host
+func (migobj *Migrate) powerOffOverSSH(ctx context.Context, vmID string) error {
+	u, err := netutils.NormalizeVCenterURL(migobj.URL)
+	if err != nil {
+		return errors.Wrap(err, "failed to parse ESXi host address")
+	}
+
+	esxiClient := esxissh.NewClient()
+	defer esxiClient.Disconnect()
+	if err := esxiClient.Connect(ctx, u.Hostname(), "root", migobj.ESXiSSHPrivateKey); err != nil {
+		return errors.Wrap(err, "failed to connect to ESXi via SSH")
+	}
+	if err := esxiClient.PowerOffVM(vmID); err != nil {
+		return errors.Wrap(err, "failed to power off VM over SSH")
+	}
+	return nil
+}
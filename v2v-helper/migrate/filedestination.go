@@ -0,0 +1,188 @@
+// Copyright © 2024 The vjailbreak authors
+
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/gophercloud/gophercloud/v2/openstack/blockstorage/v3/volumes"
+	"github.com/pkg/errors"
+	"github.com/platform9/vjailbreak/v2v-helper/pkg/constants"
+	"github.com/platform9/vjailbreak/v2v-helper/pkg/utils"
+	"github.com/platform9/vjailbreak/v2v-helper/pkg/xml"
+	"github.com/platform9/vjailbreak/v2v-helper/vm"
+)
+
+// FileDestination writes the disks of the migrated VM as images to a directory named after the VM,
+// next to a libvirt domain XML describing it. The disks are written and converted as sparse raw
+// files and turned into Format images when the VM is created. Their targets are described as
+// volumes whose ID is the path of the raw file.
+type FileDestination struct {
+	// Dir is where the directory of the VM is created
+	Dir string
+	// Format is qcow2 or raw
+	Format string
+
+	// bootDisk is the raw file of the disk the VM boots from
+	bootDisk string
+}
+
+// NewFileDestination returns a destination writing images of the given format to dir, qcow2 when
+// format is empty
+func NewFileDestination(dir, format string) (*FileDestination, error) {
+	switch format {
+	case "":
+		format = constants.DestinationFormatQcow2
+	case constants.DestinationFormatQcow2, constants.DestinationFormatRaw:
+	default:
+		return nil, errors.Errorf("unsupported destination image format %q, expected qcow2 or raw", format)
+	}
+	return &FileDestination{Dir: dir, Format: format}, nil
+}
+
+func (dest *FileDestination) CreateDisk(ctx context.Context, vminfo vm.VMInfo, idx int, volumeType string) (*volumes.Volume, error) {
+	vmDir := filepath.Join(dest.Dir, vminfo.Name)
+	if err := os.MkdirAll(vmDir, 0o755); err != nil {
+		return nil, errors.Wrap(err, "failed to create directory of the VM")
+	}
+	name := fmt.Sprintf("%s-disk%d", vminfo.Name, idx)
+	path := filepath.Join(vmDir, name+".raw")
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create disk image")
+	}
+	defer file.Close()
+	if err := file.Truncate(vminfo.VMDisks[idx].Size); err != nil {
+		return nil, errors.Wrap(err, "failed to size disk image")
+	}
+	return &volumes.Volume{
+		ID:   path,
+		Name: name,
+		Size: int(math.Ceil(float64(vminfo.VMDisks[idx].Size) / (1024 * 1024 * 1024))),
+	}, nil
+}
+
+// SetBootDisk records the disk the domain XML boots from
+func (dest *FileDestination) SetBootDisk(ctx context.Context, disk vm.VMDisk) error {
+	dest.bootDisk = disk.OpenstackVol.ID
+	return nil
+}
+
+// AttachDisk returns the path of the raw file, the helper writes to it directly
+func (dest *FileDestination) AttachDisk(ctx context.Context, disk vm.VMDisk) (string, error) {
+	if _, err := os.Stat(disk.OpenstackVol.ID); err != nil {
+		return "", errors.Wrap(err, "failed to find disk image")
+	}
+	return disk.OpenstackVol.ID, nil
+}
+
+func (dest *FileDestination) DetachDisk(ctx context.Context, disk vm.VMDisk) error {
+	return nil
+}
+
+// DeleteDisk deletes the raw file of a disk and the image converted from it
+func (dest *FileDestination) DeleteDisk(ctx context.Context, disk vm.VMDisk) error {
+	for _, path := range []string{disk.OpenstackVol.ID, dest.imagePath(disk.OpenstackVol.ID)} {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return errors.Wrap(err, "failed to delete disk image")
+		}
+	}
+	return nil
+}
+
+// ReservePorts reserves nothing, the NICs of the VM are connected to the libvirt networks named
+// networknames
+func (dest *FileDestination) ReservePorts(ctx context.Context, vminfo *vm.VMInfo, networknames []string) ([]string, []string, []string, error) {
+	return append([]string{}, networknames...), nil, nil, nil
+}
+
+// CreateInstance converts the raw files to the images of the VM and writes its domain XML. The
+// path of the domain XML is returned as the ID of the VM.
+func (dest *FileDestination) CreateInstance(ctx context.Context, vminfo vm.VMInfo, networkids, portids []string) (string, error) {
+	images := []string{}
+	for _, vmdisk := range vminfo.VMDisks {
+		raw := vmdisk.OpenstackVol.ID
+		image := dest.imagePath(raw)
+		if image != raw {
+			utils.PrintLog(fmt.Sprintf("Converting %s to %s", raw, image))
+			cmd := exec.CommandContext(ctx, "qemu-img", "convert", "-f", "raw", "-O", dest.Format, raw, image)
+			if output, err := cmd.CombinedOutput(); err != nil {
+				return "", errors.Wrapf(err, "failed to convert disk image %s: %s", raw, strings.TrimSpace(string(output)))
+			}
+			if err := os.Remove(raw); err != nil {
+				return "", errors.Wrap(err, "failed to delete raw disk image")
+			}
+		}
+		images = append(images, image)
+	}
+
+	domain := dest.domain(vminfo, images, networkids)
+	domainPath := filepath.Join(dest.Dir, vminfo.Name, vminfo.Name+".xml")
+	if err := xml.WriteDomain(domain, domainPath); err != nil {
+		return "", errors.Wrap(err, "failed to write domain XML")
+	}
+	utils.PrintLog(fmt.Sprintf("Wrote domain XML of VM %s to %s", vminfo.Name, domainPath))
+	return domainPath, nil
+}
+
+// imagePath returns the path of the image converted from the raw file of a disk
+func (dest *FileDestination) imagePath(raw string) string {
+	return strings.TrimSuffix(raw, ".raw") + "." + dest.Format
+}
+
+// domain returns the libvirt domain of the VM booting from images, its NICs keep the MAC addresses
+// of the source VM
+func (dest *FileDestination) domain(vminfo vm.VMInfo, images, networks []string) xml.Domain {
+	domain := xml.Domain{
+		Type:   "kvm",
+		Name:   vminfo.Name,
+		Memory: &xml.Memory{Unit: "MiB", Value: int64(vminfo.Memory)},
+		VCPU:   vminfo.CPU,
+		OS: &xml.OS{
+			Type: xml.OSType{Arch: "x86_64", Machine: "q35", Value: "hvm"},
+		},
+		Features: &xml.Features{ACPI: &struct{}{}, APIC: &struct{}{}},
+	}
+	if vminfo.UEFI {
+		domain.OS.Firmware = "efi"
+	}
+	if vminfo.SecureBoot {
+		domain.OS.FirmwareInfo = &xml.FirmwareFeatures{Features: []xml.FirmwareFeature{
+			{Enabled: "yes", Name: "secure-boot"},
+			{Enabled: "yes", Name: "enrolled-keys"},
+		}}
+		domain.OS.Loader = &xml.Loader{Secure: "yes"}
+		domain.Features.SMM = &xml.SMM{State: "on"}
+	}
+	for idx, image := range images {
+		disk := xml.Disk{
+			Type:   "file",
+			Device: "disk",
+			Driver: xml.Driver{Name: "qemu", Type: dest.Format},
+			Source: xml.Source{File: image},
+			Target: xml.Target{Dev: fmt.Sprintf("vd%c", 'a'+idx), Bus: "virtio"},
+		}
+		if vminfo.VMDisks[idx].Boot || vminfo.VMDisks[idx].OpenstackVol.ID == dest.bootDisk {
+			disk.Boot = &xml.Boot{Order: 1}
+		}
+		domain.Devices.Disks = append(domain.Devices.Disks, disk)
+	}
+	for idx, network := range networks {
+		domain.Devices.Interfaces = append(domain.Devices.Interfaces, xml.Interface{
+			Type:   "network",
+			MAC:    xml.MAC{Address: vminfo.Mac[idx]},
+			Source: xml.InterfaceSource{Network: network},
+			Model:  xml.Model{Type: "virtio"},
+		})
+	}
+	if vminfo.VTPM {
+		domain.Devices.TPM = &xml.TPM{Model: "tpm-crb", Backend: xml.TPMBackend{Type: "emulator", Version: "2.0"}}
+	}
+	return domain
+}
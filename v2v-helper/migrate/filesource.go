@@ -0,0 +1,161 @@
+// Copyright © 2024 The vjailbreak authors
+
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+	"github.com/platform9/vjailbreak/v2v-helper/pkg/k8sutils"
+	"github.com/platform9/vjailbreak/v2v-helper/pkg/utils"
+	"github.com/platform9/vjailbreak/v2v-helper/vm"
+)
+
+// MigrateFromFile imports the VM of migobj.FileSource as vmName. There is no source VM, the disks are
+// copied once, converted and booted the same way as those of a VMware VM, and a failed migration has
+// nothing to roll back to.
+func (migobj *Migrate) MigrateFromFile(ctx context.Context, vmName string) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Wait until the data copy start time
+	var zerotime time.Time
+	if !migobj.MigrationTimes.DataCopyStart.Equal(zerotime) && migobj.MigrationTimes.DataCopyStart.After(time.Now()) {
+		migobj.logMessage("Waiting for data copy start time")
+		time.Sleep(time.Until(migobj.MigrationTimes.DataCopyStart))
+		migobj.logMessage("Data copy start time reached")
+	}
+	if err := migobj.waitForDataCopyWindow(ctx); err != nil {
+		return errors.Wrap(err, "failed to wait for data copy window")
+	}
+	migobj.logMessage(fmt.Sprintf("Reading VM from %s", migobj.FileSource.Location()))
+	vminfo, err := migobj.FileSource.Open(ctx, vmName, migobj.Ostype)
+	if err != nil {
+		return errors.Wrap(err, "failed to read VM from file")
+	}
+	if len(migobj.Networknames) == 0 {
+		migobj.Networknames, err = fileSourceNetworks(vminfo, migobj.NetworkMapping)
+		if err != nil {
+			return err
+		}
+	}
+	// A single volume type from the storage mapping applies to all disks of the file, volume types
+	// only apply to Cinder volumes
+	if migobj.Destination != nil {
+		migobj.Volumetypes = make([]string, len(vminfo.VMDisks))
+	} else if len(migobj.Volumetypes) == 1 {
+		for len(migobj.Volumetypes) < len(vminfo.VMDisks) {
+			migobj.Volumetypes = append(migobj.Volumetypes, migobj.Volumetypes[0])
+		}
+	}
+	if len(vminfo.VMDisks) != len(migobj.Volumetypes) {
+		return errors.Errorf("number of volume types does not match number of disks vm(%d) volume(%d)", len(vminfo.VMDisks), len(migobj.Volumetypes))
+	}
+	if len(vminfo.Mac) != len(migobj.Networknames) {
+		return errors.Errorf("number of mac addresses does not match number of network names mac(%d) network(%d)", len(vminfo.Mac), len(migobj.Networknames))
+	}
+	// Graceful Termination clean-up volumes
+	go migobj.gracefulTerminate(ctx, vminfo, cancel)
+
+	networkids, portids, ipaddresses, err := migobj.destination().ReservePorts(ctx, &vminfo, migobj.Networknames)
+	if err != nil {
+		return errors.Wrap(err, "failed to reserve ports for VM")
+	}
+	vcenterSettings, err := k8sutils.GetVjailbreakSettings(ctx, migobj.K8sClient)
+	if err != nil {
+		return errors.Wrap(err, "failed to get vcenter settings")
+	}
+	migobj.targetIPs = ipaddresses
+	if err := migobj.RunHooks(ctx, vjailbreakv1alpha1.HookStagePreCopy); err != nil {
+		return err
+	}
+
+	vminfo, err = migobj.CreateVolumes(ctx, vminfo)
+	if err != nil {
+		return errors.Wrap(err, "failed to add volumes to host")
+	}
+	if err := migobj.copyFileDisks(ctx, vminfo); err != nil {
+		if cleanuperror := migobj.cleanup(ctx, vminfo, fmt.Sprintf("failed to copy disks: %s", err), portids, vcenterSettings); cleanuperror != nil {
+			// combine both errors
+			return errors.Wrapf(err, "failed to cleanup disks: %s", cleanuperror)
+		}
+		return errors.Wrap(err, "failed to copy disks")
+	}
+
+	err = migobj.ConvertVolumes(ctx, vminfo)
+	if err != nil {
+		if !vcenterSettings.CleanupVolumesAfterConvertFailure {
+			migobj.logMessage("Cleanup volumes after convert failure is disabled, detaching volumes")
+			if detachErr := migobj.DetachAllVolumes(ctx, vminfo); detachErr != nil {
+				utils.PrintLog(fmt.Sprintf("Failed to detach all volumes from VM: %s\n", detachErr))
+			}
+			return errors.Wrap(err, "failed to convert disks")
+		}
+		if cleanuperror := migobj.cleanup(ctx, vminfo, fmt.Sprintf("failed to convert volumes: %s", err), portids, vcenterSettings); cleanuperror != nil {
+			// combine both errors
+			return errors.Wrapf(err, "failed to cleanup disks: %s", cleanuperror)
+		}
+		return errors.Wrap(err, "failed to convert disks")
+	}
+
+	err = migobj.CreateTargetInstance(ctx, vminfo, networkids, portids, ipaddresses)
+	if err != nil {
+		// A target VM that did not pass its health checks or PostCutover hooks is kept for inspection
+		if errors.Is(err, ErrHealthChecksFailed) || errors.Is(err, ErrHookFailed) {
+			return err
+		}
+		if cleanuperror := migobj.cleanup(ctx, vminfo, fmt.Sprintf("failed to create target instance: %s", err), portids, vcenterSettings); cleanuperror != nil {
+			// combine both errors
+			return errors.Wrapf(err, "failed to cleanup disks: %s", cleanuperror)
+		}
+		return errors.Wrap(err, "failed to create target instance")
+	}
+	return nil
+}
+
+// copyFileDisks writes the disks of the file source to their volumes
+func (migobj *Migrate) copyFileDisks(ctx context.Context, vminfo vm.VMInfo) error {
+	for idx, vmdisk := range vminfo.VMDisks {
+		devicePath, err := migobj.AttachVolume(ctx, vmdisk)
+		if err != nil {
+			return errors.Wrap(err, "failed to attach volume")
+		}
+		migobj.logMessage(fmt.Sprintf("Copying disk %d, Completed: 0%%", idx))
+		lastReported := 0
+		err = migobj.FileSource.CopyDisk(ctx, idx, devicePath, func(percent int) {
+			if percent >= lastReported+10 {
+				lastReported = percent - percent%10
+				migobj.logMessage(fmt.Sprintf("Copying disk %d, Completed: %d%%", idx, lastReported))
+			}
+		})
+		if err != nil {
+			return errors.Wrapf(err, "failed to copy disk %s", vmdisk.Name)
+		}
+		if lastReported < 100 {
+			migobj.logMessage(fmt.Sprintf("Copying disk %d, Completed: 100%%", idx))
+		}
+	}
+	return migobj.DetachAllVolumes(ctx, vminfo)
+}
+
+// fileSourceNetworks returns the OpenStack network of each NIC of a VM read from a file. A mapping
+// with a single entry applies to all NICs, descriptors of loose disks do not name their networks.
+func fileSourceNetworks(vminfo vm.VMInfo, mapping map[string]string) ([]string, error) {
+	networks := []string{}
+	for _, nic := range vminfo.NetworkInterfaces {
+		if network, ok := mapping[nic.Network]; ok {
+			networks = append(networks, network)
+			continue
+		}
+		if len(mapping) != 1 {
+			return nil, errors.Errorf("network %q of NIC %d not found in the network mapping", nic.Network, nic.Index)
+		}
+		for _, network := range mapping {
+			networks = append(networks, network)
+		}
+	}
+	return networks, nil
+}
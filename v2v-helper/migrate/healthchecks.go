@@ -0,0 +1,224 @@
+// Copyright © 2024 The vjailbreak authors
+
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+	netutils "github.com/platform9/vjailbreak/pkg/common/utils"
+	"github.com/platform9/vjailbreak/v2v-helper/pkg/constants"
+	"github.com/platform9/vjailbreak/v2v-helper/vm"
+)
+
+// ErrHealthChecksFailed is returned by CreateTargetInstance when the target VM did not pass its
+// health checks and the failure policy does not allow the migration to continue
+var ErrHealthChecksFailed = errors.New("target VM did not pass its health checks")
+
+// errConsoleLogMatched fails a ConsoleLog health check without further attempts
+var errConsoleLogMatched = errors.New("console log matches the failure pattern")
+
+// healthCheckFailurePolicy returns the failure policy of the health checks. Without an explicit
+// policy, health checks that did not pass roll back when a rollback policy is set.
+func (migobj *Migrate) healthCheckFailurePolicy() string {
+	if migobj.HealthCheckPolicy != "" {
+		return migobj.HealthCheckPolicy
+	}
+	if migobj.RollbackPolicy != "" {
+		return constants.HealthCheckFailurePolicyRollback
+	}
+	return constants.HealthCheckFailurePolicyWarn
+}
+
+// checkTargetHealth runs the health checks of the migration against the target server and applies
+// the failure policy. The error wraps ErrHealthChecksFailed unless the policy is Warn.
+func (migobj *Migrate) checkTargetHealth(ctx context.Context, serverID string, vminfo vm.VMInfo, ips []string) error {
+	var err error
+	switch {
+	case len(migobj.HealthChecks) > 0:
+		err = migobj.RunHealthChecks(ctx, serverID, ips)
+	case migobj.PerformHealthChecks:
+		err = migobj.HealthCheck(vminfo, ips)
+		if err != nil {
+			migobj.logMessage(fmt.Sprintf("Health Check failed: %s", err))
+		}
+	default:
+		migobj.logMessage("Skipping Health Checks")
+		return nil
+	}
+	if err == nil {
+		return nil
+	}
+	if migobj.healthCheckFailurePolicy() == constants.HealthCheckFailurePolicyWarn {
+		migobj.logMessage(fmt.Sprintf("Continuing with the migration, %s", err))
+		return nil
+	}
+	return errors.Wrap(ErrHealthChecksFailed, err.Error())
+}
+
+// RunHealthChecks runs the health checks of the migration plan one after the other and reports
+// the result of each of them. It returns an error naming the checks that did not pass.
+func (migobj *Migrate) RunHealthChecks(ctx context.Context, serverID string, ips []string) error {
+	migobj.logMessage("Performing Health Checks")
+	var failedChecks []string
+	for _, check := range migobj.HealthChecks {
+		message, err := migobj.runHealthCheck(ctx, check, serverID, ips)
+		if err != nil {
+			migobj.logMessage(fmt.Sprintf("%s%s did not pass: %v", constants.EventMessageHealthCheck, check.Name, err))
+			failedChecks = append(failedChecks, check.Name)
+			continue
+		}
+		migobj.logMessage(fmt.Sprintf("%s%s passed: %s", constants.EventMessageHealthCheck, check.Name, message))
+	}
+	if len(failedChecks) > 0 {
+		return errors.Errorf("health checks %s did not pass", strings.Join(failedChecks, ", "))
+	}
+	return nil
+}
+
+// runHealthCheck makes the attempts of a health check until one passes or the retry budget is
+// used up. A ConsoleLog check watches the console for its whole retry budget.
+func (migobj *Migrate) runHealthCheck(ctx context.Context, check vjailbreakv1alpha1.HealthCheck, serverID string, ips []string) (string, error) {
+	attempts := max(check.Retries, 0) + 1
+	timeout := time.Duration(max(check.TimeoutSeconds, 1)) * time.Second
+	interval := time.Duration(max(check.RetryIntervalSeconds, 1)) * time.Second
+
+	var lastErr error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if attempt > 1 {
+			select {
+			case <-ctx.Done():
+				return "", ctx.Err()
+			case <-time.After(interval):
+			}
+		}
+		attemptCtx, cancel := context.WithTimeout(ctx, timeout)
+		message, err := migobj.probeHealthCheck(attemptCtx, check, serverID, ips)
+		cancel()
+		switch {
+		case errors.Is(err, errConsoleLogMatched):
+			return "", err
+		case err != nil:
+			lastErr = err
+		case check.Type == constants.HealthCheckTypeConsoleLog && attempt < attempts:
+			// keep watching the console until the retry budget is used up
+			lastErr = nil
+		default:
+			return fmt.Sprintf("%s (attempt %d of %d)", message, attempt, attempts), nil
+		}
+	}
+	return "", errors.Wrapf(lastErr, "after %d attempts", attempts)
+}
+
+// probeHealthCheck makes a single attempt of a health check
+func (migobj *Migrate) probeHealthCheck(ctx context.Context, check vjailbreakv1alpha1.HealthCheck, serverID string, ips []string) (string, error) {
+	switch check.Type {
+	case constants.HealthCheckTypeTCP:
+		return probeTCP(ctx, ips, check.Port)
+	case constants.HealthCheckTypeHTTP, constants.HealthCheckTypeHTTPS:
+		return probeHTTP(ctx, check, ips)
+	case constants.HealthCheckTypeConsoleLog:
+		return migobj.probeConsoleLog(ctx, check, serverID)
+	default:
+		return "", errors.Errorf("unsupported health check type %q", check.Type)
+	}
+}
+
+// probeTCP connects to the port on every IP address of the target VM
+func probeTCP(ctx context.Context, ips []string, port int) (string, error) {
+	if len(ips) == 0 {
+		return "", errors.New("target VM has no IP address")
+	}
+	var dialer net.Dialer
+	for _, ip := range ips {
+		address := net.JoinHostPort(ip, strconv.Itoa(port))
+		conn, err := dialer.DialContext(ctx, "tcp", address)
+		if err != nil {
+			return "", errors.Wrapf(err, "could not connect to %s", address)
+		}
+		conn.Close()
+	}
+	return fmt.Sprintf("port %d is open on %s", port, strings.Join(ips, ", ")), nil
+}
+
+// probeHTTP gets the path from every IP address of the target VM and checks the status and body
+// of the responses. Certificates are not verified, the target VM is addressed by IP.
+func probeHTTP(ctx context.Context, check vjailbreakv1alpha1.HealthCheck, ips []string) (string, error) {
+	if len(ips) == 0 {
+		return "", errors.New("target VM has no IP address")
+	}
+	expectedStatus := check.ExpectedStatus
+	if expectedStatus == 0 {
+		expectedStatus = http.StatusOK
+	}
+	var bodyRegex *regexp.Regexp
+	if check.BodyRegex != "" {
+		var err error
+		if bodyRegex, err = regexp.Compile(check.BodyRegex); err != nil {
+			return "", errors.Wrap(err, "invalid body regex")
+		}
+	}
+	path := check.Path
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	vjbNet := netutils.NewVjbNet()
+	vjbNet.Insecure = true
+	if err := vjbNet.CreateSecureHTTPClient(); err != nil {
+		return "", errors.Wrap(err, "failed to create HTTP client")
+	}
+	client := vjbNet.GetClient()
+
+	scheme := strings.ToLower(check.Type)
+	for _, ip := range ips {
+		url := fmt.Sprintf("%s://%s%s", scheme, net.JoinHostPort(ip, strconv.Itoa(check.Port)), path)
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return "", errors.Wrapf(err, "invalid request for %s", url)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return "", errors.Wrapf(err, "GET %s", url)
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return "", errors.Wrapf(err, "could not read the response of %s", url)
+		}
+		if resp.StatusCode != expectedStatus {
+			return "", errors.Errorf("GET %s returned %d, expected %d", url, resp.StatusCode, expectedStatus)
+		}
+		if bodyRegex != nil && !bodyRegex.Match(body) {
+			return "", errors.Errorf("response of %s does not match %q", url, check.BodyRegex)
+		}
+	}
+	return fmt.Sprintf("GET %s returned %d on %s", path, expectedStatus, strings.Join(ips, ", ")), nil
+}
+
+// probeConsoleLog searches the end of the console log of the target VM for the failure pattern
+func (migobj *Migrate) probeConsoleLog(ctx context.Context, check vjailbreakv1alpha1.HealthCheck, serverID string) (string, error) {
+	if check.Pattern == "" {
+		return "", errors.New("console log check has no pattern")
+	}
+	pattern, err := regexp.Compile(check.Pattern)
+	if err != nil {
+		return "", errors.Wrap(err, "invalid console log pattern")
+	}
+	output, err := migobj.Openstackclients.GetConsoleOutput(ctx, serverID, constants.HealthCheckConsoleLogLines)
+	if err != nil {
+		return "", err
+	}
+	if match := pattern.FindString(output); match != "" {
+		return "", errors.Wrapf(errConsoleLogMatched, "found %q", match)
+	}
+	return fmt.Sprintf("console log does not match %q", check.Pattern), nil
+}
@@ -0,0 +1,123 @@
+// Copyright © 2024 The vjailbreak authors
+
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+	"github.com/platform9/vjailbreak/v2v-helper/pkg/constants"
+	"github.com/platform9/vjailbreak/v2v-helper/pkg/k8sutils"
+	"github.com/platform9/vjailbreak/v2v-helper/pkg/utils"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// ErrHookFailed is returned when a hook with the Block failure policy did not succeed
+var ErrHookFailed = errors.New("hook did not succeed")
+
+// RunHooks asks the controller to run the hooks of a stage as Kubernetes Jobs and waits for them.
+// The hooks of a stage run in parallel. It returns an error wrapping ErrHookFailed when a hook
+// with the Block failure policy did not succeed, hooks of the OnFailure stage never block.
+func (migobj *Migrate) RunHooks(ctx context.Context, stage string) error {
+	var hooks []vjailbreakv1alpha1.MigrationHook
+	for _, hook := range migobj.Hooks {
+		if hook.Stage == stage {
+			hooks = append(hooks, hook)
+		}
+	}
+	if len(hooks) == 0 {
+		return nil
+	}
+	vmK8sName, err := k8sutils.GetVMwareMachineName()
+	if err != nil {
+		return errors.Wrap(err, "failed to get vmware machine name")
+	}
+
+	migobj.logMessage(fmt.Sprintf("%sstage %s started, waiting for %d hooks", constants.EventMessageHook, stage, len(hooks)))
+	if err := k8sutils.RequestHookStage(ctx, migobj.K8sClient, vmK8sName, stage, migobj.targetServerID, migobj.targetIPs); err != nil {
+		return errors.Wrapf(err, "failed to request %s hooks", stage)
+	}
+
+	var blocking []string
+	for _, hook := range hooks {
+		message, err := migobj.waitForHook(ctx, vmK8sName, hook)
+		if err != nil {
+			migobj.logMessage(fmt.Sprintf("%s%s at %s did not succeed: %v", constants.EventMessageHook, hook.Name, stage, err))
+			if stage != vjailbreakv1alpha1.HookStageOnFailure && hook.FailurePolicy != vjailbreakv1alpha1.HookFailurePolicyWarn {
+				blocking = append(blocking, hook.Name)
+			}
+			continue
+		}
+		migobj.logMessage(fmt.Sprintf("%s%s at %s succeeded: %s", constants.EventMessageHook, hook.Name, stage, message))
+	}
+	if len(blocking) > 0 {
+		return errors.Wrapf(ErrHookFailed, "%s hooks %s", stage, strings.Join(blocking, ", "))
+	}
+	return nil
+}
+
+// RunFailureHooks runs the hooks of the OnFailure stage after the migration of the VM failed
+func (migobj *Migrate) RunFailureHooks(ctx context.Context) {
+	if err := migobj.RunHooks(ctx, vjailbreakv1alpha1.HookStageOnFailure); err != nil {
+		utils.PrintLog(fmt.Sprintf("Could not run the OnFailure hooks: %v", err))
+	}
+}
+
+// waitForHook waits until the Job of a hook completed or failed. The Job stops itself after the
+// timeout of the hook, the controller gets HookStartTimeout on top of it to create the Job.
+func (migobj *Migrate) waitForHook(ctx context.Context, vmK8sName string, hook vjailbreakv1alpha1.MigrationHook) (string, error) {
+	timeoutSeconds := hook.TimeoutSeconds
+	if timeoutSeconds <= 0 {
+		timeoutSeconds = constants.DefaultHookTimeoutSeconds
+	}
+	deadline := time.Now().Add(time.Duration(timeoutSeconds)*time.Second + constants.HookStartTimeout)
+
+	ticker := time.NewTicker(constants.HookPollInterval)
+	defer ticker.Stop()
+	for {
+		job, err := k8sutils.GetHookJob(ctx, migobj.K8sClient, vmK8sName, hook.Stage, hook.Name)
+		if err != nil {
+			utils.PrintLog(fmt.Sprintf("Could not check the job of hook %s: %v", hook.Name, err))
+		} else if job != nil {
+			if message, done, err := hookJobResult(job); done {
+				return message, err
+			}
+		}
+		if time.Now().After(deadline) {
+			if job == nil {
+				return "", errors.New("no job was started for the hook")
+			}
+			return "", errors.Errorf("job %s did not finish in time", job.Name)
+		}
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// hookJobResult returns the outcome of a hook Job, done is false while the Job is running
+func hookJobResult(job *batchv1.Job) (message string, done bool, err error) {
+	for _, condition := range job.Status.Conditions {
+		if condition.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch condition.Type {
+		case batchv1.JobComplete:
+			return fmt.Sprintf("job %s completed", job.Name), true, nil
+		case batchv1.JobFailed:
+			reason := condition.Reason
+			if condition.Message != "" {
+				reason = fmt.Sprintf("%s: %s", reason, condition.Message)
+			}
+			return "", true, errors.Errorf("job %s failed: %s", job.Name, reason)
+		}
+	}
+	return "", false, nil
+}
@@ -0,0 +1,71 @@
+// Copyright © 2024 The vjailbreak authors
+
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/platform9/vjailbreak/v2v-helper/pkg/constants"
+	"github.com/platform9/vjailbreak/v2v-helper/pkg/k8sutils"
+)
+
+// maintenanceWindowPollInterval is how often a waiting migration reads its maintenance window
+// again, so that changes to the window apply to migrations already waiting for it
+const maintenanceWindowPollInterval = time.Minute
+
+// waitForMaintenanceWindow blocks until the maintenance window name is open. waitingMessage is
+// reported once when the window is closed. It returns whether the window was closed.
+func (migobj *Migrate) waitForMaintenanceWindow(ctx context.Context, name, waitingMessage string) (bool, error) {
+	if name == "" || migobj.K8sClient == nil {
+		return false, nil
+	}
+	waited := false
+	for {
+		calendar, err := k8sutils.GetMaintenanceCalendar(ctx, migobj.K8sClient, name)
+		if err != nil {
+			return waited, errors.Wrap(err, "failed to get maintenance window")
+		}
+		now := time.Now()
+		if open, closes := calendar.OpenAt(now); open {
+			if waited {
+				migobj.logMessage(fmt.Sprintf("Maintenance window %s is open until %s", name, closes.Format(time.RFC3339)))
+			}
+			return waited, nil
+		}
+
+		wait := maintenanceWindowPollInterval
+		next, ok := calendar.NextOpen(now)
+		if !waited {
+			message := fmt.Sprintf("%s: maintenance window %s is closed", waitingMessage, name)
+			if ok {
+				message = fmt.Sprintf("%s, it opens at %s", message, next.Format(time.RFC3339))
+			}
+			migobj.logMessage(message)
+			waited = true
+		}
+		if ok && time.Until(next) < wait {
+			wait = max(time.Until(next), time.Second)
+		}
+		select {
+		case <-ctx.Done():
+			return waited, ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// waitForDataCopyWindow blocks until the data copy window of the migration plan is open
+func (migobj *Migrate) waitForDataCopyWindow(ctx context.Context) error {
+	_, err := migobj.waitForMaintenanceWindow(ctx, migobj.MigrationTimes.DataCopyWindow, constants.EventMessageWaitingForDataCopyStart)
+	return err
+}
+
+// waitForCutoverWindow blocks until the cutover window of the migration plan is open. It is checked
+// before the source VM is shut down, and returns whether the window was closed so that a hot
+// migration copies the blocks changed while it waited.
+func (migobj *Migrate) waitForCutoverWindow(ctx context.Context) (bool, error) {
+	return migobj.waitForMaintenanceWindow(ctx, migobj.MigrationTimes.CutoverWindow, constants.EventMessageWaitingForCutOverStart)
+}
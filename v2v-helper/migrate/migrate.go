@@ -10,15 +10,20 @@ import (
 	"os"
 	"os/signal"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/gophercloud/gophercloud/v2/openstack/compute/v2/flavors"
+	"github.com/gophercloud/gophercloud/v2/openstack/compute/v2/servers"
 	"github.com/pkg/errors"
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
 	"github.com/platform9/vjailbreak/pkg/vpwned/sdk/storage"
 	_ "github.com/platform9/vjailbreak/pkg/vpwned/sdk/storage/providers"
+	"github.com/platform9/vjailbreak/v2v-helper/filesource"
 	"github.com/platform9/vjailbreak/v2v-helper/nbd"
 	"github.com/platform9/vjailbreak/v2v-helper/openstack"
 	"github.com/platform9/vjailbreak/v2v-helper/pkg/constants"
@@ -82,12 +87,57 @@ type Migrate struct {
 	StorageProvider   storage.StorageProvider
 	ESXiSSHPrivateKey []byte
 	ESXiSSHSecretName string // Name of the Kubernetes secret containing ESXi SSH private key
+	// SourceIsESXi is true when the source is a standalone ESXi host instead of a vCenter server
+	SourceIsESXi bool
+	// esxiSSHPowerOff powers off a VM of a standalone ESXi host over SSH, powerOffOverSSH when nil
+	esxiSSHPowerOff func(ctx context.Context, vmID string) error
+	// FileSource is the file the VM is imported from instead of a VMware VM, VMops is nil then.
+	// NetworkMapping maps the networks of its NICs to OpenStack networks.
+	FileSource     *filesource.Source
+	NetworkMapping map[string]string
+	// Destination is where the disks are written and the target VM is created, OpenStack when nil
+	Destination Destination
+	// Checkpoint is the persisted disk replication state, nil when checkpointing is disabled
+	Checkpoint       *k8sutils.MigrationCheckpoint
+	checkpointVMName string
+	// checkpointLock serializes checkpoint updates from disks copied in parallel
+	checkpointLock sync.Mutex
+	// DiskCopyParallelism is the number of disks copied in parallel, 0 uses the vjailbreak-settings value
+	DiskCopyParallelism int
+	// VerifyMode enables hashing the copied data against the source snapshot, empty disables it
+	VerifyMode          string
+	VerifySamplePercent int
+	// RollbackPolicy is Automatic, Manual or empty when a failed cutover is not rolled back
+	RollbackPolicy string
+	// State of the source VM before the migration and the target server, restored and deleted on rollback
+	sourcePowerState types.VirtualMachinePowerState
+	targetServerID   string
+	targetIPs        []string
+	// HealthChecks replace the ping and HTTP checks when set, HealthCheckPolicy is Warn, Fail or Rollback
+	HealthChecks      []vjailbreakv1alpha1.HealthCheck
+	HealthCheckPolicy string
+	// Hooks are run as Kubernetes Jobs at the stages of the migration
+	Hooks []vjailbreakv1alpha1.MigrationHook
+	// SnapshotConsistency quiesces the guest for the snapshot of the final sync when set
+	SnapshotConsistency *vjailbreakv1alpha1.SnapshotConsistency
+	// guestScriptRunner runs the scripts of SnapshotConsistency in the guest, runGuestScript when nil
+	guestScriptRunner func(ctx context.Context, script *vjailbreakv1alpha1.GuestScript) error
+	// testBoot is the test boot currently on the target, testBootLock guards it
+	testBoot     *testBoot
+	testBootLock sync.Mutex
+	// targetVolumesLock is held by the periodic sync while it writes the target volumes, and by
+	// test boots while they clone them
+	targetVolumesLock sync.RWMutex
 }
 
 type MigrationTimes struct {
 	DataCopyStart  time.Time
 	VMCutoverStart time.Time
 	VMCutoverEnd   time.Time
+	// DataCopyWindow and CutoverWindow are the names of the maintenance windows the data copy and
+	// the cutover wait for, empty when there is none
+	DataCopyWindow string
+	CutoverWindow  string
 }
 
 type PeriodicSyncStates int
@@ -126,74 +176,49 @@ func (migobj *Migrate) logMessage(message string) {
 
 // This function creates volumes in OpenStack and attaches them to the helper vm
 func (migobj *Migrate) CreateVolumes(ctx context.Context, vminfo vm.VMInfo) (vm.VMInfo, error) {
-	openstackops := migobj.Openstackclients
+	destination := migobj.destination()
 	migobj.logMessage("Creating volumes in OpenStack")
 
 	for idx, vmdisk := range vminfo.VMDisks {
-		setRDMLabel := false
-		if len(vminfo.RDMDisks) > 0 {
-			setRDMLabel = true
+		if volume := migobj.adoptVolume(ctx, vmdisk); volume != nil {
+			vminfo.VMDisks[idx].OpenstackVol = volume
+			continue
 		}
-		volume, err := openstackops.CreateVolume(ctx, vminfo.Name+"-"+vmdisk.Name, vmdisk.Size, vminfo.OSType, vminfo.UEFI, migobj.Volumetypes[idx], setRDMLabel)
+		volume, err := destination.CreateDisk(ctx, vminfo, idx, migobj.Volumetypes[idx])
 		if err != nil {
-			return vminfo, errors.Wrap(err, "failed to create volume")
+			return vminfo, err
 		}
 		vminfo.VMDisks[idx].OpenstackVol = volume
 		if vminfo.VMDisks[idx].Boot {
-			err = openstackops.SetVolumeBootable(ctx, volume)
+			err = destination.SetBootDisk(ctx, vminfo.VMDisks[idx])
 			if err != nil {
 				return vminfo, errors.Wrap(err, "failed to set volume as bootable")
 			}
 		}
+		migobj.checkpointVolume(ctx, vminfo.VMDisks[idx])
 	}
 	migobj.logMessage("Volumes created successfully")
 	return vminfo, nil
 }
 
 func (migobj *Migrate) AttachVolume(ctx context.Context, disk vm.VMDisk) (string, error) {
-	openstackops := migobj.Openstackclients
 	migobj.logMessage(fmt.Sprintf("Attaching volumes to VM: %s", disk.Name))
 	if disk.OpenstackVol == nil {
 		return "", errors.Wrap(fmt.Errorf("OpenStack volume is nil"), "failed to attach volume to VM")
 	}
-	volumeID := disk.OpenstackVol.ID
-	if err := openstackops.AttachVolumeToVM(ctx, volumeID); err != nil {
-		return "", errors.Wrap(err, "failed to attach volume to VM")
-	}
-
-	// Get the Path of the attached volume
-	devicePath, err := openstackops.FindDevice(volumeID)
-	if err != nil {
-		return "", errors.Wrap(err, "failed to find device")
-	}
-	return devicePath, nil
+	return migobj.destination().AttachDisk(ctx, disk)
 }
 
 func (migobj *Migrate) DetachVolume(ctx context.Context, disk vm.VMDisk) error {
-	openstackops := migobj.Openstackclients
-
-	if err := openstackops.DetachVolumeFromVM(ctx, disk.OpenstackVol.ID); err != nil {
-		return errors.Wrap(err, "failed to detach volume from VM")
-	}
-
-	err := openstackops.WaitForVolume(ctx, disk.OpenstackVol.ID)
-	if err != nil {
-		return errors.Wrap(err, "failed to wait for volume to become available")
-	}
-	return nil
+	return migobj.destination().DetachDisk(ctx, disk)
 }
 
 func (migobj *Migrate) DetachAllVolumes(ctx context.Context, vminfo vm.VMInfo) error {
-	openstackops := migobj.Openstackclients
+	destination := migobj.destination()
 	for _, vmdisk := range vminfo.VMDisks {
 		migobj.logMessage(fmt.Sprintf("Detaching volume %s from VM", vmdisk.Name))
-		if err := openstackops.DetachVolumeFromVM(ctx, vmdisk.OpenstackVol.ID); err != nil && !strings.Contains(err.Error(), "is not attached to volume") {
-			return errors.Wrap(err, "failed to detach volume from VM")
-		}
-
-		err := openstackops.WaitForVolume(ctx, vmdisk.OpenstackVol.ID)
-		if err != nil {
-			return errors.Wrap(err, "failed to wait for volume to become available")
+		if err := destination.DetachDisk(ctx, vmdisk); err != nil && !strings.Contains(err.Error(), "is not attached to volume") {
+			return err
 		}
 		migobj.logMessage(fmt.Sprintf("Volume %s detached from VM", vmdisk.Name))
 	}
@@ -202,11 +227,10 @@ func (migobj *Migrate) DetachAllVolumes(ctx context.Context, vminfo vm.VMInfo) e
 }
 
 func (migobj *Migrate) DeleteAllVolumes(ctx context.Context, vminfo vm.VMInfo) error {
-	openstackops := migobj.Openstackclients
+	destination := migobj.destination()
 	for _, vmdisk := range vminfo.VMDisks {
-		err := openstackops.DeleteVolume(ctx, vmdisk.OpenstackVol.ID)
-		if err != nil {
-			return errors.Wrap(err, "failed to delete volume")
+		if err := destination.DeleteDisk(ctx, vmdisk); err != nil {
+			return err
 		}
 		migobj.logMessage(fmt.Sprintf("Volume %s deleted", vmdisk.Name))
 	}
@@ -289,6 +313,8 @@ func (migobj *Migrate) EnableCBTWrapper() error {
 	migobj.logMessage(fmt.Sprintf("CBT Enabled: %t", cbt))
 
 	if !cbt {
+		// Change IDs recorded before CBT was (re-)enabled are meaningless
+		migobj.resetCheckpointCopyState(context.Background())
 		// 7.5. Enable CBT
 		migobj.logMessage("CBT is not enabled. Enabling CBT")
 		err = vmops.EnableCBT()
@@ -315,7 +341,7 @@ func (migobj *Migrate) EnableCBTWrapper() error {
 	return nil
 }
 
-func (migobj *Migrate) WaitforCutover() error {
+func (migobj *Migrate) WaitforCutover(ctx context.Context) error {
 	var zerotime time.Time
 	if !migobj.MigrationTimes.VMCutoverStart.Equal(zerotime) && migobj.MigrationTimes.VMCutoverStart.After(time.Now()) {
 		migobj.logMessage("Waiting for VM Cutover start time")
@@ -326,6 +352,9 @@ func (migobj *Migrate) WaitforCutover() error {
 			return errors.New("VM Cutover End time has already passed")
 		}
 	}
+	if _, err := migobj.waitForCutoverWindow(ctx); err != nil {
+		return errors.Wrap(err, "failed to wait for cutover window")
+	}
 	return nil
 }
 func (migobj *Migrate) SyncCBT(ctx context.Context, vminfo vm.VMInfo) error {
@@ -468,6 +497,10 @@ func (migobj *Migrate) WaitforAdminCutover(ctx context.Context, vminfo vm.VMInfo
 	vmops := migobj.VMops
 	maxRetries, capInterval = utils.GetRetryLimits()
 	migobj.logMessage(constants.EventMessageWaitingForAdminCutOver)
+	// Test boots are only run while waiting, the test server is removed before the cutover
+	testBootTicker := time.NewTicker(constants.TestBootPollInterval)
+	defer testBootTicker.Stop()
+	defer migobj.removeTestBoot(context.WithoutCancel(ctx))
 	elapsed := time.Duration(0)
 	for {
 		syncEnabled := migobj.getSyncEnabled()
@@ -478,10 +511,12 @@ func (migobj *Migrate) WaitforAdminCutover(ctx context.Context, vminfo vm.VMInfo
 		case <-ctx.Done():
 			return ctx.Err()
 		case label := <-migobj.PodLabelWatcher:
-			if label == "yes" && currentState == initial {
+			if label == "yes" && currentState == initial && !migobj.isCutoverBlocked(ctx) {
 				migobj.logMessage("Admin cutover triggered")
 				return nil
 			}
+		case <-testBootTicker.C:
+			migobj.reconcileTestBoot(ctx, vminfo)
 		default:
 			if !syncEnabled {
 				continue
@@ -493,13 +528,25 @@ func (migobj *Migrate) WaitforAdminCutover(ctx context.Context, vminfo vm.VMInfo
 			// Otherwise wait remaining time
 			waitTime := syncInterval - elapsed
 			migobj.logMessage(fmt.Sprintf("Periodic Sync: Waiting %s before next sync cycle", waitTime))
-			select {
-			case <-ctx.Done():
-				return ctx.Err()
-			case <-migobj.PodLabelWatcher:
-				return nil // admin triggered cutover during wait
-			case <-time.After(waitTime):
-				// wait completed → loop and sync again
+			syncTimer := time.NewTimer(waitTime)
+		wait:
+			for {
+				select {
+				case <-ctx.Done():
+					syncTimer.Stop()
+					return ctx.Err()
+				case <-migobj.PodLabelWatcher:
+					if migobj.isCutoverBlocked(ctx) {
+						continue
+					}
+					syncTimer.Stop()
+					return nil // admin triggered cutover during wait
+				case <-testBootTicker.C:
+					migobj.reconcileTestBoot(ctx, vminfo)
+				case <-syncTimer.C:
+					// wait completed → loop and sync again
+					break wait
+				}
 			}
 			// Perform sync
 			migobj.logMessage(fmt.Sprintf("Periodic Sync: Starting sync cycle (interval: %s)", syncInterval))
@@ -527,7 +574,10 @@ func (migobj *Migrate) WaitforAdminCutover(ctx context.Context, vminfo vm.VMInfo
 				}
 			}
 			if currentState == TookSnapshot {
-				if err := migobj.SyncCBT(ctx, vminfo); err != nil {
+				migobj.targetVolumesLock.Lock()
+				err := migobj.SyncCBT(ctx, vminfo)
+				migobj.targetVolumesLock.Unlock()
+				if err != nil {
 					migobj.logMessage(fmt.Sprintf("Periodic Sync: Failed to sync Changed Block Tracking (CBT): %v", err))
 					currentState = initial // Reset state on failure so we retry from start next loop
 					continue
@@ -539,6 +589,30 @@ func (migobj *Migrate) WaitforAdminCutover(ctx context.Context, vminfo vm.VMInfo
 	}
 }
 
+// isCutoverBlocked reports whether the cutover of the VM still waits for VMs it depends on. The
+// controller resets the cutover label while the cutover is blocked, a cutover triggered before
+// that is ignored.
+func (migobj *Migrate) isCutoverBlocked(ctx context.Context) bool {
+	if migobj.K8sClient == nil {
+		return false
+	}
+	vmK8sName, err := k8sutils.GetVMwareMachineName()
+	if err != nil {
+		utils.PrintLog(fmt.Sprintf("Could not check the dependencies of the cutover: %v", err))
+		return false
+	}
+	blockers, err := k8sutils.GetCutoverBlockers(ctx, migobj.K8sClient, vmK8sName)
+	if err != nil {
+		utils.PrintLog(fmt.Sprintf("Could not check the dependencies of the cutover: %v", err))
+		return false
+	}
+	if len(blockers) == 0 {
+		return false
+	}
+	migobj.logMessage(fmt.Sprintf("Ignoring cutover request, the cutover waits for %s", strings.Join(blockers, ", ")))
+	return true
+}
+
 func (migobj *Migrate) CheckIfAdminCutoverSelected() bool {
 	if migobj.Reporter == nil {
 		return false
@@ -579,6 +653,9 @@ func (migobj *Migrate) LiveReplicateDisks(ctx context.Context, vminfo vm.VMInfo)
 	thumbprint := migobj.Thumbprint
 
 	cutoverLabelPresent, cutoverLabelValue := migobj.CheckCutoverOptions()
+	// finalSnapshotTaken is set when the quiesced snapshot of the final sync was taken before the
+	// source VM was shut down. It is kept instead of being replaced by a crash consistent one.
+	finalSnapshotTaken := false
 	// if the cutover immediately is selected with cold migration type then the migration will happen like cold migration
 	var currentCutoverOption string
 	if migobj.MigrationType == "cold" {
@@ -591,8 +668,23 @@ func (migobj *Migrate) LiveReplicateDisks(ctx context.Context, vminfo vm.VMInfo)
 			}
 			migobj.logMessage(fmt.Sprintf("Migration Type : %s | Cutover Option %s", migobj.MigrationType, currentCutoverOption))
 		}
-		if err := vmops.VMPowerOff(); err != nil {
-			return vminfo, errors.Wrap(err, "failed to power off VM")
+		// A cold migration shuts the source VM down before the data copy
+		if _, err := migobj.waitForCutoverWindow(ctx); err != nil {
+			return vminfo, errors.Wrap(err, "failed to wait for cutover window")
+		}
+		if err := migobj.RunHooks(ctx, vjailbreakv1alpha1.HookStagePreCutover); err != nil {
+			return vminfo, err
+		}
+		taken, err := migobj.takeFinalSnapshot(ctx)
+		if err != nil {
+			return vminfo, errors.Wrap(err, "failed to take final snapshot")
+		}
+		finalSnapshotTaken = taken
+		// The source VM is powered off already when the quiesced snapshot was taken
+		if !finalSnapshotTaken {
+			if err := migobj.powerOffSourceVM(ctx); err != nil {
+				return vminfo, errors.Wrap(err, "failed to power off VM")
+			}
 		}
 		// Verify VM is actually powered off
 		if err := utils.DoRetryWithExponentialBackoff(ctx, func() error {
@@ -609,23 +701,27 @@ func (migobj *Migrate) LiveReplicateDisks(ctx context.Context, vminfo vm.VMInfo)
 		}
 	}
 
-	// clean up snapshots
-	utils.PrintLog("Cleaning up snapshots before copy")
-	err := vmops.CleanUpSnapshots(false)
-	if err != nil {
-		return vminfo, errors.Wrap(err, "failed to clean up snapshots: %s, please delete manually before starting again")
-	}
+	var err error
+	if !finalSnapshotTaken {
+		// clean up snapshots
+		utils.PrintLog("Cleaning up snapshots before copy")
+		err = vmops.CleanUpSnapshots(false)
+		if err != nil {
+			return vminfo, errors.Wrap(err, "failed to clean up snapshots: %s, please delete manually before starting again")
+		}
 
-	utils.PrintLog("Starting NBD server")
-	err = vmops.TakeSnapshot(constants.MigrationSnapshotName)
-	if err != nil {
-		return vminfo, errors.Wrap(err, "failed to take snapshot of source VM")
+		err = vmops.TakeSnapshot(constants.MigrationSnapshotName)
+		if err != nil {
+			return vminfo, errors.Wrap(err, "failed to take snapshot of source VM")
+		}
 	}
 
+	utils.PrintLog("Starting NBD server")
 	err = vmops.UpdateDisksInfo(&vminfo)
 	if err != nil {
 		return vminfo, errors.Wrap(err, "failed to update disk info")
 	}
+	migobj.restoreChangeIDs(&vminfo)
 
 	for idx, vmdisk := range vminfo.VMDisks {
 		migobj.logMessage(fmt.Sprintf("Copying disk %d, Completed: 0%%", idx))
@@ -658,6 +754,8 @@ func (migobj *Migrate) LiveReplicateDisks(ctx context.Context, vminfo vm.VMInfo)
 		return vminfo, errors.Wrap(err, "failed to get vcenter settings")
 	}
 	utils.PrintLog(fmt.Sprintf("Fetched vjailbreak settings for Changed Blocks Copy Iteration Threshold: %d", vcenterSettings.ChangedBlocksCopyIterationThreshold))
+	parallelism := migobj.diskCopyParallelism(vcenterSettings.DiskCopyParallelism, len(vminfo.VMDisks))
+	migobj.logMessage(fmt.Sprintf("Copying up to %d disks in parallel", parallelism))
 
 	// Check if migration has admin cutover if so don't copy any more changed blocks
 	adminInitiatedCutover := cutoverLabelPresent && (cutoverLabelValue == "no")
@@ -665,25 +763,34 @@ func (migobj *Migrate) LiveReplicateDisks(ctx context.Context, vminfo vm.VMInfo)
 	for {
 		// If its the first copy, copy the entire disk
 		if incrementalCopyCount == 0 {
-			for idx := range vminfo.VMDisks {
+			err = forEachDisk(ctx, len(vminfo.VMDisks), parallelism, func(ctx context.Context, idx int) error {
 				startTime := time.Now()
 				disk := vminfo.VMDisks[idx]
 
+				if migobj.fullCopyCompleted(disk) {
+					migobj.logMessage(fmt.Sprintf("Disk %d (%s) was fully copied before the restart, skipping full copy", idx, disk.Name))
+					return nil
+				}
+
 				migobj.logMessage(fmt.Sprintf("Starting full disk copy [%d/%d]: %s (DeviceKey=%d)",
 					idx+1, len(vminfo.VMDisks), disk.Name, disk.Disk.Key))
 				migobj.logMessage(fmt.Sprintf("  Source: %s", extractFileName(disk.SnapBackingDisk)))
 				migobj.logMessage(fmt.Sprintf("  Target: %s (Volume ID: %s)", disk.Path, disk.OpenstackVol.ID))
 
-				err = nbdops[idx].CopyDisk(ctx, disk.Path, idx)
-				if err != nil {
-					return vminfo, errors.Wrap(err, fmt.Sprintf("failed to copy disk %s (DeviceKey=%d)", disk.Name, disk.Disk.Key))
+				if err := nbdops[idx].CopyDisk(ctx, disk.Path, idx); err != nil {
+					return errors.Wrap(err, fmt.Sprintf("failed to copy disk %s (DeviceKey=%d)", disk.Name, disk.Disk.Key))
 				}
+				migobj.checkpointChangeID(ctx, disk)
 				duration := time.Since(startTime)
 				if migobj.MigrationType == "cold" {
 					migobj.logMessage(fmt.Sprintf("✓ Disk %d (%s) copied successfully in %s", idx, disk.Name, duration))
 				} else {
 					migobj.logMessage(fmt.Sprintf("✓ Disk %d (%s) copied successfully in %s, copying changed blocks now", idx, disk.Name, duration))
 				}
+				return nil
+			})
+			if err != nil {
+				return vminfo, err
 			}
 
 			if adminInitiatedCutover {
@@ -691,10 +798,22 @@ func (migobj *Migrate) LiveReplicateDisks(ctx context.Context, vminfo vm.VMInfo)
 				if err := migobj.WaitforAdminCutover(ctx, vminfo); err != nil {
 					return vminfo, errors.Wrap(err, "failed to start VM Cutover")
 				}
-				utils.PrintLog("Shutting down source VM and performing final copy")
-				err = vmops.VMPowerOff()
+				if _, err := migobj.waitForCutoverWindow(ctx); err != nil {
+					return vminfo, errors.Wrap(err, "failed to wait for cutover window")
+				}
+				if err := migobj.RunHooks(ctx, vjailbreakv1alpha1.HookStagePreCutover); err != nil {
+					return vminfo, err
+				}
+				finalSnapshotTaken, err = migobj.takeFinalSnapshot(ctx)
 				if err != nil {
-					return vminfo, errors.Wrap(err, "failed to power off VM")
+					return vminfo, errors.Wrap(err, "failed to take final snapshot")
+				}
+				utils.PrintLog("Shutting down source VM and performing final copy")
+				if !finalSnapshotTaken {
+					err = migobj.powerOffSourceVM(ctx)
+					if err != nil {
+						return vminfo, errors.Wrap(err, "failed to power off VM")
+					}
 				}
 				// Verify VM is actually powered off
 				if err := utils.DoRetryWithExponentialBackoff(ctx, func() error {
@@ -710,7 +829,7 @@ func (migobj *Migrate) LiveReplicateDisks(ctx context.Context, vminfo vm.VMInfo)
 					return vminfo, errors.Wrap(err, "failed to verify VM power state after power off")
 				}
 			}
-			if err := migobj.WaitforCutover(); err != nil {
+			if err := migobj.WaitforCutover(ctx); err != nil {
 				return vminfo, errors.Wrap(err, "failed to start VM Cutover")
 			}
 		} else {
@@ -719,94 +838,130 @@ func (migobj *Migrate) LiveReplicateDisks(ctx context.Context, vminfo vm.VMInfo)
 				return vminfo, errors.Wrap(err, "failed to get snapshot")
 			}
 
-			var changedAreas types.DiskChangeInfo
+			// vmLock serializes vCenter calls and updates of vminfo made by disks copied in parallel
+			var vmLock sync.Mutex
 			done := true
 
-			for idx := range vminfo.VMDisks {
+			err = forEachDisk(ctx, len(vminfo.VMDisks), parallelism, func(ctx context.Context, idx int) error {
+				vmLock.Lock()
 				err := vmops.UpdateDiskInfo(&vminfo, vminfo.VMDisks[idx], false)
 				if err != nil {
-					return vminfo, errors.Wrap(err, "failed to update disk info")
+					vmLock.Unlock()
+					return errors.Wrap(err, "failed to update disk info")
 				}
 
-				changedAreas, err = vmops.CustomQueryChangedDiskAreas(vminfo.VMDisks[idx].ChangeID, migration_snapshot, vminfo.VMDisks[idx].Disk, 0)
+				changedAreas, err := vmops.CustomQueryChangedDiskAreas(vminfo.VMDisks[idx].ChangeID, migration_snapshot, vminfo.VMDisks[idx].Disk, 0)
+				disk := vminfo.VMDisks[idx]
+				vmLock.Unlock()
 				if err != nil {
-					return vminfo, errors.Wrap(err, "failed to get changed disk areas")
+					return errors.Wrap(err, "failed to get changed disk areas")
 				}
 
 				if len(changedAreas.ChangedArea) == 0 {
 					if migobj.MigrationType != "cold" {
 						migobj.logMessage(fmt.Sprintf("Disk %d: No changed blocks found. Skipping copy", idx))
 					}
-				} else {
-					migobj.logMessage(fmt.Sprintf("Disk %d: Blocks have Changed.", idx))
+					return nil
+				}
+				migobj.logMessage(fmt.Sprintf("Disk %d: Blocks have Changed.", idx))
 
-					utils.PrintLog("Restarting NBD server")
-					err = nbdops[idx].StopNBDServer()
-					if err != nil {
-						return vminfo, errors.Wrap(err, "failed to stop NBD server")
-					}
+				utils.PrintLog("Restarting NBD server")
+				err = nbdops[idx].StopNBDServer()
+				if err != nil {
+					return errors.Wrap(err, "failed to stop NBD server")
+				}
 
-					err = nbdops[idx].StartNBDServer(vmops.GetVMObj(), envURL, envUserName, envPassword, thumbprint, vminfo.VMDisks[idx].Snapname, vminfo.VMDisks[idx].SnapBackingDisk, migobj.EventReporter)
-					if err != nil {
-						return vminfo, errors.Wrap(err, "failed to start NBD server")
-					}
-					// sleep for 2 seconds to allow the NBD server to start
-					time.Sleep(2 * time.Second)
+				err = nbdops[idx].StartNBDServer(vmops.GetVMObj(), envURL, envUserName, envPassword, thumbprint, disk.Snapname, disk.SnapBackingDisk, migobj.EventReporter)
+				if err != nil {
+					return errors.Wrap(err, "failed to start NBD server")
+				}
+				// sleep for 2 seconds to allow the NBD server to start
+				time.Sleep(2 * time.Second)
 
-					// 11. Copy Changed Blocks over
-					done = false
-					changedBlockCopySuccess := true
-					migobj.logMessage("Copying changed blocks")
+				// 11. Copy Changed Blocks over
+				vmLock.Lock()
+				done = false
+				vmLock.Unlock()
+				changedBlockCopySuccess := true
+				migobj.logMessage("Copying changed blocks")
 
-					// incremental block copy
+				// incremental block copy
 
-					startTime := time.Now()
-					migobj.logMessage(fmt.Sprintf("Starting incremental block copy for disk %d at %s", idx, startTime))
+				startTime := time.Now()
+				migobj.logMessage(fmt.Sprintf("Starting incremental block copy for disk %d at %s", idx, startTime))
 
-					err = nbdops[idx].CopyChangedBlocks(ctx, changedAreas, vminfo.VMDisks[idx].Path)
-					if err != nil {
-						migobj.logMessage(fmt.Sprintf("Failed to copy changed blocks: %v", err))
-						changedBlockCopySuccess = false
-					}
+				err = nbdops[idx].CopyChangedBlocks(ctx, changedAreas, disk.Path)
+				if err != nil {
+					migobj.logMessage(fmt.Sprintf("Failed to copy changed blocks: %v", err))
+					changedBlockCopySuccess = false
+				}
 
-					duration := time.Since(startTime)
+				duration := time.Since(startTime)
 
-					migobj.logMessage(fmt.Sprintf("Incremental block copy for disk %d completed in %s", idx, duration))
+				migobj.logMessage(fmt.Sprintf("Incremental block copy for disk %d completed in %s", idx, duration))
 
-					err = vmops.UpdateDiskInfo(&vminfo, vminfo.VMDisks[idx], changedBlockCopySuccess)
-					if err != nil {
-						return vminfo, errors.Wrap(err, "failed to update disk info")
-					}
-					if !changedBlockCopySuccess {
-						migobj.logMessage(fmt.Sprintf("Failed to copy changed blocks: %s", err))
-						migobj.logMessage(fmt.Sprintf("Since full copy has completed, Retrying copy of changed blocks for disk: %d", idx))
-					}
-					migobj.logMessage(fmt.Sprintf("Finished copying and syncing changed blocks for disk %d in %s [Progress: %d/20]", idx, duration, incrementalCopyCount))
+				vmLock.Lock()
+				err = vmops.UpdateDiskInfo(&vminfo, disk, changedBlockCopySuccess)
+				disk = vminfo.VMDisks[idx]
+				vmLock.Unlock()
+				if err != nil {
+					return errors.Wrap(err, "failed to update disk info")
+				}
+				if changedBlockCopySuccess {
+					migobj.checkpointChangeID(ctx, disk)
 				}
+				if !changedBlockCopySuccess {
+					migobj.logMessage(fmt.Sprintf("Failed to copy changed blocks: %s", err))
+					migobj.logMessage(fmt.Sprintf("Since full copy has completed, Retrying copy of changed blocks for disk: %d", idx))
+				}
+				migobj.logMessage(fmt.Sprintf("Finished copying and syncing changed blocks for disk %d in %s [Progress: %d/20]", idx, duration, incrementalCopyCount))
+				return nil
+			})
+			if err != nil {
+				return vminfo, err
 			}
 			if final {
 				break
 			}
 			if done || incrementalCopyCount > vcenterSettings.ChangedBlocksCopyIterationThreshold {
-				utils.PrintLog("Shutting down source VM and performing final copy")
-				err = vmops.VMPowerOff()
+				// The cutover window can close while the changed blocks are copied. The source VM then
+				// keeps running until the next window and the blocks changed meanwhile are copied first
+				windowClosed, err := migobj.waitForCutoverWindow(ctx)
 				if err != nil {
-					return vminfo, errors.Wrap(err, "failed to power off VM")
+					return vminfo, errors.Wrap(err, "failed to wait for cutover window")
 				}
-				// Verify VM is actually powered off
-				if err := utils.DoRetryWithExponentialBackoff(ctx, func() error {
-					currState, stateErr := vmops.GetVMObj().PowerState(ctx)
-					if stateErr != nil {
-						return stateErr
+				if !windowClosed {
+					if err := migobj.RunHooks(ctx, vjailbreakv1alpha1.HookStagePreCutover); err != nil {
+						return vminfo, err
 					}
-					if currState != types.VirtualMachinePowerStatePoweredOff {
-						return fmt.Errorf("VM power-off command completed but VM is still in state: %s", currState)
+					if !finalSnapshotTaken {
+						finalSnapshotTaken, err = migobj.takeFinalSnapshot(ctx)
+						if err != nil {
+							return vminfo, errors.Wrap(err, "failed to take final snapshot")
+						}
 					}
-					return nil
-				}, constants.MaxPowerOffRetryLimit, constants.PowerOffRetryCap); err != nil {
-					return vminfo, errors.Wrap(err, "failed to verify VM power state after power off")
+					utils.PrintLog("Shutting down source VM and performing final copy")
+					if !finalSnapshotTaken {
+						err = migobj.powerOffSourceVM(ctx)
+						if err != nil {
+							return vminfo, errors.Wrap(err, "failed to power off VM")
+						}
+					}
+					// Verify VM is actually powered off
+					if err := utils.DoRetryWithExponentialBackoff(ctx, func() error {
+						currState, stateErr := vmops.GetVMObj().PowerState(ctx)
+						if stateErr != nil {
+							return stateErr
+						}
+						if currState != types.VirtualMachinePowerStatePoweredOff {
+							return fmt.Errorf("VM power-off command completed but VM is still in state: %s", currState)
+						}
+						return nil
+					}, constants.MaxPowerOffRetryLimit, constants.PowerOffRetryCap); err != nil {
+						return vminfo, errors.Wrap(err, "failed to verify VM power state after power off")
+					}
+					final = true
 				}
-				final = true
 			}
 		}
 
@@ -814,17 +969,24 @@ func (migobj *Migrate) LiveReplicateDisks(ctx context.Context, vminfo vm.VMInfo)
 		// Only do this after you have gone through all disks with old change id.
 		// If you dont, only your first disk will have the updated changes
 
-		err = vmops.CleanUpSnapshots(false)
-		if err != nil {
-			return vminfo, errors.Wrap(err, "failed to cleanup snapshot of source VM")
-		}
-		err = vmops.TakeSnapshot(constants.MigrationSnapshotName)
-		if err != nil {
-			return vminfo, errors.Wrap(err, "failed to take snapshot of source VM")
+		// The quiesced snapshot is the state the final sync copies, writes made after it are not migrated
+		if !finalSnapshotTaken {
+			err = vmops.CleanUpSnapshots(false)
+			if err != nil {
+				return vminfo, errors.Wrap(err, "failed to cleanup snapshot of source VM")
+			}
+			err = vmops.TakeSnapshot(constants.MigrationSnapshotName)
+			if err != nil {
+				return vminfo, errors.Wrap(err, "failed to take snapshot of source VM")
+			}
 		}
 
-		incrementalCopyCount += 1
+		incrementalCopyCount = migobj.checkpointIteration(ctx, incrementalCopyCount+1)
+
+	}
 
+	if err := migobj.VerifyDataIntegrity(ctx, vminfo, parallelism); err != nil {
+		return vminfo, errors.Wrap(err, "failed to verify data integrity")
 	}
 
 	err = migobj.DetachAllVolumes(ctx, vminfo)
@@ -1033,6 +1195,18 @@ func (migobj *Migrate) performDiskConversion(ctx context.Context, vminfo vm.VMIn
 		if err := virtv2v.NTFSFix(vminfo.VMDisks[bootVolumeIndex].Path); err != nil {
 			return errors.Wrap(err, "failed to run ntfsfix")
 		}
+		// Windows keeps static addresses on the VMware adapters, the firstboot script moves them to
+		// the virtio adapters with the same MAC addresses
+		if persisNetwork {
+			if script := virtv2v.GenerateWindowsNetworkScript(vminfo.GuestNetworks, vminfo.GatewayIP, vminfo.IPperMac); script != "" {
+				firstbootscriptname := "windows_network_persistence"
+				if err := virtv2v.AddFirstBootScript(script, firstbootscriptname); err != nil {
+					return errors.Wrap(err, "failed to add first boot script")
+				}
+				firstbootscripts = append(firstbootscripts, firstbootscriptname)
+				utils.PrintLog("Windows network persistence script added successfully")
+			}
+		}
 	}
 
 	// Add first boot scripts for RHEL family
@@ -1067,7 +1241,7 @@ func (migobj *Migrate) performDiskConversion(ctx context.Context, vminfo vm.VMIn
 	}
 
 	// Set volume as bootable
-	if err := migobj.Openstackclients.SetVolumeBootable(ctx, vminfo.VMDisks[bootVolumeIndex].OpenstackVol); err != nil {
+	if err := migobj.destination().SetBootDisk(ctx, vminfo.VMDisks[bootVolumeIndex]); err != nil {
 		return errors.Wrap(err, "failed to set volume as bootable")
 	}
 
@@ -1181,7 +1355,15 @@ func (migobj *Migrate) configureRHELNetwork(vminfo vm.VMInfo, bootVolumeIndex in
 
 func (migobj *Migrate) ConvertVolumes(ctx context.Context, vminfo vm.VMInfo) error {
 	migobj.logMessage("Converting disk")
+	if err := migobj.convertVolumes(ctx, vminfo); err != nil {
+		return err
+	}
+	migobj.logMessage("Successfully converted disk")
+	return nil
+}
 
+// convertVolumes attaches the volumes of vminfo to the helper VM, converts them and detaches them
+func (migobj *Migrate) convertVolumes(ctx context.Context, vminfo vm.VMInfo) error {
 	// Step 1: Determine boot command based on OS type
 	getBootCommand := migobj.getBootCommand(vminfo.OSType)
 
@@ -1253,8 +1435,6 @@ func (migobj *Migrate) ConvertVolumes(ctx context.Context, vminfo vm.VMInfo) err
 	if err := migobj.DetachAllVolumes(ctx, vminfo); err != nil {
 		return errors.Wrap(err, "Failed to detach all volumes from VM")
 	}
-
-	migobj.logMessage("Successfully converted disk")
 	return nil
 }
 
@@ -1304,6 +1484,34 @@ func DetectAndHandleNetwork(diskPath string, osRelease string, vmInfo vm.VMInfo)
 
 func (migobj *Migrate) CreateTargetInstance(ctx context.Context, vminfo vm.VMInfo, networkids, portids []string, ipaddresses []string) error {
 	migobj.logMessage("Creating target instance")
+	instanceID, err := migobj.destination().CreateInstance(ctx, vminfo, networkids, portids)
+	if instanceID != "" {
+		migobj.targetServerID = instanceID
+	}
+	if err != nil {
+		return err
+	}
+
+	// The migration only succeeds once the health checks had their say, only VMs created in
+	// OpenStack are booted by the migration
+	if migobj.Destination == nil {
+		if err := migobj.checkTargetHealth(ctx, instanceID, vminfo, ipaddresses); err != nil {
+			return err
+		}
+	} else {
+		migobj.logMessage("Skipping Health Checks, the VM is not booted at its destination")
+	}
+	if err := migobj.RunHooks(ctx, vjailbreakv1alpha1.HookStagePostCutover); err != nil {
+		return err
+	}
+
+	migobj.logMessage(fmt.Sprintf("VM created successfully: ID: %s", instanceID))
+	return nil
+}
+
+// bootServer creates a server from the volumes of vminfo and waits until it is active. The server
+// is returned as soon as it was created, also when it did not become active.
+func (migobj *Migrate) bootServer(ctx context.Context, vminfo vm.VMInfo, networkids, portids []string) (*servers.Server, error) {
 	openstackops := migobj.Openstackclients
 	var flavor *flavors.Flavor
 	var err error
@@ -1311,29 +1519,32 @@ func (migobj *Migrate) CreateTargetInstance(ctx context.Context, vminfo vm.VMInf
 	if migobj.UseFlavorless {
 		if migobj.TargetFlavorId == "" {
 			err = fmt.Errorf("flavorless creation is enabled, but TargetFlavorId in vmwaremachine %s is empty. Please set it to the ID of your base flavor (e.g., '0-0-x')", vminfo.Name)
-			return errors.Wrap(err, "failed to create target instance")
+			return nil, errors.Wrap(err, "failed to create target instance")
 		}
 		migobj.logMessage(fmt.Sprintf("Using flavorless creation with base flavor ID: %s", migobj.TargetFlavorId))
 		flavor, err = openstackops.GetFlavor(ctx, migobj.TargetFlavorId)
 		if err != nil {
-			return errors.Wrap(err, "failed to get the specified base flavor for flavorless creation")
+			return nil, errors.Wrap(err, "failed to get the specified base flavor for flavorless creation")
 		}
 	} else if migobj.TargetFlavorId != "" {
 		flavor, err = openstackops.GetFlavor(ctx, migobj.TargetFlavorId)
 		if err != nil {
-			return errors.Wrap(err, "failed to get OpenStack flavor")
+			return nil, errors.Wrap(err, "failed to get OpenStack flavor")
 		}
 	} else {
 		flavor, err = openstackops.GetClosestFlavour(ctx, vminfo.CPU, vminfo.Memory)
 		if err != nil {
-			return errors.Wrap(err, "failed to get closest OpenStack flavor")
+			return nil, errors.Wrap(err, "failed to get closest OpenStack flavor")
 		}
 		utils.PrintLog(fmt.Sprintf("Closest OpenStack flavor: %s: CPU: %dvCPUs\tMemory: %dMB\n", flavor.Name, flavor.VCPUs, flavor.RAM))
 	}
+	if err := migobj.applySecurityFeatures(ctx, vminfo); err != nil {
+		return nil, err
+	}
 	// Get security group IDs
 	securityGroupIDs, err := openstackops.GetSecurityGroupIDs(ctx, migobj.SecurityGroups, migobj.TenantName)
 	if err != nil {
-		return errors.Wrap(err, "failed to resolve security group names to IDs")
+		return nil, errors.Wrap(err, "failed to resolve security group names to IDs")
 	}
 	utils.PrintLog(fmt.Sprintf("Using security group IDs: %v", securityGroupIDs))
 
@@ -1346,14 +1557,14 @@ func (migobj *Migrate) CreateTargetInstance(ctx context.Context, vminfo vm.VMInf
 	// Get vjailbreak settings
 	vjailbreakSettings, err := k8sutils.GetVjailbreakSettings(context.Background(), migobj.K8sClient)
 	if err != nil {
-		return errors.Wrap(err, "failed to get vjailbreak settings")
+		return nil, errors.Wrap(err, "failed to get vjailbreak settings")
 	}
 	utils.PrintLog(fmt.Sprintf("Fetched vjailbreak settings for VM active wait retry limit: %d, VM active wait interval seconds: %d", vjailbreakSettings.VMActiveWaitRetryLimit, vjailbreakSettings.VMActiveWaitIntervalSeconds))
 
 	// Create a new VM in OpenStack
 	newVM, err := openstackops.CreateVM(ctx, flavor, networkids, portids, vminfo, migobj.TargetAvailabilityZone, securityGroupIDs, migobj.ServerGroup, *vjailbreakSettings, migobj.UseFlavorless)
 	if err != nil {
-		return errors.Wrap(err, "failed to create VM")
+		return nil, errors.Wrap(err, "failed to create VM")
 	}
 
 	// Wait for VM to become active
@@ -1361,28 +1572,35 @@ func (migobj *Migrate) CreateTargetInstance(ctx context.Context, vminfo vm.VMInf
 		migobj.logMessage(fmt.Sprintf("Waiting for VM to become active: %d/%d retries\n", i+1, vjailbreakSettings.VMActiveWaitRetryLimit))
 		active, err := openstackops.WaitUntilVMActive(ctx, newVM.ID)
 		if err != nil {
-			return errors.Wrap(err, "failed to wait for VM to become active")
+			return newVM, errors.Wrap(err, "failed to wait for VM to become active")
 		}
 		if active {
 			break
 		}
 		if i == vjailbreakSettings.VMActiveWaitRetryLimit-1 {
-			return errors.Errorf("VM is not active after %d retries", vjailbreakSettings.VMActiveWaitRetryLimit)
+			return newVM, errors.Errorf("VM is not active after %d retries", vjailbreakSettings.VMActiveWaitRetryLimit)
 		}
 		time.Sleep(time.Duration(vjailbreakSettings.VMActiveWaitIntervalSeconds) * time.Second)
 	}
+	return newVM, nil
+}
 
-	migobj.logMessage(fmt.Sprintf("VM created successfully: ID: %s", newVM.ID))
-
-	if migobj.PerformHealthChecks {
-		err = migobj.HealthCheck(vminfo, ipaddresses)
-		if err != nil {
-			migobj.logMessage(fmt.Sprintf("Health Check failed: %s", err))
+// applySecurityFeatures carries Secure Boot and the vTPM of the source VM over to the boot volume.
+// Plan validation warns when the flavor or the cloud cannot honour them.
+func (migobj *Migrate) applySecurityFeatures(ctx context.Context, vminfo vm.VMInfo) error {
+	if !vminfo.SecureBoot && !vminfo.VTPM {
+		return nil
+	}
+	openstackops := migobj.Openstackclients
+	for _, disk := range vminfo.VMDisks {
+		if !disk.Boot || disk.OpenstackVol == nil {
+			continue
 		}
-	} else {
-		migobj.logMessage("Skipping Health Checks")
+		if err := openstackops.SetVolumeSecurityFeatures(ctx, disk.OpenstackVol, vminfo.SecureBoot, vminfo.VTPM); err != nil {
+			return errors.Wrap(err, "failed to set secure boot and vtpm on the boot volume")
+		}
+		migobj.logMessage(fmt.Sprintf("Enabled Secure Boot: %v, vTPM: %v on boot volume %s", vminfo.SecureBoot, vminfo.VTPM, disk.OpenstackVol.ID))
 	}
-
 	return nil
 }
 
@@ -1556,13 +1774,19 @@ func (migobj *Migrate) HealthCheck(vminfo vm.VMInfo, ips []string) error {
 		migobj.logMessage("Waiting for 60 seconds before retrying health checks")
 		time.Sleep(60 * time.Second)
 	}
+	var failedChecks []string
 	for key, value := range healthChecks {
 		if !value {
 			migobj.logMessage(fmt.Sprintf("Health Check %s failed", key))
+			failedChecks = append(failedChecks, key)
 		} else {
 			migobj.logMessage(fmt.Sprintf("Health Check %s succeeded", key))
 		}
 	}
+	if len(failedChecks) > 0 {
+		sort.Strings(failedChecks)
+		return errors.Errorf("health checks %s did not pass", strings.Join(failedChecks, ", "))
+	}
 	return nil
 }
 
@@ -1573,6 +1797,12 @@ func (migobj *Migrate) gracefulTerminate(ctx context.Context, vminfo vm.VMInfo,
 	<-gracefulShutdown
 	migobj.logMessage("Gracefully terminating")
 	cancel()
+	migobj.removeTestBoot(context.Background())
+	if migobj.shouldPreserveCheckpoint(context.Background()) {
+		// Exit with a failure, the job replaces pods lost to a disruption and the next pod adopts the volumes
+		migobj.logMessage("Migration is still active, keeping volumes so that disk replication resumes after restart")
+		os.Exit(1)
+	}
 	migobj.cleanup(ctx, vminfo, "Migration terminated", nil, nil)
 	os.Exit(0)
 }
@@ -1588,6 +1818,9 @@ func (migobj *Migrate) MigrateVM(ctx context.Context) error {
 		time.Sleep(time.Until(migobj.MigrationTimes.DataCopyStart))
 		migobj.logMessage("Data copy start time reached")
 	}
+	if err := migobj.waitForDataCopyWindow(ctx); err != nil {
+		return errors.Wrap(err, "failed to wait for data copy window")
+	}
 	fmt.Println("Starting VM Migration with RDM disks : ", migobj.RDMDisks)
 	// Get Info about VM
 	vminfo, err := migobj.VMops.GetVMInfo(migobj.Ostype, migobj.RDMDisks)
@@ -1596,6 +1829,13 @@ func (migobj *Migrate) MigrateVM(ctx context.Context) error {
 		cancel()
 		return errors.Wrap(err, "failed to get all info")
 	}
+	if migobj.Destination != nil && (migobj.StorageCopyMethod == constants.StorageCopyMethod || migobj.RollbackPolicy != "") {
+		return errors.New("StorageAcceleratedCopy and rollback are only supported when migrating to OpenStack")
+	}
+	if migobj.Destination != nil {
+		// Volume types only apply to Cinder volumes
+		migobj.Volumetypes = make([]string, len(vminfo.VMDisks))
+	}
 	if (len(vminfo.VMDisks) != len(migobj.Volumetypes)) && migobj.StorageCopyMethod != constants.StorageCopyMethod {
 		return errors.Errorf("number of volume types does not match number of disks vm(%d) volume(%d)", len(vminfo.VMDisks), len(migobj.Volumetypes))
 	}
@@ -1604,9 +1844,12 @@ func (migobj *Migrate) MigrateVM(ctx context.Context) error {
 	}
 	// Graceful Termination clean-up volumes and snapshots
 	go migobj.gracefulTerminate(ctx, vminfo, cancel)
+	if migobj.RollbackPolicy != "" {
+		migobj.recordSourceState(vminfo)
+	}
 
 	// Reserve ports for VM
-	networkids, portids, ipaddresses, err := migobj.ReservePortsForVM(ctx, &vminfo)
+	networkids, portids, ipaddresses, err := migobj.destination().ReservePorts(ctx, &vminfo, migobj.Networknames)
 	if err != nil {
 		return errors.Wrap(err, "failed to reserve ports for VM")
 	}
@@ -1614,6 +1857,10 @@ func (migobj *Migrate) MigrateVM(ctx context.Context) error {
 	if err != nil {
 		return errors.Wrap(err, "failed to get vcenter settings")
 	}
+	migobj.targetIPs = ipaddresses
+	if err := migobj.RunHooks(ctx, vjailbreakv1alpha1.HookStagePreCopy); err != nil {
+		return err
+	}
 
 	if migobj.StorageCopyMethod == constants.StorageCopyMethod {
 		// Initialize storage provider if using StorageAcceleratedCopy migration
@@ -1636,6 +1883,9 @@ func (migobj *Migrate) MigrateVM(ctx context.Context) error {
 
 	} else {
 
+		if err := migobj.LoadCheckpoint(ctx, vminfo); err != nil {
+			return errors.Wrap(err, "failed to load migration checkpoint")
+		}
 		// Create and Add Volumes to Host
 		vminfo, err = migobj.CreateVolumes(ctx, vminfo)
 		if err != nil {
@@ -1648,6 +1898,9 @@ func (migobj *Migrate) MigrateVM(ctx context.Context) error {
 			return errors.Wrap(err, "CBT Failure")
 		}
 
+		// Throttling applies to the NBD servers started from here on
+		migobj.StartBandwidthThrottle(ctx, vminfo)
+
 		// Create NBD servers
 		for range vminfo.VMDisks {
 			migobj.Nbdops = append(migobj.Nbdops, &nbd.NBDServer{})
@@ -1662,6 +1915,8 @@ func (migobj *Migrate) MigrateVM(ctx context.Context) error {
 			}
 			return errors.Wrap(err, "failed to live replicate disks")
 		}
+		// Conversion modifies the volumes in place, a restart from here on cannot resume the copy
+		migobj.ClearCheckpoint(ctx)
 	}
 	// Convert the Boot Disk to raw format
 	err = migobj.ConvertVolumes(ctx, vminfo)
@@ -1689,6 +1944,19 @@ func (migobj *Migrate) MigrateVM(ctx context.Context) error {
 
 	err = migobj.CreateTargetInstance(ctx, vminfo, networkids, portids, ipaddresses)
 	if err != nil {
+		// A target VM that did not pass its health checks or PostCutover hooks is kept for inspection
+		// unless rolled back
+		if errors.Is(err, ErrHealthChecksFailed) && migobj.healthCheckFailurePolicy() == constants.HealthCheckFailurePolicyFail ||
+			errors.Is(err, ErrHookFailed) {
+			return err
+		}
+		rolledBack, rollbackErr := migobj.handleCutoverFailure(ctx, vminfo, portids, err)
+		if rollbackErr != nil {
+			return errors.Wrapf(err, "rollback did not complete: %s", rollbackErr)
+		}
+		if rolledBack {
+			return errors.Wrap(ErrRolledBack, err.Error())
+		}
 		if cleanuperror := migobj.cleanup(ctx, vminfo, fmt.Sprintf("failed to create target instance: %s", err), portids, vcenterSettings); cleanuperror != nil {
 			// combine both errors
 			return errors.Wrapf(err, "failed to cleanup disks: %s", cleanuperror)
@@ -1712,11 +1980,16 @@ func (migobj *Migrate) cleanup(ctx context.Context, vminfo vm.VMInfo, message st
 	err = migobj.DeleteAllVolumes(ctx, vminfo)
 	if err != nil {
 		utils.PrintLog(fmt.Sprintf("Failed to delete all volumes from host: %s\n", err))
+	} else {
+		migobj.ClearCheckpoint(ctx)
 	}
-	err = migobj.VMops.CleanUpSnapshots(true)
-	if err != nil {
-		utils.PrintLog(fmt.Sprintf("Failed to cleanup snapshot of source VM: %s\n", err))
-		return errors.Wrap(err, fmt.Sprintf("Failed to cleanup snapshot of source VM: %s\n", err))
+	// VMs imported from files have no source VM to take snapshots of
+	if migobj.VMops != nil {
+		err = migobj.VMops.CleanUpSnapshots(true)
+		if err != nil {
+			utils.PrintLog(fmt.Sprintf("Failed to cleanup snapshot of source VM: %s\n", err))
+			return errors.Wrap(err, fmt.Sprintf("Failed to cleanup snapshot of source VM: %s\n", err))
+		}
 	}
 
 	// Delete ports if cleanup is enabled
@@ -3,15 +3,27 @@ package migrate
 
 import (
 	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
 	"github.com/platform9/vjailbreak/v2v-helper/nbd"
 	"github.com/platform9/vjailbreak/v2v-helper/openstack"
 	"github.com/platform9/vjailbreak/v2v-helper/pkg/constants"
+	"github.com/platform9/vjailbreak/v2v-helper/pkg/k8sutils"
 	"github.com/platform9/vjailbreak/v2v-helper/vm"
 
 	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
 	"github.com/gophercloud/gophercloud/v2/openstack/blockstorage/v3/volumes"
 	"github.com/gophercloud/gophercloud/v2/openstack/compute/v2/flavors"
 	"github.com/gophercloud/gophercloud/v2/openstack/compute/v2/servers"
@@ -20,8 +32,12 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/vmware/govmomi/object"
 	"github.com/vmware/govmomi/vim25/types"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrlfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
 )
 
@@ -82,6 +98,77 @@ func TestCreateVolumes(t *testing.T) {
 	assert.Equal(t, "/dev/sdb", outputvminfo.VMDisks[1].Path)
 }
 
+func TestCreateVolumesAdoptsCheckpointedVolumes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	inputvminfo := vm.VMInfo{
+		Name:   "test-vm",
+		UUID:   "vm-uuid",
+		OSType: "linux",
+		VMDisks: []vm.VMDisk{
+			{Name: "disk1", Size: int64(1024), Disk: &types.VirtualDisk{VirtualDevice: types.VirtualDevice{Key: 2000}}},
+			{Name: "disk2", Size: int64(2048), Disk: &types.VirtualDisk{VirtualDevice: types.VirtualDevice{Key: 2001}}},
+		},
+	}
+
+	mockOpenStackOps := openstack.NewMockOpenstackOperations(ctrl)
+	// disk1 was created by a previous run and is still attached to another agent
+	mockOpenStackOps.EXPECT().GetVolume(gomock.Any(), "id1").Return(&volumes.Volume{
+		ID:          "id1",
+		Size:        2,
+		Attachments: []volumes.Attachment{{ServerID: "old-agent"}},
+	}, nil)
+	mockOpenStackOps.EXPECT().DetachVolumeFromServer(gomock.Any(), "old-agent", "id1").Return(nil)
+	mockOpenStackOps.EXPECT().WaitForVolume(gomock.Any(), "id1").Return(nil)
+	mockOpenStackOps.EXPECT().
+		CreateVolume(gomock.Any(), "test-vm-disk2", int64(2048), "linux", false, "voltype-2", false).
+		Return(&volumes.Volume{ID: "id2"}, nil)
+
+	vmK8sName := "test-vm"
+	fakeCtrlClient := ctrlfake.NewClientBuilder().WithObjects(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      k8sutils.GetMigrationCheckpointConfigMapName(vmK8sName),
+			Namespace: constants.NamespaceMigrationSystem,
+		},
+	}).Build()
+
+	migobj := Migrate{
+		Openstackclients: mockOpenStackOps,
+		InPod:            false,
+		Volumetypes:      []string{"voltype-1", "voltype-2"},
+		K8sClient:        fakeCtrlClient,
+		Checkpoint: &k8sutils.MigrationCheckpoint{
+			VMUUID: "vm-uuid",
+			Disks: []k8sutils.DiskCheckpoint{
+				{DiskName: "disk1", DeviceKey: 2000, VolumeID: "id1", ChangeID: "52 1/5", FullCopyCompleted: true},
+			},
+		},
+		checkpointVMName: vmK8sName,
+	}
+
+	ctx := context.Background()
+	outputvminfo, err := migobj.CreateVolumes(ctx, inputvminfo)
+	assert.NoError(t, err)
+	assert.Equal(t, "id1", outputvminfo.VMDisks[0].OpenstackVol.ID)
+	assert.Equal(t, "id2", outputvminfo.VMDisks[1].OpenstackVol.ID)
+	assert.True(t, migobj.fullCopyCompleted(outputvminfo.VMDisks[0]))
+	assert.False(t, migobj.fullCopyCompleted(outputvminfo.VMDisks[1]))
+
+	migobj.restoreChangeIDs(&outputvminfo)
+	assert.Equal(t, "52 1/5", outputvminfo.VMDisks[0].ChangeID)
+
+	saved, err := k8sutils.GetMigrationCheckpoint(ctx, fakeCtrlClient, vmK8sName)
+	assert.NoError(t, err)
+	assert.Equal(t, migobj.Checkpoint, saved)
+	assert.Equal(t, "id2", saved.GetDisk(2001).VolumeID)
+
+	migobj.ClearCheckpoint(ctx)
+	saved, err = k8sutils.GetMigrationCheckpoint(ctx, fakeCtrlClient, vmK8sName)
+	assert.NoError(t, err)
+	assert.Nil(t, saved)
+}
+
 func TestEnableCBTWrapper(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -202,13 +289,13 @@ func TestLiveReplicateDisks(t *testing.T) {
 			AnyTimes(),
 		mockOpenStackOps.EXPECT().AttachVolumeToVM(gomock.Any(), "id1").Return(nil).AnyTimes(),
 		mockOpenStackOps.EXPECT().FindDevice("id1").Return("/dev/sda", nil).AnyTimes(),
-		mockNBD.EXPECT().CopyDisk(context.TODO(), "/dev/sda", 0).Return(nil).AnyTimes(),
+		mockNBD.EXPECT().CopyDisk(gomock.Any(), "/dev/sda", 0).Return(nil).AnyTimes(),
 		mockOpenStackOps.EXPECT().DetachVolumeFromVM(gomock.Any(), gomock.Any()).Return(nil).AnyTimes(),
 		mockOpenStackOps.EXPECT().WaitForVolume(gomock.Any(), gomock.Any()).Return(nil).AnyTimes(),
 
 		mockOpenStackOps.EXPECT().AttachVolumeToVM(gomock.Any(), "id2").Return(nil).AnyTimes(),
 		mockOpenStackOps.EXPECT().FindDevice("id2").Return("/dev/sdb", nil).AnyTimes(),
-		mockNBD.EXPECT().CopyDisk(context.TODO(), "/dev/sdb", 1).Return(nil).AnyTimes(),
+		mockNBD.EXPECT().CopyDisk(gomock.Any(), "/dev/sdb", 1).Return(nil).AnyTimes(),
 		// 1. Both Disks Change
 		mockVMOps.EXPECT().
 			UpdateDiskInfo(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes(),
@@ -242,7 +329,7 @@ func TestLiveReplicateDisks(t *testing.T) {
 			AnyTimes(),
 		mockOpenStackOps.EXPECT().AttachVolumeToVM(gomock.Any(), "id1").Return(nil).AnyTimes(),
 		mockOpenStackOps.EXPECT().FindDevice("id1").Return("/dev/sda", nil).AnyTimes(),
-		mockNBD.EXPECT().CopyChangedBlocks(context.TODO(), changedAreasexample, "/dev/sda").Return(nil).AnyTimes(),
+		mockNBD.EXPECT().CopyChangedBlocks(gomock.Any(), changedAreasexample, "/dev/sda").Return(nil).AnyTimes(),
 		mockOpenStackOps.EXPECT().DetachVolumeFromVM(gomock.Any(), gomock.Any()).Return(nil).AnyTimes(),
 		mockOpenStackOps.EXPECT().WaitForVolume(gomock.Any(), gomock.Any()).Return(nil).AnyTimes(),
 		// Incremental Copy Disk 2
@@ -262,7 +349,7 @@ func TestLiveReplicateDisks(t *testing.T) {
 			AnyTimes(),
 		mockOpenStackOps.EXPECT().AttachVolumeToVM(gomock.Any(), "id2").Return(nil).AnyTimes(),
 		mockOpenStackOps.EXPECT().FindDevice("id2").Return("/dev/sdb", nil).AnyTimes(),
-		mockNBD.EXPECT().CopyChangedBlocks(context.TODO(), changedAreasexample, "/dev/sdb").Return(nil).AnyTimes(),
+		mockNBD.EXPECT().CopyChangedBlocks(gomock.Any(), changedAreasexample, "/dev/sdb").Return(nil).AnyTimes(),
 		// 2. Only Disk 1 Changes
 		mockVMOps.EXPECT().
 			UpdateDiskInfo(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes(),
@@ -286,7 +373,7 @@ func TestLiveReplicateDisks(t *testing.T) {
 		mockNBD.EXPECT().StartNBDServer(&object.VirtualMachine{}, envURL, envUserName, envPassword, thumbprint, "migration-snap", "[ds1] test_vm/test_vm.vmdk", dummychan).Return(nil).AnyTimes(),
 		mockOpenStackOps.EXPECT().AttachVolumeToVM(gomock.Any(), "id1").Return(nil).AnyTimes(),
 		mockOpenStackOps.EXPECT().FindDevice("id1").Return("/dev/sda", nil).AnyTimes(),
-		mockNBD.EXPECT().CopyChangedBlocks(context.TODO(), changedAreasexample, "/dev/sda").Return(nil).AnyTimes(),
+		mockNBD.EXPECT().CopyChangedBlocks(gomock.Any(), changedAreasexample, "/dev/sda").Return(nil).AnyTimes(),
 		mockOpenStackOps.EXPECT().DetachVolumeFromVM(gomock.Any(), gomock.Any()).Return(nil).AnyTimes(),
 		mockOpenStackOps.EXPECT().WaitForVolume(gomock.Any(), gomock.Any()).Return(nil).AnyTimes(),
 		// No copy for Disk 2
@@ -603,3 +690,713 @@ func TestCreateTargetInstance_AdvancedMapping_InsufficientPorts(t *testing.T) {
 	// This test now just verifies that CreateTargetInstance can handle mismatched Networkports config
 	assert.NoError(t, err)
 }
+
+func TestForEachDiskLimitsParallelism(t *testing.T) {
+	var mu sync.Mutex
+	running, maxRunning, calls := 0, 0, 0
+	err := forEachDisk(context.TODO(), 5, 2, func(ctx context.Context, idx int) error {
+		mu.Lock()
+		running++
+		calls++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 5, calls)
+	assert.Equal(t, 2, maxRunning)
+}
+
+func TestForEachDiskCancelsSiblingsOnFailure(t *testing.T) {
+	copyErr := errors.New("copy failed")
+	var cancelled atomic.Bool
+	var started atomic.Int32
+	err := forEachDisk(context.TODO(), 4, 2, func(ctx context.Context, idx int) error {
+		started.Add(1)
+		if idx == 0 {
+			return copyErr
+		}
+		select {
+		case <-ctx.Done():
+			cancelled.Store(true)
+		case <-time.After(5 * time.Second):
+		}
+		return ctx.Err()
+	})
+	assert.Equal(t, copyErr, err)
+	assert.True(t, cancelled.Load())
+	assert.Equal(t, int32(2), started.Load())
+}
+
+func TestDiskCopyParallelism(t *testing.T) {
+	migobj := Migrate{}
+	assert.Equal(t, 1, migobj.diskCopyParallelism(0, 3))
+	assert.Equal(t, 2, migobj.diskCopyParallelism(2, 3))
+	assert.Equal(t, 3, migobj.diskCopyParallelism(8, 3))
+	migobj.DiskCopyParallelism = 2
+	assert.Equal(t, 2, migobj.diskCopyParallelism(4, 3))
+}
+
+func TestVerifyDataIntegrityRecopiesMismatchedExtents(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockVMOps := vm.NewMockVMOperations(ctrl)
+	mockVMOps.EXPECT().GetVMObj().Return(&object.VirtualMachine{}).AnyTimes()
+	mockNBD0 := nbd.NewMockNBDOperations(ctrl)
+	mockNBD1 := nbd.NewMockNBDOperations(ctrl)
+	for _, mockNBD := range []*nbd.MockNBDOperations{mockNBD0, mockNBD1} {
+		mockNBD.EXPECT().StopNBDServer().Return(nil)
+		mockNBD.EXPECT().StartNBDServer(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), "snap-2", gomock.Any(), gomock.Any()).Return(nil)
+	}
+
+	diskSize := int64(2 * nbd.VerifyExtentSize)
+	allExtents := nbd.VerificationExtents(diskSize, 100)
+	mismatched := []types.DiskChangeExtent{allExtents[1]}
+	mockNBD0.EXPECT().VerifyDisk(gomock.Any(), "/dev/sda", allExtents).Return(nil, nil)
+	gomock.InOrder(
+		mockNBD1.EXPECT().VerifyDisk(gomock.Any(), "/dev/sdb", allExtents).Return(mismatched, nil),
+		mockNBD1.EXPECT().CopyChangedBlocks(gomock.Any(), types.DiskChangeInfo{ChangedArea: mismatched}, "/dev/sdb").Return(nil),
+		mockNBD1.EXPECT().VerifyDisk(gomock.Any(), "/dev/sdb", mismatched).Return(nil, nil),
+	)
+
+	migobj := Migrate{
+		VMops:      mockVMOps,
+		Nbdops:     []nbd.NBDOperations{mockNBD0, mockNBD1},
+		VerifyMode: constants.IntegrityVerificationModeFull,
+	}
+	vminfo := vm.VMInfo{
+		VMDisks: []vm.VMDisk{
+			{Name: "disk1", Size: diskSize, Path: "/dev/sda", Snapname: "snap-2"},
+			{Name: "disk2", Size: diskSize, Path: "/dev/sdb", Snapname: "snap-2"},
+		},
+	}
+	assert.NoError(t, migobj.VerifyDataIntegrity(context.TODO(), vminfo, 2))
+}
+
+func TestVerifyDataIntegrityFailsOnPersistentMismatch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockVMOps := vm.NewMockVMOperations(ctrl)
+	mockVMOps.EXPECT().GetVMObj().Return(&object.VirtualMachine{}).AnyTimes()
+	mockNBD := nbd.NewMockNBDOperations(ctrl)
+	mockNBD.EXPECT().StopNBDServer().Return(nil)
+	mockNBD.EXPECT().StartNBDServer(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+	mismatched := []types.DiskChangeExtent{{Start: 0, Length: 512}}
+	mockNBD.EXPECT().VerifyDisk(gomock.Any(), "/dev/sda", gomock.Any()).Return(mismatched, nil).Times(2)
+	mockNBD.EXPECT().CopyChangedBlocks(gomock.Any(), gomock.Any(), "/dev/sda").Return(nil)
+
+	migobj := Migrate{
+		VMops:      mockVMOps,
+		Nbdops:     []nbd.NBDOperations{mockNBD},
+		VerifyMode: constants.IntegrityVerificationModeSample,
+	}
+	vminfo := vm.VMInfo{VMDisks: []vm.VMDisk{{Name: "disk1", Size: 512, Path: "/dev/sda"}}}
+	assert.Error(t, migobj.VerifyDataIntegrity(context.TODO(), vminfo, 1))
+}
+
+func TestVerifyDataIntegrityDisabled(t *testing.T) {
+	migobj := Migrate{}
+	assert.NoError(t, migobj.VerifyDataIntegrity(context.TODO(), vm.VMInfo{VMDisks: []vm.VMDisk{{Name: "disk1"}}}, 1))
+}
+
+func TestEffectiveBandwidthMbps(t *testing.T) {
+	assert.Equal(t, 0, effectiveBandwidthMbps(0, nil))
+	assert.Equal(t, 500, effectiveBandwidthMbps(500, []bandwidthShare{{limitMbps: 0, migrations: 3}}))
+	// host cap of 800 Mbps shared by 4 copying migrations
+	assert.Equal(t, 200, effectiveBandwidthMbps(500, []bandwidthShare{{limitMbps: 800, migrations: 4}}))
+	assert.Equal(t, 100, effectiveBandwidthMbps(0, []bandwidthShare{{limitMbps: 800, migrations: 2}, {limitMbps: 300, migrations: 3}}))
+	assert.Equal(t, 1, effectiveBandwidthMbps(0, []bandwidthShare{{limitMbps: 2, migrations: 5}}))
+}
+
+func TestNBDRateBitsPerSecond(t *testing.T) {
+	assert.Equal(t, int64(0), nbdRateBitsPerSecond(0, 2))
+	assert.Equal(t, int64(400_000_000), nbdRateBitsPerSecond(400, 1))
+	assert.Equal(t, int64(100_000_000), nbdRateBitsPerSecond(400, 4))
+	assert.Equal(t, int64(400_000_000), nbdRateBitsPerSecond(400, 0))
+}
+
+func TestRollbackCutover(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockOpenStackOps := openstack.NewMockOpenstackOperations(ctrl)
+	mockVMOps := vm.NewMockVMOperations(ctrl)
+	gomock.InOrder(
+		mockOpenStackOps.EXPECT().DeleteVM(gomock.Any(), "server-id").Return(nil),
+		mockOpenStackOps.EXPECT().WaitForVolume(gomock.Any(), "vol-id").Return(nil),
+		mockOpenStackOps.EXPECT().DeleteVolume(gomock.Any(), "vol-id").Return(nil),
+		mockOpenStackOps.EXPECT().DeletePort(gomock.Any(), "port-id").Return(nil),
+		mockVMOps.EXPECT().CleanUpSnapshots(true).Return(nil),
+		mockVMOps.EXPECT().ReconnectNetworkInterfaces().Return(nil),
+		mockVMOps.EXPECT().VMPowerOn().Return(nil),
+	)
+
+	migobj := Migrate{
+		Openstackclients:        mockOpenStackOps,
+		VMops:                   mockVMOps,
+		DisconnectSourceNetwork: true,
+		RollbackPolicy:          constants.RollbackPolicyAutomatic,
+		sourcePowerState:        types.VirtualMachinePowerStatePoweredOn,
+		targetServerID:          "server-id",
+	}
+	vminfo := vm.VMInfo{VMDisks: []vm.VMDisk{{Name: "disk1", OpenstackVol: &volumes.Volume{ID: "vol-id"}}}}
+	rolledBack, err := migobj.handleCutoverFailure(context.TODO(), vminfo, []string{"port-id"}, errors.New("health checks Ping did not pass"))
+	assert.NoError(t, err)
+	assert.True(t, rolledBack)
+	assert.Empty(t, migobj.targetServerID)
+}
+
+func TestRollbackCutoverKeepsUserPortsAndPowerState(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockOpenStackOps := openstack.NewMockOpenstackOperations(ctrl)
+	mockOpenStackOps.EXPECT().DeleteVM(gomock.Any(), "server-id").Return(errors.New("nova unavailable"))
+	mockOpenStackOps.EXPECT().WaitForVolume(gomock.Any(), "vol-id").Return(errors.New("volume in-use"))
+	mockVMOps := vm.NewMockVMOperations(ctrl)
+	mockVMOps.EXPECT().CleanUpSnapshots(true).Return(nil)
+
+	// A cold migrated VM stays powered off and ports given in the plan are kept
+	migobj := Migrate{
+		Openstackclients: mockOpenStackOps,
+		VMops:            mockVMOps,
+		Networkports:     []string{"port-id"},
+		sourcePowerState: types.VirtualMachinePowerStatePoweredOff,
+		targetServerID:   "server-id",
+	}
+	vminfo := vm.VMInfo{VMDisks: []vm.VMDisk{{Name: "disk1", OpenstackVol: &volumes.Volume{ID: "vol-id"}}}}
+	assert.NoError(t, migobj.RollbackCutover(context.TODO(), vminfo, []string{"port-id"}))
+	assert.Equal(t, "server-id", migobj.targetServerID)
+}
+
+func TestRollbackCutoverFailsWhenSourceStaysDown(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockVMOps := vm.NewMockVMOperations(ctrl)
+	mockVMOps.EXPECT().CleanUpSnapshots(true).Return(nil)
+	mockVMOps.EXPECT().VMPowerOn().Return(errors.New("host in maintenance mode"))
+
+	migobj := Migrate{
+		Openstackclients: openstack.NewMockOpenstackOperations(ctrl),
+		VMops:            mockVMOps,
+		RollbackPolicy:   constants.RollbackPolicyAutomatic,
+	}
+	rolledBack, err := migobj.handleCutoverFailure(context.TODO(), vm.VMInfo{}, nil, errors.New("failed to create VM"))
+	assert.Error(t, err)
+	assert.False(t, rolledBack)
+}
+
+func TestHandleCutoverFailureWithoutRollbackPolicy(t *testing.T) {
+	migobj := Migrate{}
+	rolledBack, err := migobj.handleCutoverFailure(context.TODO(), vm.VMInfo{}, nil, errors.New("failed to create VM"))
+	assert.NoError(t, err)
+	assert.False(t, rolledBack)
+}
+
+func TestRunTestBootCleansUpWhenCloneFails(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	dir := t.TempDir()
+	disk1 := filepath.Join(dir, "vdb")
+	disk2 := filepath.Join(dir, "vdc")
+	assert.NoError(t, os.WriteFile(disk1, nil, 0600))
+	assert.NoError(t, os.WriteFile(disk2, nil, 0600))
+
+	mockOpenStackOps := openstack.NewMockOpenstackOperations(ctrl)
+	gomock.InOrder(
+		mockOpenStackOps.EXPECT().CloneVolume(gomock.Any(), "vol-1", "test-vm-testboot-disk1").Return(&volumes.Volume{ID: "clone-1"}, nil),
+		mockOpenStackOps.EXPECT().CloneVolume(gomock.Any(), "vol-2", "test-vm-testboot-disk2").Return(nil, errors.New("over quota")),
+		mockOpenStackOps.EXPECT().WaitForVolume(gomock.Any(), "clone-1").Return(nil),
+		mockOpenStackOps.EXPECT().DeleteVolume(gomock.Any(), "clone-1").Return(nil),
+	)
+
+	// The source VM is not touched, the VMops mock fails on any call
+	migobj := Migrate{
+		Openstackclients: mockOpenStackOps,
+		VMops:            vm.NewMockVMOperations(ctrl),
+	}
+	vminfo := vm.VMInfo{
+		Name: "test-vm",
+		VMDisks: []vm.VMDisk{
+			{Name: "disk1", Path: disk1, OpenstackVol: &volumes.Volume{ID: "vol-1"}},
+			{Name: "disk2", Path: disk2, OpenstackVol: &volumes.Volume{ID: "vol-2"}},
+		},
+	}
+	migobj.startTestBoot(context.TODO(), vminfo, "quarantine")
+	<-migobj.testBoot.done
+
+	// The failed request is remembered so that it is not retried, the replicated volumes are kept
+	assert.NotNil(t, migobj.testBoot)
+	assert.Equal(t, "quarantine", migobj.testBoot.network)
+	assert.Empty(t, migobj.testBoot.volumeIDs)
+	assert.Equal(t, "vol-1", vminfo.VMDisks[0].OpenstackVol.ID)
+	assert.Equal(t, disk1, vminfo.VMDisks[0].Path)
+}
+
+func TestRemoveTestBoot(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockOpenStackOps := openstack.NewMockOpenstackOperations(ctrl)
+	gomock.InOrder(
+		mockOpenStackOps.EXPECT().DeleteVM(gomock.Any(), "server-id").Return(nil),
+		mockOpenStackOps.EXPECT().DeletePort(gomock.Any(), "port-id").Return(nil),
+		mockOpenStackOps.EXPECT().WaitForVolume(gomock.Any(), "clone-1").Return(nil),
+		mockOpenStackOps.EXPECT().DeleteVolume(gomock.Any(), "clone-1").Return(nil),
+	)
+
+	migobj := Migrate{
+		Openstackclients: mockOpenStackOps,
+		testBoot: &testBoot{
+			network:   "quarantine",
+			serverID:  "server-id",
+			portIDs:   []string{"port-id"},
+			volumeIDs: []string{"clone-1"},
+		},
+	}
+	migobj.removeTestBoot(context.TODO())
+	assert.Nil(t, migobj.testBoot)
+
+	// Removing again is a no-op
+	migobj.removeTestBoot(context.TODO())
+}
+
+func TestRemoveTestBootStopsRunningBoot(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	dir := t.TempDir()
+	disk1 := filepath.Join(dir, "vdb")
+	assert.NoError(t, os.WriteFile(disk1, nil, 0600))
+
+	started := make(chan struct{})
+	mockOpenStackOps := openstack.NewMockOpenstackOperations(ctrl)
+	mockOpenStackOps.EXPECT().CloneVolume(gomock.Any(), "vol-1", "test-vm-testboot-disk1").DoAndReturn(
+		func(ctx context.Context, _, _ string) (*volumes.Volume, error) {
+			close(started)
+			<-ctx.Done()
+			return nil, ctx.Err()
+		})
+
+	migobj := Migrate{Openstackclients: mockOpenStackOps}
+	vminfo := vm.VMInfo{
+		Name:    "test-vm",
+		VMDisks: []vm.VMDisk{{Name: "disk1", Path: disk1, OpenstackVol: &volumes.Volume{ID: "vol-1"}}},
+	}
+	migobj.startTestBoot(context.TODO(), vminfo, "quarantine")
+	<-started
+
+	// The boot is cancelled and has stopped by the time the removal returns
+	tb := migobj.testBoot
+	migobj.removeTestBoot(context.TODO())
+	assert.Nil(t, migobj.testBoot)
+	select {
+	case <-tb.done:
+	default:
+		t.Fatal("test boot still running after removal")
+	}
+}
+
+func TestRunHealthChecks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/healthz" {
+			fmt.Fprint(w, "status: ok")
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+	host, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	assert.NoError(t, err)
+	portNumber, err := strconv.Atoi(port)
+	assert.NoError(t, err)
+
+	migobj := Migrate{
+		HealthChecks: []vjailbreakv1alpha1.HealthCheck{
+			{Name: "tcp", Type: constants.HealthCheckTypeTCP, Port: portNumber, TimeoutSeconds: 1},
+			{Name: "http", Type: constants.HealthCheckTypeHTTP, Port: portNumber, Path: "healthz", BodyRegex: "status: (ok|ready)", TimeoutSeconds: 1},
+			{Name: "missing", Type: constants.HealthCheckTypeHTTP, Port: portNumber, Path: "/missing", TimeoutSeconds: 1, Retries: 1, RetryIntervalSeconds: 1},
+		},
+	}
+	err = migobj.RunHealthChecks(context.TODO(), "server-id", []string{host})
+	assert.EqualError(t, err, "health checks missing did not pass")
+
+	// Only the checks that did not pass count against the failure policy
+	migobj.HealthChecks = migobj.HealthChecks[:2]
+	assert.NoError(t, migobj.RunHealthChecks(context.TODO(), "server-id", []string{host}))
+}
+
+func TestConsoleLogHealthCheckStopsOnMatch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockOpenStackOps := openstack.NewMockOpenstackOperations(ctrl)
+	mockOpenStackOps.EXPECT().GetConsoleOutput(gomock.Any(), "server-id", constants.HealthCheckConsoleLogLines).
+		Return("[    2.1] Kernel panic - not syncing: VFS: Unable to mount root fs", nil).Times(1)
+
+	migobj := Migrate{Openstackclients: mockOpenStackOps}
+	check := vjailbreakv1alpha1.HealthCheck{Name: "console", Type: constants.HealthCheckTypeConsoleLog, Pattern: "Kernel panic", TimeoutSeconds: 1, Retries: 5, RetryIntervalSeconds: 1}
+	_, err := migobj.runHealthCheck(context.TODO(), check, "server-id", nil)
+	assert.ErrorIs(t, err, errConsoleLogMatched)
+}
+
+func TestCheckTargetHealthFailurePolicy(t *testing.T) {
+	checks := []vjailbreakv1alpha1.HealthCheck{{Name: "tcp", Type: constants.HealthCheckTypeTCP, Port: 22, TimeoutSeconds: 1}}
+	tests := []struct {
+		name           string
+		policy         string
+		rollbackPolicy string
+		wantErr        bool
+	}{
+		{name: "warn by default", wantErr: false},
+		{name: "fail", policy: constants.HealthCheckFailurePolicyFail, wantErr: true},
+		{name: "rollback", policy: constants.HealthCheckFailurePolicyRollback, wantErr: true},
+		{name: "rollback policy implies rollback", rollbackPolicy: constants.RollbackPolicyManual, wantErr: true},
+		{name: "explicit warn with rollback policy", policy: constants.HealthCheckFailurePolicyWarn, rollbackPolicy: constants.RollbackPolicyManual, wantErr: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			migobj := Migrate{HealthChecks: checks, HealthCheckPolicy: tt.policy, RollbackPolicy: tt.rollbackPolicy}
+			// The target VM has no IP address, so the check cannot pass
+			err := migobj.checkTargetHealth(context.TODO(), "server-id", vm.VMInfo{}, nil)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrHealthChecksFailed)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestRunHooks(t *testing.T) {
+	vmK8sName := "test-vm"
+	t.Setenv("VMWARE_MACHINE_OBJECT_NAME", vmK8sName)
+
+	scheme := runtime.NewScheme()
+	assert.NoError(t, clientgoscheme.AddToScheme(scheme))
+	assert.NoError(t, vjailbreakv1alpha1.AddToScheme(scheme))
+	hookJob := func(name string, conditionType batchv1.JobConditionType) *batchv1.Job {
+		return &batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "hook-" + name,
+				Namespace: constants.NamespaceMigrationSystem,
+				Labels: map[string]string{
+					vjailbreakv1alpha1.HookVMLabel:    vmK8sName,
+					vjailbreakv1alpha1.HookStageLabel: vjailbreakv1alpha1.HookStagePreCutover,
+					vjailbreakv1alpha1.HookNameLabel:  name,
+				},
+			},
+			Status: batchv1.JobStatus{
+				Conditions: []batchv1.JobCondition{{Type: conditionType, Status: corev1.ConditionTrue, Reason: "BackoffLimitExceeded"}},
+			},
+		}
+	}
+	fakeCtrlClient := ctrlfake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&vjailbreakv1alpha1.Migration{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "migration-" + vmK8sName,
+				Namespace: constants.NamespaceMigrationSystem,
+			},
+		},
+		hookJob("quiesce", batchv1.JobComplete),
+		hookJob("notify", batchv1.JobFailed),
+		hookJob("deregister", batchv1.JobFailed),
+	).Build()
+
+	migobj := Migrate{
+		K8sClient: fakeCtrlClient,
+		targetIPs: []string{"10.0.0.5"},
+		Hooks: []vjailbreakv1alpha1.MigrationHook{
+			{Name: "quiesce", Stage: vjailbreakv1alpha1.HookStagePreCutover},
+			{Name: "notify", Stage: vjailbreakv1alpha1.HookStagePreCutover, FailurePolicy: vjailbreakv1alpha1.HookFailurePolicyWarn},
+			{Name: "deregister", Stage: vjailbreakv1alpha1.HookStagePreCutover, FailurePolicy: vjailbreakv1alpha1.HookFailurePolicyBlock},
+			{Name: "cmdb", Stage: vjailbreakv1alpha1.HookStagePostCutover},
+		},
+	}
+
+	// No hook runs at PreCopy, the controller is not asked for Jobs
+	assert.NoError(t, migobj.RunHooks(context.TODO(), vjailbreakv1alpha1.HookStagePreCopy))
+	migration := &vjailbreakv1alpha1.Migration{}
+	assert.NoError(t, fakeCtrlClient.Get(context.TODO(), k8stypes.NamespacedName{Name: "migration-" + vmK8sName, Namespace: constants.NamespaceMigrationSystem}, migration))
+	assert.Empty(t, migration.Annotations[vjailbreakv1alpha1.HookStageAnnotation])
+
+	err := migobj.RunHooks(context.TODO(), vjailbreakv1alpha1.HookStagePreCutover)
+	assert.ErrorIs(t, err, ErrHookFailed)
+	assert.Contains(t, err.Error(), "PreCutover hooks deregister:")
+	assert.NoError(t, fakeCtrlClient.Get(context.TODO(), k8stypes.NamespacedName{Name: "migration-" + vmK8sName, Namespace: constants.NamespaceMigrationSystem}, migration))
+	assert.Equal(t, vjailbreakv1alpha1.HookStagePreCutover, migration.Annotations[vjailbreakv1alpha1.HookStageAnnotation])
+	assert.Equal(t, "10.0.0.5", migration.Annotations[vjailbreakv1alpha1.HookTargetIPsAnnotation])
+}
+
+func TestApplySecurityFeatures(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	bootVolume := &volumes.Volume{ID: "id1"}
+	vminfo := vm.VMInfo{
+		Name:       "test-vm",
+		UEFI:       true,
+		SecureBoot: true,
+		VTPM:       true,
+		VMDisks: []vm.VMDisk{
+			{Name: "disk1", Boot: true, OpenstackVol: bootVolume},
+			{Name: "disk2", OpenstackVol: &volumes.Volume{ID: "id2"}},
+		},
+	}
+
+	mockOpenStackOps := openstack.NewMockOpenstackOperations(ctrl)
+	mockOpenStackOps.EXPECT().
+		SetVolumeSecurityFeatures(gomock.Any(), bootVolume, true, true).
+		Return(nil)
+
+	migobj := Migrate{Openstackclients: mockOpenStackOps}
+	assert.NoError(t, migobj.applySecurityFeatures(context.Background(), vminfo))
+
+	// Nothing is checked or set for VMs without Secure Boot and vTPM
+	vminfo.SecureBoot, vminfo.VTPM = false, false
+	assert.NoError(t, migobj.applySecurityFeatures(context.Background(), vminfo))
+}
+
+func TestPowerOffSourceVM(t *testing.T) {
+	apiErr := errors.New("license does not allow power operations")
+	tests := []struct {
+		name         string
+		sourceIsESXi bool
+		apiErr       error
+		sshKey       []byte
+		sshErr       error
+		wantSSH      bool
+		wantErr      string
+	}{
+		{
+			name:         "the API powers off the VM",
+			sourceIsESXi: true,
+		},
+		{
+			name:    "sources managed by vCenter never fall back to SSH",
+			apiErr:  apiErr,
+			sshKey:  []byte("key"),
+			wantErr: "license does not allow power operations",
+		},
+		{
+			name:         "standalone ESXi host falls back to SSH",
+			sourceIsESXi: true,
+			apiErr:       apiErr,
+			sshKey:       []byte("key"),
+			wantSSH:      true,
+		},
+		{
+			name:         "SSH power off fails",
+			sourceIsESXi: true,
+			apiErr:       apiErr,
+			sshKey:       []byte("key"),
+			sshErr:       errors.New("failed to connect to ESXi via SSH"),
+			wantSSH:      true,
+			wantErr:      "failed to connect to ESXi via SSH",
+		},
+		{
+			name:         "no SSH key",
+			sourceIsESXi: true,
+			apiErr:       apiErr,
+			wantErr:      "no ESXi SSH key to power off over SSH",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockVMOps := vm.NewMockVMOperations(ctrl)
+			mockVMOps.EXPECT().VMPowerOff().Return(tt.apiErr)
+			mockVMOps.EXPECT().GetVMObj().
+				Return(object.NewVirtualMachine(nil, types.ManagedObjectReference{Type: "VirtualMachine", Value: "42"})).
+				AnyTimes()
+			sshCalled := false
+			migobj := Migrate{
+				VMops:             mockVMOps,
+				K8sClient:         ctrlfake.NewClientBuilder().Build(),
+				SourceIsESXi:      tt.sourceIsESXi,
+				ESXiSSHPrivateKey: tt.sshKey,
+				esxiSSHPowerOff: func(_ context.Context, vmID string) error {
+					sshCalled = true
+					assert.Equal(t, "42", vmID)
+					return tt.sshErr
+				},
+			}
+
+			err := migobj.powerOffSourceVM(context.Background())
+			assert.Equal(t, tt.wantSSH, sshCalled)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestTakeQuiescedSnapshot(t *testing.T) {
+	preFreeze := &vjailbreakv1alpha1.GuestScript{Path: "/usr/local/bin/pre-freeze"}
+	postThaw := &vjailbreakv1alpha1.GuestScript{Path: "/usr/local/bin/post-thaw"}
+	tests := []struct {
+		name          string
+		failurePolicy string
+		preFreezeErr  error
+		snapshotErr   error
+		powerOffErr   error
+		expectedCalls []string
+		expectedTaken bool
+		expectedErr   string
+	}{
+		{
+			name: "quiesced snapshot",
+			// The VM is powered off before its applications are thawed, the post-thaw script never runs
+			expectedCalls: []string{"cleanup", preFreeze.Path, "snapshot", "poweroff"},
+			expectedTaken: true,
+		},
+		{
+			name:          "snapshot failed, crash consistent fallback",
+			failurePolicy: vjailbreakv1alpha1.SnapshotFailurePolicyCrashConsistent,
+			snapshotErr:   errors.New("VMware Tools are not running"),
+			expectedCalls: []string{"cleanup", preFreeze.Path, "snapshot", postThaw.Path},
+		},
+		{
+			name:          "pre-freeze script failed, crash consistent fallback",
+			failurePolicy: vjailbreakv1alpha1.SnapshotFailurePolicyCrashConsistent,
+			preFreezeErr:  errors.New("exited with code 1"),
+			expectedCalls: []string{"cleanup", preFreeze.Path, postThaw.Path},
+		},
+		{
+			name:          "snapshot failed",
+			failurePolicy: vjailbreakv1alpha1.SnapshotFailurePolicyFail,
+			snapshotErr:   errors.New("VSS failed"),
+			expectedCalls: []string{"cleanup", preFreeze.Path, "snapshot", postThaw.Path},
+			expectedErr:   "failed to quiesce the guest: failed to take quiesced snapshot: VSS failed",
+		},
+		{
+			name:          "power off failed",
+			powerOffErr:   errors.New("task failed"),
+			expectedCalls: []string{"cleanup", preFreeze.Path, "snapshot", "poweroff", postThaw.Path},
+			expectedErr:   "failed to power off VM: task failed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			calls := []string{}
+			mockVMOps := vm.NewMockVMOperations(ctrl)
+			migobj := Migrate{
+				VMops: mockVMOps,
+				SnapshotConsistency: &vjailbreakv1alpha1.SnapshotConsistency{
+					IncludeMemory:   true,
+					FailurePolicy:   tt.failurePolicy,
+					PreFreezeScript: preFreeze,
+					PostThawScript:  postThaw,
+				},
+				guestScriptRunner: func(_ context.Context, script *vjailbreakv1alpha1.GuestScript) error {
+					calls = append(calls, script.Path)
+					if script == preFreeze {
+						return tt.preFreezeErr
+					}
+					return nil
+				},
+			}
+
+			mockVMOps.EXPECT().CleanUpSnapshots(false).Do(func(bool) { calls = append(calls, "cleanup") }).Return(nil)
+			mockVMOps.EXPECT().TakeQuiescedSnapshot(constants.MigrationSnapshotName, true).
+				Do(func(string, bool) { calls = append(calls, "snapshot") }).Return(tt.snapshotErr).MaxTimes(1)
+			mockVMOps.EXPECT().VMPowerOff().Do(func() { calls = append(calls, "poweroff") }).Return(tt.powerOffErr).MaxTimes(1)
+
+			taken, err := migobj.takeQuiescedSnapshot(context.Background())
+			if tt.expectedErr != "" {
+				assert.EqualError(t, err, tt.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.expectedTaken, taken)
+			assert.Equal(t, tt.expectedCalls, calls)
+		})
+	}
+}
+
+func TestFileSourceNetworks(t *testing.T) {
+	vminfo := vm.VMInfo{NetworkInterfaces: []vjailbreakv1alpha1.NIC{
+		{Network: "VM Network", Index: 0},
+		{Network: "Backup", Index: 1},
+	}}
+
+	networks, err := fileSourceNetworks(vminfo, map[string]string{"VM Network": "net-a", "Backup": "net-b"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"net-a", "net-b"}, networks)
+
+	// A single mapping applies to NICs of networks it does not name
+	networks, err = fileSourceNetworks(vminfo, map[string]string{"VM Network": "net-a"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"net-a", "net-a"}, networks)
+
+	_, err = fileSourceNetworks(vminfo, map[string]string{"VM Network": "net-a", "Other": "net-c"})
+	assert.EqualError(t, err, `network "Backup" of NIC 1 not found in the network mapping`)
+}
+
+func TestFileDestination(t *testing.T) {
+	ctx := context.Background()
+	dest, err := NewFileDestination(t.TempDir(), constants.DestinationFormatRaw)
+	assert.NoError(t, err)
+	_, err = NewFileDestination(dest.Dir, "vmdk")
+	assert.Error(t, err)
+
+	vminfo := vm.VMInfo{
+		Name:   "vm1",
+		CPU:    2,
+		Memory: 4096,
+		UEFI:   true,
+		Mac:    []string{"00:50:56:00:00:01"},
+		VMDisks: []vm.VMDisk{
+			{Name: "Hard disk 1", Size: 1024 * 1024},
+			{Name: "Hard disk 2", Size: 2 * 1024 * 1024},
+		},
+	}
+	for idx := range vminfo.VMDisks {
+		volume, err := dest.CreateDisk(ctx, vminfo, idx, "")
+		assert.NoError(t, err)
+		vminfo.VMDisks[idx].OpenstackVol = volume
+
+		path, err := dest.AttachDisk(ctx, vminfo.VMDisks[idx])
+		assert.NoError(t, err)
+		info, err := os.Stat(path)
+		assert.NoError(t, err)
+		assert.Equal(t, vminfo.VMDisks[idx].Size, info.Size())
+	}
+	assert.NoError(t, dest.SetBootDisk(ctx, vminfo.VMDisks[1]))
+
+	networkids, portids, _, err := dest.ReservePorts(ctx, &vminfo, []string{"default"})
+	assert.NoError(t, err)
+	domainPath, err := dest.CreateInstance(ctx, vminfo, networkids, portids)
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(dest.Dir, "vm1", "vm1.xml"), domainPath)
+
+	domain := dest.domain(vminfo, []string{vminfo.VMDisks[0].OpenstackVol.ID, vminfo.VMDisks[1].OpenstackVol.ID}, networkids)
+	assert.Equal(t, "efi", domain.OS.Firmware)
+	assert.Nil(t, domain.Devices.Disks[0].Boot)
+	assert.Equal(t, 1, domain.Devices.Disks[1].Boot.Order)
+	assert.Equal(t, "default", domain.Devices.Interfaces[0].Source.Network)
+	assert.Equal(t, "00:50:56:00:00:01", domain.Devices.Interfaces[0].MAC.Address)
+
+	for _, vmdisk := range vminfo.VMDisks {
+		assert.NoError(t, dest.DeleteDisk(ctx, vmdisk))
+		_, err := os.Stat(vmdisk.OpenstackVol.ID)
+		assert.True(t, os.IsNotExist(err))
+	}
+}
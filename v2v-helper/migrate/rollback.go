@@ -0,0 +1,148 @@
+// Copyright © 2024 The vjailbreak authors
+
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/platform9/vjailbreak/v2v-helper/pkg/constants"
+	"github.com/platform9/vjailbreak/v2v-helper/pkg/k8sutils"
+	"github.com/platform9/vjailbreak/v2v-helper/pkg/utils"
+	"github.com/platform9/vjailbreak/v2v-helper/vm"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// ErrRolledBack is returned by MigrateVM when a failed cutover was rolled back to the source VM
+var ErrRolledBack = errors.New("cutover rolled back to the source VM")
+
+// recordSourceState remembers the power state of the source VM before the migration changes it,
+// so that a rollback can restore it
+func (migobj *Migrate) recordSourceState(vminfo vm.VMInfo) {
+	migobj.sourcePowerState = vminfo.State
+}
+
+// waitForRollbackRequest waits until a rollback is requested on the Migration of the VM.
+// It returns false when the rollback request timeout of vjailbreak-settings expires first.
+func (migobj *Migrate) waitForRollbackRequest(ctx context.Context) (bool, error) {
+	vmK8sName, err := k8sutils.GetVMwareMachineName()
+	if err != nil {
+		return false, errors.Wrap(err, "failed to get vmware machine name")
+	}
+	settings, err := k8sutils.GetVjailbreakSettings(ctx, migobj.K8sClient)
+	if err != nil {
+		return false, errors.Wrap(err, "failed to get vjailbreak settings")
+	}
+	deadline := time.Now().Add(time.Duration(settings.RollbackRequestTimeoutMinutes) * time.Minute)
+
+	ticker := time.NewTicker(constants.RollbackRequestPollInterval)
+	defer ticker.Stop()
+	for {
+		requested, err := k8sutils.IsRollbackRequested(ctx, migobj.K8sClient, vmK8sName)
+		if err != nil {
+			utils.PrintLog(fmt.Sprintf("Could not check for a rollback request: %v", err))
+		} else if requested {
+			return true, nil
+		}
+		if time.Now().After(deadline) {
+			return false, nil
+		}
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// handleCutoverFailure applies the rollback policy after the cutover failed. It returns true
+// once the source VM was restored, false when no rollback was requested.
+func (migobj *Migrate) handleCutoverFailure(ctx context.Context, vminfo vm.VMInfo, portids []string, cutoverErr error) (bool, error) {
+	policy := migobj.RollbackPolicy
+	// Health checks with the Rollback failure policy roll back right away when no rollback policy is set
+	if policy == "" && errors.Is(cutoverErr, ErrHealthChecksFailed) {
+		policy = constants.RollbackPolicyAutomatic
+	}
+	switch policy {
+	case constants.RollbackPolicyAutomatic:
+		migobj.logMessage(fmt.Sprintf("%s, cutover did not complete: %v", constants.EventMessageRollingBack, cutoverErr))
+	case constants.RollbackPolicyManual:
+		migobj.logMessage(fmt.Sprintf("%s, cutover did not complete: %v", constants.EventMessageWaitingForRollback, cutoverErr))
+		requested, err := migobj.waitForRollbackRequest(ctx)
+		if err != nil {
+			return false, err
+		}
+		if !requested {
+			migobj.logMessage("No rollback was requested before the timeout, keeping the target VM")
+			return false, nil
+		}
+		migobj.logMessage(fmt.Sprintf("%s, requested on the migration", constants.EventMessageRollingBack))
+	default:
+		return false, nil
+	}
+	if err := migobj.RollbackCutover(ctx, vminfo, portids); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// RollbackCutover restores the source VM after a failed cutover. The target server, volumes and
+// the ports created for it are deleted, the source VM gets its network back and is powered on
+// again if it was running before the migration. Leftovers on the target side are reported but do
+// not fail the rollback, an error is returned only if the source VM could not be powered back on.
+func (migobj *Migrate) RollbackCutover(ctx context.Context, vminfo vm.VMInfo, portids []string) error {
+	openstackops := migobj.Openstackclients
+	var warnings []string
+
+	if migobj.targetServerID != "" {
+		utils.PrintLog(fmt.Sprintf("Deleting target server %s", migobj.targetServerID))
+		if err := openstackops.DeleteVM(ctx, migobj.targetServerID); err != nil {
+			warnings = append(warnings, fmt.Sprintf("target server %s was not deleted: %v", migobj.targetServerID, err))
+		} else {
+			migobj.targetServerID = ""
+		}
+	}
+	for _, vmdisk := range vminfo.VMDisks {
+		if vmdisk.OpenstackVol == nil {
+			continue
+		}
+		if err := openstackops.WaitForVolume(ctx, vmdisk.OpenstackVol.ID); err != nil {
+			warnings = append(warnings, fmt.Sprintf("volume %s was not released: %v", vmdisk.OpenstackVol.ID, err))
+			continue
+		}
+		if err := openstackops.DeleteVolume(ctx, vmdisk.OpenstackVol.ID); err != nil {
+			warnings = append(warnings, fmt.Sprintf("volume %s was not deleted: %v", vmdisk.OpenstackVol.ID, err))
+		}
+	}
+	migobj.ClearCheckpoint(ctx)
+	// Ports given in the migration plan belong to the user and are kept
+	if len(migobj.Networkports) == 0 && len(portids) > 0 {
+		if err := migobj.DeleteAllPorts(ctx, portids); err != nil {
+			warnings = append(warnings, err.Error())
+		}
+	}
+	if err := migobj.VMops.CleanUpSnapshots(true); err != nil {
+		warnings = append(warnings, fmt.Sprintf("migration snapshots were not removed: %v", err))
+	}
+
+	if migobj.DisconnectSourceNetwork {
+		if err := migobj.VMops.ReconnectNetworkInterfaces(); err != nil {
+			warnings = append(warnings, fmt.Sprintf("source VM network interfaces were not reconnected: %v", err))
+		}
+	}
+	if migobj.sourcePowerState != types.VirtualMachinePowerStatePoweredOff {
+		if err := migobj.VMops.VMPowerOn(); err != nil {
+			return errors.Wrap(err, "failed to power on the source VM during rollback")
+		}
+	}
+
+	if len(warnings) > 0 {
+		migobj.logMessage(fmt.Sprintf("%s with warnings: %s", constants.EventMessageRolledBack, strings.Join(warnings, "; ")))
+	} else {
+		migobj.logMessage(constants.EventMessageRolledBack)
+	}
+	return nil
+}
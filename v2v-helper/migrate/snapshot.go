@@ -0,0 +1,151 @@
+// Copyright © 2024 The vjailbreak authors
+
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+	"github.com/platform9/vjailbreak/v2v-helper/pkg/constants"
+	"github.com/platform9/vjailbreak/v2v-helper/pkg/k8sutils"
+	"github.com/platform9/vjailbreak/v2v-helper/pkg/utils"
+	"github.com/vmware/govmomi/guest"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+const (
+	// guestScriptTimeout is how long a guest script runs when it sets no timeout
+	guestScriptTimeout = 300 * time.Second
+	// guestScriptPollInterval is how often a running guest script is checked for completion
+	guestScriptPollInterval = 2 * time.Second
+)
+
+// takeFinalSnapshot takes the quiesced snapshot the final sync copies from while the source VM
+// still runs, and powers the VM off right after it. It returns whether it took the snapshot, the
+// caller then keeps it instead of taking a crash consistent one after the shutdown. The VM is still
+// running when no snapshot was taken.
+func (migobj *Migrate) takeFinalSnapshot(ctx context.Context) (bool, error) {
+	if migobj.SnapshotConsistency == nil {
+		return false, nil
+	}
+	state, err := migobj.VMops.GetVMObj().PowerState(ctx)
+	if err != nil {
+		return false, errors.Wrap(err, "failed to get VM power state")
+	}
+	if state != types.VirtualMachinePowerStatePoweredOn {
+		// A VM that does not run has nothing to quiesce, its snapshot is consistent already
+		return false, nil
+	}
+	return migobj.takeQuiescedSnapshot(ctx)
+}
+
+// takeQuiescedSnapshot runs the pre-freeze script, takes the quiesced snapshot and powers the source
+// VM off before its applications are thawed, so that no write acknowledged after the snapshot is
+// lost. The post-thaw script only runs when the guest keeps running: when it cannot be quiesced or
+// cannot be powered off. The migration then fails or leaves the snapshot to be taken crash
+// consistent, following the failure policy. The kind of snapshot is reported either way.
+func (migobj *Migrate) takeQuiescedSnapshot(ctx context.Context) (bool, error) {
+	consistency := migobj.SnapshotConsistency
+	vmops := migobj.VMops
+	if err := vmops.CleanUpSnapshots(false); err != nil {
+		return false, errors.Wrap(err, "failed to cleanup snapshot of source VM")
+	}
+
+	migobj.logMessage("Quiescing the guest for the final snapshot")
+	err := migobj.runSnapshotScript(ctx, consistency.PreFreezeScript)
+	if err != nil {
+		err = errors.Wrap(err, "pre-freeze script failed")
+	} else if err = vmops.TakeQuiescedSnapshot(constants.MigrationSnapshotName, consistency.IncludeMemory); err != nil {
+		err = errors.Wrap(err, "failed to take quiesced snapshot")
+	} else if err = migobj.powerOffSourceVM(ctx); err != nil {
+		// The applications stay frozen in a VM that keeps running, they are thawed before failing
+		migobj.thawGuest(ctx)
+		return false, errors.Wrap(err, "failed to power off VM")
+	}
+	if err == nil {
+		migobj.logMessage(constants.EventMessageFinalSnapshot + vjailbreakv1alpha1.SnapshotTypeQuiesced)
+		return true, nil
+	}
+	// The pre-freeze script can have stopped applications even when it failed, so they are
+	// thawed while the VM still runs
+	migobj.thawGuest(ctx)
+	if consistency.FailurePolicy == vjailbreakv1alpha1.SnapshotFailurePolicyFail {
+		return false, errors.Wrap(err, "failed to quiesce the guest")
+	}
+	migobj.logMessage(fmt.Sprintf("%s%s: %v", constants.EventMessageFinalSnapshot, vjailbreakv1alpha1.SnapshotTypeCrashConsistent, err))
+	return false, nil
+}
+
+// thawGuest runs the post-thaw script. A failed thaw does not fail the migration, the source VM is
+// shut down next or the migration fails anyway.
+func (migobj *Migrate) thawGuest(ctx context.Context) {
+	if err := migobj.runSnapshotScript(ctx, migobj.SnapshotConsistency.PostThawScript); err != nil {
+		utils.PrintLog(fmt.Sprintf("Post-thaw script did not succeed: %v", err))
+	}
+}
+
+// runSnapshotScript runs a pre-freeze or post-thaw script with the guestScriptRunner of the migration
+func (migobj *Migrate) runSnapshotScript(ctx context.Context, script *vjailbreakv1alpha1.GuestScript) error {
+	if migobj.guestScriptRunner != nil {
+		return migobj.guestScriptRunner(ctx, script)
+	}
+	return migobj.runGuestScript(ctx, script)
+}
+
+// runGuestScript runs a script in the guest through VMware Tools and waits for it to exit with 0.
+// It is terminated when it runs longer than its timeout.
+func (migobj *Migrate) runGuestScript(ctx context.Context, script *vjailbreakv1alpha1.GuestScript) error {
+	if script == nil {
+		return nil
+	}
+	username, password, err := k8sutils.GetGuestCredentials(ctx, migobj.K8sClient, migobj.SnapshotConsistency.GuestCredentialsSecretRef)
+	if err != nil {
+		return err
+	}
+	vmObj := migobj.VMops.GetVMObj()
+	processManager, err := guest.NewOperationsManager(vmObj.Client(), vmObj.Reference()).ProcessManager(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to get guest process manager")
+	}
+	auth := &types.NamePasswordAuthentication{Username: username, Password: password}
+	pid, err := processManager.StartProgram(ctx, auth, &types.GuestProgramSpec{
+		ProgramPath: script.Path,
+		Arguments:   script.Arguments,
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to start %s", script.Path)
+	}
+	utils.PrintLog(fmt.Sprintf("Started %s in the guest with PID %d", script.Path, pid))
+
+	timeout := guestScriptTimeout
+	if script.TimeoutSeconds > 0 {
+		timeout = time.Duration(script.TimeoutSeconds) * time.Second
+	}
+	deadline := time.Now().Add(timeout)
+	for {
+		processes, err := processManager.ListProcesses(ctx, auth, []int64{pid})
+		if err != nil {
+			return errors.Wrapf(err, "failed to get the state of %s", script.Path)
+		}
+		if len(processes) == 1 && processes[0].EndTime != nil {
+			if processes[0].ExitCode != 0 {
+				return fmt.Errorf("%s exited with code %d", script.Path, processes[0].ExitCode)
+			}
+			return nil
+		}
+		if time.Now().After(deadline) {
+			if err := processManager.TerminateProcess(ctx, auth, pid); err != nil {
+				utils.PrintLog(fmt.Sprintf("Failed to terminate %s in the guest: %v", script.Path, err))
+			}
+			return fmt.Errorf("%s did not exit within %s", script.Path, timeout)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(guestScriptPollInterval):
+		}
+	}
+}
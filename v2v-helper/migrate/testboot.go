@@ -0,0 +1,227 @@
+// Copyright © 2024 The vjailbreak authors
+
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/platform9/vjailbreak/v2v-helper/pkg/constants"
+	"github.com/platform9/vjailbreak/v2v-helper/pkg/k8sutils"
+	"github.com/platform9/vjailbreak/v2v-helper/pkg/utils"
+	"github.com/platform9/vjailbreak/v2v-helper/vm"
+)
+
+// testBoot tracks the OpenStack resources of a test boot. They are kept apart from the volumes
+// that still receive the changed blocks of the source VM.
+type testBoot struct {
+	network   string
+	serverID  string
+	portIDs   []string
+	volumeIDs []string
+	// cancel stops the test boot, done is closed once it stopped. The resources of the test boot
+	// are only touched by the test boot until then.
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// reconcileTestBoot starts or removes a test boot to match the request on the Migration of the VM.
+// A test boot runs once per request, changing the network of the request runs it again. The test
+// boot runs in the background, the wait for the cutover goes on meanwhile.
+func (migobj *Migrate) reconcileTestBoot(ctx context.Context, vminfo vm.VMInfo) {
+	// Test boots clone Cinder volumes, they are only available when migrating to OpenStack
+	if migobj.Destination != nil {
+		return
+	}
+	vmK8sName, err := k8sutils.GetVMwareMachineName()
+	if err != nil {
+		utils.PrintLog(fmt.Sprintf("Could not check for a test boot request: %v", err))
+		return
+	}
+	request, err := k8sutils.GetTestBootRequest(ctx, migobj.K8sClient, vmK8sName)
+	if err != nil {
+		utils.PrintLog(fmt.Sprintf("Could not check for a test boot request: %v", err))
+		return
+	}
+
+	migobj.testBootLock.Lock()
+	current := migobj.testBoot
+	migobj.testBootLock.Unlock()
+	if current != nil && (request == nil || request.Network != current.network) {
+		migobj.removeTestBoot(context.WithoutCancel(ctx))
+	}
+	if request != nil && (current == nil || request.Network != current.network) {
+		migobj.startTestBoot(ctx, vminfo, request.Network)
+	}
+}
+
+// startTestBoot runs a test boot on the given network in the background
+func (migobj *Migrate) startTestBoot(ctx context.Context, vminfo vm.VMInfo, network string) {
+	bootCtx, cancel := context.WithCancel(ctx)
+	tb := &testBoot{network: network, cancel: cancel, done: make(chan struct{})}
+	migobj.testBootLock.Lock()
+	migobj.testBoot = tb
+	migobj.testBootLock.Unlock()
+	go func() {
+		defer close(tb.done)
+		defer cancel()
+		migobj.runTestBoot(bootCtx, vminfo, tb)
+	}()
+}
+
+// runTestBoot boots a test server from clones of the target volumes. The outcome is reported with
+// an event, the resources of a test boot that did not succeed are removed right away.
+func (migobj *Migrate) runTestBoot(ctx context.Context, vminfo vm.VMInfo, tb *testBoot) {
+	migobj.logMessage(fmt.Sprintf("%s on network %s", constants.EventMessageTestBootStarted, tb.network))
+	if err := migobj.bootTestServer(ctx, vminfo, tb); err != nil {
+		migobj.logMessage(fmt.Sprintf("%s: %v", constants.EventMessageTestBootNotSucceeded, err))
+		if warnings := migobj.deleteTestBootResources(context.WithoutCancel(ctx), tb); len(warnings) > 0 {
+			utils.PrintLog(fmt.Sprintf("Test boot resources were left behind: %s", strings.Join(warnings, "; ")))
+		}
+		return
+	}
+	migobj.logMessage(fmt.Sprintf("%s, server %s is active on network %s", constants.EventMessageTestBootSucceeded, tb.serverID, tb.network))
+}
+
+// bootTestServer clones the target volumes, converts the clones and boots a server from them
+func (migobj *Migrate) bootTestServer(ctx context.Context, vminfo vm.VMInfo, tb *testBoot) error {
+	openstackops := migobj.Openstackclients
+
+	testinfo := vminfo
+	testinfo.Name = vminfo.Name + constants.TestBootNameSuffix
+	testinfo.VMDisks = make([]vm.VMDisk, len(vminfo.VMDisks))
+	copy(testinfo.VMDisks, vminfo.VMDisks)
+	if err := migobj.cloneTargetVolumes(ctx, testinfo, tb); err != nil {
+		return err
+	}
+
+	if err := migobj.convertVolumes(ctx, testinfo); err != nil {
+		// The clones stay attached to the helper VM when the conversion stops half way
+		if detachErr := migobj.DetachAllVolumes(ctx, testinfo); detachErr != nil {
+			utils.PrintLog(fmt.Sprintf("Could not detach test boot volumes: %v", detachErr))
+		}
+		return errors.Wrap(err, "failed to convert volume clones")
+	}
+
+	quarantine, err := openstackops.GetNetwork(ctx, tb.network)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get network %s", tb.network)
+	}
+	securityGroupIDs, err := openstackops.GetSecurityGroupIDs(ctx, migobj.SecurityGroups, migobj.TenantName)
+	if err != nil {
+		return errors.Wrap(err, "failed to resolve security group names to IDs")
+	}
+	networkids := []string{}
+	for _, mac := range vminfo.Mac {
+		// The ports keep the MAC addresses of the source VM so that the guest finds its interfaces,
+		// the IP addresses are assigned by the network so that they do not clash with the source VM
+		port, err := openstackops.CreatePort(ctx, quarantine, mac, nil, testinfo.Name, securityGroupIDs, true, map[string]string{})
+		if err != nil {
+			return errors.Wrapf(err, "failed to create port for MAC %s", mac)
+		}
+		tb.portIDs = append(tb.portIDs, port.ID)
+		networkids = append(networkids, quarantine.ID)
+	}
+
+	server, err := migobj.bootServer(ctx, testinfo, networkids, tb.portIDs)
+	if server != nil {
+		tb.serverID = server.ID
+	}
+	return err
+}
+
+// cloneTargetVolumes replaces the volumes of the disks of testinfo with clones of them. The clones
+// are taken between sync cycles, while the volumes do not change.
+func (migobj *Migrate) cloneTargetVolumes(ctx context.Context, testinfo vm.VMInfo, tb *testBoot) error {
+	migobj.targetVolumesLock.RLock()
+	defer migobj.targetVolumesLock.RUnlock()
+
+	// The copy writes through the page cache of the helper VM, flush it so that the clones
+	// contain the last sync cycle
+	for _, vmdisk := range testinfo.VMDisks {
+		if err := flushDevice(vmdisk.Path); err != nil {
+			return errors.Wrapf(err, "failed to flush disk %s", vmdisk.Name)
+		}
+	}
+	for idx, vmdisk := range testinfo.VMDisks {
+		clone, err := migobj.Openstackclients.CloneVolume(ctx, vmdisk.OpenstackVol.ID, testinfo.Name+"-"+vmdisk.Name)
+		if clone != nil {
+			tb.volumeIDs = append(tb.volumeIDs, clone.ID)
+		}
+		if err != nil {
+			return errors.Wrapf(err, "failed to clone volume of disk %s", vmdisk.Name)
+		}
+		testinfo.VMDisks[idx].OpenstackVol = clone
+		testinfo.VMDisks[idx].Path = ""
+	}
+	return nil
+}
+
+// removeTestBoot stops a running test boot and deletes the test server, its ports and volume clones
+func (migobj *Migrate) removeTestBoot(ctx context.Context) {
+	migobj.testBootLock.Lock()
+	tb := migobj.testBoot
+	migobj.testBoot = nil
+	migobj.testBootLock.Unlock()
+	if tb == nil {
+		return
+	}
+	if tb.cancel != nil {
+		tb.cancel()
+		<-tb.done
+	}
+	warnings := migobj.deleteTestBootResources(ctx, tb)
+	if len(warnings) > 0 {
+		migobj.logMessage(fmt.Sprintf("%s with leftovers: %s", constants.EventMessageTestBootRemoved, strings.Join(warnings, "; ")))
+		return
+	}
+	migobj.logMessage(constants.EventMessageTestBootRemoved)
+}
+
+// deleteTestBootResources deletes what was created for a test boot and returns what could not be deleted
+func (migobj *Migrate) deleteTestBootResources(ctx context.Context, tb *testBoot) []string {
+	openstackops := migobj.Openstackclients
+	var warnings []string
+	if tb.serverID != "" {
+		if err := openstackops.DeleteVM(ctx, tb.serverID); err != nil {
+			warnings = append(warnings, fmt.Sprintf("server %s was not deleted: %v", tb.serverID, err))
+		} else {
+			tb.serverID = ""
+		}
+	}
+	var leftPorts []string
+	for _, portID := range tb.portIDs {
+		if err := openstackops.DeletePort(ctx, portID); err != nil {
+			warnings = append(warnings, fmt.Sprintf("port %s was not deleted: %v", portID, err))
+			leftPorts = append(leftPorts, portID)
+		}
+	}
+	tb.portIDs = leftPorts
+	var leftVolumes []string
+	for _, volumeID := range tb.volumeIDs {
+		if err := openstackops.WaitForVolume(ctx, volumeID); err != nil {
+			warnings = append(warnings, fmt.Sprintf("volume %s was not released: %v", volumeID, err))
+			leftVolumes = append(leftVolumes, volumeID)
+			continue
+		}
+		if err := openstackops.DeleteVolume(ctx, volumeID); err != nil {
+			warnings = append(warnings, fmt.Sprintf("volume %s was not deleted: %v", volumeID, err))
+			leftVolumes = append(leftVolumes, volumeID)
+		}
+	}
+	tb.volumeIDs = leftVolumes
+	return warnings
+}
+
+// flushDevice writes the cached data of a disk attached to the helper VM to the volume
+func flushDevice(path string) error {
+	device, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer device.Close()
+	return device.Sync()
+}
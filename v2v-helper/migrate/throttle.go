@@ -0,0 +1,165 @@
+// Copyright © 2024 The vjailbreak authors
+
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/platform9/vjailbreak/v2v-helper/nbd"
+	"github.com/platform9/vjailbreak/v2v-helper/pkg/constants"
+	"github.com/platform9/vjailbreak/v2v-helper/pkg/k8sutils"
+	"github.com/platform9/vjailbreak/v2v-helper/pkg/utils"
+	"github.com/platform9/vjailbreak/v2v-helper/vm"
+
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+)
+
+// bandwidthShare is a cap shared evenly by the migrations reading from the same host or datastore
+type bandwidthShare struct {
+	limitMbps  int
+	migrations int
+}
+
+// effectiveBandwidthMbps returns the strictest of the migration cap and the fair shares of the
+// host and datastore caps, or 0 if nothing is capped
+func effectiveBandwidthMbps(migrationMbps int, shares []bandwidthShare) int {
+	limit := migrationMbps
+	for _, share := range shares {
+		if share.limitMbps <= 0 {
+			continue
+		}
+		migrations := share.migrations
+		if migrations < 1 {
+			migrations = 1
+		}
+		fairShare := share.limitMbps / migrations
+		if fairShare < 1 {
+			fairShare = 1
+		}
+		if limit <= 0 || fairShare < limit {
+			limit = fairShare
+		}
+	}
+	return limit
+}
+
+// nbdRateBitsPerSecond splits the bandwidth of a migration between the nbdkit servers of the
+// disks copied in parallel, since every server applies the limit on its own
+func nbdRateBitsPerSecond(limitMbps, parallelism int) int64 {
+	if limitMbps <= 0 {
+		return 0
+	}
+	if parallelism < 1 {
+		parallelism = 1
+	}
+	return int64(limitMbps) * 1000 * 1000 / int64(parallelism)
+}
+
+// bandwidthLimit computes the current bandwidth cap of this migration in Mbps from the
+// migration plan and the per-host and per-datastore caps in vjailbreak-settings
+func (migobj *Migrate) bandwidthLimit(ctx context.Context, vmK8sName string, diskCount int) (limitMbps, parallelism int, err error) {
+	settings, err := k8sutils.GetVjailbreakSettings(ctx, migobj.K8sClient)
+	if err != nil {
+		return 0, 0, errors.Wrap(err, "failed to get vjailbreak settings")
+	}
+	planLimit, err := k8sutils.GetMigrationPlanBandwidthLimit(ctx, migobj.K8sClient, vmK8sName)
+	if err != nil {
+		return 0, 0, errors.Wrap(err, "failed to get migration plan bandwidth limit")
+	}
+	parallelism = migobj.diskCopyParallelism(settings.DiskCopyParallelism, diskCount)
+
+	hostLimits := k8sutils.ParseBandwidthLimits(settings.ESXiHostBandwidthLimits)
+	datastoreLimits := k8sutils.ParseBandwidthLimits(settings.DatastoreBandwidthLimits)
+	if len(hostLimits) == 0 && len(datastoreLimits) == 0 {
+		return planLimit, parallelism, nil
+	}
+
+	vmwareMachine := &vjailbreakv1alpha1.VMwareMachine{}
+	if err := migobj.K8sClient.Get(ctx, k8stypes.NamespacedName{Name: vmK8sName, Namespace: constants.NamespaceMigrationSystem}, vmwareMachine); err != nil {
+		return 0, 0, errors.Wrap(err, "failed to get vmware machine")
+	}
+	hostMigrations, datastoreMigrations, err := k8sutils.CountCopyingMigrations(ctx, migobj.K8sClient, vmK8sName)
+	if err != nil {
+		return 0, 0, errors.Wrap(err, "failed to count copying migrations")
+	}
+
+	host := vmwareMachine.Spec.VMInfo.ESXiName
+	shares := []bandwidthShare{{limitMbps: k8sutils.BandwidthLimitFor(hostLimits, host), migrations: hostMigrations[host]}}
+	for _, datastore := range vmwareMachine.Spec.VMInfo.Datastores {
+		shares = append(shares, bandwidthShare{limitMbps: k8sutils.BandwidthLimitFor(datastoreLimits, datastore), migrations: datastoreMigrations[datastore]})
+	}
+	return effectiveBandwidthMbps(planLimit, shares), parallelism, nil
+}
+
+// writeRateFile atomically replaces the rate file read by the nbdkit rate filter
+func writeRateFile(path string, bitsPerSecond int64) error {
+	tmpFile := path + ".tmp"
+	if err := os.WriteFile(tmpFile, []byte(strconv.FormatInt(bitsPerSecond, 10)+"\n"), 0644); err != nil {
+		return errors.Wrap(err, "failed to write rate file")
+	}
+	return errors.Wrap(os.Rename(tmpFile, path), "failed to replace rate file")
+}
+
+// StartBandwidthThrottle publishes the bandwidth cap of this migration to the nbdkit servers and
+// keeps re-evaluating it until ctx is done, so caps changed on the migration plan or in
+// vjailbreak-settings apply to a running copy. It must be called before the NBD servers start.
+func (migobj *Migrate) StartBandwidthThrottle(ctx context.Context, vminfo vm.VMInfo) {
+	if !migobj.InPod || migobj.K8sClient == nil {
+		return
+	}
+	vmK8sName, err := k8sutils.GetVMwareMachineName()
+	if err != nil {
+		utils.PrintLog(fmt.Sprintf("Bandwidth throttling disabled, failed to get vmware machine name: %v", err))
+		return
+	}
+	rateFile := filepath.Join(os.TempDir(), fmt.Sprintf("nbdkit-rate-%s", vminfo.UUID))
+	if err := writeRateFile(rateFile, 0); err != nil {
+		utils.PrintLog(fmt.Sprintf("Bandwidth throttling disabled: %v", err))
+		return
+	}
+	nbd.RateFile = rateFile
+
+	currentLimit := -1
+	refresh := func() {
+		limitMbps, parallelism, err := migobj.bandwidthLimit(ctx, vmK8sName, len(vminfo.VMDisks))
+		if err != nil {
+			// Keep the last known limit rather than lifting it on a transient API error
+			utils.PrintLog(fmt.Sprintf("Failed to refresh bandwidth limit, keeping the current one: %v", err))
+			return
+		}
+		if limitMbps == currentLimit {
+			return
+		}
+		if err := writeRateFile(rateFile, nbdRateBitsPerSecond(limitMbps, parallelism)); err != nil {
+			utils.PrintLog(fmt.Sprintf("Failed to apply bandwidth limit: %v", err))
+			return
+		}
+		if limitMbps > 0 {
+			migobj.logMessage(fmt.Sprintf("Bandwidth limit set to %d Mbps", limitMbps))
+		} else if currentLimit > 0 {
+			migobj.logMessage("Bandwidth limit removed")
+		}
+		currentLimit = limitMbps
+	}
+	refresh()
+
+	go func() {
+		ticker := time.NewTicker(constants.BandwidthLimitRefreshInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				refresh()
+			}
+		}
+	}()
+}
@@ -11,6 +11,7 @@ import (
 
 	cindervolumes "github.com/gophercloud/gophercloud/v2/openstack/blockstorage/v3/volumes"
 	"github.com/pkg/errors"
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
 	"github.com/platform9/vjailbreak/pkg/vpwned/sdk/storage"
 	esxissh "github.com/platform9/vjailbreak/v2v-helper/esxi-ssh"
 	"github.com/platform9/vjailbreak/v2v-helper/pkg/k8sutils"
@@ -97,8 +98,14 @@ func (migobj *Migrate) StorageAcceleratedCopyCopyDisks(ctx context.Context, vmin
 	// The VM should already be powered off by the migration flow before calling this function
 	if vminfo.State != "poweredOff" {
 		migobj.logMessage(fmt.Sprintf("VM %s is not powered off (state: %s). VM must be powered off before storage copy can proceed", vminfo.Name, vminfo.State))
+		if _, err := migobj.waitForCutoverWindow(ctx); err != nil {
+			return []storage.Volume{}, errors.Wrap(err, "failed to wait for cutover window")
+		}
+		if err := migobj.RunHooks(ctx, vjailbreakv1alpha1.HookStagePreCutover); err != nil {
+			return []storage.Volume{}, err
+		}
 		migobj.logMessage("Powering off VM")
-		if err := migobj.VMops.VMPowerOff(); err != nil {
+		if err := migobj.powerOffSourceVM(ctx); err != nil {
 			return []storage.Volume{}, errors.Wrap(err, "failed to power off VM")
 		}
 		migobj.logMessage("VM powered off successfully")
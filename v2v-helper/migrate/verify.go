@@ -0,0 +1,97 @@
+// Copyright © 2024 The vjailbreak authors
+
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/platform9/vjailbreak/v2v-helper/nbd"
+	"github.com/platform9/vjailbreak/v2v-helper/pkg/constants"
+	"github.com/platform9/vjailbreak/v2v-helper/vm"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// verificationSamplePercent returns the share of extents to hash for the configured mode
+func (migobj *Migrate) verificationSamplePercent() int {
+	if migobj.VerifyMode != constants.IntegrityVerificationModeSample {
+		return 100
+	}
+	if migobj.VerifySamplePercent <= 0 {
+		return constants.DefaultIntegrityVerificationSamplePercent
+	}
+	return migobj.VerifySamplePercent
+}
+
+// VerifyDataIntegrity compares the target volumes with the final migration snapshot after
+// the last changed block sync. Mismatched extents are re-copied and verified once more;
+// the migration fails if they still differ. It is a no-op unless verification is enabled.
+func (migobj *Migrate) VerifyDataIntegrity(ctx context.Context, vminfo vm.VMInfo, parallelism int) error {
+	if migobj.VerifyMode == "" {
+		return nil
+	}
+	vmops := migobj.VMops
+	nbdops := migobj.Nbdops
+	samplePercent := migobj.verificationSamplePercent()
+	migobj.logMessage(fmt.Sprintf("%s (%s mode, %d%% of extents)", constants.EventMessageVerifyingData, migobj.VerifyMode, samplePercent))
+
+	var (
+		lock          sync.Mutex
+		verifiedCount int
+		verifiedBytes int64
+		recopiedCount int
+	)
+	err := forEachDisk(ctx, len(vminfo.VMDisks), parallelism, func(ctx context.Context, idx int) error {
+		disk := vminfo.VMDisks[idx]
+
+		// Servers of disks without changes in the last iterations still serve an older snapshot
+		if err := nbdops[idx].StopNBDServer(); err != nil {
+			return errors.Wrap(err, "failed to stop NBD server")
+		}
+		err := nbdops[idx].StartNBDServer(vmops.GetVMObj(), migobj.URL, migobj.UserName, migobj.Password, migobj.Thumbprint, disk.Snapname, disk.SnapBackingDisk, migobj.EventReporter)
+		if err != nil {
+			return errors.Wrap(err, "failed to start NBD server")
+		}
+		// sleep for 2 seconds to allow the NBD server to start
+		time.Sleep(2 * time.Second)
+
+		extents := nbd.VerificationExtents(disk.Size, samplePercent)
+		mismatched, err := nbdops[idx].VerifyDisk(ctx, disk.Path, extents)
+		if err != nil {
+			return errors.Wrapf(err, "failed to verify disk %s", disk.Name)
+		}
+		if len(mismatched) > 0 {
+			migobj.logMessage(fmt.Sprintf("Disk %d (%s): %d of %d extents differ from the source snapshot, re-copying them", idx, disk.Name, len(mismatched), len(extents)))
+			if err := nbdops[idx].CopyChangedBlocks(ctx, types.DiskChangeInfo{ChangedArea: mismatched}, disk.Path); err != nil {
+				return errors.Wrapf(err, "failed to re-copy mismatched extents of disk %s", disk.Name)
+			}
+			remaining, err := nbdops[idx].VerifyDisk(ctx, disk.Path, mismatched)
+			if err != nil {
+				return errors.Wrapf(err, "failed to verify disk %s", disk.Name)
+			}
+			if len(remaining) > 0 {
+				migobj.logMessage(fmt.Sprintf("%s: disk %d (%s) still differs in %d extents after re-copy", constants.EventMessageDataIntegrityMismatch, idx, disk.Name, len(remaining)))
+				return errors.Errorf("disk %s still differs from the source snapshot in %d extents", disk.Name, len(remaining))
+			}
+		}
+		migobj.logMessage(fmt.Sprintf("Disk %d (%s): %d extents match the source snapshot", idx, disk.Name, len(extents)))
+
+		lock.Lock()
+		defer lock.Unlock()
+		verifiedCount += len(extents)
+		for _, extent := range extents {
+			verifiedBytes += extent.Length
+		}
+		recopiedCount += len(mismatched)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	migobj.logMessage(fmt.Sprintf("%s: %d extents (%d MiB) hashed across %d disks, %d mismatched extents re-copied",
+		constants.EventMessageDataVerified, verifiedCount, verifiedBytes>>20, len(vminfo.VMDisks), recopiedCount))
+	return nil
+}
@@ -33,6 +33,7 @@ type NBDOperations interface {
 	CopyDisk(ctx context.Context, dest string, diskindex int) error
 	CopyChangedBlocks(ctx context.Context, changedAreas types.DiskChangeInfo, path string) error
 	GetProgress() (int64, int64, time.Duration)
+	VerifyDisk(ctx context.Context, path string, extents []types.DiskChangeExtent) ([]types.DiskChangeExtent, error)
 }
 
 type NBDServer struct {
@@ -76,6 +77,11 @@ const MaxPreadLengthVC = (2 << 20)
 // vCenter endpoints.
 var MaxPreadLength = MaxPreadLengthESX
 
+// RateFile is the file holding the read rate limit of every nbdkit server in bits per
+// second, 0 meaning unlimited. When set, nbdkit runs with the rate filter which re-reads
+// the file periodically, so the limit can be changed while a copy is running.
+var RateFile string
+
 // Request blocks one at a time from libnbd
 var fixedOptArgs = libnbd.BlockStatusOptargs{
 	Flags:    libnbd.CMD_FLAG_REQ_ONE,
@@ -102,8 +108,7 @@ vixDiskLib.nfcAio.Session.BufCount=4`
 	socket := fmt.Sprintf("%s/nbdkit.sock", tmp_dir)
 	pidFile := fmt.Sprintf("%s/nbdkit.pid", tmp_dir)
 
-	cmd := exec.Command(
-		"nbdkit",
+	args := []string{
 		"--exit-with-parent",
 		"--readonly",
 		"--foreground",
@@ -112,6 +117,11 @@ vixDiskLib.nfcAio.Session.BufCount=4`
 		"--verbose",
 		"-D vddk.datapath=0",
 		"-D nbdkit.backend.datapath=0",
+	}
+	if RateFile != "" {
+		args = append(args, "--filter=rate")
+	}
+	args = append(args,
 		"vddk",
 		"libdir=/home/fedora/vmware-vix-disklib-distrib",
 		fmt.Sprintf("server=%s", server),
@@ -125,6 +135,10 @@ vixDiskLib.nfcAio.Session.BufCount=4`
 		fmt.Sprintf("snapshot=%s", snapref),
 		file,
 	)
+	if RateFile != "" {
+		args = append(args, fmt.Sprintf("rate-file=%s", RateFile))
+	}
+	cmd := exec.Command("nbdkit", args...)
 
 	// Log the command with password redacted
 	cmdstring := ""
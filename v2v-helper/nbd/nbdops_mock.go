@@ -108,3 +108,18 @@ func (mr *MockNBDOperationsMockRecorder) StopNBDServer() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StopNBDServer", reflect.TypeOf((*MockNBDOperations)(nil).StopNBDServer))
 }
+
+// VerifyDisk mocks base method.
+func (m *MockNBDOperations) VerifyDisk(ctx context.Context, path string, extents []types.DiskChangeExtent) ([]types.DiskChangeExtent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyDisk", ctx, path, extents)
+	ret0, _ := ret[0].([]types.DiskChangeExtent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// VerifyDisk indicates an expected call of VerifyDisk.
+func (mr *MockNBDOperationsMockRecorder) VerifyDisk(ctx, path, extents interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyDisk", reflect.TypeOf((*MockNBDOperations)(nil).VerifyDisk), ctx, path, extents)
+}
@@ -3,12 +3,15 @@
 package nbd
 
 import (
+	"bytes"
+	"crypto/sha256"
 	"fmt"
 	"os/exec"
 	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/vmware/govmomi/vim25/types"
 )
 
 // TestPasswordRedactionLogic verifies that passwords are properly redacted from command strings
@@ -84,3 +87,38 @@ func TestPasswordRedactionLogic(t *testing.T) {
 		})
 	}
 }
+
+func TestVerificationExtents(t *testing.T) {
+	size := int64(10*VerifyExtentSize + 4096)
+
+	extents := VerificationExtents(size, 100)
+	assert.Len(t, extents, 11)
+	assert.Equal(t, int64(0), extents[0].Start)
+	assert.Equal(t, int64(4096), extents[10].Length)
+
+	sampled := VerificationExtents(size, 30)
+	assert.Len(t, sampled, 3)
+	for i := 1; i < len(sampled); i++ {
+		assert.Less(t, sampled[i-1].Start, sampled[i].Start)
+	}
+
+	assert.Len(t, VerificationExtents(4096, 1), 1)
+	assert.Empty(t, VerificationExtents(0, 100))
+}
+
+func TestHashRange(t *testing.T) {
+	data := bytes.Repeat([]byte("vjailbreak"), 1000)
+	readAt := func(buffer []byte, offset int64) error {
+		_, err := bytes.NewReader(data).ReadAt(buffer, offset)
+		return err
+	}
+	extent := types.DiskChangeExtent{Start: 100, Length: 5000}
+
+	digest, err := hashRange(readAt, extent, 64)
+	assert.NoError(t, err)
+	expected := sha256.Sum256(data[100:5100])
+	assert.Equal(t, expected[:], digest)
+
+	_, err = hashRange(readAt, types.DiskChangeExtent{Start: 9000, Length: 5000}, 64)
+	assert.Error(t, err)
+}
@@ -0,0 +1,123 @@
+// Copyright © 2024 The vjailbreak authors
+
+package nbd
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"math/rand"
+	"os"
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/platform9/vjailbreak/v2v-helper/pkg/utils"
+	"github.com/vmware/govmomi/vim25/types"
+	"libguestfs.org/libnbd"
+)
+
+// VerifyExtentSize is the size of the extents hashed during data integrity verification
+const VerifyExtentSize = 16 << 20
+
+// VerificationExtents splits a disk of the given size into extents of VerifyExtentSize.
+// All extents are returned when samplePercent is 100 or more, otherwise a random sample
+// of at least one extent covering samplePercent of them, ordered by offset.
+func VerificationExtents(size int64, samplePercent int) []types.DiskChangeExtent {
+	var extents []types.DiskChangeExtent
+	for start := int64(0); start < size; start += VerifyExtentSize {
+		length := int64(VerifyExtentSize)
+		if size-start < length {
+			length = size - start
+		}
+		extents = append(extents, types.DiskChangeExtent{Start: start, Length: length})
+	}
+	if samplePercent >= 100 || len(extents) == 0 {
+		return extents
+	}
+
+	count := len(extents) * samplePercent / 100
+	if count < 1 {
+		count = 1
+	}
+	rand.Shuffle(len(extents), func(i, j int) {
+		extents[i], extents[j] = extents[j], extents[i]
+	})
+	extents = extents[:count]
+	sort.Slice(extents, func(i, j int) bool {
+		return extents[i].Start < extents[j].Start
+	})
+	return extents
+}
+
+// hashRange computes the SHA-256 digest of an extent read in chunks of at most chunkSize bytes
+func hashRange(readAt func(buffer []byte, offset int64) error, extent types.DiskChangeExtent, chunkSize int) ([]byte, error) {
+	hash := sha256.New()
+	buffer := make([]byte, chunkSize)
+	for count := int64(0); count < extent.Length; {
+		length := int64(chunkSize)
+		if extent.Length-count < length {
+			length = extent.Length - count
+		}
+		offset := extent.Start + count
+		if err := readAt(buffer[:length], offset); err != nil {
+			return nil, errors.Wrapf(err, "failed to read %d bytes at offset %d", length, offset)
+		}
+		hash.Write(buffer[:length])
+		count += length
+	}
+	return hash.Sum(nil), nil
+}
+
+// VerifyDisk hashes the given extents on the source snapshot served by the NBD server
+// and on the target device at path, and returns the extents whose contents differ.
+func (nbdserver *NBDServer) VerifyDisk(ctx context.Context, path string, extents []types.DiskChangeExtent) ([]types.DiskChangeExtent, error) {
+	handle, err := libnbd.Create()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create libnbd handle: %v", err)
+	}
+	err = handle.ConnectUri(generateSockUrl(nbdserver.tmp_dir))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to source: %v", err)
+	}
+	defer handle.Close()
+
+	fd, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %v", err)
+	}
+	defer fd.Close()
+
+	readSource := func(buffer []byte, offset int64) error {
+		return handle.Pread(buffer, uint64(offset), nil)
+	}
+	readTarget := func(buffer []byte, offset int64) error {
+		_, err := fd.ReadAt(buffer, offset)
+		return err
+	}
+
+	var mismatched []types.DiskChangeExtent
+	lastLoggedPct := -1
+	for idx, extent := range extents {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		sourceHash, err := hashRange(readSource, extent, MaxPreadLength)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to hash source extent")
+		}
+		targetHash, err := hashRange(readTarget, extent, MaxPreadLength)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to hash target extent")
+		}
+		if !bytes.Equal(sourceHash, targetHash) {
+			utils.PrintLog(fmt.Sprintf("Extent at offset %d (%d bytes) differs: source %x, target %x", extent.Start, extent.Length, sourceHash, targetHash))
+			mismatched = append(mismatched, extent)
+		}
+		if pct := (idx + 1) * 100 / len(extents); pct/10 > lastLoggedPct/10 {
+			utils.PrintLog(fmt.Sprintf("Verification progress for %s: %d%%", path, pct))
+			lastLoggedPct = pct
+		}
+	}
+	return mismatched, nil
+}
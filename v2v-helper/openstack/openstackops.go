@@ -30,13 +30,16 @@ import (
 
 type OpenstackOperations interface {
 	CreateVolume(ctx context.Context, name string, size int64, ostype string, uefi bool, volumetype string, setRDMLabel bool) (*volumes.Volume, error)
+	GetVolume(ctx context.Context, volumeID string) (*volumes.Volume, error)
 	WaitForVolume(ctx context.Context, volumeID string) error
 	AttachVolumeToVM(ctx context.Context, volumeID string) error
 	WaitForVolumeAttachment(ctx context.Context, volumeID string) error
 	DetachVolumeFromVM(ctx context.Context, volumeID string) error
+	DetachVolumeFromServer(ctx context.Context, serverID, volumeID string) error
 	SetVolumeUEFI(ctx context.Context, volume *volumes.Volume) error
 	EnableQGA(ctx context.Context, volume *volumes.Volume) error
 	SetVolumeImageMetadata(ctx context.Context, volume *volumes.Volume, setRDMLabel bool) error
+	SetVolumeSecurityFeatures(ctx context.Context, volume *volumes.Volume, secureBoot, vtpm bool) error
 	SetVolumeBootable(ctx context.Context, volume *volumes.Volume) error
 	GetClosestFlavour(ctx context.Context, cpu int32, memory int32) (*flavors.Flavor, error)
 	GetFlavor(ctx context.Context, flavorId string) (*flavors.Flavor, error)
@@ -50,9 +53,12 @@ type OpenstackOperations interface {
 	GetServerGroups(ctx context.Context, projectName string) ([]vjailbreakv1alpha1.ServerGroupInfo, error)
 	GetSecurityGroupIDs(ctx context.Context, groupNames []string, projectName string) ([]string, error)
 	DeleteVolume(ctx context.Context, volumeID string) error
+	CloneVolume(ctx context.Context, volumeID, name string) (*volumes.Volume, error)
 	FindDevice(volumeID string) (string, error)
 	ManageExistingVolume(name string, ref map[string]interface{}, host string, volumeType string) (*volumes.Volume, error)
 	WaitUntilVMActive(ctx context.Context, vmID string) (bool, error)
+	DeleteVM(ctx context.Context, vmID string) error
+	GetConsoleOutput(ctx context.Context, vmID string, lines int) (string, error)
 	// GetCinderVolumeServices returns Cinder volume services (Host, Status, State)
 	// Returns a slice of structs with these fields - defined in implementation package to avoid import cycles
 	GetCinderVolumeServices(ctx context.Context) (interface{}, error)
@@ -57,6 +57,21 @@ func (mr *MockOpenstackOperationsMockRecorder) AttachVolumeToVM(ctx, volumeID in
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AttachVolumeToVM", reflect.TypeOf((*MockOpenstackOperations)(nil).AttachVolumeToVM), ctx, volumeID)
 }
 
+// CloneVolume mocks base method.
+func (m *MockOpenstackOperations) CloneVolume(ctx context.Context, volumeID, name string) (*volumes.Volume, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CloneVolume", ctx, volumeID, name)
+	ret0, _ := ret[0].(*volumes.Volume)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CloneVolume indicates an expected call of CloneVolume.
+func (mr *MockOpenstackOperationsMockRecorder) CloneVolume(ctx, volumeID, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CloneVolume", reflect.TypeOf((*MockOpenstackOperations)(nil).CloneVolume), ctx, volumeID, name)
+}
+
 // CreatePort mocks base method.
 func (m *MockOpenstackOperations) CreatePort(ctx context.Context, networkid *networks.Network, mac string, ip []string, vmname string, securityGroups []string, fallbackToDHCP bool, gatewayIP map[string]string) (*ports.Port, error) {
 	m.ctrl.T.Helper()
@@ -116,6 +131,20 @@ func (mr *MockOpenstackOperationsMockRecorder) DeletePort(ctx, portID interface{
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeletePort", reflect.TypeOf((*MockOpenstackOperations)(nil).DeletePort), ctx, portID)
 }
 
+// DeleteVM mocks base method.
+func (m *MockOpenstackOperations) DeleteVM(ctx context.Context, vmID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteVM", ctx, vmID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteVM indicates an expected call of DeleteVM.
+func (mr *MockOpenstackOperationsMockRecorder) DeleteVM(ctx, vmID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteVM", reflect.TypeOf((*MockOpenstackOperations)(nil).DeleteVM), ctx, vmID)
+}
+
 // DeleteVolume mocks base method.
 func (m *MockOpenstackOperations) DeleteVolume(ctx context.Context, volumeID string) error {
 	m.ctrl.T.Helper()
@@ -130,6 +159,20 @@ func (mr *MockOpenstackOperationsMockRecorder) DeleteVolume(ctx, volumeID interf
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteVolume", reflect.TypeOf((*MockOpenstackOperations)(nil).DeleteVolume), ctx, volumeID)
 }
 
+// DetachVolumeFromServer mocks base method.
+func (m *MockOpenstackOperations) DetachVolumeFromServer(ctx context.Context, serverID, volumeID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DetachVolumeFromServer", ctx, serverID, volumeID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DetachVolumeFromServer indicates an expected call of DetachVolumeFromServer.
+func (mr *MockOpenstackOperationsMockRecorder) DetachVolumeFromServer(ctx, serverID, volumeID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DetachVolumeFromServer", reflect.TypeOf((*MockOpenstackOperations)(nil).DetachVolumeFromServer), ctx, serverID, volumeID)
+}
+
 // DetachVolumeFromVM mocks base method.
 func (m *MockOpenstackOperations) DetachVolumeFromVM(ctx context.Context, volumeID string) error {
 	m.ctrl.T.Helper()
@@ -173,6 +216,21 @@ func (mr *MockOpenstackOperationsMockRecorder) FindDevice(volumeID interface{})
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindDevice", reflect.TypeOf((*MockOpenstackOperations)(nil).FindDevice), volumeID)
 }
 
+// GetCinderVolumeServices mocks base method.
+func (m *MockOpenstackOperations) GetCinderVolumeServices(ctx context.Context) (interface{}, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCinderVolumeServices", ctx)
+	ret0, _ := ret[0].(interface{})
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCinderVolumeServices indicates an expected call of GetCinderVolumeServices.
+func (mr *MockOpenstackOperationsMockRecorder) GetCinderVolumeServices(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCinderVolumeServices", reflect.TypeOf((*MockOpenstackOperations)(nil).GetCinderVolumeServices), ctx)
+}
+
 // GetClosestFlavour mocks base method.
 func (m *MockOpenstackOperations) GetClosestFlavour(ctx context.Context, cpu, memory int32) (*flavors.Flavor, error) {
 	m.ctrl.T.Helper()
@@ -188,6 +246,21 @@ func (mr *MockOpenstackOperationsMockRecorder) GetClosestFlavour(ctx, cpu, memor
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetClosestFlavour", reflect.TypeOf((*MockOpenstackOperations)(nil).GetClosestFlavour), ctx, cpu, memory)
 }
 
+// GetConsoleOutput mocks base method.
+func (m *MockOpenstackOperations) GetConsoleOutput(ctx context.Context, vmID string, lines int) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetConsoleOutput", ctx, vmID, lines)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetConsoleOutput indicates an expected call of GetConsoleOutput.
+func (mr *MockOpenstackOperationsMockRecorder) GetConsoleOutput(ctx, vmID, lines interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetConsoleOutput", reflect.TypeOf((*MockOpenstackOperations)(nil).GetConsoleOutput), ctx, vmID, lines)
+}
+
 // GetFlavor mocks base method.
 func (m *MockOpenstackOperations) GetFlavor(ctx context.Context, flavorId string) (*flavors.Flavor, error) {
 	m.ctrl.T.Helper()
@@ -278,6 +351,36 @@ func (mr *MockOpenstackOperationsMockRecorder) GetSubnet(ctx, network, ip interf
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSubnet", reflect.TypeOf((*MockOpenstackOperations)(nil).GetSubnet), ctx, network, ip)
 }
 
+// GetVolume mocks base method.
+func (m *MockOpenstackOperations) GetVolume(ctx context.Context, volumeID string) (*volumes.Volume, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetVolume", ctx, volumeID)
+	ret0, _ := ret[0].(*volumes.Volume)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetVolume indicates an expected call of GetVolume.
+func (mr *MockOpenstackOperationsMockRecorder) GetVolume(ctx, volumeID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetVolume", reflect.TypeOf((*MockOpenstackOperations)(nil).GetVolume), ctx, volumeID)
+}
+
+// ManageExistingVolume mocks base method.
+func (m *MockOpenstackOperations) ManageExistingVolume(name string, ref map[string]interface{}, host, volumeType string) (*volumes.Volume, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ManageExistingVolume", name, ref, host, volumeType)
+	ret0, _ := ret[0].(*volumes.Volume)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ManageExistingVolume indicates an expected call of ManageExistingVolume.
+func (mr *MockOpenstackOperationsMockRecorder) ManageExistingVolume(name, ref, host, volumeType interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ManageExistingVolume", reflect.TypeOf((*MockOpenstackOperations)(nil).ManageExistingVolume), name, ref, host, volumeType)
+}
+
 // SetVolumeBootable mocks base method.
 func (m *MockOpenstackOperations) SetVolumeBootable(ctx context.Context, volume *volumes.Volume) error {
 	m.ctrl.T.Helper()
@@ -306,6 +409,20 @@ func (mr *MockOpenstackOperationsMockRecorder) SetVolumeImageMetadata(ctx, volum
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetVolumeImageMetadata", reflect.TypeOf((*MockOpenstackOperations)(nil).SetVolumeImageMetadata), ctx, volume, setRDMLabel)
 }
 
+// SetVolumeSecurityFeatures mocks base method.
+func (m *MockOpenstackOperations) SetVolumeSecurityFeatures(ctx context.Context, volume *volumes.Volume, secureBoot, vtpm bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetVolumeSecurityFeatures", ctx, volume, secureBoot, vtpm)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetVolumeSecurityFeatures indicates an expected call of SetVolumeSecurityFeatures.
+func (mr *MockOpenstackOperationsMockRecorder) SetVolumeSecurityFeatures(ctx, volume, secureBoot, vtpm interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetVolumeSecurityFeatures", reflect.TypeOf((*MockOpenstackOperations)(nil).SetVolumeSecurityFeatures), ctx, volume, secureBoot, vtpm)
+}
+
 // SetVolumeUEFI mocks base method.
 func (m *MockOpenstackOperations) SetVolumeUEFI(ctx context.Context, volume *volumes.Volume) error {
 	m.ctrl.T.Helper()
@@ -66,6 +66,80 @@ for iface in $NEW_IFACES; do
 done
 echo "$(date '+%Y-%m-%d %H:%M:%S') - Network fix script completed" >> "$LOG_FILE"`
 
+	// WindowsNetworkPersistenceScript is a firstboot batch file that runs the PowerShell after the
+	// marker line. It removes the VMware adapters left behind by the migration and gives the
+	// adapters with the MAC addresses of the source VM their static addressing back.
+	// WindowsNetworkConfigPlaceholder is replaced by the per-MAC configuration.
+	WindowsNetworkPersistenceScript = `@echo off
+powershell -NoProfile -ExecutionPolicy Bypass -Command "$s = Get-Content -Raw -LiteralPath '%~f0'; $m = '#' + 'POWERSHELL' + '#'; Invoke-Expression $s.Substring($s.IndexOf($m) + $m.Length)"
+exit /b %errorlevel%
+#POWERSHELL#
+$log = "$env:SystemDrive\vjailbreak-network.log"
+function Write-Log($message) {
+    Add-Content -Path $log -Value ("{0} - {1}" -f (Get-Date -Format 'yyyy-MM-dd HH:mm:ss'), $message)
+}
+Write-Log "Starting network persistence script"
+
+$config = @(
+{{NETWORK_CONFIG}}
+)
+
+# Adapters that are no longer present still hold the static addresses of the source VM
+$stale = Get-PnpDevice -Class Net -ErrorAction SilentlyContinue |
+    Where-Object { $_.Status -eq 'Unknown' -and $_.FriendlyName -match 'vmxnet' }
+foreach ($device in $stale) {
+    Write-Log "Removing stale adapter $($device.FriendlyName) ($($device.InstanceId))"
+    & pnputil.exe /remove-device "$($device.InstanceId)" | Out-Null
+    if ($LASTEXITCODE -ne 0) {
+        Write-Log "Could not remove $($device.InstanceId), pnputil exited with $LASTEXITCODE"
+    }
+}
+
+foreach ($nic in $config) {
+    $adapter = $null
+    # The virtio adapter shows up once its driver is installed, which may take a while
+    for ($i = 0; $i -lt 60 -and -not $adapter; $i++) {
+        $adapter = Get-NetAdapter -ErrorAction SilentlyContinue | Where-Object { $_.MacAddress -eq $nic.Mac } | Select-Object -First 1
+        if (-not $adapter) { Start-Sleep -Seconds 5 }
+    }
+    if (-not $adapter) {
+        Write-Log "No adapter with MAC $($nic.Mac), leaving it on DHCP"
+        continue
+    }
+    Write-Log "Configuring $($adapter.Name) with MAC $($nic.Mac)"
+    Set-NetIPInterface -InterfaceIndex $adapter.ifIndex -AddressFamily IPv4 -Dhcp Disabled -ErrorAction SilentlyContinue
+    Get-NetIPAddress -InterfaceIndex $adapter.ifIndex -AddressFamily IPv4 -ErrorAction SilentlyContinue |
+        Remove-NetIPAddress -Confirm:$false -ErrorAction SilentlyContinue
+    # IPv6 addresses from router advertisements and DHCPv6 are kept, the IPv6 default route comes from them
+    Get-NetIPAddress -InterfaceIndex $adapter.ifIndex -AddressFamily IPv6 -PrefixOrigin Manual -ErrorAction SilentlyContinue |
+        Remove-NetIPAddress -Confirm:$false -ErrorAction SilentlyContinue
+    Get-NetRoute -InterfaceIndex $adapter.ifIndex -DestinationPrefix '0.0.0.0/0' -ErrorAction SilentlyContinue |
+        Remove-NetRoute -Confirm:$false -ErrorAction SilentlyContinue
+    foreach ($address in $nic.Addresses) {
+        try {
+            New-NetIPAddress -InterfaceIndex $adapter.ifIndex -IPAddress $address.IP -PrefixLength $address.Prefix -ErrorAction Stop | Out-Null
+            Write-Log "Assigned $($address.IP)/$($address.Prefix)"
+        } catch {
+            Write-Log "Could not assign $($address.IP)/$($address.Prefix): $_"
+        }
+    }
+    if ($nic.Gateway) {
+        try {
+            New-NetRoute -InterfaceIndex $adapter.ifIndex -DestinationPrefix '0.0.0.0/0' -NextHop $nic.Gateway -ErrorAction Stop | Out-Null
+            Write-Log "Set default gateway $($nic.Gateway)"
+        } catch {
+            Write-Log "Could not set default gateway $($nic.Gateway): $_"
+        }
+    }
+    if ($nic.Dns.Count -gt 0) {
+        Set-DnsClientServerAddress -InterfaceIndex $adapter.ifIndex -ServerAddresses $nic.Dns -ErrorAction SilentlyContinue
+        Write-Log "Set DNS servers $($nic.Dns -join ', ')"
+    }
+}
+Write-Log "Network persistence script completed"
+`
+	WindowsNetworkConfigPlaceholder = "{{NETWORK_CONFIG}}"
+
 	MaxCPU = 9999999
 	MaxRAM = 9999999
 
@@ -82,6 +156,9 @@ echo "$(date '+%Y-%m-%d %H:%M:%S') - Network fix script completed" >> "$LOG_FILE
 	NamespaceMigrationSystem = "migration-system"
 	TrueString               = "true"
 
+	// RollingMigrationPlanLabel names the RollingMigrationPlan a batch MigrationPlan was created for
+	RollingMigrationPlanLabel = "vjailbreak.k8s.pf9.io/rollingmigrationplan"
+
 	LogsDir = "/var/log/pf9"
 
 	EventMessageConvertingDisk                    = "Converting disk"
@@ -95,6 +172,20 @@ echo "$(date '+%Y-%m-%d %H:%M:%S') - Network fix script completed" >> "$LOG_FILE
 	EventMessageCopyingDisk                       = "Copying disk"
 	EventMessageFailed                            = "Failed to"
 	EventDisconnect                               = "Disconnected network interfaces"
+	EventMessageVerifyingData                     = "Verifying data integrity"
+	EventMessageDataVerified                      = "Data integrity verified"
+	EventMessageDataIntegrityMismatch             = "Data integrity mismatch"
+	EventMessageRollingBack                       = "Rolling back to the source VM"
+	EventMessageRolledBack                        = "Rolled back to the source VM"
+	EventMessageWaitingForRollback                = "Waiting for rollback request"
+	EventMessageTestBoot                          = "Test boot"
+	EventMessageTestBootStarted                   = "Test boot started"
+	EventMessageTestBootSucceeded                 = "Test boot succeeded"
+	EventMessageTestBootNotSucceeded              = "Test boot did not succeed"
+	EventMessageTestBootRemoved                   = "Test boot removed"
+	EventMessageHealthCheck                       = "Health check "
+	EventMessageHook                              = "Hook "
+	EventMessageFinalSnapshot                     = "Final snapshot "
 
 	// StorageAcceleratedCopy specific event messages
 	EventMessageEsxiSSHConnect                       = "Connecting to ESXi"
@@ -115,6 +206,52 @@ echo "$(date '+%Y-%m-%d %H:%M:%S') - Network fix script completed" >> "$LOG_FILE
 	// VCenterVMScanConcurrencyLimit is the limit for concurrency while scanning vCenter VMs
 	VCenterVMScanConcurrencyLimit = 100
 
+	// BandwidthLimitRefreshInterval is how often the bandwidth caps of a running copy are re-evaluated
+	BandwidthLimitRefreshInterval = 30 * time.Second
+
+	// IntegrityVerificationModeFull hashes every extent of the copied disks
+	IntegrityVerificationModeFull = "Full"
+	// IntegrityVerificationModeSample hashes a random sample of extents of the copied disks
+	IntegrityVerificationModeSample = "Sample"
+	// DefaultIntegrityVerificationSamplePercent is the share of extents hashed in sample mode when unset
+	DefaultIntegrityVerificationSamplePercent = 10
+
+	// RollbackPolicyAutomatic rolls back to the source VM as soon as the cutover fails
+	RollbackPolicyAutomatic = "Automatic"
+	// RollbackPolicyManual keeps the failed cutover in place until a rollback is requested on the Migration
+	RollbackPolicyManual = "Manual"
+	// RollbackRequestPollInterval is how often the Migration is checked for a rollback request
+	RollbackRequestPollInterval = 15 * time.Second
+	// ServerDeleteTimeout is how long to wait for a target server to be deleted during rollback
+	ServerDeleteTimeout = 5 * time.Minute
+	// HealthCheckFailurePolicyWarn reports health checks that did not pass without failing the migration
+	HealthCheckFailurePolicyWarn = "Warn"
+	// HealthCheckFailurePolicyFail fails the migration and keeps the target VM for inspection
+	HealthCheckFailurePolicyFail = "Fail"
+	// HealthCheckFailurePolicyRollback rolls back to the source VM following the rollback policy
+	HealthCheckFailurePolicyRollback = "Rollback"
+	// Health check types
+	HealthCheckTypeTCP        = "TCP"
+	HealthCheckTypeHTTP       = "HTTP"
+	HealthCheckTypeHTTPS      = "HTTPS"
+	HealthCheckTypeConsoleLog = "ConsoleLog"
+	// HealthCheckConsoleLogLines is the number of lines from the end of the console log searched by ConsoleLog checks
+	HealthCheckConsoleLogLines = 1000
+
+	// TestBootPollInterval is how often the Migration is checked for a test boot request
+	TestBootPollInterval = 30 * time.Second
+	// VolumeCloneTimeout is how long to wait for a Cinder clone of a volume to become available
+	VolumeCloneTimeout = 30 * time.Minute
+	// TestBootNameSuffix is appended to the names of the servers, volumes and ports of a test boot
+	TestBootNameSuffix = "-testboot"
+
+	// HookPollInterval is how often the Job of a hook is checked for completion
+	HookPollInterval = 10 * time.Second
+	// HookStartTimeout is how long the controller gets to start the Job of a hook on top of its timeout
+	HookStartTimeout = 5 * time.Minute
+	// DefaultHookTimeoutSeconds is how long a hook may run when it sets no timeout
+	DefaultHookTimeoutSeconds = 600
+
 	// ConfigMap default values
 	ChangedBlocksCopyIterationThreshold = 20
 	PeriodicSyncInterval                = "1h"
@@ -151,6 +288,12 @@ echo "$(date '+%Y-%m-%d %H:%M:%S') - Network fix script completed" >> "$LOG_FILE
 	// VjailbreakSettingsConfigMapName is the name of the vjailbreak settings configmap
 	VjailbreakSettingsConfigMapName = "vjailbreak-settings"
 
+	// MigrationCheckpointConfigMapPrefix is the prefix of the per-VM configmap holding disk replication checkpoints
+	MigrationCheckpointConfigMapPrefix = "migration-checkpoint-"
+
+	// MigrationCheckpointKey is the configmap key under which the replication checkpoint is stored
+	MigrationCheckpointKey = "checkpoint"
+
 	// VCenterLoginRetryLimit is the number of retries for vcenter login
 	VCenterLoginRetryLimit = 1
 
@@ -184,6 +327,15 @@ echo "$(date '+%Y-%m-%d %H:%M:%S') - Network fix script completed" >> "$LOG_FILE
 	// StorageCopyMethod is the default value for storage copy method
 	StorageCopyMethod = "StorageAcceleratedCopy"
 
+	// DestinationTypeFile writes the converted disks and a libvirt domain XML to DestinationMountPath
+	// instead of creating volumes and a server in OpenStack
+	DestinationTypeFile = "file"
+	// DestinationMountPath is where the PVC of a file destination is mounted
+	DestinationMountPath = "/home/fedora/destination"
+	// Image formats of a file destination
+	DestinationFormatQcow2 = "qcow2"
+	DestinationFormatRaw   = "raw"
+
 	// MaxPowerOffRetryLimit is the max number of retries for power off status check
 	MaxPowerOffRetryLimit = 3
 
@@ -219,4 +371,44 @@ echo "$(date '+%Y-%m-%d %H:%M:%S') - Network fix script completed" >> "$LOG_FILE
 	V2VHelperPodEphemeralStorageLimit = "3Gi"
 	// V2VHelperPodEphemeralStorageLimitKey is the key for v2v-helper pod ephemeral storage limit
 	V2VHelperPodEphemeralStorageLimitKey = "V2V_HELPER_POD_EPHEMERAL_STORAGE_LIMIT"
+
+	// DiskCopyParallelism is the default number of disks of a VM copied in parallel
+	DiskCopyParallelism = 1
+	// DiskCopyParallelismKey is the key for the number of disks of a VM copied in parallel
+	DiskCopyParallelismKey = "DISK_COPY_PARALLELISM"
+
+	// ESXiHostBandwidthLimits is the default per-ESXi-host bandwidth cap, empty means unlimited
+	ESXiHostBandwidthLimits = ""
+	// ESXiHostBandwidthLimitsKey is the settings key for bandwidth caps in Mbps per ESXi host, e.g. "esxi-01=400,*=800"
+	ESXiHostBandwidthLimitsKey = "ESXI_HOST_BANDWIDTH_LIMITS_MBPS"
+
+	// DatastoreBandwidthLimits is the default per-datastore bandwidth cap, empty means unlimited
+	DatastoreBandwidthLimits = ""
+	// DatastoreBandwidthLimitsKey is the settings key for bandwidth caps in Mbps per datastore, e.g. "datastore1=300,*=1000"
+	DatastoreBandwidthLimitsKey = "DATASTORE_BANDWIDTH_LIMITS_MBPS"
+
+	// RollbackRequestTimeoutMinutes is how long a failed cutover waits for a rollback request under the Manual rollback policy
+	RollbackRequestTimeoutMinutes = 60
+	// RollbackRequestTimeoutMinutesKey is the key for the rollback request timeout
+	RollbackRequestTimeoutMinutesKey = "ROLLBACK_REQUEST_TIMEOUT_MINUTES"
+
+	// MaxMigrationsPerAgent is the default number of migrations an agent runs at the same time
+	MaxMigrationsPerAgent = 4
+	// MaxMigrationsPerAgentKey is the key for the number of migrations an agent runs at the same time
+	MaxMigrationsPerAgentKey = "MAX_MIGRATIONS_PER_AGENT"
+
+	// VCenterMigrationLimits is the default per-vCenter migration limit, empty means unlimited
+	VCenterMigrationLimits = ""
+	// VCenterMigrationLimitsKey is the settings key for the number of migrations copying from a vCenter at the same time, keyed by VMwareCreds name, e.g. "vcenter-a=10,*=20"
+	VCenterMigrationLimitsKey = "VCENTER_MIGRATION_LIMITS"
+
+	// ESXiHostMigrationLimits is the default per-ESXi-host migration limit, empty means unlimited
+	ESXiHostMigrationLimits = ""
+	// ESXiHostMigrationLimitsKey is the settings key for the number of migrations copying from an ESXi host at the same time, e.g. "esxi-01=2,*=4"
+	ESXiHostMigrationLimitsKey = "ESXI_HOST_MIGRATION_LIMITS"
+
+	// DatastoreMigrationLimits is the default per-datastore migration limit, empty means unlimited
+	DatastoreMigrationLimits = ""
+	// DatastoreMigrationLimitsKey is the settings key for the number of migrations copying from a datastore at the same time, e.g. "datastore1=3,*=6"
+	DatastoreMigrationLimitsKey = "DATASTORE_MIGRATION_LIMITS"
 )
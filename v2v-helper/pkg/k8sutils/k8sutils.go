@@ -2,6 +2,7 @@ package k8sutils
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"strconv"
@@ -9,14 +10,18 @@ import (
 
 	"github.com/pkg/errors"
 	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+	commonutils "github.com/platform9/vjailbreak/pkg/common/utils"
 	"github.com/platform9/vjailbreak/v2v-helper/pkg/constants"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	k8stypes "k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/retry"
 	client "sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -128,6 +133,14 @@ func GetVjailbreakSettings(ctx context.Context, k8sClient client.Client) (*Vjail
 			V2VHelperPodMemoryLimit:             constants.V2VHelperPodMemoryLimit,
 			V2VHelperPodEphemeralStorageRequest: constants.V2VHelperPodEphemeralStorageRequest,
 			V2VHelperPodEphemeralStorageLimit:   constants.V2VHelperPodEphemeralStorageLimit,
+			DiskCopyParallelism:                 constants.DiskCopyParallelism,
+			ESXiHostBandwidthLimits:             constants.ESXiHostBandwidthLimits,
+			DatastoreBandwidthLimits:            constants.DatastoreBandwidthLimits,
+			RollbackRequestTimeoutMinutes:       constants.RollbackRequestTimeoutMinutes,
+			MaxMigrationsPerAgent:               constants.MaxMigrationsPerAgent,
+			VCenterMigrationLimits:              constants.VCenterMigrationLimits,
+			ESXiHostMigrationLimits:             constants.ESXiHostMigrationLimits,
+			DatastoreMigrationLimits:            constants.DatastoreMigrationLimits,
 		}, nil
 	}
 
@@ -226,6 +239,18 @@ func GetVjailbreakSettings(ctx context.Context, k8sClient client.Client) (*Vjail
 		vjailbreakSettingsCM.Data[constants.V2VHelperPodEphemeralStorageLimitKey] = constants.V2VHelperPodEphemeralStorageLimit
 	}
 
+	if vjailbreakSettingsCM.Data[constants.DiskCopyParallelismKey] == "" {
+		vjailbreakSettingsCM.Data[constants.DiskCopyParallelismKey] = strconv.Itoa(constants.DiskCopyParallelism)
+	}
+
+	if vjailbreakSettingsCM.Data[constants.RollbackRequestTimeoutMinutesKey] == "" {
+		vjailbreakSettingsCM.Data[constants.RollbackRequestTimeoutMinutesKey] = strconv.Itoa(constants.RollbackRequestTimeoutMinutes)
+	}
+
+	if vjailbreakSettingsCM.Data[constants.MaxMigrationsPerAgentKey] == "" {
+		vjailbreakSettingsCM.Data[constants.MaxMigrationsPerAgentKey] = strconv.Itoa(constants.MaxMigrationsPerAgent)
+	}
+
 	return &VjailbreakSettings{
 		ChangedBlocksCopyIterationThreshold: atoi(vjailbreakSettingsCM.Data["CHANGED_BLOCKS_COPY_ITERATION_THRESHOLD"]),
 		PeriodicSyncInterval:                vjailbreakSettingsCM.Data["PERIODIC_SYNC_INTERVAL"],
@@ -252,6 +277,14 @@ func GetVjailbreakSettings(ctx context.Context, k8sClient client.Client) (*Vjail
 		V2VHelperPodMemoryLimit:             vjailbreakSettingsCM.Data[constants.V2VHelperPodMemoryLimitKey],
 		V2VHelperPodEphemeralStorageRequest: vjailbreakSettingsCM.Data[constants.V2VHelperPodEphemeralStorageRequestKey],
 		V2VHelperPodEphemeralStorageLimit:   vjailbreakSettingsCM.Data[constants.V2VHelperPodEphemeralStorageLimitKey],
+		DiskCopyParallelism:                 atoi(vjailbreakSettingsCM.Data[constants.DiskCopyParallelismKey]),
+		ESXiHostBandwidthLimits:             vjailbreakSettingsCM.Data[constants.ESXiHostBandwidthLimitsKey],
+		DatastoreBandwidthLimits:            vjailbreakSettingsCM.Data[constants.DatastoreBandwidthLimitsKey],
+		RollbackRequestTimeoutMinutes:       atoi(vjailbreakSettingsCM.Data[constants.RollbackRequestTimeoutMinutesKey]),
+		MaxMigrationsPerAgent:               atoi(vjailbreakSettingsCM.Data[constants.MaxMigrationsPerAgentKey]),
+		VCenterMigrationLimits:              vjailbreakSettingsCM.Data[constants.VCenterMigrationLimitsKey],
+		ESXiHostMigrationLimits:             vjailbreakSettingsCM.Data[constants.ESXiHostMigrationLimitsKey],
+		DatastoreMigrationLimits:            vjailbreakSettingsCM.Data[constants.DatastoreMigrationLimitsKey],
 	}, nil
 }
 
@@ -293,3 +326,298 @@ func GetESXiSSHPrivateKey(ctx context.Context, k8sClient client.Client, secretNa
 
 	return privateKey, nil
 }
+
+// GetGuestCredentials retrieves the username and password of a guest account from a Kubernetes secret
+func GetGuestCredentials(ctx context.Context, k8sClient client.Client, secretName string) (string, string, error) {
+	secret := &corev1.Secret{}
+	if err := k8sClient.Get(ctx, k8stypes.NamespacedName{
+		Name:      secretName,
+		Namespace: constants.NamespaceMigrationSystem,
+	}, secret); err != nil {
+		return "", "", errors.Wrapf(err, "failed to get guest credentials secret %s", secretName)
+	}
+
+	username := string(secret.Data["username"])
+	if username == "" {
+		return "", "", fmt.Errorf("secret %s does not contain 'username' key", secretName)
+	}
+	return username, string(secret.Data["password"]), nil
+}
+
+// GetMigrationCheckpointConfigMapName returns the name of the checkpoint configmap of a VM
+func GetMigrationCheckpointConfigMapName(vmK8sName string) string {
+	return constants.MigrationCheckpointConfigMapPrefix + vmK8sName
+}
+
+// GetMigrationCheckpoint reads the disk replication checkpoint of a VM.
+// It returns nil without an error if no checkpoint has been recorded yet.
+func GetMigrationCheckpoint(ctx context.Context, k8sClient client.Client, vmK8sName string) (*MigrationCheckpoint, error) {
+	checkpointCM := &corev1.ConfigMap{}
+	if err := k8sClient.Get(ctx, k8stypes.NamespacedName{Name: GetMigrationCheckpointConfigMapName(vmK8sName), Namespace: constants.NamespaceMigrationSystem}, checkpointCM); err != nil {
+		return nil, errors.Wrap(err, "failed to get migration checkpoint configmap")
+	}
+	data := checkpointCM.Data[constants.MigrationCheckpointKey]
+	if data == "" {
+		return nil, nil
+	}
+	checkpoint := &MigrationCheckpoint{}
+	if err := json.Unmarshal([]byte(data), checkpoint); err != nil {
+		return nil, errors.Wrap(err, "failed to parse migration checkpoint")
+	}
+	return checkpoint, nil
+}
+
+// SaveMigrationCheckpoint stores the disk replication checkpoint of a VM.
+// Passing a nil checkpoint clears it.
+func SaveMigrationCheckpoint(ctx context.Context, k8sClient client.Client, vmK8sName string, checkpoint *MigrationCheckpoint) error {
+	data := ""
+	if checkpoint != nil {
+		raw, err := json.Marshal(checkpoint)
+		if err != nil {
+			return errors.Wrap(err, "failed to marshal migration checkpoint")
+		}
+		data = string(raw)
+	}
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		checkpointCM := &corev1.ConfigMap{}
+		if err := k8sClient.Get(ctx, k8stypes.NamespacedName{Name: GetMigrationCheckpointConfigMapName(vmK8sName), Namespace: constants.NamespaceMigrationSystem}, checkpointCM); err != nil {
+			return errors.Wrap(err, "failed to get migration checkpoint configmap")
+		}
+		if checkpointCM.Data == nil {
+			checkpointCM.Data = map[string]string{}
+		}
+		checkpointCM.Data[constants.MigrationCheckpointKey] = data
+		return k8sClient.Update(ctx, checkpointCM)
+	})
+}
+
+// IsMigrationActive reports whether the Migration object of a VM still exists and is not being deleted
+func IsMigrationActive(ctx context.Context, k8sClient client.Client, vmK8sName string) (bool, error) {
+	migration := &vjailbreakv1alpha1.Migration{}
+	err := k8sClient.Get(ctx, k8stypes.NamespacedName{Name: fmt.Sprintf("migration-%s", vmK8sName), Namespace: constants.NamespaceMigrationSystem}, migration)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, errors.Wrap(err, "failed to get migration")
+	}
+	return migration.DeletionTimestamp.IsZero(), nil
+}
+
+// IsRollbackRequested reports whether a rollback to the source VM was requested on the Migration of a VM
+func IsRollbackRequested(ctx context.Context, k8sClient client.Client, vmK8sName string) (bool, error) {
+	migration := &vjailbreakv1alpha1.Migration{}
+	if err := k8sClient.Get(ctx, k8stypes.NamespacedName{Name: fmt.Sprintf("migration-%s", vmK8sName), Namespace: constants.NamespaceMigrationSystem}, migration); err != nil {
+		return false, errors.Wrap(err, "failed to get migration")
+	}
+	return migration.Spec.Rollback, nil
+}
+
+// GetCutoverBlockers returns the VMs of the plan the cutover of the Migration of a VM waits for
+func GetCutoverBlockers(ctx context.Context, k8sClient client.Client, vmK8sName string) ([]string, error) {
+	migration := &vjailbreakv1alpha1.Migration{}
+	if err := k8sClient.Get(ctx, k8stypes.NamespacedName{Name: fmt.Sprintf("migration-%s", vmK8sName), Namespace: constants.NamespaceMigrationSystem}, migration); err != nil {
+		return nil, errors.Wrap(err, "failed to get migration")
+	}
+	return migration.Status.BlockedBy, nil
+}
+
+// GetTestBootRequest returns the test boot requested on the Migration of a VM, nil when none is requested
+func GetTestBootRequest(ctx context.Context, k8sClient client.Client, vmK8sName string) (*vjailbreakv1alpha1.TestBootSpec, error) {
+	migration := &vjailbreakv1alpha1.Migration{}
+	if err := k8sClient.Get(ctx, k8stypes.NamespacedName{Name: fmt.Sprintf("migration-%s", vmK8sName), Namespace: constants.NamespaceMigrationSystem}, migration); err != nil {
+		return nil, errors.Wrap(err, "failed to get migration")
+	}
+	return migration.Spec.TestBoot, nil
+}
+
+// RequestHookStage asks the controller to run the hooks of a stage for the Migration of a VM. The
+// target server ID and IPs are passed on to the hooks, they are empty before the cutover.
+func RequestHookStage(ctx context.Context, k8sClient client.Client, vmK8sName, stage, serverID string, ips []string) error {
+	return retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		migration := &vjailbreakv1alpha1.Migration{}
+		if err := k8sClient.Get(ctx, k8stypes.NamespacedName{Name: fmt.Sprintf("migration-%s", vmK8sName), Namespace: constants.NamespaceMigrationSystem}, migration); err != nil {
+			return errors.Wrap(err, "failed to get migration")
+		}
+		if migration.Annotations == nil {
+			migration.Annotations = map[string]string{}
+		}
+		migration.Annotations[vjailbreakv1alpha1.HookStageAnnotation] = stage
+		migration.Annotations[vjailbreakv1alpha1.HookTargetServerIDAnnotation] = serverID
+		migration.Annotations[vjailbreakv1alpha1.HookTargetIPsAnnotation] = strings.Join(ips, ",")
+		return k8sClient.Update(ctx, migration)
+	})
+}
+
+// GetHookJob returns the Job running a hook at a stage for a VM, nil when it was not created yet
+func GetHookJob(ctx context.Context, k8sClient client.Client, vmK8sName, stage, hookName string) (*batchv1.Job, error) {
+	jobs := &batchv1.JobList{}
+	if err := k8sClient.List(ctx, jobs, client.InNamespace(constants.NamespaceMigrationSystem), client.MatchingLabels{
+		vjailbreakv1alpha1.HookVMLabel:    vmK8sName,
+		vjailbreakv1alpha1.HookStageLabel: stage,
+		vjailbreakv1alpha1.HookNameLabel:  hookName,
+	}); err != nil {
+		return nil, errors.Wrap(err, "failed to list hook jobs")
+	}
+	if len(jobs.Items) == 0 {
+		return nil, nil
+	}
+	return &jobs.Items[0], nil
+}
+
+// ParseBandwidthLimits parses a bandwidth caps setting of the form "name=mbps,name=mbps".
+// The name "*" sets the cap of every name that is not listed. Invalid entries are skipped.
+func ParseBandwidthLimits(value string) map[string]int {
+	return parseNamedLimits(value)
+}
+
+// ParseMigrationLimits parses a migration limits setting of the form "name=count,name=count".
+// The name "*" sets the limit of every name that is not listed. Invalid entries are skipped.
+func ParseMigrationLimits(value string) map[string]int {
+	return parseNamedLimits(value)
+}
+
+// parseNamedLimits parses positive limits of the form "name=limit,name=limit"
+func parseNamedLimits(value string) map[string]int {
+	limits := map[string]int{}
+	for _, entry := range strings.Split(value, ",") {
+		name, limit, found := strings.Cut(strings.TrimSpace(entry), "=")
+		if !found {
+			continue
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(limit))
+		if err != nil || n <= 0 {
+			continue
+		}
+		limits[strings.TrimSpace(name)] = n
+	}
+	return limits
+}
+
+// BandwidthLimitFor returns the cap in Mbps that applies to name, 0 if it is unlimited
+func BandwidthLimitFor(limits map[string]int, name string) int {
+	return limitFor(limits, name)
+}
+
+// MigrationLimitFor returns the number of migrations allowed for name, 0 if it is unlimited
+func MigrationLimitFor(limits map[string]int, name string) int {
+	return limitFor(limits, name)
+}
+
+// limitFor returns the limit of name, or the "*" limit when name is not listed
+func limitFor(limits map[string]int, name string) int {
+	if limit, ok := limits[name]; ok {
+		return limit
+	}
+	return limits["*"]
+}
+
+// GetMigrationPlanBandwidthLimit returns the bandwidth cap in Mbps of the plan migrating a VM, 0 if it is unlimited.
+// The cap of a RollingMigrationPlan applies to the migrations of its batch MigrationPlans.
+func GetMigrationPlanBandwidthLimit(ctx context.Context, k8sClient client.Client, vmK8sName string) (int, error) {
+	migration := &vjailbreakv1alpha1.Migration{}
+	if err := k8sClient.Get(ctx, k8stypes.NamespacedName{Name: fmt.Sprintf("migration-%s", vmK8sName), Namespace: constants.NamespaceMigrationSystem}, migration); err != nil {
+		return 0, errors.Wrap(err, "failed to get migration")
+	}
+	rollingPlanName := migration.Spec.MigrationPlan
+	migrationPlan := &vjailbreakv1alpha1.MigrationPlan{}
+	err := k8sClient.Get(ctx, k8stypes.NamespacedName{Name: migration.Spec.MigrationPlan, Namespace: constants.NamespaceMigrationSystem}, migrationPlan)
+	switch {
+	case err == nil:
+		owner := rollingMigrationPlanOwner(migrationPlan)
+		if owner == "" {
+			return migrationPlan.Spec.MigrationStrategy.BandwidthLimitMbps, nil
+		}
+		rollingPlanName = owner
+	case !apierrors.IsNotFound(err):
+		return 0, errors.Wrap(err, "failed to get migration plan")
+	}
+	rollingMigrationPlan := &vjailbreakv1alpha1.RollingMigrationPlan{}
+	if err := k8sClient.Get(ctx, k8stypes.NamespacedName{Name: rollingPlanName, Namespace: constants.NamespaceMigrationSystem}, rollingMigrationPlan); err != nil {
+		return 0, errors.Wrap(err, "failed to get rolling migration plan")
+	}
+	return rollingMigrationPlan.Spec.MigrationStrategy.BandwidthLimitMbps, nil
+}
+
+// rollingMigrationPlanOwner returns the name of the RollingMigrationPlan a MigrationPlan was created for,
+// "" if it was created on its own
+func rollingMigrationPlanOwner(migrationPlan *vjailbreakv1alpha1.MigrationPlan) string {
+	if name := migrationPlan.Labels[constants.RollingMigrationPlanLabel]; name != "" {
+		return name
+	}
+	for _, owner := range migrationPlan.OwnerReferences {
+		if owner.Kind == vjailbreakv1alpha1.WavePlanKindRollingMigrationPlan {
+			return owner.Name
+		}
+	}
+	return ""
+}
+
+// CountCopyingMigrations returns the number of migrations copying data from each ESXi host and
+// datastore. The VM vmK8sName is counted even if its migration has not reached a copy phase yet.
+func CountCopyingMigrations(ctx context.Context, k8sClient client.Client, vmK8sName string) (hosts, datastores map[string]int, err error) {
+	migrationList := &vjailbreakv1alpha1.MigrationList{}
+	if err := k8sClient.List(ctx, migrationList, client.InNamespace(constants.NamespaceMigrationSystem)); err != nil {
+		return nil, nil, errors.Wrap(err, "failed to list migrations")
+	}
+	vmwareMachineList := &vjailbreakv1alpha1.VMwareMachineList{}
+	if err := k8sClient.List(ctx, vmwareMachineList, client.InNamespace(constants.NamespaceMigrationSystem)); err != nil {
+		return nil, nil, errors.Wrap(err, "failed to list vmware machines")
+	}
+	vmInfos := map[string]vjailbreakv1alpha1.VMInfo{}
+	for _, vmwareMachine := range vmwareMachineList.Items {
+		vmInfos[vmwareMachine.Name] = vmwareMachine.Spec.VMInfo
+	}
+
+	hosts = map[string]int{}
+	datastores = map[string]int{}
+	for _, migration := range migrationList.Items {
+		name := strings.TrimPrefix(migration.Name, "migration-")
+		switch migration.Status.Phase {
+		case vjailbreakv1alpha1.VMMigrationPhaseCopying,
+			vjailbreakv1alpha1.VMMigrationPhaseCopyingChangedBlocks,
+			vjailbreakv1alpha1.VMMigrationPhaseVerifyingData:
+		default:
+			if name != vmK8sName {
+				continue
+			}
+		}
+		vmInfo, ok := vmInfos[name]
+		if !ok {
+			continue
+		}
+		hosts[vmInfo.ESXiName]++
+		for _, datastore := range vmInfo.Datastores {
+			datastores[datastore]++
+		}
+	}
+	return hosts, datastores, nil
+}
+
+// NewMaintenanceCalendar builds the calendar of the windows and blackouts of a maintenance window
+func NewMaintenanceCalendar(spec vjailbreakv1alpha1.MaintenanceWindowSpec) (*commonutils.MaintenanceCalendar, error) {
+	calendar := &commonutils.MaintenanceCalendar{}
+	for _, window := range spec.Windows {
+		recurring, err := commonutils.NewRecurringWindow(window.Schedule, window.Duration.Duration, window.TimeZone)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid window %q", window.Schedule)
+		}
+		calendar.Windows = append(calendar.Windows, recurring)
+	}
+	for _, blackout := range spec.Blackouts {
+		if !blackout.End.After(blackout.Start.Time) {
+			return nil, errors.Errorf("blackout %q ends before it starts", blackout.Name)
+		}
+		calendar.Blackouts = append(calendar.Blackouts, commonutils.Blackout{Start: blackout.Start.Time, End: blackout.End.Time})
+	}
+	return calendar, nil
+}
+
+// GetMaintenanceCalendar returns the calendar of the maintenance window name
+func GetMaintenanceCalendar(ctx context.Context, k8sClient client.Client, name string) (*commonutils.MaintenanceCalendar, error) {
+	window := &vjailbreakv1alpha1.MaintenanceWindow{}
+	if err := k8sClient.Get(ctx, k8stypes.NamespacedName{Name: name, Namespace: constants.NamespaceMigrationSystem}, window); err != nil {
+		return nil, errors.Wrapf(err, "failed to get maintenance window %s", name)
+	}
+	return NewMaintenanceCalendar(window.Spec)
+}
@@ -0,0 +1,134 @@
+package k8sutils
+
+import (
+	"context"
+	"testing"
+
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+	"github.com/platform9/vjailbreak/v2v-helper/pkg/constants"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrlfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestParseMigrationLimits(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  map[string]int
+	}{
+		{name: "empty", value: "", want: map[string]int{}},
+		{name: "limits", value: "vcenter-a=4,ds1=2", want: map[string]int{"vcenter-a": 4, "ds1": 2}},
+		{name: "spaces", value: " esx-01 = 3 , * = 1 ", want: map[string]int{"esx-01": 3, "*": 1}},
+		{name: "last entry wins", value: "ds1=2,ds1=5", want: map[string]int{"ds1": 5}},
+		{
+			name:  "invalid entries are skipped",
+			value: "ds1,ds2=two,ds3=0,ds4=-1,ds5=2",
+			want:  map[string]int{"ds5": 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ParseMigrationLimits(tt.value))
+		})
+	}
+}
+
+func TestMigrationLimitFor(t *testing.T) {
+	limits := ParseMigrationLimits("esx-01=3,*=1")
+	assert.Equal(t, 3, MigrationLimitFor(limits, "esx-01"))
+	assert.Equal(t, 1, MigrationLimitFor(limits, "esx-02"))
+
+	limits = ParseMigrationLimits("esx-01=3")
+	assert.Equal(t, 0, MigrationLimitFor(limits, "esx-02"))
+	assert.Equal(t, 0, MigrationLimitFor(map[string]int{}, "esx-01"))
+}
+
+func TestGetMigrationPlanBandwidthLimit(t *testing.T) {
+	vmK8sName := "test-vm"
+	scheme := runtime.NewScheme()
+	assert.NoError(t, vjailbreakv1alpha1.AddToScheme(scheme))
+
+	migration := func(plan string) *vjailbreakv1alpha1.Migration {
+		return &vjailbreakv1alpha1.Migration{
+			ObjectMeta: metav1.ObjectMeta{Name: "migration-" + vmK8sName, Namespace: constants.NamespaceMigrationSystem},
+			Spec:       vjailbreakv1alpha1.MigrationSpec{MigrationPlan: plan},
+		}
+	}
+	migrationPlan := func(name string, limit int, labels map[string]string, owners ...metav1.OwnerReference) *vjailbreakv1alpha1.MigrationPlan {
+		plan := &vjailbreakv1alpha1.MigrationPlan{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: constants.NamespaceMigrationSystem, Labels: labels, OwnerReferences: owners},
+		}
+		plan.Spec.MigrationStrategy.BandwidthLimitMbps = limit
+		return plan
+	}
+	rollingPlan := &vjailbreakv1alpha1.RollingMigrationPlan{
+		ObjectMeta: metav1.ObjectMeta{Name: "rolling", Namespace: constants.NamespaceMigrationSystem},
+	}
+	rollingPlan.Spec.MigrationStrategy.BandwidthLimitMbps = 200
+
+	tests := []struct {
+		name      string
+		objects   []client.Object
+		want      int
+		expectErr bool
+	}{
+		{
+			name:    "migration plan",
+			objects: []client.Object{migration("plan"), migrationPlan("plan", 100, nil)},
+			want:    100,
+		},
+		{
+			name:    "unlimited migration plan",
+			objects: []client.Object{migration("plan"), migrationPlan("plan", 0, nil)},
+			want:    0,
+		},
+		{
+			name: "batch plan labelled with its rolling migration plan",
+			objects: []client.Object{
+				migration("rolling-batch-0"),
+				migrationPlan("rolling-batch-0", 100, map[string]string{constants.RollingMigrationPlanLabel: "rolling"}),
+				rollingPlan,
+			},
+			want: 200,
+		},
+		{
+			name: "batch plan owned by its rolling migration plan",
+			objects: []client.Object{
+				migration("rolling-batch-0"),
+				migrationPlan("rolling-batch-0", 100, nil, metav1.OwnerReference{Kind: "RollingMigrationPlan", Name: "rolling"}),
+				rollingPlan,
+			},
+			want: 200,
+		},
+		{
+			name:    "rolling migration plan referenced by the migration",
+			objects: []client.Object{migration("rolling"), rollingPlan},
+			want:    200,
+		},
+		{
+			name:      "no plan",
+			objects:   []client.Object{migration("plan")},
+			expectErr: true,
+		},
+		{
+			name:      "no migration",
+			objects:   []client.Object{migrationPlan("plan", 100, nil)},
+			expectErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k8sClient := ctrlfake.NewClientBuilder().WithScheme(scheme).WithObjects(tt.objects...).Build()
+			got, err := GetMigrationPlanBandwidthLimit(context.TODO(), k8sClient, vmK8sName)
+			if tt.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
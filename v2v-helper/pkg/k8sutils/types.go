@@ -27,4 +27,46 @@ type VjailbreakSettings struct {
 	V2VHelperPodMemoryLimit             string
 	V2VHelperPodEphemeralStorageRequest string
 	V2VHelperPodEphemeralStorageLimit   string
+	DiskCopyParallelism                 int
+	ESXiHostBandwidthLimits             string
+	DatastoreBandwidthLimits            string
+	RollbackRequestTimeoutMinutes       int
+	MaxMigrationsPerAgent               int
+	VCenterMigrationLimits              string
+	ESXiHostMigrationLimits             string
+	DatastoreMigrationLimits            string
+}
+
+// DiskCheckpoint records how far replication of a single source disk has progressed
+type DiskCheckpoint struct {
+	// DiskName is the name of the source disk
+	DiskName string `json:"diskName"`
+	// DeviceKey is the vCenter device key of the source disk
+	DeviceKey int32 `json:"deviceKey"`
+	// VolumeID is the ID of the Cinder volume the disk is being copied to
+	VolumeID string `json:"volumeID,omitempty"`
+	// ChangeID is the CBT change ID up to which the volume holds the disk contents
+	ChangeID string `json:"changeID,omitempty"`
+	// FullCopyCompleted is true once the initial full copy of the disk has finished
+	FullCopyCompleted bool `json:"fullCopyCompleted"`
+}
+
+// MigrationCheckpoint is the replication state of a VM that survives v2v-helper pod restarts
+type MigrationCheckpoint struct {
+	// VMUUID is the UUID of the source VM, used to reject checkpoints of another VM
+	VMUUID string `json:"vmUUID"`
+	// CBTIteration is the number of changed block copy iterations already performed
+	CBTIteration int `json:"cbtIteration"`
+	// Disks holds the per-disk checkpoints
+	Disks []DiskCheckpoint `json:"disks,omitempty"`
+}
+
+// GetDisk returns the checkpoint of the disk with the given device key, or nil if there is none
+func (c *MigrationCheckpoint) GetDisk(deviceKey int32) *DiskCheckpoint {
+	for idx := range c.Disks {
+		if c.Disks[idx].DeviceKey == deviceKey {
+			return &c.Disks[idx]
+		}
+	}
+	return nil
 }
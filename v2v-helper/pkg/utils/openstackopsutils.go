@@ -163,6 +163,43 @@ func (osclient *OpenStackClients) CreateVolume(ctx context.Context, name string,
 	return volume, nil
 }
 
+// CloneVolume creates a Cinder clone of a volume and waits until it is available. The clone has
+// the type, bootable flag and image metadata of the source volume.
+func (osclient *OpenStackClients) CloneVolume(ctx context.Context, volumeID, name string) (*volumes.Volume, error) {
+	PrintLog(fmt.Sprintf("OPENSTACK API: Cloning volume %s into %s, authurl %s, tenant %s", volumeID, name, osclient.AuthURL, osclient.Tenant))
+	source, err := volumes.Get(ctx, osclient.BlockStorageClient, volumeID).Extract()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get volume: %s", err)
+	}
+	opts := volumes.CreateOpts{
+		Name:        name,
+		Size:        source.Size,
+		SourceVolID: volumeID,
+	}
+	clone, err := volumes.Create(ctx, osclient.BlockStorageClient, opts, nil).Extract()
+	if err != nil {
+		return nil, fmt.Errorf("failed to clone volume: %s", err)
+	}
+	waitCtx, cancel := context.WithTimeout(ctx, constants.VolumeCloneTimeout)
+	defer cancel()
+	err = gophercloud.WaitFor(waitCtx, func(ctx context.Context) (bool, error) {
+		current, err := volumes.Get(ctx, osclient.BlockStorageClient, clone.ID).Extract()
+		if err != nil {
+			return false, err
+		}
+		if current.Status == "error" {
+			return false, fmt.Errorf("volume %s is in error state", clone.ID)
+		}
+		clone = current
+		return current.Status == "available", nil
+	})
+	if err != nil {
+		return clone, fmt.Errorf("failed to wait for volume clone %s: %s", clone.ID, err)
+	}
+	PrintLog(fmt.Sprintf("Volume %s cloned into %s", volumeID, clone.ID))
+	return clone, nil
+}
+
 func (osclient *OpenStackClients) DeleteVolume(ctx context.Context, volumeID string) error {
 	PrintLog(fmt.Sprintf("OPENSTACK API: Deleting volume with ID %s, authurl %s, tenant %s", volumeID, osclient.AuthURL, osclient.Tenant))
 	err := volumes.Delete(ctx, osclient.BlockStorageClient, volumeID, volumes.DeleteOpts{}).ExtractErr()
@@ -172,6 +209,26 @@ func (osclient *OpenStackClients) DeleteVolume(ctx context.Context, volumeID str
 	return nil
 }
 
+func (osclient *OpenStackClients) GetVolume(ctx context.Context, volumeID string) (*volumes.Volume, error) {
+	PrintLog(fmt.Sprintf("OPENSTACK API: Getting volume with ID %s, authurl %s, tenant %s", volumeID, osclient.AuthURL, osclient.Tenant))
+	volume, err := volumes.Get(ctx, osclient.BlockStorageClient, volumeID).Extract()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get volume: %s", err)
+	}
+	return volume, nil
+}
+
+// DetachVolumeFromServer detaches a volume from an arbitrary server, e.g. an agent
+// that ran a previous attempt of the migration and left the volume attached.
+func (osclient *OpenStackClients) DetachVolumeFromServer(ctx context.Context, serverID, volumeID string) error {
+	PrintLog(fmt.Sprintf("OPENSTACK API: Detaching volume %s from server %s, authurl %s, tenant %s", volumeID, serverID, osclient.AuthURL, osclient.Tenant))
+	err := volumeattach.Delete(ctx, osclient.ComputeClient, serverID, volumeID).ExtractErr()
+	if err != nil && !strings.Contains(err.Error(), "is not attached") {
+		return fmt.Errorf("failed to detach volume from server: %s", err)
+	}
+	return nil
+}
+
 func (osclient *OpenStackClients) WaitForVolume(ctx context.Context, volumeID string) error {
 	// Get vjailbreak settings
 	vjailbreakSettings, err := k8sutils.GetVjailbreakSettings(ctx, osclient.K8sClient)
@@ -339,6 +396,29 @@ func (osclient *OpenStackClients) SetVolumeUEFI(ctx context.Context, volume *vol
 	return nil
 }
 
+// SetVolumeSecurityFeatures sets the image properties that make Nova boot the volume with Secure Boot
+// and a vTPM. Both need the q35 machine type. vSphere only offers TPM 2.0 with the CRB interface.
+func (osclient *OpenStackClients) SetVolumeSecurityFeatures(ctx context.Context, volume *volumes.Volume, secureBoot, vtpm bool) error {
+	PrintLog(fmt.Sprintf("OPENSTACK API: Setting Secure Boot %v and vTPM %v for volume %s, authurl %s, tenant %s", secureBoot, vtpm, volume.ID, osclient.AuthURL, osclient.Tenant))
+	options := volumes.ImageMetadataOpts{
+		Metadata: map[string]string{
+			"hw_machine_type": "q35",
+		},
+	}
+	if secureBoot {
+		options.Metadata["os_secure_boot"] = "required"
+	}
+	if vtpm {
+		options.Metadata["hw_tpm_version"] = "2.0"
+		options.Metadata["hw_tpm_model"] = "tpm-crb"
+	}
+	err := volumes.SetImageMetadata(ctx, osclient.BlockStorageClient, volume.ID, options).ExtractErr()
+	if err != nil {
+		return fmt.Errorf("failed to set volume image metadata for secure boot and vtpm: %s", err)
+	}
+	return nil
+}
+
 func (osclient *OpenStackClients) SetVolumeImageMetadata(ctx context.Context, volume *volumes.Volume, setRDMLabel bool) error {
 	options := volumes.ImageMetadataOpts{
 		Metadata: map[string]string{
@@ -466,6 +546,12 @@ func (osclient *OpenStackClients) GetSubnet(ctx context.Context, subnetList []st
 	return nil, fmt.Errorf("IP %s is not in any of the subnets %v", ip, subnetList)
 }
 
+// isAutoAddressSubnet reports whether Neutron assigns the addresses of an IPv6 subnet through
+// SLAAC, in which case the address of a port is derived from its MAC address and cannot be chosen
+func isAutoAddressSubnet(subnet *subnets.Subnet) bool {
+	return subnet.IPVersion == 6 && (subnet.IPv6AddressMode == "slaac" || subnet.IPv6AddressMode == "dhcpv6-stateless")
+}
+
 func (osclient *OpenStackClients) CheckIfPortExists(ctx context.Context, ipEntries []vm.IpEntry, mac string, network *networks.Network, gatewayIP map[string]string) (*ports.Port, error) {
 
 	pages, err := ports.List(osclient.NetworkingClient, ports.ListOpts{
@@ -492,14 +578,18 @@ func (osclient *OpenStackClients) CheckIfPortExists(ctx context.Context, ipEntri
 				}
 				contain_all := true
 				for _, ipIdx := range ipEntries {
-					if !slices.Contains(fixedIps, ipIdx.IP) {
-						contain_all = false
-					}
 					subnetId, err := osclient.GetSubnet(ctx, network.Subnets, ipIdx.IP)
 					if err != nil {
 						return nil, fmt.Errorf("subnet not found for IP %s", ipIdx.IP)
 					}
-					gatewayIP[mac] = subnetId.GatewayIP
+					// Addresses on SLAAC subnets are chosen by Neutron, the guest address may differ
+					if !slices.Contains(fixedIps, ipIdx.IP) && !isAutoAddressSubnet(subnetId) {
+						contain_all = false
+					}
+					// IPv6 guests learn their default route from router advertisements
+					if !ipIdx.IsIPv6() {
+						gatewayIP[mac] = subnetId.GatewayIP
+					}
 				}
 				if !contain_all {
 					return nil, fmt.Errorf("port conflict: a port with MAC %s already exists but has IPs %v, while IPs %v were requested", mac, fixedIps, ipEntries)
@@ -540,24 +630,41 @@ func (osclient *OpenStackClients) GetCreateOpts(ctx context.Context, network *ne
 		for _, ipEntry := range ipEntries {
 			subnetId, err := osclient.GetSubnet(ctx, network.Subnets, ipEntry.IP)
 			if err != nil {
+				if ipEntry.IsIPv6() {
+					return createOpts, fmt.Errorf("network %s has no IPv6 subnet containing %s", network.Name, ipEntry.IP)
+				}
 				return createOpts, fmt.Errorf("subnet not found for IP %s", ipEntry.IP)
-			} else {
+			}
+			PrintLog(fmt.Sprintf("IP %s is in subnet %s", ipEntry.IP, subnetId.ID))
+			if !ipEntry.IsIPv6() {
 				gatewayIP[mac] = subnetId.GatewayIP
-				PrintLog(fmt.Sprintf("IP %s is in subnet %s", ipEntry.IP, subnetId.ID))
-				fixedIPs = append(fixedIPs, ports.IP{
-					SubnetID:  subnetId.ID,
-					IPAddress: ipEntry.IP,
-				})
 			}
+			if isAutoAddressSubnet(subnetId) {
+				// Neutron derives the address from the MAC address, which is kept, so the guest
+				// gets the same address back unless it used privacy extensions
+				PrintLog(fmt.Sprintf("Subnet %s uses %s, IP %s will be assigned by Neutron", subnetId.ID, subnetId.IPv6AddressMode, ipEntry.IP))
+				fixedIPs = append(fixedIPs, ports.IP{SubnetID: subnetId.ID})
+				continue
+			}
+			fixedIPs = append(fixedIPs, ports.IP{
+				SubnetID:  subnetId.ID,
+				IPAddress: ipEntry.IP,
+			})
 		}
 		createOpts.FixedIPs = fixedIPs
 	} else if len(ipEntries) == 0 {
 		PrintLog("Empty port on vcentre detected for mac " + mac)
-		subnetID, err := subnets.Get(ctx, osclient.NetworkingClient, network.Subnets[0]).Extract()
-		if err != nil {
-			return createOpts, fmt.Errorf("subnet not found for network %s", network.ID)
+		// Use the gateway of the first IPv4 subnet, IPv6 routes come from router advertisements
+		for _, subnet := range network.Subnets {
+			subnetID, err := subnets.Get(ctx, osclient.NetworkingClient, subnet).Extract()
+			if err != nil {
+				return createOpts, fmt.Errorf("subnet not found for network %s", network.ID)
+			}
+			if subnetID.IPVersion != 6 {
+				gatewayIP[mac] = subnetID.GatewayIP
+				break
+			}
 		}
-		gatewayIP[mac] = subnetID.GatewayIP
 	}
 	return createOpts, nil
 }
@@ -612,11 +719,14 @@ func (osclient *OpenStackClients) CreatePortWithDHCP(ctx context.Context, networ
 		if err != nil {
 			return nil, fmt.Errorf("subnet not found for IP %s", iAddr.IPAddress)
 		}
-		ipPerMac[mac] = append(ipPerMac[mac], vm.IpEntry{
+		entry := vm.IpEntry{
 			IP:     iAddr.IPAddress,
 			Prefix: 0,
-		})
-		gatewayIP[mac] = dhcpSubnetId.GatewayIP
+		}
+		ipPerMac[mac] = append(ipPerMac[mac], entry)
+		if !entry.IsIPv6() {
+			gatewayIP[mac] = dhcpSubnetId.GatewayIP
+		}
 	}
 	logMsg := "Port created with DHCP instead of static IP"
 	if len(ipPerMac[mac]) > 0 {
@@ -789,6 +899,44 @@ func (osclient *OpenStackClients) WaitUntilVMActive(ctx context.Context, vmID st
 	return true, nil
 }
 
+// GetConsoleOutput returns the last lines of the console log of a server
+func (osclient *OpenStackClients) GetConsoleOutput(ctx context.Context, vmID string, lines int) (string, error) {
+	output, err := servers.ShowConsoleOutput(ctx, osclient.ComputeClient, vmID, servers.ShowConsoleOutputOpts{Length: lines}).Extract()
+	if err != nil {
+		return "", fmt.Errorf("failed to get console output of server %s: %s", vmID, err)
+	}
+	return output, nil
+}
+
+// DeleteVM deletes a server and waits until it is gone, so that its volumes are released
+func (osclient *OpenStackClients) DeleteVM(ctx context.Context, vmID string) error {
+	PrintLog(fmt.Sprintf("OPENSTACK API: Deleting server %s, authurl %s, tenant %s", vmID, osclient.AuthURL, osclient.Tenant))
+	err := servers.Delete(ctx, osclient.ComputeClient, vmID).ExtractErr()
+	if err != nil {
+		if gophercloud.ResponseCodeIs(err, http.StatusNotFound) {
+			return nil
+		}
+		return fmt.Errorf("failed to delete server %s: %s", vmID, err)
+	}
+	waitCtx, cancel := context.WithTimeout(ctx, constants.ServerDeleteTimeout)
+	defer cancel()
+	err = gophercloud.WaitFor(waitCtx, func(ctx context.Context) (bool, error) {
+		_, err := servers.Get(ctx, osclient.ComputeClient, vmID).Extract()
+		if err != nil {
+			if gophercloud.ResponseCodeIs(err, http.StatusNotFound) {
+				return true, nil
+			}
+			return false, err
+		}
+		return false, nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to wait for server %s deletion: %s", vmID, err)
+	}
+	PrintLog(fmt.Sprintf("Successfully deleted server %s", vmID))
+	return nil
+}
+
 // ManageExistingVolume manages an existing volume on the storage backend into Cinder
 // Uses the manageable_volumes endpoint which is the standard Cinder manage API
 func (osclient *OpenStackClients) ManageExistingVolume(name string, ref map[string]interface{}, host string, volumeType string) (*volumes.Volume, error) {
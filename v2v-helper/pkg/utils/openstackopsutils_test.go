@@ -0,0 +1,87 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gophercloud/gophercloud/v2"
+	"github.com/gophercloud/gophercloud/v2/openstack/networking/v2/networks"
+	"github.com/gophercloud/gophercloud/v2/openstack/networking/v2/ports"
+	"github.com/platform9/vjailbreak/v2v-helper/vm"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestNetworkingClients returns clients whose networking service serves subnets
+func newTestNetworkingClients(t *testing.T, subnets map[string]map[string]interface{}) *OpenStackClients {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		subnet, ok := subnets[strings.TrimPrefix(r.URL.Path, "/v2.0/subnets/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		assert.NoError(t, json.NewEncoder(w).Encode(map[string]interface{}{"subnet": subnet}))
+	}))
+	t.Cleanup(server.Close)
+	return &OpenStackClients{
+		NetworkingClient: &gophercloud.ServiceClient{
+			ProviderClient: &gophercloud.ProviderClient{HTTPClient: *server.Client()},
+			Endpoint:       server.URL + "/v2.0/",
+		},
+	}
+}
+
+func TestGetCreateOpts(t *testing.T) {
+	osclient := newTestNetworkingClients(t, map[string]map[string]interface{}{
+		"v4":       {"id": "v4", "cidr": "10.0.0.0/24", "ip_version": 4, "gateway_ip": "10.0.0.1"},
+		"stateful": {"id": "stateful", "cidr": "2001:db8:1::/64", "ip_version": 6, "gateway_ip": "2001:db8:1::1", "ipv6_address_mode": "dhcpv6-stateful"},
+		"slaac":    {"id": "slaac", "cidr": "2001:db8:2::/64", "ip_version": 6, "gateway_ip": "2001:db8:2::1", "ipv6_address_mode": "slaac"},
+	})
+	const mac = "00:50:56:aa:00:01"
+	tests := []struct {
+		name        string
+		subnets     []string
+		ipEntries   []vm.IpEntry
+		wantIPs     []ports.IP
+		wantGateway string
+		wantErr     string
+	}{
+		{
+			name:        "dual stack",
+			subnets:     []string{"v4", "stateful"},
+			ipEntries:   []vm.IpEntry{{IP: "2001:db8:1::5", Origin: "manual"}, {IP: "10.0.0.5", Prefix: 24}},
+			wantIPs:     []ports.IP{{SubnetID: "stateful", IPAddress: "2001:db8:1::5"}, {SubnetID: "v4", IPAddress: "10.0.0.5"}},
+			wantGateway: "10.0.0.1",
+		},
+		{
+			name:      "SLAAC subnet",
+			subnets:   []string{"slaac"},
+			ipEntries: []vm.IpEntry{{IP: "2001:db8:2::250:56ff:feaa:1", Origin: "linklayer"}},
+			wantIPs:   []ports.IP{{SubnetID: "slaac"}},
+		},
+		{
+			name:      "no IPv6 subnet",
+			subnets:   []string{"v4"},
+			ipEntries: []vm.IpEntry{{IP: "10.0.0.5"}, {IP: "2001:db8:1::5", Origin: "manual"}},
+			wantErr:   "network net-1 has no IPv6 subnet containing 2001:db8:1::5",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			network := &networks.Network{ID: "net-1-id", Name: "net-1", Subnets: tt.subnets}
+			gatewayIP := map[string]string{}
+			createOpts, err := osclient.GetCreateOpts(context.Background(), network, mac, tt.ipEntries, "vm-1", nil, gatewayIP)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantIPs, createOpts.FixedIPs)
+			assert.Equal(t, tt.wantGateway, gatewayIP[mac])
+		})
+	}
+}
@@ -2,8 +2,11 @@ package utils
 
 import (
 	"context"
+	"encoding/json"
+	"strconv"
 
 	"github.com/pkg/errors"
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
 	"github.com/platform9/vjailbreak/v2v-helper/pkg/constants"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
@@ -27,6 +30,8 @@ type MigrationParams struct {
 	DataCopyStart           string
 	VMcutoverStart          string
 	VMcutoverEnd            string
+	DataCopyWindow          string
+	CutoverWindow           string
 	MigrationType           string
 	PerformHealthChecks     bool
 	HealthCheckPort         string
@@ -43,10 +48,37 @@ type MigrationParams struct {
 	PeriodicSyncInterval    string
 	PeriodicSyncEnabled     bool
 	NetworkPersistance      bool
+	DiskCopyParallelism     int
+	// VerifyMode is Full, Sample or empty when verification is disabled
+	VerifyMode          string
+	VerifySamplePercent int
+	// RollbackPolicy is Automatic, Manual or empty when a failed cutover is not rolled back
+	RollbackPolicy string
+	// HealthChecks replace the ping and HTTP checks when set, the failure policy is Warn, Fail or Rollback
+	HealthChecks      []vjailbreakv1alpha1.HealthCheck
+	HealthCheckPolicy string
+	// Hooks are run as Kubernetes Jobs by the controller at the stages of the migration
+	Hooks []vjailbreakv1alpha1.MigrationHook
+	// SnapshotConsistency quiesces the guest for the snapshot of the final sync when set
+	SnapshotConsistency *vjailbreakv1alpha1.SnapshotConsistency
 
 	StorageCopyMethod string
 	VendorType        string
 	ArrayCredsMapping string
+
+	// File source params, the VM is imported from the file at SourceFilePath or SourceFileURL
+	// instead of a VMware VM when one of them is set
+	SourceFilePath     string
+	SourceFileURL      string
+	SourceFileFormat   string
+	SourceFileCPU      int
+	SourceFileMemoryMB int
+	// NetworkMapping maps the networks of the NICs of the file to OpenStack networks
+	NetworkMapping map[string]string
+
+	// DestinationType is file when the VM is written as images to a PVC instead of OpenStack
+	DestinationType   string
+	DestinationFormat string
 }
 
 // GetMigrationParams is function that returns the migration parameters
@@ -64,6 +96,36 @@ func GetMigrationParams(ctx context.Context, client client.Client) (*MigrationPa
 	if err != nil {
 		return nil, errors.Wrap(err, "Failed to get configmap")
 	}
+	// A missing or invalid value falls back to the vjailbreak-settings default
+	diskCopyParallelism, _ := strconv.Atoi(string(configMap.Data["DISK_COPY_PARALLELISM"]))
+	verifySamplePercent, _ := strconv.Atoi(string(configMap.Data["INTEGRITY_VERIFICATION_SAMPLE_PERCENT"]))
+	var healthChecks []vjailbreakv1alpha1.HealthCheck
+	if value := configMap.Data["HEALTH_CHECKS"]; value != "" {
+		if err := json.Unmarshal([]byte(value), &healthChecks); err != nil {
+			return nil, errors.Wrap(err, "failed to parse health checks")
+		}
+	}
+	var networkMapping map[string]string
+	if value := configMap.Data["NETWORK_MAPPING"]; value != "" {
+		if err := json.Unmarshal([]byte(value), &networkMapping); err != nil {
+			return nil, errors.Wrap(err, "failed to parse network mapping")
+		}
+	}
+	sourceFileCPU, _ := strconv.Atoi(string(configMap.Data["SOURCE_FILE_CPU"]))
+	sourceFileMemoryMB, _ := strconv.Atoi(string(configMap.Data["SOURCE_FILE_MEMORY"]))
+	var hooks []vjailbreakv1alpha1.MigrationHook
+	if value := configMap.Data["HOOKS"]; value != "" {
+		if err := json.Unmarshal([]byte(value), &hooks); err != nil {
+			return nil, errors.Wrap(err, "failed to parse hooks")
+		}
+	}
+	var snapshotConsistency *vjailbreakv1alpha1.SnapshotConsistency
+	if value := configMap.Data["SNAPSHOT_CONSISTENCY"]; value != "" {
+		snapshotConsistency = &vjailbreakv1alpha1.SnapshotConsistency{}
+		if err := json.Unmarshal([]byte(value), snapshotConsistency); err != nil {
+			return nil, errors.Wrap(err, "failed to parse snapshot consistency")
+		}
+	}
 	return &MigrationParams{
 		SourceVMName:            string(configMap.Data["SOURCE_VM_NAME"]),
 		OpenstackNetworkNames:   string(configMap.Data["NEUTRON_NETWORK_NAMES"]),
@@ -75,6 +137,8 @@ func GetMigrationParams(ctx context.Context, client client.Client) (*MigrationPa
 		DataCopyStart:           string(configMap.Data["DATACOPYSTART"]),
 		VMcutoverStart:          string(configMap.Data["CUTOVERSTART"]),
 		VMcutoverEnd:            string(configMap.Data["CUTOVEREND"]),
+		DataCopyWindow:          string(configMap.Data["DATA_COPY_WINDOW"]),
+		CutoverWindow:           string(configMap.Data["CUTOVER_WINDOW"]),
 		MigrationType:           string(configMap.Data["TYPE"]),
 		PerformHealthChecks:     string(configMap.Data["PERFORM_HEALTH_CHECKS"]) == constants.TrueString,
 		HealthCheckPort:         string(configMap.Data["HEALTH_CHECK_PORT"]),
@@ -91,8 +155,24 @@ func GetMigrationParams(ctx context.Context, client client.Client) (*MigrationPa
 		PeriodicSyncInterval:    string(configMap.Data["PERIODIC_SYNC_INTERVAL"]),
 		PeriodicSyncEnabled:     string(configMap.Data["PERIODIC_SYNC_ENABLED"]) == constants.TrueString,
 		NetworkPersistance:      string(configMap.Data["NETWORK_PERSISTENCE"]) == constants.TrueString,
+		DiskCopyParallelism:     diskCopyParallelism,
+		VerifyMode:              string(configMap.Data["INTEGRITY_VERIFICATION_MODE"]),
+		VerifySamplePercent:     verifySamplePercent,
+		RollbackPolicy:          string(configMap.Data["ROLLBACK_POLICY"]),
+		HealthChecks:            healthChecks,
+		HealthCheckPolicy:       string(configMap.Data["HEALTH_CHECK_FAILURE_POLICY"]),
+		Hooks:                   hooks,
+		SnapshotConsistency:     snapshotConsistency,
 		StorageCopyMethod:       string(configMap.Data["STORAGE_COPY_METHOD"]),
 		VendorType:              string(configMap.Data["VENDOR_TYPE"]),
 		ArrayCredsMapping:       string(configMap.Data["ARRAY_CREDS_MAPPING"]),
+		SourceFilePath:          string(configMap.Data["SOURCE_FILE_PATH"]),
+		SourceFileURL:           string(configMap.Data["SOURCE_FILE_URL"]),
+		SourceFileFormat:        string(configMap.Data["SOURCE_FILE_FORMAT"]),
+		SourceFileCPU:           sourceFileCPU,
+		SourceFileMemoryMB:      sourceFileMemoryMB,
+		NetworkMapping:          networkMapping,
+		DestinationType:         string(configMap.Data["DESTINATION_TYPE"]),
+		DestinationFormat:       string(configMap.Data["DESTINATION_FORMAT"]),
 	}, nil
 }
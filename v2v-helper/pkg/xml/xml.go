@@ -7,14 +7,62 @@ import (
 )
 
 type Domain struct {
-	XMLName xml.Name `xml:"domain"`
-	Type    string   `xml:"type,attr"`
-	Name    string   `xml:"name"`
-	Devices Devices  `xml:"devices"`
+	XMLName  xml.Name  `xml:"domain"`
+	Type     string    `xml:"type,attr"`
+	Name     string    `xml:"name"`
+	Memory   *Memory   `xml:"memory,omitempty"`
+	VCPU     int32     `xml:"vcpu,omitempty"`
+	OS       *OS       `xml:"os,omitempty"`
+	Features *Features `xml:"features,omitempty"`
+	Devices  Devices   `xml:"devices"`
+}
+
+type Memory struct {
+	Unit  string `xml:"unit,attr"`
+	Value int64  `xml:",chardata"`
+}
+
+// OS selects the firmware of the domain, libvirt picks a UEFI loader when Firmware is efi
+type OS struct {
+	Firmware     string            `xml:"firmware,attr,omitempty"`
+	Type         OSType            `xml:"type"`
+	FirmwareInfo *FirmwareFeatures `xml:"firmware,omitempty"`
+	Loader       *Loader           `xml:"loader,omitempty"`
+}
+
+type OSType struct {
+	Arch    string `xml:"arch,attr,omitempty"`
+	Machine string `xml:"machine,attr,omitempty"`
+	Value   string `xml:",chardata"`
+}
+
+type FirmwareFeatures struct {
+	Features []FirmwareFeature `xml:"feature"`
+}
+
+type FirmwareFeature struct {
+	Enabled string `xml:"enabled,attr"`
+	Name    string `xml:"name,attr"`
+}
+
+type Loader struct {
+	Secure string `xml:"secure,attr,omitempty"`
+}
+
+type Features struct {
+	ACPI *struct{} `xml:"acpi,omitempty"`
+	APIC *struct{} `xml:"apic,omitempty"`
+	SMM  *SMM      `xml:"smm,omitempty"`
+}
+
+type SMM struct {
+	State string `xml:"state,attr"`
 }
 
 type Devices struct {
-	Disks []Disk `xml:"disk"`
+	Disks      []Disk      `xml:"disk"`
+	Interfaces []Interface `xml:"interface"`
+	TPM        *TPM        `xml:"tpm,omitempty"`
 }
 
 type Disk struct {
@@ -23,6 +71,11 @@ type Disk struct {
 	Driver Driver `xml:"driver"`
 	Source Source `xml:"source"`
 	Target Target `xml:"target"`
+	Boot   *Boot  `xml:"boot,omitempty"`
+}
+
+type Boot struct {
+	Order int `xml:"order,attr"`
 }
 
 type Driver struct {
@@ -39,6 +92,35 @@ type Target struct {
 	Bus string `xml:"bus,attr"`
 }
 
+type Interface struct {
+	Type   string          `xml:"type,attr"`
+	MAC    MAC             `xml:"mac"`
+	Source InterfaceSource `xml:"source"`
+	Model  Model           `xml:"model"`
+}
+
+type MAC struct {
+	Address string `xml:"address,attr"`
+}
+
+type InterfaceSource struct {
+	Network string `xml:"network,attr"`
+}
+
+type Model struct {
+	Type string `xml:"type,attr"`
+}
+
+type TPM struct {
+	Model   string     `xml:"model,attr"`
+	Backend TPMBackend `xml:"backend"`
+}
+
+type TPMBackend struct {
+	Type    string `xml:"type,attr"`
+	Version string `xml:"version,attr"`
+}
+
 func GenerateXML(diskFiles []string, outputFile, vmname string) error {
 	var disks []Disk
 	for i, file := range diskFiles {
@@ -57,7 +139,11 @@ func GenerateXML(diskFiles []string, outputFile, vmname string) error {
 		Name:    vmname,
 		Devices: Devices{Disks: disks},
 	}
+	return WriteDomain(domain, outputFile)
+}
 
+// WriteDomain writes the XML of a libvirt domain to outputFile
+func WriteDomain(domain Domain, outputFile string) error {
 	output, err := xml.MarshalIndent(domain, "", "  ")
 	if err != nil {
 		return err
@@ -299,7 +299,15 @@ func InjectMacToIps(disks []vm.VMDisk, useSingleDisk bool, diskPath string, gues
 	defer f.Close()
 	for mac, ips := range macToIPs {
 		if len(ips) > 0 {
-			_, err := fmt.Fprintf(f, "%s:ip:%s\n", mac, ips[0].IP)
+			// Interfaces are looked up by their IPv4 address, the IPv6 one is only used when there is none
+			ip := ips[0].IP
+			for _, e := range ips {
+				if !e.IsIPv6() {
+					ip = e.IP
+					break
+				}
+			}
+			_, err := fmt.Fprintf(f, "%s:ip:%s\n", mac, ip)
 			if err != nil {
 				return err
 			}
@@ -330,17 +338,18 @@ func InjectMacToIps(disks []vm.VMDisk, useSingleDisk bool, diskPath string, gues
 	return nil
 }
 
-func AddWildcardNetplan(disks []vm.VMDisk, useSingleDisk bool, diskPath string, guestNetworks []vjailbreakv1alpha1.GuestNetwork, gatewayIP map[string]string, ipPerMac map[string][]vm.IpEntry) error {
+// GenerateNetplan builds the netplan that restores the addressing of a Linux guest. Interfaces are
+// matched by MAC address and the default route is set on the first interface that has a gateway.
+func GenerateNetplan(guestNetworks []vjailbreakv1alpha1.GuestNetwork, gatewayIP map[string]string, ipPerMac map[string][]vm.IpEntry) string {
 	// Add wildcard to netplan
 	macToIPs := ipPerMac
 	macToDNS := make(map[string][]string)
 	if len(guestNetworks) > 0 {
 		for _, gn := range guestNetworks {
-			if strings.Contains(gn.IP, ":") { // skip IPv6 here
-				continue
-			}
 			if len(gn.DNS) > 0 {
-				macToDNS[gn.MAC] = gn.DNS
+				if _, ok := macToDNS[gn.MAC]; !ok || !strings.Contains(gn.IP, ":") {
+					macToDNS[gn.MAC] = gn.DNS
+				}
 			}
 		}
 	}
@@ -363,14 +372,31 @@ func AddWildcardNetplan(disks []vm.VMDisk, useSingleDisk bool, diskPath string,
 		b.WriteString("      match:\n")
 		b.WriteString(fmt.Sprintf("        macaddress: %s\n", mac))
 		b.WriteString("      dhcp4: false\n")
-		b.WriteString("      addresses:\n")
+		// IPv6 addresses from DHCPv6 or SLAAC are obtained again on the target, only static ones
+		// are written. The IPv6 default route comes from router advertisements.
+		var addresses []string
+		dhcp6, acceptRA := false, false
 		for _, e := range entries {
-			// default prefix to 24 if zero
-			prefix := e.Prefix
-			if prefix == 0 {
-				prefix = 24
+			switch {
+			case e.IsAutoconfigured():
+				acceptRA = true
+			case e.IsIPv6() && strings.EqualFold(e.Origin, "dhcp"):
+				dhcp6 = true
+			default:
+				addresses = append(addresses, fmt.Sprintf("%s/%d", e.IP, e.PrefixLength()))
+			}
+		}
+		if dhcp6 {
+			b.WriteString("      dhcp6: true\n")
+		}
+		if acceptRA {
+			b.WriteString("      accept-ra: true\n")
+		}
+		if len(addresses) > 0 {
+			b.WriteString("      addresses:\n")
+			for _, address := range addresses {
+				b.WriteString(fmt.Sprintf("        - %s\n", address))
 			}
-			b.WriteString(fmt.Sprintf("        - %s/%d\n", e.IP, prefix))
 		}
 		if gateway, ok := gatewayIP[mac]; ok && gateway != "" {
 			if !routesAdded {
@@ -393,7 +419,11 @@ func AddWildcardNetplan(disks []vm.VMDisk, useSingleDisk bool, diskPath string,
 	if !routesAdded {
 		log.Println("WARNING: No gateway found")
 	}
-	netplanYAML := b.String()
+	return b.String()
+}
+
+func AddWildcardNetplan(disks []vm.VMDisk, useSingleDisk bool, diskPath string, guestNetworks []vjailbreakv1alpha1.GuestNetwork, gatewayIP map[string]string, ipPerMac map[string][]vm.IpEntry) error {
+	netplanYAML := GenerateNetplan(guestNetworks, gatewayIP, ipPerMac)
 	log.Printf("NETPLAN YAML : %s", netplanYAML)
 	// Create the netplan file
 	err := os.WriteFile("/home/fedora/99-wildcard.network", []byte(netplanYAML), 0644)
@@ -450,6 +480,80 @@ func AddFirstBootScript(firstbootscript, firstbootscriptname string) error {
 	return nil
 }
 
+// GenerateWindowsNetworkScript builds the firstboot script that restores the static addressing of
+// a Windows guest. Adapters are matched by MAC address, the default gateway is set on the first
+// adapter that has one, like the netplan written for Linux guests. MAC addresses whose addresses
+// were all obtained through DHCP are left alone, as are IPv6 addresses from DHCPv6 or SLAAC which
+// Windows configures again by itself. It returns an empty script when no adapter has a static address.
+func GenerateWindowsNetworkScript(guestNetworks []vjailbreakv1alpha1.GuestNetwork, gatewayIP map[string]string, ipPerMac map[string][]vm.IpEntry) string {
+	dhcpOnly := map[string]bool{}
+	macToDNS := map[string][]string{}
+	for _, gn := range guestNetworks {
+		mac := strings.ToLower(gn.MAC)
+		entry := vm.IpEntry{IP: gn.IP, Origin: gn.Origin}
+		if entry.IsIPv6() && (entry.IsAutoconfigured() || strings.HasPrefix(strings.ToLower(gn.IP), "fe80:")) {
+			continue
+		}
+		if _, ok := dhcpOnly[mac]; !ok {
+			dhcpOnly[mac] = true
+		}
+		if !strings.EqualFold(gn.Origin, "dhcp") {
+			dhcpOnly[mac] = false
+		}
+		if _, ok := macToDNS[mac]; len(gn.DNS) > 0 && (!ok || !entry.IsIPv6()) {
+			macToDNS[mac] = gn.DNS
+		}
+	}
+
+	// Sort the MAC addresses so that the gateway always goes to the same adapter
+	macs := make([]string, 0, len(ipPerMac))
+	for mac := range ipPerMac {
+		macs = append(macs, mac)
+	}
+	slices.Sort(macs)
+
+	quote := func(value string) string {
+		return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+	}
+	var entries []string
+	gatewaySet := false
+	for _, mac := range macs {
+		ips := ipPerMac[mac]
+		if len(ips) == 0 || dhcpOnly[strings.ToLower(mac)] {
+			continue
+		}
+		addresses := make([]string, 0, len(ips))
+		for _, e := range ips {
+			if e.IsAutoconfigured() || (e.IsIPv6() && strings.EqualFold(e.Origin, "dhcp")) {
+				continue
+			}
+			addresses = append(addresses, fmt.Sprintf("@{ IP = %s; Prefix = %d }", quote(e.IP), e.PrefixLength()))
+		}
+		if len(addresses) == 0 {
+			continue
+		}
+		gateway := ""
+		if gw, ok := gatewayIP[mac]; ok && gw != "" && !gatewaySet {
+			gateway = gw
+			gatewaySet = true
+		}
+		dns := make([]string, 0, len(macToDNS[strings.ToLower(mac)]))
+		for _, d := range macToDNS[strings.ToLower(mac)] {
+			dns = append(dns, quote(d))
+		}
+		// Get-NetAdapter reports MAC addresses in upper case, separated by dashes
+		windowsMac := strings.ToUpper(strings.ReplaceAll(mac, ":", "-"))
+		entries = append(entries, fmt.Sprintf("    @{ Mac = %s; Addresses = @(%s); Gateway = %s; Dns = @(%s) }",
+			quote(windowsMac), strings.Join(addresses, ", "), quote(gateway), strings.Join(dns, ", ")))
+	}
+	if len(entries) == 0 {
+		return ""
+	}
+	script := strings.Replace(constants.WindowsNetworkPersistenceScript, constants.WindowsNetworkConfigPlaceholder, strings.Join(entries, ",\n"), 1)
+	// cmd.exe expects CRLF line endings in batch files
+	return strings.ReplaceAll(script, "\n", "\r\n")
+}
+
 // Runs command inside temporary qemu-kvm that virt-v2v creates
 func RunCommandInGuest(path string, command string, write bool) (string, error) {
 	os.Setenv("LIBGUESTFS_BACKEND", "direct")
@@ -833,7 +937,7 @@ func RunGetBootablePartitionScript(disks []vm.VMDisk) (string, error) {
 func RunNetworkPersistence(disks []vm.VMDisk, useSingleDisk bool, diskPath string, ostype string, isNetplan bool) error {
 	// Skip this entirely for Windows as it doesn't use these udev rules/bash scripts
 	if strings.ToLower(ostype) == constants.OSFamilyWindows {
-		log.Println("Skipping offline network persistence for Windows guest")
+		log.Println("Skipping offline network persistence for Windows guest, its addressing is restored by a firstboot script")
 		return nil
 	}
 
@@ -0,0 +1,175 @@
+package virtv2v
+
+import (
+	"strings"
+	"testing"
+
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+	"github.com/platform9/vjailbreak/v2v-helper/pkg/constants"
+	"github.com/platform9/vjailbreak/v2v-helper/vm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateNetplanIPv6(t *testing.T) {
+	const mac = "00:50:56:aa:00:01"
+	tests := []struct {
+		name    string
+		entries []vm.IpEntry
+		want    string
+	}{
+		{
+			name:    "static IPv6",
+			entries: []vm.IpEntry{{IP: "10.0.0.5", Prefix: 24}, {IP: "2001:db8:1::5", Origin: "manual"}},
+			want: "      dhcp4: false\n" +
+				"      addresses:\n" +
+				"        - 10.0.0.5/24\n" +
+				"        - 2001:db8:1::5/64\n",
+		},
+		{
+			name:    "DHCPv6",
+			entries: []vm.IpEntry{{IP: "10.0.0.5", Prefix: 24}, {IP: "2001:db8:1::5", Prefix: 128, Origin: "dhcp"}},
+			want: "      dhcp4: false\n" +
+				"      dhcp6: true\n" +
+				"      addresses:\n" +
+				"        - 10.0.0.5/24\n",
+		},
+		{
+			name: "SLAAC with privacy extensions",
+			entries: []vm.IpEntry{
+				{IP: "2001:db8:1::250:56ff:feaa:1", Prefix: 64, Origin: "linklayer"},
+				{IP: "2001:db8:1::1234", Prefix: 64, Origin: "random"},
+			},
+			want: "      dhcp4: false\n" +
+				"      accept-ra: true\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			netplan := GenerateNetplan(nil, map[string]string{mac: "10.0.0.1"}, map[string][]vm.IpEntry{mac: tt.entries})
+			assert.Contains(t, netplan, "        macaddress: "+mac+"\n"+tt.want+"      routes:\n")
+		})
+	}
+}
+
+func TestGenerateWindowsNetworkScriptIPv6(t *testing.T) {
+	guestNetworks := []vjailbreakv1alpha1.GuestNetwork{
+		{MAC: "00:50:56:aa:00:01", IP: "10.0.0.5", Origin: "manual", DNS: []string{"10.0.0.2"}},
+		{MAC: "00:50:56:aa:00:01", IP: "2001:db8:1::5", Origin: "manual", DNS: []string{"2001:db8:1::2"}},
+		{MAC: "00:50:56:aa:00:01", IP: "2001:db8:1::250:56ff:feaa:1", Origin: "linklayer"},
+		{MAC: "00:50:56:aa:00:01", IP: "fe80::250:56ff:feaa:1", Origin: "manual"},
+		{MAC: "00:50:56:aa:00:02", IP: "10.1.0.5", Origin: "dhcp"},
+		{MAC: "00:50:56:aa:00:02", IP: "2001:db8:2::250:56ff:feaa:2", Origin: "linklayer"},
+		{MAC: "00:50:56:aa:00:03", IP: "2001:db8:3::5", Origin: "dhcp"},
+	}
+	ipPerMac := map[string][]vm.IpEntry{
+		"00:50:56:aa:00:01": {
+			{IP: "10.0.0.5", Prefix: 24, Origin: "manual"},
+			{IP: "2001:db8:1::5", Prefix: 64, Origin: "manual"},
+			{IP: "2001:db8:1::250:56ff:feaa:1", Prefix: 64, Origin: "linklayer"},
+			{IP: "2001:db8:1::6", Prefix: 128, Origin: "dhcp"},
+		},
+		"00:50:56:aa:00:02": {
+			{IP: "10.1.0.5", Prefix: 24, Origin: "dhcp"},
+			{IP: "2001:db8:2::250:56ff:feaa:2", Prefix: 64, Origin: "linklayer"},
+		},
+		"00:50:56:aa:00:03": {
+			{IP: "2001:db8:3::5", Prefix: 128, Origin: "dhcp"},
+		},
+	}
+	script := GenerateWindowsNetworkScript(guestNetworks, map[string]string{"00:50:56:aa:00:01": "10.0.0.1"}, ipPerMac)
+	// Addresses from SLAAC and DHCPv6 are configured by Windows again, the DNS servers of the
+	// IPv4 address win
+	assert.Contains(t, script, "    @{ Mac = '00-50-56-AA-00-01'; Addresses = @(@{ IP = '10.0.0.5'; Prefix = 24 }, "+
+		"@{ IP = '2001:db8:1::5'; Prefix = 64 }); Gateway = '10.0.0.1'; Dns = @('10.0.0.2') }")
+	// Adapters whose addresses all come from DHCP or SLAAC are left alone
+	assert.NotContains(t, script, "00-50-56-AA-00-02")
+	assert.NotContains(t, script, "00-50-56-AA-00-03")
+}
+
+func TestGenerateWindowsNetworkScript(t *testing.T) {
+	const (
+		mac1 = "00:50:56:aa:00:01"
+		mac2 = "00:50:56:aa:00:02"
+	)
+	tests := []struct {
+		name          string
+		guestNetworks []vjailbreakv1alpha1.GuestNetwork
+		gatewayIP     map[string]string
+		ipPerMac      map[string][]vm.IpEntry
+		want          []string
+	}{
+		{
+			name: "static address",
+			guestNetworks: []vjailbreakv1alpha1.GuestNetwork{
+				{MAC: mac1, IP: "10.0.0.5", Origin: "manual", PrefixLength: 24, DNS: []string{"10.0.0.2", "10.0.0.3"}},
+			},
+			gatewayIP: map[string]string{mac1: "10.0.0.1"},
+			ipPerMac:  map[string][]vm.IpEntry{mac1: {{IP: "10.0.0.5", Prefix: 24}}},
+			want: []string{
+				"    @{ Mac = '00-50-56-AA-00-01'; Addresses = @(@{ IP = '10.0.0.5'; Prefix = 24 }); Gateway = '10.0.0.1'; Dns = @('10.0.0.2', '10.0.0.3') }",
+			},
+		},
+		{
+			name: "multiple NICs",
+			guestNetworks: []vjailbreakv1alpha1.GuestNetwork{
+				{MAC: mac2, IP: "10.1.0.5", Origin: "manual", DNS: []string{"10.1.0.2"}},
+				{MAC: mac1, IP: "10.0.0.5", Origin: "manual", DNS: []string{"10.0.0.2"}},
+				{MAC: mac1, IP: "10.0.0.6", Origin: "manual"},
+			},
+			gatewayIP: map[string]string{mac1: "10.0.0.1", mac2: "10.1.0.1"},
+			ipPerMac: map[string][]vm.IpEntry{
+				mac2: {{IP: "10.1.0.5", Prefix: 16}},
+				mac1: {{IP: "10.0.0.5"}, {IP: "10.0.0.6", Prefix: 25}},
+			},
+			// The gateway is only set on the first adapter
+			want: []string{
+				"    @{ Mac = '00-50-56-AA-00-01'; Addresses = @(@{ IP = '10.0.0.5'; Prefix = 24 }, @{ IP = '10.0.0.6'; Prefix = 25 }); Gateway = '10.0.0.1'; Dns = @('10.0.0.2') }",
+				"    @{ Mac = '00-50-56-AA-00-02'; Addresses = @(@{ IP = '10.1.0.5'; Prefix = 16 }); Gateway = ''; Dns = @('10.1.0.2') }",
+			},
+		},
+		{
+			name:          "missing gateway",
+			guestNetworks: []vjailbreakv1alpha1.GuestNetwork{{MAC: mac1, IP: "10.0.0.5", Origin: "manual"}},
+			gatewayIP:     map[string]string{mac1: ""},
+			ipPerMac:      map[string][]vm.IpEntry{mac1: {{IP: "10.0.0.5", Prefix: 24}}},
+			want: []string{
+				"    @{ Mac = '00-50-56-AA-00-01'; Addresses = @(@{ IP = '10.0.0.5'; Prefix = 24 }); Gateway = ''; Dns = @() }",
+			},
+		},
+		{
+			name: "gateway of a DHCP adapter",
+			guestNetworks: []vjailbreakv1alpha1.GuestNetwork{
+				{MAC: mac1, IP: "10.0.0.5", Origin: "dhcp"},
+				{MAC: mac2, IP: "10.1.0.5", Origin: "manual"},
+			},
+			gatewayIP: map[string]string{mac1: "10.0.0.1", mac2: "10.1.0.1"},
+			ipPerMac: map[string][]vm.IpEntry{
+				mac1: {{IP: "10.0.0.5", Prefix: 24}},
+				mac2: {{IP: "10.1.0.5", Prefix: 24}},
+			},
+			want: []string{
+				"    @{ Mac = '00-50-56-AA-00-02'; Addresses = @(@{ IP = '10.1.0.5'; Prefix = 24 }); Gateway = '10.1.0.1'; Dns = @() }",
+			},
+		},
+		{
+			name:          "DHCP only",
+			guestNetworks: []vjailbreakv1alpha1.GuestNetwork{{MAC: mac1, IP: "10.0.0.5", Origin: "dhcp"}},
+			gatewayIP:     map[string]string{mac1: "10.0.0.1"},
+			ipPerMac:      map[string][]vm.IpEntry{mac1: {{IP: "10.0.0.5", Prefix: 24}}},
+		},
+		{
+			name:     "no addresses",
+			ipPerMac: map[string][]vm.IpEntry{mac1: {}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := ""
+			if len(tt.want) > 0 {
+				want = strings.Replace(constants.WindowsNetworkPersistenceScript, constants.WindowsNetworkConfigPlaceholder, strings.Join(tt.want, ",\n"), 1)
+				want = strings.ReplaceAll(want, "\n", "\r\n")
+			}
+			assert.Equal(t, want, GenerateWindowsNetworkScript(tt.guestNetworks, tt.gatewayIP, tt.ipPerMac))
+		})
+	}
+}
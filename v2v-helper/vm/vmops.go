@@ -33,6 +33,7 @@ type VMOperations interface {
 	IsCBTEnabled() (bool, error)
 	EnableCBT() error
 	TakeSnapshot(name string) error
+	TakeQuiescedSnapshot(name string, memory bool) error
 	DeleteSnapshot(name string) error
 	DeleteSnapshotByRef(snap *types.ManagedObjectReference) error
 	GetSnapshot(name string) (*types.ManagedObjectReference, error)
@@ -44,11 +45,38 @@ type VMOperations interface {
 	VMPowerOff() error
 	VMPowerOn() error
 	DisconnectNetworkInterfaces() error
+	ReconnectNetworkInterfaces() error
 }
 
 type IpEntry struct {
 	IP     string
 	Prefix int32
+	// Origin is how the guest got the address as reported by VMware Tools: manual, dhcp,
+	// linklayer (SLAAC) or random (privacy extensions). Empty when it is not known.
+	Origin string
+}
+
+// IsIPv6 reports whether the entry holds an IPv6 address
+func (e IpEntry) IsIPv6() bool {
+	return strings.Contains(e.IP, ":")
+}
+
+// PrefixLength returns the prefix length of the address, 24 for IPv4 and 64 for IPv6 when it is not known
+func (e IpEntry) PrefixLength() int32 {
+	switch {
+	case e.Prefix != 0:
+		return e.Prefix
+	case e.IsIPv6():
+		return 64
+	default:
+		return 24
+	}
+}
+
+// IsAutoconfigured reports whether an IPv6 address was configured from router advertisements
+// (SLAAC) rather than statically or through DHCPv6
+func (e IpEntry) IsAutoconfigured() bool {
+	return e.IsIPv6() && (strings.EqualFold(e.Origin, "linklayer") || strings.EqualFold(e.Origin, "random"))
 }
 
 type VMInfo struct {
@@ -61,6 +89,8 @@ type VMInfo struct {
 	Host              string
 	VMDisks           []VMDisk
 	UEFI              bool
+	SecureBoot        bool
+	VTPM              bool
 	Name              string
 	OSType            string
 	GuestNetworks     []vjailbreakv1alpha1.GuestNetwork
@@ -192,6 +222,14 @@ func (vmops *VMOps) GetVMInfo(ostype string, rdmDisks []string) (VMInfo, error)
 						ipPerMac[guestNetwork.MAC] = append(ipPerMac[guestNetwork.MAC], IpEntry{
 							IP:     guestNetwork.IP,
 							Prefix: guestNetwork.PrefixLength,
+							Origin: guestNetwork.Origin,
+						})
+					} else if !strings.HasPrefix(strings.ToLower(guestNetwork.IP), "fe80:") {
+						// IPv6 link-local addresses are derived from the MAC address on the target again
+						ipPerMac[guestNetwork.MAC] = append(ipPerMac[guestNetwork.MAC], IpEntry{
+							IP:     guestNetwork.IP,
+							Prefix: guestNetwork.PrefixLength,
+							Origin: guestNetwork.Origin,
 						})
 					}
 				}
@@ -268,6 +306,17 @@ func (vmops *VMOps) GetVMInfo(ostype string, rdmDisks []string) (VMInfo, error)
 	if o.Config.Firmware == "efi" {
 		uefi = true
 	}
+	secureBoot := false
+	if uefi && o.Config.BootOptions != nil && o.Config.BootOptions.EfiSecureBootEnabled != nil {
+		secureBoot = *o.Config.BootOptions.EfiSecureBootEnabled
+	}
+	vtpm := false
+	for _, device := range o.Config.Hardware.Device {
+		if _, ok := device.(*types.VirtualTPM); ok {
+			vtpm = true
+			break
+		}
+	}
 	if ostype == "" {
 		if strings.EqualFold(string(o.Guest.GuestFamily), string(types.VirtualMachineGuestOsFamilyWindowsGuest)) {
 			ostype = constants.OSFamilyWindows
@@ -298,6 +347,8 @@ func (vmops *VMOps) GetVMInfo(ostype string, rdmDisks []string) (VMInfo, error)
 		VMDisks:           vmdisks,
 		RDMDisks:          rdmDiskSlice,
 		UEFI:              uefi,
+		SecureBoot:        secureBoot,
+		VTPM:              vtpm,
 		OSType:            ostype,
 		NetworkInterfaces: vmwareMachine.Spec.VMInfo.NetworkInterfaces,
 		GuestNetworks:     vmwareMachine.Spec.VMInfo.GuestNetworks,
@@ -544,9 +595,19 @@ func (vmops *VMOps) EnableCBT() error {
 }
 
 func (vmops *VMOps) TakeSnapshot(name string) error {
+	return vmops.createSnapshot(name, false, false)
+}
+
+// TakeQuiescedSnapshot takes a snapshot after VMware Tools quiesced the file systems of the guest,
+// optionally including the memory of the VM
+func (vmops *VMOps) TakeQuiescedSnapshot(name string, memory bool) error {
+	return vmops.createSnapshot(name, memory, true)
+}
+
+func (vmops *VMOps) createSnapshot(name string, memory, quiesce bool) error {
 	vm := vmops.VMObj
 
-	task, err := vm.CreateSnapshot(vmops.ctx, name, "", false, false)
+	task, err := vm.CreateSnapshot(vmops.ctx, name, "", memory, quiesce)
 	if err != nil {
 		if !strings.Contains(err.Error(), "NotAuthenticated") {
 			return fmt.Errorf("failed to take snapshot: %s", err)
@@ -555,7 +616,7 @@ func (vmops *VMOps) TakeSnapshot(name string) error {
 			return fmt.Errorf("failed to refresh VM reference: %s", err)
 		}
 		vm = vmops.VMObj
-		task, err = vm.CreateSnapshot(vmops.ctx, name, "", false, false)
+		task, err = vm.CreateSnapshot(vmops.ctx, name, "", memory, quiesce)
 		if err != nil {
 			return fmt.Errorf("failed to take snapshot: %s", err)
 		}
@@ -870,12 +931,21 @@ func (vmops *VMOps) VMPowerOn() error {
 }
 
 func (vmops *VMOps) DisconnectNetworkInterfaces() error {
+	return vmops.setNetworkInterfacesConnected(false)
+}
+
+// ReconnectNetworkInterfaces connects the NICs disconnected by DisconnectNetworkInterfaces again
+func (vmops *VMOps) ReconnectNetworkInterfaces() error {
+	return vmops.setNetworkInterfacesConnected(true)
+}
+
+func (vmops *VMOps) setNetworkInterfacesConnected(connected bool) error {
 	ctx := vmops.ctx
 
 	vm := vmops.VMObj
 
 	var mvm mo.VirtualMachine
-	if err := vm.Properties(ctx, vm.Reference(), []string{"config.hardware"}, &mvm); err != nil {
+	if err := vm.Properties(ctx, vm.Reference(), []string{"config.hardware", "runtime.powerState"}, &mvm); err != nil {
 		if !strings.Contains(err.Error(), "NotAuthenticated") {
 			return fmt.Errorf("failed to get VM properties: %s", err)
 		}
@@ -883,7 +953,7 @@ func (vmops *VMOps) DisconnectNetworkInterfaces() error {
 			return fmt.Errorf("failed to refresh VM reference: %s", err)
 		}
 		vm = vmops.VMObj
-		if err := vm.Properties(ctx, vm.Reference(), []string{"config.hardware"}, &mvm); err != nil {
+		if err := vm.Properties(ctx, vm.Reference(), []string{"config.hardware", "runtime.powerState"}, &mvm); err != nil {
 			return fmt.Errorf("failed to get VM properties: %s", err)
 		}
 	}
@@ -896,11 +966,15 @@ func (vmops *VMOps) DisconnectNetworkInterfaces() error {
 	for _, device := range mvm.Config.Hardware.Device {
 		if nic, ok := device.(types.BaseVirtualEthernetCard); ok {
 			nicName := nic.GetVirtualEthernetCard().DeviceInfo.GetDescription().Label
-			log.Printf("Found NIC to disconnect: %s", nicName)
-			deviceCopy := device
 			connectable := nic.GetVirtualEthernetCard().Connectable
-			connectable.Connected = false
-			connectable.StartConnected = false
+			if connectable == nil || (connectable.StartConnected == connected && connectable.Connected == connected) {
+				continue
+			}
+			log.Printf("Found NIC to update: %s, connected: %t", nicName, connected)
+			deviceCopy := device
+			// Only a running VM can have its NICs connected, a powered off VM connects them at power on
+			connectable.Connected = connected && mvm.Runtime.PowerState == types.VirtualMachinePowerStatePoweredOn
+			connectable.StartConnected = connected
 			spec := &types.VirtualDeviceConfigSpec{
 				Operation: types.VirtualDeviceConfigSpecOperationEdit,
 				Device:    deviceCopy,
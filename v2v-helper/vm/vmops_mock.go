@@ -208,6 +208,34 @@ func (mr *MockVMOperationsMockRecorder) ListSnapshots() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListSnapshots", reflect.TypeOf((*MockVMOperations)(nil).ListSnapshots))
 }
 
+// ReconnectNetworkInterfaces mocks base method.
+func (m *MockVMOperations) ReconnectNetworkInterfaces() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReconnectNetworkInterfaces")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReconnectNetworkInterfaces indicates an expected call of ReconnectNetworkInterfaces.
+func (mr *MockVMOperationsMockRecorder) ReconnectNetworkInterfaces() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReconnectNetworkInterfaces", reflect.TypeOf((*MockVMOperations)(nil).ReconnectNetworkInterfaces))
+}
+
+// TakeQuiescedSnapshot mocks base method.
+func (m *MockVMOperations) TakeQuiescedSnapshot(name string, memory bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TakeQuiescedSnapshot", name, memory)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// TakeQuiescedSnapshot indicates an expected call of TakeQuiescedSnapshot.
+func (mr *MockVMOperationsMockRecorder) TakeQuiescedSnapshot(name, memory interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TakeQuiescedSnapshot", reflect.TypeOf((*MockVMOperations)(nil).TakeQuiescedSnapshot), name, memory)
+}
+
 // TakeSnapshot mocks base method.
 func (m *MockVMOperations) TakeSnapshot(name string) error {
 	m.ctrl.T.Helper()
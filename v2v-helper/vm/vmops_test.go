@@ -208,3 +208,24 @@ func TestGetSnapshot(t *testing.T) {
 
 // Could not make unit tests for CustomQueryChangedDiskAreas and UpdateDiskInfo
 // as they rely on change block tracking which is not supported by the simulator
+
+func TestIpEntry(t *testing.T) {
+	tests := []struct {
+		entry          IpEntry
+		ipv6           bool
+		prefix         int32
+		autoconfigured bool
+	}{
+		{IpEntry{IP: "10.0.0.5"}, false, 24, false},
+		{IpEntry{IP: "10.0.0.5", Prefix: 16, Origin: "manual"}, false, 16, false},
+		{IpEntry{IP: "2001:db8::5", Origin: "manual"}, true, 64, false},
+		{IpEntry{IP: "2001:db8::5", Prefix: 48, Origin: "dhcp"}, true, 48, false},
+		{IpEntry{IP: "2001:db8::250:56ff:fe01:203", Origin: "linklayer"}, true, 64, true},
+		{IpEntry{IP: "2001:db8::8d1c:2a4f", Origin: "random"}, true, 64, true},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.ipv6, tt.entry.IsIPv6(), tt.entry.IP)
+		assert.Equal(t, tt.prefix, tt.entry.PrefixLength(), tt.entry.IP)
+		assert.Equal(t, tt.autoconfigured, tt.entry.IsAutoconfigured(), tt.entry.IP)
+	}
+}